@@ -2,41 +2,299 @@ package main
 
 import (
 	"context"
+	"crypto/rand"
+	"errors"
+	"flag"
+	"fmt"
 	grpc_recovery "github.com/grpc-ecosystem/go-grpc-middleware/recovery"
 	"google.golang.org/grpc"
 	"homework9/internal/adapters/adrepo"
+	"homework9/internal/adapters/blobfs"
+	"homework9/internal/adapters/categoryrepo"
+	"homework9/internal/adapters/favoriterepo"
+	"homework9/internal/adapters/filerepo"
+	"homework9/internal/adapters/jwtauth"
+	"homework9/internal/adapters/lognotifier"
+	"homework9/internal/adapters/memtx"
+	"homework9/internal/adapters/messagerepo"
+	"homework9/internal/adapters/reportrepo"
+	"homework9/internal/adapters/sessionrepo"
+	"homework9/internal/adapters/sqlrepo"
 	"homework9/internal/adapters/userrepo"
+	"homework9/internal/ads"
 	"homework9/internal/app"
+	"homework9/internal/categories"
+	"homework9/internal/checks"
+	"homework9/internal/config"
+	"homework9/internal/events"
+	"homework9/internal/favorites"
+	"homework9/internal/money"
 	grpcPort "homework9/internal/ports/grpc"
 	"homework9/internal/ports/httpgin"
+	"homework9/internal/reports"
+	"homework9/internal/search"
+	"homework9/internal/users"
+	"io"
 	"log"
 	"net"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sync"
+	"syscall"
 )
 
 func main() {
-	server := httpgin.NewHTTPServer(":18080", app.NewApp(adrepo.New(), userrepo.New()))
-	err := server.Listen()
+	cfg, err := config.Load(os.Args[1:], os.Getenv)
+	if errors.Is(err, flag.ErrHelp) {
+		return
+	} else if err != nil {
+		log.Fatal(err)
+	}
+	if err := run(cfg); err != nil {
+		log.Fatal(err)
+	}
+}
+
+func run(cfg config.Config) error {
+	var adRepo ads.AdRepository
+	var userRepo users.UserRepository
+	var categoryRepo categories.CategoryRepository
+	var reportRepo reports.ReportRepository
+	var favoriteRepo favorites.FavoriteRepository
+	var uow app.UnitOfWork
+	switch cfg.Storage.Backend {
+	case config.StorageMemory:
+		adMap, userMap := adrepo.New(), userrepo.New()
+		adRepo, userRepo, uow = adMap, userMap, memtx.New(adMap, userMap)
+		categoryRepo, reportRepo, favoriteRepo = categoryrepo.New(), reportrepo.New(), favoriterepo.New()
+	case config.StorageFile:
+		store, err := filerepo.Open(cfg.Storage.Path, cfg.Storage.CompactInterval)
+		if err != nil {
+			return fmt.Errorf("failed to open storage: %w", err)
+		}
+		defer store.Close()
+		adRepo, userRepo, uow = store.Ads(), store.Users(), store
+		categoryRepo, reportRepo, favoriteRepo = store.Categories(), store.Reports(), store.Favorites()
+	case config.StorageSQLite:
+		if err := os.MkdirAll(filepath.Dir(cfg.Storage.Path), 0o755); err != nil {
+			return fmt.Errorf("failed to create data directory: %w", err)
+		}
+		db, err := sqlrepo.Open(context.Background(), cfg.Storage.Path)
+		if err != nil {
+			return fmt.Errorf("failed to open database: %w", err)
+		}
+		defer db.Close()
+		adRepo, userRepo, uow = db.Ads(), db.Users(), db
+		categoryRepo, reportRepo, favoriteRepo = db.Categories(), db.Reports(), db.Favorites()
+	}
+
+	index, err := buildSearchIndex(context.Background(), adRepo)
 	if err != nil {
-		panic(err)
+		return fmt.Errorf("failed to build search index: %w", err)
 	}
 
-	port := ":50054"
-	lis, err := net.Listen("tcp", port)
+	keyring, err := signingKeys(cfg.Auth.Keys, levelLog(cfg, config.LogWarn))
 	if err != nil {
-		log.Fatalf("fail")
+		return err
+	}
+	rates, err := money.NewRates(cfg.Currency.Base, cfg.Currency.Rates)
+	if err != nil {
+		return err
+	}
+	blobStore, err := blobfs.New(cfg.Images.Path)
+	if err != nil {
+		return fmt.Errorf("failed to open image storage: %w", err)
 	}
-	service := grpcPort.NewService()
-	serverGrpc := grpc.NewServer(grpc.ChainUnaryInterceptor(LoggerInterceptor, grpc_recovery.UnaryServerInterceptor()))
-	defer serverGrpc.GracefulStop()
 
-	grpcPort.RegisterAdServiceServer(serverGrpc, service)
+	infoLog := levelLog(cfg, config.LogInfo)
 
-	if err = serverGrpc.Serve(lis); err != nil {
-		log.Fatal("failed to serve")
+	checkers, err := contentCheckers(cfg.Content, adRepo)
+	if err != nil {
+		return err
 	}
+
+	// оба транспорта работают с одним и тем же приложением и получают события из одного хаба
+	hub := events.NewHub()
+	a := app.NewApp(adRepo, userRepo, categoryRepo, uow,
+		app.WithSessions(sessionrepo.New()),
+		app.WithAccessTokens(keyring),
+		app.WithSearchIndex(index),
+		app.WithAccessTTL(cfg.Auth.AccessTTL),
+		app.WithRefreshTTL(cfg.Auth.RefreshTTL),
+		app.WithAdminEmails(cfg.Auth.AdminEmails...),
+		app.WithRates(rates),
+		app.WithBlobStore(blobStore),
+		app.WithImageLimits(app.ImageLimits{MaxBytes: int64(cfg.Images.MaxBytes), PerAd: cfg.Images.PerAd, ThumbSize: cfg.Images.ThumbSize}),
+		app.WithAdLifetime(cfg.Schedule.AdLifetime),
+		app.WithModeration(cfg.Moderation.Enabled),
+		app.WithContentCheckers(checkers...),
+		app.WithReports(reportRepo),
+		app.WithReportThreshold(cfg.Reports.Threshold),
+		app.WithFavorites(favoriteRepo),
+		// переписки, как и сессии, живут только в памяти процесса при любом хранилище
+		app.WithMessages(messagerepo.New()),
+		app.WithEvents(hub),
+		app.WithNotifier(lognotifier.New(infoLog)))
+
+	// планировщик останавливается после серверов, но до закрытия хранилища (defer выше)
+	scheduler := app.NewScheduler(a, cfg.Schedule.Interval)
+	scheduler.Start()
+	defer scheduler.Stop()
+
+	// журнал запросов пишется на уровне info
+	requestLog := cfg.LogEnabled(config.LogInfo)
+	server := httpgin.NewHTTPServer(cfg.HTTPAddr, a,
+		httpgin.WithLimits(httpgin.Limits(cfg.Limits)),
+		httpgin.WithRequestLog(requestLog))
+
+	lis, err := net.Listen("tcp", cfg.GRPCAddr)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %w", cfg.GRPCAddr, err)
+	}
+	interceptors := []grpc.UnaryServerInterceptor{grpc_recovery.UnaryServerInterceptor(), grpcPort.AuthInterceptor(a)}
+	streamInterceptors := []grpc.StreamServerInterceptor{grpc_recovery.StreamServerInterceptor(), grpcPort.AuthStreamInterceptor(a)}
+	if requestLog {
+		interceptors = append([]grpc.UnaryServerInterceptor{LoggerInterceptor}, interceptors...)
+		streamInterceptors = append([]grpc.StreamServerInterceptor{StreamLoggerInterceptor}, streamInterceptors...)
+	}
+	serverGrpc := grpc.NewServer(grpc.ChainUnaryInterceptor(interceptors...), grpc.ChainStreamInterceptor(streamInterceptors...))
+	grpcPort.RegisterAdServiceServer(serverGrpc, grpcPort.NewService(a))
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	errs := make(chan error, 2)
+	go func() {
+		if err := server.Listen(); err != nil {
+			errs <- fmt.Errorf("http server: %w", err)
+		}
+	}()
+	go func() {
+		if err := serverGrpc.Serve(lis); err != nil {
+			errs <- fmt.Errorf("grpc server: %w", err)
+		}
+	}()
+	infoLog.Printf("http server listening on %s, grpc server listening on %s", cfg.HTTPAddr, cfg.GRPCAddr)
+
+	var serveErr error
+	select {
+	case <-ctx.Done():
+		infoLog.Println("shutting down")
+	case serveErr = <-errs:
+		log.Printf("%v, shutting down", serveErr)
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.ShutdownTimeout)
+	defer cancel()
+	// подписки закрываются первыми: иначе потоки WatchAds держали бы GracefulStop до таймаута
+	hub.Close()
+	shutdown(shutdownCtx, &server, serverGrpc)
+	return serveErr
+}
+
+// buildSearchIndex индексирует объявления, уже лежащие в хранилище. Дальше индекс обновляет приложение
+func buildSearchIndex(ctx context.Context, adRepo ads.AdRepository) (*search.Index, error) {
+	all, err := adRepo.GetAllAds(ctx)
+	if err != nil {
+		return nil, err
+	}
+	index := search.NewIndex()
+	for _, ad := range all {
+		index.Put(ad.ID, ad.Title, ad.Text)
+	}
+	return index, nil
+}
+
+// contentCheckers собирает проверки содержимого из конфигурации. Проверки с решением allow не создаются
+func contentCheckers(cfg config.Content, adRepo ads.AdRepository) ([]checks.Checker, error) {
+	var res []checks.Checker
+	if len(cfg.BannedWords) > 0 {
+		res = append(res, checks.NewWords("banned_words", checks.Reject, cfg.BannedWords))
+	}
+	if len(cfg.FlaggedWords) > 0 {
+		res = append(res, checks.NewWords("flagged_words", checks.Flag, cfg.FlaggedWords))
+	}
+	for _, c := range []struct {
+		verdict string
+		build   func(v checks.Verdict) checks.Checker
+	}{
+		{cfg.Contacts, func(v checks.Verdict) checks.Checker { return checks.NewContacts(v) }},
+		{cfg.Spam, func(v checks.Verdict) checks.Checker { return checks.NewSpam(v) }},
+		{cfg.Duplicates, func(v checks.Verdict) checks.Checker { return checks.NewDuplicates(adRepo, v) }},
+	} {
+		v, err := checks.ParseVerdict(c.verdict)
+		if err != nil {
+			return nil, err
+		}
+		if v != checks.Allow {
+			res = append(res, c.build(v))
+		}
+	}
+	return res, nil
+}
+
+// signingKeys собирает ключи подписи токенов из конфигурации.
+// Если ключей нет, генерирует случайный: выданные токены перестанут действовать после перезапуска
+func signingKeys(keys []config.SigningKey, warnLog *log.Logger) (*jwtauth.Keyring, error) {
+	if len(keys) == 0 {
+		secret := make([]byte, jwtauth.MinSecretLen)
+		if _, err := rand.Read(secret); err != nil {
+			return nil, err
+		}
+		warnLog.Println("no token signing keys configured, using a random key; tokens will not survive a restart")
+		return jwtauth.New(jwtauth.Key{ID: "ephemeral", Secret: secret})
+	}
+	converted := make([]jwtauth.Key, 0, len(keys))
+	for _, k := range keys {
+		converted = append(converted, jwtauth.Key{ID: k.ID, Secret: []byte(k.Secret)})
+	}
+	return jwtauth.New(converted...)
+}
+
+// levelLog - журнал для сообщений уровня level: стандартный, если уровень включён в конфигурации, иначе немой.
+// Ошибки пишутся через log напрямую
+func levelLog(cfg config.Config, level string) *log.Logger {
+	if !cfg.LogEnabled(level) {
+		return log.New(io.Discard, "", 0)
+	}
+	return log.Default()
+}
+
+// shutdown останавливает оба сервера параллельно, давая текущим запросам завершиться до истечения ctx
+func shutdown(ctx context.Context, server *httpgin.Server, serverGrpc *grpc.Server) {
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		if err := server.Shutdown(ctx); err != nil {
+			log.Printf("http shutdown: %v", err)
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		done := make(chan struct{})
+		go func() {
+			serverGrpc.GracefulStop()
+			close(done)
+		}()
+		select {
+		case <-done:
+		case <-ctx.Done():
+			log.Println("grpc shutdown: drain timeout exceeded, connections closed")
+			serverGrpc.Stop()
+			<-done
+		}
+	}()
+	wg.Wait()
 }
 
 func LoggerInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
 	log.Println(info.FullMethod)
 	return handler(ctx, req)
 }
+
+func StreamLoggerInterceptor(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	log.Println(info.FullMethod)
+	return handler(srv, ss)
+}
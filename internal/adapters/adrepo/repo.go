@@ -2,19 +2,21 @@ package adrepo
 
 import (
 	"context"
-	"errors"
 	"homework9/internal/ads"
+	"homework9/internal/geo"
 	"sync"
 )
 
 type RepositoryMap struct {
-	repo   map[int64]ads.Ad
+	repo map[int64]ads.Ad
+	// places - геохеш-сетка объявлений с местом, из неё берутся кандидаты для Filter.Near и Filter.Box
+	places *geo.Index
 	lastId int64
 	mx     *sync.RWMutex
 }
 
 func New() *RepositoryMap {
-	return &RepositoryMap{repo: make(map[int64]ads.Ad), lastId: -1, mx: &sync.RWMutex{}}
+	return &RepositoryMap{repo: make(map[int64]ads.Ad), places: geo.NewIndex(), lastId: -1, mx: &sync.RWMutex{}}
 }
 
 // - создание нового объявления
@@ -24,68 +26,178 @@ func New() *RepositoryMap {
 func (r *RepositoryMap) GetAdById(ctx context.Context, id int64) (*ads.Ad, error) {
 	r.mx.RLock()
 	defer r.mx.RUnlock()
-	ad, ok := r.repo[id]
-	if !ok {
-		return nil, errors.New("ad not found")
-	}
-	return &ad, nil
+	return r.get(id)
 }
 
 func (r *RepositoryMap) AddAd(ctx context.Context, ad ads.Ad) (int64, error) {
 	r.mx.Lock()
 	defer r.mx.Unlock()
-	r.lastId++
-	id := r.lastId
-	r.repo[id] = ad
-	return id, nil
+	return r.add(ad), nil
 }
 
 func (r *RepositoryMap) DeleteAdById(ctx context.Context, id int64) error {
 	r.mx.Lock()
 	defer r.mx.Unlock()
-	_, ok := r.repo[id]
+	_, ok := r.remove(id)
 	if !ok {
-		return errors.New("ad not found")
+		return ads.ErrNotFound
 	}
-	delete(r.repo, id)
 	return nil
 }
 
 func (r *RepositoryMap) UpdateById(ctx context.Context, id int64, ad ads.Ad) error {
 	r.mx.Lock()
 	defer r.mx.Unlock()
-	_, ok := r.repo[id]
-	if !ok {
-		return errors.New("ad not found")
-	}
-	r.repo[id] = ad
-	return nil
+	_, err := r.update(id, ad)
+	return err
 }
 
-func (r *RepositoryMap) ListPublishedAds(ctx context.Context) ([]ads.Ad, error) {
+func (r *RepositoryMap) ListPublishedAds(ctx context.Context, p ads.Page) ([]ads.Ad, error) {
 	r.mx.RLock()
 	defer r.mx.RUnlock()
-	res := make([]ads.Ad, 0)
-	for _, ad := range r.repo {
-		if ad.Published {
-			res = append(res, ad)
-		}
-	}
-	return res, nil
+	return r.find(ads.Filter{Published: ads.OnlyPublished}, p), nil
 }
 
 func (r *RepositoryMap) GetAllAds(ctx context.Context) ([]ads.Ad, error) {
 	r.mx.RLock()
 	defer r.mx.RUnlock()
-	res := make([]ads.Ad, 0)
-	for _, ad := range r.repo {
-		res = append(res, ad)
-	}
-	return res, nil
+	return r.all(), nil
+}
+
+func (r *RepositoryMap) FindAds(ctx context.Context, f ads.Filter, p ads.Page) ([]ads.Ad, error) {
+	r.mx.RLock()
+	defer r.mx.RUnlock()
+	return r.find(f, p), nil
 }
 
 func (r *RepositoryMap) DeleteAd(ctx context.Context, id int64) {
 	r.mx.Lock()
 	defer r.mx.Unlock()
+	r.remove(id)
+}
+
+// Restore кладёт объявление под уже выданным ему ID (например, при восстановлении из журнала)
+func (r *RepositoryMap) Restore(ad ads.Ad) {
+	r.mx.Lock()
+	defer r.mx.Unlock()
+	r.store(ad)
+	if ad.ID > r.lastId {
+		r.lastId = ad.ID
+	}
+}
+
+// LastID возвращает последний выданный ID, даже если объявление с ним уже удалено
+func (r *RepositoryMap) LastID() int64 {
+	r.mx.RLock()
+	defer r.mx.RUnlock()
+	return r.lastId
+}
+
+// SetLastID сдвигает счётчик ID вперёд, чтобы удалённые ID не выдавались повторно
+func (r *RepositoryMap) SetLastID(id int64) {
+	r.mx.Lock()
+	defer r.mx.Unlock()
+	if id > r.lastId {
+		r.lastId = id
+	}
+}
+
+// методы ниже вызываются под r.mx
+
+func (r *RepositoryMap) get(id int64) (*ads.Ad, error) {
+	ad, ok := r.repo[id]
+	if !ok {
+		return nil, ads.ErrNotFound
+	}
+	ad = detach(ad)
+	return &ad, nil
+}
+
+func (r *RepositoryMap) add(ad ads.Ad) int64 {
+	r.lastId++
+	id := r.lastId
+	ad.ID = id
+	ad.Version = 1
+	r.store(ad)
+	return id
+}
+
+func (r *RepositoryMap) update(id int64, ad ads.Ad) (ads.Ad, error) {
+	prev, ok := r.repo[id]
+	if !ok {
+		return ads.Ad{}, ads.ErrNotFound
+	}
+	if ad.Version != prev.Version {
+		return ads.Ad{}, ads.ErrVersionConflict
+	}
+	ad.ID = id
+	ad.Version = prev.Version + 1
+	r.store(ad)
+	return prev, nil
+}
+
+func (r *RepositoryMap) remove(id int64) (ads.Ad, bool) {
+	prev, ok := r.repo[id]
+	if ok {
+		r.drop(id)
+	}
+	return prev, ok
+}
+
+// store кладёт объявление в карту и геохеш-сетку
+func (r *RepositoryMap) store(ad ads.Ad) {
+	r.repo[ad.ID] = detach(ad)
+	if ad.Location != nil {
+		r.places.Put(ad.ID, ad.Location.Point())
+	} else {
+		r.places.Remove(ad.ID)
+	}
+}
+
+func (r *RepositoryMap) drop(id int64) {
 	delete(r.repo, id)
+	r.places.Remove(id)
+}
+
+// detach копирует списки фотографий и замечаний и место, чтобы хранимое объявление не делило их с вызывающим.
+// Объявления из all и find не копируются: их только читают
+func detach(ad ads.Ad) ads.Ad {
+	if ad.Images != nil {
+		ad.Images = append([]ads.Image(nil), ad.Images...)
+	}
+	if ad.Flags != nil {
+		ad.Flags = append([]ads.Flag(nil), ad.Flags...)
+	}
+	if ad.Location != nil {
+		loc := *ad.Location
+		ad.Location = &loc
+	}
+	return ad
+}
+
+func (r *RepositoryMap) all() []ads.Ad {
+	res := make([]ads.Ad, 0)
+	for _, ad := range r.repo {
+		res = append(res, ad)
+	}
+	return res
+}
+
+// find с условиями на место проверяет только объявления из ячеек сетки, покрывающих область поиска
+func (r *RepositoryMap) find(f ads.Filter, p ads.Page) []ads.Ad {
+	res := make([]ads.Ad, 0)
+	if bounds, ok := f.GeoBounds(); ok {
+		for _, id := range r.places.Query(bounds) {
+			if ad := r.repo[id]; f.Match(ad) {
+				res = append(res, ad)
+			}
+		}
+		return p.Apply(res)
+	}
+	for _, ad := range r.repo {
+		if f.Match(ad) {
+			res = append(res, ad)
+		}
+	}
+	return p.Apply(res)
 }
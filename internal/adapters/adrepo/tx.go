@@ -0,0 +1,106 @@
+package adrepo
+
+import (
+	"context"
+	"homework9/internal/ads"
+)
+
+// Tx - транзакция над RepositoryMap. Держит блокировку на запись до Commit или Rollback,
+// поэтому пока транзакция открыта, к самому RepositoryMap обращаться нельзя.
+type Tx struct {
+	r       *RepositoryMap
+	undo    []func()
+	changed map[int64]struct{}
+	done    bool
+}
+
+func (r *RepositoryMap) Begin() *Tx {
+	r.mx.Lock()
+	return &Tx{r: r, changed: make(map[int64]struct{})}
+}
+
+// Commit оставляет изменения и снимает блокировку
+func (t *Tx) Commit() {
+	if t.done {
+		return
+	}
+	t.done = true
+	t.r.mx.Unlock()
+}
+
+// Rollback отменяет все изменения транзакции в обратном порядке и снимает блокировку
+func (t *Tx) Rollback() {
+	if t.done {
+		return
+	}
+	for i := len(t.undo) - 1; i >= 0; i-- {
+		t.undo[i]()
+	}
+	t.done = true
+	t.r.mx.Unlock()
+}
+
+// Changed возвращает ID объявлений, которые транзакция добавила, изменила или удалила
+func (t *Tx) Changed() []int64 {
+	res := make([]int64, 0, len(t.changed))
+	for id := range t.changed {
+		res = append(res, id)
+	}
+	return res
+}
+
+// LastID - последний выданный ID с учётом изменений транзакции
+func (t *Tx) LastID() int64 {
+	return t.r.lastId
+}
+
+func (t *Tx) GetAdById(ctx context.Context, id int64) (*ads.Ad, error) {
+	return t.r.get(id)
+}
+
+func (t *Tx) ListPublishedAds(ctx context.Context, p ads.Page) ([]ads.Ad, error) {
+	return t.r.find(ads.Filter{Published: ads.OnlyPublished}, p), nil
+}
+
+func (t *Tx) GetAllAds(ctx context.Context) ([]ads.Ad, error) {
+	return t.r.all(), nil
+}
+
+func (t *Tx) FindAds(ctx context.Context, f ads.Filter, p ads.Page) ([]ads.Ad, error) {
+	return t.r.find(f, p), nil
+}
+
+func (t *Tx) AddAd(ctx context.Context, ad ads.Ad) (int64, error) {
+	lastId := t.r.lastId
+	id := t.r.add(ad)
+	t.changed[id] = struct{}{}
+	t.undo = append(t.undo, func() {
+		t.r.drop(id)
+		t.r.lastId = lastId
+	})
+	return id, nil
+}
+
+func (t *Tx) UpdateById(ctx context.Context, id int64, ad ads.Ad) error {
+	prev, err := t.r.update(id, ad)
+	if err != nil {
+		return err
+	}
+	t.changed[id] = struct{}{}
+	t.undo = append(t.undo, func() { t.r.store(prev) })
+	return nil
+}
+
+func (t *Tx) DeleteAdById(ctx context.Context, id int64) error {
+	prev, ok := t.r.remove(id)
+	if !ok {
+		return ads.ErrNotFound
+	}
+	t.changed[id] = struct{}{}
+	t.undo = append(t.undo, func() { t.r.store(prev) })
+	return nil
+}
+
+func (t *Tx) DeleteAd(ctx context.Context, id int64) {
+	_ = t.DeleteAdById(ctx, id)
+}
@@ -0,0 +1,102 @@
+package categoryrepo
+
+import (
+	"context"
+	"homework9/internal/categories"
+	"sort"
+	"sync"
+)
+
+type RepositoryMap struct {
+	repo   map[int64]categories.Category
+	lastId int64
+	mx     *sync.RWMutex
+}
+
+func New() *RepositoryMap {
+	return &RepositoryMap{repo: make(map[int64]categories.Category), mx: &sync.RWMutex{}}
+}
+
+func (r *RepositoryMap) GetCategory(ctx context.Context, id int64) (*categories.Category, error) {
+	r.mx.RLock()
+	defer r.mx.RUnlock()
+	c, ok := r.repo[id]
+	if !ok {
+		return nil, categories.ErrNotFound
+	}
+	return &c, nil
+}
+
+func (r *RepositoryMap) ListCategories(ctx context.Context) ([]categories.Category, error) {
+	r.mx.RLock()
+	defer r.mx.RUnlock()
+	res := make([]categories.Category, 0, len(r.repo))
+	for _, c := range r.repo {
+		res = append(res, c)
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i].ID < res[j].ID })
+	return res, nil
+}
+
+func (r *RepositoryMap) AddCategory(ctx context.Context, c categories.Category) (int64, error) {
+	r.mx.Lock()
+	defer r.mx.Unlock()
+	r.lastId++
+	c.ID = r.lastId
+	r.repo[c.ID] = c
+	return c.ID, nil
+}
+
+func (r *RepositoryMap) UpdateCategory(ctx context.Context, id int64, c categories.Category) error {
+	r.mx.Lock()
+	defer r.mx.Unlock()
+	if _, ok := r.repo[id]; !ok {
+		return categories.ErrNotFound
+	}
+	c.ID = id
+	r.repo[id] = c
+	return nil
+}
+
+func (r *RepositoryMap) DeleteCategory(ctx context.Context, id int64) error {
+	r.mx.Lock()
+	defer r.mx.Unlock()
+	if _, ok := r.repo[id]; !ok {
+		return categories.ErrNotFound
+	}
+	delete(r.repo, id)
+	return nil
+}
+
+// Restore кладёт категорию под уже выданным ей ID (например, при восстановлении из журнала)
+func (r *RepositoryMap) Restore(c categories.Category) {
+	r.mx.Lock()
+	defer r.mx.Unlock()
+	r.repo[c.ID] = c
+	if c.ID > r.lastId {
+		r.lastId = c.ID
+	}
+}
+
+// Remove удаляет категорию, отсутствующая категория не считается ошибкой
+func (r *RepositoryMap) Remove(id int64) {
+	r.mx.Lock()
+	defer r.mx.Unlock()
+	delete(r.repo, id)
+}
+
+// LastID возвращает последний выданный ID, даже если категория с ним уже удалена
+func (r *RepositoryMap) LastID() int64 {
+	r.mx.RLock()
+	defer r.mx.RUnlock()
+	return r.lastId
+}
+
+// SetLastID сдвигает счётчик ID вперёд, чтобы удалённые ID не выдавались повторно
+func (r *RepositoryMap) SetLastID(id int64) {
+	r.mx.Lock()
+	defer r.mx.Unlock()
+	if id > r.lastId {
+		r.lastId = id
+	}
+}
@@ -0,0 +1,151 @@
+package favoriterepo
+
+import (
+	"context"
+	"homework9/internal/favorites"
+	"sort"
+	"sync"
+)
+
+type RepositoryMap struct {
+	// repo - избранное по пользователям, counts - сколько раз каждое объявление добавлено в избранное
+	repo   map[int64]map[int64]favorites.Favorite
+	counts map[int64]int
+	mx     *sync.RWMutex
+}
+
+func New() *RepositoryMap {
+	return &RepositoryMap{repo: make(map[int64]map[int64]favorites.Favorite), counts: make(map[int64]int), mx: &sync.RWMutex{}}
+}
+
+func (r *RepositoryMap) AddFavorite(ctx context.Context, f favorites.Favorite) error {
+	r.mx.Lock()
+	defer r.mx.Unlock()
+	if _, ok := r.repo[f.UserID][f.AdID]; ok {
+		return favorites.ErrDuplicate
+	}
+	r.put(f)
+	return nil
+}
+
+func (r *RepositoryMap) GetFavorite(ctx context.Context, userID int64, adID int64) (*favorites.Favorite, error) {
+	r.mx.RLock()
+	defer r.mx.RUnlock()
+	f, ok := r.repo[userID][adID]
+	if !ok {
+		return nil, favorites.ErrNotFound
+	}
+	return &f, nil
+}
+
+func (r *RepositoryMap) RemoveFavorite(ctx context.Context, userID int64, adID int64) error {
+	r.mx.Lock()
+	defer r.mx.Unlock()
+	if _, ok := r.repo[userID][adID]; !ok {
+		return favorites.ErrNotFound
+	}
+	r.remove(userID, adID)
+	return nil
+}
+
+func (r *RepositoryMap) ListFavorites(ctx context.Context, userID int64, after *favorites.Favorite, limit int) ([]favorites.Favorite, error) {
+	r.mx.RLock()
+	defer r.mx.RUnlock()
+	res := make([]favorites.Favorite, 0, len(r.repo[userID]))
+	for _, f := range r.repo[userID] {
+		if after == nil || after.Before(f) {
+			res = append(res, f)
+		}
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i].Before(res[j]) })
+	if limit > 0 && len(res) > limit {
+		res = res[:limit]
+	}
+	return res, nil
+}
+
+func (r *RepositoryMap) CountFavorites(ctx context.Context, adIDs []int64) (map[int64]int, error) {
+	r.mx.RLock()
+	defer r.mx.RUnlock()
+	res := make(map[int64]int, len(adIDs))
+	for _, id := range adIDs {
+		if n := r.counts[id]; n > 0 {
+			res[id] = n
+		}
+	}
+	return res, nil
+}
+
+func (r *RepositoryMap) FavoriteUsers(ctx context.Context, adID int64) ([]int64, error) {
+	r.mx.RLock()
+	defer r.mx.RUnlock()
+	res := make([]int64, 0, r.counts[adID])
+	for userID, byAd := range r.repo {
+		if _, ok := byAd[adID]; ok {
+			res = append(res, userID)
+		}
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i] < res[j] })
+	return res, nil
+}
+
+func (r *RepositoryMap) DeleteUserFavorites(ctx context.Context, userID int64) error {
+	r.mx.Lock()
+	defer r.mx.Unlock()
+	for adID := range r.repo[userID] {
+		r.remove(userID, adID)
+	}
+	return nil
+}
+
+// Restore кладёт запись избранного, заменяя прежнюю (например, при восстановлении из журнала)
+func (r *RepositoryMap) Restore(f favorites.Favorite) {
+	r.mx.Lock()
+	defer r.mx.Unlock()
+	r.put(f)
+}
+
+// Remove убирает запись избранного, если она есть
+func (r *RepositoryMap) Remove(userID int64, adID int64) {
+	r.mx.Lock()
+	defer r.mx.Unlock()
+	r.remove(userID, adID)
+}
+
+// All возвращает всё избранное всех пользователей, нужен для снимка хранилища
+func (r *RepositoryMap) All() []favorites.Favorite {
+	r.mx.RLock()
+	defer r.mx.RUnlock()
+	res := make([]favorites.Favorite, 0)
+	for _, byAd := range r.repo {
+		for _, f := range byAd {
+			res = append(res, f)
+		}
+	}
+	return res
+}
+
+func (r *RepositoryMap) put(f favorites.Favorite) {
+	byAd, ok := r.repo[f.UserID]
+	if !ok {
+		byAd = make(map[int64]favorites.Favorite)
+		r.repo[f.UserID] = byAd
+	}
+	if _, ok := byAd[f.AdID]; !ok {
+		r.counts[f.AdID]++
+	}
+	byAd[f.AdID] = f
+}
+
+func (r *RepositoryMap) remove(userID int64, adID int64) {
+	if _, ok := r.repo[userID][adID]; !ok {
+		return
+	}
+	delete(r.repo[userID], adID)
+	if len(r.repo[userID]) == 0 {
+		delete(r.repo, userID)
+	}
+	if r.counts[adID]--; r.counts[adID] <= 0 {
+		delete(r.counts, adID)
+	}
+}
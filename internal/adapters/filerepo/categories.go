@@ -0,0 +1,66 @@
+package filerepo
+
+import (
+	"context"
+
+	"homework9/internal/categories"
+)
+
+// CategoryRepository читает категории из памяти. Категории не участвуют в Store.Do:
+// каждое изменение сначала дописывается в журнал отдельной строкой и только потом применяется
+type CategoryRepository struct {
+	s *Store
+}
+
+func (r *CategoryRepository) GetCategory(ctx context.Context, id int64) (*categories.Category, error) {
+	return r.s.categories.GetCategory(ctx, id)
+}
+
+func (r *CategoryRepository) ListCategories(ctx context.Context) ([]categories.Category, error) {
+	return r.s.categories.ListCategories(ctx)
+}
+
+func (r *CategoryRepository) AddCategory(ctx context.Context, c categories.Category) (int64, error) {
+	r.s.mx.Lock()
+	defer r.s.mx.Unlock()
+
+	c.ID = r.s.categories.LastID() + 1
+	err := r.s.write(
+		record{Kind: kindCategory, Op: opPut, ID: c.ID, Category: &c},
+		record{Kind: kindCategory, Op: opSeq, ID: c.ID},
+	)
+	if err != nil {
+		return 0, err
+	}
+	r.s.categories.Restore(c)
+	return c.ID, nil
+}
+
+func (r *CategoryRepository) UpdateCategory(ctx context.Context, id int64, c categories.Category) error {
+	r.s.mx.Lock()
+	defer r.s.mx.Unlock()
+
+	if _, err := r.s.categories.GetCategory(ctx, id); err != nil {
+		return err
+	}
+	c.ID = id
+	if err := r.s.write(record{Kind: kindCategory, Op: opPut, ID: id, Category: &c}); err != nil {
+		return err
+	}
+	r.s.categories.Restore(c)
+	return nil
+}
+
+func (r *CategoryRepository) DeleteCategory(ctx context.Context, id int64) error {
+	r.s.mx.Lock()
+	defer r.s.mx.Unlock()
+
+	if _, err := r.s.categories.GetCategory(ctx, id); err != nil {
+		return err
+	}
+	if err := r.s.write(record{Kind: kindCategory, Op: opDelete, ID: id}); err != nil {
+		return err
+	}
+	r.s.categories.Remove(id)
+	return nil
+}
@@ -0,0 +1,77 @@
+package filerepo
+
+import (
+	"context"
+
+	"homework9/internal/favorites"
+)
+
+// FavoriteRepository читает избранное из памяти. Как и жалобы, избранное не участвует в Store.Do:
+// каждое изменение сначала дописывается в журнал и только потом применяется
+type FavoriteRepository struct {
+	s *Store
+}
+
+func (r *FavoriteRepository) AddFavorite(ctx context.Context, f favorites.Favorite) error {
+	r.s.mx.Lock()
+	defer r.s.mx.Unlock()
+
+	if _, err := r.s.favorites.GetFavorite(ctx, f.UserID, f.AdID); err == nil {
+		return favorites.ErrDuplicate
+	}
+	if err := r.s.write(record{Kind: kindFavorite, Op: opPut, ID: f.AdID, Favorite: &f}); err != nil {
+		return err
+	}
+	r.s.favorites.Restore(f)
+	return nil
+}
+
+func (r *FavoriteRepository) GetFavorite(ctx context.Context, userID int64, adID int64) (*favorites.Favorite, error) {
+	return r.s.favorites.GetFavorite(ctx, userID, adID)
+}
+
+func (r *FavoriteRepository) RemoveFavorite(ctx context.Context, userID int64, adID int64) error {
+	r.s.mx.Lock()
+	defer r.s.mx.Unlock()
+
+	f, err := r.s.favorites.GetFavorite(ctx, userID, adID)
+	if err != nil {
+		return err
+	}
+	if err := r.s.write(record{Kind: kindFavorite, Op: opDelete, ID: adID, Favorite: f}); err != nil {
+		return err
+	}
+	r.s.favorites.Remove(userID, adID)
+	return nil
+}
+
+func (r *FavoriteRepository) ListFavorites(ctx context.Context, userID int64, after *favorites.Favorite, limit int) ([]favorites.Favorite, error) {
+	return r.s.favorites.ListFavorites(ctx, userID, after, limit)
+}
+
+func (r *FavoriteRepository) CountFavorites(ctx context.Context, adIDs []int64) (map[int64]int, error) {
+	return r.s.favorites.CountFavorites(ctx, adIDs)
+}
+
+func (r *FavoriteRepository) FavoriteUsers(ctx context.Context, adID int64) ([]int64, error) {
+	return r.s.favorites.FavoriteUsers(ctx, adID)
+}
+
+// DeleteUserFavorites пишет удаление всех записей пользователя одной строкой журнала
+func (r *FavoriteRepository) DeleteUserFavorites(ctx context.Context, userID int64) error {
+	r.s.mx.Lock()
+	defer r.s.mx.Unlock()
+
+	list, err := r.s.favorites.ListFavorites(ctx, userID, nil, 0)
+	if err != nil || len(list) == 0 {
+		return err
+	}
+	records := make([]record, 0, len(list))
+	for i := range list {
+		records = append(records, record{Kind: kindFavorite, Op: opDelete, ID: list[i].AdID, Favorite: &list[i]})
+	}
+	if err := r.s.write(records...); err != nil {
+		return err
+	}
+	return r.s.favorites.DeleteUserFavorites(ctx, userID)
+}
@@ -0,0 +1,91 @@
+package filerepo
+
+import (
+	"context"
+
+	"homework9/internal/ads"
+	"homework9/internal/users"
+)
+
+// AdRepository читает объявления из памяти, а каждое изменение выполняет как отдельную транзакцию Store.Do
+type AdRepository struct {
+	s *Store
+}
+
+func (r *AdRepository) GetAdById(ctx context.Context, id int64) (*ads.Ad, error) {
+	return r.s.ads.GetAdById(ctx, id)
+}
+
+func (r *AdRepository) ListPublishedAds(ctx context.Context, p ads.Page) ([]ads.Ad, error) {
+	return r.s.ads.ListPublishedAds(ctx, p)
+}
+
+func (r *AdRepository) GetAllAds(ctx context.Context) ([]ads.Ad, error) {
+	return r.s.ads.GetAllAds(ctx)
+}
+
+func (r *AdRepository) FindAds(ctx context.Context, f ads.Filter, p ads.Page) ([]ads.Ad, error) {
+	return r.s.ads.FindAds(ctx, f, p)
+}
+
+func (r *AdRepository) AddAd(ctx context.Context, ad ads.Ad) (int64, error) {
+	var id int64
+	err := r.s.Do(ctx, func(adRepo ads.AdRepository, _ users.UserRepository) error {
+		var err error
+		id, err = adRepo.AddAd(ctx, ad)
+		return err
+	})
+	return id, err
+}
+
+func (r *AdRepository) UpdateById(ctx context.Context, id int64, ad ads.Ad) error {
+	return r.s.Do(ctx, func(adRepo ads.AdRepository, _ users.UserRepository) error {
+		return adRepo.UpdateById(ctx, id, ad)
+	})
+}
+
+func (r *AdRepository) DeleteAdById(ctx context.Context, id int64) error {
+	return r.s.Do(ctx, func(adRepo ads.AdRepository, _ users.UserRepository) error {
+		return adRepo.DeleteAdById(ctx, id)
+	})
+}
+
+func (r *AdRepository) DeleteAd(ctx context.Context, id int64) {
+	_ = r.DeleteAdById(ctx, id)
+}
+
+// UserRepository устроен так же, как AdRepository
+type UserRepository struct {
+	s *Store
+}
+
+func (r *UserRepository) GetUserByID(ctx context.Context, id int64) (*users.User, error) {
+	return r.s.users.GetUserByID(ctx, id)
+}
+
+func (r *UserRepository) GetUserByEmail(ctx context.Context, email string) (*users.User, error) {
+	return r.s.users.GetUserByEmail(ctx, email)
+}
+
+func (r *UserRepository) AddUser(ctx context.Context, u users.User) (int64, error) {
+	var id int64
+	err := r.s.Do(ctx, func(_ ads.AdRepository, userRepo users.UserRepository) error {
+		var err error
+		id, err = userRepo.AddUser(ctx, u)
+		return err
+	})
+	return id, err
+}
+
+func (r *UserRepository) UpdateByID(ctx context.Context, id int64, u users.User) error {
+	return r.s.Do(ctx, func(_ ads.AdRepository, userRepo users.UserRepository) error {
+		return userRepo.UpdateByID(ctx, id, u)
+	})
+}
+
+func (r *UserRepository) DeleteUser(ctx context.Context, id int64) {
+	_ = r.s.Do(ctx, func(_ ads.AdRepository, userRepo users.UserRepository) error {
+		userRepo.DeleteUser(ctx, id)
+		return nil
+	})
+}
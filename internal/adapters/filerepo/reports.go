@@ -0,0 +1,56 @@
+package filerepo
+
+import (
+	"context"
+
+	"homework9/internal/reports"
+)
+
+// ReportRepository читает жалобы из памяти. Как и категории, жалобы не участвуют в Store.Do:
+// каждое изменение сначала дописывается в журнал отдельной строкой и только потом применяется
+type ReportRepository struct {
+	s *Store
+}
+
+func (r *ReportRepository) AddReport(ctx context.Context, rep reports.Report) (int64, error) {
+	r.s.mx.Lock()
+	defer r.s.mx.Unlock()
+
+	if r.s.reports.Filed(rep) {
+		return 0, reports.ErrDuplicate
+	}
+	rep.ID = r.s.reports.LastID() + 1
+	err := r.s.write(
+		record{Kind: kindReport, Op: opPut, ID: rep.ID, Report: &rep},
+		record{Kind: kindReport, Op: opSeq, ID: rep.ID},
+	)
+	if err != nil {
+		return 0, err
+	}
+	r.s.reports.Restore(rep)
+	return rep.ID, nil
+}
+
+func (r *ReportRepository) GetReport(ctx context.Context, id int64) (*reports.Report, error) {
+	return r.s.reports.GetReport(ctx, id)
+}
+
+func (r *ReportRepository) FindReports(ctx context.Context, f reports.Filter, afterID int64, limit int) ([]reports.Report, error) {
+	return r.s.reports.FindReports(ctx, f, afterID, limit)
+}
+
+func (r *ReportRepository) UpdateReport(ctx context.Context, id int64, rep reports.Report) error {
+	r.s.mx.Lock()
+	defer r.s.mx.Unlock()
+
+	old, err := r.s.reports.GetReport(ctx, id)
+	if err != nil {
+		return err
+	}
+	rep.ID, rep.ReporterID, rep.Target, rep.TargetID = id, old.ReporterID, old.Target, old.TargetID
+	if err := r.s.write(record{Kind: kindReport, Op: opPut, ID: id, Report: &rep}); err != nil {
+		return err
+	}
+	r.s.reports.Restore(rep)
+	return nil
+}
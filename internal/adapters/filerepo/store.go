@@ -0,0 +1,353 @@
+package filerepo
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"homework9/internal/adapters/adrepo"
+	"homework9/internal/adapters/categoryrepo"
+	"homework9/internal/adapters/favoriterepo"
+	"homework9/internal/adapters/reportrepo"
+	"homework9/internal/adapters/userrepo"
+	"homework9/internal/ads"
+	"homework9/internal/categories"
+	"homework9/internal/favorites"
+	"homework9/internal/reports"
+	"homework9/internal/users"
+)
+
+var ErrCorrupted = errors.New("write-ahead log is corrupted")
+
+const (
+	kindAd       = "ad"
+	kindUser     = "user"
+	kindCategory = "category"
+	kindReport   = "report"
+	kindFavorite = "favorite"
+
+	opPut    = "put"
+	opDelete = "delete"
+	opSeq    = "seq"
+)
+
+// record - одно изменение в журнале
+type record struct {
+	Kind     string               `json:"kind"`
+	Op       string               `json:"op"`
+	ID       int64                `json:"id"`
+	Ad       *ads.Ad              `json:"ad,omitempty"`
+	User     *users.User          `json:"user,omitempty"`
+	Category *categories.Category `json:"category,omitempty"`
+	Report   *reports.Report      `json:"report,omitempty"`
+	// Favorite - запись избранного, ID у неё - ID объявления
+	Favorite *favorites.Favorite `json:"favorite,omitempty"`
+}
+
+// entry - одна строка журнала. Записи внутри строки применяются целиком или не применяются вовсе
+type entry struct {
+	Records []record `json:"records"`
+}
+
+// Store хранит объявления, пользователей, категории, жалобы и избранное в памяти и дописывает каждое изменение в журнал на диске.
+// При открытии журнал проигрывается заново, а фоновая компакция периодически сворачивает его в снимок.
+type Store struct {
+	mx    sync.Mutex
+	path  string
+	file  *os.File
+	dirty int
+
+	ads        *adrepo.RepositoryMap
+	users      *userrepo.RepositoryMap
+	categories *categoryrepo.RepositoryMap
+	reports    *reportrepo.RepositoryMap
+	favorites  *favoriterepo.RepositoryMap
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+// Open открывает (или создаёт) журнал по пути path и восстанавливает из него состояние.
+// Если compactInterval > 0, журнал компактируется в фоне с этим интервалом.
+func Open(path string, compactInterval time.Duration) (*Store, error) {
+	s := &Store{
+		path:       path,
+		ads:        adrepo.New(),
+		users:      userrepo.New(),
+		categories: categoryrepo.New(),
+		reports:    reportrepo.New(),
+		favorites:  favoriterepo.New(),
+		stop:       make(chan struct{}),
+		done:       make(chan struct{}),
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, err
+	}
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.replay(f); err != nil {
+		f.Close()
+		return nil, err
+	}
+	s.file = f
+
+	if compactInterval > 0 {
+		go s.compactLoop(compactInterval)
+	} else {
+		close(s.done)
+	}
+	return s, nil
+}
+
+func (s *Store) Ads() *AdRepository {
+	return &AdRepository{s: s}
+}
+
+func (s *Store) Users() *UserRepository {
+	return &UserRepository{s: s}
+}
+
+func (s *Store) Categories() *CategoryRepository {
+	return &CategoryRepository{s: s}
+}
+
+func (s *Store) Reports() *ReportRepository {
+	return &ReportRepository{s: s}
+}
+
+func (s *Store) Favorites() *FavoriteRepository {
+	return &FavoriteRepository{s: s}
+}
+
+// replay проигрывает журнал. Недописанная последняя строка (например, после падения процесса) отрезается
+func (s *Store) replay(f *os.File) error {
+	r := bufio.NewReader(f)
+	var offset int64
+	for {
+		line, err := r.ReadBytes('\n')
+		if err == io.EOF {
+			if len(bytes.TrimSpace(line)) > 0 {
+				// хвост без перевода строки - запись не успела завершиться
+				if err := f.Truncate(offset); err != nil {
+					return err
+				}
+			}
+			break
+		} else if err != nil {
+			return err
+		}
+
+		var e entry
+		if err := json.Unmarshal(line, &e); err != nil {
+			return fmt.Errorf("%w: offset %d: %v", ErrCorrupted, offset, err)
+		}
+		s.apply(e)
+		s.dirty += len(e.Records)
+		offset += int64(len(line))
+	}
+	_, err := f.Seek(offset, io.SeekStart)
+	return err
+}
+
+func (s *Store) apply(e entry) {
+	for _, rec := range e.Records {
+		switch {
+		case rec.Kind == kindAd && rec.Op == opPut && rec.Ad != nil:
+			s.ads.Restore(*rec.Ad)
+		case rec.Kind == kindAd && rec.Op == opDelete:
+			s.ads.DeleteAd(context.Background(), rec.ID)
+		case rec.Kind == kindAd && rec.Op == opSeq:
+			s.ads.SetLastID(rec.ID)
+		case rec.Kind == kindUser && rec.Op == opPut && rec.User != nil:
+			s.users.Restore(*rec.User)
+		case rec.Kind == kindUser && rec.Op == opDelete:
+			s.users.DeleteUser(context.Background(), rec.ID)
+		case rec.Kind == kindUser && rec.Op == opSeq:
+			s.users.SetLastID(rec.ID)
+		case rec.Kind == kindCategory && rec.Op == opPut && rec.Category != nil:
+			s.categories.Restore(*rec.Category)
+		case rec.Kind == kindCategory && rec.Op == opDelete:
+			s.categories.Remove(rec.ID)
+		case rec.Kind == kindCategory && rec.Op == opSeq:
+			s.categories.SetLastID(rec.ID)
+		case rec.Kind == kindReport && rec.Op == opPut && rec.Report != nil:
+			s.reports.Restore(*rec.Report)
+		case rec.Kind == kindReport && rec.Op == opSeq:
+			s.reports.SetLastID(rec.ID)
+		case rec.Kind == kindFavorite && rec.Op == opPut && rec.Favorite != nil:
+			s.favorites.Restore(*rec.Favorite)
+		case rec.Kind == kindFavorite && rec.Op == opDelete && rec.Favorite != nil:
+			s.favorites.Remove(rec.Favorite.UserID, rec.Favorite.AdID)
+		}
+	}
+}
+
+// write дописывает строку в журнал и сбрасывает её на диск. Вызывается под s.mx
+func (s *Store) write(records ...record) error {
+	data, err := json.Marshal(entry{Records: records})
+	if err != nil {
+		return err
+	}
+	data = append(data, '\n')
+	// конец файла, а не текущее смещение: после компакции журнал открыт с O_APPEND и смещение в нём 0
+	pos, err := s.file.Seek(0, io.SeekEnd)
+	if err != nil {
+		return err
+	}
+	if _, err := s.file.Write(data); err != nil {
+		// не оставляем в журнале оборванную строку, иначе следующие записи окажутся за ней
+		_ = s.file.Truncate(pos)
+		return err
+	}
+	if err := s.file.Sync(); err != nil {
+		// изменение в памяти откатится, поэтому и из журнала его убираем, чтобы оно не появилось после перезапуска
+		_ = s.file.Truncate(pos)
+		return err
+	}
+	s.dirty += len(records)
+	return nil
+}
+
+// Compact заменяет журнал снимком текущего состояния
+func (s *Store) Compact() error {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+	return s.compact()
+}
+
+func (s *Store) compact() error {
+	allAds, err := s.ads.GetAllAds(context.Background())
+	if err != nil {
+		return err
+	}
+	allUsers, err := s.users.GetAllUsers(context.Background())
+	if err != nil {
+		return err
+	}
+
+	allCategories, err := s.categories.ListCategories(context.Background())
+	if err != nil {
+		return err
+	}
+
+	allReports, err := s.reports.FindReports(context.Background(), reports.Filter{}, 0, 0)
+	if err != nil {
+		return err
+	}
+
+	allFavorites := s.favorites.All()
+
+	records := make([]record, 0, len(allAds)+len(allUsers)+len(allCategories)+len(allReports)+len(allFavorites)+4)
+	records = append(records,
+		record{Kind: kindAd, Op: opSeq, ID: s.ads.LastID()},
+		record{Kind: kindUser, Op: opSeq, ID: s.users.LastID()},
+		record{Kind: kindCategory, Op: opSeq, ID: s.categories.LastID()},
+		record{Kind: kindReport, Op: opSeq, ID: s.reports.LastID()},
+	)
+	for i := range allAds {
+		records = append(records, record{Kind: kindAd, Op: opPut, ID: allAds[i].ID, Ad: &allAds[i]})
+	}
+	for i := range allUsers {
+		records = append(records, record{Kind: kindUser, Op: opPut, ID: allUsers[i].ID, User: &allUsers[i]})
+	}
+	for i := range allCategories {
+		records = append(records, record{Kind: kindCategory, Op: opPut, ID: allCategories[i].ID, Category: &allCategories[i]})
+	}
+	for i := range allReports {
+		records = append(records, record{Kind: kindReport, Op: opPut, ID: allReports[i].ID, Report: &allReports[i]})
+	}
+	for i := range allFavorites {
+		records = append(records, record{Kind: kindFavorite, Op: opPut, ID: allFavorites[i].AdID, Favorite: &allFavorites[i]})
+	}
+	data, err := json.Marshal(entry{Records: records})
+	if err != nil {
+		return err
+	}
+	data = append(data, '\n')
+
+	tmpPath := s.path + ".tmp"
+	tmp, err := os.OpenFile(tmpPath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o644)
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmpPath, s.path); err != nil {
+		return err
+	}
+	syncDir(filepath.Dir(s.path))
+
+	f, err := os.OpenFile(s.path, os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return err
+	}
+	s.file.Close()
+	s.file = f
+	s.dirty = 0
+	return nil
+}
+
+func (s *Store) compactLoop(interval time.Duration) {
+	defer close(s.done)
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-s.stop:
+			return
+		case <-t.C:
+			s.mx.Lock()
+			if s.dirty > 0 {
+				if err := s.compact(); err != nil {
+					// журнал остался прежним, попробуем в следующий раз
+					log.Printf("filerepo: compaction failed: %v", err)
+				}
+			}
+			s.mx.Unlock()
+		}
+	}
+}
+
+// Close останавливает фоновую компакцию и закрывает журнал
+func (s *Store) Close() error {
+	select {
+	case <-s.stop:
+	default:
+		close(s.stop)
+	}
+	<-s.done
+
+	s.mx.Lock()
+	defer s.mx.Unlock()
+	return s.file.Close()
+}
+
+func syncDir(dir string) {
+	d, err := os.Open(dir)
+	if err != nil {
+		return
+	}
+	defer d.Close()
+	_ = d.Sync()
+}
@@ -0,0 +1,70 @@
+package filerepo
+
+import (
+	"context"
+
+	"homework9/internal/adapters/adrepo"
+	"homework9/internal/adapters/userrepo"
+	"homework9/internal/ads"
+	"homework9/internal/users"
+)
+
+// Do выполняет fn как одну транзакцию. Все изменения попадают в журнал одной строкой,
+// поэтому при восстановлении транзакция применяется либо целиком, либо никак.
+func (s *Store) Do(ctx context.Context, fn func(adRepo ads.AdRepository, userRepo users.UserRepository) error) error {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+
+	adTx := s.ads.Begin()
+	userTx := s.users.Begin()
+	rollback := func() {
+		userTx.Rollback()
+		adTx.Rollback()
+	}
+
+	adsLastID, usersLastID := adTx.LastID(), userTx.LastID()
+	if err := fn(adTx, userTx); err != nil {
+		rollback()
+		return err
+	}
+
+	records := changes(ctx, adTx, userTx)
+	if adTx.LastID() != adsLastID {
+		records = append(records, record{Kind: kindAd, Op: opSeq, ID: adTx.LastID()})
+	}
+	if userTx.LastID() != usersLastID {
+		records = append(records, record{Kind: kindUser, Op: opSeq, ID: userTx.LastID()})
+	}
+	if len(records) > 0 {
+		if err := s.write(records...); err != nil {
+			rollback()
+			return err
+		}
+	}
+
+	userTx.Commit()
+	adTx.Commit()
+	return nil
+}
+
+// changes превращает изменённые транзакцией записи в записи журнала с их итоговым состоянием
+func changes(ctx context.Context, adTx *adrepo.Tx, userTx *userrepo.Tx) []record {
+	var records []record
+	for _, id := range adTx.Changed() {
+		ad, err := adTx.GetAdById(ctx, id)
+		if err != nil {
+			records = append(records, record{Kind: kindAd, Op: opDelete, ID: id})
+			continue
+		}
+		records = append(records, record{Kind: kindAd, Op: opPut, ID: id, Ad: ad})
+	}
+	for _, id := range userTx.Changed() {
+		u, err := userTx.GetUserByID(ctx, id)
+		if err != nil {
+			records = append(records, record{Kind: kindUser, Op: opDelete, ID: id})
+			continue
+		}
+		records = append(records, record{Kind: kindUser, Op: opPut, ID: id, User: u})
+	}
+	return records
+}
@@ -0,0 +1,132 @@
+// Package jwtauth выпускает и проверяет access-токены в формате JWT (HS256).
+// Ключей может быть несколько: новые токены подписываются текущим ключом,
+// а проверка принимает подпись любым активным ключом, который выбирается по заголовку kid.
+package jwtauth
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+var ErrInvalidToken = errors.New("invalid token")
+var ErrInvalidKey = errors.New("invalid signing key")
+
+// MinSecretLen - минимальная длина секрета. Для HS256 секрет короче размера хеша ослабляет подпись
+const MinSecretLen = 32
+
+const issuer = "ads"
+
+type Key struct {
+	ID     string
+	Secret []byte
+}
+
+// Keyring - набор активных ключей подписи. Первый ключ - текущий, им подписываются новые токены
+type Keyring struct {
+	mu   sync.RWMutex
+	keys []Key
+}
+
+// New создаёт набор ключей. Первый ключ становится текущим
+func New(keys ...Key) (*Keyring, error) {
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("%w: at least one key is required", ErrInvalidKey)
+	}
+	k := &Keyring{}
+	for i := len(keys) - 1; i >= 0; i-- {
+		if err := k.Rotate(keys[i]); err != nil {
+			return nil, err
+		}
+	}
+	return k, nil
+}
+
+// Rotate делает key текущим ключом подписи. Прежние ключи остаются активными,
+// поэтому выданные ими токены действуют до истечения
+func (k *Keyring) Rotate(key Key) error {
+	if key.ID == "" {
+		return fmt.Errorf("%w: empty key id", ErrInvalidKey)
+	}
+	if len(key.Secret) < MinSecretLen {
+		return fmt.Errorf("%w: secret of key %q is shorter than %d bytes", ErrInvalidKey, key.ID, MinSecretLen)
+	}
+
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	for _, existing := range k.keys {
+		if existing.ID == key.ID {
+			return fmt.Errorf("%w: duplicate key id %q", ErrInvalidKey, key.ID)
+		}
+	}
+	k.keys = append([]Key{key}, k.keys...)
+	return nil
+}
+
+// Retire выводит ключ из оборота: подписанные им токены перестают приниматься.
+// Текущий ключ вывести нельзя - сначала нужно сделать текущим другой через Rotate
+func (k *Keyring) Retire(id string) error {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	if len(k.keys) > 0 && k.keys[0].ID == id {
+		return fmt.Errorf("%w: key %q is used for signing", ErrInvalidKey, id)
+	}
+	for i, key := range k.keys {
+		if key.ID == id {
+			k.keys = append(k.keys[:i], k.keys[i+1:]...)
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: unknown key id %q", ErrInvalidKey, id)
+}
+
+// Issue подписывает текущим ключом токен пользователя userID, действующий до expires
+func (k *Keyring) Issue(userID int64, expires time.Time) (string, error) {
+	k.mu.RLock()
+	key := k.keys[0]
+	k.mu.RUnlock()
+
+	claims := jwt.RegisteredClaims{
+		Issuer:    issuer,
+		Subject:   strconv.FormatInt(userID, 10),
+		IssuedAt:  jwt.NewNumericDate(time.Now()),
+		ExpiresAt: jwt.NewNumericDate(expires),
+	}
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	token.Header["kid"] = key.ID
+	return token.SignedString(key.Secret)
+}
+
+// Verify проверяет подпись и срок действия токена и возвращает ID пользователя
+func (k *Keyring) Verify(token string) (int64, error) {
+	var claims jwt.RegisteredClaims
+	_, err := jwt.ParseWithClaims(token, &claims, k.secret,
+		jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}),
+		jwt.WithIssuer(issuer),
+		jwt.WithExpirationRequired())
+	if err != nil {
+		return 0, fmt.Errorf("%w: %v", ErrInvalidToken, err)
+	}
+	userID, err := strconv.ParseInt(claims.Subject, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("%w: bad subject %q", ErrInvalidToken, claims.Subject)
+	}
+	return userID, nil
+}
+
+// secret выбирает ключ проверки по kid из заголовка токена
+func (k *Keyring) secret(token *jwt.Token) (any, error) {
+	id, _ := token.Header["kid"].(string)
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	for _, key := range k.keys {
+		if key.ID == id {
+			return key.Secret, nil
+		}
+	}
+	return nil, fmt.Errorf("unknown key id %q", id)
+}
@@ -0,0 +1,22 @@
+// Package lognotifier пишет уведомления в журнал сервера. Годится, пока у сервиса нет почты или push-уведомлений
+package lognotifier
+
+import (
+	"context"
+	"log"
+
+	"homework9/internal/notifications"
+)
+
+type Notifier struct {
+	log *log.Logger
+}
+
+func New(l *log.Logger) *Notifier {
+	return &Notifier{log: l}
+}
+
+func (n *Notifier) Notify(ctx context.Context, msg notifications.Notification) error {
+	n.log.Printf("notify user %d: %s, ad %d: %s", msg.UserID, msg.Kind, msg.AdID, msg.Text)
+	return nil
+}
@@ -0,0 +1,34 @@
+package memtx
+
+import (
+	"context"
+
+	"homework9/internal/adapters/adrepo"
+	"homework9/internal/adapters/userrepo"
+	"homework9/internal/ads"
+	"homework9/internal/users"
+)
+
+// UnitOfWork объединяет транзакции RepositoryMap объявлений и пользователей.
+// Блокировки всегда берутся в одном порядке (объявления, затем пользователи), чтобы не было взаимоблокировок.
+type UnitOfWork struct {
+	ads   *adrepo.RepositoryMap
+	users *userrepo.RepositoryMap
+}
+
+func New(adRepo *adrepo.RepositoryMap, userRepo *userrepo.RepositoryMap) *UnitOfWork {
+	return &UnitOfWork{ads: adRepo, users: userRepo}
+}
+
+func (u *UnitOfWork) Do(ctx context.Context, fn func(adRepo ads.AdRepository, userRepo users.UserRepository) error) error {
+	adTx := u.ads.Begin()
+	userTx := u.users.Begin()
+	if err := fn(adTx, userTx); err != nil {
+		userTx.Rollback()
+		adTx.Rollback()
+		return err
+	}
+	userTx.Commit()
+	adTx.Commit()
+	return nil
+}
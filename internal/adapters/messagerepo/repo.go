@@ -0,0 +1,177 @@
+package messagerepo
+
+import (
+	"context"
+	"homework9/internal/messages"
+	"sort"
+	"sync"
+)
+
+// threadKey - объявление и покупатель, по ним отсекаются повторные переписки
+type threadKey struct {
+	adID    int64
+	buyerID int64
+}
+
+// blockKey - кто кого заблокировал
+type blockKey struct {
+	userID    int64
+	blockedID int64
+}
+
+type RepositoryMap struct {
+	threads map[int64]messages.Thread
+	byKey   map[threadKey]int64
+	// messages - сообщения переписок по возрастанию ID
+	messages      map[int64][]messages.Message
+	blocks        map[blockKey]messages.Block
+	lastThreadId  int64
+	lastMessageId int64
+	mx            *sync.RWMutex
+}
+
+func New() *RepositoryMap {
+	return &RepositoryMap{
+		threads:  make(map[int64]messages.Thread),
+		byKey:    make(map[threadKey]int64),
+		messages: make(map[int64][]messages.Message),
+		blocks:   make(map[blockKey]messages.Block),
+		mx:       &sync.RWMutex{},
+	}
+}
+
+func (r *RepositoryMap) CreateThread(ctx context.Context, t messages.Thread) (int64, error) {
+	r.mx.Lock()
+	defer r.mx.Unlock()
+	k := threadKey{adID: t.AdID, buyerID: t.BuyerID}
+	if _, ok := r.byKey[k]; ok {
+		return 0, messages.ErrDuplicate
+	}
+	r.lastThreadId++
+	t.ID = r.lastThreadId
+	r.threads[t.ID] = t
+	r.byKey[k] = t.ID
+	return t.ID, nil
+}
+
+func (r *RepositoryMap) GetThread(ctx context.Context, id int64) (*messages.Thread, error) {
+	r.mx.RLock()
+	defer r.mx.RUnlock()
+	t, ok := r.threads[id]
+	if !ok {
+		return nil, messages.ErrNotFound
+	}
+	return &t, nil
+}
+
+func (r *RepositoryMap) FindThread(ctx context.Context, adID int64, buyerID int64) (*messages.Thread, error) {
+	r.mx.RLock()
+	defer r.mx.RUnlock()
+	id, ok := r.byKey[threadKey{adID: adID, buyerID: buyerID}]
+	if !ok {
+		return nil, messages.ErrNotFound
+	}
+	t := r.threads[id]
+	return &t, nil
+}
+
+func (r *RepositoryMap) ListThreads(ctx context.Context, userID int64, adID *int64, after *messages.Thread, limit int) ([]messages.Thread, error) {
+	r.mx.RLock()
+	defer r.mx.RUnlock()
+	res := make([]messages.Thread, 0)
+	for _, t := range r.threads {
+		if !t.Participant(userID) || adID != nil && t.AdID != *adID {
+			continue
+		}
+		if after == nil || after.Before(t) {
+			res = append(res, t)
+		}
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i].Before(res[j]) })
+	if limit > 0 && len(res) > limit {
+		res = res[:limit]
+	}
+	return res, nil
+}
+
+func (r *RepositoryMap) AddMessage(ctx context.Context, m messages.Message) (int64, error) {
+	r.mx.Lock()
+	defer r.mx.Unlock()
+	t, ok := r.threads[m.ThreadID]
+	if !ok {
+		return 0, messages.ErrNotFound
+	}
+	r.lastMessageId++
+	m.ID = r.lastMessageId
+	r.messages[t.ID] = append(r.messages[t.ID], m)
+
+	t.LastMessageID, t.LastMessageAt = m.ID, m.Sent
+	if m.SenderID == t.SellerID {
+		t.SellerReadID = m.ID
+	} else {
+		t.BuyerReadID = m.ID
+	}
+	r.threads[t.ID] = t
+	return m.ID, nil
+}
+
+func (r *RepositoryMap) ListMessages(ctx context.Context, threadID int64, afterID int64, limit int) ([]messages.Message, error) {
+	r.mx.RLock()
+	defer r.mx.RUnlock()
+	list := r.messages[threadID]
+	i := sort.Search(len(list), func(i int) bool { return list[i].ID > afterID })
+	list = list[i:]
+	if limit > 0 && len(list) > limit {
+		list = list[:limit]
+	}
+	return append([]messages.Message(nil), list...), nil
+}
+
+func (r *RepositoryMap) MarkRead(ctx context.Context, threadID int64, userID int64, messageID int64) error {
+	r.mx.Lock()
+	defer r.mx.Unlock()
+	t, ok := r.threads[threadID]
+	if !ok || !t.Participant(userID) {
+		return messages.ErrNotFound
+	}
+	if messageID > t.LastMessageID {
+		messageID = t.LastMessageID
+	}
+	if userID == t.SellerID && messageID > t.SellerReadID {
+		t.SellerReadID = messageID
+	}
+	if userID == t.BuyerID && messageID > t.BuyerReadID {
+		t.BuyerReadID = messageID
+	}
+	r.threads[threadID] = t
+	return nil
+}
+
+func (r *RepositoryMap) Block(ctx context.Context, b messages.Block) error {
+	r.mx.Lock()
+	defer r.mx.Unlock()
+	k := blockKey{userID: b.UserID, blockedID: b.BlockedID}
+	if _, ok := r.blocks[k]; ok {
+		return messages.ErrDuplicate
+	}
+	r.blocks[k] = b
+	return nil
+}
+
+func (r *RepositoryMap) Unblock(ctx context.Context, userID int64, blockedID int64) error {
+	r.mx.Lock()
+	defer r.mx.Unlock()
+	k := blockKey{userID: userID, blockedID: blockedID}
+	if _, ok := r.blocks[k]; !ok {
+		return messages.ErrNotFound
+	}
+	delete(r.blocks, k)
+	return nil
+}
+
+func (r *RepositoryMap) Blocked(ctx context.Context, userID int64, otherID int64) (bool, error) {
+	r.mx.RLock()
+	defer r.mx.RUnlock()
+	_, ok := r.blocks[blockKey{userID: userID, blockedID: otherID}]
+	return ok, nil
+}
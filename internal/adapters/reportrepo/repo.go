@@ -0,0 +1,117 @@
+package reportrepo
+
+import (
+	"context"
+	"homework9/internal/reports"
+	"sort"
+	"sync"
+)
+
+// key - цель жалобы конкретного пользователя, по нему отсекаются повторные жалобы
+type key struct {
+	reporterID int64
+	target     reports.Target
+	targetID   int64
+}
+
+type RepositoryMap struct {
+	repo   map[int64]reports.Report
+	filed  map[key]int64
+	lastId int64
+	mx     *sync.RWMutex
+}
+
+func New() *RepositoryMap {
+	return &RepositoryMap{repo: make(map[int64]reports.Report), filed: make(map[key]int64), mx: &sync.RWMutex{}}
+}
+
+func keyOf(r reports.Report) key {
+	return key{reporterID: r.ReporterID, target: r.Target, targetID: r.TargetID}
+}
+
+func (r *RepositoryMap) AddReport(ctx context.Context, rep reports.Report) (int64, error) {
+	r.mx.Lock()
+	defer r.mx.Unlock()
+	if _, ok := r.filed[keyOf(rep)]; ok {
+		return 0, reports.ErrDuplicate
+	}
+	r.lastId++
+	rep.ID = r.lastId
+	r.repo[rep.ID] = rep
+	r.filed[keyOf(rep)] = rep.ID
+	return rep.ID, nil
+}
+
+func (r *RepositoryMap) GetReport(ctx context.Context, id int64) (*reports.Report, error) {
+	r.mx.RLock()
+	defer r.mx.RUnlock()
+	rep, ok := r.repo[id]
+	if !ok {
+		return nil, reports.ErrNotFound
+	}
+	return &rep, nil
+}
+
+func (r *RepositoryMap) FindReports(ctx context.Context, f reports.Filter, afterID int64, limit int) ([]reports.Report, error) {
+	r.mx.RLock()
+	defer r.mx.RUnlock()
+	res := make([]reports.Report, 0)
+	for _, rep := range r.repo {
+		if rep.ID > afterID && f.Match(rep) {
+			res = append(res, rep)
+		}
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i].ID < res[j].ID })
+	if limit > 0 && len(res) > limit {
+		res = res[:limit]
+	}
+	return res, nil
+}
+
+// UpdateReport меняет жалобу целиком. Автор и цель жалобы не меняются
+func (r *RepositoryMap) UpdateReport(ctx context.Context, id int64, rep reports.Report) error {
+	r.mx.Lock()
+	defer r.mx.Unlock()
+	old, ok := r.repo[id]
+	if !ok {
+		return reports.ErrNotFound
+	}
+	rep.ID, rep.ReporterID, rep.Target, rep.TargetID = id, old.ReporterID, old.Target, old.TargetID
+	r.repo[id] = rep
+	return nil
+}
+
+// Restore кладёт жалобу под уже выданным ей ID (например, при восстановлении из журнала)
+func (r *RepositoryMap) Restore(rep reports.Report) {
+	r.mx.Lock()
+	defer r.mx.Unlock()
+	r.repo[rep.ID] = rep
+	r.filed[keyOf(rep)] = rep.ID
+	if rep.ID > r.lastId {
+		r.lastId = rep.ID
+	}
+}
+
+// Filed - жаловался ли уже автор rep на ту же цель
+func (r *RepositoryMap) Filed(rep reports.Report) bool {
+	r.mx.RLock()
+	defer r.mx.RUnlock()
+	_, ok := r.filed[keyOf(rep)]
+	return ok
+}
+
+// LastID возвращает последний выданный ID
+func (r *RepositoryMap) LastID() int64 {
+	r.mx.RLock()
+	defer r.mx.RUnlock()
+	return r.lastId
+}
+
+// SetLastID сдвигает счётчик ID вперёд, чтобы ID не выдавались повторно
+func (r *RepositoryMap) SetLastID(id int64) {
+	r.mx.Lock()
+	defer r.mx.Unlock()
+	if id > r.lastId {
+		r.lastId = id
+	}
+}
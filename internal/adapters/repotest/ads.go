@@ -0,0 +1,504 @@
+// Package repotest содержит общий набор проверок контракта репозиториев.
+// Любое хранилище должно вести себя так же, как adrepo.RepositoryMap и userrepo.RepositoryMap.
+package repotest
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"homework9/internal/ads"
+	"homework9/internal/geo"
+)
+
+// AdRepository прогоняет контракт ads.AdRepository. newRepo должен возвращать пустое хранилище
+func AdRepository(t *testing.T, newRepo func(t *testing.T) ads.AdRepository) {
+	ctx := context.Background()
+
+	t.Run("ID assignment", func(t *testing.T) {
+		r := newRepo(t)
+		for want := int64(0); want < 3; want++ {
+			id, err := r.AddAd(ctx, ads.Ad{ID: 100, Title: "hello", Text: "world"})
+			require.NoError(t, err)
+			assert.Equal(t, want, id)
+
+			got, err := r.GetAdById(ctx, id)
+			require.NoError(t, err)
+			assert.Equal(t, id, got.ID)
+		}
+	})
+
+	t.Run("round trip", func(t *testing.T) {
+		r := newRepo(t)
+		created := time.Date(2023, 4, 1, 12, 30, 0, 123456789, time.UTC)
+		ad := ads.Ad{Title: "Кот", Text: "продаётся", AuthorID: 7, CategoryID: 3, Price: ads.Price{Amount: 150000, Currency: "RUB", Negotiable: true}, Published: true, Created: created, Modified: created.Add(time.Hour),
+			Status: ads.StatusPublished, Review: ads.Review{ModeratorID: 2, Reason: "всё в порядке", At: created.Add(time.Minute)},
+			Flags:    []ads.Flag{{Check: "contacts", Field: "text", Reason: "contains a link"}},
+			Location: &ads.Location{Lat: 55.7558, Lon: 37.6173, City: "Москва"},
+			Images: []ads.Image{
+				{ID: "b", Key: "ads/0/b", ThumbKey: "ads/0/b_thumb", ContentType: "image/png", Size: 2048, Width: 640, Height: 480},
+				{ID: "a", Key: "ads/0/a", ThumbKey: "ads/0/a_thumb", ContentType: "image/jpeg", Size: 1024, Width: 100, Height: 200},
+			}}
+		id, err := r.AddAd(ctx, ad)
+		require.NoError(t, err)
+
+		got, err := r.GetAdById(ctx, id)
+		require.NoError(t, err)
+		assertSameAd(t, ad, *got)
+
+		got.Title = "changed"
+		got.Images[0].ID = "changed"
+		got.Location.City = "changed"
+		got.Flags[0].Reason = "changed"
+		again, err := r.GetAdById(ctx, id)
+		require.NoError(t, err)
+		assert.Equal(t, "Кот", again.Title, "returned ad must be a copy")
+		assert.Equal(t, "b", again.Images[0].ID, "returned images must be a copy")
+		assert.Equal(t, "Москва", again.Location.City, "returned location must be a copy")
+		assert.Equal(t, "contains a link", again.Flags[0].Reason, "returned flags must be a copy")
+	})
+
+	t.Run("update", func(t *testing.T) {
+		r := newRepo(t)
+		id, err := r.AddAd(ctx, ads.Ad{Title: "hello", Text: "world", AuthorID: 1})
+		require.NoError(t, err)
+
+		changed := ads.Ad{Title: "bye", Text: "world", AuthorID: 1, Published: true, Modified: time.Now(), Version: 1,
+			Images: []ads.Image{{ID: "a", Key: "ads/0/a", ThumbKey: "ads/0/a_thumb", ContentType: "image/gif", Size: 1}}}
+		require.NoError(t, r.UpdateById(ctx, id, changed))
+
+		got, err := r.GetAdById(ctx, id)
+		require.NoError(t, err)
+		assert.Equal(t, id, got.ID)
+		assertSameAd(t, changed, *got)
+	})
+
+	t.Run("versions", func(t *testing.T) {
+		r := newRepo(t)
+		id, err := r.AddAd(ctx, ads.Ad{Title: "hello", Version: 42})
+		require.NoError(t, err)
+
+		got, err := r.GetAdById(ctx, id)
+		require.NoError(t, err)
+		assert.Equal(t, int64(1), got.Version)
+
+		got.Title = "first"
+		require.NoError(t, r.UpdateById(ctx, id, *got))
+		got, err = r.GetAdById(ctx, id)
+		require.NoError(t, err)
+		assert.Equal(t, int64(2), got.Version)
+
+		// второй писатель прочитал версию 1 и опоздал
+		stale := ads.Ad{Title: "second", Version: 1}
+		assert.ErrorIs(t, r.UpdateById(ctx, id, stale), ads.ErrVersionConflict)
+		got, err = r.GetAdById(ctx, id)
+		require.NoError(t, err)
+		assert.Equal(t, "first", got.Title)
+		assert.Equal(t, int64(2), got.Version)
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		r := newRepo(t)
+		_, err := r.GetAdById(ctx, 42)
+		assert.ErrorIs(t, err, ads.ErrNotFound)
+		assert.ErrorIs(t, r.UpdateById(ctx, 42, ads.Ad{Title: "hello"}), ads.ErrNotFound)
+		assert.ErrorIs(t, r.DeleteAdById(ctx, 42), ads.ErrNotFound)
+		r.DeleteAd(ctx, 42)
+
+		_, err = r.GetAdById(ctx, 42)
+		assert.ErrorIs(t, err, ads.ErrNotFound, "update of a missing ad must not create it")
+	})
+
+	t.Run("published filtering", func(t *testing.T) {
+		r := newRepo(t)
+		var published []int64
+		for i := 0; i < 6; i++ {
+			id, err := r.AddAd(ctx, ads.Ad{Title: "hello", Text: "world", Published: i%2 == 0})
+			require.NoError(t, err)
+			if i%2 == 0 {
+				published = append(published, id)
+			}
+		}
+
+		list, err := r.ListPublishedAds(ctx, ads.Page{})
+		require.NoError(t, err)
+		assert.ElementsMatch(t, published, adIDs(list))
+		for _, ad := range list {
+			assert.True(t, ad.Published)
+		}
+
+		all, err := r.GetAllAds(ctx)
+		require.NoError(t, err)
+		assert.Len(t, all, 6)
+	})
+
+	t.Run("filter", func(t *testing.T) {
+		r := newRepo(t)
+		base := time.Date(2023, 4, 1, 0, 0, 0, 0, time.UTC)
+		fixtures := []ads.Ad{
+			{Title: "Продаю КОТА", Text: "рыжий", AuthorID: 1, CategoryID: 1, Price: ads.Price{Amount: 50000, Currency: "RUB"}, Published: true, Created: base, Modified: base},
+			{Title: "Котёл", Text: "газовый", AuthorID: 2, CategoryID: 2, Price: ads.Price{Amount: 50000, Currency: "USD"}, Published: false, Created: base.Add(time.Hour), Modified: base.Add(3 * time.Hour)},
+			{Title: "Собака", Text: "Ищет дом, любит котов", AuthorID: 3, CategoryID: 1, Price: ads.Price{Amount: 0, Currency: "RUB"}, Published: true, Created: base.Add(2 * time.Hour), Modified: base.Add(2 * time.Hour)},
+		}
+		for _, ad := range fixtures {
+			_, err := r.AddAd(ctx, ad)
+			require.NoError(t, err)
+		}
+
+		tests := []struct {
+			name   string
+			filter ads.Filter
+			want   []int64
+		}{
+			{"empty", ads.Filter{}, []int64{0, 1, 2}},
+			{"authors", ads.Filter{AuthorIDs: []int64{1, 3, 42}}, []int64{0, 2}},
+			{"published", ads.Filter{Published: ads.OnlyPublished}, []int64{0, 2}},
+			{"unpublished", ads.Filter{Published: ads.OnlyUnpublished}, []int64{1}},
+			{"visible to author", ads.Filter{VisibleTo: userID(2)}, []int64{0, 1, 2}},
+			{"visible to others", ads.Filter{VisibleTo: userID(1)}, []int64{0, 2}},
+			{"categories", ads.Filter{CategoryIDs: []int64{1, 5}}, []int64{0, 2}},
+			{"created from is inclusive", ads.Filter{CreatedFrom: base.Add(time.Hour)}, []int64{1, 2}},
+			{"created to is exclusive", ads.Filter{CreatedTo: base.Add(time.Hour)}, []int64{0}},
+			{"modified range", ads.Filter{ModifiedFrom: base.Add(time.Hour), ModifiedTo: base.Add(3 * time.Hour)}, []int64{2}},
+			{"title ignores case", ads.Filter{TitleContains: "кота"}, []int64{0}},
+			{"text", ads.Filter{TextContains: "КОТ"}, []int64{2}},
+			{"combined", ads.Filter{Published: ads.OnlyPublished, CreatedFrom: base.Add(time.Minute), TextContains: "дом"}, []int64{2}},
+			{"nothing", ads.Filter{AuthorIDs: []int64{1}, Published: ads.OnlyUnpublished}, []int64{}},
+			{"currency", ads.Filter{Currency: "RUB"}, []int64{0, 2}},
+			{"price bounds are inclusive", ads.Filter{PriceMin: price(0), PriceMax: price(50000)}, []int64{0, 1, 2}},
+			{"price in currency", ads.Filter{Currency: "RUB", PriceMin: price(1)}, []int64{0}},
+			{"free", ads.Filter{PriceMax: price(0)}, []int64{2}},
+		}
+		for _, tc := range tests {
+			t.Run(tc.name, func(t *testing.T) {
+				list, err := r.FindAds(ctx, tc.filter, ads.Page{})
+				require.NoError(t, err)
+				assert.Equal(t, tc.want, adIDs(list))
+				for _, ad := range list {
+					assert.True(t, tc.filter.Match(ad))
+				}
+			})
+		}
+	})
+
+	t.Run("sorting and pages", func(t *testing.T) {
+		r := newRepo(t)
+		base := time.Date(2023, 4, 1, 0, 0, 0, 0, time.UTC)
+		// одинаковые ключи сортировки у нескольких объявлений: порядок между ними задаёт ID
+		fixtures := []ads.Ad{
+			{Title: "b", Price: ads.Price{Amount: 300}, Published: true, Created: base.Add(time.Hour), Modified: base.Add(5 * time.Hour)},
+			{Title: "a", Price: ads.Price{Amount: 100}, Published: true, Created: base, Modified: base.Add(time.Hour)},
+			{Title: "b", Price: ads.Price{Amount: 200}, Published: false, Created: base.Add(time.Hour), Modified: base},
+			{Title: "Б", Price: ads.Price{Amount: 100}, Published: true, Created: base.Add(2 * time.Hour), Modified: base.Add(time.Hour)},
+			{Title: "a", Price: ads.Price{Amount: 300}, Published: true, Created: base.Add(time.Hour), Modified: base.Add(2 * time.Hour)},
+		}
+		for _, ad := range fixtures {
+			_, err := r.AddAd(ctx, ad)
+			require.NoError(t, err)
+		}
+
+		tests := []struct {
+			sort ads.Sort
+			want []int64
+		}{
+			{ads.Sort{}, []int64{0, 1, 3, 4}},
+			{ads.Sort{Desc: true}, []int64{4, 3, 1, 0}},
+			{ads.Sort{Field: ads.SortByCreated}, []int64{1, 0, 4, 3}},
+			{ads.Sort{Field: ads.SortByCreated, Desc: true}, []int64{3, 4, 0, 1}},
+			{ads.Sort{Field: ads.SortByModified}, []int64{1, 3, 4, 0}},
+			{ads.Sort{Field: ads.SortByModified, Desc: true}, []int64{0, 4, 3, 1}},
+			{ads.Sort{Field: ads.SortByTitle}, []int64{1, 4, 0, 3}},
+			{ads.Sort{Field: ads.SortByTitle, Desc: true}, []int64{3, 0, 4, 1}},
+			{ads.Sort{Field: ads.SortByPrice}, []int64{1, 3, 0, 4}},
+			{ads.Sort{Field: ads.SortByPrice, Desc: true}, []int64{4, 0, 3, 1}},
+		}
+		for _, tc := range tests {
+			list, err := r.ListPublishedAds(ctx, ads.Page{Sort: tc.sort})
+			require.NoError(t, err)
+			assert.Equal(t, tc.want, adIDs(list), "sort %+v", tc.sort)
+
+			// постраничный обход даёт ту же выдачу без пропусков и повторов
+			var paged []int64
+			page := ads.Page{Sort: tc.sort, Limit: 3}
+			for {
+				list, err := r.ListPublishedAds(ctx, page)
+				require.NoError(t, err)
+				paged = append(paged, adIDs(list)...)
+				if len(list) < page.Limit {
+					break
+				}
+				after := tc.sort.CursorOf(list[len(list)-1])
+				page.After = &after
+			}
+			assert.Equal(t, tc.want, paged, "paged sort %+v", tc.sort)
+		}
+
+		list, err := r.FindAds(ctx, ads.Filter{TitleContains: "b"}, ads.Page{Sort: ads.Sort{Field: ads.SortByModified}, Limit: 1})
+		require.NoError(t, err)
+		assert.Equal(t, []int64{2}, adIDs(list))
+	})
+
+	t.Run("schedule", func(t *testing.T) {
+		r := newRepo(t)
+		now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+		fixtures := []ads.Ad{
+			{Title: "due", PublishAt: now.Add(-time.Minute), ExpiresAt: now.Add(time.Hour)},
+			{Title: "later", PublishAt: now.Add(time.Minute)},
+			{Title: "expired", Published: true, ExpiresAt: now.Add(-time.Second)},
+			{Title: "forever", Published: true},
+			{Title: "exactly now", Published: true, PublishAt: now, ExpiresAt: now},
+		}
+		for _, ad := range fixtures {
+			_, err := r.AddAd(ctx, ad)
+			require.NoError(t, err)
+		}
+		got, err := r.GetAdById(ctx, 0)
+		require.NoError(t, err)
+		assert.True(t, fixtures[0].PublishAt.Equal(got.PublishAt))
+		assert.True(t, fixtures[0].ExpiresAt.Equal(got.ExpiresAt))
+		got, err = r.GetAdById(ctx, 3)
+		require.NoError(t, err)
+		assert.True(t, got.PublishAt.IsZero() && got.ExpiresAt.IsZero(), "unset deadlines stay zero")
+
+		tests := []struct {
+			name   string
+			filter ads.Filter
+			want   []int64
+		}{
+			{"publish due", ads.Filter{PublishBefore: now}, []int64{0}},
+			{"publish due later", ads.Filter{PublishBefore: now.Add(time.Hour)}, []int64{0, 1, 4}},
+			{"expired", ads.Filter{ExpiresBefore: now}, []int64{2}},
+			{"expired published", ads.Filter{Published: ads.OnlyPublished, ExpiresBefore: now.Add(2 * time.Hour)}, []int64{2, 4}},
+		}
+		for _, tc := range tests {
+			list, err := r.FindAds(ctx, tc.filter, ads.Page{})
+			require.NoError(t, err)
+			assert.Equal(t, tc.want, adIDs(list), tc.name)
+		}
+	})
+
+	t.Run("moderation", func(t *testing.T) {
+		r := newRepo(t)
+		fixtures := []ads.Ad{
+			{Title: "draft", Status: ads.StatusDraft},
+			{Title: "pending", Status: ads.StatusPending},
+			{Title: "legacy published", Published: true},
+			{Title: "legacy draft"},
+			{Title: "pending too", Status: ads.StatusPending},
+		}
+		for _, ad := range fixtures {
+			_, err := r.AddAd(ctx, ad)
+			require.NoError(t, err)
+		}
+		tests := []struct {
+			name   string
+			filter ads.Filter
+			want   []int64
+		}{
+			{"pending", ads.Filter{Status: ads.StatusPending}, []int64{1, 4}},
+			{"draft with legacy", ads.Filter{Status: ads.StatusDraft}, []int64{0, 3}},
+			{"published with legacy", ads.Filter{Status: ads.StatusPublished}, []int64{2}},
+			{"none", ads.Filter{Status: ads.StatusRejected}, []int64{}},
+		}
+		for _, tc := range tests {
+			list, err := r.FindAds(ctx, tc.filter, ads.Page{})
+			require.NoError(t, err)
+			assert.Equal(t, tc.want, adIDs(list), tc.name)
+		}
+
+		got, err := r.GetAdById(ctx, 1)
+		require.NoError(t, err)
+		got.Status = ads.StatusRejected
+		got.Review = ads.Review{ModeratorID: 9, Reason: "нет фото", At: time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)}
+		require.NoError(t, r.UpdateById(ctx, 1, *got))
+		again, err := r.GetAdById(ctx, 1)
+		require.NoError(t, err)
+		assert.Equal(t, ads.StatusRejected, again.Status)
+		assert.Equal(t, "нет фото", again.Review.Reason)
+		assert.True(t, got.Review.At.Equal(again.Review.At))
+	})
+
+	t.Run("places", func(t *testing.T) {
+		r := newRepo(t)
+		moscow := geo.Point{Lat: 55.7558, Lon: 37.6173}
+		fixtures := []*ads.Location{
+			{Lat: 55.7558, Lon: 37.6173, City: "Москва"},
+			{Lat: 55.8970, Lon: 37.4297, City: "Химки"},
+			{Lat: 59.9343, Lon: 30.3351, City: "Санкт-Петербург"},
+			nil,
+			{Lat: 54.1931, Lon: 37.6173, City: "Тула"},
+			{Lat: 64.7337, Lon: -177.5089, City: "Анадырь"},
+		}
+		for _, loc := range fixtures {
+			_, err := r.AddAd(ctx, ads.Ad{Title: "hello", Published: true, Location: loc})
+			require.NoError(t, err)
+		}
+		got, err := r.GetAdById(ctx, 2)
+		require.NoError(t, err)
+		assert.Equal(t, fixtures[2], got.Location)
+
+		tests := []struct {
+			name   string
+			filter ads.Filter
+			want   []int64
+		}{
+			{"near", ads.Filter{Near: &geo.Circle{Center: moscow, RadiusKm: 25}}, []int64{0, 1}},
+			{"wide radius", ads.Filter{Near: &geo.Circle{Center: moscow, RadiusKm: 200}}, []int64{0, 1, 4}},
+			{"box", ads.Filter{Box: &geo.Box{MinLat: 54, MinLon: 37, MaxLat: 56, MaxLon: 38}}, []int64{0, 1, 4}},
+			{"near and box", ads.Filter{Near: &geo.Circle{Center: moscow, RadiusKm: 200}, Box: &geo.Box{MinLat: 55, MinLon: 30, MaxLat: 60, MaxLon: 40}}, []int64{0, 1}},
+			{"disjoint", ads.Filter{Near: &geo.Circle{Center: moscow, RadiusKm: 25}, Box: &geo.Box{MinLat: 59, MinLon: 30, MaxLat: 60, MaxLon: 31}}, []int64{}},
+			{"across the date line", ads.Filter{Near: &geo.Circle{Center: geo.Point{Lat: 64.7, Lon: 177.5}, RadiusKm: 300}}, []int64{5}},
+			{"whole earth", ads.Filter{Near: &geo.Circle{Center: moscow, RadiusKm: geo.MaxRadiusKm}}, []int64{0, 1, 2, 4, 5}},
+		}
+		for _, tc := range tests {
+			list, err := r.FindAds(ctx, tc.filter, ads.Page{})
+			require.NoError(t, err)
+			assert.Equal(t, tc.want, adIDs(list), tc.name)
+		}
+
+		// объявления без места идут после остальных
+		for _, tc := range []struct {
+			sort ads.Sort
+			want []int64
+		}{
+			{ads.Sort{Field: ads.SortByDistance, Origin: &moscow}, []int64{0, 1, 4, 2, 5, 3}},
+			{ads.Sort{Field: ads.SortByDistance, Origin: &moscow, Desc: true}, []int64{3, 5, 2, 4, 1, 0}},
+		} {
+			var paged []int64
+			page := ads.Page{Sort: tc.sort, Limit: 4}
+			for {
+				list, err := r.FindAds(ctx, ads.Filter{}, page)
+				require.NoError(t, err)
+				paged = append(paged, adIDs(list)...)
+				if len(list) < page.Limit {
+					break
+				}
+				after := tc.sort.CursorOf(list[len(list)-1])
+				page.After = &after
+			}
+			assert.Equal(t, tc.want, paged, "paged sort %+v", tc.sort)
+		}
+
+		// перенос и удаление обновляют сетку
+		near := ads.Filter{Near: &geo.Circle{Center: moscow, RadiusKm: 25}}
+		got.Location = &ads.Location{Lat: 55.75, Lon: 37.62, City: "Москва"}
+		require.NoError(t, r.UpdateById(ctx, 2, *got))
+		require.NoError(t, r.DeleteAdById(ctx, 1))
+		list, err := r.FindAds(ctx, near, ads.Page{})
+		require.NoError(t, err)
+		assert.Equal(t, []int64{0, 2}, adIDs(list))
+		got, err = r.GetAdById(ctx, 2)
+		require.NoError(t, err)
+		got.Location = nil
+		require.NoError(t, r.UpdateById(ctx, 2, *got))
+		list, err = r.FindAds(ctx, near, ads.Page{})
+		require.NoError(t, err)
+		assert.Equal(t, []int64{0}, adIDs(list))
+	})
+
+	t.Run("empty lists", func(t *testing.T) {
+		r := newRepo(t)
+		list, err := r.ListPublishedAds(ctx, ads.Page{})
+		require.NoError(t, err)
+		assert.NotNil(t, list)
+		assert.Empty(t, list)
+
+		all, err := r.GetAllAds(ctx)
+		require.NoError(t, err)
+		assert.NotNil(t, all)
+		assert.Empty(t, all)
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		r := newRepo(t)
+		first, err := r.AddAd(ctx, ads.Ad{Title: "first", Published: true})
+		require.NoError(t, err)
+		second, err := r.AddAd(ctx, ads.Ad{Title: "second", Published: true})
+		require.NoError(t, err)
+
+		require.NoError(t, r.DeleteAdById(ctx, second))
+		_, err = r.GetAdById(ctx, second)
+		assert.ErrorIs(t, err, ads.ErrNotFound)
+		assert.ErrorIs(t, r.DeleteAdById(ctx, second), ads.ErrNotFound)
+
+		r.DeleteAd(ctx, first)
+		_, err = r.GetAdById(ctx, first)
+		assert.ErrorIs(t, err, ads.ErrNotFound)
+
+		list, err := r.ListPublishedAds(ctx, ads.Page{})
+		require.NoError(t, err)
+		assert.Empty(t, list)
+
+		// удалённые ID не выдаются повторно
+		third, err := r.AddAd(ctx, ads.Ad{Title: "third"})
+		require.NoError(t, err)
+		assert.Equal(t, second+1, third)
+	})
+
+	t.Run("concurrent writes", func(t *testing.T) {
+		r := newRepo(t)
+		const n = 50
+		ids := make([]int64, n)
+		var wg sync.WaitGroup
+		for i := 0; i < n; i++ {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				id, err := r.AddAd(ctx, ads.Ad{Title: "hello", Text: "world"})
+				assert.NoError(t, err)
+				ids[i] = id
+				assert.NoError(t, r.UpdateById(ctx, id, ads.Ad{Title: "hello", Text: "world", Published: true, Version: 1}))
+			}(i)
+		}
+		wg.Wait()
+
+		sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+		for i, id := range ids {
+			assert.Equal(t, int64(i), id)
+		}
+
+		list, err := r.ListPublishedAds(ctx, ads.Page{})
+		require.NoError(t, err)
+		assert.Len(t, list, n)
+	})
+}
+
+func assertSameAd(t *testing.T, want ads.Ad, got ads.Ad) {
+	t.Helper()
+	assert.Equal(t, want.Title, got.Title)
+	assert.Equal(t, want.Text, got.Text)
+	assert.Equal(t, want.AuthorID, got.AuthorID)
+	assert.Equal(t, want.CategoryID, got.CategoryID)
+	assert.Equal(t, want.Price, got.Price)
+	assert.Equal(t, want.Images, got.Images)
+	assert.Equal(t, want.Flags, got.Flags)
+	assert.Equal(t, want.Location, got.Location)
+	assert.Equal(t, want.Published, got.Published)
+	assert.Equal(t, want.EffectiveStatus(), got.EffectiveStatus())
+	assert.Equal(t, want.Review.ModeratorID, got.Review.ModeratorID)
+	assert.Equal(t, want.Review.Reason, got.Review.Reason)
+	assert.True(t, want.Review.At.Equal(got.Review.At), "reviewed: want %v, got %v", want.Review.At, got.Review.At)
+	assert.True(t, want.Created.Equal(got.Created), "created: want %v, got %v", want.Created, got.Created)
+	assert.True(t, want.Modified.Equal(got.Modified), "modified: want %v, got %v", want.Modified, got.Modified)
+}
+
+func adIDs(list []ads.Ad) []int64 {
+	res := make([]int64, 0, len(list))
+	for _, ad := range list {
+		res = append(res, ad.ID)
+	}
+	return res
+}
+
+func price(v int64) *int64 {
+	return &v
+}
+
+func userID(v int64) *int64 {
+	return &v
+}
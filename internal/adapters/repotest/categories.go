@@ -0,0 +1,73 @@
+package repotest
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"homework9/internal/categories"
+)
+
+// CategoryRepository прогоняет контракт categories.CategoryRepository. newRepo должен возвращать пустое хранилище
+func CategoryRepository(t *testing.T, newRepo func(t *testing.T) categories.CategoryRepository) {
+	ctx := context.Background()
+
+	t.Run("round trip", func(t *testing.T) {
+		r := newRepo(t)
+		root, err := r.AddCategory(ctx, categories.Category{ID: 100, Name: "Транспорт"})
+		require.NoError(t, err)
+		assert.Equal(t, int64(1), root, "IDs start at 1, 0 means no parent")
+		child, err := r.AddCategory(ctx, categories.Category{Name: "Велосипеды", ParentID: root})
+		require.NoError(t, err)
+		assert.Equal(t, int64(2), child)
+
+		got, err := r.GetCategory(ctx, child)
+		require.NoError(t, err)
+		assert.Equal(t, categories.Category{ID: child, Name: "Велосипеды", ParentID: root}, *got)
+
+		require.NoError(t, r.UpdateCategory(ctx, child, categories.Category{Name: "Самокаты"}))
+		got, err = r.GetCategory(ctx, child)
+		require.NoError(t, err)
+		assert.Equal(t, categories.Category{ID: child, Name: "Самокаты"}, *got)
+	})
+
+	t.Run("list", func(t *testing.T) {
+		r := newRepo(t)
+		list, err := r.ListCategories(ctx)
+		require.NoError(t, err)
+		assert.NotNil(t, list)
+		assert.Empty(t, list)
+
+		for _, name := range []string{"a", "b", "c"} {
+			_, err := r.AddCategory(ctx, categories.Category{Name: name})
+			require.NoError(t, err)
+		}
+		list, err = r.ListCategories(ctx)
+		require.NoError(t, err)
+		require.Len(t, list, 3)
+		for i, c := range list {
+			assert.Equal(t, int64(i+1), c.ID)
+		}
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		r := newRepo(t)
+		_, err := r.GetCategory(ctx, 42)
+		assert.ErrorIs(t, err, categories.ErrNotFound)
+		assert.ErrorIs(t, r.UpdateCategory(ctx, 42, categories.Category{Name: "a"}), categories.ErrNotFound)
+		assert.ErrorIs(t, r.DeleteCategory(ctx, 42), categories.ErrNotFound)
+
+		id, err := r.AddCategory(ctx, categories.Category{Name: "a"})
+		require.NoError(t, err)
+		require.NoError(t, r.DeleteCategory(ctx, id))
+		_, err = r.GetCategory(ctx, id)
+		assert.ErrorIs(t, err, categories.ErrNotFound)
+
+		// удалённые ID не выдаются повторно
+		next, err := r.AddCategory(ctx, categories.Category{Name: "b"})
+		require.NoError(t, err)
+		assert.Equal(t, id+1, next)
+	})
+}
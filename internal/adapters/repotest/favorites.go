@@ -0,0 +1,105 @@
+package repotest
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"homework9/internal/favorites"
+)
+
+// FavoriteRepository прогоняет контракт favorites.FavoriteRepository. newRepo должен возвращать пустое хранилище
+func FavoriteRepository(t *testing.T, newRepo func(t *testing.T) favorites.FavoriteRepository) {
+	ctx := context.Background()
+	added := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	t.Run("add and remove", func(t *testing.T) {
+		r := newRepo(t)
+		f := favorites.Favorite{UserID: 1, AdID: 5, Added: added}
+		require.NoError(t, r.AddFavorite(ctx, f))
+		assert.ErrorIs(t, r.AddFavorite(ctx, favorites.Favorite{UserID: 1, AdID: 5, Added: added.Add(time.Hour)}), favorites.ErrDuplicate)
+
+		got, err := r.GetFavorite(ctx, 1, 5)
+		require.NoError(t, err)
+		assert.Equal(t, f.UserID, got.UserID)
+		assert.Equal(t, f.AdID, got.AdID)
+		assert.True(t, added.Equal(got.Added), "the first add is kept: want %v, got %v", added, got.Added)
+		_, err = r.GetFavorite(ctx, 2, 5)
+		assert.ErrorIs(t, err, favorites.ErrNotFound)
+
+		require.NoError(t, r.RemoveFavorite(ctx, 1, 5))
+		assert.ErrorIs(t, r.RemoveFavorite(ctx, 1, 5), favorites.ErrNotFound)
+		_, err = r.GetFavorite(ctx, 1, 5)
+		assert.ErrorIs(t, err, favorites.ErrNotFound)
+		assert.NoError(t, r.AddFavorite(ctx, f), "removed favorite can be added again")
+	})
+
+	t.Run("list", func(t *testing.T) {
+		r := newRepo(t)
+		list, err := r.ListFavorites(ctx, 1, nil, 0)
+		require.NoError(t, err)
+		assert.NotNil(t, list)
+		assert.Empty(t, list)
+
+		for _, f := range []favorites.Favorite{
+			{UserID: 1, AdID: 1, Added: added},
+			{UserID: 1, AdID: 2, Added: added.Add(time.Minute)},
+			{UserID: 1, AdID: 3, Added: added},
+			{UserID: 2, AdID: 4, Added: added.Add(time.Hour)},
+			{UserID: 1, AdID: 5, Added: added.Add(2 * time.Minute)},
+		} {
+			require.NoError(t, r.AddFavorite(ctx, f))
+		}
+
+		adIDs := func(after *favorites.Favorite, limit int) []int64 {
+			t.Helper()
+			list, err := r.ListFavorites(ctx, 1, after, limit)
+			require.NoError(t, err)
+			res := make([]int64, 0, len(list))
+			for _, f := range list {
+				assert.Equal(t, int64(1), f.UserID)
+				res = append(res, f.AdID)
+			}
+			return res
+		}
+		assert.Equal(t, []int64{5, 2, 3, 1}, adIDs(nil, 0))
+		assert.Equal(t, []int64{5, 2}, adIDs(nil, 2))
+		assert.Equal(t, []int64{3, 1}, adIDs(&favorites.Favorite{AdID: 2, Added: added.Add(time.Minute)}, 0))
+		assert.Equal(t, []int64{1}, adIDs(&favorites.Favorite{AdID: 3, Added: added}, 5))
+	})
+
+	t.Run("count", func(t *testing.T) {
+		r := newRepo(t)
+		for _, f := range []favorites.Favorite{
+			{UserID: 1, AdID: 1}, {UserID: 2, AdID: 1}, {UserID: 3, AdID: 1},
+			{UserID: 1, AdID: 2}, {UserID: 3, AdID: 3},
+		} {
+			f.Added = added
+			require.NoError(t, r.AddFavorite(ctx, f))
+		}
+		counts, err := r.CountFavorites(ctx, []int64{1, 2, 4})
+		require.NoError(t, err)
+		assert.Equal(t, map[int64]int{1: 3, 2: 1}, counts)
+		counts, err = r.CountFavorites(ctx, nil)
+		require.NoError(t, err)
+		assert.Empty(t, counts)
+		users, err := r.FavoriteUsers(ctx, 1)
+		require.NoError(t, err)
+		assert.Equal(t, []int64{1, 2, 3}, users)
+		users, err = r.FavoriteUsers(ctx, 4)
+		require.NoError(t, err)
+		assert.Empty(t, users)
+
+		require.NoError(t, r.DeleteUserFavorites(ctx, 1))
+		require.NoError(t, r.DeleteUserFavorites(ctx, 42))
+		counts, err = r.CountFavorites(ctx, []int64{1, 2, 3})
+		require.NoError(t, err)
+		assert.Equal(t, map[int64]int{1: 2, 3: 1}, counts)
+		list, err := r.ListFavorites(ctx, 1, nil, 0)
+		require.NoError(t, err)
+		assert.Empty(t, list)
+	})
+}
@@ -0,0 +1,121 @@
+package repotest
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"homework9/internal/reports"
+)
+
+// ReportRepository прогоняет контракт reports.ReportRepository. newRepo должен возвращать пустое хранилище
+func ReportRepository(t *testing.T, newRepo func(t *testing.T) reports.ReportRepository) {
+	ctx := context.Background()
+	created := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	t.Run("round trip", func(t *testing.T) {
+		r := newRepo(t)
+		want := reports.Report{ID: 100, ReporterID: 3, Target: reports.TargetAd, TargetID: 0, Reason: reports.ReasonScam,
+			Comment: "просит предоплату", Status: reports.StatusOpen, Created: created}
+		id, err := r.AddReport(ctx, want)
+		require.NoError(t, err)
+		assert.Equal(t, int64(1), id, "IDs start at 1")
+		want.ID = id
+
+		got, err := r.GetReport(ctx, id)
+		require.NoError(t, err)
+		assertSameReport(t, want, *got)
+
+		closed := *got
+		closed.Status = reports.StatusResolved
+		closed.Resolution = reports.Resolution{ModeratorID: 7, Note: "объявление удалено", At: created.Add(time.Hour)}
+		// автор и цель не меняются
+		closed.ReporterID, closed.TargetID = 42, 42
+		require.NoError(t, r.UpdateReport(ctx, id, closed))
+		got, err = r.GetReport(ctx, id)
+		require.NoError(t, err)
+		closed.ReporterID, closed.TargetID = want.ReporterID, want.TargetID
+		assertSameReport(t, closed, *got)
+
+		_, err = r.GetReport(ctx, 42)
+		assert.ErrorIs(t, err, reports.ErrNotFound)
+		assert.ErrorIs(t, r.UpdateReport(ctx, 42, closed), reports.ErrNotFound)
+	})
+
+	t.Run("duplicates", func(t *testing.T) {
+		r := newRepo(t)
+		first := reports.Report{ReporterID: 1, Target: reports.TargetAd, TargetID: 5, Reason: reports.ReasonSpam, Status: reports.StatusOpen}
+		id, err := r.AddReport(ctx, first)
+		require.NoError(t, err)
+
+		again := first
+		again.Reason = reports.ReasonScam
+		_, err = r.AddReport(ctx, again)
+		assert.ErrorIs(t, err, reports.ErrDuplicate)
+
+		// закрытая жалоба тоже считается
+		first.Status = reports.StatusDismissed
+		require.NoError(t, r.UpdateReport(ctx, id, first))
+		_, err = r.AddReport(ctx, again)
+		assert.ErrorIs(t, err, reports.ErrDuplicate)
+
+		for _, other := range []reports.Report{
+			{ReporterID: 2, Target: reports.TargetAd, TargetID: 5},
+			{ReporterID: 1, Target: reports.TargetUser, TargetID: 5},
+			{ReporterID: 1, Target: reports.TargetAd, TargetID: 6},
+		} {
+			other.Reason, other.Status = reports.ReasonSpam, reports.StatusOpen
+			_, err := r.AddReport(ctx, other)
+			assert.NoError(t, err)
+		}
+	})
+
+	t.Run("find", func(t *testing.T) {
+		r := newRepo(t)
+		list, err := r.FindReports(ctx, reports.Filter{}, 0, 0)
+		require.NoError(t, err)
+		assert.NotNil(t, list)
+		assert.Empty(t, list)
+
+		for i, rep := range []reports.Report{
+			{Target: reports.TargetAd, TargetID: 1, Status: reports.StatusOpen},
+			{Target: reports.TargetUser, TargetID: 1, Status: reports.StatusOpen},
+			{Target: reports.TargetAd, TargetID: 2, Status: reports.StatusResolved},
+			{Target: reports.TargetAd, TargetID: 1, Status: reports.StatusDismissed},
+			{Target: reports.TargetAd, TargetID: 1, Status: reports.StatusOpen},
+		} {
+			rep.ReporterID, rep.Reason = int64(i), reports.ReasonSpam
+			_, err := r.AddReport(ctx, rep)
+			require.NoError(t, err)
+		}
+
+		ids := func(f reports.Filter, afterID int64, limit int) []int64 {
+			t.Helper()
+			list, err := r.FindReports(ctx, f, afterID, limit)
+			require.NoError(t, err)
+			res := make([]int64, 0, len(list))
+			for _, rep := range list {
+				res = append(res, rep.ID)
+			}
+			return res
+		}
+		assert.Equal(t, []int64{1, 2, 3, 4, 5}, ids(reports.Filter{}, 0, 0))
+		assert.Equal(t, []int64{1, 2, 5}, ids(reports.Filter{Status: reports.StatusOpen}, 0, 0))
+		assert.Equal(t, []int64{1, 4, 5}, ids(reports.Filter{Target: reports.TargetAd, TargetIDs: []int64{1}}, 0, 0))
+		assert.Equal(t, []int64{1, 5}, ids(reports.Filter{Status: reports.StatusOpen, Target: reports.TargetAd, TargetIDs: []int64{1, 2}}, 0, 0))
+		assert.Equal(t, []int64{3, 4}, ids(reports.Filter{}, 2, 2))
+		assert.Equal(t, []int64{}, ids(reports.Filter{}, 5, 0))
+	})
+}
+
+func assertSameReport(t *testing.T, want reports.Report, got reports.Report) {
+	t.Helper()
+	assert.True(t, want.Created.Equal(got.Created), "created: want %v, got %v", want.Created, got.Created)
+	assert.True(t, want.Resolution.At.Equal(got.Resolution.At), "resolved at: want %v, got %v", want.Resolution.At, got.Resolution.At)
+	want.Created, got.Created = time.Time{}, time.Time{}
+	want.Resolution.At, got.Resolution.At = time.Time{}, time.Time{}
+	assert.Equal(t, want, got)
+}
@@ -0,0 +1,136 @@
+package repotest
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"homework9/internal/ads"
+	"homework9/internal/app"
+	"homework9/internal/users"
+)
+
+// Backend - хранилище целиком: репозитории и единица работы над ними
+type Backend struct {
+	Ads   ads.AdRepository
+	Users users.UserRepository
+	UoW   app.UnitOfWork
+}
+
+var errAbort = errors.New("abort")
+
+// UnitOfWork прогоняет контракт app.UnitOfWork. newBackend должен возвращать пустое хранилище
+func UnitOfWork(t *testing.T, newBackend func(t *testing.T) Backend) {
+	ctx := context.Background()
+
+	t.Run("commit", func(t *testing.T) {
+		b := newBackend(t)
+		var adID, userID int64
+		err := b.UoW.Do(ctx, func(adRepo ads.AdRepository, userRepo users.UserRepository) error {
+			var err error
+			userID, err = userRepo.AddUser(ctx, users.User{Nickname: "Petya"})
+			if err != nil {
+				return err
+			}
+			adID, err = adRepo.AddAd(ctx, ads.Ad{Title: "hello", AuthorID: userID})
+			return err
+		})
+		require.NoError(t, err)
+
+		_, err = b.Users.GetUserByID(ctx, userID)
+		assert.NoError(t, err)
+		ad, err := b.Ads.GetAdById(ctx, adID)
+		require.NoError(t, err)
+		assert.Equal(t, userID, ad.AuthorID)
+	})
+
+	t.Run("rollback", func(t *testing.T) {
+		b := newBackend(t)
+		userID, err := b.Users.AddUser(ctx, users.User{Nickname: "Petya"})
+		require.NoError(t, err)
+		adID, err := b.Ads.AddAd(ctx, ads.Ad{Title: "hello", AuthorID: userID})
+		require.NoError(t, err)
+
+		err = b.UoW.Do(ctx, func(adRepo ads.AdRepository, userRepo users.UserRepository) error {
+			if _, err := adRepo.AddAd(ctx, ads.Ad{Title: "new"}); err != nil {
+				return err
+			}
+			if err := adRepo.UpdateById(ctx, adID, ads.Ad{Title: "changed", AuthorID: userID, Version: 1}); err != nil {
+				return err
+			}
+			if _, err := userRepo.AddUser(ctx, users.User{Nickname: "Vasya"}); err != nil {
+				return err
+			}
+			userRepo.DeleteUser(ctx, userID)
+			return errAbort
+		})
+		assert.ErrorIs(t, err, errAbort)
+
+		ad, err := b.Ads.GetAdById(ctx, adID)
+		require.NoError(t, err)
+		assert.Equal(t, "hello", ad.Title)
+		assert.Equal(t, int64(1), ad.Version)
+		_, err = b.Users.GetUserByID(ctx, userID)
+		assert.NoError(t, err)
+
+		all, err := b.Ads.GetAllAds(ctx)
+		require.NoError(t, err)
+		assert.Len(t, all, 1)
+
+		// откат возвращает и счётчики ID
+		nextAd, err := b.Ads.AddAd(ctx, ads.Ad{Title: "next"})
+		require.NoError(t, err)
+		assert.Equal(t, adID+1, nextAd)
+		nextUser, err := b.Users.AddUser(ctx, users.User{Nickname: "next"})
+		require.NoError(t, err)
+		assert.Equal(t, userID+1, nextUser)
+	})
+
+	t.Run("repository error rolls back", func(t *testing.T) {
+		b := newBackend(t)
+		err := b.UoW.Do(ctx, func(adRepo ads.AdRepository, _ users.UserRepository) error {
+			if _, err := adRepo.AddAd(ctx, ads.Ad{Title: "hello"}); err != nil {
+				return err
+			}
+			return adRepo.UpdateById(ctx, 42, ads.Ad{Title: "missing"})
+		})
+		assert.ErrorIs(t, err, ads.ErrNotFound)
+
+		all, err := b.Ads.GetAllAds(ctx)
+		require.NoError(t, err)
+		assert.Empty(t, all)
+	})
+
+	t.Run("isolation", func(t *testing.T) {
+		b := newBackend(t)
+		id, err := b.Ads.AddAd(ctx, ads.Ad{Title: ""})
+		require.NoError(t, err)
+
+		const n = 30
+		var wg sync.WaitGroup
+		for i := 0; i < n; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				err := b.UoW.Do(ctx, func(adRepo ads.AdRepository, _ users.UserRepository) error {
+					ad, err := adRepo.GetAdById(ctx, id)
+					if err != nil {
+						return err
+					}
+					ad.Title += "x"
+					return adRepo.UpdateById(ctx, id, *ad)
+				})
+				assert.NoError(t, err)
+			}()
+		}
+		wg.Wait()
+
+		ad, err := b.Ads.GetAdById(ctx, id)
+		require.NoError(t, err)
+		assert.Len(t, ad.Title, n, "concurrent read-modify-write lost updates")
+	})
+}
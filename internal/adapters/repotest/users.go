@@ -0,0 +1,132 @@
+package repotest
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"homework9/internal/users"
+)
+
+// UserRepository прогоняет контракт users.UserRepository. newRepo должен возвращать пустое хранилище
+func UserRepository(t *testing.T, newRepo func(t *testing.T) users.UserRepository) {
+	ctx := context.Background()
+
+	t.Run("ID assignment", func(t *testing.T) {
+		r := newRepo(t)
+		for want := int64(0); want < 3; want++ {
+			id, err := r.AddUser(ctx, users.User{ID: 100, Nickname: "Petya", Email: "petya@mail.ru"})
+			require.NoError(t, err)
+			assert.Equal(t, want, id)
+
+			got, err := r.GetUserByID(ctx, id)
+			require.NoError(t, err)
+			assert.Equal(t, id, got.ID)
+			assert.Equal(t, "Petya", got.Nickname)
+			assert.Equal(t, "petya@mail.ru", got.Email)
+			assert.Equal(t, users.RoleUser, got.EffectiveRole())
+		}
+	})
+
+	t.Run("update", func(t *testing.T) {
+		r := newRepo(t)
+		id, err := r.AddUser(ctx, users.User{Nickname: "Petya", Email: "petya@mail.ru"})
+		require.NoError(t, err)
+
+		require.NoError(t, r.UpdateByID(ctx, id, users.User{Nickname: "Вася", Email: "vasya@mail.ru", Role: users.RoleModerator, Version: 1}))
+		got, err := r.GetUserByID(ctx, id)
+		require.NoError(t, err)
+		assert.Equal(t, id, got.ID)
+		assert.Equal(t, "Вася", got.Nickname)
+		assert.Equal(t, "vasya@mail.ru", got.Email)
+		assert.Equal(t, users.RoleModerator, got.EffectiveRole())
+		assert.Equal(t, int64(2), got.Version)
+	})
+
+	t.Run("email lookup", func(t *testing.T) {
+		r := newRepo(t)
+		first, err := r.AddUser(ctx, users.User{Nickname: "Petya", Email: "petya@mail.ru", PasswordHash: "hash"})
+		require.NoError(t, err)
+		_, err = r.AddUser(ctx, users.User{Nickname: "Vasya", Email: "vasya@mail.ru"})
+		require.NoError(t, err)
+		_, err = r.AddUser(ctx, users.User{Nickname: "Petya 2", Email: "petya@mail.ru"})
+		require.NoError(t, err)
+
+		got, err := r.GetUserByEmail(ctx, "petya@mail.ru")
+		require.NoError(t, err)
+		assert.Equal(t, first, got.ID)
+		assert.Equal(t, "hash", got.PasswordHash)
+
+		_, err = r.GetUserByEmail(ctx, "fedya@mail.ru")
+		assert.ErrorIs(t, err, users.ErrNotFound)
+	})
+
+	t.Run("version conflict", func(t *testing.T) {
+		r := newRepo(t)
+		id, err := r.AddUser(ctx, users.User{Nickname: "Petya"})
+		require.NoError(t, err)
+		require.NoError(t, r.UpdateByID(ctx, id, users.User{Nickname: "Vasya", Version: 1}))
+
+		assert.ErrorIs(t, r.UpdateByID(ctx, id, users.User{Nickname: "Fedya", Version: 1}), users.ErrVersionConflict)
+		got, err := r.GetUserByID(ctx, id)
+		require.NoError(t, err)
+		assert.Equal(t, "Vasya", got.Nickname)
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		r := newRepo(t)
+		_, err := r.GetUserByID(ctx, 42)
+		assert.ErrorIs(t, err, users.ErrNotFound)
+		assert.ErrorIs(t, r.UpdateByID(ctx, 42, users.User{Nickname: "Petya"}), users.ErrNotFound)
+		r.DeleteUser(ctx, 42)
+
+		_, err = r.GetUserByID(ctx, 42)
+		assert.ErrorIs(t, err, users.ErrNotFound, "update of a missing user must not create it")
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		r := newRepo(t)
+		first, err := r.AddUser(ctx, users.User{Nickname: "Petya"})
+		require.NoError(t, err)
+		second, err := r.AddUser(ctx, users.User{Nickname: "Vasya"})
+		require.NoError(t, err)
+
+		r.DeleteUser(ctx, second)
+		_, err = r.GetUserByID(ctx, second)
+		assert.ErrorIs(t, err, users.ErrNotFound)
+
+		_, err = r.GetUserByID(ctx, first)
+		assert.NoError(t, err)
+
+		third, err := r.AddUser(ctx, users.User{Nickname: "Fedya"})
+		require.NoError(t, err)
+		assert.Equal(t, second+1, third)
+	})
+
+	t.Run("concurrent writes", func(t *testing.T) {
+		r := newRepo(t)
+		const n = 50
+		ids := make([]int64, n)
+		var wg sync.WaitGroup
+		for i := 0; i < n; i++ {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				id, err := r.AddUser(ctx, users.User{Nickname: "Petya"})
+				assert.NoError(t, err)
+				ids[i] = id
+				assert.NoError(t, r.UpdateByID(ctx, id, users.User{Nickname: "Vasya", Version: 1}))
+			}(i)
+		}
+		wg.Wait()
+
+		sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+		for i, id := range ids {
+			assert.Equal(t, int64(i), id)
+		}
+	})
+}
@@ -0,0 +1,67 @@
+package sessionrepo
+
+import (
+	"context"
+	"sync"
+
+	"homework9/internal/sessions"
+)
+
+type RepositoryMap struct {
+	repo map[string]sessions.Session
+	mx   *sync.RWMutex
+}
+
+func New() *RepositoryMap {
+	return &RepositoryMap{repo: make(map[string]sessions.Session), mx: &sync.RWMutex{}}
+}
+
+// AddSession заодно выбрасывает сессии, истёкшие к моменту создания новой
+func (r *RepositoryMap) AddSession(ctx context.Context, s sessions.Session) error {
+	r.mx.Lock()
+	defer r.mx.Unlock()
+	for hash, old := range r.repo {
+		if old.Expires.Before(s.Created) {
+			delete(r.repo, hash)
+		}
+	}
+	r.repo[s.TokenHash] = s
+	return nil
+}
+
+func (r *RepositoryMap) GetSession(ctx context.Context, tokenHash string) (*sessions.Session, error) {
+	r.mx.RLock()
+	defer r.mx.RUnlock()
+	s, ok := r.repo[tokenHash]
+	if !ok {
+		return nil, sessions.ErrNotFound
+	}
+	return &s, nil
+}
+
+func (r *RepositoryMap) TakeSession(ctx context.Context, tokenHash string) (*sessions.Session, error) {
+	r.mx.Lock()
+	defer r.mx.Unlock()
+	s, ok := r.repo[tokenHash]
+	if !ok {
+		return nil, sessions.ErrNotFound
+	}
+	delete(r.repo, tokenHash)
+	return &s, nil
+}
+
+func (r *RepositoryMap) DeleteSession(ctx context.Context, tokenHash string) {
+	r.mx.Lock()
+	defer r.mx.Unlock()
+	delete(r.repo, tokenHash)
+}
+
+func (r *RepositoryMap) DeleteUserSessions(ctx context.Context, userID int64) {
+	r.mx.Lock()
+	defer r.mx.Unlock()
+	for hash, s := range r.repo {
+		if s.UserID == userID {
+			delete(r.repo, hash)
+		}
+	}
+}
@@ -0,0 +1,77 @@
+package sqlrepo
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"homework9/internal/categories"
+)
+
+type CategoryRepository struct {
+	q querier
+}
+
+const categoryColumns = `id, name, parent_id`
+
+func (r *CategoryRepository) GetCategory(ctx context.Context, id int64) (*categories.Category, error) {
+	var c categories.Category
+	err := r.q.QueryRowContext(ctx, `SELECT `+categoryColumns+` FROM categories WHERE id = ?`, id).Scan(&c.ID, &c.Name, &c.ParentID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, categories.ErrNotFound
+	} else if err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+func (r *CategoryRepository) ListCategories(ctx context.Context) ([]categories.Category, error) {
+	rows, err := r.q.QueryContext(ctx, `SELECT `+categoryColumns+` FROM categories ORDER BY id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	res := make([]categories.Category, 0)
+	for rows.Next() {
+		var c categories.Category
+		if err := rows.Scan(&c.ID, &c.Name, &c.ParentID); err != nil {
+			return nil, err
+		}
+		res = append(res, c)
+	}
+	return res, rows.Err()
+}
+
+func (r *CategoryRepository) AddCategory(ctx context.Context, c categories.Category) (int64, error) {
+	var id int64
+	err := inTx(ctx, r.q, func(q querier) error {
+		var err error
+		id, err = nextID(ctx, q, "categories")
+		if err != nil {
+			return err
+		}
+		_, err = q.ExecContext(ctx, `INSERT INTO categories (`+categoryColumns+`) VALUES (?, ?, ?)`, id, c.Name, c.ParentID)
+		return err
+	})
+	if err != nil {
+		return 0, err
+	}
+	return id, nil
+}
+
+func (r *CategoryRepository) UpdateCategory(ctx context.Context, id int64, c categories.Category) error {
+	res, err := r.q.ExecContext(ctx, `UPDATE categories SET name = ?, parent_id = ? WHERE id = ?`, c.Name, c.ParentID, id)
+	if err != nil {
+		return err
+	}
+	return requireAffected(res, categories.ErrNotFound)
+}
+
+func (r *CategoryRepository) DeleteCategory(ctx context.Context, id int64) error {
+	res, err := r.q.ExecContext(ctx, `DELETE FROM categories WHERE id = ?`, id)
+	if err != nil {
+		return err
+	}
+	return requireAffected(res, categories.ErrNotFound)
+}
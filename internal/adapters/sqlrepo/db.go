@@ -0,0 +1,128 @@
+package sqlrepo
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	_ "modernc.org/sqlite"
+
+	"homework9/internal/ads"
+	"homework9/internal/users"
+)
+
+// DB - хранилище объявлений, пользователей, категорий, жалоб и избранного во встроенной базе SQLite.
+// Драйвер написан на чистом Go, поэтому сборка не требует cgo.
+type DB struct {
+	db *sql.DB
+}
+
+// Open открывает (или создаёт) файл базы и применяет недостающие миграции
+func Open(ctx context.Context, path string) (*DB, error) {
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		return nil, err
+	}
+	// SQLite всё равно пропускает только одного писателя за раз, а одно соединение
+	// избавляет от SQLITE_BUSY и позволяет использовать базу ":memory:"
+	db.SetMaxOpenConns(1)
+
+	for _, pragma := range []string{
+		`PRAGMA journal_mode = WAL`,
+		`PRAGMA synchronous = NORMAL`,
+		`PRAGMA busy_timeout = 5000`,
+	} {
+		if _, err := db.ExecContext(ctx, pragma); err != nil {
+			db.Close()
+			return nil, err
+		}
+	}
+
+	if err := migrate(ctx, db); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return &DB{db: db}, nil
+}
+
+func (d *DB) Ads() *AdRepository {
+	return &AdRepository{q: d.db}
+}
+
+func (d *DB) Users() *UserRepository {
+	return &UserRepository{q: d.db}
+}
+
+func (d *DB) Categories() *CategoryRepository {
+	return &CategoryRepository{q: d.db}
+}
+
+func (d *DB) Reports() *ReportRepository {
+	return &ReportRepository{q: d.db}
+}
+
+func (d *DB) Favorites() *FavoriteRepository {
+	return &FavoriteRepository{q: d.db}
+}
+
+func (d *DB) Close() error {
+	return d.db.Close()
+}
+
+// querier - общее у *sql.DB и *sql.Tx
+type querier interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+// inTx выполняет fn в транзакции. Если q уже транзакция, fn выполняется в ней же
+func inTx(ctx context.Context, q querier, fn func(q querier) error) error {
+	db, ok := q.(*sql.DB)
+	if !ok {
+		return fn(q)
+	}
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if err := fn(tx); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func nextID(ctx context.Context, q querier, sequence string) (int64, error) {
+	var id int64
+	err := q.QueryRowContext(ctx, `UPDATE sequences SET last_id = last_id + 1 WHERE name = ? RETURNING last_id`, sequence).Scan(&id)
+	return id, err
+}
+
+// время хранится в наносекундах, нулевое время - как 0
+func toNanos(t time.Time) int64 {
+	if t.IsZero() {
+		return 0
+	}
+	return t.UnixNano()
+}
+
+func fromNanos(n int64) time.Time {
+	if n == 0 {
+		return time.Time{}
+	}
+	return time.Unix(0, n)
+}
+
+// Do выполняет fn в одной транзакции базы
+func (d *DB) Do(ctx context.Context, fn func(adRepo ads.AdRepository, userRepo users.UserRepository) error) error {
+	tx, err := d.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if err := fn(&AdRepository{q: tx}, &UserRepository{q: tx}); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,124 @@
+package sqlrepo
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"homework9/internal/favorites"
+)
+
+type FavoriteRepository struct {
+	q querier
+}
+
+func (r *FavoriteRepository) AddFavorite(ctx context.Context, f favorites.Favorite) error {
+	res, err := r.q.ExecContext(ctx, `INSERT INTO favorites (user_id, ad_id, added) VALUES (?, ?, ?) ON CONFLICT DO NOTHING`,
+		f.UserID, f.AdID, toNanos(f.Added))
+	if err != nil {
+		return err
+	}
+	return requireAffected(res, favorites.ErrDuplicate)
+}
+
+func (r *FavoriteRepository) GetFavorite(ctx context.Context, userID int64, adID int64) (*favorites.Favorite, error) {
+	f := favorites.Favorite{UserID: userID, AdID: adID}
+	var added int64
+	err := r.q.QueryRowContext(ctx, `SELECT added FROM favorites WHERE user_id = ? AND ad_id = ?`, userID, adID).Scan(&added)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, favorites.ErrNotFound
+	} else if err != nil {
+		return nil, err
+	}
+	f.Added = fromNanos(added)
+	return &f, nil
+}
+
+func (r *FavoriteRepository) RemoveFavorite(ctx context.Context, userID int64, adID int64) error {
+	res, err := r.q.ExecContext(ctx, `DELETE FROM favorites WHERE user_id = ? AND ad_id = ?`, userID, adID)
+	if err != nil {
+		return err
+	}
+	return requireAffected(res, favorites.ErrNotFound)
+}
+
+func (r *FavoriteRepository) ListFavorites(ctx context.Context, userID int64, after *favorites.Favorite, limit int) ([]favorites.Favorite, error) {
+	query := `SELECT ad_id, added FROM favorites WHERE user_id = ?`
+	args := []any{userID}
+	if after != nil {
+		query += ` AND (added < ? OR added = ? AND ad_id < ?)`
+		args = append(args, toNanos(after.Added), toNanos(after.Added), after.AdID)
+	}
+	query += ` ORDER BY added DESC, ad_id DESC`
+	if limit > 0 {
+		query += ` LIMIT ?`
+		args = append(args, limit)
+	}
+
+	rows, err := r.q.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	res := make([]favorites.Favorite, 0)
+	for rows.Next() {
+		f := favorites.Favorite{UserID: userID}
+		var added int64
+		if err := rows.Scan(&f.AdID, &added); err != nil {
+			return nil, err
+		}
+		f.Added = fromNanos(added)
+		res = append(res, f)
+	}
+	return res, rows.Err()
+}
+
+func (r *FavoriteRepository) CountFavorites(ctx context.Context, adIDs []int64) (map[int64]int, error) {
+	res := make(map[int64]int, len(adIDs))
+	if len(adIDs) == 0 {
+		return res, nil
+	}
+	args := make([]any, 0, len(adIDs))
+	for _, id := range adIDs {
+		args = append(args, id)
+	}
+	rows, err := r.q.QueryContext(ctx, `SELECT ad_id, COUNT(*) FROM favorites WHERE `+inList("ad_id", len(adIDs))+` GROUP BY ad_id`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id int64
+		var n int
+		if err := rows.Scan(&id, &n); err != nil {
+			return nil, err
+		}
+		res[id] = n
+	}
+	return res, rows.Err()
+}
+
+func (r *FavoriteRepository) FavoriteUsers(ctx context.Context, adID int64) ([]int64, error) {
+	rows, err := r.q.QueryContext(ctx, `SELECT user_id FROM favorites WHERE ad_id = ? ORDER BY user_id`, adID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	res := make([]int64, 0)
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		res = append(res, id)
+	}
+	return res, rows.Err()
+}
+
+func (r *FavoriteRepository) DeleteUserFavorites(ctx context.Context, userID int64) error {
+	_, err := r.q.ExecContext(ctx, `DELETE FROM favorites WHERE user_id = ?`, userID)
+	return err
+}
@@ -0,0 +1,224 @@
+package sqlrepo
+
+import (
+	"context"
+	"database/sql/driver"
+	"fmt"
+	"strings"
+	"time"
+
+	"modernc.org/sqlite"
+
+	"homework9/internal/ads"
+	"homework9/internal/geo"
+)
+
+// Встроенный lower в SQLite понимает только ASCII, а объявления в основном на русском.
+// unicode_lower переводит строку в нижний регистр так же, как strings.ToLower в ads.Filter.Match
+func init() {
+	sqlite.MustRegisterDeterministicScalarFunction("unicode_lower", 1,
+		func(ctx *sqlite.FunctionContext, args []driver.Value) (driver.Value, error) {
+			switch v := args[0].(type) {
+			case string:
+				return strings.ToLower(v), nil
+			case nil:
+				return nil, nil
+			default:
+				return nil, fmt.Errorf("unicode_lower: unexpected argument %T", v)
+			}
+		})
+	// geo_distance(lat, lon, lat0, lon0) считает расстояние той же функцией, что ads.Distance,
+	// поэтому отбор по радиусу и сортировка по расстоянию совпадают с ads.Filter.Match и ads.Sort.Less
+	sqlite.MustRegisterDeterministicScalarFunction("geo_distance", 4,
+		func(ctx *sqlite.FunctionContext, args []driver.Value) (driver.Value, error) {
+			lat, ok1 := args[0].(float64)
+			lon, ok2 := args[1].(float64)
+			if !ok1 || !ok2 {
+				return ads.NoDistance, nil
+			}
+			lat0, ok1 := args[2].(float64)
+			lon0, ok2 := args[3].(float64)
+			if !ok1 || !ok2 {
+				return nil, fmt.Errorf("geo_distance: unexpected origin %T, %T", args[2], args[3])
+			}
+			return geo.Distance(geo.Point{Lat: lat0, Lon: lon0}, geo.Point{Lat: lat, Lon: lon}), nil
+		})
+}
+
+func (r *AdRepository) FindAds(ctx context.Context, f ads.Filter, p ads.Page) ([]ads.Ad, error) {
+	where, args := filterWhere(f)
+	if p.After != nil {
+		cond, after := afterCursor(p.Sort, *p.After)
+		where += ` AND ` + cond
+		args = append(args, after...)
+	}
+	order, orderArgs := orderBy(p.Sort)
+	args = append(args, orderArgs...)
+	query := `SELECT ` + adColumns + ` FROM ads WHERE ` + where + ` ORDER BY ` + order
+	if p.Limit > 0 {
+		query += ` LIMIT ?`
+		args = append(args, p.Limit)
+	}
+	return r.queryAds(ctx, query, args...)
+}
+
+// filterWhere переводит фильтр в условие WHERE с плейсхолдерами
+func filterWhere(f ads.Filter) (string, []any) {
+	conds := []string{"1 = 1"}
+	var args []any
+
+	if len(f.AuthorIDs) > 0 {
+		conds = append(conds, inList(`author_id`, len(f.AuthorIDs)))
+		for _, id := range f.AuthorIDs {
+			args = append(args, id)
+		}
+	}
+	if len(f.CategoryIDs) > 0 {
+		conds = append(conds, inList(`category_id`, len(f.CategoryIDs)))
+		for _, id := range f.CategoryIDs {
+			args = append(args, id)
+		}
+	}
+	switch f.Published {
+	case ads.OnlyPublished:
+		conds = append(conds, `published = 1`)
+	case ads.OnlyUnpublished:
+		conds = append(conds, `published = 0`)
+	}
+	if f.VisibleTo != nil {
+		conds = append(conds, `(published = 1 OR author_id = ?)`)
+		args = append(args, *f.VisibleTo)
+	}
+	if f.Status != "" {
+		conds = append(conds, `status = ?`)
+		args = append(args, f.Status)
+	}
+
+	bounds := []struct {
+		cond string
+		t    time.Time
+	}{
+		{`created >= ?`, f.CreatedFrom},
+		{`created < ?`, f.CreatedTo},
+		{`modified >= ?`, f.ModifiedFrom},
+		{`modified < ?`, f.ModifiedTo},
+		{`publish_at > 0 AND publish_at < ?`, f.PublishBefore},
+		{`expires_at > 0 AND expires_at < ?`, f.ExpiresBefore},
+	}
+	for _, bound := range bounds {
+		if !bound.t.IsZero() {
+			conds = append(conds, bound.cond)
+			args = append(args, toNanos(bound.t))
+		}
+	}
+
+	if f.TitleContains != "" {
+		conds = append(conds, `instr(unicode_lower(title), ?) > 0`)
+		args = append(args, strings.ToLower(f.TitleContains))
+	}
+	if f.TextContains != "" {
+		conds = append(conds, `instr(unicode_lower(text), ?) > 0`)
+		args = append(args, strings.ToLower(f.TextContains))
+	}
+
+	if f.Currency != "" {
+		conds = append(conds, `currency = ?`)
+		args = append(args, f.Currency)
+	}
+	if f.PriceMin != nil {
+		conds = append(conds, `price >= ?`)
+		args = append(args, *f.PriceMin)
+	}
+	if f.PriceMax != nil {
+		conds = append(conds, `price <= ?`)
+		args = append(args, *f.PriceMax)
+	}
+
+	// кандидаты выбираются по индексу на geohash, точные условия проверяются на них
+	if bounds, ok := f.GeoBounds(); ok {
+		cond, cellArgs := geohashCells(geo.Cover(bounds))
+		conds = append(conds, cond)
+		args = append(args, cellArgs...)
+	}
+	if f.Near != nil {
+		conds = append(conds, `geo_distance(lat, lon, ?, ?) <= ?`)
+		args = append(args, f.Near.Center.Lat, f.Near.Center.Lon, f.Near.RadiusKm)
+	}
+	if f.Box != nil {
+		conds = append(conds, `lat BETWEEN ? AND ? AND lon BETWEEN ? AND ?`)
+		args = append(args, f.Box.MinLat, f.Box.MaxLat, f.Box.MinLon, f.Box.MaxLon)
+	}
+	return strings.Join(conds, ` AND `), args
+}
+
+// geohashCells - условие «геохеш начинается с одного из префиксов» в виде диапазонов, которые читаются по индексу
+func geohashCells(prefixes []string) (string, []any) {
+	if len(prefixes) == 0 {
+		return `0 = 1`, nil
+	}
+	var ranges []string
+	var args []any
+	for _, prefix := range prefixes {
+		if prefix == "" {
+			ranges = append(ranges, `geohash <> ''`)
+			continue
+		}
+		ranges = append(ranges, `(geohash >= ? AND geohash < ?)`)
+		args = append(args, prefix, geo.PrefixEnd(prefix))
+	}
+	return `(` + strings.Join(ranges, ` OR `) + `)`, args
+}
+
+func inList(col string, n int) string {
+	return col + ` IN (?` + strings.Repeat(`, ?`, n-1) + `)`
+}
+
+// sortColumn возвращает выражение ключа сортировки с его параметрами и значение этого ключа в курсоре
+func sortColumn(s ads.Sort, c ads.Cursor) (string, []any, any) {
+	switch s.Field {
+	case ads.SortByCreated:
+		return `created`, nil, toNanos(c.Created)
+	case ads.SortByModified:
+		return `modified`, nil, toNanos(c.Modified)
+	case ads.SortByTitle:
+		return `title`, nil, c.Title
+	case ads.SortByPrice:
+		return `price`, nil, c.Price
+	case ads.SortByDistance:
+		// без точки отсчёта все расстояния равны, как в ads.Sort.Less, и порядок задаёт id
+		if s.Origin == nil {
+			return ``, nil, nil
+		}
+		return `geo_distance(lat, lon, ?, ?)`, []any{s.Origin.Lat, s.Origin.Lon}, c.Distance
+	default:
+		return ``, nil, nil
+	}
+}
+
+// orderBy повторяет ads.Sort.Less: ключ сортировки, затем id в том же направлении.
+// Строки сравниваются побайтово (BINARY), как строки в Go
+func orderBy(s ads.Sort) (string, []any) {
+	dir := ` ASC`
+	if s.Desc {
+		dir = ` DESC`
+	}
+	col, colArgs, _ := sortColumn(s, ads.Cursor{})
+	if col == `` {
+		return `id` + dir, nil
+	}
+	return col + dir + `, id` + dir, colArgs
+}
+
+// afterCursor - условие «строго после курсора» в порядке orderBy
+func afterCursor(s ads.Sort, c ads.Cursor) (string, []any) {
+	op := ` > `
+	if s.Desc {
+		op = ` < `
+	}
+	col, colArgs, key := sortColumn(s, c)
+	if col == `` {
+		return `id` + op + `?`, []any{c.ID}
+	}
+	args := append(append(append([]any{}, colArgs...), key), colArgs...)
+	return `(` + col + op + `? OR (` + col + ` = ? AND id` + op + `?))`, append(args, key, c.ID)
+}
@@ -0,0 +1,264 @@
+package sqlrepo
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+var ErrSchemaTooNew = errors.New("database schema is newer than this build supports")
+
+type migration struct {
+	version int
+	name    string
+	stmts   []string
+}
+
+// migrations применяются строго по порядку. Уже выпущенные миграции не меняются - только дописываются новые
+var migrations = []migration{
+	{
+		version: 1,
+		name:    "create users",
+		stmts: []string{
+			`CREATE TABLE users (
+				id       INTEGER PRIMARY KEY,
+				nickname TEXT NOT NULL,
+				email    TEXT NOT NULL
+			)`,
+		},
+	},
+	{
+		version: 2,
+		name:    "create ads",
+		stmts: []string{
+			`CREATE TABLE ads (
+				id        INTEGER PRIMARY KEY,
+				title     TEXT NOT NULL,
+				text      TEXT NOT NULL,
+				author_id INTEGER NOT NULL,
+				published INTEGER NOT NULL DEFAULT 0,
+				created   INTEGER NOT NULL DEFAULT 0,
+				modified  INTEGER NOT NULL DEFAULT 0
+			)`,
+			`CREATE INDEX ads_published ON ads (published)`,
+			`CREATE INDEX ads_author_id ON ads (author_id)`,
+		},
+	},
+	{
+		// ID выдаются с нуля и никогда не переиспользуются, как в RepositoryMap
+		version: 3,
+		name:    "create sequences",
+		stmts: []string{
+			`CREATE TABLE sequences (
+				name    TEXT PRIMARY KEY,
+				last_id INTEGER NOT NULL
+			)`,
+			`INSERT INTO sequences (name, last_id) VALUES
+				('ads', COALESCE((SELECT MAX(id) FROM ads), -1)),
+				('users', COALESCE((SELECT MAX(id) FROM users), -1))`,
+		},
+	},
+	{
+		version: 4,
+		name:    "add versions",
+		stmts: []string{
+			`ALTER TABLE ads ADD COLUMN version INTEGER NOT NULL DEFAULT 1`,
+			`ALTER TABLE users ADD COLUMN version INTEGER NOT NULL DEFAULT 1`,
+		},
+	},
+	{
+		version: 5,
+		name:    "add password hashes",
+		stmts: []string{
+			`ALTER TABLE users ADD COLUMN password_hash TEXT NOT NULL DEFAULT ''`,
+			`CREATE INDEX users_email ON users (email)`,
+		},
+	},
+	{
+		version: 6,
+		name:    "add user roles",
+		stmts: []string{
+			`ALTER TABLE users ADD COLUMN role TEXT NOT NULL DEFAULT 'user'`,
+		},
+	},
+	{
+		// ID категорий выдаются с единицы: 0 в parent_id - корень, в ads.category_id - объявление без категории
+		version: 7,
+		name:    "create categories",
+		stmts: []string{
+			`CREATE TABLE categories (
+				id        INTEGER PRIMARY KEY,
+				name      TEXT NOT NULL,
+				parent_id INTEGER NOT NULL DEFAULT 0
+			)`,
+			`CREATE INDEX categories_parent_id ON categories (parent_id)`,
+			`INSERT INTO sequences (name, last_id) VALUES ('categories', 0)`,
+			`ALTER TABLE ads ADD COLUMN category_id INTEGER NOT NULL DEFAULT 0`,
+			`CREATE INDEX ads_category_id ON ads (category_id)`,
+		},
+	},
+	{
+		// price - сумма в минимальных единицах валюты, пустая currency - цена не указана
+		version: 8,
+		name:    "add prices",
+		stmts: []string{
+			`ALTER TABLE ads ADD COLUMN price INTEGER NOT NULL DEFAULT 0`,
+			`ALTER TABLE ads ADD COLUMN currency TEXT NOT NULL DEFAULT ''`,
+			`ALTER TABLE ads ADD COLUMN negotiable INTEGER NOT NULL DEFAULT 0`,
+			`CREATE INDEX ads_currency_price ON ads (currency, price)`,
+		},
+	},
+	{
+		// images - JSON-массив ads.Image в порядке показа, сами файлы лежат в хранилище блобов
+		version: 9,
+		name:    "add ad images",
+		stmts: []string{
+			`ALTER TABLE ads ADD COLUMN images TEXT NOT NULL DEFAULT '[]'`,
+		},
+	},
+	{
+		// lat и lon NULL, если место не указано. geohash - геохеш места длины geo.HashPrecision или пустая строка:
+		// поиск поблизости выбирает по индексу диапазоны геохешей ячеек, покрывающих область
+		version: 10,
+		name:    "add ad locations",
+		stmts: []string{
+			`ALTER TABLE ads ADD COLUMN lat REAL`,
+			`ALTER TABLE ads ADD COLUMN lon REAL`,
+			`ALTER TABLE ads ADD COLUMN city TEXT NOT NULL DEFAULT ''`,
+			`ALTER TABLE ads ADD COLUMN geohash TEXT NOT NULL DEFAULT ''`,
+			`CREATE INDEX ads_geohash ON ads (geohash)`,
+		},
+	},
+	{
+		// сроки в наносекундах Unix, 0 - срок не задан. Индексы нужны планировщику, который
+		// регулярно ищет объявления с наступившим сроком
+		version: 11,
+		name:    "add ad schedule",
+		stmts: []string{
+			`ALTER TABLE ads ADD COLUMN publish_at INTEGER NOT NULL DEFAULT 0`,
+			`ALTER TABLE ads ADD COLUMN expires_at INTEGER NOT NULL DEFAULT 0`,
+			`CREATE INDEX ads_publish_at ON ads (publish_at) WHERE publish_at > 0`,
+			`CREATE INDEX ads_expires_at ON ads (expires_at) WHERE expires_at > 0`,
+		},
+	},
+	{
+		// опубликованные до модерации объявления считаются прошедшими её, остальные - черновиками.
+		// reviewed_at 0 - решения модератора ещё не было
+		version: 12,
+		name:    "add ad moderation",
+		stmts: []string{
+			`ALTER TABLE ads ADD COLUMN status TEXT NOT NULL DEFAULT 'draft'`,
+			`UPDATE ads SET status = 'published' WHERE published = 1`,
+			`ALTER TABLE ads ADD COLUMN review_moderator INTEGER NOT NULL DEFAULT 0`,
+			`ALTER TABLE ads ADD COLUMN review_reason TEXT NOT NULL DEFAULT ''`,
+			`ALTER TABLE ads ADD COLUMN reviewed_at INTEGER NOT NULL DEFAULT 0`,
+			`CREATE INDEX ads_status ON ads (status)`,
+		},
+	},
+	{
+		// замечания проверок содержимого - JSON-массив, как фотографии
+		version: 13,
+		name:    "add ad flags",
+		stmts: []string{
+			`ALTER TABLE ads ADD COLUMN flags TEXT NOT NULL DEFAULT '[]'`,
+		},
+	},
+	{
+		// ID жалоб выдаются с единицы, на одну цель пользователь жалуется один раз
+		version: 14,
+		name:    "create reports",
+		stmts: []string{
+			`CREATE TABLE reports (
+				id          INTEGER PRIMARY KEY,
+				reporter_id INTEGER NOT NULL,
+				target      TEXT NOT NULL,
+				target_id   INTEGER NOT NULL,
+				reason      TEXT NOT NULL,
+				comment     TEXT NOT NULL DEFAULT '',
+				status      TEXT NOT NULL DEFAULT 'open',
+				moderator   INTEGER NOT NULL DEFAULT 0,
+				note        TEXT NOT NULL DEFAULT '',
+				resolved_at INTEGER NOT NULL DEFAULT 0,
+				created     INTEGER NOT NULL DEFAULT 0
+			)`,
+			`CREATE UNIQUE INDEX reports_reporter_target ON reports (reporter_id, target, target_id)`,
+			`CREATE INDEX reports_target ON reports (target, target_id)`,
+			`CREATE INDEX reports_status ON reports (status)`,
+			`INSERT INTO sequences (name, last_id) VALUES ('reports', 0)`,
+		},
+	},
+	{
+		// избранное не ссылается на объявления: запись удалённого объявления остаётся в списке пользователя
+		version: 15,
+		name:    "create favorites",
+		stmts: []string{
+			`CREATE TABLE favorites (
+				user_id INTEGER NOT NULL,
+				ad_id   INTEGER NOT NULL,
+				added   INTEGER NOT NULL DEFAULT 0,
+				PRIMARY KEY (user_id, ad_id)
+			)`,
+			`CREATE INDEX favorites_user_added ON favorites (user_id, added, ad_id)`,
+			`CREATE INDEX favorites_ad ON favorites (ad_id)`,
+		},
+	},
+}
+
+// migrate доводит схему базы до последней версии. Каждая миграция применяется в своей транзакции
+func migrate(ctx context.Context, db *sql.DB) error {
+	_, err := db.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS schema_migrations (
+		version    INTEGER PRIMARY KEY,
+		name       TEXT NOT NULL,
+		applied_at INTEGER NOT NULL
+	)`)
+	if err != nil {
+		return err
+	}
+
+	current, err := schemaVersion(ctx, db)
+	if err != nil {
+		return err
+	}
+	latest := migrations[len(migrations)-1].version
+	if current > latest {
+		return fmt.Errorf("%w: database is at version %d, latest known is %d", ErrSchemaTooNew, current, latest)
+	}
+
+	for _, m := range migrations {
+		if m.version <= current {
+			continue
+		}
+		if err := apply(ctx, db, m); err != nil {
+			return fmt.Errorf("migration %d (%s): %w", m.version, m.name, err)
+		}
+	}
+	return nil
+}
+
+func schemaVersion(ctx context.Context, db *sql.DB) (int, error) {
+	var version int
+	err := db.QueryRowContext(ctx, `SELECT COALESCE(MAX(version), 0) FROM schema_migrations`).Scan(&version)
+	return version, err
+}
+
+func apply(ctx context.Context, db *sql.DB, m migration) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, stmt := range m.stmts {
+		if _, err := tx.ExecContext(ctx, stmt); err != nil {
+			return err
+		}
+	}
+	_, err = tx.ExecContext(ctx, `INSERT INTO schema_migrations (version, name, applied_at) VALUES (?, ?, ?)`,
+		m.version, m.name, time.Now().Unix())
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,260 @@
+package sqlrepo
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+
+	"homework9/internal/ads"
+	"homework9/internal/geo"
+	"homework9/internal/users"
+)
+
+const adColumns = `id, title, text, author_id, category_id, price, currency, negotiable, images, lat, lon, city, geohash, status, published, review_moderator, review_reason, reviewed_at, flags, created, modified, publish_at, expires_at, version`
+
+type AdRepository struct {
+	q querier
+}
+
+func scanAd(row interface{ Scan(...any) error }) (ads.Ad, error) {
+	var ad ads.Ad
+	var created, modified, publishAt, expiresAt, reviewed int64
+	var images, flags, city, geohash string
+	var lat, lon sql.NullFloat64
+	err := row.Scan(&ad.ID, &ad.Title, &ad.Text, &ad.AuthorID, &ad.CategoryID, &ad.Price.Amount, &ad.Price.Currency, &ad.Price.Negotiable,
+		&images, &lat, &lon, &city, &geohash, &ad.Status, &ad.Published, &ad.Review.ModeratorID, &ad.Review.Reason, &reviewed, &flags, &created, &modified, &publishAt, &expiresAt, &ad.Version)
+	if err != nil {
+		return ad, err
+	}
+	if lat.Valid && lon.Valid {
+		ad.Location = &ads.Location{Lat: lat.Float64, Lon: lon.Float64, City: city}
+	}
+	ad.Created = fromNanos(created)
+	ad.Modified = fromNanos(modified)
+	ad.PublishAt = fromNanos(publishAt)
+	ad.ExpiresAt = fromNanos(expiresAt)
+	ad.Review.At = fromNanos(reviewed)
+	if err := json.Unmarshal([]byte(images), &ad.Images); err != nil {
+		return ad, err
+	}
+	if len(ad.Images) == 0 {
+		ad.Images = nil
+	}
+	if err := json.Unmarshal([]byte(flags), &ad.Flags); err != nil {
+		return ad, err
+	}
+	if len(ad.Flags) == 0 {
+		ad.Flags = nil
+	}
+	return ad, nil
+}
+
+// locationColumns - значения колонок lat, lon, city и geohash
+func locationColumns(l *ads.Location) []any {
+	if l == nil {
+		return []any{nil, nil, "", ""}
+	}
+	return []any{l.Lat, l.Lon, l.City, geo.Encode(l.Point(), geo.HashPrecision)}
+}
+
+func encodeImages(list []ads.Image) (string, error) {
+	if len(list) == 0 {
+		return "[]", nil
+	}
+	data, err := json.Marshal(list)
+	return string(data), err
+}
+
+func encodeFlags(list []ads.Flag) (string, error) {
+	if len(list) == 0 {
+		return "[]", nil
+	}
+	data, err := json.Marshal(list)
+	return string(data), err
+}
+
+func (r *AdRepository) queryAds(ctx context.Context, query string, args ...any) ([]ads.Ad, error) {
+	rows, err := r.q.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	res := make([]ads.Ad, 0)
+	for rows.Next() {
+		ad, err := scanAd(rows)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, ad)
+	}
+	return res, rows.Err()
+}
+
+func (r *AdRepository) GetAdById(ctx context.Context, id int64) (*ads.Ad, error) {
+	ad, err := scanAd(r.q.QueryRowContext(ctx, `SELECT `+adColumns+` FROM ads WHERE id = ?`, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ads.ErrNotFound
+	} else if err != nil {
+		return nil, err
+	}
+	return &ad, nil
+}
+
+func (r *AdRepository) ListPublishedAds(ctx context.Context, p ads.Page) ([]ads.Ad, error) {
+	return r.FindAds(ctx, ads.Filter{Published: ads.OnlyPublished}, p)
+}
+
+func (r *AdRepository) GetAllAds(ctx context.Context) ([]ads.Ad, error) {
+	return r.queryAds(ctx, `SELECT `+adColumns+` FROM ads`)
+}
+
+func (r *AdRepository) AddAd(ctx context.Context, ad ads.Ad) (int64, error) {
+	images, err := encodeImages(ad.Images)
+	if err != nil {
+		return 0, err
+	}
+	flags, err := encodeFlags(ad.Flags)
+	if err != nil {
+		return 0, err
+	}
+	var id int64
+	err = inTx(ctx, r.q, func(q querier) error {
+		var err error
+		id, err = nextID(ctx, q, "ads")
+		if err != nil {
+			return err
+		}
+		args := []any{id, ad.Title, ad.Text, ad.AuthorID, ad.CategoryID, ad.Price.Amount, ad.Price.Currency, ad.Price.Negotiable, images}
+		args = append(args, locationColumns(ad.Location)...)
+		args = append(args, ad.EffectiveStatus(), ad.Published, ad.Review.ModeratorID, ad.Review.Reason, toNanos(ad.Review.At), flags)
+		args = append(args, toNanos(ad.Created), toNanos(ad.Modified), toNanos(ad.PublishAt), toNanos(ad.ExpiresAt))
+		_, err = q.ExecContext(ctx, `INSERT INTO ads (`+adColumns+`) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, 1)`, args...)
+		return err
+	})
+	if err != nil {
+		return 0, err
+	}
+	return id, nil
+}
+
+func (r *AdRepository) UpdateById(ctx context.Context, id int64, ad ads.Ad) error {
+	images, err := encodeImages(ad.Images)
+	if err != nil {
+		return err
+	}
+	flags, err := encodeFlags(ad.Flags)
+	if err != nil {
+		return err
+	}
+	args := []any{ad.Title, ad.Text, ad.AuthorID, ad.CategoryID, ad.Price.Amount, ad.Price.Currency, ad.Price.Negotiable, images}
+	args = append(args, locationColumns(ad.Location)...)
+	args = append(args, ad.EffectiveStatus(), ad.Published, ad.Review.ModeratorID, ad.Review.Reason, toNanos(ad.Review.At), flags)
+	args = append(args, toNanos(ad.Created), toNanos(ad.Modified), toNanos(ad.PublishAt), toNanos(ad.ExpiresAt), id, ad.Version)
+	res, err := r.q.ExecContext(ctx, `UPDATE ads SET title = ?, text = ?, author_id = ?, category_id = ?, price = ?, currency = ?, negotiable = ?, images = ?,
+		lat = ?, lon = ?, city = ?, geohash = ?, status = ?, published = ?, review_moderator = ?, review_reason = ?, reviewed_at = ?, flags = ?, created = ?, modified = ?, publish_at = ?, expires_at = ?, version = version + 1
+		WHERE id = ? AND version = ?`, args...)
+	if err != nil {
+		return err
+	}
+	return requireUpdated(ctx, r.q, res, `SELECT 1 FROM ads WHERE id = ?`, id, ads.ErrNotFound, ads.ErrVersionConflict)
+}
+
+func (r *AdRepository) DeleteAdById(ctx context.Context, id int64) error {
+	res, err := r.q.ExecContext(ctx, `DELETE FROM ads WHERE id = ?`, id)
+	if err != nil {
+		return err
+	}
+	return requireAffected(res, ads.ErrNotFound)
+}
+
+func (r *AdRepository) DeleteAd(ctx context.Context, id int64) {
+	_, _ = r.q.ExecContext(ctx, `DELETE FROM ads WHERE id = ?`, id)
+}
+
+type UserRepository struct {
+	q querier
+}
+
+const userColumns = `id, nickname, email, password_hash, role, version`
+
+func (r *UserRepository) getUser(ctx context.Context, query string, args ...any) (*users.User, error) {
+	var u users.User
+	err := r.q.QueryRowContext(ctx, `SELECT `+userColumns+` FROM users `+query, args...).
+		Scan(&u.ID, &u.Nickname, &u.Email, &u.PasswordHash, &u.Role, &u.Version)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, users.ErrNotFound
+	} else if err != nil {
+		return nil, err
+	}
+	return &u, nil
+}
+
+func (r *UserRepository) GetUserByID(ctx context.Context, id int64) (*users.User, error) {
+	return r.getUser(ctx, `WHERE id = ?`, id)
+}
+
+func (r *UserRepository) GetUserByEmail(ctx context.Context, email string) (*users.User, error) {
+	return r.getUser(ctx, `WHERE email = ? ORDER BY id LIMIT 1`, email)
+}
+
+func (r *UserRepository) AddUser(ctx context.Context, u users.User) (int64, error) {
+	var id int64
+	err := inTx(ctx, r.q, func(q querier) error {
+		var err error
+		id, err = nextID(ctx, q, "users")
+		if err != nil {
+			return err
+		}
+		_, err = q.ExecContext(ctx, `INSERT INTO users (`+userColumns+`) VALUES (?, ?, ?, ?, ?, 1)`, id, u.Nickname, u.Email, u.PasswordHash, u.EffectiveRole())
+		return err
+	})
+	if err != nil {
+		return 0, err
+	}
+	return id, nil
+}
+
+func (r *UserRepository) UpdateByID(ctx context.Context, id int64, u users.User) error {
+	res, err := r.q.ExecContext(ctx, `UPDATE users SET nickname = ?, email = ?, password_hash = ?, role = ?, version = version + 1 WHERE id = ? AND version = ?`,
+		u.Nickname, u.Email, u.PasswordHash, u.EffectiveRole(), id, u.Version)
+	if err != nil {
+		return err
+	}
+	return requireUpdated(ctx, r.q, res, `SELECT 1 FROM users WHERE id = ?`, id, users.ErrNotFound, users.ErrVersionConflict)
+}
+
+func (r *UserRepository) DeleteUser(ctx context.Context, id int64) {
+	_, _ = r.q.ExecContext(ctx, `DELETE FROM users WHERE id = ?`, id)
+}
+
+// requireUpdated отличает обновление несуществующей записи от обновления устаревшей версии
+func requireUpdated(ctx context.Context, q querier, res sql.Result, exists string, id int64, notFound error, conflict error) error {
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n > 0 {
+		return nil
+	}
+	var one int
+	err = q.QueryRowContext(ctx, exists, id).Scan(&one)
+	if errors.Is(err, sql.ErrNoRows) {
+		return notFound
+	} else if err != nil {
+		return err
+	}
+	return conflict
+}
+
+func requireAffected(res sql.Result, notFound error) error {
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return notFound
+	}
+	return nil
+}
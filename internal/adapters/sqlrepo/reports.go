@@ -0,0 +1,118 @@
+package sqlrepo
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+
+	"homework9/internal/reports"
+)
+
+type ReportRepository struct {
+	q querier
+}
+
+const reportColumns = `id, reporter_id, target, target_id, reason, comment, status, moderator, note, resolved_at, created`
+
+func scanReport(row interface{ Scan(...any) error }) (reports.Report, error) {
+	var r reports.Report
+	var target, reason, status string
+	var resolvedAt, created int64
+	err := row.Scan(&r.ID, &r.ReporterID, &target, &r.TargetID, &reason, &r.Comment, &status,
+		&r.Resolution.ModeratorID, &r.Resolution.Note, &resolvedAt, &created)
+	if err != nil {
+		return reports.Report{}, err
+	}
+	r.Target, r.Reason, r.Status = reports.Target(target), reports.Reason(reason), reports.Status(status)
+	r.Resolution.At, r.Created = fromNanos(resolvedAt), fromNanos(created)
+	return r, nil
+}
+
+func (r *ReportRepository) AddReport(ctx context.Context, rep reports.Report) (int64, error) {
+	var id int64
+	err := inTx(ctx, r.q, func(q querier) error {
+		var filed int
+		err := q.QueryRowContext(ctx, `SELECT COUNT(*) FROM reports WHERE reporter_id = ? AND target = ? AND target_id = ?`,
+			rep.ReporterID, string(rep.Target), rep.TargetID).Scan(&filed)
+		if err != nil {
+			return err
+		}
+		if filed > 0 {
+			return reports.ErrDuplicate
+		}
+		id, err = nextID(ctx, q, "reports")
+		if err != nil {
+			return err
+		}
+		_, err = q.ExecContext(ctx, `INSERT INTO reports (`+reportColumns+`) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+			id, rep.ReporterID, string(rep.Target), rep.TargetID, string(rep.Reason), rep.Comment, string(rep.Status),
+			rep.Resolution.ModeratorID, rep.Resolution.Note, toNanos(rep.Resolution.At), toNanos(rep.Created))
+		return err
+	})
+	if err != nil {
+		return 0, err
+	}
+	return id, nil
+}
+
+func (r *ReportRepository) GetReport(ctx context.Context, id int64) (*reports.Report, error) {
+	rep, err := scanReport(r.q.QueryRowContext(ctx, `SELECT `+reportColumns+` FROM reports WHERE id = ?`, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, reports.ErrNotFound
+	} else if err != nil {
+		return nil, err
+	}
+	return &rep, nil
+}
+
+func (r *ReportRepository) FindReports(ctx context.Context, f reports.Filter, afterID int64, limit int) ([]reports.Report, error) {
+	conds := []string{"id > ?"}
+	args := []any{afterID}
+	if f.Status != "" {
+		conds = append(conds, "status = ?")
+		args = append(args, string(f.Status))
+	}
+	if f.Target != "" {
+		conds = append(conds, "target = ?")
+		args = append(args, string(f.Target))
+	}
+	if len(f.TargetIDs) > 0 {
+		conds = append(conds, inList("target_id", len(f.TargetIDs)))
+		for _, id := range f.TargetIDs {
+			args = append(args, id)
+		}
+	}
+	query := `SELECT ` + reportColumns + ` FROM reports WHERE ` + strings.Join(conds, " AND ") + ` ORDER BY id`
+	if limit > 0 {
+		query += ` LIMIT ?`
+		args = append(args, limit)
+	}
+
+	rows, err := r.q.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	res := make([]reports.Report, 0)
+	for rows.Next() {
+		rep, err := scanReport(rows)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, rep)
+	}
+	return res, rows.Err()
+}
+
+// UpdateReport меняет жалобу целиком. Автор и цель жалобы не меняются
+func (r *ReportRepository) UpdateReport(ctx context.Context, id int64, rep reports.Report) error {
+	res, err := r.q.ExecContext(ctx, `UPDATE reports SET reason = ?, comment = ?, status = ?, moderator = ?, note = ?, resolved_at = ?, created = ? WHERE id = ?`,
+		string(rep.Reason), rep.Comment, string(rep.Status), rep.Resolution.ModeratorID, rep.Resolution.Note,
+		toNanos(rep.Resolution.At), toNanos(rep.Created), id)
+	if err != nil {
+		return err
+	}
+	return requireAffected(res, reports.ErrNotFound)
+}
@@ -2,7 +2,6 @@ package userrepo
 
 import (
 	"context"
-	"errors"
 	"homework9/internal/users"
 	"sync"
 )
@@ -17,11 +16,78 @@ func New() *RepositoryMap {
 	return &RepositoryMap{repo: make(map[int64]users.User), lastId: -1, mx: &sync.RWMutex{}}
 }
 
-var ErrNotFound = errors.New("not found")
+var ErrNotFound = users.ErrNotFound
 
 func (r *RepositoryMap) GetUserByID(ctx context.Context, id int64) (*users.User, error) {
 	r.mx.RLock()
 	defer r.mx.RUnlock()
+	return r.get(id)
+}
+
+func (r *RepositoryMap) GetUserByEmail(ctx context.Context, email string) (*users.User, error) {
+	r.mx.RLock()
+	defer r.mx.RUnlock()
+	return r.getByEmail(email)
+}
+
+func (r *RepositoryMap) AddUser(ctx context.Context, u users.User) (int64, error) {
+	r.mx.Lock()
+	defer r.mx.Unlock()
+	return r.add(u), nil
+}
+func (r *RepositoryMap) UpdateByID(ctx context.Context, id int64, u users.User) error {
+	r.mx.Lock()
+	defer r.mx.Unlock()
+	_, err := r.update(id, u)
+	return err
+}
+
+func (r *RepositoryMap) DeleteUser(ctx context.Context, id int64) {
+	r.mx.Lock()
+	defer r.mx.Unlock()
+	r.remove(id)
+}
+
+// GetAllUsers возвращает всех пользователей, порядок не гарантируется
+func (r *RepositoryMap) GetAllUsers(ctx context.Context) ([]users.User, error) {
+	r.mx.RLock()
+	defer r.mx.RUnlock()
+	res := make([]users.User, 0, len(r.repo))
+	for _, u := range r.repo {
+		res = append(res, u)
+	}
+	return res, nil
+}
+
+// Restore кладёт пользователя под уже выданным ему ID (например, при восстановлении из журнала)
+func (r *RepositoryMap) Restore(u users.User) {
+	r.mx.Lock()
+	defer r.mx.Unlock()
+	r.repo[u.ID] = u
+	if u.ID > r.lastId {
+		r.lastId = u.ID
+	}
+}
+
+// LastID возвращает последний выданный ID, даже если пользователь с ним уже удалён
+func (r *RepositoryMap) LastID() int64 {
+	r.mx.RLock()
+	defer r.mx.RUnlock()
+	return r.lastId
+}
+
+// SetLastID сдвигает счётчик ID вперёд, чтобы удалённые ID не выдавались повторно
+func (r *RepositoryMap) SetLastID(id int64) {
+	r.mx.Lock()
+	defer r.mx.Unlock()
+	if id > r.lastId {
+		r.lastId = id
+	}
+}
+
+// методы ниже вызываются под r.mx
+
+func (r *RepositoryMap) get(id int64) (*users.User, error) {
 	user, ok := r.repo[id]
 	if !ok {
 		return nil, ErrNotFound
@@ -29,27 +95,48 @@ func (r *RepositoryMap) GetUserByID(ctx context.Context, id int64) (*users.User,
 	return &user, nil
 }
 
-func (r *RepositoryMap) AddUser(ctx context.Context, u users.User) (int64, error) {
-	r.mx.Lock()
-	defer r.mx.Unlock()
+func (r *RepositoryMap) getByEmail(email string) (*users.User, error) {
+	var found *users.User
+	for _, u := range r.repo {
+		if u.Email != email || (found != nil && found.ID < u.ID) {
+			continue
+		}
+		u := u
+		found = &u
+	}
+	if found == nil {
+		return nil, ErrNotFound
+	}
+	return found, nil
+}
+
+func (r *RepositoryMap) add(u users.User) int64 {
 	r.lastId++
 	id := r.lastId
+	u.ID = id
+	u.Version = 1
 	r.repo[id] = u
-	return id, nil
+	return id
 }
-func (r *RepositoryMap) UpdateByID(ctx context.Context, id int64, u users.User) error {
-	r.mx.Lock()
-	defer r.mx.Unlock()
-	_, ok := r.repo[id]
+
+func (r *RepositoryMap) update(id int64, u users.User) (users.User, error) {
+	prev, ok := r.repo[id]
 	if !ok {
-		return errors.New("ad not found")
+		return users.User{}, ErrNotFound
 	}
+	if u.Version != prev.Version {
+		return users.User{}, users.ErrVersionConflict
+	}
+	u.ID = id
+	u.Version = prev.Version + 1
 	r.repo[id] = u
-	return nil
+	return prev, nil
 }
 
-func (r *RepositoryMap) DeleteUser(ctx context.Context, id int64) {
-	r.mx.Lock()
-	defer r.mx.Unlock()
-	delete(r.repo, id)
+func (r *RepositoryMap) remove(id int64) (users.User, bool) {
+	prev, ok := r.repo[id]
+	if ok {
+		delete(r.repo, id)
+	}
+	return prev, ok
 }
@@ -0,0 +1,93 @@
+package userrepo
+
+import (
+	"context"
+	"homework9/internal/users"
+)
+
+// Tx - транзакция над RepositoryMap. Держит блокировку на запись до Commit или Rollback,
+// поэтому пока транзакция открыта, к самому RepositoryMap обращаться нельзя.
+type Tx struct {
+	r       *RepositoryMap
+	undo    []func()
+	changed map[int64]struct{}
+	done    bool
+}
+
+func (r *RepositoryMap) Begin() *Tx {
+	r.mx.Lock()
+	return &Tx{r: r, changed: make(map[int64]struct{})}
+}
+
+// Commit оставляет изменения и снимает блокировку
+func (t *Tx) Commit() {
+	if t.done {
+		return
+	}
+	t.done = true
+	t.r.mx.Unlock()
+}
+
+// Rollback отменяет все изменения транзакции в обратном порядке и снимает блокировку
+func (t *Tx) Rollback() {
+	if t.done {
+		return
+	}
+	for i := len(t.undo) - 1; i >= 0; i-- {
+		t.undo[i]()
+	}
+	t.done = true
+	t.r.mx.Unlock()
+}
+
+// Changed возвращает ID пользователей, которых транзакция добавила, изменила или удалила
+func (t *Tx) Changed() []int64 {
+	res := make([]int64, 0, len(t.changed))
+	for id := range t.changed {
+		res = append(res, id)
+	}
+	return res
+}
+
+// LastID - последний выданный ID с учётом изменений транзакции
+func (t *Tx) LastID() int64 {
+	return t.r.lastId
+}
+
+func (t *Tx) GetUserByID(ctx context.Context, id int64) (*users.User, error) {
+	return t.r.get(id)
+}
+
+func (t *Tx) GetUserByEmail(ctx context.Context, email string) (*users.User, error) {
+	return t.r.getByEmail(email)
+}
+
+func (t *Tx) AddUser(ctx context.Context, u users.User) (int64, error) {
+	lastId := t.r.lastId
+	id := t.r.add(u)
+	t.changed[id] = struct{}{}
+	t.undo = append(t.undo, func() {
+		delete(t.r.repo, id)
+		t.r.lastId = lastId
+	})
+	return id, nil
+}
+
+func (t *Tx) UpdateByID(ctx context.Context, id int64, u users.User) error {
+	prev, err := t.r.update(id, u)
+	if err != nil {
+		return err
+	}
+	t.changed[id] = struct{}{}
+	t.undo = append(t.undo, func() { t.r.repo[id] = prev })
+	return nil
+}
+
+func (t *Tx) DeleteUser(ctx context.Context, id int64) {
+	prev, ok := t.r.remove(id)
+	if !ok {
+		return
+	}
+	t.changed[id] = struct{}{}
+	t.undo = append(t.undo, func() { t.r.repo[id] = prev })
+}
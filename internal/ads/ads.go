@@ -1,13 +1,114 @@
 package ads
 
-import "time"
+import (
+	"time"
+
+	"homework9/internal/geo"
+)
 
 type Ad struct {
-	ID        int64
-	Title     string
-	Text      string
-	AuthorID  int64
+	ID       int64
+	Title    string
+	Text     string
+	AuthorID int64
+	// CategoryID - категория из дерева categories. 0 только у объявлений, созданных до появления категорий
+	CategoryID int64
+	Price      Price
+	// Location - где находится товар, nil - не указано
+	Location *Location
+	// Images - фотографии в порядке показа, первая - обложка
+	Images []Image
+	// Status - этап модерации, Published - опубликовано ли объявление, то есть Status == StatusPublished.
+	// Status пуст у объявлений, сохранённых до появления модерации; см. EffectiveStatus
+	Status    Status
 	Published bool
-	Created   time.Time
-	Modified  time.Time
+	// Review - последнее решение модератора, нулевое - объявление ещё не проверяли
+	Review Review
+	// Flags - замечания автоматических проверок к текущему содержимому. Объявление с замечаниями
+	// публикуется только после проверки модератором
+	Flags    []Flag
+	Created  time.Time
+	Modified time.Time
+	// PublishAt - когда опубликовать объявление, нулевое - публикация не запланирована.
+	// ExpiresAt - когда снять с публикации, нулевое - объявление не истекает
+	PublishAt time.Time
+	ExpiresAt time.Time
+	// Version растёт на единицу при каждом изменении объявления
+	Version int64
+}
+
+// Status - этап жизни объявления: черновик, на проверке, одобрено или отклонено модератором,
+// опубликовано, снято с публикации
+type Status string
+
+const (
+	StatusDraft     Status = "draft"
+	StatusPending   Status = "pending"
+	StatusApproved  Status = "approved"
+	StatusRejected  Status = "rejected"
+	StatusPublished Status = "published"
+	StatusArchived  Status = "archived"
+)
+
+func (s Status) Valid() bool {
+	switch s {
+	case StatusDraft, StatusPending, StatusApproved, StatusRejected, StatusPublished, StatusArchived:
+		return true
+	}
+	return false
+}
+
+// EffectiveStatus возвращает статус объявления, выводя пустой статус из Published
+func (a Ad) EffectiveStatus() Status {
+	if a.Status != "" {
+		return a.Status
+	}
+	if a.Published {
+		return StatusPublished
+	}
+	return StatusDraft
+}
+
+// Review - решение модератора. Reason обязателен при отклонении, при одобрении это комментарий
+type Review struct {
+	ModeratorID int64
+	Reason      string
+	At          time.Time
+}
+
+// Flag - замечание проверки Check к полю Field ("title", "text" или пусто - ко всему объявлению)
+type Flag struct {
+	Check  string
+	Field  string
+	Reason string
+}
+
+// Price - цена объявления. Amount хранится в минимальных единицах валюты Currency (ISO 4217).
+// Пустая Currency - цена не указана, Amount тогда 0. Negotiable - цена договорная, в том числе без суммы
+type Price struct {
+	Amount     int64
+	Currency   string
+	Negotiable bool
+}
+
+// Image - фотография объявления. Оригинал и превью лежат в хранилище блобов под ключами Key и ThumbKey
+type Image struct {
+	ID          string
+	Key         string
+	ThumbKey    string
+	ContentType string
+	Size        int64
+	Width       int
+	Height      int
+}
+
+// Location - координаты в градусах WGS 84 и название населённого пункта для показа
+type Location struct {
+	Lat  float64
+	Lon  float64
+	City string
+}
+
+func (l Location) Point() geo.Point {
+	return geo.Point{Lat: l.Lat, Lon: l.Lon}
 }
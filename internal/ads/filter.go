@@ -0,0 +1,156 @@
+package ads
+
+import (
+	"strings"
+	"time"
+
+	"homework9/internal/geo"
+)
+
+// PublishedFilter - отбор по статусу публикации
+type PublishedFilter int
+
+const (
+	AnyStatus PublishedFilter = iota
+	OnlyPublished
+	OnlyUnpublished
+)
+
+// Filter - условия отбора объявлений для AdRepository.FindAds. Условия объединяются через И,
+// незаданное поле выборку не ограничивает. Диапазоны времени полуоткрытые: [From, To)
+type Filter struct {
+	// AuthorIDs - объявление подходит, если его автор есть в списке
+	AuthorIDs []int64
+	// CategoryIDs - объявление подходит, если его категория есть в списке. Подкатегории сюда
+	// не попадают сами: их добавляет вызывающий (см. categories.Tree.Subtree)
+	CategoryIDs []int64
+	Published   PublishedFilter
+	// VisibleTo - неопубликованные объявления подходят, только если их автор VisibleTo. nil - подходят все
+	VisibleTo *int64
+	// Status - этап модерации, пустой - любой. Пустой статус объявления сравнивается как EffectiveStatus
+	Status Status
+
+	CreatedFrom  time.Time
+	CreatedTo    time.Time
+	ModifiedFrom time.Time
+	ModifiedTo   time.Time
+
+	// PublishBefore и ExpiresBefore оставляют объявления, у которых PublishAt или ExpiresAt задан и раньше границы
+	PublishBefore time.Time
+	ExpiresBefore time.Time
+
+	// TitleContains и TextContains ищут подстроку без учёта регистра
+	TitleContains string
+	TextContains  string
+
+	// Currency - валюта цены. PriceMin и PriceMax - границы цены в минимальных единицах, обе включаются.
+	// Цены в разных валютах не сравниваются: границы имеют смысл только вместе с Currency
+	Currency string
+	PriceMin *int64
+	PriceMax *int64
+
+	// Near - не дальше радиуса от точки, Box - внутри прямоугольника. Объявления без места им не подходят
+	Near *geo.Circle
+	Box  *geo.Box
+}
+
+// GeoBounds - прямоугольник, вне которого нет подходящих под Near и Box объявлений. ok false, если
+// географических условий нет. Хранилища берут из него кандидатов по геохеш-сетке, см. geo.Cover
+func (f Filter) GeoBounds() (b geo.Box, ok bool) {
+	switch {
+	case f.Near != nil && f.Box != nil:
+		b, ok = f.Near.Bounds().Intersect(*f.Box)
+		if !ok {
+			// пустое пересечение: прямоугольник, в который не попадёт ни одна точка
+			return geo.Box{MinLat: 1, MaxLat: -1}, true
+		}
+		return b, true
+	case f.Near != nil:
+		return f.Near.Bounds(), true
+	case f.Box != nil:
+		return *f.Box, true
+	}
+	return geo.Box{}, false
+}
+
+// Match проверяет объявление на соответствие фильтру. Хранилища, которые фильтруют сами,
+// должны давать тот же результат
+func (f Filter) Match(ad Ad) bool {
+	if len(f.AuthorIDs) > 0 && !containsID(f.AuthorIDs, ad.AuthorID) {
+		return false
+	}
+	if len(f.CategoryIDs) > 0 && !containsID(f.CategoryIDs, ad.CategoryID) {
+		return false
+	}
+	switch f.Published {
+	case OnlyPublished:
+		if !ad.Published {
+			return false
+		}
+	case OnlyUnpublished:
+		if ad.Published {
+			return false
+		}
+	}
+	if f.VisibleTo != nil && !ad.Published && ad.AuthorID != *f.VisibleTo {
+		return false
+	}
+	if f.Status != "" && ad.EffectiveStatus() != f.Status {
+		return false
+	}
+	if !inRange(ad.Created, f.CreatedFrom, f.CreatedTo) || !inRange(ad.Modified, f.ModifiedFrom, f.ModifiedTo) {
+		return false
+	}
+	if !dueBefore(ad.PublishAt, f.PublishBefore) || !dueBefore(ad.ExpiresAt, f.ExpiresBefore) {
+		return false
+	}
+	if f.TitleContains != "" && !strings.Contains(strings.ToLower(ad.Title), strings.ToLower(f.TitleContains)) {
+		return false
+	}
+	if f.TextContains != "" && !strings.Contains(strings.ToLower(ad.Text), strings.ToLower(f.TextContains)) {
+		return false
+	}
+	if f.Currency != "" && ad.Price.Currency != f.Currency {
+		return false
+	}
+	if f.PriceMin != nil && ad.Price.Amount < *f.PriceMin {
+		return false
+	}
+	if f.PriceMax != nil && ad.Price.Amount > *f.PriceMax {
+		return false
+	}
+	if f.Near != nil || f.Box != nil {
+		if ad.Location == nil {
+			return false
+		}
+		p := ad.Location.Point()
+		if f.Near != nil && !f.Near.Contains(p) || f.Box != nil && !f.Box.Contains(p) {
+			return false
+		}
+	}
+	return true
+}
+
+func containsID(ids []int64, id int64) bool {
+	for _, v := range ids {
+		if v == id {
+			return true
+		}
+	}
+	return false
+}
+
+func inRange(t time.Time, from time.Time, to time.Time) bool {
+	if !from.IsZero() && t.Before(from) {
+		return false
+	}
+	if !to.IsZero() && !t.Before(to) {
+		return false
+	}
+	return true
+}
+
+// dueBefore - срок t задан и наступает раньше before. Нулевая before условия не задаёт
+func dueBefore(t time.Time, before time.Time) bool {
+	return before.IsZero() || !t.IsZero() && t.Before(before)
+}
@@ -0,0 +1,157 @@
+package ads
+
+import (
+	"math"
+	"sort"
+	"time"
+
+	"homework9/internal/geo"
+)
+
+// SortField - поле, по которому упорядочивается выдача. При равных значениях порядок задаёт ID
+type SortField int
+
+const (
+	SortByID SortField = iota
+	SortByCreated
+	SortByModified
+	SortByTitle
+	// SortByPrice сравнивает суммы в минимальных единицах без учёта валюты, поэтому нужен вместе с Filter.Currency
+	SortByPrice
+	// SortByDistance - по расстоянию от Sort.Origin, объявления без места идут после остальных
+	SortByDistance
+)
+
+func (f SortField) Valid() bool {
+	return f >= SortByID && f <= SortByDistance
+}
+
+type Sort struct {
+	Field SortField
+	Desc  bool
+	// Origin - точка отсчёта для SortByDistance
+	Origin *geo.Point
+}
+
+// NoDistance - расстояние до объявления без места при сортировке по расстоянию
+const NoDistance = math.MaxFloat64
+
+// Distance - расстояние от p до объявления или NoDistance, если место не указано
+func Distance(p geo.Point, ad Ad) float64 {
+	if ad.Location == nil {
+		return NoDistance
+	}
+	return geo.Distance(p, ad.Location.Point())
+}
+
+// Cursor - позиция в выдаче: ключ сортировки последнего объявления предыдущей страницы.
+// Страница продолжается строго после него
+type Cursor struct {
+	ID       int64
+	Created  time.Time
+	Modified time.Time
+	Title    string
+	Price    int64
+	Distance float64
+}
+
+// CursorOf - ключи объявления для сортировки s. Расстояние заполняется только для SortByDistance
+func (s Sort) CursorOf(ad Ad) Cursor {
+	c := Cursor{ID: ad.ID, Created: ad.Created, Modified: ad.Modified, Title: ad.Title, Price: ad.Price.Amount}
+	if s.Field == SortByDistance && s.Origin != nil {
+		c.Distance = Distance(*s.Origin, ad)
+	}
+	return c
+}
+
+// Page - какую часть выдачи вернуть. Нулевой Page - вся выдача по возрастанию ID
+type Page struct {
+	Sort  Sort
+	After *Cursor
+	// Limit ограничивает число объявлений, 0 - без ограничения
+	Limit int
+}
+
+// Less сообщает, идёт ли a раньше b. Хранилища, которые сортируют сами, должны давать тот же порядок
+func (s Sort) Less(a Ad, b Ad) bool {
+	return s.before(s.CursorOf(a), s.CursorOf(b))
+}
+
+func (s Sort) before(a Cursor, b Cursor) bool {
+	c := s.compareKeys(a, b)
+	if c == 0 {
+		c = compareInt(a.ID, b.ID)
+	}
+	if s.Desc {
+		return c > 0
+	}
+	return c < 0
+}
+
+func (s Sort) compareKeys(a Cursor, b Cursor) int {
+	switch s.Field {
+	case SortByCreated:
+		return compareTime(a.Created, b.Created)
+	case SortByModified:
+		return compareTime(a.Modified, b.Modified)
+	case SortByTitle:
+		switch {
+		case a.Title < b.Title:
+			return -1
+		case a.Title > b.Title:
+			return 1
+		}
+	case SortByPrice:
+		return compareInt(a.Price, b.Price)
+	case SortByDistance:
+		switch {
+		case a.Distance < b.Distance:
+			return -1
+		case a.Distance > b.Distance:
+			return 1
+		}
+	}
+	return 0
+}
+
+// Apply упорядочивает отобранные объявления и вырезает из них страницу. list переиспользуется
+func (p Page) Apply(list []Ad) []Ad {
+	sortAds(list, p.Sort)
+	if p.After != nil {
+		i := 0
+		for i < len(list) && !p.Sort.before(*p.After, p.Sort.CursorOf(list[i])) {
+			i++
+		}
+		list = list[i:]
+	}
+	if p.Limit > 0 && len(list) > p.Limit {
+		list = list[:p.Limit]
+	}
+	return list
+}
+
+func compareInt(a int64, b int64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+// время сравнивается с точностью до наносекунды, как оно хранится в SQLite (нулевое время - 0)
+func compareTime(a time.Time, b time.Time) int {
+	return compareInt(nanos(a), nanos(b))
+}
+
+func nanos(t time.Time) int64 {
+	if t.IsZero() {
+		return 0
+	}
+	return t.UnixNano()
+}
+
+func sortAds(list []Ad, s Sort) {
+	sort.Slice(list, func(i, j int) bool { return s.Less(list[i], list[j]) })
+}
@@ -2,11 +2,22 @@ package ads
 
 import (
 	"context"
+	"errors"
 )
 
+var ErrNotFound = errors.New("ad not found")
+
+// ErrVersionConflict возвращается из UpdateById, если объявление успело измениться с момента чтения
+var ErrVersionConflict = errors.New("ad version conflict")
+
+// AdRepository выдаёт новому объявлению версию 1. UpdateById принимает объявление с той версией,
+// которую прочитал вызывающий, и увеличивает её на единицу
 type AdRepository interface {
 	GetAdById(ctx context.Context, id int64) (*Ad, error)
-	ListPublishedAds(ctx context.Context) ([]Ad, error)
+	// ListPublishedAds и FindAds возвращают страницу выдачи в порядке p.Sort (см. Page.Apply)
+	ListPublishedAds(ctx context.Context, p Page) ([]Ad, error)
+	// FindAds отбирает объявления, подходящие под f (см. Filter.Match)
+	FindAds(ctx context.Context, f Filter, p Page) ([]Ad, error)
 	GetAllAds(ctx context.Context) ([]Ad, error)
 	AddAd(ctx context.Context, ad Ad) (int64, error)
 	UpdateById(ctx context.Context, id int64, ad Ad) error
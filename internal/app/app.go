@@ -3,168 +3,443 @@ package app
 import (
 	"context"
 	"errors"
-	"homework9/internal/adapters/userrepo"
 	"homework9/internal/ads"
+	"homework9/internal/blobs"
+	"homework9/internal/categories"
+	"homework9/internal/checks"
+	"homework9/internal/events"
+	"homework9/internal/favorites"
+	"homework9/internal/messages"
+	"homework9/internal/money"
+	"homework9/internal/notifications"
+	"homework9/internal/reports"
+	"homework9/internal/search"
+	"homework9/internal/sessions"
 	"homework9/internal/users"
+	"io"
+	"log"
+	"sync"
 	"time"
 )
 
-type FilterOpts struct {
-	ID           int64
-	Title        string
-	AuthorID     int64
-	ModifiedTime time.Time
-	CreatedTime  time.Time
-	Hidden       bool
-}
+// FilterOpts - условия отбора объявлений, см. ads.Filter
+type FilterOpts = ads.Filter
 
+// App - сценарии сервиса. Методы, меняющие данные, выполняются от имени пользователя из контекста
+// (см. WithUserID) и без него возвращают ErrUnauthenticated
 type App interface {
-	CreateAd(ctx context.Context, title string, text string, authorId int64) (*ads.Ad, error)
-	UpdateStatusById(ctx context.Context, id int64, status bool, authorId int64) (*ads.Ad, error)
-	UpdateAdById(ctx context.Context, id int64, title string, text string, authorId int64) (*ads.Ad, error)
-	ListPublishedAds(ctx context.Context) ([]ads.Ad, error)
-	GetAdsByFilter(ctx context.Context, opts FilterOpts) ([]ads.Ad, error)
-	DeleteAd(ctx context.Context, id int64, userId int64) error
+	// CreateAd создаёт объявление в существующей категории categoryID. location nil - объявление без места
+	CreateAd(ctx context.Context, title string, text string, categoryID int64, price ads.Price, location *ads.Location) (*ads.Ad, error)
+	// UpdateStatusById публикует или снимает объявление. С модерацией (WithModeration) публикация черновика
+	// или отклонённого объявления отправляет его на проверку. При публикации отменяется запланированная
+	// публикация, а объявление без срока получает срок жизни из WithAdLifetime. Истёкшее объявление
+	// сначала продлевают (RenewAd). Снятое опубликованное объявление уходит в архив
+	UpdateStatusById(ctx context.Context, id int64, status bool) (*ads.Ad, error)
+	// ScheduleAd назначает публикацию и снятие объявления, нулевое время отменяет срок
+	ScheduleAd(ctx context.Context, id int64, publishAt time.Time, expiresAt time.Time) (*ads.Ad, error)
+	// RenewAd продлевает объявление и снова публикует истёкшее
+	RenewAd(ctx context.Context, id int64) (*ads.Ad, error)
+	// RunSchedule - один проход планировщика, см. Scheduler
+	RunSchedule(ctx context.Context) (ScheduleResult, error)
+	// UpdateAdById обновляет объявление, если его текущая версия равна version. Нулевая version - без проверки,
+	// нулевая categoryID оставляет прежнюю категорию, nil price - прежнюю цену, nil location - прежнее место.
+	// С модерацией изменённое опубликованное объявление снимается и возвращается на проверку
+	UpdateAdById(ctx context.Context, id int64, title string, text string, categoryID int64, price *ads.Price, location *ads.Location, version int64) (*ads.Ad, error)
+	// GetAdByID возвращает объявление. Неопубликованное объявление видят только автор и модераторы,
+	// для остальных его нет (ErrNotFound). Решение модератора и замечания проверок тоже видны только им
+	GetAdByID(ctx context.Context, id int64) (*ads.Ad, error)
+	// ReviewQueue, ApproveAd и RejectAd доступны модераторам и администраторам, кроме проверки своих объявлений.
+	// Очередь - объявления на проверке по возрастанию ID. Отклонение требует причину, автор получает уведомление
+	ReviewQueue(ctx context.Context, p PageRequest) (*AdsPage, error)
+	ApproveAd(ctx context.Context, id int64, reason string) (*ads.Ad, error)
+	RejectAd(ctx context.Context, id int64, reason string) (*ads.Ad, error)
+	// CreateReport - жалоба пользователя из контекста на объявление или пользователя. На одну цель пользователь
+	// жалуется один раз, на себя и свои объявления - нельзя. Опубликованное объявление, на которое набралось
+	// WithReportThreshold открытых жалоб, снимается с публикации, а автор получает уведомление
+	CreateReport(ctx context.Context, target reports.Target, targetID int64, reason reports.Reason, comment string) (*reports.Report, error)
+	// ListReports, ResolveReport и DismissReport доступны модераторам и администраторам. Жалобы идут по возрастанию ID,
+	// закрыть можно только открытую жалобу, кроме жалоб на себя и свои объявления
+	ListReports(ctx context.Context, f reports.Filter, p PageRequest) (*ReportsPage, error)
+	ResolveReport(ctx context.Context, id int64, note string) (*reports.Report, error)
+	DismissReport(ctx context.Context, id int64, note string) (*reports.Report, error)
+	// AddFavorite добавляет опубликованное объявление в избранное пользователя из контекста, повторное добавление
+	// возвращает прежнюю запись. ListFavorites возвращает избранное от новых к старым вместе с объявлениями:
+	// удалённое объявление остаётся в списке с пустым Ad, снятое - с Available() == false
+	AddFavorite(ctx context.Context, adID int64) (*FavoriteAd, error)
+	RemoveFavorite(ctx context.Context, adID int64) error
+	ListFavorites(ctx context.Context, p PageRequest) (*FavoritesPage, error)
+	// FavoriteCounts - у скольких пользователей каждое из объявлений в избранном, нули в ответ не попадают
+	FavoriteCounts(ctx context.Context, adIDs []int64) (map[int64]int, error)
+	// ContactSeller пишет автору объявления от пользователя из контекста: первое сообщение начинает переписку
+	// (только по опубликованному объявлению), следующие продолжают её. SendMessage отвечает в переписке, где
+	// пользователь участвует. Если кто-то из собеседников заблокировал другого, писать нельзя
+	ContactSeller(ctx context.Context, adID int64, text string) (*messages.Message, error)
+	SendMessage(ctx context.Context, threadID int64, text string) (*messages.Message, error)
+	// ListThreads - входящие пользователя из контекста как продавца и как покупателя, сначала переписки с более
+	// новым сообщением. adID, если задан, оставляет переписки об одном объявлении
+	ListThreads(ctx context.Context, adID *int64, p PageRequest) (*ThreadsPage, error)
+	// ListMessages возвращает сообщения переписки от старых к новым, MarkThreadRead отмечает прочитанной всю переписку
+	ListMessages(ctx context.Context, threadID int64, p PageRequest) (*MessagesPage, error)
+	MarkThreadRead(ctx context.Context, threadID int64) (*ThreadView, error)
+	// BlockUser и UnblockUser закрывают и открывают переписку пользователя из контекста с другим пользователем
+	BlockUser(ctx context.Context, userID int64) error
+	UnblockUser(ctx context.Context, userID int64) error
+	// SubscribeEvents подписывает пользователя из контекста на события: сообщения ему, смену статуса объявлений из его
+	// избранного и опубликованные объявления под фильтр подписки. buffer - сколько событий может ждать доставки,
+	// подписка, которая не успевает, закрывается с events.ErrSlowSubscriber. Подписку нужно закрыть
+	SubscribeEvents(ctx context.Context, buffer int) (*EventSubscription, error)
+	// WatchAds подписывает на создание, изменение, смену статуса и удаление объявлений, подходящих под opts
+	// до или после изменения. Неопубликованные объявления видны по правилам GetAdByID, см. AdWatch.View.
+	// Непустой resumeToken (AdWatch.ResumeToken или Start прошлого наблюдения) продолжает с места, где оно
+	// остановилось: пропущенные события придут в Backlog. Если их уже не осталось - ErrResumeTokenExpired.
+	// Подписку нужно закрыть
+	WatchAds(ctx context.Context, opts FilterOpts, resumeToken string, buffer int) (*AdWatch, error)
+	ListPublishedAds(ctx context.Context, p PageRequest) (*AdsPage, error)
+	// GetAdsByFilter отбирает объявления по opts. Категории из opts.CategoryIDs берутся вместе с подкатегориями,
+	// границы цены без opts.Currency относятся к базовой валюте. Неопубликованные объявления попадают в выдачу,
+	// только если их видно по правилам GetAdByID
+	GetAdsByFilter(ctx context.Context, opts FilterOpts, p PageRequest) (*AdsPage, error)
+	// SearchAds ищет опубликованные объявления по словам из заголовка и текста и возвращает не больше limit
+	// самых релевантных. Нулевой limit - DefaultSearchLimit. Ненулевая categoryID оставляет только объявления
+	// из этой категории и её подкатегорий
+	SearchAds(ctx context.Context, query string, categoryID int64, limit int) ([]SearchResult, error)
+	// DeleteAd удаляет объявление вместе с файлами его фотографий
+	DeleteAd(ctx context.Context, id int64) error
+	// ConvertPrice переводит цену в валюту показа по локальной таблице курсов
+	ConvertPrice(ctx context.Context, p ads.Price, currency string) (ads.Price, error)
+
+	// AddAdImage добавляет фотографию в конец списка объявления. Формат (jpeg, png, gif) определяется по содержимому
+	AddAdImage(ctx context.Context, adID int64, r io.Reader) (*ads.Ad, error)
+	DeleteAdImage(ctx context.Context, adID int64, imageID string) (*ads.Ad, error)
+	// ReorderAdImages задаёт новый порядок фотографий, imageIDs перечисляет все фотографии объявления
+	ReorderAdImages(ctx context.Context, adID int64, imageIDs []string) (*ads.Ad, error)
+	// OpenAdImage открывает оригинал или превью (thumb) фотографии, вызывающий закрывает файл.
+	// Фотографии скрытого объявления - ErrNotFound, как и оно само
+	OpenAdImage(ctx context.Context, adID int64, imageID string, thumb bool) (io.ReadCloser, ads.Image, error)
 
 	GetUserByID(ctx context.Context, userID int64) (*users.User, error)
-	CreateUser(ctx context.Context, nickname string, email string) (*users.User, error)
-	UpdateUserByID(ctx context.Context, updatedID int64, nickname string, email string, updaterID int64) (*users.User, error)
+	// CreateUser регистрирует пользователя. Email должен быть уникальным
+	CreateUser(ctx context.Context, nickname string, email string, password string) (*users.User, error)
+	UpdateUserByID(ctx context.Context, updatedID int64, nickname string, email string) (*users.User, error)
+	// DeleteUser удаляет пользователя id. Удалить можно себя, администратор может удалить любого
 	DeleteUser(ctx context.Context, id int64) error
+	// SetUserRole меняет роль пользователя. Доступно администраторам
+	SetUserRole(ctx context.Context, id int64, role users.Role) (*users.User, error)
+
+	// ListCategories возвращает всё дерево категорий плоским списком по возрастанию ID
+	ListCategories(ctx context.Context) ([]categories.Category, error)
+	GetCategory(ctx context.Context, id int64) (*categories.Category, error)
+	// CreateCategory, UpdateCategory и DeleteCategory доступны администраторам. Нулевой parentID - корневая категория
+	CreateCategory(ctx context.Context, name string, parentID int64) (*categories.Category, error)
+	UpdateCategory(ctx context.Context, id int64, name string, parentID int64) (*categories.Category, error)
+	// DeleteCategory удаляет только пустую категорию: без подкатегорий и объявлений
+	DeleteCategory(ctx context.Context, id int64) error
+
+	Login(ctx context.Context, email string, password string) (*Tokens, error)
+	Refresh(ctx context.Context, refreshToken string) (*Tokens, error)
+	Authenticate(ctx context.Context, accessToken string) (int64, error)
+	Logout(ctx context.Context, refreshToken string) error
 }
 
 var ErrAccessDenied = errors.New("forbidden")
 var ErrNotFound = errors.New("not found")
+var ErrVersionConflict = errors.New("version conflict")
+var ErrInvalidRole = errors.New("unknown role")
 
 type MyApp struct {
 	adRepository   ads.AdRepository
 	userRepository users.UserRepository
+	categories     categories.CategoryRepository
+	uow            UnitOfWork
+	policy         Policy
+	index          *search.Index
+	rates          *money.Rates
+	blobs          blobs.BlobStore
+	imageLimits    ImageLimits
+	// now - часы приложения, по ним ставятся даты объявлений и наступают сроки
+	now        func() time.Time
+	adLifetime time.Duration
+	// moderation - публикация только после одобрения модератором
+	moderation bool
+	notifier   notifications.Notifier
+	checkers   []checks.Checker
+	reports    reports.ReportRepository
+	// reportThreshold - после скольких открытых жалоб объявление снимается с публикации
+	reportThreshold int
+	favorites       favorites.FavoriteRepository
+	messages        messages.MessageRepository
+	events          *events.Hub
+	// adWrites выстраивает записи объявлений в очередь, см. writeAds
+	adWrites *sync.Mutex
+	// categoryWrites выстраивает в очередь изменения категорий: каждое проверяется по дереву, которое не изменится до записи
+	categoryWrites *sync.Mutex
+	// adminEmails получают роль администратора при регистрации
+	adminEmails map[string]bool
+
+	sessions     sessions.SessionRepository
+	accessTokens AccessTokens
+	accessTTL    time.Duration
+	refreshTTL   time.Duration
+	passwordCost int
+	// dummyHash сравнивается с паролем, когда пользователя с таким email нет, чтобы по времени ответа
+	// нельзя было понять, зарегистрирован ли email. Поэтому он посчитан с той же стоимостью, что и настоящие
+	dummyHash []byte
 }
 
-func NewApp(adRepo ads.AdRepository, userRepo users.UserRepository) App {
-	return MyApp{adRepository: adRepo, userRepository: userRepo}
+func NewApp(adRepo ads.AdRepository, userRepo users.UserRepository, categoryRepo categories.CategoryRepository, uow UnitOfWork, opts ...Option) App {
+	m := defaultApp()
+	m.adRepository, m.userRepository, m.categories, m.uow = adRepo, userRepo, categoryRepo, uow
+	for _, opt := range opts {
+		opt(&m)
+	}
+	m.dummyHash = dummyHash(m.passwordCost)
+	return m
 }
 
-func (m MyApp) CreateAd(ctx context.Context, title string, text string, authorId int64) (*ads.Ad, error) {
-	a := ads.Ad{Title: title, Text: text, AuthorID: authorId, Published: false, Created: time.Now(), Modified: time.Now()}
-	id, err := m.adRepository.AddAd(ctx, a)
+func (m MyApp) CreateAd(ctx context.Context, title string, text string, categoryID int64, price ads.Price, location *ads.Location) (*ads.Ad, error) {
+	authorId, err := actingUser(ctx)
 	if err != nil {
 		return nil, err
 	}
-
-	a.ID = id
-
-	return &a, nil
-}
-
-func (m MyApp) UpdateStatusById(ctx context.Context, id int64, status bool, authorId int64) (*ads.Ad, error) {
-	a, err := m.adRepository.GetAdById(ctx, id)
+	price, err = normalizePrice(price)
 	if err != nil {
 		return nil, err
 	}
-	if a.AuthorID != authorId {
-		return nil, ErrAccessDenied
+	if location != nil {
+		l, err := normalizeLocation(*location)
+		if err != nil {
+			return nil, err
+		}
+		location = &l
 	}
-	changed := ads.Ad{
-		Title:     a.Title,
-		Text:      a.Text,
-		AuthorID:  a.AuthorID,
-		Published: status,
-		Created:   a.Created,
+	if err := m.requireCategory(ctx, categoryID); err != nil {
+		return nil, err
 	}
-	err = m.adRepository.UpdateById(ctx, id, changed)
+	flags, err := m.checkContent(ctx, checks.Submission{New: true, AuthorID: authorId, Title: title, Text: text})
 	if err != nil {
 		return nil, err
 	}
-	return &changed, nil
-}
-
-func (m MyApp) UpdateAdById(ctx context.Context, id int64, title string, text string, authorId int64) (*ads.Ad, error) {
-	a, err := m.adRepository.GetAdById(ctx, id)
+	a := ads.Ad{Title: title, Text: text, AuthorID: authorId, CategoryID: categoryID, Price: price, Location: location, Status: ads.StatusDraft, Flags: flags, Created: m.now(), Modified: m.now()}
+	err = m.writeAdsIn(ctx, categoryID, func(adRepo ads.AdRepository, userRepo users.UserRepository) error {
+		if _, err := m.authorize(ctx, userRepo, ActionCreateAd, authorId); err != nil {
+			return err
+		}
+		id, err := adRepo.AddAd(ctx, a)
+		a.ID = id
+		return err
+	}, func() {
+		m.index.Put(a.ID, a.Title, a.Text)
+		a.Version = 1
+		m.adChanged(ctx, nil, &a)
+	})
 	if err != nil {
 		return nil, err
 	}
-	if a.AuthorID != authorId {
-		return nil, ErrAccessDenied
+	return &a, nil
+}
+
+func (m MyApp) UpdateStatusById(ctx context.Context, id int64, status bool) (*ads.Ad, error) {
+	action := ActionUnpublishAd
+	if status {
+		action = ActionPublishAd
 	}
-	changed := ads.Ad{
-		Title:     title,
-		Text:      text,
-		AuthorID:  a.AuthorID,
-		Published: a.Published,
-		Created:   a.Created,
-		Modified:  time.Now(),
+	reported := 0
+	if status {
+		var err error
+		if reported, err = m.reportedAd(ctx, id); err != nil {
+			return nil, err
+		}
 	}
-	err = m.adRepository.UpdateById(ctx, id, changed)
+	var changed, was ads.Ad
+	err := m.writeAds(ctx, func(adRepo ads.AdRepository, userRepo users.UserRepository) error {
+		a, err := adRepo.GetAdById(ctx, id)
+		if err != nil {
+			return err
+		}
+		if _, err := m.authorize(ctx, userRepo, action, a.AuthorID); err != nil {
+			return err
+		}
+		changed, was = *a, *a
+		if !status {
+			withdraw(&changed)
+			return adRepo.UpdateById(ctx, id, changed)
+		}
+		now := m.now()
+		if !a.Published && expired(*a, now) {
+			return ErrAdExpired
+		}
+		m.requestPublication(&changed, now, reported > 0)
+		return adRepo.UpdateById(ctx, id, changed)
+	}, func() {
+		changed.Version++
+		m.adChanged(ctx, &was, &changed)
+	})
 	if err != nil {
-		return nil, err
+		return nil, adError(err)
 	}
 	return &changed, nil
 }
 
-func (m MyApp) ListPublishedAds(ctx context.Context) ([]ads.Ad, error) {
-	res, err := m.adRepository.ListPublishedAds(ctx)
+func (m MyApp) UpdateAdById(ctx context.Context, id int64, title string, text string, categoryID int64, price *ads.Price, location *ads.Location, version int64) (*ads.Ad, error) {
+	if price != nil {
+		p, err := normalizePrice(*price)
+		if err != nil {
+			return nil, err
+		}
+		price = &p
+	}
+	if location != nil {
+		l, err := normalizeLocation(*location)
+		if err != nil {
+			return nil, err
+		}
+		location = &l
+	}
+	if categoryID != 0 {
+		if err := m.requireCategory(ctx, categoryID); err != nil {
+			return nil, err
+		}
+	}
+	flags, err := m.checkUpdate(ctx, id, title, text)
 	if err != nil {
 		return nil, err
 	}
-	return res, nil
+	var changed, was ads.Ad
+	err = m.writeAdsIn(ctx, categoryID, func(adRepo ads.AdRepository, userRepo users.UserRepository) error {
+		a, err := adRepo.GetAdById(ctx, id)
+		if err != nil {
+			return err
+		}
+		if _, err := m.authorize(ctx, userRepo, ActionUpdateAd, a.AuthorID); err != nil {
+			return err
+		}
+		if version != 0 && a.Version != version {
+			return ErrVersionConflict
+		}
+		was = *a
+		changed = ads.Ad{
+			ID:         id,
+			Title:      title,
+			Text:       text,
+			AuthorID:   a.AuthorID,
+			CategoryID: a.CategoryID,
+			Price:      a.Price,
+			Images:     a.Images,
+			Location:   a.Location,
+			Status:     a.Status,
+			Published:  a.Published,
+			Review:     a.Review,
+			Flags:      flags,
+			PublishAt:  a.PublishAt,
+			ExpiresAt:  a.ExpiresAt,
+			Created:    a.Created,
+			Modified:   m.now(),
+			Version:    a.Version,
+		}
+		if categoryID != 0 {
+			changed.CategoryID = categoryID
+		}
+		if price != nil {
+			changed.Price = *price
+		}
+		if location != nil {
+			changed.Location = location
+		}
+		m.contentChanged(&changed)
+		return adRepo.UpdateById(ctx, id, changed)
+	}, func() {
+		m.index.Put(id, title, text)
+		changed.Version++
+		// исправленное опубликованное объявление может снова уйти на проверку
+		m.adChanged(ctx, &was, &changed)
+	})
+	if err != nil {
+		return nil, adError(err)
+	}
+	return &changed, nil
 }
 
-func (m MyApp) CreateUser(ctx context.Context, nickname string, email string) (*users.User, error) {
-	u := users.User{Nickname: nickname, Email: email}
-	id, err := m.userRepository.AddUser(ctx, u)
+func (m MyApp) GetAdByID(ctx context.Context, id int64) (*ads.Ad, error) {
+	v, err := m.viewer(ctx)
 	if err != nil {
 		return nil, err
 	}
-	u.ID = id
-	return &u, nil
+	a, err := m.adRepository.GetAdById(ctx, id)
+	if err != nil {
+		return nil, adError(err)
+	}
+	if !v.canSee(*a) {
+		return nil, ErrNotFound
+	}
+	v.redact(a)
+	return a, nil
 }
 
-func (m MyApp) UpdateUserByID(ctx context.Context, updatedID int64, nickname string, email string, updaterID int64) (*users.User, error) {
-	u, err := m.userRepository.GetUserByID(ctx, updatedID)
+func (m MyApp) CreateUser(ctx context.Context, nickname string, email string, password string) (*users.User, error) {
+	email = normalizeEmail(email)
+	if !validEmail(email) {
+		return nil, ErrInvalidEmail
+	}
+	hash, err := m.hashPassword(password)
 	if err != nil {
 		return nil, err
 	}
-	if u.ID != updaterID {
-		return nil, ErrAccessDenied
+
+	u := users.User{Nickname: nickname, Email: email, PasswordHash: hash, Role: users.RoleUser}
+	if m.adminEmails[email] {
+		u.Role = users.RoleAdmin
 	}
-	changed := users.User{Nickname: nickname, Email: email}
-	err = m.userRepository.UpdateByID(ctx, updatedID, changed)
+	err = m.uow.Do(ctx, func(_ ads.AdRepository, userRepo users.UserRepository) error {
+		if err := checkEmailFree(ctx, userRepo, email, -1); err != nil {
+			return err
+		}
+		id, err := userRepo.AddUser(ctx, u)
+		u.ID = id
+		return err
+	})
 	if err != nil {
 		return nil, err
 	}
-	return &changed, nil
+	u.Version = 1
+	return &u, nil
 }
 
-func (m MyApp) GetAdsByFilter(ctx context.Context, opts FilterOpts) ([]ads.Ad, error) {
-	adsAll, err := m.adRepository.GetAllAds(ctx)
-	if err != nil {
-		return nil, err
+func (m MyApp) UpdateUserByID(ctx context.Context, updatedID int64, nickname string, email string) (*users.User, error) {
+	email = normalizeEmail(email)
+	if !validEmail(email) {
+		return nil, ErrInvalidEmail
 	}
-	adsFiltered := make([]ads.Ad, 0)
-	for _, ad := range adsAll {
-		if opts.ID != 0 && ad.ID != opts.ID {
-			continue
+	changed := users.User{ID: updatedID, Nickname: nickname, Email: email}
+	err := m.uow.Do(ctx, func(_ ads.AdRepository, userRepo users.UserRepository) error {
+		u, err := userRepo.GetUserByID(ctx, updatedID)
+		if err != nil {
+			return err
 		}
-		if opts.Title != "" && ad.Title != opts.Title {
-			continue
+		if _, err := m.authorize(ctx, userRepo, ActionUpdateUser, u.ID); err != nil {
+			return err
 		}
-		if opts.AuthorID != 0 && ad.AuthorID != opts.AuthorID {
-			continue
+		if err := checkEmailFree(ctx, userRepo, email, u.ID); err != nil {
+			return err
 		}
-		if !opts.CreatedTime.IsZero() && !ad.Created.Equal(opts.CreatedTime) {
-			continue
-		}
-		if !opts.ModifiedTime.IsZero() && !ad.Modified.Equal(opts.ModifiedTime) {
-			continue
-		}
-		adsFiltered = append(adsFiltered, ad)
+		changed.PasswordHash = u.PasswordHash
+		changed.Role = u.EffectiveRole()
+		changed.Version = u.Version
+		return userRepo.UpdateByID(ctx, updatedID, changed)
+	})
+	if errors.Is(err, users.ErrNotFound) {
+		return nil, ErrNotFound
+	} else if errors.Is(err, users.ErrVersionConflict) {
+		return nil, ErrVersionConflict
+	} else if err != nil {
+		return nil, err
 	}
-	return adsFiltered, nil
+	changed.Version++
+	return &changed, nil
 }
 
 func (m MyApp) GetUserByID(ctx context.Context, userID int64) (*users.User, error) {
 	u, err := m.userRepository.GetUserByID(ctx, userID)
-	if err == userrepo.ErrNotFound {
+	if errors.Is(err, users.ErrNotFound) {
 		return nil, ErrNotFound
 	} else if err != nil {
 		return nil, err
@@ -172,26 +447,123 @@ func (m MyApp) GetUserByID(ctx context.Context, userID int64) (*users.User, erro
 	return u, nil
 }
 
-func (m MyApp) DeleteAd(ctx context.Context, id int64, userId int64) error {
-	ad, err := m.adRepository.GetAdById(ctx, id)
+func (m MyApp) DeleteAd(ctx context.Context, id int64) error {
+	var images []ads.Image
+	var was ads.Ad
+	err := m.writeAds(ctx, func(adRepo ads.AdRepository, userRepo users.UserRepository) error {
+		ad, err := adRepo.GetAdById(ctx, id)
+		if errors.Is(err, ads.ErrNotFound) {
+			return ErrNotFound
+		} else if err != nil {
+			return err
+		}
+		if _, err := m.authorize(ctx, userRepo, ActionDeleteAd, ad.AuthorID); err != nil {
+			return err
+		}
+		images, was = ad.Images, *ad
+		return adRepo.DeleteAdById(ctx, id)
+	}, func() {
+		m.index.Remove(id)
+		m.adChanged(ctx, &was, nil)
+	})
 	if err != nil {
 		return err
 	}
-	if ad.AuthorID != userId {
-		return ErrAccessDenied
-	}
-	if err == ErrNotFound {
-		return err
-	}
-	m.adRepository.DeleteAd(ctx, id)
+	m.deleteImageBlobs(ctx, images)
 	return nil
 }
 
+// DeleteUser удаляет пользователя вместе со всеми его объявлениями в одной транзакции и отзывает его refresh-токены.
+// Файлы фотографий удаляются после транзакции
 func (m MyApp) DeleteUser(ctx context.Context, id int64) error {
-	_, err := m.userRepository.GetUserByID(ctx, id)
-	if err == ErrNotFound {
+	var deleted []ads.Ad
+	var images []ads.Image
+	err := m.writeAds(ctx, func(adRepo ads.AdRepository, userRepo users.UserRepository) error {
+		deleted, images = deleted[:0], images[:0]
+		_, err := userRepo.GetUserByID(ctx, id)
+		if errors.Is(err, users.ErrNotFound) {
+			return ErrNotFound
+		} else if err != nil {
+			return err
+		}
+		if _, err := m.authorize(ctx, userRepo, ActionDeleteUser, id); err != nil {
+			return err
+		}
+
+		all, err := adRepo.GetAllAds(ctx)
+		if err != nil {
+			return err
+		}
+		for _, ad := range all {
+			if ad.AuthorID != id {
+				continue
+			}
+			if err := adRepo.DeleteAdById(ctx, ad.ID); err != nil {
+				return err
+			}
+			deleted = append(deleted, ad)
+			images = append(images, ad.Images...)
+		}
+		userRepo.DeleteUser(ctx, id)
+		return nil
+	}, func() {
+		for i := range deleted {
+			m.index.Remove(deleted[i].ID)
+			m.adChanged(ctx, &deleted[i], nil)
+		}
+	})
+	if err != nil {
 		return err
 	}
-	m.userRepository.DeleteUser(ctx, id)
+	m.deleteImageBlobs(ctx, images)
+	if m.sessions != nil {
+		m.sessions.DeleteUserSessions(ctx, id)
+	}
+	if m.favorites != nil {
+		// пользователь уже удалён, поэтому оставшееся избранное только завышает счётчики
+		if err := m.favorites.DeleteUserFavorites(ctx, id); err != nil {
+			log.Printf("delete favorites of user %d: %v", id, err)
+		}
+	}
 	return nil
 }
+
+func (m MyApp) SetUserRole(ctx context.Context, id int64, role users.Role) (*users.User, error) {
+	if !role.Valid() {
+		return nil, ErrInvalidRole
+	}
+	var changed users.User
+	err := m.uow.Do(ctx, func(_ ads.AdRepository, userRepo users.UserRepository) error {
+		u, err := userRepo.GetUserByID(ctx, id)
+		if err != nil {
+			return err
+		}
+		if _, err := m.authorize(ctx, userRepo, ActionSetRole, u.ID); err != nil {
+			return err
+		}
+		changed = *u
+		changed.Role = role
+		return userRepo.UpdateByID(ctx, id, changed)
+	})
+	if errors.Is(err, users.ErrNotFound) {
+		return nil, ErrNotFound
+	} else if errors.Is(err, users.ErrVersionConflict) {
+		return nil, ErrVersionConflict
+	} else if err != nil {
+		return nil, err
+	}
+	changed.Version++
+	return &changed, nil
+}
+
+// adError переводит ошибки репозитория объявлений в ошибки приложения
+func adError(err error) error {
+	switch {
+	case errors.Is(err, ads.ErrNotFound):
+		return ErrNotFound
+	case errors.Is(err, ads.ErrVersionConflict):
+		return ErrVersionConflict
+	default:
+		return err
+	}
+}
@@ -0,0 +1,163 @@
+package app
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+
+	"homework9/internal/sessions"
+	"homework9/internal/users"
+)
+
+var ErrUnauthenticated = errors.New("unauthenticated")
+var ErrInvalidCredentials = errors.New("invalid email or password")
+var ErrEmailTaken = errors.New("email is already registered")
+var ErrInvalidEmail = errors.New("invalid email")
+var ErrInvalidPassword = errors.New("password must be from 8 to 72 bytes long")
+
+// AccessTokens выпускает подписанные access-токены и проверяет их без обращения к хранилищу
+type AccessTokens interface {
+	Issue(userID int64, expires time.Time) (string, error)
+	Verify(token string) (int64, error)
+}
+
+// Tokens - пара токенов, выданная при входе или обновлении.
+// Refresh-токен показывается клиенту один раз, сервер хранит только его хеш
+type Tokens struct {
+	UserID         int64
+	AccessToken    string
+	AccessExpires  time.Time
+	RefreshToken   string
+	RefreshExpires time.Time
+}
+
+// dummyHash - хеш пароля, которого нет ни у кого, со стоимостью cost
+func dummyHash(cost int) []byte {
+	hash, _ := bcrypt.GenerateFromPassword([]byte("dummy password"), cost)
+	return hash
+}
+
+func normalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+func validEmail(email string) bool {
+	at := strings.Index(email, "@")
+	return at > 0 && at < len(email)-1
+}
+
+func (m MyApp) hashPassword(password string) (string, error) {
+	if len(password) < 8 || len(password) > 72 {
+		return "", ErrInvalidPassword
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), m.passwordCost)
+	if err != nil {
+		return "", err
+	}
+	return string(hash), nil
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// Login проверяет пароль и выдаёт пару токенов
+func (m MyApp) Login(ctx context.Context, email string, password string) (*Tokens, error) {
+	if m.sessions == nil || m.accessTokens == nil {
+		return nil, errors.New("authentication is not configured")
+	}
+	u, err := m.userRepository.GetUserByEmail(ctx, normalizeEmail(email))
+	if errors.Is(err, users.ErrNotFound) {
+		_ = bcrypt.CompareHashAndPassword(m.dummyHash, []byte(password))
+		return nil, ErrInvalidCredentials
+	} else if err != nil {
+		return nil, err
+	}
+	if u.PasswordHash == "" || bcrypt.CompareHashAndPassword([]byte(u.PasswordHash), []byte(password)) != nil {
+		return nil, ErrInvalidCredentials
+	}
+	return m.issueTokens(ctx, u.ID)
+}
+
+// Refresh обменивает refresh-токен на новую пару. Старый refresh-токен при этом перестаёт действовать:
+// сессия забирается из хранилища одной операцией, поэтому из одновременных обменов одного токена удаётся один
+func (m MyApp) Refresh(ctx context.Context, refreshToken string) (*Tokens, error) {
+	if m.sessions == nil || m.accessTokens == nil || refreshToken == "" {
+		return nil, ErrUnauthenticated
+	}
+	s, err := m.sessions.TakeSession(ctx, hashToken(refreshToken))
+	if errors.Is(err, sessions.ErrNotFound) {
+		return nil, ErrUnauthenticated
+	} else if err != nil {
+		return nil, err
+	}
+	if !time.Now().Before(s.Expires) {
+		return nil, ErrUnauthenticated
+	}
+	return m.issueTokens(ctx, s.UserID)
+}
+
+func (m MyApp) issueTokens(ctx context.Context, userID int64) (*Tokens, error) {
+	now := time.Now()
+	t := Tokens{UserID: userID, AccessExpires: now.Add(m.accessTTL), RefreshExpires: now.Add(m.refreshTTL)}
+
+	var err error
+	t.AccessToken, err = m.accessTokens.Issue(userID, t.AccessExpires)
+	if err != nil {
+		return nil, err
+	}
+
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return nil, err
+	}
+	t.RefreshToken = base64.RawURLEncoding.EncodeToString(raw)
+	s := sessions.Session{TokenHash: hashToken(t.RefreshToken), UserID: userID, Created: now, Expires: t.RefreshExpires}
+	if err := m.sessions.AddSession(ctx, s); err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+// Authenticate проверяет access-токен и возвращает ID его владельца
+func (m MyApp) Authenticate(_ context.Context, accessToken string) (int64, error) {
+	if m.accessTokens == nil || accessToken == "" {
+		return 0, ErrUnauthenticated
+	}
+	userID, err := m.accessTokens.Verify(accessToken)
+	if err != nil {
+		return 0, ErrUnauthenticated
+	}
+	return userID, nil
+}
+
+// Logout отзывает refresh-токен. Выданные access-токены действуют до истечения.
+// Повторный выход с тем же токеном не считается ошибкой
+func (m MyApp) Logout(ctx context.Context, refreshToken string) error {
+	if m.sessions != nil {
+		m.sessions.DeleteSession(ctx, hashToken(refreshToken))
+	}
+	return nil
+}
+
+// checkEmailFree проверяет, что email не занят другим пользователем. Вызывается внутри транзакции
+func checkEmailFree(ctx context.Context, userRepo users.UserRepository, email string, self int64) error {
+	u, err := userRepo.GetUserByEmail(ctx, email)
+	if errors.Is(err, users.ErrNotFound) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	if u.ID != self {
+		return ErrEmailTaken
+	}
+	return nil
+}
@@ -0,0 +1,170 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"unicode/utf8"
+
+	"homework9/internal/ads"
+	"homework9/internal/categories"
+)
+
+var (
+	ErrInvalidCategory  = errors.New("unknown category")
+	ErrCategoryName     = errors.New("category name must be non-empty and unique among siblings")
+	ErrCategoryCycle    = errors.New("category cannot be moved into its own subcategory")
+	ErrCategoryNotEmpty = errors.New("category has subcategories or ads")
+)
+
+const MaxCategoryNameLen = 100
+
+func (m MyApp) ListCategories(ctx context.Context) ([]categories.Category, error) {
+	return m.categories.ListCategories(ctx)
+}
+
+func (m MyApp) GetCategory(ctx context.Context, id int64) (*categories.Category, error) {
+	c, err := m.categories.GetCategory(ctx, id)
+	if errors.Is(err, categories.ErrNotFound) {
+		return nil, ErrNotFound
+	}
+	return c, err
+}
+
+func (m MyApp) CreateCategory(ctx context.Context, name string, parentID int64) (*categories.Category, error) {
+	if _, err := m.authorize(ctx, m.userRepository, ActionManageCategories, noOwner); err != nil {
+		return nil, err
+	}
+	m.categoryWrites.Lock()
+	defer m.categoryWrites.Unlock()
+	tree, err := m.categoryTree(ctx)
+	if err != nil {
+		return nil, err
+	}
+	c := categories.Category{Name: strings.TrimSpace(name), ParentID: parentID}
+	if err := checkPlacement(tree, c); err != nil {
+		return nil, err
+	}
+	c.ID, err = m.categories.AddCategory(ctx, c)
+	if err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+func (m MyApp) UpdateCategory(ctx context.Context, id int64, name string, parentID int64) (*categories.Category, error) {
+	if _, err := m.authorize(ctx, m.userRepository, ActionManageCategories, noOwner); err != nil {
+		return nil, err
+	}
+	m.categoryWrites.Lock()
+	defer m.categoryWrites.Unlock()
+	tree, err := m.categoryTree(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := tree.Get(id); !ok {
+		return nil, ErrNotFound
+	}
+	c := categories.Category{ID: id, Name: strings.TrimSpace(name), ParentID: parentID}
+	if parentID != 0 && tree.IsWithin(parentID, id) {
+		return nil, ErrCategoryCycle
+	}
+	if err := checkPlacement(tree, c); err != nil {
+		return nil, err
+	}
+	err = m.categories.UpdateCategory(ctx, id, c)
+	if errors.Is(err, categories.ErrNotFound) {
+		return nil, ErrNotFound
+	} else if err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+func (m MyApp) DeleteCategory(ctx context.Context, id int64) error {
+	if _, err := m.authorize(ctx, m.userRepository, ActionManageCategories, noOwner); err != nil {
+		return err
+	}
+	m.categoryWrites.Lock()
+	defer m.categoryWrites.Unlock()
+	tree, err := m.categoryTree(ctx)
+	if err != nil {
+		return err
+	}
+	if _, ok := tree.Get(id); !ok {
+		return ErrNotFound
+	}
+	if len(tree.Children(id)) > 0 {
+		return ErrCategoryNotEmpty
+	}
+	// пока проверяем и удаляем, объявления не пишутся, см. writeAdsIn
+	m.adWrites.Lock()
+	defer m.adWrites.Unlock()
+	inUse, err := m.adRepository.FindAds(ctx, ads.Filter{CategoryIDs: []int64{id}}, ads.Page{Limit: 1})
+	if err != nil {
+		return err
+	}
+	if len(inUse) > 0 {
+		return ErrCategoryNotEmpty
+	}
+	err = m.categories.DeleteCategory(ctx, id)
+	if errors.Is(err, categories.ErrNotFound) {
+		return ErrNotFound
+	}
+	return err
+}
+
+func (m MyApp) categoryTree(ctx context.Context) (*categories.Tree, error) {
+	list, err := m.categories.ListCategories(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return categories.NewTree(list), nil
+}
+
+// checkPlacement проверяет, что родитель c существует, а имя непустое и не повторяется среди соседей
+func checkPlacement(tree *categories.Tree, c categories.Category) error {
+	if c.Name == "" || utf8.RuneCountInString(c.Name) > MaxCategoryNameLen {
+		return ErrCategoryName
+	}
+	if c.ParentID != 0 {
+		if _, ok := tree.Get(c.ParentID); !ok {
+			return ErrInvalidCategory
+		}
+	}
+	for _, sibling := range tree.Children(c.ParentID) {
+		if sibling.ID != c.ID && strings.EqualFold(sibling.Name, c.Name) {
+			return ErrCategoryName
+		}
+	}
+	return nil
+}
+
+// requireCategory проверяет, что в категорию id можно поместить объявление
+func (m MyApp) requireCategory(ctx context.Context, id int64) error {
+	_, err := m.categories.GetCategory(ctx, id)
+	if errors.Is(err, categories.ErrNotFound) {
+		return ErrInvalidCategory
+	}
+	return err
+}
+
+// expandCategories заменяет каждую категорию фильтра её поддеревом
+func (m MyApp) expandCategories(ctx context.Context, ids []int64) ([]int64, error) {
+	if len(ids) == 0 {
+		return nil, nil
+	}
+	tree, err := m.categoryTree(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var res []int64
+	for _, id := range ids {
+		subtree := tree.Subtree(id)
+		if subtree == nil {
+			return nil, ErrInvalidCategory
+		}
+		res = append(res, subtree...)
+	}
+	return res, nil
+}
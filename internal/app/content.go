@@ -0,0 +1,68 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"homework9/internal/ads"
+	"homework9/internal/checks"
+)
+
+var ErrContentRejected = errors.New("ad content rejected")
+
+// ContentError - объявление не прошло проверки содержимого. Findings - все замечания, в том числе
+// с решением Flag, чтобы автор исправил всё сразу. errors.Is(err, ErrContentRejected) для него истинно
+type ContentError struct {
+	Findings []checks.Finding
+}
+
+func (e *ContentError) Error() string {
+	reasons := make([]string, 0, len(e.Findings))
+	for _, f := range e.Findings {
+		if f.Verdict == checks.Reject {
+			reasons = append(reasons, f.Check+": "+f.Reason)
+		}
+	}
+	return ErrContentRejected.Error() + ": " + strings.Join(reasons, "; ")
+}
+
+func (e *ContentError) Unwrap() error {
+	return ErrContentRejected
+}
+
+// checkContent проверяет содержимое проверками из WithContentCheckers. Отклонённое содержимое - *ContentError,
+// иначе возвращаются замечания, с которыми объявление сохраняется
+func (m MyApp) checkContent(ctx context.Context, s checks.Submission) ([]ads.Flag, error) {
+	if len(m.checkers) == 0 {
+		return nil, nil
+	}
+	report, err := checks.Run(ctx, m.checkers, s)
+	if err != nil {
+		return nil, err
+	}
+	if report.Verdict == checks.Reject {
+		return nil, &ContentError{Findings: report.Findings}
+	}
+	var flags []ads.Flag
+	for _, f := range report.Findings {
+		flags = append(flags, ads.Flag{Check: f.Check, Field: f.Field, Reason: f.Reason})
+	}
+	return flags, nil
+}
+
+// checkUpdate проверяет новое содержимое объявления id. Проверки выполняются до транзакции изменения:
+// им может понадобиться хранилище, поэтому права проверяются заранее, чтобы чужой не узнал их решения
+func (m MyApp) checkUpdate(ctx context.Context, id int64, title string, text string) ([]ads.Flag, error) {
+	if len(m.checkers) == 0 {
+		return nil, nil
+	}
+	a, err := m.adRepository.GetAdById(ctx, id)
+	if err != nil {
+		return nil, adError(err)
+	}
+	if _, err := m.authorize(ctx, m.userRepository, ActionUpdateAd, a.AuthorID); err != nil {
+		return nil, err
+	}
+	return m.checkContent(ctx, checks.Submission{ID: id, AuthorID: a.AuthorID, Title: title, Text: text})
+}
@@ -0,0 +1,40 @@
+package app
+
+import "context"
+
+type userIDKey struct{}
+
+// WithUserID возвращает контекст запроса от имени пользователя userID.
+// Транспорты кладут сюда ID из проверенного токена, методы App читают его через UserIDFromContext
+func WithUserID(ctx context.Context, userID int64) context.Context {
+	return context.WithValue(ctx, userIDKey{}, userID)
+}
+
+// UserIDFromContext возвращает ID пользователя, от имени которого выполняется запрос
+func UserIDFromContext(ctx context.Context) (int64, bool) {
+	userID, ok := ctx.Value(userIDKey{}).(int64)
+	return userID, ok
+}
+
+// actingUser - как UserIDFromContext, но для методов, которым аноним не подходит
+func actingUser(ctx context.Context) (int64, error) {
+	userID, ok := UserIDFromContext(ctx)
+	if !ok {
+		return 0, ErrUnauthenticated
+	}
+	return userID, nil
+}
+
+type systemKey struct{}
+
+// asSystem возвращает контекст действия, которое приложение выполняет само, а не по просьбе пользователя
+// (например, снятие объявления по жалобам). authorize разрешает такие действия без проверки политики.
+// Транспорты такой контекст создать не могут
+func asSystem(ctx context.Context) context.Context {
+	return context.WithValue(ctx, systemKey{}, true)
+}
+
+func isSystem(ctx context.Context) bool {
+	system, _ := ctx.Value(systemKey{}).(bool)
+	return system
+}
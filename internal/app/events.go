@@ -0,0 +1,279 @@
+package app
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"log"
+	"sync/atomic"
+
+	"homework9/internal/ads"
+	"homework9/internal/events"
+	"homework9/internal/messages"
+)
+
+var (
+	ErrEventsDisabled     = errors.New("events are not configured")
+	ErrInvalidResumeToken = errors.New("invalid resume token")
+	// ErrResumeTokenExpired - событий после токена уже не осталось: клиенту нужно заново загрузить объявления
+	// и начать наблюдение без токена
+	ErrResumeTokenExpired = errors.New("resume token expired")
+)
+
+// EventSubscription - события для одного пользователя: сообщения ему, смена статуса объявлений из его избранного
+// и, пока задан фильтр, опубликованные объявления под этот фильтр
+type EventSubscription struct {
+	*events.Subscription
+	filter  atomic.Pointer[FilterOpts]
+	prepare func(ctx context.Context, opts FilterOpts) (FilterOpts, error)
+	viewer  adViewer
+}
+
+// View - событие таким, каким его положено видеть подписчику: объявления, которое ему не видно, в событии нет,
+// у видимых чужих объявлений нет решения модератора и замечаний проверок. Транспорт отдаёт клиенту только такие события
+func (s *EventSubscription) View(e events.Event) events.Event {
+	e.Ad = s.viewer.visible(e.Ad)
+	return e
+}
+
+// SetFilter заменяет фильтр опубликованных объявлений, nil - объявления больше не присылать.
+// Фильтр понимается так же, как в GetAdsByFilter
+func (s *EventSubscription) SetFilter(ctx context.Context, opts *FilterOpts) error {
+	if opts == nil {
+		s.filter.Store(nil)
+		return nil
+	}
+	o := *opts
+	o.Published = ads.OnlyPublished
+	f, err := s.prepare(ctx, o)
+	if err != nil {
+		return err
+	}
+	s.filter.Store(&f)
+	return nil
+}
+
+func (s *EventSubscription) match(userID int64, e events.Event) bool {
+	switch e.Kind {
+	case events.KindAdStatus:
+		f := s.filter.Load()
+		return f != nil && e.Ad != nil && e.Ad.Published && f.Match(*e.Ad)
+	case events.KindFavoriteStatus, events.KindMessage:
+		return e.For(userID)
+	default:
+		return false
+	}
+}
+
+func (m MyApp) SubscribeEvents(ctx context.Context, buffer int) (*EventSubscription, error) {
+	if m.events == nil {
+		return nil, ErrEventsDisabled
+	}
+	userID, err := actingUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := m.authorize(ctx, m.userRepository, ActionWatchEvents, userID); err != nil {
+		return nil, err
+	}
+	v, err := m.viewer(ctx)
+	if err != nil {
+		return nil, err
+	}
+	s := &EventSubscription{prepare: m.prepareFilter, viewer: v}
+	s.Subscription, err = m.events.Subscribe(buffer, func(e events.Event) bool { return s.match(userID, e) })
+	if err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// AdWatch - события об объявлениях под фильтр WatchAds: создание, изменение, смена статуса и удаление
+type AdWatch struct {
+	*events.Subscription
+	// Backlog - события после resume-токена, случившиеся до подписки. Их отдают раньше Events()
+	Backlog []events.Event
+	// Start - токен места, с которого идут события Events(), то есть сразу за Backlog
+	Start  string
+	epoch  int64
+	viewer adViewer
+}
+
+// View - событие таким, каким его положено видеть наблюдающему: объявления, которое ему не видно
+// (например, снятого с публикации чужого), в событии нет, у чужих нет решения модератора и замечаний проверок.
+// Транспорт отдаёт клиенту только такие события
+func (w *AdWatch) View(e events.Event) events.Event {
+	e.Ad = w.viewer.visible(e.Ad)
+	e.Was = w.viewer.visible(e.Was)
+	return e
+}
+
+// ResumeToken - токен, с которым наблюдение продолжится сразу после события e
+func (w *AdWatch) ResumeToken(e events.Event) string {
+	return encodeResumeToken(w.epoch, e.Seq)
+}
+
+func (m MyApp) WatchAds(ctx context.Context, opts FilterOpts, resumeToken string, buffer int) (*AdWatch, error) {
+	if m.events == nil {
+		return nil, ErrEventsDisabled
+	}
+	userID, err := actingUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := m.authorize(ctx, m.userRepository, ActionWatchEvents, userID); err != nil {
+		return nil, err
+	}
+	v, err := m.viewer(ctx)
+	if err != nil {
+		return nil, err
+	}
+	epoch := m.events.Epoch()
+	var after uint64
+	if resumeToken != "" {
+		if after, err = decodeResumeToken(resumeToken, epoch); err != nil {
+			return nil, err
+		}
+	}
+	f, err := m.prepareFilter(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	match := func(e events.Event) bool { return watchMatch(f, v, e) }
+	var sub *events.Subscription
+	var backlog []events.Event
+	if resumeToken == "" {
+		sub, err = m.events.Subscribe(buffer, match)
+	} else {
+		sub, backlog, err = m.events.SubscribeFrom(after, buffer, match)
+	}
+	switch {
+	case errors.Is(err, events.ErrHistoryExpired):
+		return nil, ErrResumeTokenExpired
+	case errors.Is(err, events.ErrUnknownPosition):
+		return nil, ErrInvalidResumeToken
+	case err != nil:
+		return nil, err
+	}
+	return &AdWatch{Subscription: sub, Backlog: backlog, Start: encodeResumeToken(epoch, sub.Head()), epoch: epoch, viewer: v}, nil
+}
+
+// watchMatch - событие об объявлении, которое видно v и подходит под фильтр до или после изменения:
+// так наблюдающий видит и то, как объявление уходит из выборки или из публикации
+func watchMatch(f FilterOpts, v adViewer, e events.Event) bool {
+	switch e.Kind {
+	case events.KindAdCreated, events.KindAdUpdated, events.KindAdStatus, events.KindAdDeleted:
+		return e.Ad != nil && v.canSee(*e.Ad) && f.Match(*e.Ad) || e.Was != nil && v.canSee(*e.Was) && f.Match(*e.Was)
+	default:
+		return false
+	}
+}
+
+// resumeToken - содержимое токена WatchAds: номер события и эпоха Hub, в которой этот номер что-то значит
+type resumeToken struct {
+	Epoch int64  `json:"e"`
+	Seq   uint64 `json:"s"`
+}
+
+func encodeResumeToken(epoch int64, seq uint64) string {
+	data, _ := json.Marshal(resumeToken{Epoch: epoch, Seq: seq})
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// decodeResumeToken возвращает номер события из токена. Токен другой эпохи (например, выданный до перезапуска)
+// не продолжить без пропусков, поэтому он считается истёкшим
+func decodeResumeToken(token string, epoch int64) (uint64, error) {
+	data, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return 0, ErrInvalidResumeToken
+	}
+	var tok resumeToken
+	if err := json.Unmarshal(data, &tok); err != nil || tok.Epoch == 0 {
+		return 0, ErrInvalidResumeToken
+	}
+	if tok.Epoch != epoch {
+		return 0, ErrResumeTokenExpired
+	}
+	return tok.Seq, nil
+}
+
+// prepareFilter готовит фильтр к проверке объявлений по одному: цены и места проверены,
+// категории раскрыты в поддеревья
+func (m MyApp) prepareFilter(ctx context.Context, opts FilterOpts) (FilterOpts, error) {
+	if err := m.normalizePriceFilter(&opts); err != nil {
+		return FilterOpts{}, err
+	}
+	if err := normalizeGeoFilter(opts, &ads.Sort{}); err != nil {
+		return FilterOpts{}, err
+	}
+	var err error
+	opts.CategoryIDs, err = m.expandCategories(ctx, opts.CategoryIDs)
+	if err != nil {
+		return FilterOpts{}, err
+	}
+	return opts, nil
+}
+
+// emit отдаёт событие подписчикам. Вызывается после успешной записи, поэтому подписчики не увидят
+// изменений, которых нет в хранилище
+func (m MyApp) emit(e events.Event) {
+	if m.events == nil {
+		return
+	}
+	if e.At.IsZero() {
+		e.At = m.now()
+	}
+	m.events.Publish(e)
+}
+
+// adChanged сообщает о записи объявления: was nil - объявление создано, ad nil - удалено.
+// О смене статуса (и удалении) отдельно узнают те, у кого объявление в избранном
+func (m MyApp) adChanged(ctx context.Context, was *ads.Ad, ad *ads.Ad) {
+	if m.events == nil {
+		return
+	}
+	// подписчики получают свои копии, вызывающий может менять объявления дальше
+	e := events.Event{Kind: events.KindAdUpdated}
+	if was != nil {
+		cp := *was
+		e.AdID, e.Was = cp.ID, &cp
+	}
+	if ad != nil {
+		cp := *ad
+		e.AdID, e.Ad = cp.ID, &cp
+	}
+	switch {
+	case was == nil:
+		e.Kind = events.KindAdCreated
+	case ad == nil:
+		e.Kind = events.KindAdDeleted
+	case ad.EffectiveStatus() != was.EffectiveStatus():
+		e.Kind = events.KindAdStatus
+	}
+	m.emit(e)
+	if e.Kind == events.KindAdStatus || e.Kind == events.KindAdDeleted {
+		m.favoriteStatusChanged(ctx, e.AdID, e.Ad)
+	}
+}
+
+// favoriteStatusChanged сообщает о смене статуса объявления тем, у кого оно в избранном. ad nil - объявление удалено
+func (m MyApp) favoriteStatusChanged(ctx context.Context, adID int64, ad *ads.Ad) {
+	if m.favorites == nil {
+		return
+	}
+	// изменение уже записано, поэтому ошибка только пишется в журнал
+	userIDs, err := m.favorites.FavoriteUsers(ctx, adID)
+	if err != nil {
+		log.Printf("favorite users of ad %d: %v", adID, err)
+		return
+	}
+	if len(userIDs) > 0 {
+		m.emit(events.Event{Kind: events.KindFavoriteStatus, AdID: adID, Ad: ad, UserIDs: userIDs})
+	}
+}
+
+func (m MyApp) messageSent(t messages.Thread, msg messages.Message) {
+	m.emit(events.Event{Kind: events.KindMessage, At: msg.Sent, AdID: t.AdID, Message: &msg,
+		UserIDs: []int64{t.Correspondent(msg.SenderID)}})
+}
@@ -0,0 +1,157 @@
+package app
+
+import (
+	"context"
+	"errors"
+
+	"homework9/internal/ads"
+	"homework9/internal/favorites"
+)
+
+var (
+	ErrFavoritesDisabled   = errors.New("favorites are not configured")
+	ErrFavoriteUnavailable = errors.New("only published ads can be added to favorites")
+)
+
+// FavoriteAd - объявление из избранного. Ad nil, если объявление удалено
+type FavoriteAd struct {
+	favorites.Favorite
+	Ad *ads.Ad
+}
+
+// Available - объявление не удалено и опубликовано
+func (f FavoriteAd) Available() bool {
+	return f.Ad != nil && f.Ad.Published
+}
+
+type FavoritesPage struct {
+	Favorites []FavoriteAd
+	// NextCursor пустой на последней странице
+	NextCursor string
+}
+
+// favoritesSort - порядок избранного в курсоре: от новых к старым, см. favorites.Favorite.Before
+var favoritesSort = ads.Sort{Field: ads.SortByCreated, Desc: true}
+
+func (m MyApp) AddFavorite(ctx context.Context, adID int64) (*FavoriteAd, error) {
+	userID, err := m.favoritesUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+	v, err := m.viewer(ctx)
+	if err != nil {
+		return nil, err
+	}
+	ad, err := m.adRepository.GetAdById(ctx, adID)
+	if err != nil {
+		return nil, adError(err)
+	}
+	v.redact(ad)
+	// повторное добавление ничего не меняет, даже если объявление уже снято
+	if f, err := m.favorites.GetFavorite(ctx, userID, adID); err == nil {
+		return &FavoriteAd{Favorite: *f, Ad: ad}, nil
+	} else if !errors.Is(err, favorites.ErrNotFound) {
+		return nil, err
+	}
+	if !ad.Published {
+		return nil, ErrFavoriteUnavailable
+	}
+
+	f := favorites.Favorite{UserID: userID, AdID: adID, Added: m.now()}
+	err = m.favorites.AddFavorite(ctx, f)
+	if errors.Is(err, favorites.ErrDuplicate) {
+		// параллельный запрос успел раньше
+		existing, err := m.favorites.GetFavorite(ctx, userID, adID)
+		if err != nil {
+			return nil, err
+		}
+		f = *existing
+	} else if err != nil {
+		return nil, err
+	}
+	return &FavoriteAd{Favorite: f, Ad: ad}, nil
+}
+
+func (m MyApp) RemoveFavorite(ctx context.Context, adID int64) error {
+	userID, err := m.favoritesUser(ctx)
+	if err != nil {
+		return err
+	}
+	err = m.favorites.RemoveFavorite(ctx, userID, adID)
+	if errors.Is(err, favorites.ErrNotFound) {
+		return ErrNotFound
+	}
+	return err
+}
+
+func (m MyApp) ListFavorites(ctx context.Context, p PageRequest) (*FavoritesPage, error) {
+	userID, err := m.favoritesUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+	limit := p.Limit
+	if limit <= 0 {
+		limit = DefaultPageSize
+	}
+	if limit > MaxPageSize {
+		limit = MaxPageSize
+	}
+	var after *favorites.Favorite
+	if p.Cursor != "" {
+		c, err := decodeCursor(p.Cursor, favoritesSort)
+		if err != nil {
+			return nil, err
+		}
+		after = &favorites.Favorite{UserID: userID, AdID: c.ID, Added: c.Created}
+	}
+	list, err := m.favorites.ListFavorites(ctx, userID, after, limit+1)
+	if err != nil {
+		return nil, err
+	}
+	v, err := m.viewer(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	res := &FavoritesPage{}
+	if len(list) > limit {
+		list = list[:limit]
+		last := list[limit-1]
+		res.NextCursor = encodeCursor(favoritesSort, ads.Cursor{ID: last.AdID, Created: last.Added})
+	}
+	res.Favorites = make([]FavoriteAd, 0, len(list))
+	for _, f := range list {
+		ad, err := m.adRepository.GetAdById(ctx, f.AdID)
+		if err != nil && !errors.Is(err, ads.ErrNotFound) {
+			return nil, err
+		}
+		if ad != nil {
+			v.redact(ad)
+		}
+		res.Favorites = append(res.Favorites, FavoriteAd{Favorite: f, Ad: ad})
+	}
+	return res, nil
+}
+
+// FavoriteCounts без WithFavorites возвращает пустой ответ: число в избранном тогда у всех объявлений нулевое
+func (m MyApp) FavoriteCounts(ctx context.Context, adIDs []int64) (map[int64]int, error) {
+	if m.favorites == nil || len(adIDs) == 0 {
+		return map[int64]int{}, nil
+	}
+	return m.favorites.CountFavorites(ctx, adIDs)
+}
+
+// favoritesUser возвращает пользователя из контекста, которому разрешено работать со своим избранным
+func (m MyApp) favoritesUser(ctx context.Context) (int64, error) {
+	if m.favorites == nil {
+		return 0, ErrFavoritesDisabled
+	}
+	userID, err := actingUser(ctx)
+	if err != nil {
+		return 0, err
+	}
+	if _, err := m.authorize(ctx, m.userRepository, ActionManageFavorites, userID); err != nil {
+		return 0, err
+	}
+	return userID, nil
+}
@@ -0,0 +1,252 @@
+package app
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+
+	"homework9/internal/ads"
+	"homework9/internal/blobs"
+	"homework9/internal/images"
+	"homework9/internal/users"
+)
+
+var (
+	ErrImagesDisabled   = errors.New("image storage is not configured")
+	ErrImageTooLarge    = errors.New("image is too large")
+	ErrUnsupportedImage = errors.New("unsupported image, use jpeg, png or gif")
+	ErrTooManyImages    = errors.New("too many images for one ad")
+	ErrImageOrder       = errors.New("image order must list every image of the ad exactly once")
+)
+
+const (
+	DefaultImageMaxBytes int64 = 5 << 20
+	DefaultImagesPerAd         = 10
+	DefaultThumbSize           = 320
+)
+
+// ImageLimits - ограничения на фотографии объявлений. Нулевые поля заменяются значениями по умолчанию
+type ImageLimits struct {
+	// MaxBytes - максимальный размер одного файла
+	MaxBytes int64
+	// PerAd - сколько фотографий может быть у объявления
+	PerAd int
+	// ThumbSize - большая сторона превью в пикселях
+	ThumbSize int
+}
+
+func (l ImageLimits) withDefaults() ImageLimits {
+	if l.MaxBytes <= 0 {
+		l.MaxBytes = DefaultImageMaxBytes
+	}
+	if l.PerAd <= 0 {
+		l.PerAd = DefaultImagesPerAd
+	}
+	if l.ThumbSize <= 0 {
+		l.ThumbSize = DefaultThumbSize
+	}
+	return l
+}
+
+// AddAdImage читает фотографию из r, проверяет формат и размер, сохраняет оригинал и превью в хранилище блобов
+// и добавляет фотографию в конец списка объявления. Доступно автору объявления
+func (m MyApp) AddAdImage(ctx context.Context, adID int64, r io.Reader) (*ads.Ad, error) {
+	if m.blobs == nil {
+		return nil, ErrImagesDisabled
+	}
+	// права и место проверяются до чтения файла, в транзакции ниже - ещё раз
+	ad, err := m.adRepository.GetAdById(ctx, adID)
+	if err != nil {
+		return nil, adError(err)
+	}
+	if _, err := m.authorize(ctx, m.userRepository, ActionUpdateAd, ad.AuthorID); err != nil {
+		return nil, err
+	}
+	if len(ad.Images) >= m.imageLimits.PerAd {
+		return nil, ErrTooManyImages
+	}
+
+	data, err := io.ReadAll(io.LimitReader(r, m.imageLimits.MaxBytes+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(data)) > m.imageLimits.MaxBytes {
+		return nil, ErrImageTooLarge
+	}
+	img, contentType, err := images.Decode(data)
+	if errors.Is(err, images.ErrTooManyPixels) {
+		return nil, ErrImageTooLarge
+	} else if err != nil {
+		return nil, ErrUnsupportedImage
+	}
+	thumb, err := images.EncodeJPEG(images.Thumbnail(img, m.imageLimits.ThumbSize))
+	if err != nil {
+		return nil, err
+	}
+
+	id, err := newImageID()
+	if err != nil {
+		return nil, err
+	}
+	image := ads.Image{
+		ID:          id,
+		Key:         fmt.Sprintf("ads/%d/%s", adID, id),
+		ThumbKey:    fmt.Sprintf("ads/%d/%s_thumb", adID, id),
+		ContentType: contentType,
+		Size:        int64(len(data)),
+		Width:       img.Bounds().Dx(),
+		Height:      img.Bounds().Dy(),
+	}
+	if err := m.blobs.Put(ctx, image.Key, bytes.NewReader(data)); err != nil {
+		return nil, err
+	}
+	if err := m.blobs.Put(ctx, image.ThumbKey, bytes.NewReader(thumb)); err != nil {
+		m.deleteImageBlobs(ctx, []ads.Image{image})
+		return nil, err
+	}
+
+	changed, err := m.changeImages(ctx, adID, func(list []ads.Image) ([]ads.Image, error) {
+		if len(list) >= m.imageLimits.PerAd {
+			return nil, ErrTooManyImages
+		}
+		return append(list, image), nil
+	})
+	if err != nil {
+		m.deleteImageBlobs(ctx, []ads.Image{image})
+		return nil, err
+	}
+	return changed, nil
+}
+
+// DeleteAdImage убирает фотографию из объявления и удаляет её файлы. Доступно автору объявления
+func (m MyApp) DeleteAdImage(ctx context.Context, adID int64, imageID string) (*ads.Ad, error) {
+	var removed []ads.Image
+	changed, err := m.changeImages(ctx, adID, func(list []ads.Image) ([]ads.Image, error) {
+		i := findImage(list, imageID)
+		if i < 0 {
+			return nil, ErrNotFound
+		}
+		removed = []ads.Image{list[i]}
+		return append(list[:i], list[i+1:]...), nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	m.deleteImageBlobs(ctx, removed)
+	return changed, nil
+}
+
+// ReorderAdImages задаёт порядок фотографий: imageIDs должен перечислять все фотографии объявления. Доступно автору
+func (m MyApp) ReorderAdImages(ctx context.Context, adID int64, imageIDs []string) (*ads.Ad, error) {
+	return m.changeImages(ctx, adID, func(list []ads.Image) ([]ads.Image, error) {
+		if len(imageIDs) != len(list) {
+			return nil, ErrImageOrder
+		}
+		res := make([]ads.Image, 0, len(list))
+		for _, id := range imageIDs {
+			i := findImage(list, id)
+			if i < 0 || findImage(res, id) >= 0 {
+				return nil, ErrImageOrder
+			}
+			res = append(res, list[i])
+		}
+		return res, nil
+	})
+}
+
+// OpenAdImage открывает оригинал фотографии или, если thumb, её превью. Фотографии неопубликованного объявления
+// видны только тем, кому видно оно само. Вызывающий обязан закрыть файл
+func (m MyApp) OpenAdImage(ctx context.Context, adID int64, imageID string, thumb bool) (io.ReadCloser, ads.Image, error) {
+	if m.blobs == nil {
+		return nil, ads.Image{}, ErrImagesDisabled
+	}
+	v, err := m.viewer(ctx)
+	if err != nil {
+		return nil, ads.Image{}, err
+	}
+	ad, err := m.adRepository.GetAdById(ctx, adID)
+	if err != nil {
+		return nil, ads.Image{}, adError(err)
+	}
+	// фотографии скрытого объявления скрыты вместе с ним
+	if !v.canSee(*ad) {
+		return nil, ads.Image{}, ErrNotFound
+	}
+	i := findImage(ad.Images, imageID)
+	if i < 0 {
+		return nil, ads.Image{}, ErrNotFound
+	}
+	image := ad.Images[i]
+	key := image.Key
+	if thumb {
+		key = image.ThumbKey
+	}
+	f, err := m.blobs.Get(ctx, key)
+	if errors.Is(err, blobs.ErrNotFound) {
+		return nil, ads.Image{}, ErrNotFound
+	}
+	return f, image, err
+}
+
+// changeImages заменяет список фотографий объявления на результат change в транзакции.
+// change получает копию списка и может менять её
+func (m MyApp) changeImages(ctx context.Context, adID int64, change func([]ads.Image) ([]ads.Image, error)) (*ads.Ad, error) {
+	var changed, was ads.Ad
+	err := m.writeAds(ctx, func(adRepo ads.AdRepository, userRepo users.UserRepository) error {
+		a, err := adRepo.GetAdById(ctx, adID)
+		if err != nil {
+			return err
+		}
+		if _, err := m.authorize(ctx, userRepo, ActionUpdateAd, a.AuthorID); err != nil {
+			return err
+		}
+		list, err := change(append([]ads.Image(nil), a.Images...))
+		if err != nil {
+			return err
+		}
+		changed, was = *a, *a
+		changed.Images = list
+		changed.Modified = m.now()
+		return adRepo.UpdateById(ctx, adID, changed)
+	}, func() {
+		changed.Version++
+		m.adChanged(ctx, &was, &changed)
+	})
+	if err != nil {
+		return nil, adError(err)
+	}
+	return &changed, nil
+}
+
+// deleteImageBlobs удаляет файлы фотографий. Ошибки не возвращаются: запись об объявлении уже изменена,
+// а оставшийся в хранилище файл ни на что не влияет
+func (m MyApp) deleteImageBlobs(ctx context.Context, list []ads.Image) {
+	if m.blobs == nil {
+		return
+	}
+	for _, image := range list {
+		_ = m.blobs.Delete(ctx, image.Key)
+		_ = m.blobs.Delete(ctx, image.ThumbKey)
+	}
+}
+
+func findImage(list []ads.Image, id string) int {
+	for i := range list {
+		if list[i].ID == id {
+			return i
+		}
+	}
+	return -1
+}
+
+func newImageID() (string, error) {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,326 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"unicode/utf8"
+
+	"homework9/internal/ads"
+	"homework9/internal/messages"
+)
+
+var (
+	ErrMessagesDisabled    = errors.New("messages are not configured")
+	ErrInvalidMessage      = errors.New("message text is required and is at most 2000 characters")
+	ErrSelfMessage         = errors.New("cannot message or block yourself")
+	ErrThreadUnavailable   = errors.New("only published ads can be discussed")
+	ErrConversationBlocked = errors.New("conversation is blocked")
+)
+
+// MaxMessageLength - длина сообщения в символах
+const MaxMessageLength = 2000
+
+// ThreadView - переписка глазами участника: сколько в ней непрочитанных сообщений собеседника и закрыта ли она
+// блокировкой с любой из сторон
+type ThreadView struct {
+	messages.Thread
+	Unread  int
+	Blocked bool
+}
+
+type ThreadsPage struct {
+	Threads []ThreadView
+	// NextCursor пустой на последней странице
+	NextCursor string
+}
+
+type MessagesPage struct {
+	Thread   ThreadView
+	Messages []messages.Message
+	// NextCursor пустой на последней странице
+	NextCursor string
+}
+
+// threadsSort - порядок входящих в курсоре: сначала переписки с более новым сообщением, см. messages.Thread.Before
+var threadsSort = ads.Sort{Field: ads.SortByModified, Desc: true}
+
+func (m MyApp) ContactSeller(ctx context.Context, adID int64, text string) (*messages.Message, error) {
+	text, err := messageText(text)
+	if err != nil {
+		return nil, err
+	}
+	userID, err := m.messagesUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+	ad, err := m.adRepository.GetAdById(ctx, adID)
+	if err != nil {
+		return nil, adError(err)
+	}
+	if ad.AuthorID == userID {
+		return nil, ErrSelfMessage
+	}
+
+	t, err := m.messages.FindThread(ctx, adID, userID)
+	if errors.Is(err, messages.ErrNotFound) {
+		// начать переписку можно только по опубликованному объявлению, начатая продолжается и после снятия
+		if !ad.Published {
+			return nil, ErrThreadUnavailable
+		}
+		// блокировка проверяется до создания, чтобы не оставлять пустых переписок
+		if blocked, err := m.blockedBetween(ctx, userID, ad.AuthorID); err != nil {
+			return nil, err
+		} else if blocked {
+			return nil, ErrConversationBlocked
+		}
+		t, err = m.startThread(ctx, *ad, userID)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return m.sendMessage(ctx, *t, userID, text)
+}
+
+func (m MyApp) SendMessage(ctx context.Context, threadID int64, text string) (*messages.Message, error) {
+	text, err := messageText(text)
+	if err != nil {
+		return nil, err
+	}
+	userID, err := m.messagesUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+	t, err := m.participantThread(ctx, threadID, userID)
+	if err != nil {
+		return nil, err
+	}
+	return m.sendMessage(ctx, *t, userID, text)
+}
+
+func (m MyApp) ListThreads(ctx context.Context, adID *int64, p PageRequest) (*ThreadsPage, error) {
+	userID, err := m.messagesUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+	limit := p.Limit
+	if limit <= 0 {
+		limit = DefaultPageSize
+	}
+	if limit > MaxPageSize {
+		limit = MaxPageSize
+	}
+	var after *messages.Thread
+	if p.Cursor != "" {
+		c, err := decodeCursor(p.Cursor, threadsSort)
+		if err != nil {
+			return nil, err
+		}
+		after = &messages.Thread{ID: c.ID, LastMessageAt: c.Modified}
+	}
+	list, err := m.messages.ListThreads(ctx, userID, adID, after, limit+1)
+	if err != nil {
+		return nil, err
+	}
+
+	res := &ThreadsPage{}
+	if len(list) > limit {
+		list = list[:limit]
+		last := list[limit-1]
+		res.NextCursor = encodeCursor(threadsSort, ads.Cursor{ID: last.ID, Modified: last.LastMessageAt})
+	}
+	res.Threads = make([]ThreadView, 0, len(list))
+	for _, t := range list {
+		v, err := m.threadView(ctx, t, userID)
+		if err != nil {
+			return nil, err
+		}
+		res.Threads = append(res.Threads, v)
+	}
+	return res, nil
+}
+
+// ListMessages не отмечает сообщения прочитанными, для этого есть MarkThreadRead
+func (m MyApp) ListMessages(ctx context.Context, threadID int64, p PageRequest) (*MessagesPage, error) {
+	userID, err := m.messagesUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+	t, err := m.participantThread(ctx, threadID, userID)
+	if err != nil {
+		return nil, err
+	}
+	limit := p.Limit
+	if limit <= 0 {
+		limit = DefaultPageSize
+	}
+	if limit > MaxPageSize {
+		limit = MaxPageSize
+	}
+	// курсор тот же, что у объявлений по ID
+	var after ads.Cursor
+	if p.Cursor != "" {
+		after, err = decodeCursor(p.Cursor, ads.Sort{})
+		if err != nil {
+			return nil, err
+		}
+	}
+	list, err := m.messages.ListMessages(ctx, threadID, after.ID, limit+1)
+	if err != nil {
+		return nil, err
+	}
+	view, err := m.threadView(ctx, *t, userID)
+	if err != nil {
+		return nil, err
+	}
+	res := &MessagesPage{Thread: view, Messages: list}
+	if len(list) > limit {
+		res.Messages = list[:limit]
+		res.NextCursor = encodeCursor(ads.Sort{}, ads.Cursor{ID: list[limit-1].ID})
+	}
+	return res, nil
+}
+
+func (m MyApp) MarkThreadRead(ctx context.Context, threadID int64) (*ThreadView, error) {
+	userID, err := m.messagesUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+	t, err := m.participantThread(ctx, threadID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if err := m.messages.MarkRead(ctx, threadID, userID, t.LastMessageID); err != nil {
+		return nil, err
+	}
+	if t, err = m.messages.GetThread(ctx, threadID); err != nil {
+		return nil, err
+	}
+	view, err := m.threadView(ctx, *t, userID)
+	if err != nil {
+		return nil, err
+	}
+	return &view, nil
+}
+
+// BlockUser повторно ничего не меняет
+func (m MyApp) BlockUser(ctx context.Context, blockedID int64) error {
+	userID, err := m.messagesUser(ctx)
+	if err != nil {
+		return err
+	}
+	if blockedID == userID {
+		return ErrSelfMessage
+	}
+	if _, err := m.GetUserByID(ctx, blockedID); err != nil {
+		return err
+	}
+	err = m.messages.Block(ctx, messages.Block{UserID: userID, BlockedID: blockedID, Created: m.now()})
+	if errors.Is(err, messages.ErrDuplicate) {
+		return nil
+	}
+	return err
+}
+
+func (m MyApp) UnblockUser(ctx context.Context, blockedID int64) error {
+	userID, err := m.messagesUser(ctx)
+	if err != nil {
+		return err
+	}
+	err = m.messages.Unblock(ctx, userID, blockedID)
+	if errors.Is(err, messages.ErrNotFound) {
+		return ErrNotFound
+	}
+	return err
+}
+
+func (m MyApp) startThread(ctx context.Context, ad ads.Ad, buyerID int64) (*messages.Thread, error) {
+	t := messages.Thread{AdID: ad.ID, SellerID: ad.AuthorID, BuyerID: buyerID, Created: m.now()}
+	id, err := m.messages.CreateThread(ctx, t)
+	if errors.Is(err, messages.ErrDuplicate) {
+		// параллельный запрос успел раньше
+		return m.messages.FindThread(ctx, ad.ID, buyerID)
+	} else if err != nil {
+		return nil, err
+	}
+	t.ID = id
+	return &t, nil
+}
+
+func (m MyApp) sendMessage(ctx context.Context, t messages.Thread, senderID int64, text string) (*messages.Message, error) {
+	blocked, err := m.blockedBetween(ctx, senderID, t.Correspondent(senderID))
+	if err != nil {
+		return nil, err
+	}
+	if blocked {
+		return nil, ErrConversationBlocked
+	}
+	msg := messages.Message{ThreadID: t.ID, SenderID: senderID, Text: text, Sent: m.now()}
+	msg.ID, err = m.messages.AddMessage(ctx, msg)
+	if err != nil {
+		return nil, err
+	}
+	m.messageSent(t, msg)
+	return &msg, nil
+}
+
+func (m MyApp) threadView(ctx context.Context, t messages.Thread, userID int64) (ThreadView, error) {
+	// свои сообщения участник читает сразу, поэтому всё после отметки - сообщения собеседника
+	unread, err := m.messages.ListMessages(ctx, t.ID, t.ReadID(userID), 0)
+	if err != nil {
+		return ThreadView{}, err
+	}
+	blocked, err := m.blockedBetween(ctx, userID, t.Correspondent(userID))
+	if err != nil {
+		return ThreadView{}, err
+	}
+	return ThreadView{Thread: t, Unread: len(unread), Blocked: blocked}, nil
+}
+
+// blockedBetween - заблокировал ли кто-то из двоих другого
+func (m MyApp) blockedBetween(ctx context.Context, a, b int64) (bool, error) {
+	for _, pair := range [][2]int64{{a, b}, {b, a}} {
+		blocked, err := m.messages.Blocked(ctx, pair[0], pair[1])
+		if err != nil || blocked {
+			return blocked, err
+		}
+	}
+	return false, nil
+}
+
+// participantThread возвращает переписку, если userID её участник. Чужая переписка для него не существует
+func (m MyApp) participantThread(ctx context.Context, threadID int64, userID int64) (*messages.Thread, error) {
+	t, err := m.messages.GetThread(ctx, threadID)
+	if errors.Is(err, messages.ErrNotFound) {
+		return nil, ErrNotFound
+	} else if err != nil {
+		return nil, err
+	}
+	if !t.Participant(userID) {
+		return nil, ErrNotFound
+	}
+	return t, nil
+}
+
+// messagesUser возвращает пользователя из контекста, которому разрешено переписываться
+func (m MyApp) messagesUser(ctx context.Context) (int64, error) {
+	if m.messages == nil {
+		return 0, ErrMessagesDisabled
+	}
+	userID, err := actingUser(ctx)
+	if err != nil {
+		return 0, err
+	}
+	if _, err := m.authorize(ctx, m.userRepository, ActionSendMessages, userID); err != nil {
+		return 0, err
+	}
+	return userID, nil
+}
+
+func messageText(text string) (string, error) {
+	text = strings.TrimSpace(text)
+	if text == "" || utf8.RuneCountInString(text) > MaxMessageLength {
+		return "", ErrInvalidMessage
+	}
+	return text, nil
+}
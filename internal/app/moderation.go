@@ -0,0 +1,167 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"log"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"homework9/internal/ads"
+	"homework9/internal/notifications"
+	"homework9/internal/users"
+)
+
+var (
+	ErrInvalidTransition = errors.New("ad status does not allow this action")
+	ErrInvalidReason     = errors.New("rejection needs a reason, a reason is at most 500 characters")
+)
+
+// MaxReasonLength - длина причины решения модератора в символах
+const MaxReasonLength = 500
+
+// ReviewQueue возвращает объявления, ждущие проверки. Доступно модераторам и администраторам
+func (m MyApp) ReviewQueue(ctx context.Context, p PageRequest) (*AdsPage, error) {
+	actor, err := m.authorize(ctx, m.userRepository, ActionReviewAd, noOwner)
+	if err != nil {
+		return nil, err
+	}
+	return m.findPage(adViewer{policy: m.policy, actor: &actor, system: isSystem(ctx)}, p, func(page ads.Page) ([]ads.Ad, error) {
+		return m.adRepository.FindAds(ctx, FilterOpts{Status: ads.StatusPending}, page)
+	})
+}
+
+// ApproveAd одобряет объявление на проверке и сразу публикует его, если публикация не назначена на будущее.
+// reason - необязательный комментарий модератора
+func (m MyApp) ApproveAd(ctx context.Context, id int64, reason string) (*ads.Ad, error) {
+	return m.review(ctx, id, true, reason)
+}
+
+// RejectAd отклоняет объявление на проверке с обязательной причиной и уведомляет автора
+func (m MyApp) RejectAd(ctx context.Context, id int64, reason string) (*ads.Ad, error) {
+	return m.review(ctx, id, false, reason)
+}
+
+func (m MyApp) review(ctx context.Context, id int64, approve bool, reason string) (*ads.Ad, error) {
+	reason = strings.TrimSpace(reason)
+	if !approve && reason == "" || utf8.RuneCountInString(reason) > MaxReasonLength {
+		return nil, ErrInvalidReason
+	}
+	var changed, was ads.Ad
+	err := m.writeAds(ctx, func(adRepo ads.AdRepository, userRepo users.UserRepository) error {
+		a, err := adRepo.GetAdById(ctx, id)
+		if err != nil {
+			return err
+		}
+		actor, err := m.authorize(ctx, userRepo, ActionReviewAd, noOwner)
+		if err != nil {
+			return err
+		}
+		// свои объявления модератор не проверяет
+		if actor.ID == a.AuthorID {
+			return ErrAccessDenied
+		}
+		if a.EffectiveStatus() != ads.StatusPending {
+			return ErrInvalidTransition
+		}
+		now := m.now()
+		changed, was = *a, *a
+		changed.Review = ads.Review{ModeratorID: actor.ID, Reason: reason, At: now}
+		switch {
+		case !approve:
+			changed.Status = ads.StatusRejected
+		case changed.PublishAt.After(now):
+			// опубликует планировщик
+			changed.Status = ads.StatusApproved
+		default:
+			m.publish(&changed, now)
+		}
+		return adRepo.UpdateById(ctx, id, changed)
+	}, func() {
+		changed.Version++
+		m.adChanged(ctx, &was, &changed)
+	})
+	if err != nil {
+		return nil, adError(err)
+	}
+	if !approve {
+		m.notify(ctx, notifications.Notification{UserID: changed.AuthorID, Kind: notifications.KindAdRejected,
+			AdID: changed.ID, Text: reason, Created: changed.Review.At})
+	}
+	return &changed, nil
+}
+
+// requestPublication - автор хочет опубликовать объявление. С модерацией, а также с замечаниями проверок
+// черновик и отклонённое объявление уходят на проверку. Одобренное и снятое с публикации
+// (его содержимое уже проверяли) публикуются сразу. reported - на объявление открыто не меньше
+// m.reportThreshold жалоб: тогда на проверку уходит любое объявление, см. reportedAd
+func (m MyApp) requestPublication(a *ads.Ad, now time.Time, reported bool) {
+	switch a.EffectiveStatus() {
+	case ads.StatusPublished, ads.StatusPending:
+		return
+	case ads.StatusDraft, ads.StatusRejected:
+		reported = reported || m.needsReview(*a)
+	}
+	if reported {
+		a.Status = ads.StatusPending
+		a.Published = false
+		return
+	}
+	m.publish(a, now)
+}
+
+// publish публикует объявление, отменяя запланированную публикацию. Объявление без срока получает срок жизни
+func (m MyApp) publish(a *ads.Ad, now time.Time) {
+	a.Status = ads.StatusPublished
+	a.Published = true
+	a.PublishAt = time.Time{}
+	if a.ExpiresAt.IsZero() {
+		a.ExpiresAt = m.expiry(now)
+	}
+}
+
+// withdraw снимает объявление: опубликованное уходит в архив, ждущее проверки или публикации - в черновики
+func withdraw(a *ads.Ad) {
+	switch a.EffectiveStatus() {
+	case ads.StatusPublished:
+		a.Status = ads.StatusArchived
+	case ads.StatusPending, ads.StatusApproved:
+		a.Status = ads.StatusDraft
+	}
+	a.Published = false
+}
+
+// contentChanged - автор изменил объявление. С модерацией или замечаниями проверок содержимое нужно
+// проверить заново: опубликованное и одобренное объявление возвращается на проверку, снятое - в черновики.
+// Без модерации объявление, которое ждало проверки из-за исправленных замечаний, возвращается в черновики
+func (m MyApp) contentChanged(a *ads.Ad) {
+	if !m.needsReview(*a) {
+		if a.EffectiveStatus() == ads.StatusPending {
+			a.Status = ads.StatusDraft
+		}
+		return
+	}
+	switch a.EffectiveStatus() {
+	case ads.StatusPublished, ads.StatusApproved:
+		a.Status = ads.StatusPending
+		a.Published = false
+	case ads.StatusArchived:
+		a.Status = ads.StatusDraft
+	}
+}
+
+// needsReview - содержимое объявления публикуется только после одобрения модератором
+func (m MyApp) needsReview(a ads.Ad) bool {
+	return m.moderation || len(a.Flags) > 0
+}
+
+// notify отправляет уведомление, если задан Notifier. Ошибка доставки только пишется в журнал
+func (m MyApp) notify(ctx context.Context, n notifications.Notification) {
+	if m.notifier == nil {
+		return
+	}
+	if err := m.notifier.Notify(ctx, n); err != nil {
+		log.Printf("notify user %d: %v", n.UserID, err)
+	}
+}
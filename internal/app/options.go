@@ -0,0 +1,202 @@
+package app
+
+import (
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+
+	"homework9/internal/blobs"
+	"homework9/internal/checks"
+	"homework9/internal/events"
+	"homework9/internal/favorites"
+	"homework9/internal/messages"
+	"homework9/internal/money"
+	"homework9/internal/notifications"
+	"homework9/internal/reports"
+	"homework9/internal/search"
+	"homework9/internal/sessions"
+)
+
+type Option func(m *MyApp)
+
+// WithSessions задаёт хранилище refresh-сессий. Без него войти в сервис нельзя
+func WithSessions(repo sessions.SessionRepository) Option {
+	return func(m *MyApp) {
+		m.sessions = repo
+	}
+}
+
+// WithAccessTokens задаёт выпуск и проверку access-токенов. Без него войти в сервис нельзя
+func WithAccessTokens(tokens AccessTokens) Option {
+	return func(m *MyApp) {
+		m.accessTokens = tokens
+	}
+}
+
+// WithAccessTTL задаёт время жизни access-токена, по умолчанию DefaultAccessTTL
+func WithAccessTTL(ttl time.Duration) Option {
+	return func(m *MyApp) {
+		m.accessTTL = ttl
+	}
+}
+
+// WithRefreshTTL задаёт время жизни refresh-токена, по умолчанию DefaultRefreshTTL
+func WithRefreshTTL(ttl time.Duration) Option {
+	return func(m *MyApp) {
+		m.refreshTTL = ttl
+	}
+}
+
+// WithPolicy задаёт правила доступа вместо DefaultPolicy
+func WithPolicy(p Policy) Option {
+	return func(m *MyApp) {
+		m.policy = p
+	}
+}
+
+// WithAdminEmails назначает роль администратора пользователям, которые регистрируются с этими email.
+// Так появляется первый администратор, который дальше раздаёт роли через SetUserRole
+func WithAdminEmails(emails ...string) Option {
+	return func(m *MyApp) {
+		m.adminEmails = make(map[string]bool, len(emails))
+		for _, email := range emails {
+			m.adminEmails[normalizeEmail(email)] = true
+		}
+	}
+}
+
+// WithSearchIndex задаёт полнотекстовый индекс объявлений. Индекс должен быть заполнен объявлениями
+// из хранилища (см. search.Index.Put) - дальше приложение само обновляет его при записи
+func WithSearchIndex(idx *search.Index) Option {
+	return func(m *MyApp) {
+		m.index = idx
+	}
+}
+
+// WithPasswordCost задаёт стоимость bcrypt. Понижать её имеет смысл только в тестах
+func WithPasswordCost(cost int) Option {
+	return func(m *MyApp) {
+		m.passwordCost = cost
+	}
+}
+
+// WithRates задаёт таблицу курсов для ConvertPrice. Её базовая валюта подставляется в фильтр
+// по цене без валюты. По умолчанию курсов нет, а базовая валюта - DefaultCurrency
+func WithRates(r *money.Rates) Option {
+	return func(m *MyApp) {
+		m.rates = r
+	}
+}
+
+// WithBlobStore задаёт хранилище фотографий объявлений. Без него фотографии загрузить нельзя
+func WithBlobStore(store blobs.BlobStore) Option {
+	return func(m *MyApp) {
+		m.blobs = store
+	}
+}
+
+// WithImageLimits задаёт ограничения на фотографии, нулевые поля - значения по умолчанию
+func WithImageLimits(l ImageLimits) Option {
+	return func(m *MyApp) {
+		m.imageLimits = l.withDefaults()
+	}
+}
+
+// WithClock подменяет часы приложения, по умолчанию time.Now. Нужен тестам сроков публикации
+func WithClock(now func() time.Time) Option {
+	return func(m *MyApp) {
+		m.now = now
+	}
+}
+
+// WithAdLifetime задаёт, сколько живёт опубликованное объявление до снятия, если автор не задал срок сам.
+// 0 - объявления не истекают
+func WithAdLifetime(d time.Duration) Option {
+	return func(m *MyApp) {
+		m.adLifetime = d
+	}
+}
+
+// WithModeration включает модерацию: объявление публикуется только после одобрения модератором,
+// а изменённое опубликованное объявление проверяется заново. По умолчанию выключена
+func WithModeration(enabled bool) Option {
+	return func(m *MyApp) {
+		m.moderation = enabled
+	}
+}
+
+// WithNotifier задаёт доставку уведомлений пользователям. Без него уведомления не отправляются
+func WithNotifier(n notifications.Notifier) Option {
+	return func(m *MyApp) {
+		m.notifier = n
+	}
+}
+
+// WithContentCheckers задаёт проверки содержимого новых и изменённых объявлений. Отклонённое объявление
+// не сохраняется (ContentError), объявление с замечаниями публикуется только после проверки модератором
+func WithContentCheckers(cs ...checks.Checker) Option {
+	return func(m *MyApp) {
+		m.checkers = cs
+	}
+}
+
+// WithReports задаёт хранилище жалоб. Без него жаловаться нельзя
+func WithReports(repo reports.ReportRepository) Option {
+	return func(m *MyApp) {
+		m.reports = repo
+	}
+}
+
+// WithReportThreshold задаёт, после скольких открытых жалоб объявление снимается с публикации,
+// по умолчанию DefaultReportThreshold. 0 - объявления по жалобам не снимаются
+func WithReportThreshold(n int) Option {
+	return func(m *MyApp) {
+		m.reportThreshold = n
+	}
+}
+
+// WithFavorites задаёт хранилище избранного. Без него избранного нет, а число в избранном у объявлений нулевое
+func WithFavorites(repo favorites.FavoriteRepository) Option {
+	return func(m *MyApp) {
+		m.favorites = repo
+	}
+}
+
+// WithMessages задаёт хранилище переписок. Без него писать продавцам нельзя
+func WithMessages(repo messages.MessageRepository) Option {
+	return func(m *MyApp) {
+		m.messages = repo
+	}
+}
+
+// WithEvents задаёт хаб, в который приложение публикует события после успешных изменений. Без него подписаться нельзя
+func WithEvents(h *events.Hub) Option {
+	return func(m *MyApp) {
+		m.events = h
+	}
+}
+
+const (
+	DefaultAccessTTL  = 15 * time.Minute
+	DefaultRefreshTTL = 30 * 24 * time.Hour
+)
+
+func defaultApp() MyApp {
+	rates, _ := money.NewRates(DefaultCurrency, nil)
+	return MyApp{
+		rates:        rates,
+		now:          time.Now,
+		imageLimits:  ImageLimits{}.withDefaults(),
+		policy:       DefaultPolicy(),
+		index:        search.NewIndex(),
+		accessTTL:    DefaultAccessTTL,
+		refreshTTL:   DefaultRefreshTTL,
+		passwordCost: bcrypt.DefaultCost,
+		adWrites:     &sync.Mutex{},
+
+		categoryWrites: &sync.Mutex{},
+
+		reportThreshold: DefaultReportThreshold,
+	}
+}
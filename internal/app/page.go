@@ -0,0 +1,172 @@
+package app
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"homework9/internal/ads"
+	"homework9/internal/geo"
+)
+
+var (
+	ErrInvalidCursor = errors.New("invalid page cursor")
+	ErrInvalidSort   = errors.New("invalid sort field")
+)
+
+const (
+	DefaultPageSize = 20
+	MaxPageSize     = 100
+)
+
+// PageRequest - какую страницу выдачи вернуть. Cursor - NextCursor предыдущей страницы,
+// пустой - первая страница. Курсор действует только с той сортировкой, с которой он выдан
+type PageRequest struct {
+	Sort ads.Sort
+	// Limit 0 - значение по умолчанию (DefaultPageSize), больше MaxPageSize не возвращается
+	Limit  int
+	Cursor string
+}
+
+type AdsPage struct {
+	Ads []ads.Ad
+	// NextCursor пустой на последней странице
+	NextCursor string
+}
+
+func (m MyApp) ListPublishedAds(ctx context.Context, p PageRequest) (*AdsPage, error) {
+	v, err := m.viewer(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return m.findPage(v, p, func(page ads.Page) ([]ads.Ad, error) {
+		return m.adRepository.ListPublishedAds(ctx, page)
+	})
+}
+
+// GetAdsByFilter возвращает страницу объявлений, подходящих под все условия opts. Отбор выполняет хранилище,
+// поэтому категории заранее раскрываются в поддеревья
+func (m MyApp) GetAdsByFilter(ctx context.Context, opts FilterOpts, p PageRequest) (*AdsPage, error) {
+	v, err := m.viewer(ctx)
+	if err != nil {
+		return nil, err
+	}
+	opts.VisibleTo = v.visibleTo()
+	if err := m.normalizePriceFilter(&opts); err != nil {
+		return nil, err
+	}
+	if err := normalizeGeoFilter(opts, &p.Sort); err != nil {
+		return nil, err
+	}
+	opts.CategoryIDs, err = m.expandCategories(ctx, opts.CategoryIDs)
+	if err != nil {
+		return nil, err
+	}
+	return m.findPage(v, p, func(page ads.Page) ([]ads.Ad, error) {
+		return m.adRepository.FindAds(ctx, opts, page)
+	})
+}
+
+// findPage запрашивает у хранилища на одно объявление больше страницы: если оно нашлось,
+// у выдачи есть продолжение и последнее объявление страницы становится курсором. Объявления страницы
+// показываются так, как их положено видеть v
+func (m MyApp) findPage(v adViewer, p PageRequest, find func(ads.Page) ([]ads.Ad, error)) (*AdsPage, error) {
+	if !p.Sort.Field.Valid() || p.Sort.Field == ads.SortByDistance && p.Sort.Origin == nil {
+		return nil, ErrInvalidSort
+	}
+	if p.Sort.Origin != nil && !p.Sort.Origin.Valid() {
+		return nil, ErrInvalidLocation
+	}
+	limit := p.Limit
+	if limit <= 0 {
+		limit = DefaultPageSize
+	}
+	if limit > MaxPageSize {
+		limit = MaxPageSize
+	}
+	page := ads.Page{Sort: p.Sort, Limit: limit + 1}
+	if p.Cursor != "" {
+		after, err := decodeCursor(p.Cursor, p.Sort)
+		if err != nil {
+			return nil, err
+		}
+		page.After = &after
+	}
+
+	list, err := find(page)
+	if err != nil {
+		return nil, err
+	}
+	res := &AdsPage{Ads: list}
+	if len(list) > limit {
+		res.Ads = list[:limit]
+		res.NextCursor = encodeCursor(p.Sort, p.Sort.CursorOf(list[limit-1]))
+	}
+	v.redactAll(res.Ads)
+	return res, nil
+}
+
+// cursorToken - содержимое курсора. Клиенту он отдаётся как непрозрачная строка,
+// в которой хранится только ключ текущей сортировки
+type cursorToken struct {
+	Field ads.SortField `json:"f"`
+	Desc  bool          `json:"d,omitempty"`
+	ID    int64         `json:"id"`
+	Time  int64         `json:"t,omitempty"`
+	Title string        `json:"s,omitempty"`
+	Price int64         `json:"p,omitempty"`
+	// Distance и Origin - расстояние и точка отсчёта для сортировки по расстоянию
+	Distance float64    `json:"km,omitempty"`
+	Origin   *geo.Point `json:"o,omitempty"`
+}
+
+func encodeCursor(s ads.Sort, c ads.Cursor) string {
+	tok := cursorToken{Field: s.Field, Desc: s.Desc, ID: c.ID}
+	var t time.Time
+	switch s.Field {
+	case ads.SortByCreated:
+		t = c.Created
+	case ads.SortByModified:
+		t = c.Modified
+	case ads.SortByTitle:
+		tok.Title = c.Title
+	case ads.SortByPrice:
+		tok.Price = c.Price
+	case ads.SortByDistance:
+		tok.Distance, tok.Origin = c.Distance, s.Origin
+	}
+	// нулевое время хранилища сравнивают как 0, см. ads.Sort.Less
+	if !t.IsZero() {
+		tok.Time = t.UnixNano()
+	}
+	data, _ := json.Marshal(tok)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeCursor(cursor string, s ads.Sort) (ads.Cursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return ads.Cursor{}, ErrInvalidCursor
+	}
+	var tok cursorToken
+	if err := json.Unmarshal(data, &tok); err != nil {
+		return ads.Cursor{}, ErrInvalidCursor
+	}
+	if tok.Field != s.Field || tok.Desc != s.Desc {
+		return ads.Cursor{}, ErrInvalidCursor
+	}
+	// расстояния от другой точки дают другой порядок
+	if s.Field == ads.SortByDistance && (tok.Origin == nil || *tok.Origin != *s.Origin) {
+		return ads.Cursor{}, ErrInvalidCursor
+	}
+	c := ads.Cursor{ID: tok.ID, Title: tok.Title, Price: tok.Price, Distance: tok.Distance}
+	switch s.Field {
+	case ads.SortByCreated:
+		c.Created = time.Unix(0, tok.Time)
+	case ads.SortByModified:
+		c.Modified = time.Unix(0, tok.Time)
+	}
+	return c, nil
+}
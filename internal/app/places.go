@@ -0,0 +1,49 @@
+package app
+
+import (
+	"errors"
+	"strings"
+	"unicode/utf8"
+
+	"homework9/internal/ads"
+	"homework9/internal/geo"
+)
+
+var (
+	ErrInvalidLocation = errors.New("location must have latitude in [-90, 90], longitude in [-180, 180] and a city up to 100 characters")
+	ErrInvalidArea     = errors.New("search area must be a circle with a positive radius or a box with min not greater than max")
+)
+
+// MaxCityLength - длина названия города в символах
+const MaxCityLength = 100
+
+// normalizeLocation проверяет координаты и обрезает пробелы вокруг названия города
+func normalizeLocation(l ads.Location) (ads.Location, error) {
+	l.City = strings.TrimSpace(l.City)
+	if !l.Point().Valid() || utf8.RuneCountInString(l.City) > MaxCityLength {
+		return ads.Location{}, ErrInvalidLocation
+	}
+	return l, nil
+}
+
+// normalizeGeoFilter проверяет область поиска и выбирает точку отсчёта для сортировки по расстоянию,
+// если клиент её не задал: центр круга, а без него - центр прямоугольника
+func normalizeGeoFilter(f FilterOpts, s *ads.Sort) error {
+	if f.Near != nil && !f.Near.Valid() || f.Box != nil && !f.Box.Valid() {
+		return ErrInvalidArea
+	}
+	if s.Field != ads.SortByDistance || s.Origin != nil {
+		return nil
+	}
+	var origin geo.Point
+	switch {
+	case f.Near != nil:
+		origin = f.Near.Center
+	case f.Box != nil:
+		origin = f.Box.Center()
+	default:
+		return nil
+	}
+	s.Origin = &origin
+	return nil
+}
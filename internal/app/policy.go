@@ -0,0 +1,127 @@
+package app
+
+import (
+	"context"
+	"errors"
+
+	"homework9/internal/users"
+)
+
+// Action - действие, разрешение на которое проверяет Policy
+type Action string
+
+const (
+	ActionCreateAd    Action = "ad.create"
+	ActionUpdateAd    Action = "ad.update"
+	ActionPublishAd   Action = "ad.publish"
+	ActionUnpublishAd Action = "ad.unpublish"
+	ActionDeleteAd    Action = "ad.delete"
+	// ActionReviewAd - просмотр очереди модерации, одобрение и отклонение объявлений
+	ActionReviewAd   Action = "ad.review"
+	ActionUpdateUser Action = "user.update"
+	ActionDeleteUser Action = "user.delete"
+	ActionSetRole    Action = "user.set_role"
+	// ActionManageCategories - создание, изменение и удаление категорий
+	ActionManageCategories Action = "category.manage"
+	// ActionCreateReport - жалоба на объявление или пользователя, владелец - сам жалующийся
+	ActionCreateReport Action = "report.create"
+	// ActionReviewReports - просмотр, подтверждение и отклонение жалоб
+	ActionReviewReports Action = "report.review"
+	// ActionManageFavorites - добавление, удаление и просмотр своего избранного
+	ActionManageFavorites Action = "favorite.manage"
+	// ActionSendMessages - переписка об объявлениях и блокировка собеседников, владелец - сам пользователь
+	ActionSendMessages Action = "message.send"
+	// ActionWatchEvents - подписка на свои события, владелец - сам пользователь
+	ActionWatchEvents Action = "event.watch"
+	// ActionViewUnpublishedAd - просмотр неопубликованных объявлений, решений модератора и замечаний проверок
+	ActionViewUnpublishedAd Action = "ad.view_unpublished"
+)
+
+// noOwner - владелец ресурсов, у которых его нет (например, категорий). ID выдаются с нуля, поэтому -1 ни с кем не совпадает
+const noOwner int64 = -1
+
+// Actor - пользователь, от имени которого выполняется запрос
+type Actor struct {
+	ID   int64
+	Role users.Role
+}
+
+// Policy решает, может ли actor выполнить action над ресурсом, которым владеет ownerID.
+// Для объявления владелец - автор, для пользователя - он сам
+type Policy interface {
+	Authorize(actor Actor, action Action, ownerID int64) error
+}
+
+// Rule - кто может выполнять действие
+type Rule struct {
+	// Owner разрешает действие владельцу ресурса
+	Owner bool
+	// Roles разрешает действие над любым ресурсом
+	Roles []users.Role
+}
+
+// RolePolicy - политика из таблицы правил. Действие без правила запрещено всем
+type RolePolicy map[Action]Rule
+
+func (p RolePolicy) Authorize(actor Actor, action Action, ownerID int64) error {
+	rule, ok := p[action]
+	if !ok {
+		return ErrAccessDenied
+	}
+	if rule.Owner && actor.ID == ownerID {
+		return nil
+	}
+	for _, role := range rule.Roles {
+		if actor.Role == role {
+			return nil
+		}
+	}
+	return ErrAccessDenied
+}
+
+// DefaultPolicy: модератор проверяет и снимает с публикации любые объявления и рассматривает жалобы, администратор вдобавок
+// удаляет любые объявления и пользователей, назначает роли и управляет категориями. Содержимое объявления меняет только автор,
+// неопубликованное объявление видят только автор и модераторы
+func DefaultPolicy() RolePolicy {
+	return RolePolicy{
+		ActionCreateAd:    {Owner: true},
+		ActionUpdateAd:    {Owner: true},
+		ActionPublishAd:   {Owner: true},
+		ActionUnpublishAd: {Owner: true, Roles: []users.Role{users.RoleModerator, users.RoleAdmin}},
+		ActionDeleteAd:    {Owner: true, Roles: []users.Role{users.RoleAdmin}},
+		ActionReviewAd:    {Roles: []users.Role{users.RoleModerator, users.RoleAdmin}},
+		ActionUpdateUser:  {Owner: true, Roles: []users.Role{users.RoleAdmin}},
+		ActionDeleteUser:  {Owner: true, Roles: []users.Role{users.RoleAdmin}},
+		ActionSetRole:     {Roles: []users.Role{users.RoleAdmin}},
+
+		ActionManageCategories: {Roles: []users.Role{users.RoleAdmin}},
+		ActionCreateReport:     {Owner: true},
+		ActionReviewReports:    {Roles: []users.Role{users.RoleModerator, users.RoleAdmin}},
+		ActionManageFavorites:  {Owner: true},
+		ActionSendMessages:     {Owner: true},
+		ActionWatchEvents:      {Owner: true},
+
+		ActionViewUnpublishedAd: {Owner: true, Roles: []users.Role{users.RoleModerator, users.RoleAdmin}},
+	}
+}
+
+// authorize проверяет по политике, что пользователь из ctx может выполнить action над ресурсом ownerID.
+// Роль читается из userRepo, поэтому внутри транзакции нужно передавать её репозиторий.
+// Токен удалённого пользователя больше ничего не разрешает. Действия самого приложения (asSystem) разрешены всегда
+func (m MyApp) authorize(ctx context.Context, userRepo users.UserRepository, action Action, ownerID int64) (Actor, error) {
+	if isSystem(ctx) {
+		return Actor{ID: noOwner}, nil
+	}
+	userID, err := actingUser(ctx)
+	if err != nil {
+		return Actor{}, err
+	}
+	u, err := userRepo.GetUserByID(ctx, userID)
+	if errors.Is(err, users.ErrNotFound) {
+		return Actor{}, ErrUnauthenticated
+	} else if err != nil {
+		return Actor{}, err
+	}
+	actor := Actor{ID: u.ID, Role: u.EffectiveRole()}
+	return actor, m.policy.Authorize(actor, action, ownerID)
+}
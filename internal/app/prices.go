@@ -0,0 +1,71 @@
+package app
+
+import (
+	"context"
+	"errors"
+
+	"homework9/internal/ads"
+	"homework9/internal/money"
+)
+
+var (
+	ErrInvalidPrice    = errors.New("price must be a non-negative amount in minor units with a currency")
+	ErrInvalidCurrency = errors.New("unknown currency")
+	ErrNoRate          = errors.New("no exchange rate for currency")
+)
+
+// DefaultCurrency - базовая валюта, если курсы не заданы через WithRates
+const DefaultCurrency = "RUB"
+
+// ConvertPrice переводит цену в валюту currency по таблице курсов. Цена без валюты возвращается как есть
+func (m MyApp) ConvertPrice(_ context.Context, p ads.Price, currency string) (ads.Price, error) {
+	currency = money.Normalize(currency)
+	if !money.Known(currency) {
+		return ads.Price{}, ErrInvalidCurrency
+	}
+	if p.Currency == "" {
+		return p, nil
+	}
+	amount, err := m.rates.Convert(p.Amount, p.Currency, currency)
+	if errors.Is(err, money.ErrNoRate) {
+		return ads.Price{}, ErrNoRate
+	} else if errors.Is(err, money.ErrUnknownCurrency) {
+		return ads.Price{}, ErrInvalidCurrency
+	} else if err != nil {
+		return ads.Price{}, err
+	}
+	return ads.Price{Amount: amount, Currency: currency, Negotiable: p.Negotiable}, nil
+}
+
+// normalizePrice приводит код валюты к ISO 4217 и проверяет сумму. Без валюты сумма должна быть нулевой
+func normalizePrice(p ads.Price) (ads.Price, error) {
+	p.Currency = money.Normalize(p.Currency)
+	if p.Currency == "" {
+		if p.Amount != 0 {
+			return ads.Price{}, ErrInvalidPrice
+		}
+		return p, nil
+	}
+	if !money.Known(p.Currency) {
+		return ads.Price{}, ErrInvalidCurrency
+	}
+	if p.Amount < 0 || p.Amount > money.MaxAmount {
+		return ads.Price{}, ErrInvalidPrice
+	}
+	return p, nil
+}
+
+// normalizePriceFilter проверяет ценовые условия фильтра. Границы без валюты относятся к базовой валюте
+func (m MyApp) normalizePriceFilter(f *FilterOpts) error {
+	f.Currency = money.Normalize(f.Currency)
+	if f.Currency == "" && (f.PriceMin != nil || f.PriceMax != nil) {
+		f.Currency = m.rates.Base()
+	}
+	if f.Currency != "" && !money.Known(f.Currency) {
+		return ErrInvalidCurrency
+	}
+	if (f.PriceMin != nil && *f.PriceMin < 0) || (f.PriceMax != nil && *f.PriceMax < 0) {
+		return ErrInvalidPrice
+	}
+	return nil
+}
@@ -0,0 +1,218 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"unicode/utf8"
+
+	"homework9/internal/ads"
+	"homework9/internal/notifications"
+	"homework9/internal/reports"
+	"homework9/internal/users"
+)
+
+var (
+	ErrReportsDisabled = errors.New("reports are not configured")
+	ErrInvalidReport   = errors.New("report needs a target, a known reason and a comment of at most 1000 characters, reason other needs a comment")
+	ErrSelfReport      = errors.New("cannot report yourself or your own ad")
+	ErrAlreadyReported = errors.New("already reported")
+	ErrReportClosed    = errors.New("report is already closed")
+	ErrInvalidNote     = errors.New("note is at most 500 characters")
+)
+
+const (
+	// MaxReportCommentLength - длина комментария к жалобе в символах
+	MaxReportCommentLength = 1000
+	DefaultReportThreshold = 3
+)
+
+type ReportsPage struct {
+	Reports []reports.Report
+	// NextCursor пустой на последней странице
+	NextCursor string
+}
+
+func (m MyApp) CreateReport(ctx context.Context, target reports.Target, targetID int64, reason reports.Reason, comment string) (*reports.Report, error) {
+	if m.reports == nil {
+		return nil, ErrReportsDisabled
+	}
+	comment = strings.TrimSpace(comment)
+	if !target.Valid() || !reason.Valid() || utf8.RuneCountInString(comment) > MaxReportCommentLength ||
+		reason == reports.ReasonOther && comment == "" {
+		return nil, ErrInvalidReport
+	}
+	reporterID, err := actingUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := m.authorize(ctx, m.userRepository, ActionCreateReport, reporterID); err != nil {
+		return nil, err
+	}
+	owner, err := m.reportTargetOwner(ctx, target, targetID)
+	if err != nil {
+		return nil, err
+	}
+	if owner == reporterID {
+		return nil, ErrSelfReport
+	}
+
+	r := reports.Report{ReporterID: reporterID, Target: target, TargetID: targetID, Reason: reason, Comment: comment,
+		Status: reports.StatusOpen, Created: m.now()}
+	r.ID, err = m.reports.AddReport(ctx, r)
+	if errors.Is(err, reports.ErrDuplicate) {
+		return nil, ErrAlreadyReported
+	} else if err != nil {
+		return nil, err
+	}
+	if target == reports.TargetAd {
+		// жалоба уже принята, поэтому неудачное снятие только пишется в журнал
+		if err := m.suspendReported(ctx, targetID); err != nil {
+			log.Printf("suspend reported ad %d: %v", targetID, err)
+		}
+	}
+	return &r, nil
+}
+
+// suspendReported снимает опубликованное объявление, на которое набралось m.reportThreshold открытых жалоб.
+// Снимает его само приложение через UpdateStatusById, как снял бы модератор
+func (m MyApp) suspendReported(ctx context.Context, adID int64) error {
+	open, err := m.reportedAd(ctx, adID)
+	if err != nil || open == 0 {
+		return err
+	}
+	a, err := m.adRepository.GetAdById(ctx, adID)
+	if err != nil || !a.Published {
+		return err
+	}
+	a, err = m.UpdateStatusById(asSystem(ctx), adID, false)
+	if err != nil {
+		return err
+	}
+	m.notify(ctx, notifications.Notification{UserID: a.AuthorID, Kind: notifications.KindAdSuspended, AdID: a.ID,
+		Text: fmt.Sprintf("unpublished after %d reports", open), Created: a.Modified})
+	return nil
+}
+
+// reportedAd возвращает число открытых жалоб на объявление, если их не меньше m.reportThreshold, иначе 0.
+// Пока жалобы не рассмотрены, такое объявление публикуется только после проверки модератором
+func (m MyApp) reportedAd(ctx context.Context, adID int64) (int, error) {
+	if m.reports == nil || m.reportThreshold <= 0 {
+		return 0, nil
+	}
+	open, err := m.reports.FindReports(ctx, reports.Filter{Status: reports.StatusOpen, Target: reports.TargetAd, TargetIDs: []int64{adID}}, 0, m.reportThreshold)
+	if err != nil || len(open) < m.reportThreshold {
+		return 0, err
+	}
+	return len(open), nil
+}
+
+func (m MyApp) ListReports(ctx context.Context, f reports.Filter, p PageRequest) (*ReportsPage, error) {
+	if m.reports == nil {
+		return nil, ErrReportsDisabled
+	}
+	if _, err := m.authorize(ctx, m.userRepository, ActionReviewReports, noOwner); err != nil {
+		return nil, err
+	}
+	if f.Status != "" && !f.Status.Valid() || f.Target != "" && !f.Target.Valid() {
+		return nil, ErrInvalidReport
+	}
+	limit := p.Limit
+	if limit <= 0 {
+		limit = DefaultPageSize
+	}
+	if limit > MaxPageSize {
+		limit = MaxPageSize
+	}
+	// курсор тот же, что у объявлений по ID
+	var after ads.Cursor
+	if p.Cursor != "" {
+		var err error
+		after, err = decodeCursor(p.Cursor, ads.Sort{})
+		if err != nil {
+			return nil, err
+		}
+	}
+	list, err := m.reports.FindReports(ctx, f, after.ID, limit+1)
+	if err != nil {
+		return nil, err
+	}
+	res := &ReportsPage{Reports: list}
+	if len(list) > limit {
+		res.Reports = list[:limit]
+		res.NextCursor = encodeCursor(ads.Sort{}, ads.Cursor{ID: list[limit-1].ID})
+	}
+	return res, nil
+}
+
+// ResolveReport подтверждает жалобу. Что делать с объявлением или пользователем, модератор решает отдельно
+func (m MyApp) ResolveReport(ctx context.Context, id int64, note string) (*reports.Report, error) {
+	return m.closeReport(ctx, id, reports.StatusResolved, note)
+}
+
+// DismissReport отклоняет жалобу. Когда открытых жалоб становится меньше порога, снятое по ним объявление
+// автор снова публикует сам, без проверки модератором
+func (m MyApp) DismissReport(ctx context.Context, id int64, note string) (*reports.Report, error) {
+	return m.closeReport(ctx, id, reports.StatusDismissed, note)
+}
+
+func (m MyApp) closeReport(ctx context.Context, id int64, status reports.Status, note string) (*reports.Report, error) {
+	if m.reports == nil {
+		return nil, ErrReportsDisabled
+	}
+	note = strings.TrimSpace(note)
+	if utf8.RuneCountInString(note) > MaxReasonLength {
+		return nil, ErrInvalidNote
+	}
+	actor, err := m.authorize(ctx, m.userRepository, ActionReviewReports, noOwner)
+	if err != nil {
+		return nil, err
+	}
+	r, err := m.reports.GetReport(ctx, id)
+	if errors.Is(err, reports.ErrNotFound) {
+		return nil, ErrNotFound
+	} else if err != nil {
+		return nil, err
+	}
+	// жалобы на себя и свои объявления модератор не рассматривает, удалённая цель никому не принадлежит
+	owner, err := m.reportTargetOwner(ctx, r.Target, r.TargetID)
+	if err != nil && !errors.Is(err, ErrNotFound) {
+		return nil, err
+	}
+	if err == nil && owner == actor.ID {
+		return nil, ErrAccessDenied
+	}
+	if r.Status != reports.StatusOpen {
+		return nil, ErrReportClosed
+	}
+	r.Status = status
+	r.Resolution = reports.Resolution{ModeratorID: actor.ID, Note: note, At: m.now()}
+	err = m.reports.UpdateReport(ctx, id, *r)
+	if errors.Is(err, reports.ErrNotFound) {
+		return nil, ErrNotFound
+	} else if err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// reportTargetOwner возвращает владельца цели жалобы: автора объявления или самого пользователя.
+// Для несуществующей цели - ErrNotFound
+func (m MyApp) reportTargetOwner(ctx context.Context, target reports.Target, id int64) (int64, error) {
+	if target == reports.TargetAd {
+		a, err := m.adRepository.GetAdById(ctx, id)
+		if err != nil {
+			return 0, adError(err)
+		}
+		return a.AuthorID, nil
+	}
+	u, err := m.userRepository.GetUserByID(ctx, id)
+	if errors.Is(err, users.ErrNotFound) {
+		return 0, ErrNotFound
+	} else if err != nil {
+		return 0, err
+	}
+	return u.ID, nil
+}
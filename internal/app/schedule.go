@@ -0,0 +1,250 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sync"
+	"time"
+
+	"homework9/internal/ads"
+	"homework9/internal/users"
+)
+
+var (
+	ErrInvalidSchedule = errors.New("publish time must be in the future and before expiration")
+	ErrAdExpired       = errors.New("ad has expired, renew it to publish again")
+)
+
+// DefaultScheduleInterval - как часто Scheduler проверяет сроки, если интервал не задан
+const DefaultScheduleInterval = time.Minute
+
+// ScheduleResult - что сделал один проход планировщика. Submitted - сколько объявлений с наступившей
+// публикацией ушли на проверку модератору
+type ScheduleResult struct {
+	Published int
+	Submitted int
+	Expired   int
+}
+
+// ScheduleAd назначает публикацию объявления на publishAt и снятие на expiresAt. Нулевое время отменяет срок.
+// Опубликованному объявлению publishAt задать нельзя
+func (m MyApp) ScheduleAd(ctx context.Context, id int64, publishAt time.Time, expiresAt time.Time) (*ads.Ad, error) {
+	now := m.now()
+	if !publishAt.IsZero() && !publishAt.After(now) || !expiresAt.IsZero() && !expiresAt.After(now) ||
+		!publishAt.IsZero() && !expiresAt.IsZero() && !publishAt.Before(expiresAt) {
+		return nil, ErrInvalidSchedule
+	}
+	return m.changeSchedule(ctx, id, func(a *ads.Ad) error {
+		if a.Published && !publishAt.IsZero() {
+			return ErrInvalidSchedule
+		}
+		a.PublishAt, a.ExpiresAt = publishAt, expiresAt
+		return nil
+	})
+}
+
+// RenewAd продлевает объявление на срок жизни из WithAdLifetime от текущего момента. Истёкшее
+// объявление снова публикуется, как при UpdateStatusById. Без срока жизни объявление больше не истекает
+func (m MyApp) RenewAd(ctx context.Context, id int64) (*ads.Ad, error) {
+	now := m.now()
+	reported, err := m.reportedAd(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return m.changeSchedule(ctx, id, func(a *ads.Ad) error {
+		republish := !a.Published && expired(*a, now)
+		a.ExpiresAt = m.expiry(now)
+		if republish {
+			m.requestPublication(a, now, reported > 0)
+		}
+		return nil
+	})
+}
+
+func (m MyApp) changeSchedule(ctx context.Context, id int64, change func(a *ads.Ad) error) (*ads.Ad, error) {
+	var changed, was ads.Ad
+	err := m.writeAds(ctx, func(adRepo ads.AdRepository, userRepo users.UserRepository) error {
+		a, err := adRepo.GetAdById(ctx, id)
+		if err != nil {
+			return err
+		}
+		if _, err := m.authorize(ctx, userRepo, ActionPublishAd, a.AuthorID); err != nil {
+			return err
+		}
+		changed, was = *a, *a
+		if err := change(&changed); err != nil {
+			return err
+		}
+		return adRepo.UpdateById(ctx, id, changed)
+	}, func() {
+		changed.Version++
+		m.adChanged(ctx, &was, &changed)
+	})
+	if err != nil {
+		return nil, adError(err)
+	}
+	return &changed, nil
+}
+
+// RunSchedule публикует объявления, чей PublishAt наступил, и снимает те, чей ExpiresAt прошёл.
+// С модерацией непроверенное объявление, а также объявление с открытыми жалобами вместо публикации уходит на проверку.
+// Выполняется от имени системы, без проверки прав. Объявление, изменённое между поиском и записью,
+// пропускается и обработается при следующем проходе
+func (m MyApp) RunSchedule(ctx context.Context) (ScheduleResult, error) {
+	var res ScheduleResult
+	now := m.now()
+
+	due, err := m.adRepository.FindAds(ctx, FilterOpts{Published: ads.OnlyUnpublished, PublishBefore: now}, ads.Page{})
+	if err != nil {
+		return res, err
+	}
+	for _, ad := range due {
+		reported, err := m.reportedAd(ctx, ad.ID)
+		if err != nil {
+			return res, err
+		}
+		changed, err := m.applySchedule(ctx, ad, func(a *ads.Ad) bool {
+			if a.Published || a.PublishAt.IsZero() || !a.PublishAt.Before(now) {
+				return false
+			}
+			a.PublishAt = time.Time{}
+			// срок публикации прошёл, пока объявление ждало: публиковать уже нечего
+			if expired(*a, now) {
+				return true
+			}
+			m.requestPublication(a, now, reported > 0)
+			return true
+		})
+		if err != nil {
+			return res, err
+		}
+		switch {
+		case changed == nil:
+		case changed.Published:
+			res.Published++
+		case changed.Status == ads.StatusPending && ad.EffectiveStatus() != ads.StatusPending:
+			res.Submitted++
+		}
+	}
+
+	stale, err := m.adRepository.FindAds(ctx, FilterOpts{Published: ads.OnlyPublished, ExpiresBefore: now}, ads.Page{})
+	if err != nil {
+		return res, err
+	}
+	for _, ad := range stale {
+		changed, err := m.applySchedule(ctx, ad, func(a *ads.Ad) bool {
+			if !a.Published || !expired(*a, now) {
+				return false
+			}
+			withdraw(a)
+			return true
+		})
+		if err != nil {
+			return res, err
+		}
+		if changed != nil {
+			res.Expired++
+		}
+	}
+	return res, nil
+}
+
+// applySchedule применяет change к свежей версии объявления и возвращает изменённое объявление.
+// nil - объявление изменилось или удалено и change к нему больше не относится
+func (m MyApp) applySchedule(ctx context.Context, ad ads.Ad, change func(a *ads.Ad) bool) (*ads.Ad, error) {
+	var changed *ads.Ad
+	var was ads.Ad
+	err := m.writeAds(ctx, func(adRepo ads.AdRepository, _ users.UserRepository) error {
+		changed = nil
+		a, err := adRepo.GetAdById(ctx, ad.ID)
+		if err != nil {
+			return err
+		}
+		next := *a
+		was = *a
+		if !change(&next) {
+			return nil
+		}
+		changed = &next
+		return adRepo.UpdateById(ctx, ad.ID, next)
+	}, func() {
+		if changed != nil {
+			changed.Version++
+			m.adChanged(ctx, &was, changed)
+		}
+	})
+	if errors.Is(err, ads.ErrNotFound) || errors.Is(err, ads.ErrVersionConflict) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	return changed, nil
+}
+
+// expiry - ExpiresAt объявления, опубликованного в now. Нулевое, если срок жизни не задан
+func (m MyApp) expiry(now time.Time) time.Time {
+	if m.adLifetime <= 0 {
+		return time.Time{}
+	}
+	return now.Add(m.adLifetime)
+}
+
+func expired(a ads.Ad, now time.Time) bool {
+	return !a.ExpiresAt.IsZero() && !a.ExpiresAt.After(now)
+}
+
+// Scheduler вызывает App.RunSchedule раз в интервал, так что объявление публикуется или снимается
+// не позже чем через интервал после срока
+type Scheduler struct {
+	app      App
+	interval time.Duration
+
+	once   sync.Once
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// NewScheduler создаёт остановленный планировщик. Интервал 0 - DefaultScheduleInterval
+func NewScheduler(a App, interval time.Duration) *Scheduler {
+	if interval <= 0 {
+		interval = DefaultScheduleInterval
+	}
+	return &Scheduler{app: a, interval: interval, done: make(chan struct{})}
+}
+
+// Start запускает проходы в фоне, первый - сразу
+func (s *Scheduler) Start() {
+	ctx, cancel := context.WithCancel(context.Background())
+	s.cancel = cancel
+	go s.loop(ctx)
+}
+
+func (s *Scheduler) loop(ctx context.Context) {
+	defer close(s.done)
+	t := time.NewTicker(s.interval)
+	defer t.Stop()
+	for {
+		if _, err := s.app.RunSchedule(ctx); err != nil && ctx.Err() == nil {
+			// сроки никуда не денутся, попробуем в следующий раз
+			log.Printf("scheduler: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+		}
+	}
+}
+
+// Stop прерывает текущий проход и ждёт, пока планировщик остановится. Повторный Stop ничего не делает
+func (s *Scheduler) Stop() {
+	s.once.Do(func() {
+		if s.cancel == nil {
+			close(s.done)
+			return
+		}
+		s.cancel()
+	})
+	<-s.done
+}
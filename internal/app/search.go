@@ -0,0 +1,69 @@
+package app
+
+import (
+	"context"
+	"errors"
+
+	"homework9/internal/ads"
+)
+
+var ErrEmptyQuery = errors.New("empty search query")
+
+const (
+	DefaultSearchLimit = 20
+	MaxSearchLimit     = 100
+)
+
+// SearchResult - найденное объявление и его релевантность по BM25
+type SearchResult struct {
+	Ad    ads.Ad
+	Score float64
+}
+
+func (m MyApp) SearchAds(ctx context.Context, query string, categoryID int64, limit int) ([]SearchResult, error) {
+	if query == "" {
+		return nil, ErrEmptyQuery
+	}
+	v, err := m.viewer(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var inCategory map[int64]bool
+	if categoryID != 0 {
+		ids, err := m.expandCategories(ctx, []int64{categoryID})
+		if err != nil {
+			return nil, err
+		}
+		inCategory = make(map[int64]bool, len(ids))
+		for _, id := range ids {
+			inCategory[id] = true
+		}
+	}
+	if limit <= 0 {
+		limit = DefaultSearchLimit
+	}
+	if limit > MaxSearchLimit {
+		limit = MaxSearchLimit
+	}
+
+	// индекс хранит и неопубликованные объявления, чтобы смена статуса не требовала переиндексации,
+	// поэтому кандидатов больше, чем limit, и лишние отсеиваются здесь
+	results := make([]SearchResult, 0)
+	for _, hit := range m.index.Search(query) {
+		ad, err := m.adRepository.GetAdById(ctx, hit.ID)
+		if errors.Is(err, ads.ErrNotFound) {
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+		if !ad.Published || (inCategory != nil && !inCategory[ad.CategoryID]) {
+			continue
+		}
+		v.redact(ad)
+		results = append(results, SearchResult{Ad: *ad, Score: hit.Score})
+		if len(results) == limit {
+			break
+		}
+	}
+	return results, nil
+}
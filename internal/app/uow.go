@@ -0,0 +1,47 @@
+package app
+
+import (
+	"context"
+
+	"homework9/internal/ads"
+	"homework9/internal/users"
+)
+
+// UnitOfWork выполняет fn атомарно: изменения, сделанные через переданные в fn репозитории,
+// фиксируются вместе, а если fn вернула ошибку - откатываются вместе.
+// Внутри fn нужно работать только с переданными репозиториями.
+type UnitOfWork interface {
+	Do(ctx context.Context, fn func(adRepo ads.AdRepository, userRepo users.UserRepository) error) error
+}
+
+// writeAds - uow.Do для записей, меняющих объявления. Такие записи выполняются по одной, а committed
+// вызывается после фиксации до начала следующей записи, поэтому события из committed попадают в ленту
+// в порядке фиксации изменений
+func (m MyApp) writeAds(ctx context.Context, fn func(adRepo ads.AdRepository, userRepo users.UserRepository) error, committed func()) error {
+	m.adWrites.Lock()
+	defer m.adWrites.Unlock()
+	return m.commitAds(ctx, fn, committed)
+}
+
+// writeAdsIn - writeAds для объявления, которое кладётся в категорию categoryID (0 - категория не меняется).
+// Категория проверяется под той же блокировкой, под которой DeleteCategory ищет её объявления,
+// поэтому объявление не попадёт в удалённую категорию
+func (m MyApp) writeAdsIn(ctx context.Context, categoryID int64, fn func(adRepo ads.AdRepository, userRepo users.UserRepository) error, committed func()) error {
+	m.adWrites.Lock()
+	defer m.adWrites.Unlock()
+	if categoryID != 0 {
+		if err := m.requireCategory(ctx, categoryID); err != nil {
+			return err
+		}
+	}
+	return m.commitAds(ctx, fn, committed)
+}
+
+// commitAds выполняет fn и после фиксации committed. Вызывается под adWrites
+func (m MyApp) commitAds(ctx context.Context, fn func(adRepo ads.AdRepository, userRepo users.UserRepository) error, committed func()) error {
+	if err := m.uow.Do(ctx, fn); err != nil {
+		return err
+	}
+	committed()
+	return nil
+}
@@ -0,0 +1,90 @@
+package app
+
+import (
+	"context"
+	"errors"
+
+	"homework9/internal/ads"
+)
+
+// adViewer - кто читает объявления. Неопубликованные объявления, решение модератора и замечания проверок
+// содержимого видят только те, кому политика разрешает ActionViewUnpublishedAd, остальным достаются
+// опубликованные объявления без них
+type adViewer struct {
+	policy Policy
+	// actor nil - аноним или пользователь удалён
+	actor  *Actor
+	system bool
+}
+
+// viewer определяет читателя по ctx. Анонимный запрос не ошибка: такой читатель видит только опубликованное
+func (m MyApp) viewer(ctx context.Context) (adViewer, error) {
+	v := adViewer{policy: m.policy, system: isSystem(ctx)}
+	if v.system {
+		return v, nil
+	}
+	actor, err := m.authorize(ctx, m.userRepository, ActionViewUnpublishedAd, noOwner)
+	if errors.Is(err, ErrUnauthenticated) {
+		return v, nil
+	} else if err != nil && !errors.Is(err, ErrAccessDenied) {
+		return adViewer{}, err
+	}
+	v.actor = &actor
+	return v, nil
+}
+
+// details - читателю видны неопубликованное объявление автора authorID, решение модератора и замечания по нему
+func (v adViewer) details(authorID int64) bool {
+	if v.system {
+		return true
+	}
+	return v.actor != nil && v.policy.Authorize(*v.actor, ActionViewUnpublishedAd, authorID) == nil
+}
+
+func (v adViewer) canSee(ad ads.Ad) bool {
+	return ad.Published || v.details(ad.AuthorID)
+}
+
+// visibleTo - условие для ads.Filter.VisibleTo: nil, если читателю видны все объявления
+func (v adViewer) visibleTo() *int64 {
+	if v.details(noOwner) {
+		return nil
+	}
+	id := noOwner
+	if v.actor != nil {
+		id = v.actor.ID
+	}
+	return &id
+}
+
+// redact убирает из объявления то, что читателю видеть не положено
+func (v adViewer) redact(ad *ads.Ad) {
+	if !v.details(ad.AuthorID) {
+		ad.Review = ads.Review{}
+		ad.Flags = nil
+	}
+}
+
+// redacted - как redact, но объявление копируется: события делят одно объявление между подписчиками
+func (v adViewer) redacted(ad *ads.Ad) *ads.Ad {
+	if ad == nil {
+		return nil
+	}
+	cp := *ad
+	v.redact(&cp)
+	return &cp
+}
+
+// visible - как redacted, но объявление, которое читателю не видно, становится nil
+func (v adViewer) visible(ad *ads.Ad) *ads.Ad {
+	if ad == nil || !v.canSee(*ad) {
+		return nil
+	}
+	return v.redacted(ad)
+}
+
+func (v adViewer) redactAll(list []ads.Ad) {
+	for i := range list {
+		v.redact(&list[i])
+	}
+}
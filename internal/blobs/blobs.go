@@ -0,0 +1,42 @@
+// Package blobs описывает хранилище двоичных объектов (фотографий объявлений)
+package blobs
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+)
+
+var (
+	ErrNotFound   = errors.New("blob not found")
+	ErrInvalidKey = errors.New("invalid blob key")
+)
+
+// BlobStore хранит блобы под ключами вида "a/b/c". Сегменты ключа состоят из латинских букв, цифр, '.', '_' и '-'
+type BlobStore interface {
+	// Put записывает блоб целиком, заменяя прежний с тем же ключом. Читатели не видят недописанный блоб
+	Put(ctx context.Context, key string, r io.Reader) error
+	// Get открывает блоб на чтение, вызывающий обязан закрыть его
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+	// Delete удаляет блоб. Удаление отсутствующего блоба не ошибка
+	Delete(ctx context.Context, key string) error
+}
+
+// ValidKey проверяет ключ: непустые сегменты через '/', без "." и ".."
+func ValidKey(key string) bool {
+	if key == "" {
+		return false
+	}
+	for _, seg := range strings.Split(key, "/") {
+		if seg == "" || seg == "." || seg == ".." {
+			return false
+		}
+		for _, r := range seg {
+			if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '.' || r == '_' || r == '-') {
+				return false
+			}
+		}
+	}
+	return true
+}
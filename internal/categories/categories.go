@@ -0,0 +1,97 @@
+// Package categories - дерево категорий объявлений
+package categories
+
+import "sort"
+
+// Category - узел дерева категорий. ID выдаются с единицы: нулевой ParentID означает корневую категорию,
+// а нулевой ads.Ad.CategoryID - объявление без категории
+type Category struct {
+	ID       int64
+	Name     string
+	ParentID int64
+}
+
+func (c Category) IsRoot() bool {
+	return c.ParentID == 0
+}
+
+// Tree - снимок дерева категорий для обхода по иерархии
+type Tree struct {
+	byID     map[int64]Category
+	children map[int64][]int64
+}
+
+// NewTree строит дерево из списка всех категорий. Категории с несуществующим родителем считаются корневыми
+func NewTree(list []Category) *Tree {
+	t := &Tree{
+		byID:     make(map[int64]Category, len(list)),
+		children: make(map[int64][]int64),
+	}
+	for _, c := range list {
+		t.byID[c.ID] = c
+	}
+	for _, c := range list {
+		parent := c.ParentID
+		if _, ok := t.byID[parent]; !ok {
+			parent = 0
+		}
+		t.children[parent] = append(t.children[parent], c.ID)
+	}
+	for _, ids := range t.children {
+		sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	}
+	return t
+}
+
+func (t *Tree) Get(id int64) (Category, bool) {
+	c, ok := t.byID[id]
+	return c, ok
+}
+
+// Children возвращает непосредственные подкатегории id по возрастанию ID, для 0 - корневые категории
+func (t *Tree) Children(id int64) []Category {
+	res := make([]Category, 0, len(t.children[id]))
+	for _, child := range t.children[id] {
+		res = append(res, t.byID[child])
+	}
+	return res
+}
+
+// Subtree возвращает ID категории и всех её подкатегорий на любой глубине. Для неизвестной категории - nil
+func (t *Tree) Subtree(id int64) []int64 {
+	if _, ok := t.byID[id]; !ok {
+		return nil
+	}
+	res := []int64{id}
+	seen := map[int64]bool{id: true}
+	for i := 0; i < len(res); i++ {
+		for _, child := range t.children[res[i]] {
+			if !seen[child] {
+				seen[child] = true
+				res = append(res, child)
+			}
+		}
+	}
+	return res
+}
+
+// Path возвращает цепочку категорий от корня до id включительно
+func (t *Tree) Path(id int64) []Category {
+	var path []Category
+	seen := make(map[int64]bool)
+	for c, ok := t.byID[id]; ok && !seen[c.ID]; c, ok = t.byID[c.ParentID] {
+		seen[c.ID] = true
+		path = append([]Category{c}, path...)
+	}
+	return path
+}
+
+// IsWithin сообщает, лежит ли id в поддереве ancestor (в том числе совпадает с ним)
+func (t *Tree) IsWithin(id int64, ancestor int64) bool {
+	for _, c := range t.Path(id) {
+		if c.ID == ancestor {
+			return true
+		}
+	}
+	return false
+}
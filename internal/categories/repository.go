@@ -0,0 +1,19 @@
+package categories
+
+import (
+	"context"
+	"errors"
+)
+
+var ErrNotFound = errors.New("category not found")
+
+// CategoryRepository хранит категории. Целостность дерева (существование родителя, отсутствие циклов)
+// проверяет приложение, хранилище только выдаёт ID с единицы и никогда не переиспользует их
+type CategoryRepository interface {
+	GetCategory(ctx context.Context, id int64) (*Category, error)
+	// ListCategories возвращает все категории по возрастанию ID
+	ListCategories(ctx context.Context) ([]Category, error)
+	AddCategory(ctx context.Context, c Category) (int64, error)
+	UpdateCategory(ctx context.Context, id int64, c Category) error
+	DeleteCategory(ctx context.Context, id int64) error
+}
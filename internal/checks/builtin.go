@@ -0,0 +1,182 @@
+package checks
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode"
+
+	"homework9/internal/search"
+)
+
+// Words ищет в заголовке и тексте слова и фразы из списка. Слова сравниваются по основам (см. search.Terms),
+// поэтому «дурак» находит и «дураки», а регистр и буква ё не важны. Годится для русских и английских списков
+type Words struct {
+	name    string
+	verdict Verdict
+	phrases []phrase
+}
+
+type phrase struct {
+	word  string
+	terms []string
+}
+
+// NewWords создаёт проверку name, которая выносит verdict, если нашлось слово из words
+func NewWords(name string, verdict Verdict, words []string) *Words {
+	w := &Words{name: name, verdict: verdict}
+	for _, word := range words {
+		if terms := search.Terms(word); len(terms) > 0 {
+			w.phrases = append(w.phrases, phrase{word: strings.TrimSpace(word), terms: terms})
+		}
+	}
+	return w
+}
+
+func (w *Words) Name() string {
+	return w.name
+}
+
+func (w *Words) Check(ctx context.Context, s Submission) ([]Finding, error) {
+	var res []Finding
+	for _, f := range fields(s) {
+		terms := search.Terms(f.value)
+		for _, p := range w.phrases {
+			if containsTerms(terms, p.terms) {
+				res = append(res, Finding{Verdict: w.verdict, Field: f.name, Reason: fmt.Sprintf("contains %q", p.word)})
+			}
+		}
+	}
+	return res, nil
+}
+
+// containsTerms ищет sub в terms подряд
+func containsTerms(terms []string, sub []string) bool {
+	for i := 0; i+len(sub) <= len(terms); i++ {
+		match := true
+		for j := range sub {
+			if terms[i+j] != sub[j] {
+				match = false
+				break
+			}
+		}
+		if match {
+			return true
+		}
+	}
+	return false
+}
+
+var (
+	// phoneCandidate - цифры вперемешку с пробелами, скобками и дефисами; телефон ли это, решает число цифр
+	phoneCandidate = regexp.MustCompile(`\+?\d[\d\s\-().]{5,}\d`)
+	link           = regexp.MustCompile(`(?i)\b(?:https?://|www\.)\S+|\b[a-z0-9-]+\.(?:ru|com|net|org|su|io|me|info)\b|\bt\.me/\S+`)
+)
+
+// Contacts находит телефоны и ссылки: договариваться с покупателями предлагается через сервис
+type Contacts struct {
+	verdict Verdict
+}
+
+func NewContacts(verdict Verdict) *Contacts {
+	return &Contacts{verdict: verdict}
+}
+
+func (c *Contacts) Name() string {
+	return "contacts"
+}
+
+func (c *Contacts) Check(ctx context.Context, s Submission) ([]Finding, error) {
+	var res []Finding
+	for _, f := range fields(s) {
+		if hasPhone(f.value) {
+			res = append(res, Finding{Verdict: c.verdict, Field: f.name, Reason: "contains a phone number"})
+		}
+		if link.MatchString(f.value) {
+			res = append(res, Finding{Verdict: c.verdict, Field: f.name, Reason: "contains a link"})
+		}
+	}
+	return res, nil
+}
+
+// hasPhone - есть ли в тексте номер из 10-15 цифр
+func hasPhone(text string) bool {
+	for _, m := range phoneCandidate.FindAllString(text, -1) {
+		digits := 0
+		for _, r := range m {
+			if r >= '0' && r <= '9' {
+				digits++
+			}
+		}
+		if digits >= 10 && digits <= 15 {
+			return true
+		}
+	}
+	return false
+}
+
+const (
+	// MaxRepeat - сколько одинаковых символов подряд ещё не считается спамом
+	MaxRepeat = 5
+	// capsMinLetters - короче этого заглавные буквы не проверяются: «БМВ X5» не крик
+	capsMinLetters = 8
+)
+
+// Spam находит признаки спама: один символ много раз подряд («!!!!!!», «срочноооооо») и текст заглавными буквами
+type Spam struct {
+	verdict Verdict
+}
+
+func NewSpam(verdict Verdict) *Spam {
+	return &Spam{verdict: verdict}
+}
+
+func (sp *Spam) Name() string {
+	return "spam"
+}
+
+func (sp *Spam) Check(ctx context.Context, s Submission) ([]Finding, error) {
+	var res []Finding
+	for _, f := range fields(s) {
+		if longestRun(f.value) > MaxRepeat {
+			res = append(res, Finding{Verdict: sp.verdict, Field: f.name, Reason: "repeats a character too many times"})
+		}
+		if shouting(f.value) {
+			res = append(res, Finding{Verdict: sp.verdict, Field: f.name, Reason: "written in capital letters"})
+		}
+	}
+	return res, nil
+}
+
+// longestRun - длина самой длинной серии одинаковых непробельных символов
+func longestRun(text string) int {
+	longest, run := 0, 0
+	var prev rune
+	for _, r := range text {
+		if r == prev && !unicode.IsSpace(r) {
+			run++
+		} else {
+			run = 1
+		}
+		prev = r
+		if run > longest {
+			longest = run
+		}
+	}
+	return longest
+}
+
+// shouting - в тексте достаточно букв и почти все заглавные
+func shouting(text string) bool {
+	letters, upper := 0, 0
+	for _, r := range text {
+		if unicode.IsLetter(r) {
+			letters++
+			if unicode.IsUpper(r) {
+				upper++
+			}
+		}
+	}
+	return letters >= capsMinLetters && upper*10 >= letters*9
+}
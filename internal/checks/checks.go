@@ -0,0 +1,109 @@
+// Package checks - автоматические проверки содержимого объявлений перед сохранением
+package checks
+
+import (
+	"context"
+	"fmt"
+)
+
+// Verdict - решение проверки. Итоговое решение по объявлению - самое строгое из решений проверок
+type Verdict int
+
+const (
+	// Allow - замечаний нет
+	Allow Verdict = iota
+	// Flag - объявление сохраняется, но публикуется только после проверки модератором
+	Flag
+	// Reject - объявление не сохраняется
+	Reject
+)
+
+func (v Verdict) String() string {
+	switch v {
+	case Allow:
+		return "allow"
+	case Flag:
+		return "flag"
+	case Reject:
+		return "reject"
+	}
+	return fmt.Sprintf("Verdict(%d)", int(v))
+}
+
+// ParseVerdict - обратное к String
+func ParseVerdict(s string) (Verdict, error) {
+	for _, v := range []Verdict{Allow, Flag, Reject} {
+		if v.String() == s {
+			return v, nil
+		}
+	}
+	return Allow, fmt.Errorf("unknown verdict %q", s)
+}
+
+// Поля объявления, к которым относится замечание
+const (
+	FieldTitle = "title"
+	FieldText  = "text"
+)
+
+// Submission - содержимое объявления перед сохранением. У нового объявления New true, а ID ещё нет
+type Submission struct {
+	ID       int64
+	New      bool
+	AuthorID int64
+	Title    string
+	Text     string
+}
+
+// Finding - замечание проверки. Field пуст, если замечание относится к объявлению целиком
+type Finding struct {
+	Check   string
+	Verdict Verdict
+	Field   string
+	Reason  string
+}
+
+// Checker - одна проверка. Check возвращает замечания с решением Flag или Reject, без замечаний - Allow
+type Checker interface {
+	// Name попадает в Finding.Check
+	Name() string
+	Check(ctx context.Context, s Submission) ([]Finding, error)
+}
+
+// Report - итог всех проверок
+type Report struct {
+	Verdict  Verdict
+	Findings []Finding
+}
+
+// Run выполняет проверки по очереди и собирает их замечания. Ошибка любой проверки прерывает Run
+func Run(ctx context.Context, checkers []Checker, s Submission) (Report, error) {
+	var r Report
+	for _, c := range checkers {
+		findings, err := c.Check(ctx, s)
+		if err != nil {
+			return Report{}, fmt.Errorf("check %s: %w", c.Name(), err)
+		}
+		for _, f := range findings {
+			if f.Verdict == Allow {
+				continue
+			}
+			f.Check = c.Name()
+			if f.Verdict > r.Verdict {
+				r.Verdict = f.Verdict
+			}
+			r.Findings = append(r.Findings, f)
+		}
+	}
+	return r, nil
+}
+
+type field struct {
+	name  string
+	value string
+}
+
+// fields - проверяемые поля объявления
+func fields(s Submission) []field {
+	return []field{{FieldTitle, s.Title}, {FieldText, s.Text}}
+}
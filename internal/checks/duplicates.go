@@ -0,0 +1,50 @@
+package checks
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"unicode"
+
+	"homework9/internal/ads"
+)
+
+// Duplicates не даёт автору повторить своё же объявление: совпадение заголовка и текста без учёта регистра,
+// знаков препинания и пробелов. Сравнивается со всеми объявлениями автора, включая снятые
+type Duplicates struct {
+	repo    ads.AdRepository
+	verdict Verdict
+}
+
+func NewDuplicates(repo ads.AdRepository, verdict Verdict) *Duplicates {
+	return &Duplicates{repo: repo, verdict: verdict}
+}
+
+func (d *Duplicates) Name() string {
+	return "duplicates"
+}
+
+func (d *Duplicates) Check(ctx context.Context, s Submission) ([]Finding, error) {
+	own, err := d.repo.FindAds(ctx, ads.Filter{AuthorIDs: []int64{s.AuthorID}}, ads.Page{})
+	if err != nil {
+		return nil, err
+	}
+	title, text := normalize(s.Title), normalize(s.Text)
+	for _, ad := range own {
+		if !s.New && ad.ID == s.ID {
+			continue
+		}
+		if normalize(ad.Title) == title && normalize(ad.Text) == text {
+			return []Finding{{Verdict: d.verdict, Reason: fmt.Sprintf("duplicates ad %d", ad.ID)}}, nil
+		}
+	}
+	return nil, nil
+}
+
+// normalize оставляет от текста слова в нижнем регистре через пробел
+func normalize(text string) string {
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	return strings.ReplaceAll(strings.Join(words, " "), "ё", "е")
+}
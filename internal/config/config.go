@@ -0,0 +1,472 @@
+// Package config собирает настройки сервиса из YAML-файла, переменных окружения и флагов командной строки.
+// Каждый следующий источник перекрывает предыдущий: файл < окружение < флаги.
+package config
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"homework9/internal/adapters/jwtauth"
+	"homework9/internal/checks"
+	"homework9/internal/money"
+)
+
+var ErrInvalid = errors.New("invalid config")
+
+const (
+	StorageMemory = "memory"
+	StorageFile   = "file"
+	StorageSQLite = "sqlite"
+)
+
+// Уровни журнала, log_level - с какого из них он пишется: info - запросы, уведомления, запуск и остановка,
+// warn - предупреждения, error - только ошибки
+const (
+	LogDebug = "debug"
+	LogInfo  = "info"
+	LogWarn  = "warn"
+	LogError = "error"
+)
+
+// logRanks - уровни журнала от подробного к важному
+var logRanks = map[string]int{LogDebug: 0, LogInfo: 1, LogWarn: 2, LogError: 3}
+
+type Config struct {
+	HTTPAddr        string        `yaml:"http_addr"`
+	GRPCAddr        string        `yaml:"grpc_addr"`
+	ShutdownTimeout time.Duration `yaml:"shutdown_timeout"`
+	LogLevel        string        `yaml:"log_level"`
+	Storage         Storage       `yaml:"storage"`
+	Limits          Limits        `yaml:"limits"`
+	Auth            Auth          `yaml:"auth"`
+	Currency        Currency      `yaml:"currency"`
+	Images          Images        `yaml:"images"`
+	Schedule        Schedule      `yaml:"schedule"`
+	Moderation      Moderation    `yaml:"moderation"`
+	Content         Content       `yaml:"content"`
+	Reports         Reports       `yaml:"reports"`
+}
+
+type Storage struct {
+	Backend string `yaml:"backend"`
+	// Path - журнал для file или файл базы для sqlite. Пустой путь заменяется значением по умолчанию для бэкенда
+	Path            string        `yaml:"path"`
+	CompactInterval time.Duration `yaml:"compact_interval"`
+}
+
+type Auth struct {
+	AccessTTL  time.Duration `yaml:"access_ttl"`
+	RefreshTTL time.Duration `yaml:"refresh_ttl"`
+	// Keys - ключи подписи access-токенов. Первый подписывает новые токены, остальные только проверяют подпись.
+	// Пустой список - ключ генерируется при запуске, и токены не переживают перезапуск
+	Keys []SigningKey `yaml:"keys"`
+	// AdminEmails получают роль администратора при регистрации
+	AdminEmails []string `yaml:"admin_emails"`
+}
+
+// Currency - таблица курсов для показа цен в другой валюте. Rates - сколько единиц Base стоит
+// одна единица валюты. Валюты без курса в другие не переводятся
+type Currency struct {
+	Base  string             `yaml:"base"`
+	Rates map[string]float64 `yaml:"rates"`
+}
+
+// Images - где хранятся фотографии объявлений и какими они могут быть
+type Images struct {
+	// Path - каталог с файлами фотографий и превью
+	Path string `yaml:"path"`
+	// MaxBytes - максимальный размер одного файла
+	MaxBytes int `yaml:"max_bytes"`
+	PerAd    int `yaml:"per_ad"`
+	// ThumbSize - большая сторона превью в пикселях
+	ThumbSize int `yaml:"thumb_size"`
+}
+
+// Schedule - сроки публикации объявлений
+type Schedule struct {
+	// Interval - как часто проверяются сроки публикации и снятия
+	Interval time.Duration `yaml:"interval"`
+	// AdLifetime - сколько живёт опубликованное объявление без своего срока, 0 - не истекает
+	AdLifetime time.Duration `yaml:"ad_lifetime"`
+}
+
+// Moderation - проверка объявлений модераторами
+type Moderation struct {
+	// Enabled - объявление публикуется только после одобрения модератором
+	Enabled bool `yaml:"enabled"`
+}
+
+// Content - автоматические проверки объявлений. Решения проверок: allow (проверка выключена),
+// flag (объявление уходит модератору) или reject (объявление не сохраняется)
+type Content struct {
+	// BannedWords отклоняют объявление, FlaggedWords отправляют его модератору. Можно задавать фразы
+	BannedWords  []string `yaml:"banned_words"`
+	FlaggedWords []string `yaml:"flagged_words"`
+	// Contacts - телефоны и ссылки в объявлении
+	Contacts string `yaml:"contacts"`
+	// Spam - повторы символов и текст заглавными буквами
+	Spam string `yaml:"spam"`
+	// Duplicates - повтор другого объявления того же автора
+	Duplicates string `yaml:"duplicates"`
+}
+
+// Reports - жалобы пользователей
+type Reports struct {
+	// Threshold - после скольких открытых жалоб объявление снимается с публикации, 0 - не снимается
+	Threshold int `yaml:"threshold"`
+}
+
+type SigningKey struct {
+	ID     string `yaml:"id"`
+	Secret string `yaml:"secret"`
+}
+
+// Limits - допустимая длина заголовка и текста объявления в байтах
+type Limits struct {
+	TitleMin int `yaml:"title_min"`
+	TitleMax int `yaml:"title_max"`
+	TextMin  int `yaml:"text_min"`
+	TextMax  int `yaml:"text_max"`
+}
+
+func Default() Config {
+	return Config{
+		HTTPAddr:        ":18080",
+		GRPCAddr:        ":50054",
+		ShutdownTimeout: 10 * time.Second,
+		LogLevel:        LogInfo,
+		Storage: Storage{
+			Backend:         StorageMemory,
+			CompactInterval: 10 * time.Minute,
+		},
+		Limits:     Limits{TitleMin: 1, TitleMax: 100, TextMin: 1, TextMax: 500},
+		Auth:       Auth{AccessTTL: 15 * time.Minute, RefreshTTL: 30 * 24 * time.Hour},
+		Currency:   Currency{Base: "RUB"},
+		Images:     Images{Path: "data/images", MaxBytes: 5 << 20, PerAd: 10, ThumbSize: 320},
+		Schedule:   Schedule{Interval: time.Minute, AdLifetime: 30 * 24 * time.Hour},
+		Moderation: Moderation{Enabled: true},
+		Content:    Content{Contacts: "flag", Spam: "flag", Duplicates: "reject"},
+		Reports:    Reports{Threshold: 3},
+	}
+}
+
+// option - одна настройка, доступная из окружения и флагом
+type option struct {
+	flag  string
+	env   string
+	usage string
+	set   func(c *Config, v string) error
+}
+
+var options = []option{
+	{"http-addr", "ADS_HTTP_ADDR", "REST listen address", setString(func(c *Config) *string { return &c.HTTPAddr })},
+	{"grpc-addr", "ADS_GRPC_ADDR", "gRPC listen address", setString(func(c *Config) *string { return &c.GRPCAddr })},
+	{"shutdown-timeout", "ADS_SHUTDOWN_TIMEOUT", "how long in-flight requests may drain on shutdown", setDuration(func(c *Config) *time.Duration { return &c.ShutdownTimeout })},
+	{"log-level", "ADS_LOG_LEVEL", "log level: debug, info, warn or error", setString(func(c *Config) *string { return &c.LogLevel })},
+	{"storage", "ADS_STORAGE", "storage backend: memory, file or sqlite", setString(func(c *Config) *string { return &c.Storage.Backend })},
+	{"storage-path", "ADS_STORAGE_PATH", "path to the write-ahead log (file) or database (sqlite)", setString(func(c *Config) *string { return &c.Storage.Path })},
+	{"compact-interval", "ADS_COMPACT_INTERVAL", "how often the write-ahead log is compacted", setDuration(func(c *Config) *time.Duration { return &c.Storage.CompactInterval })},
+	{"title-min", "ADS_TITLE_MIN", "minimal ad title length", setInt(func(c *Config) *int { return &c.Limits.TitleMin })},
+	{"title-max", "ADS_TITLE_MAX", "maximal ad title length", setInt(func(c *Config) *int { return &c.Limits.TitleMax })},
+	{"text-min", "ADS_TEXT_MIN", "minimal ad text length", setInt(func(c *Config) *int { return &c.Limits.TextMin })},
+	{"text-max", "ADS_TEXT_MAX", "maximal ad text length", setInt(func(c *Config) *int { return &c.Limits.TextMax })},
+	{"access-ttl", "ADS_ACCESS_TTL", "how long an access token stays valid", setDuration(func(c *Config) *time.Duration { return &c.Auth.AccessTTL })},
+	{"refresh-ttl", "ADS_REFRESH_TTL", "how long a refresh token stays valid", setDuration(func(c *Config) *time.Duration { return &c.Auth.RefreshTTL })},
+	{"jwt-keys", "ADS_JWT_KEYS", "token signing keys as id:secret,id:secret; the first one signs", setKeys},
+	{"admin-emails", "ADS_ADMIN_EMAILS", "comma-separated emails that get the admin role on registration", setList(func(c *Config) *[]string { return &c.Auth.AdminEmails })},
+	{"currency-base", "ADS_CURRENCY_BASE", "ISO 4217 code the exchange rates are quoted in", setString(func(c *Config) *string { return &c.Currency.Base })},
+	{"currency-rates", "ADS_CURRENCY_RATES", "exchange rates to the base currency as code:rate,code:rate", setRates},
+	{"images-path", "ADS_IMAGES_PATH", "directory for ad images and thumbnails", setString(func(c *Config) *string { return &c.Images.Path })},
+	{"image-max-bytes", "ADS_IMAGE_MAX_BYTES", "maximal size of one uploaded image in bytes", setInt(func(c *Config) *int { return &c.Images.MaxBytes })},
+	{"images-per-ad", "ADS_IMAGES_PER_AD", "how many images one ad may have", setInt(func(c *Config) *int { return &c.Images.PerAd })},
+	{"thumb-size", "ADS_THUMB_SIZE", "longer side of image thumbnails in pixels", setInt(func(c *Config) *int { return &c.Images.ThumbSize })},
+	{"schedule-interval", "ADS_SCHEDULE_INTERVAL", "how often scheduled publications and expirations are applied", setDuration(func(c *Config) *time.Duration { return &c.Schedule.Interval })},
+	{"ad-lifetime", "ADS_AD_LIFETIME", "how long a published ad lives without its own expiration, 0 - forever", setDuration(func(c *Config) *time.Duration { return &c.Schedule.AdLifetime })},
+	{"moderation", "ADS_MODERATION", "publish ads only after a moderator approves them: true or false", setBool(func(c *Config) *bool { return &c.Moderation.Enabled })},
+	{"banned-words", "ADS_BANNED_WORDS", "comma-separated words and phrases that reject an ad", setList(func(c *Config) *[]string { return &c.Content.BannedWords })},
+	{"flagged-words", "ADS_FLAGGED_WORDS", "comma-separated words and phrases that send an ad to moderators", setList(func(c *Config) *[]string { return &c.Content.FlaggedWords })},
+	{"contacts-check", "ADS_CONTACTS_CHECK", "phone numbers and links in ads: allow, flag or reject", setString(func(c *Config) *string { return &c.Content.Contacts })},
+	{"spam-check", "ADS_SPAM_CHECK", "repeated characters and capital letters in ads: allow, flag or reject", setString(func(c *Config) *string { return &c.Content.Spam })},
+	{"duplicates-check", "ADS_DUPLICATES_CHECK", "ads repeating another ad of the same author: allow, flag or reject", setString(func(c *Config) *string { return &c.Content.Duplicates })},
+	{"report-threshold", "ADS_REPORT_THRESHOLD", "open reports that unpublish an ad, 0 - reports never unpublish", setInt(func(c *Config) *int { return &c.Reports.Threshold })},
+}
+
+// secretOptions не печатаются в сообщениях об ошибках
+var secretOptions = map[string]bool{"jwt-keys": true}
+
+func (o option) shown(v string) string {
+	if secretOptions[o.flag] {
+		return "***"
+	}
+	return v
+}
+
+const (
+	configFlag = "config"
+	configEnv  = "ADS_CONFIG"
+)
+
+// Load собирает конфигурацию: значения по умолчанию, затем файл из -config или ADS_CONFIG,
+// затем переменные окружения, затем флаги из args. Результат проверяется Validate
+func Load(args []string, getenv func(string) string) (Config, error) {
+	fs := flag.NewFlagSet("ads", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	path := fs.String(configFlag, "", "path to the YAML config file (env "+configEnv+")")
+
+	// флаги применяются последними, поэтому пока только запоминаются
+	type setFlag struct {
+		opt   option
+		value string
+	}
+	var flags []setFlag
+	for _, opt := range options {
+		opt := opt
+		fs.Func(opt.flag, opt.usage+" (env "+opt.env+")", func(v string) error {
+			flags = append(flags, setFlag{opt, v})
+			return nil
+		})
+	}
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			fs.SetOutput(os.Stderr)
+			fs.PrintDefaults()
+		}
+		return Config{}, err
+	}
+	if fs.NArg() > 0 {
+		return Config{}, fmt.Errorf("%w: unexpected arguments %q", ErrInvalid, fs.Args())
+	}
+
+	cfg := Default()
+	if *path == "" {
+		*path = getenv(configEnv)
+	}
+	if *path != "" {
+		if err := loadFile(&cfg, *path); err != nil {
+			return Config{}, err
+		}
+	}
+
+	for _, opt := range options {
+		v := getenv(opt.env)
+		if v == "" {
+			continue
+		}
+		if err := opt.set(&cfg, v); err != nil {
+			return Config{}, fmt.Errorf("%w: %s=%q: %v", ErrInvalid, opt.env, opt.shown(v), err)
+		}
+	}
+	for _, f := range flags {
+		if err := f.opt.set(&cfg, f.value); err != nil {
+			return Config{}, fmt.Errorf("%w: -%s=%q: %v", ErrInvalid, f.opt.flag, f.opt.shown(f.value), err)
+		}
+	}
+
+	if cfg.Storage.Path == "" {
+		switch cfg.Storage.Backend {
+		case StorageFile:
+			cfg.Storage.Path = "data/store.wal"
+		case StorageSQLite:
+			cfg.Storage.Path = "data/ads.db"
+		}
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return Config{}, err
+	}
+	return cfg, nil
+}
+
+func loadFile(cfg *Config, path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("read config: %w", err)
+	}
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	// опечатка в имени ключа не должна молча превращаться в значение по умолчанию
+	dec.KnownFields(true)
+	if err := dec.Decode(cfg); err != nil && !errors.Is(err, io.EOF) {
+		return fmt.Errorf("%w: %s: %v", ErrInvalid, path, err)
+	}
+	return nil
+}
+
+// LogEnabled - пишутся ли сообщения уровня level. Ошибки пишутся при любом LogLevel
+func (c Config) LogEnabled(level string) bool {
+	return logRanks[level] >= logRanks[c.LogLevel]
+}
+
+// Validate проверяет все поля сразу и перечисляет в ошибке каждую найденную проблему
+func (c Config) Validate() error {
+	var problems []string
+	check := func(ok bool, format string, args ...any) {
+		if !ok {
+			problems = append(problems, fmt.Sprintf(format, args...))
+		}
+	}
+
+	check(validAddr(c.HTTPAddr), "http_addr %q is not a valid host:port", c.HTTPAddr)
+	check(validAddr(c.GRPCAddr), "grpc_addr %q is not a valid host:port", c.GRPCAddr)
+	check(c.HTTPAddr != c.GRPCAddr || strings.HasSuffix(c.HTTPAddr, ":0"), "http_addr and grpc_addr must differ")
+	check(c.ShutdownTimeout > 0, "shutdown_timeout must be positive, got %v", c.ShutdownTimeout)
+	switch c.LogLevel {
+	case LogDebug, LogInfo, LogWarn, LogError:
+	default:
+		check(false, "log_level must be one of debug, info, warn, error, got %q", c.LogLevel)
+	}
+
+	switch c.Storage.Backend {
+	case StorageMemory:
+	case StorageFile:
+		check(c.Storage.CompactInterval > 0, "storage.compact_interval must be positive, got %v", c.Storage.CompactInterval)
+	case StorageSQLite:
+	default:
+		check(false, "storage.backend must be one of memory, file, sqlite, got %q", c.Storage.Backend)
+	}
+
+	check(c.Limits.TitleMin >= 0, "limits.title_min must not be negative, got %d", c.Limits.TitleMin)
+	check(c.Limits.TitleMax > 0 && c.Limits.TitleMax >= c.Limits.TitleMin,
+		"limits.title_max must be positive and not less than title_min, got %d", c.Limits.TitleMax)
+	check(c.Limits.TextMin >= 0, "limits.text_min must not be negative, got %d", c.Limits.TextMin)
+	check(c.Limits.TextMax > 0 && c.Limits.TextMax >= c.Limits.TextMin,
+		"limits.text_max must be positive and not less than text_min, got %d", c.Limits.TextMax)
+
+	check(c.Auth.AccessTTL > 0, "auth.access_ttl must be positive, got %v", c.Auth.AccessTTL)
+	check(c.Auth.RefreshTTL > 0, "auth.refresh_ttl must be positive, got %v", c.Auth.RefreshTTL)
+	seen := make(map[string]bool)
+	for i, k := range c.Auth.Keys {
+		check(k.ID != "", "auth.keys[%d].id must not be empty", i)
+		check(!seen[k.ID], "auth.keys[%d].id %q is duplicated", i, k.ID)
+		check(len(k.Secret) >= jwtauth.MinSecretLen, "auth.keys[%d].secret must be at least %d bytes", i, jwtauth.MinSecretLen)
+		seen[k.ID] = true
+	}
+
+	if _, err := money.NewRates(c.Currency.Base, c.Currency.Rates); err != nil {
+		check(false, "currency: %v", err)
+	}
+
+	check(c.Images.Path != "", "images.path must not be empty")
+	check(c.Images.MaxBytes > 0, "images.max_bytes must be positive, got %d", c.Images.MaxBytes)
+	check(c.Images.PerAd > 0, "images.per_ad must be positive, got %d", c.Images.PerAd)
+	check(c.Images.ThumbSize >= 16 && c.Images.ThumbSize <= 2048, "images.thumb_size must be between 16 and 2048, got %d", c.Images.ThumbSize)
+
+	check(c.Schedule.Interval > 0, "schedule.interval must be positive, got %s", c.Schedule.Interval)
+	check(c.Schedule.AdLifetime >= 0, "schedule.ad_lifetime must not be negative, got %s", c.Schedule.AdLifetime)
+
+	for _, v := range []struct{ name, value string }{
+		{"contacts", c.Content.Contacts}, {"spam", c.Content.Spam}, {"duplicates", c.Content.Duplicates},
+	} {
+		_, err := checks.ParseVerdict(v.value)
+		check(err == nil, "content.%s must be one of allow, flag, reject, got %q", v.name, v.value)
+	}
+	check(c.Reports.Threshold >= 0, "reports.threshold must not be negative, got %d", c.Reports.Threshold)
+
+	if len(problems) > 0 {
+		return fmt.Errorf("%w:\n  %s", ErrInvalid, strings.Join(problems, "\n  "))
+	}
+	return nil
+}
+
+func validAddr(addr string) bool {
+	_, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return false
+	}
+	n, err := strconv.Atoi(port)
+	return err == nil && n >= 0 && n <= 65535
+}
+
+func setString(field func(c *Config) *string) func(c *Config, v string) error {
+	return func(c *Config, v string) error {
+		*field(c) = v
+		return nil
+	}
+}
+
+func setInt(field func(c *Config) *int) func(c *Config, v string) error {
+	return func(c *Config, v string) error {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return errors.New("not an integer")
+		}
+		*field(c) = n
+		return nil
+	}
+}
+
+func setBool(field func(c *Config) *bool) func(c *Config, v string) error {
+	return func(c *Config, v string) error {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return errors.New("not a boolean")
+		}
+		*field(c) = b
+		return nil
+	}
+}
+
+func setDuration(field func(c *Config) *time.Duration) func(c *Config, v string) error {
+	return func(c *Config, v string) error {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			return errors.New("not a duration, expected e.g. 10s or 5m")
+		}
+		*field(c) = d
+		return nil
+	}
+}
+
+func setList(field func(c *Config) *[]string) func(c *Config, v string) error {
+	return func(c *Config, v string) error {
+		var list []string
+		for _, item := range strings.Split(v, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				list = append(list, item)
+			}
+		}
+		*field(c) = list
+		return nil
+	}
+}
+
+// setKeys разбирает список ключей вида id:secret,id:secret
+func setKeys(c *Config, v string) error {
+	var keys []SigningKey
+	for _, pair := range strings.Split(v, ",") {
+		id, secret, ok := strings.Cut(strings.TrimSpace(pair), ":")
+		if !ok {
+			return errors.New("expected id:secret pairs separated by commas")
+		}
+		keys = append(keys, SigningKey{ID: id, Secret: secret})
+	}
+	c.Auth.Keys = keys
+	return nil
+}
+
+// setRates разбирает курсы вида USD:92.5,EUR:99.1
+func setRates(c *Config, v string) error {
+	rates := make(map[string]float64)
+	for _, pair := range strings.Split(v, ",") {
+		code, value, ok := strings.Cut(strings.TrimSpace(pair), ":")
+		if !ok {
+			return errors.New("expected code:rate pairs separated by commas")
+		}
+		rate, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return fmt.Errorf("rate for %s is not a number", code)
+		}
+		rates[code] = rate
+	}
+	c.Currency.Rates = rates
+	return nil
+}
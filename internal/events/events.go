@@ -0,0 +1,53 @@
+// Package events - события о том, что изменилось в приложении, для подписчиков внутри процесса
+package events
+
+import (
+	"time"
+
+	"homework9/internal/ads"
+	"homework9/internal/messages"
+)
+
+// Kind - что произошло
+type Kind string
+
+const (
+	// KindAdCreated - объявление создано
+	KindAdCreated Kind = "ad.created"
+	// KindAdUpdated - объявление изменилось без смены статуса, Was - каким оно было
+	KindAdUpdated Kind = "ad.updated"
+	// KindAdStatus - у объявления сменился статус, Ad - объявление после изменения, Was - до него
+	KindAdStatus Kind = "ad.status"
+	// KindAdDeleted - объявление удалено, Ad nil, Was - каким оно было
+	KindAdDeleted Kind = "ad.deleted"
+	// KindFavoriteStatus - у объявления из избранного UserIDs сменился статус. Ad nil, если объявление удалено
+	KindFavoriteStatus Kind = "favorite.status"
+	// KindMessage - новое сообщение для UserIDs
+	KindMessage Kind = "message.new"
+)
+
+type Event struct {
+	// Seq - номер события в Hub, растёт на единицу с каждым событием
+	Seq     uint64
+	Kind    Kind
+	At      time.Time
+	AdID    int64
+	Ad      *ads.Ad
+	Was     *ads.Ad
+	Message *messages.Message
+	// UserIDs - кому адресовано событие, пустой - событие общее
+	UserIDs []int64
+}
+
+// For - адресовано ли событие пользователю. Общие события адресованы всем
+func (e Event) For(userID int64) bool {
+	if len(e.UserIDs) == 0 {
+		return true
+	}
+	for _, id := range e.UserIDs {
+		if id == userID {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,201 @@
+package events
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+var (
+	// ErrSlowSubscriber - подписчик не успевал забирать события и был отключён
+	ErrSlowSubscriber = errors.New("subscriber is too slow, events were dropped")
+	// ErrHistoryExpired - событий после запрошенного номера Hub уже не хранит
+	ErrHistoryExpired = errors.New("events after this position are no longer retained")
+	// ErrUnknownPosition - такого номера события в Hub ещё не было
+	ErrUnknownPosition = errors.New("unknown event position")
+	// ErrClosed - Hub закрыт, подписаться на него нельзя
+	ErrClosed = errors.New("event hub is closed")
+)
+
+// DefaultHistory - сколько последних событий Hub хранит для продолжения подписки
+const DefaultHistory = 1024
+
+// Hub раздаёт события подписчикам внутри процесса. Публикация никого не ждёт: у каждой подписки свой буфер,
+// и подписка, чей буфер переполнен, закрывается с ErrSlowSubscriber. Так медленный клиент не тормозит
+// ни публикующих, ни остальных подписчиков, а сам узнаёт, что пропустил события.
+// Последние события Hub хранит, чтобы переподключившийся подписчик мог продолжить с того места,
+// где остановился (SubscribeFrom)
+type Hub struct {
+	epoch int64
+	// mx упорядочивает публикации: все подписчики видят события в порядке Seq
+	mx  sync.Mutex
+	seq uint64
+	// subs nil после Close
+	subs map[*Subscription]struct{}
+	// history - кольцо из последних событий, history[seq % len(history)] - событие seq
+	history []Event
+}
+
+type Option func(h *Hub)
+
+// WithHistory задаёт, сколько последних событий хранить, по умолчанию DefaultHistory. 0 - не хранить
+func WithHistory(n int) Option {
+	return func(h *Hub) {
+		h.history = make([]Event, n)
+	}
+}
+
+func NewHub(opts ...Option) *Hub {
+	h := &Hub{epoch: nextEpoch(), subs: make(map[*Subscription]struct{}), history: make([]Event, DefaultHistory)}
+	for _, opt := range opts {
+		opt(h)
+	}
+	return h
+}
+
+// lastEpoch - эпоха последнего созданного Hub
+var lastEpoch atomic.Int64
+
+// nextEpoch - время создания Hub, но строго больше предыдущей эпохи, даже если часы не успели сдвинуться
+func nextEpoch() int64 {
+	for {
+		last, now := lastEpoch.Load(), time.Now().UnixNano()
+		if now <= last {
+			now = last + 1
+		}
+		if lastEpoch.CompareAndSwap(last, now) {
+			return now
+		}
+	}
+}
+
+// Epoch отличает этот Hub от других, в том числе от Hub прошлого запуска процесса: номера событий
+// в каждом начинаются с единицы и сравнивать их можно только внутри одной эпохи
+func (h *Hub) Epoch() int64 {
+	return h.epoch
+}
+
+// Publish нумерует событие и отдаёт его подходящим подписчикам. Возвращает событие с Seq
+func (h *Hub) Publish(e Event) Event {
+	h.mx.Lock()
+	defer h.mx.Unlock()
+	h.seq++
+	e.Seq = h.seq
+	if len(h.history) > 0 {
+		h.history[e.Seq%uint64(len(h.history))] = e
+	}
+	for s := range h.subs {
+		if s.match != nil && !s.match(e) {
+			continue
+		}
+		select {
+		case s.c <- e:
+		default:
+			h.drop(s, ErrSlowSubscriber)
+		}
+	}
+	return e
+}
+
+// Subscribe подписывает на события, для которых match возвращает true (nil - на все). match вызывается
+// при публикации и должен быть быстрым. buffer - сколько событий может ждать подписчика
+func (h *Hub) Subscribe(buffer int, match func(Event) bool) (*Subscription, error) {
+	h.mx.Lock()
+	defer h.mx.Unlock()
+	return h.subscribe(buffer, match)
+}
+
+// SubscribeFrom - Subscribe, который сначала возвращает хранящиеся события с номерами больше after,
+// подходящие под match. Подписка начинается сразу за ними, поэтому между backlog и Events() нет ни пропусков,
+// ни повторов
+func (h *Hub) SubscribeFrom(after uint64, buffer int, match func(Event) bool) (*Subscription, []Event, error) {
+	h.mx.Lock()
+	defer h.mx.Unlock()
+	if h.subs == nil {
+		return nil, nil, ErrClosed
+	}
+	if after > h.seq {
+		return nil, nil, ErrUnknownPosition
+	}
+	if h.seq-after > uint64(len(h.history)) {
+		return nil, nil, ErrHistoryExpired
+	}
+	var backlog []Event
+	for seq := after + 1; seq <= h.seq; seq++ {
+		e := h.history[seq%uint64(len(h.history))]
+		if match == nil || match(e) {
+			backlog = append(backlog, e)
+		}
+	}
+	s, err := h.subscribe(buffer, match)
+	return s, backlog, err
+}
+
+// subscribe регистрирует подписку. Вызывается под h.mx
+func (h *Hub) subscribe(buffer int, match func(Event) bool) (*Subscription, error) {
+	if h.subs == nil {
+		return nil, ErrClosed
+	}
+	if buffer < 1 {
+		buffer = 1
+	}
+	s := &Subscription{c: make(chan Event, buffer), match: match, hub: h, head: h.seq}
+	h.subs[s] = struct{}{}
+	return s, nil
+}
+
+// Close закрывает все подписки с ErrClosed, например при остановке сервиса, чтобы долгие потоки
+// не держали её. Публиковать в закрытый Hub можно, но получать события уже некому
+func (h *Hub) Close() {
+	h.mx.Lock()
+	defer h.mx.Unlock()
+	for s := range h.subs {
+		h.drop(s, ErrClosed)
+	}
+	h.subs = nil
+}
+
+// drop убирает подписку и закрывает её канал. Вызывается под h.mx
+func (h *Hub) drop(s *Subscription, err error) {
+	if _, ok := h.subs[s]; !ok {
+		return
+	}
+	delete(h.subs, s)
+	s.err = err
+	close(s.c)
+}
+
+type Subscription struct {
+	c     chan Event
+	match func(Event) bool
+	hub   *Hub
+	// head - номер последнего события до подписки: в Events() придут события с большими номерами
+	head uint64
+	// err пишется под hub.mx до закрытия c, поэтому читать его можно после закрытия канала
+	err error
+}
+
+// Head - номер последнего события, случившегося до подписки
+func (s *Subscription) Head() uint64 {
+	return s.head
+}
+
+// Events - канал событий. Закрывается после Close или при отключении медленного подписчика
+func (s *Subscription) Events() <-chan Event {
+	return s.c
+}
+
+// Err - почему закрыт канал событий: ErrSlowSubscriber, ErrClosed или nil после Close подписки
+func (s *Subscription) Err() error {
+	s.hub.mx.Lock()
+	defer s.hub.mx.Unlock()
+	return s.err
+}
+
+// Close отписывает от событий, повторный вызов ничего не делает
+func (s *Subscription) Close() {
+	s.hub.mx.Lock()
+	defer s.hub.mx.Unlock()
+	s.hub.drop(s, nil)
+}
@@ -0,0 +1,20 @@
+// Package favorites - избранные объявления пользователей
+package favorites
+
+import "time"
+
+// Favorite - объявление AdID в избранном пользователя UserID. Удаление или снятие объявления
+// избранное не трогает
+type Favorite struct {
+	UserID int64
+	AdID   int64
+	Added  time.Time
+}
+
+// Before - идёт ли f раньше other в списке избранного: от новых к старым, при равном времени - по убыванию AdID
+func (f Favorite) Before(other Favorite) bool {
+	if !f.Added.Equal(other.Added) {
+		return f.Added.After(other.Added)
+	}
+	return f.AdID > other.AdID
+}
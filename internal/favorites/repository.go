@@ -0,0 +1,30 @@
+package favorites
+
+import (
+	"context"
+	"errors"
+)
+
+var (
+	ErrNotFound = errors.New("favorite not found")
+	// ErrDuplicate - объявление уже в избранном пользователя
+	ErrDuplicate = errors.New("already in favorites")
+)
+
+// FavoriteRepository хранит избранное, одна запись на пару (пользователь, объявление)
+type FavoriteRepository interface {
+	// AddFavorite добавляет объявление в избранное, повторное добавление - ErrDuplicate
+	AddFavorite(ctx context.Context, f Favorite) error
+	GetFavorite(ctx context.Context, userID int64, adID int64) (*Favorite, error)
+	RemoveFavorite(ctx context.Context, userID int64, adID int64) error
+	// ListFavorites возвращает не больше limit записей пользователя в порядке Favorite.Before, идущих после after
+	// (nil - с начала). Нулевой limit - без ограничения
+	ListFavorites(ctx context.Context, userID int64, after *Favorite, limit int) ([]Favorite, error)
+	// CountFavorites - у скольких пользователей каждое из объявлений adIDs в избранном.
+	// Объявлений, которых нет ни у кого в избранном, в ответе нет
+	CountFavorites(ctx context.Context, adIDs []int64) (map[int64]int, error)
+	// FavoriteUsers - у кого объявление в избранном, по возрастанию ID пользователя
+	FavoriteUsers(ctx context.Context, adID int64) ([]int64, error)
+	// DeleteUserFavorites удаляет всё избранное пользователя
+	DeleteUserFavorites(ctx context.Context, userID int64) error
+}
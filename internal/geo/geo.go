@@ -0,0 +1,97 @@
+// Package geo - точки на поверхности Земли, расстояния между ними и геохеш-сетка для поиска поблизости.
+// Координаты - градусы WGS 84, расстояния - километры
+package geo
+
+import "math"
+
+// EarthRadiusKm - средний радиус Земли
+const EarthRadiusKm = 6371.0088
+
+// MaxRadiusKm - половина окружности Земли: дальше этого расстояния точек нет
+const MaxRadiusKm = math.Pi * EarthRadiusKm
+
+type Point struct {
+	Lat float64
+	Lon float64
+}
+
+func (p Point) Valid() bool {
+	return p.Lat >= -90 && p.Lat <= 90 && p.Lon >= -180 && p.Lon <= 180
+}
+
+// Distance - расстояние по дуге большого круга (формула гаверсинусов)
+func Distance(a Point, b Point) float64 {
+	lat1, lat2 := radians(a.Lat), radians(b.Lat)
+	dLat, dLon := lat2-lat1, radians(b.Lon-a.Lon)
+	h := math.Sin(dLat/2)*math.Sin(dLat/2) + math.Cos(lat1)*math.Cos(lat2)*math.Sin(dLon/2)*math.Sin(dLon/2)
+	return 2 * EarthRadiusKm * math.Asin(math.Min(1, math.Sqrt(h)))
+}
+
+// Circle - точки не дальше RadiusKm от Center
+type Circle struct {
+	Center   Point
+	RadiusKm float64
+}
+
+func (c Circle) Valid() bool {
+	return c.Center.Valid() && c.RadiusKm > 0 && c.RadiusKm <= MaxRadiusKm
+}
+
+func (c Circle) Contains(p Point) bool {
+	return Distance(c.Center, p) <= c.RadiusKm
+}
+
+// Bounds - прямоугольник, в котором лежит весь круг. Если круг задевает полюс или линию перемены дат,
+// прямоугольник занимает все долготы
+func (c Circle) Bounds() Box {
+	dLat := c.RadiusKm / EarthRadiusKm * 180 / math.Pi
+	b := Box{MinLat: c.Center.Lat - dLat, MaxLat: c.Center.Lat + dLat, MinLon: -180, MaxLon: 180}
+	if b.MinLat <= -90 || b.MaxLat >= 90 {
+		b.MinLat, b.MaxLat = math.Max(b.MinLat, -90), math.Min(b.MaxLat, 90)
+		return b
+	}
+	// на самой далёкой от экватора широте градус долготы короче всего
+	farthest := math.Max(math.Abs(b.MinLat), math.Abs(b.MaxLat))
+	dLon := dLat / math.Cos(radians(farthest))
+	if c.Center.Lon-dLon > -180 && c.Center.Lon+dLon < 180 {
+		b.MinLon, b.MaxLon = c.Center.Lon-dLon, c.Center.Lon+dLon
+	}
+	return b
+}
+
+// Box - прямоугольник широт и долгот, границы включаются. Прямоугольники через линию перемены дат
+// не поддерживаются: MinLon не больше MaxLon
+type Box struct {
+	MinLat float64
+	MinLon float64
+	MaxLat float64
+	MaxLon float64
+}
+
+func (b Box) Valid() bool {
+	return Point{b.MinLat, b.MinLon}.Valid() && Point{b.MaxLat, b.MaxLon}.Valid() && b.MinLat <= b.MaxLat && b.MinLon <= b.MaxLon
+}
+
+func (b Box) Contains(p Point) bool {
+	return p.Lat >= b.MinLat && p.Lat <= b.MaxLat && p.Lon >= b.MinLon && p.Lon <= b.MaxLon
+}
+
+// Center - середина прямоугольника
+func (b Box) Center() Point {
+	return Point{Lat: (b.MinLat + b.MaxLat) / 2, Lon: (b.MinLon + b.MaxLon) / 2}
+}
+
+// Intersect - общая часть двух прямоугольников; ok false, если её нет
+func (b Box) Intersect(o Box) (Box, bool) {
+	res := Box{
+		MinLat: math.Max(b.MinLat, o.MinLat),
+		MinLon: math.Max(b.MinLon, o.MinLon),
+		MaxLat: math.Min(b.MaxLat, o.MaxLat),
+		MaxLon: math.Min(b.MaxLon, o.MaxLon),
+	}
+	return res, res.MinLat <= res.MaxLat && res.MinLon <= res.MaxLon
+}
+
+func radians(deg float64) float64 {
+	return deg * math.Pi / 180
+}
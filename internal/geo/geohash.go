@@ -0,0 +1,166 @@
+package geo
+
+import (
+	"math"
+	"sort"
+	"strings"
+)
+
+// HashPrecision - длина геохеша, который хранится у объявления (ячейка в несколько сантиметров).
+// Ячейки сетки любого размера - префиксы этого геохеша
+const HashPrecision = 12
+
+// MaxCoverCells - сколько ячеек сетки не больше покрывают прямоугольник запроса, см. Cover
+const MaxCoverCells = 32
+
+const base32 = "0123456789bcdefghjkmnpqrstuvwxyz"
+
+// Encode возвращает геохеш точки длиной precision символов
+func Encode(p Point, precision int) string {
+	latLo, latHi := -90.0, 90.0
+	lonLo, lonHi := -180.0, 180.0
+	var sb strings.Builder
+	bit, ch, even := 0, 0, true
+	for sb.Len() < precision {
+		// биты чередуются, начиная с долготы
+		if even {
+			mid := (lonLo + lonHi) / 2
+			if p.Lon >= mid {
+				ch = ch<<1 | 1
+				lonLo = mid
+			} else {
+				ch <<= 1
+				lonHi = mid
+			}
+		} else {
+			mid := (latLo + latHi) / 2
+			if p.Lat >= mid {
+				ch = ch<<1 | 1
+				latLo = mid
+			} else {
+				ch <<= 1
+				latHi = mid
+			}
+		}
+		even = !even
+		if bit++; bit == 5 {
+			sb.WriteByte(base32[ch])
+			bit, ch = 0, 0
+		}
+	}
+	return sb.String()
+}
+
+// cellSize - размеры ячейки геохеша длины precision в градусах
+func cellSize(precision int) (lat float64, lon float64) {
+	bits := 5 * precision
+	lonBits := (bits + 1) / 2
+	latBits := bits / 2
+	return 180 / math.Ldexp(1, latBits), 360 / math.Ldexp(1, lonBits)
+}
+
+// cellRange - номера первой и последней ячейки шириной size, в которые попадает отрезок [lo, hi]
+func cellRange(lo float64, hi float64, origin float64, size float64) (int, int) {
+	last := int(math.Round(-2*origin/size)) - 1
+	first := int(math.Floor((lo - origin) / size))
+	end := int(math.Floor((hi - origin) / size))
+	if first < 0 {
+		first = 0
+	}
+	if end > last {
+		end = last
+	}
+	return first, end
+}
+
+// Cover возвращает геохеш-префиксы ячеек одного размера, которые вместе покрывают b. Берутся самые мелкие
+// ячейки, которых хватает не больше MaxCoverCells. Пустой префикс - весь земной шар, пустой список - пустой b
+func Cover(b Box) []string {
+	if b.MinLat > b.MaxLat || b.MinLon > b.MaxLon {
+		return []string{}
+	}
+	for precision := HashPrecision; precision > 0; precision-- {
+		latSize, lonSize := cellSize(precision)
+		lat0, lat1 := cellRange(b.MinLat, b.MaxLat, -90, latSize)
+		lon0, lon1 := cellRange(b.MinLon, b.MaxLon, -180, lonSize)
+		if (lat1-lat0+1)*(lon1-lon0+1) > MaxCoverCells {
+			continue
+		}
+		cells := make([]string, 0, (lat1-lat0+1)*(lon1-lon0+1))
+		for i := lat0; i <= lat1; i++ {
+			for j := lon0; j <= lon1; j++ {
+				center := Point{Lat: -90 + (float64(i)+0.5)*latSize, Lon: -180 + (float64(j)+0.5)*lonSize}
+				cells = append(cells, Encode(center, precision))
+			}
+		}
+		sort.Strings(cells)
+		return cells
+	}
+	return []string{""}
+}
+
+// PrefixEnd - наименьшая строка больше всех геохешей с префиксом prefix: они лежат в [prefix, PrefixEnd(prefix))
+func PrefixEnd(prefix string) string {
+	// '{' идёт сразу за 'z', последним символом алфавита геохеша
+	return prefix + "{"
+}
+
+// Index - геохеш-сетка: точки, упорядоченные по геохешу. Точки одной ячейки любого размера лежат подряд,
+// поэтому запрос по прямоугольнику - несколько двоичных поисков по префиксам ячеек из Cover.
+// Index не защищён от одновременного доступа
+type Index struct {
+	entries []indexEntry
+	hashes  map[int64]string
+}
+
+type indexEntry struct {
+	hash string
+	id   int64
+}
+
+func NewIndex() *Index {
+	return &Index{hashes: make(map[int64]string)}
+}
+
+// Put кладёт или переносит точку id
+func (x *Index) Put(id int64, p Point) {
+	x.Remove(id)
+	e := indexEntry{hash: Encode(p, HashPrecision), id: id}
+	i := x.search(e)
+	x.entries = append(x.entries, indexEntry{})
+	copy(x.entries[i+1:], x.entries[i:])
+	x.entries[i] = e
+	x.hashes[id] = e.hash
+}
+
+func (x *Index) Remove(id int64) {
+	hash, ok := x.hashes[id]
+	if !ok {
+		return
+	}
+	i := x.search(indexEntry{hash: hash, id: id})
+	x.entries = append(x.entries[:i], x.entries[i+1:]...)
+	delete(x.hashes, id)
+}
+
+// Query возвращает точки из ячеек, покрывающих b. Среди них могут быть точки рядом с b, но не внутри:
+// вызывающий проверяет их сам
+func (x *Index) Query(b Box) []int64 {
+	var res []int64
+	for _, prefix := range Cover(b) {
+		i := sort.Search(len(x.entries), func(i int) bool { return x.entries[i].hash >= prefix })
+		for ; i < len(x.entries) && strings.HasPrefix(x.entries[i].hash, prefix); i++ {
+			res = append(res, x.entries[i].id)
+		}
+	}
+	return res
+}
+
+func (x *Index) search(e indexEntry) int {
+	return sort.Search(len(x.entries), func(i int) bool {
+		if x.entries[i].hash != e.hash {
+			return x.entries[i].hash > e.hash
+		}
+		return x.entries[i].id >= e.id
+	})
+}
@@ -0,0 +1,113 @@
+// Package images проверяет загруженные фотографии и делает из них превью
+package images
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/color"
+	"image/draw"
+	_ "image/gif"
+	"image/jpeg"
+	_ "image/png"
+)
+
+var (
+	ErrUnsupported   = errors.New("unsupported image format, use jpeg, png or gif")
+	ErrTooManyPixels = errors.New("image dimensions are too large")
+)
+
+// MaxPixels ограничивает площадь картинки, чтобы маленький файл не раскрывался в гигабайты при декодировании
+const MaxPixels = 25_000_000
+
+// contentTypes - поддерживаемые форматы, имена как у image.RegisterFormat
+var contentTypes = map[string]string{
+	"jpeg": "image/jpeg",
+	"png":  "image/png",
+	"gif":  "image/gif",
+}
+
+// Decode распознаёт формат по содержимому, а не по имени файла или заголовку, и декодирует картинку.
+// Для GIF берётся первый кадр
+func Decode(data []byte) (image.Image, string, error) {
+	cfg, format, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, "", ErrUnsupported
+	}
+	contentType, ok := contentTypes[format]
+	if !ok {
+		return nil, "", ErrUnsupported
+	}
+	if cfg.Width <= 0 || cfg.Height <= 0 || int64(cfg.Width)*int64(cfg.Height) > MaxPixels {
+		return nil, "", ErrTooManyPixels
+	}
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, "", ErrUnsupported
+	}
+	return img, contentType, nil
+}
+
+// Thumbnail уменьшает картинку так, чтобы большая сторона была не больше size, сохраняя пропорции.
+// Каждый пиксель превью - среднее по закрывающему его прямоугольнику оригинала. Маленькие картинки не увеличиваются.
+// Прозрачные области заливаются белым, потому что превью кодируется в JPEG.
+// Оригинал читается по одному такому прямоугольнику, поэтому его полноразмерная копия не создаётся
+func Thumbnail(src image.Image, size int) *image.RGBA {
+	b := src.Bounds()
+	w, h := b.Dx(), b.Dy()
+	tw, th := w, h
+	if w > size || h > size {
+		if w >= h {
+			tw, th = size, h*size/w
+		} else {
+			tw, th = w*size/h, size
+		}
+	}
+	if tw < 1 {
+		tw = 1
+	}
+	if th < 1 {
+		th = 1
+	}
+
+	// прямоугольники оригинала не больше ceil(w/tw) x ceil(h/th), один буфер на все
+	cell := image.NewRGBA(image.Rect(0, 0, (w+tw-1)/tw, (h+th-1)/th))
+	white := image.NewUniform(color.White)
+	dst := image.NewRGBA(image.Rect(0, 0, tw, th))
+	for y := 0; y < th; y++ {
+		y0, y1 := y*h/th, (y+1)*h/th
+		if y1 == y0 {
+			y1++
+		}
+		for x := 0; x < tw; x++ {
+			x0, x1 := x*w/tw, (x+1)*w/tw
+			if x1 == x0 {
+				x1++
+			}
+			r := image.Rect(0, 0, x1-x0, y1-y0)
+			draw.Draw(cell, r, white, image.Point{}, draw.Src)
+			draw.Draw(cell, r, src, b.Min.Add(image.Pt(x0, y0)), draw.Over)
+			var red, g, bl, n uint64
+			for sy := 0; sy < r.Dy(); sy++ {
+				row := cell.Pix[sy*cell.Stride:]
+				for sx := 0; sx < r.Dx(); sx++ {
+					red += uint64(row[sx*4])
+					g += uint64(row[sx*4+1])
+					bl += uint64(row[sx*4+2])
+					n++
+				}
+			}
+			dst.SetRGBA(x, y, color.RGBA{R: uint8(red / n), G: uint8(g / n), B: uint8(bl / n), A: 0xff})
+		}
+	}
+	return dst
+}
+
+// EncodeJPEG кодирует превью
+func EncodeJPEG(img image.Image) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: 85}); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
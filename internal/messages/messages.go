@@ -0,0 +1,68 @@
+// Package messages - переписка покупателей с продавцами об объявлениях
+package messages
+
+import "time"
+
+// Thread - переписка покупателя BuyerID с автором объявления SellerID об объявлении AdID
+type Thread struct {
+	ID       int64
+	AdID     int64
+	SellerID int64
+	BuyerID  int64
+	Created  time.Time
+	// LastMessageID и LastMessageAt - последнее сообщение, по его времени сортируются входящие
+	LastMessageID int64
+	LastMessageAt time.Time
+	// SellerReadID и BuyerReadID - до какого сообщения участник прочитал переписку. Своё сообщение участник читает сразу
+	SellerReadID int64
+	BuyerReadID  int64
+}
+
+// Participant - участвует ли пользователь в переписке
+func (t Thread) Participant(userID int64) bool {
+	return userID == t.SellerID || userID == t.BuyerID
+}
+
+// Correspondent возвращает собеседника участника userID
+func (t Thread) Correspondent(userID int64) int64 {
+	if userID == t.SellerID {
+		return t.BuyerID
+	}
+	return t.SellerID
+}
+
+// ReadID - до какого сообщения участник userID прочитал переписку
+func (t Thread) ReadID(userID int64) int64 {
+	if userID == t.SellerID {
+		return t.SellerReadID
+	}
+	return t.BuyerReadID
+}
+
+// ReadBy - прочитал ли сообщение m его получатель
+func (t Thread) ReadBy(m Message) bool {
+	return m.ID <= t.ReadID(t.Correspondent(m.SenderID))
+}
+
+// Before - идёт ли t раньше other во входящих: сначала переписки с более новым сообщением, при равном времени - по убыванию ID
+func (t Thread) Before(other Thread) bool {
+	if !t.LastMessageAt.Equal(other.LastMessageAt) {
+		return t.LastMessageAt.After(other.LastMessageAt)
+	}
+	return t.ID > other.ID
+}
+
+type Message struct {
+	ID       int64
+	ThreadID int64
+	SenderID int64
+	Text     string
+	Sent     time.Time
+}
+
+// Block - пользователь UserID заблокировал пользователя BlockedID
+type Block struct {
+	UserID    int64
+	BlockedID int64
+	Created   time.Time
+}
@@ -0,0 +1,41 @@
+package messages
+
+import (
+	"context"
+	"errors"
+)
+
+var (
+	// ErrNotFound - нет переписки или блокировки
+	ErrNotFound = errors.New("not found")
+	// ErrDuplicate - у покупателя уже есть переписка об этом объявлении или пользователь уже заблокирован
+	ErrDuplicate = errors.New("already exists")
+)
+
+// MessageRepository хранит переписки, сообщения и блокировки. ID переписок и сообщений выдаются с единицы
+type MessageRepository interface {
+	// CreateThread сохраняет переписку и возвращает её ID. Об одном объявлении у покупателя одна переписка,
+	// повторная - ErrDuplicate
+	CreateThread(ctx context.Context, t Thread) (int64, error)
+	GetThread(ctx context.Context, id int64) (*Thread, error)
+	// FindThread возвращает переписку покупателя buyerID об объявлении adID
+	FindThread(ctx context.Context, adID int64, buyerID int64) (*Thread, error)
+	// ListThreads возвращает не больше limit переписок, где userID продавец или покупатель, в порядке Thread.Before,
+	// идущих после after (nil - с начала). adID, если задан, оставляет переписки об одном объявлении, нулевой limit - все
+	ListThreads(ctx context.Context, userID int64, adID *int64, after *Thread, limit int) ([]Thread, error)
+
+	// AddMessage сохраняет сообщение в переписке m.ThreadID и возвращает его ID. Сообщение становится последним
+	// в переписке и сразу прочитано отправителем
+	AddMessage(ctx context.Context, m Message) (int64, error)
+	// ListMessages возвращает не больше limit сообщений переписки с ID больше afterID по возрастанию ID.
+	// Нулевой limit - без ограничения
+	ListMessages(ctx context.Context, threadID int64, afterID int64, limit int) ([]Message, error)
+	// MarkRead отмечает, что участник userID прочитал переписку до сообщения messageID. Назад отметка не сдвигается
+	MarkRead(ctx context.Context, threadID int64, userID int64, messageID int64) error
+
+	// Block блокирует пользователя, повторная блокировка - ErrDuplicate
+	Block(ctx context.Context, b Block) error
+	Unblock(ctx context.Context, userID int64, blockedID int64) error
+	// Blocked - заблокировал ли userID пользователя otherID
+	Blocked(ctx context.Context, userID int64, otherID int64) (bool, error)
+}
@@ -0,0 +1,110 @@
+// Package money знает поддерживаемые валюты ISO 4217 и переводит суммы между ними по таблице курсов.
+// Суммы везде хранятся целым числом минимальных единиц валюты (копеек, центов)
+package money
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+)
+
+var (
+	ErrUnknownCurrency = errors.New("unknown currency")
+	ErrNoRate          = errors.New("no exchange rate for currency")
+)
+
+// MaxAmount ограничивает суммы так, чтобы перевод между любыми валютами не переполнял int64
+const MaxAmount int64 = 1_000_000_000_000_000
+
+// currencies - число знаков после запятой у поддерживаемых валют
+var currencies = map[string]int{
+	"RUB": 2,
+	"USD": 2,
+	"EUR": 2,
+	"GBP": 2,
+	"CHF": 2,
+	"CNY": 2,
+	"KZT": 2,
+	"BYN": 2,
+	"UAH": 2,
+	"AMD": 2,
+	"GEL": 2,
+	"TRY": 2,
+	"AED": 2,
+	"JPY": 0,
+	"KRW": 0,
+}
+
+// Known сообщает, поддерживается ли валюта. Код сравнивается как есть, см. Normalize
+func Known(currency string) bool {
+	_, ok := currencies[currency]
+	return ok
+}
+
+// Normalize приводит код валюты к виду ISO 4217: три заглавные буквы
+func Normalize(currency string) string {
+	return strings.ToUpper(strings.TrimSpace(currency))
+}
+
+// Rates - курсы валют к базовой: сколько единиц базовой валюты стоит одна единица валюты.
+// Курс базовой валюты к самой себе всегда 1
+type Rates struct {
+	base  string
+	rates map[string]float64
+}
+
+func NewRates(base string, rates map[string]float64) (*Rates, error) {
+	base = Normalize(base)
+	if !Known(base) {
+		return nil, fmt.Errorf("%w %q", ErrUnknownCurrency, base)
+	}
+	r := &Rates{base: base, rates: map[string]float64{base: 1}}
+	for code, rate := range rates {
+		code = Normalize(code)
+		if !Known(code) {
+			return nil, fmt.Errorf("%w %q", ErrUnknownCurrency, code)
+		}
+		if !(rate > 0) || math.IsInf(rate, 0) {
+			return nil, fmt.Errorf("rate for %s must be positive, got %v", code, rate)
+		}
+		if code == base && rate != 1 {
+			return nil, fmt.Errorf("rate for base currency %s must be 1, got %v", code, rate)
+		}
+		r.rates[code] = rate
+	}
+	return r, nil
+}
+
+func (r *Rates) Base() string {
+	return r.base
+}
+
+// Convert переводит amount минимальных единиц from в минимальные единицы to, округляя до ближайшей
+func (r *Rates) Convert(amount int64, from string, to string) (int64, error) {
+	fromDigits, ok := currencies[from]
+	if !ok {
+		return 0, fmt.Errorf("%w %q", ErrUnknownCurrency, from)
+	}
+	toDigits, ok := currencies[to]
+	if !ok {
+		return 0, fmt.Errorf("%w %q", ErrUnknownCurrency, to)
+	}
+	if from == to {
+		return amount, nil
+	}
+	fromRate, ok := r.rates[from]
+	if !ok {
+		return 0, fmt.Errorf("%w %s", ErrNoRate, from)
+	}
+	toRate, ok := r.rates[to]
+	if !ok {
+		return 0, fmt.Errorf("%w %s", ErrNoRate, to)
+	}
+	major := float64(amount) / math.Pow10(fromDigits) * fromRate / toRate
+	converted := math.Round(major * math.Pow10(toDigits))
+	if math.Abs(converted) > float64(MaxAmount) {
+		return 0, fmt.Errorf("%d %s is too large to convert to %s", amount, from, to)
+	}
+	return int64(converted), nil
+}
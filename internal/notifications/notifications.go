@@ -0,0 +1,31 @@
+// Package notifications описывает уведомления пользователям о том, что произошло с их объявлениями
+package notifications
+
+import (
+	"context"
+	"time"
+)
+
+// Kind - повод уведомления
+type Kind string
+
+const (
+	// KindAdRejected - модератор отклонил объявление, причина в Text
+	KindAdRejected Kind = "ad.rejected"
+	// KindAdSuspended - объявление снято с публикации по жалобам пользователей
+	KindAdSuspended Kind = "ad.suspended"
+)
+
+type Notification struct {
+	UserID  int64
+	Kind    Kind
+	AdID    int64
+	Text    string
+	Created time.Time
+}
+
+// Notifier доставляет уведомления. Доставка не входит в транзакцию изменения, о котором уведомление,
+// поэтому ошибка доставки изменение не отменяет
+type Notifier interface {
+	Notify(ctx context.Context, n Notification) error
+}
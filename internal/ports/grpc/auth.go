@@ -0,0 +1,132 @@
+package grpc
+
+import (
+	"context"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+
+	"homework9/internal/app"
+)
+
+// bearerToken достаёт токен из метаданных authorization: Bearer <token>
+func bearerToken(ctx context.Context) string {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return ""
+	}
+	for _, v := range md.Get("authorization") {
+		scheme, token, ok := strings.Cut(v, " ")
+		if ok && strings.EqualFold(scheme, "Bearer") {
+			return strings.TrimSpace(token)
+		}
+	}
+	return ""
+}
+
+// publicMethods доступны без access-токена
+var publicMethods = map[string]bool{
+	AdService_ListAds_FullMethodName:    true,
+	AdService_FindAds_FullMethodName:    true,
+	AdService_SearchAds_FullMethodName:  true,
+	AdService_CreateUser_FullMethodName: true,
+	AdService_GetUser_FullMethodName:    true,
+	AdService_Login_FullMethodName:      true,
+	AdService_Refresh_FullMethodName:    true,
+	AdService_Logout_FullMethodName:     true,
+
+	AdService_ListCategories_FullMethodName: true,
+	AdService_GetCategory_FullMethodName:    true,
+}
+
+// AuthInterceptor проверяет access-токен из метаданных и кладёт ID пользователя в контекст вызова.
+// Без токена пропускаются только publicMethods
+func AuthInterceptor(a app.App) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		ctx, err := authenticate(ctx, a, info.FullMethod)
+		if err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+// AuthStreamInterceptor - AuthInterceptor для потоковых методов
+func AuthStreamInterceptor(a app.App) grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx, err := authenticate(ss.Context(), a, info.FullMethod)
+		if err != nil {
+			return err
+		}
+		return handler(srv, authenticatedStream{ServerStream: ss, ctx: ctx})
+	}
+}
+
+// authenticatedStream подменяет контекст потока контекстом с ID пользователя
+type authenticatedStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s authenticatedStream) Context() context.Context {
+	return s.ctx
+}
+
+func authenticate(ctx context.Context, a app.App, method string) (context.Context, error) {
+	token := bearerToken(ctx)
+	if token == "" {
+		if !publicMethods[method] {
+			return nil, status.Error(codes.Unauthenticated, app.ErrUnauthenticated.Error())
+		}
+		return ctx, nil
+	}
+
+	userID, err := a.Authenticate(ctx, token)
+	if err == app.ErrUnauthenticated {
+		return nil, status.Error(codes.Unauthenticated, err.Error())
+	} else if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	return app.WithUserID(ctx, userID), nil
+}
+
+func (as AdService) Login(ctx context.Context, in *LoginRequest) (*LoginResponse, error) {
+	t, err := as.app.Login(ctx, in.Email, in.Password)
+	if err == app.ErrInvalidCredentials {
+		return nil, status.Error(codes.Unauthenticated, err.Error())
+	} else if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	return newLoginResponse(t), nil
+}
+
+func (as AdService) Refresh(ctx context.Context, in *RefreshRequest) (*LoginResponse, error) {
+	t, err := as.app.Refresh(ctx, in.RefreshToken)
+	if err == app.ErrUnauthenticated {
+		return nil, status.Error(codes.Unauthenticated, err.Error())
+	} else if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	return newLoginResponse(t), nil
+}
+
+func (as AdService) Logout(ctx context.Context, in *RefreshRequest) (*emptypb.Empty, error) {
+	if err := as.app.Logout(ctx, in.RefreshToken); err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	return &emptypb.Empty{}, nil
+}
+
+func newLoginResponse(t *app.Tokens) *LoginResponse {
+	return &LoginResponse{
+		AccessToken:      t.AccessToken,
+		UserId:           t.UserID,
+		ExpiresAt:        t.AccessExpires.Unix(),
+		RefreshToken:     t.RefreshToken,
+		RefreshExpiresAt: t.RefreshExpires.Unix(),
+	}
+}
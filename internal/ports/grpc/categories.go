@@ -0,0 +1,76 @@
+package grpc
+
+import (
+	"context"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+
+	"homework9/internal/app"
+	"homework9/internal/categories"
+)
+
+func (as AdService) ListCategories(ctx context.Context, _ *emptypb.Empty) (*ListCategoriesResponse, error) {
+	list, err := as.app.ListCategories(ctx)
+	if err != nil {
+		return nil, categoryError(err)
+	}
+	res := make([]*CategoryResponse, 0, len(list))
+	for i := range list {
+		res = append(res, newCategoryResponse(&list[i]))
+	}
+	return &ListCategoriesResponse{List: res}, nil
+}
+
+func (as AdService) GetCategory(ctx context.Context, in *GetCategoryRequest) (*CategoryResponse, error) {
+	c, err := as.app.GetCategory(ctx, in.Id)
+	if err != nil {
+		return nil, categoryError(err)
+	}
+	return newCategoryResponse(c), nil
+}
+
+func (as AdService) CreateCategory(ctx context.Context, in *CreateCategoryRequest) (*CategoryResponse, error) {
+	c, err := as.app.CreateCategory(ctx, in.Name, in.ParentId)
+	if err != nil {
+		return nil, categoryError(err)
+	}
+	return newCategoryResponse(c), nil
+}
+
+func (as AdService) UpdateCategory(ctx context.Context, in *UpdateCategoryRequest) (*CategoryResponse, error) {
+	c, err := as.app.UpdateCategory(ctx, in.Id, in.Name, in.ParentId)
+	if err != nil {
+		return nil, categoryError(err)
+	}
+	return newCategoryResponse(c), nil
+}
+
+func (as AdService) DeleteCategory(ctx context.Context, in *DeleteCategoryRequest) (*emptypb.Empty, error) {
+	if err := as.app.DeleteCategory(ctx, in.Id); err != nil {
+		return nil, categoryError(err)
+	}
+	return &emptypb.Empty{}, nil
+}
+
+func newCategoryResponse(c *categories.Category) *CategoryResponse {
+	return &CategoryResponse{Id: c.ID, Name: c.Name, ParentId: c.ParentID}
+}
+
+func categoryError(err error) error {
+	switch err {
+	case app.ErrUnauthenticated:
+		return status.Error(codes.Unauthenticated, err.Error())
+	case app.ErrAccessDenied:
+		return status.Error(codes.PermissionDenied, err.Error())
+	case app.ErrNotFound:
+		return status.Error(codes.NotFound, err.Error())
+	case app.ErrInvalidCategory, app.ErrCategoryName, app.ErrCategoryCycle:
+		return status.Error(codes.InvalidArgument, err.Error())
+	case app.ErrCategoryNotEmpty:
+		return status.Error(codes.FailedPrecondition, err.Error())
+	default:
+		return status.Error(codes.Internal, err.Error())
+	}
+}
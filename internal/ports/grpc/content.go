@@ -0,0 +1,42 @@
+package grpc
+
+import (
+	"errors"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"homework9/internal/ads"
+	"homework9/internal/app"
+)
+
+func newFlags(flags []ads.Flag) []*Flag {
+	res := make([]*Flag, 0, len(flags))
+	for _, f := range flags {
+		res = append(res, &Flag{Check: f.Check, Field: f.Field, Reason: f.Reason})
+	}
+	return res
+}
+
+// contentStatus переводит отказ проверок содержимого в InvalidArgument. Замечания лежат в деталях
+// статуса (errdetails.BadRequest): field - поле объявления, description - "проверка (решение): причина".
+// Для других ошибок - nil
+func contentStatus(err error) error {
+	var rejected *app.ContentError
+	if !errors.As(err, &rejected) {
+		return nil
+	}
+	details := &errdetails.BadRequest{}
+	for _, f := range rejected.Findings {
+		details.FieldViolations = append(details.FieldViolations, &errdetails.BadRequest_FieldViolation{
+			Field:       f.Field,
+			Description: f.Check + " (" + f.Verdict.String() + "): " + f.Reason,
+		})
+	}
+	st, detailsErr := status.New(codes.InvalidArgument, err.Error()).WithDetails(details)
+	if detailsErr != nil {
+		return status.Error(codes.InvalidArgument, err.Error())
+	}
+	return st.Err()
+}
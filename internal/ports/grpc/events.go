@@ -0,0 +1,96 @@
+package grpc
+
+import (
+	"context"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"homework9/internal/app"
+	"homework9/internal/events"
+)
+
+// watchBuffer - сколько событий WatchAds может ждать отправки клиенту, прежде чем его отключат
+const watchBuffer = 64
+
+var adEventTypes = map[events.Kind]AdEventType{
+	events.KindAdCreated: AdEventType_AD_CREATED,
+	events.KindAdUpdated: AdEventType_AD_UPDATED,
+	events.KindAdStatus:  AdEventType_AD_STATUS_CHANGED,
+	events.KindAdDeleted: AdEventType_AD_DELETED,
+}
+
+func (as AdService) WatchAds(in *WatchAdsRequest, stream AdService_WatchAdsServer) error {
+	ctx := stream.Context()
+	filter := in.Filter
+	if filter == nil {
+		filter = &FindAdsRequest{}
+	}
+	f, err := fromFindAdsRequest(filter)
+	if err != nil {
+		return err
+	}
+	d, err := as.newPriceDisplay(ctx, filter.DisplayCurrency)
+	if err != nil {
+		return err
+	}
+	w, err := as.app.WatchAds(ctx, f, in.ResumeToken, watchBuffer)
+	if err != nil {
+		return watchError(err)
+	}
+	defer w.Close()
+
+	for _, e := range w.Backlog {
+		if err := stream.Send(as.newAdEvent(ctx, w, w.View(e), d)); err != nil {
+			return err
+		}
+	}
+	if err := stream.Send(&AdEvent{Type: AdEventType_AD_BOOKMARK, ResumeToken: w.Start}); err != nil {
+		return err
+	}
+	for {
+		select {
+		case e, ok := <-w.Events():
+			if !ok {
+				return watchError(w.Err())
+			}
+			if err := stream.Send(as.newAdEvent(ctx, w, w.View(e), d)); err != nil {
+				return err
+			}
+		case <-ctx.Done():
+			return status.FromContextError(ctx.Err()).Err()
+		}
+	}
+}
+
+func (as AdService) newAdEvent(ctx context.Context, w *app.AdWatch, e events.Event, d priceDisplay) *AdEvent {
+	res := &AdEvent{Type: adEventTypes[e.Kind], ResumeToken: w.ResumeToken(e), AdId: e.AdID, At: timestamppb.New(e.At)}
+	if e.Ad != nil {
+		res.Ad = newAdResponse(e.Ad, as.favoriteCount(ctx, e.AdID), d)
+	}
+	return res
+}
+
+func watchError(err error) error {
+	switch err {
+	case nil:
+		return nil
+	case app.ErrUnauthenticated:
+		return status.Error(codes.Unauthenticated, err.Error())
+	case app.ErrAccessDenied:
+		return status.Error(codes.PermissionDenied, err.Error())
+	case app.ErrInvalidResumeToken:
+		return status.Error(codes.InvalidArgument, err.Error())
+	case app.ErrResumeTokenExpired:
+		return status.Error(codes.FailedPrecondition, err.Error())
+	case events.ErrSlowSubscriber:
+		return status.Error(codes.ResourceExhausted, err.Error())
+	case events.ErrClosed:
+		return status.Error(codes.Unavailable, err.Error())
+	case app.ErrEventsDisabled:
+		return status.Error(codes.Unimplemented, err.Error())
+	default:
+		return pageError(err)
+	}
+}
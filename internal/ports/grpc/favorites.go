@@ -0,0 +1,100 @@
+package grpc
+
+import (
+	"context"
+	"log"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"homework9/internal/ads"
+	"homework9/internal/app"
+)
+
+func (as AdService) AddFavorite(ctx context.Context, in *FavoriteRequest) (*FavoriteResponse, error) {
+	f, err := as.app.AddFavorite(ctx, in.AdId)
+	if err != nil {
+		return nil, favoriteError(err)
+	}
+	return newFavoriteResponse(f, as.favoriteCount(ctx, in.AdId)), nil
+}
+
+func (as AdService) RemoveFavorite(ctx context.Context, in *FavoriteRequest) (*emptypb.Empty, error) {
+	if err := as.app.RemoveFavorite(ctx, in.AdId); err != nil {
+		return nil, favoriteError(err)
+	}
+	return &emptypb.Empty{}, nil
+}
+
+func (as AdService) ListFavorites(ctx context.Context, in *ListFavoritesRequest) (*ListFavoritesResponse, error) {
+	p, err := newPageRequest(in.Page)
+	if err != nil {
+		return nil, err
+	}
+	page, err := as.app.ListFavorites(ctx, p)
+	if err != nil {
+		return nil, favoriteError(err)
+	}
+	list := make([]ads.Ad, 0, len(page.Favorites))
+	for _, f := range page.Favorites {
+		if f.Ad != nil {
+			list = append(list, *f.Ad)
+		}
+	}
+	favorites := as.favoriteCounts(ctx, list)
+	res := &ListFavoritesResponse{List: make([]*FavoriteResponse, 0, len(page.Favorites)), NextCursor: page.NextCursor}
+	for i := range page.Favorites {
+		res.List = append(res.List, newFavoriteResponse(&page.Favorites[i], favorites[page.Favorites[i].AdID]))
+	}
+	return res, nil
+}
+
+func newFavoriteResponse(f *app.FavoriteAd, favorites int) *FavoriteResponse {
+	res := &FavoriteResponse{AdId: f.AdID, AddedAt: timestamppb.New(f.Added), Available: f.Available(), Deleted: f.Ad == nil}
+	if f.Ad != nil {
+		res.Ad = newAdResponse(f.Ad, favorites, priceDisplay{})
+	}
+	return res
+}
+
+// favoriteCounts возвращает число в избранном для объявлений ответа. Ошибка счётчика только пишется в журнал
+func (as AdService) favoriteCounts(ctx context.Context, list []ads.Ad) map[int64]int {
+	ids := make([]int64, 0, len(list))
+	for i := range list {
+		ids = append(ids, list[i].ID)
+	}
+	counts, err := as.app.FavoriteCounts(ctx, ids)
+	if err != nil {
+		log.Printf("count favorites: %v", err)
+		return map[int64]int{}
+	}
+	return counts
+}
+
+func (as AdService) favoriteCount(ctx context.Context, id int64) int {
+	counts, err := as.app.FavoriteCounts(ctx, []int64{id})
+	if err != nil {
+		log.Printf("count favorites: %v", err)
+		return 0
+	}
+	return counts[id]
+}
+
+func favoriteError(err error) error {
+	switch err {
+	case app.ErrUnauthenticated:
+		return status.Error(codes.Unauthenticated, err.Error())
+	case app.ErrAccessDenied:
+		return status.Error(codes.PermissionDenied, err.Error())
+	case app.ErrNotFound:
+		return status.Error(codes.NotFound, err.Error())
+	case app.ErrFavoriteUnavailable:
+		return status.Error(codes.FailedPrecondition, err.Error())
+	case app.ErrFavoritesDisabled:
+		return status.Error(codes.Unimplemented, err.Error())
+	default:
+		return pageError(err)
+	}
+}
@@ -0,0 +1,89 @@
+package grpc
+
+import (
+	"io"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"homework9/internal/ads"
+	"homework9/internal/app"
+)
+
+func (as AdService) UploadAdImage(stream AdService_UploadAdImageServer) error {
+	first, err := stream.Recv()
+	if err == io.EOF {
+		return status.Error(codes.InvalidArgument, "empty upload stream")
+	} else if err != nil {
+		return err
+	}
+	r := &chunkReader{stream: stream, buf: first.Chunk}
+	ad, err := as.app.AddAdImage(stream.Context(), first.AdId, r)
+	if r.err != nil {
+		// поток оборвался: клиент отменил вызов или истёк срок
+		return r.err
+	}
+	if err != nil {
+		return imageError(err)
+	}
+	return stream.SendAndClose(newAdResponse(ad, as.favoriteCount(stream.Context(), ad.ID), priceDisplay{}))
+}
+
+// chunkReader читает файл из кусков chunk сообщений потока
+type chunkReader struct {
+	stream AdService_UploadAdImageServer
+	buf    []byte
+	// err - ошибка потока, кроме io.EOF в конце файла
+	err error
+}
+
+func (r *chunkReader) Read(p []byte) (int, error) {
+	for len(r.buf) == 0 {
+		msg, err := r.stream.Recv()
+		if err == io.EOF {
+			return 0, io.EOF
+		} else if err != nil {
+			r.err = err
+			return 0, err
+		}
+		r.buf = msg.Chunk
+	}
+	n := copy(p, r.buf)
+	r.buf = r.buf[n:]
+	return n, nil
+}
+
+func newImages(list []ads.Image) []*Image {
+	res := make([]*Image, 0, len(list))
+	for _, image := range list {
+		res = append(res, &Image{
+			Id:          image.ID,
+			ContentType: image.ContentType,
+			Size:        image.Size,
+			Width:       int32(image.Width),
+			Height:      int32(image.Height),
+		})
+	}
+	return res
+}
+
+func imageError(err error) error {
+	switch err {
+	case app.ErrUnauthenticated:
+		return status.Error(codes.Unauthenticated, err.Error())
+	case app.ErrAccessDenied:
+		return status.Error(codes.PermissionDenied, err.Error())
+	case app.ErrNotFound:
+		return status.Error(codes.NotFound, err.Error())
+	case app.ErrUnsupportedImage, app.ErrImageTooLarge:
+		return status.Error(codes.InvalidArgument, err.Error())
+	case app.ErrTooManyImages:
+		return status.Error(codes.FailedPrecondition, err.Error())
+	case app.ErrVersionConflict:
+		return status.Error(codes.Aborted, err.Error())
+	case app.ErrImagesDisabled:
+		return status.Error(codes.Unimplemented, err.Error())
+	default:
+		return status.Error(codes.Internal, err.Error())
+	}
+}
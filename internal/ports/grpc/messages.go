@@ -0,0 +1,130 @@
+package grpc
+
+import (
+	"context"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"homework9/internal/app"
+	"homework9/internal/messages"
+)
+
+func (as AdService) ContactSeller(ctx context.Context, in *ContactSellerRequest) (*MessageResponse, error) {
+	m, err := as.app.ContactSeller(ctx, in.AdId, in.Text)
+	if err != nil {
+		return nil, messageError(err)
+	}
+	return newMessageResponse(m, false), nil
+}
+
+func (as AdService) SendMessage(ctx context.Context, in *SendMessageRequest) (*MessageResponse, error) {
+	m, err := as.app.SendMessage(ctx, in.ThreadId, in.Text)
+	if err != nil {
+		return nil, messageError(err)
+	}
+	return newMessageResponse(m, false), nil
+}
+
+func (as AdService) ListThreads(ctx context.Context, in *ListThreadsRequest) (*ListThreadsResponse, error) {
+	p, err := newPageRequest(in.Page)
+	if err != nil {
+		return nil, err
+	}
+	page, err := as.app.ListThreads(ctx, in.AdId, p)
+	if err != nil {
+		return nil, messageError(err)
+	}
+	res := &ListThreadsResponse{List: make([]*ThreadResponse, 0, len(page.Threads)), NextCursor: page.NextCursor}
+	for i := range page.Threads {
+		res.List = append(res.List, newThreadResponse(&page.Threads[i]))
+	}
+	return res, nil
+}
+
+func (as AdService) ListMessages(ctx context.Context, in *ListMessagesRequest) (*ListMessagesResponse, error) {
+	p, err := newPageRequest(in.Page)
+	if err != nil {
+		return nil, err
+	}
+	page, err := as.app.ListMessages(ctx, in.ThreadId, p)
+	if err != nil {
+		return nil, messageError(err)
+	}
+	res := &ListMessagesResponse{
+		Thread:     newThreadResponse(&page.Thread),
+		List:       make([]*MessageResponse, 0, len(page.Messages)),
+		NextCursor: page.NextCursor,
+	}
+	for i := range page.Messages {
+		res.List = append(res.List, newMessageResponse(&page.Messages[i], page.Thread.ReadBy(page.Messages[i])))
+	}
+	return res, nil
+}
+
+func (as AdService) MarkThreadRead(ctx context.Context, in *ThreadRequest) (*ThreadResponse, error) {
+	t, err := as.app.MarkThreadRead(ctx, in.ThreadId)
+	if err != nil {
+		return nil, messageError(err)
+	}
+	return newThreadResponse(t), nil
+}
+
+func (as AdService) BlockUser(ctx context.Context, in *BlockUserRequest) (*emptypb.Empty, error) {
+	if err := as.app.BlockUser(ctx, in.UserId); err != nil {
+		return nil, messageError(err)
+	}
+	return &emptypb.Empty{}, nil
+}
+
+func (as AdService) UnblockUser(ctx context.Context, in *BlockUserRequest) (*emptypb.Empty, error) {
+	if err := as.app.UnblockUser(ctx, in.UserId); err != nil {
+		return nil, messageError(err)
+	}
+	return &emptypb.Empty{}, nil
+}
+
+func newThreadResponse(t *app.ThreadView) *ThreadResponse {
+	return &ThreadResponse{
+		Id:            t.ID,
+		AdId:          t.AdID,
+		SellerId:      t.SellerID,
+		BuyerId:       t.BuyerID,
+		CreatedAt:     timestamppb.New(t.Created),
+		LastMessageAt: timestamppb.New(t.LastMessageAt),
+		Unread:        int64(t.Unread),
+		Blocked:       t.Blocked,
+	}
+}
+
+func newMessageResponse(m *messages.Message, read bool) *MessageResponse {
+	return &MessageResponse{
+		Id:       m.ID,
+		ThreadId: m.ThreadID,
+		SenderId: m.SenderID,
+		Text:     m.Text,
+		SentAt:   timestamppb.New(m.Sent),
+		Read:     read,
+	}
+}
+
+func messageError(err error) error {
+	switch err {
+	case app.ErrUnauthenticated:
+		return status.Error(codes.Unauthenticated, err.Error())
+	case app.ErrAccessDenied, app.ErrConversationBlocked:
+		return status.Error(codes.PermissionDenied, err.Error())
+	case app.ErrNotFound:
+		return status.Error(codes.NotFound, err.Error())
+	case app.ErrThreadUnavailable:
+		return status.Error(codes.FailedPrecondition, err.Error())
+	case app.ErrInvalidMessage, app.ErrSelfMessage:
+		return status.Error(codes.InvalidArgument, err.Error())
+	case app.ErrMessagesDisabled:
+		return status.Error(codes.Unimplemented, err.Error())
+	default:
+		return pageError(err)
+	}
+}
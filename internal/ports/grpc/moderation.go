@@ -0,0 +1,67 @@
+package grpc
+
+import (
+	"context"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"homework9/internal/ads"
+	"homework9/internal/app"
+)
+
+func (as AdService) ReviewQueue(ctx context.Context, in *ReviewQueueRequest) (*ListAdResponse, error) {
+	p, err := newPageRequest(in.Page)
+	if err != nil {
+		return nil, err
+	}
+	page, err := as.app.ReviewQueue(ctx, p)
+	if err != nil {
+		return nil, moderationError(err)
+	}
+	return newMultipleAdsResponse(page, as.favoriteCounts(ctx, page.Ads), priceDisplay{}), nil
+}
+
+func (as AdService) ApproveAd(ctx context.Context, in *ReviewAdRequest) (*AdResponse, error) {
+	a, err := as.app.ApproveAd(ctx, in.AdId, in.Reason)
+	if err != nil {
+		return nil, moderationError(err)
+	}
+	return newAdResponse(a, as.favoriteCount(ctx, a.ID), priceDisplay{}), nil
+}
+
+func (as AdService) RejectAd(ctx context.Context, in *ReviewAdRequest) (*AdResponse, error) {
+	a, err := as.app.RejectAd(ctx, in.AdId, in.Reason)
+	if err != nil {
+		return nil, moderationError(err)
+	}
+	return newAdResponse(a, as.favoriteCount(ctx, a.ID), priceDisplay{}), nil
+}
+
+// newReview возвращает nil, если объявление ещё не проверяли
+func newReview(r ads.Review) *Review {
+	if r.At.IsZero() {
+		return nil
+	}
+	return &Review{ModeratorId: r.ModeratorID, Reason: r.Reason, ReviewedAt: timestamppb.New(r.At)}
+}
+
+func moderationError(err error) error {
+	switch err {
+	case app.ErrUnauthenticated:
+		return status.Error(codes.Unauthenticated, err.Error())
+	case app.ErrAccessDenied:
+		return status.Error(codes.PermissionDenied, err.Error())
+	case app.ErrNotFound:
+		return status.Error(codes.NotFound, err.Error())
+	case app.ErrVersionConflict:
+		return status.Error(codes.Aborted, err.Error())
+	case app.ErrInvalidTransition:
+		return status.Error(codes.FailedPrecondition, err.Error())
+	case app.ErrInvalidReason:
+		return status.Error(codes.InvalidArgument, err.Error())
+	default:
+		return pageError(err)
+	}
+}
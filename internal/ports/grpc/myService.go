@@ -5,85 +5,179 @@ import (
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 	"google.golang.org/protobuf/types/known/emptypb"
-	"homework9/internal/adapters/adrepo"
-	"homework9/internal/adapters/userrepo"
+	"google.golang.org/protobuf/types/known/timestamppb"
 	"homework9/internal/ads"
 	"homework9/internal/app"
+	"homework9/internal/money"
 	"homework9/internal/users"
+	"time"
 )
 
 type AdService struct {
 	app app.App
 }
 
-func NewService() AdServiceServer {
-	return AdService{
-		app: app.NewApp(adrepo.New(), userrepo.New()).(app.MyApp),
-	}
+func NewService(a app.App) AdServiceServer {
+	return AdService{app: a}
 }
 
 func (as AdService) CreateAd(ctx context.Context, reqBody *CreateAdRequest) (*AdResponse, error) {
-	_, err := as.app.GetUserByID(ctx, reqBody.UserId)
-	if err == app.ErrNotFound {
-		return nil, status.Error(codes.NotFound, err.Error())
+	a, err := as.app.CreateAd(ctx, reqBody.Title, reqBody.Text, reqBody.CategoryId, fromPrice(reqBody.Price), fromLocation(reqBody.Location))
+	if st := contentStatus(err); st != nil {
+		return nil, st
+	} else if err == app.ErrUnauthenticated {
+		return nil, status.Error(codes.Unauthenticated, err.Error())
+	} else if err == app.ErrInvalidCategory || err == app.ErrInvalidPrice || err == app.ErrInvalidCurrency || err == app.ErrInvalidLocation {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
 	} else if err != nil {
 		return nil, status.Error(codes.Internal, err.Error())
 	}
-
-	a, err := as.app.CreateAd(ctx, reqBody.Title, reqBody.Text, reqBody.UserId)
-	if err != nil {
-		return nil, status.Error(codes.Internal, err.Error())
-	}
-	return newAdResponse(a), nil
+	return newAdResponse(a, as.favoriteCount(ctx, a.ID), priceDisplay{}), nil
 }
 func (as AdService) ChangeAdStatus(ctx context.Context, reqBody *ChangeAdStatusRequest) (*AdResponse, error) {
-	_, err := as.app.GetUserByID(ctx, reqBody.UserId)
-	if err == app.ErrNotFound {
+	a, err := as.app.UpdateStatusById(ctx, reqBody.AdId, reqBody.Published)
+	if err == app.ErrUnauthenticated {
+		return nil, status.Error(codes.Unauthenticated, err.Error())
+	} else if err == app.ErrAdExpired {
+		return nil, status.Error(codes.FailedPrecondition, err.Error())
+	} else if err == app.ErrAccessDenied {
+		return nil, status.Error(codes.PermissionDenied, err.Error())
+	} else if err == app.ErrNotFound {
 		return nil, status.Error(codes.NotFound, err.Error())
+	} else if err == app.ErrVersionConflict {
+		return nil, status.Error(codes.Aborted, err.Error())
 	} else if err != nil {
 		return nil, status.Error(codes.Internal, err.Error())
 	}
 
-	a, err := as.app.UpdateStatusById(ctx, reqBody.AdId, reqBody.Published, reqBody.UserId)
-	if err == app.ErrAccessDenied {
+	return newAdResponse(a, as.favoriteCount(ctx, a.ID), priceDisplay{}), nil
+}
+func (as AdService) UpdateAd(ctx context.Context, in *UpdateAdRequest) (*AdResponse, error) {
+	var price *ads.Price
+	if in.Price != nil {
+		p := fromPrice(in.Price)
+		price = &p
+	}
+	a, err := as.app.UpdateAdById(ctx, in.AdId, in.Title, in.Text, in.CategoryId, price, fromLocation(in.Location), in.Version)
+	if st := contentStatus(err); st != nil {
+		return nil, st
+	} else if err == app.ErrUnauthenticated {
+		return nil, status.Error(codes.Unauthenticated, err.Error())
+	} else if err == app.ErrInvalidCategory || err == app.ErrInvalidPrice || err == app.ErrInvalidCurrency || err == app.ErrInvalidLocation {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	} else if err == app.ErrAccessDenied {
 		return nil, status.Error(codes.PermissionDenied, err.Error())
+	} else if err == app.ErrNotFound {
+		return nil, status.Error(codes.NotFound, err.Error())
+	} else if err == app.ErrVersionConflict {
+		return nil, status.Error(codes.Aborted, err.Error())
 	} else if err != nil {
 		return nil, status.Error(codes.Internal, err.Error())
 	}
 
-	return newAdResponse(a), nil
+	return newAdResponse(a, as.favoriteCount(ctx, a.ID), priceDisplay{}), nil
 }
-func (as AdService) UpdateAd(ctx context.Context, in *UpdateAdRequest) (*AdResponse, error) {
-	_, err := as.app.GetUserByID(ctx, in.UserId)
-	if err == app.ErrNotFound {
-		return nil, status.Error(codes.NotFound, "User not found")
-	} else if err != nil {
-		return nil, status.Error(codes.Internal, err.Error())
+func (as AdService) ListAds(ctx context.Context, in *ListAdsRequest) (*ListAdResponse, error) {
+	p, err := newPageRequest(in.Page)
+	if err != nil {
+		return nil, err
 	}
-
+	d, err := as.newPriceDisplay(ctx, in.DisplayCurrency)
 	if err != nil {
-		return nil, status.Error(codes.InvalidArgument, err.Error())
+		return nil, err
 	}
-
-	a, err := as.app.UpdateAdById(ctx, in.AdId, in.Title, in.Text, in.UserId)
-	if err == app.ErrAccessDenied {
-		return nil, status.Error(codes.PermissionDenied, err.Error())
-	} else if err != nil {
-		return nil, status.Error(codes.Internal, err.Error())
+	var page *app.AdsPage
+	if in.CategoryId != 0 || in.Near != nil {
+		f := app.FilterOpts{Published: ads.OnlyPublished, Near: fromCircle(in.Near)}
+		if in.CategoryId != 0 {
+			f.CategoryIDs = []int64{in.CategoryId}
+		}
+		page, err = as.app.GetAdsByFilter(ctx, f, p)
+	} else {
+		page, err = as.app.ListPublishedAds(ctx, p)
+	}
+	if err != nil {
+		return nil, pageError(err)
+	}
+	return newMultipleAdsResponse(page, as.favoriteCounts(ctx, page.Ads), d), nil
+}
+func (as AdService) FindAds(ctx context.Context, in *FindAdsRequest) (*ListAdResponse, error) {
+	f, err := fromFindAdsRequest(in)
+	if err != nil {
+		return nil, err
+	}
+	p, err := newPageRequest(in.Page)
+	if err != nil {
+		return nil, err
+	}
+	d, err := as.newPriceDisplay(ctx, in.DisplayCurrency)
+	if err != nil {
+		return nil, err
+	}
+	page, err := as.app.GetAdsByFilter(ctx, f, p)
+	if err != nil {
+		return nil, pageError(err)
 	}
+	return newMultipleAdsResponse(page, as.favoriteCounts(ctx, page.Ads), d), nil
+}
 
-	return newAdResponse(a), nil
+// fromFindAdsRequest - условия отбора FindAdsRequest без страницы и валюты показа
+func fromFindAdsRequest(in *FindAdsRequest) (app.FilterOpts, error) {
+	f := app.FilterOpts{
+		AuthorIDs:     in.AuthorIds,
+		CategoryIDs:   in.CategoryIds,
+		CreatedFrom:   fromTimestamp(in.CreatedFrom),
+		CreatedTo:     fromTimestamp(in.CreatedTo),
+		ModifiedFrom:  fromTimestamp(in.ModifiedFrom),
+		ModifiedTo:    fromTimestamp(in.ModifiedTo),
+		TitleContains: in.TitleContains,
+		TextContains:  in.TextContains,
+		Currency:      in.Currency,
+		PriceMin:      in.PriceMin,
+		PriceMax:      in.PriceMax,
+		Near:          fromCircle(in.Near),
+		Box:           fromBox(in.Box),
+	}
+	switch in.Published {
+	case PublishedFilter_ANY:
+	case PublishedFilter_PUBLISHED:
+		f.Published = ads.OnlyPublished
+	case PublishedFilter_UNPUBLISHED:
+		f.Published = ads.OnlyUnpublished
+	default:
+		return app.FilterOpts{}, status.Errorf(codes.InvalidArgument, "unknown published filter %d", in.Published)
+	}
+	return f, nil
 }
-func (as AdService) ListAds(ctx context.Context, in *emptypb.Empty) (*ListAdResponse, error) {
-	l, err := as.app.ListPublishedAds(ctx)
+func (as AdService) SearchAds(ctx context.Context, in *SearchAdsRequest) (*SearchAdsResponse, error) {
+	d, err := as.newPriceDisplay(ctx, in.DisplayCurrency)
 	if err != nil {
+		return nil, err
+	}
+	results, err := as.app.SearchAds(ctx, in.Query, in.CategoryId, int(in.Limit))
+	if err == app.ErrEmptyQuery || err == app.ErrInvalidCategory {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	} else if err != nil {
 		return nil, status.Error(codes.Internal, err.Error())
 	}
-	return newMultipleAdsResponse(l), nil
+	list := make([]ads.Ad, 0, len(results))
+	for i := range results {
+		list = append(list, results[i].Ad)
+	}
+	favorites := as.favoriteCounts(ctx, list)
+	hits := make([]*SearchHit, 0, len(results))
+	for i := range results {
+		hits = append(hits, &SearchHit{Ad: newAdResponse(&results[i].Ad, favorites[results[i].Ad.ID], d), Score: results[i].Score})
+	}
+	return &SearchAdsResponse{Hits: hits}, nil
 }
 func (as AdService) CreateUser(ctx context.Context, in *CreateUserRequest) (*UserResponse, error) {
-	u, err := as.app.CreateUser(ctx, in.Name, "")
-	if err != nil {
+	u, err := as.app.CreateUser(ctx, in.Name, in.Email, in.Password)
+	if err == app.ErrInvalidEmail || err == app.ErrInvalidPassword {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	} else if err == app.ErrEmailTaken {
+		return nil, status.Error(codes.AlreadyExists, err.Error())
+	} else if err != nil {
 		return nil, status.Error(codes.Internal, err.Error())
 	}
 	return newUserResponse(u), nil
@@ -97,16 +191,22 @@ func (as AdService) GetUser(ctx context.Context, in *GetUserRequest) (*UserRespo
 }
 func (as AdService) DeleteUser(ctx context.Context, in *DeleteUserRequest) (*emptypb.Empty, error) {
 	err := as.app.DeleteUser(ctx, in.Id)
-	if err == app.ErrNotFound {
+	if err == app.ErrUnauthenticated {
+		return nil, status.Error(codes.Unauthenticated, err.Error())
+	} else if err == app.ErrNotFound {
 		return nil, status.Error(codes.NotFound, err.Error())
+	} else if err == app.ErrAccessDenied {
+		return nil, status.Error(codes.PermissionDenied, err.Error())
 	} else if err != nil {
 		return nil, status.Error(codes.Internal, err.Error())
 	}
 	return &emptypb.Empty{}, nil
 }
 func (as AdService) DeleteAd(ctx context.Context, in *DeleteAdRequest) (*emptypb.Empty, error) {
-	err := as.app.DeleteAd(ctx, in.AdId, in.AuthorId)
-	if err == app.ErrNotFound {
+	err := as.app.DeleteAd(ctx, in.AdId)
+	if err == app.ErrUnauthenticated {
+		return nil, status.Error(codes.Unauthenticated, err.Error())
+	} else if err == app.ErrNotFound {
 		return nil, status.Error(codes.NotFound, err.Error())
 	} else if err == app.ErrAccessDenied {
 		return nil, status.Error(codes.PermissionDenied, err.Error())
@@ -116,27 +216,149 @@ func (as AdService) DeleteAd(ctx context.Context, in *DeleteAdRequest) (*emptypb
 	return &emptypb.Empty{}, nil
 }
 
-func newAdResponse(ad *ads.Ad) *AdResponse {
+func (as AdService) SetUserRole(ctx context.Context, in *SetUserRoleRequest) (*UserResponse, error) {
+	u, err := as.app.SetUserRole(ctx, in.Id, users.Role(in.Role))
+	if err == app.ErrUnauthenticated {
+		return nil, status.Error(codes.Unauthenticated, err.Error())
+	} else if err == app.ErrAccessDenied {
+		return nil, status.Error(codes.PermissionDenied, err.Error())
+	} else if err == app.ErrNotFound {
+		return nil, status.Error(codes.NotFound, err.Error())
+	} else if err == app.ErrInvalidRole {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	} else if err == app.ErrVersionConflict {
+		return nil, status.Error(codes.Aborted, err.Error())
+	} else if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	return newUserResponse(u), nil
+}
+
+func newAdResponse(ad *ads.Ad, favorites int, d priceDisplay) *AdResponse {
 	return &AdResponse{Title: ad.Title,
-		Text:      ad.Text,
-		Id:        ad.ID,
-		AuthorId:  ad.AuthorID,
-		Published: ad.Published}
+		Text:         ad.Text,
+		Id:           ad.ID,
+		AuthorId:     ad.AuthorID,
+		Published:    ad.Published,
+		Version:      ad.Version,
+		CategoryId:   ad.CategoryID,
+		Price:        newPrice(ad.Price),
+		DisplayPrice: d.price(ad.Price),
+		Images:       newImages(ad.Images),
+		Location:     newLocation(ad.Location),
+		PublishAt:    newTimestamp(ad.PublishAt),
+		ExpiresAt:    newTimestamp(ad.ExpiresAt),
+		Status:       string(ad.EffectiveStatus()),
+		Review:       newReview(ad.Review),
+		Flags:        newFlags(ad.Flags),
+		Favorites:    int64(favorites)}
+}
+
+func fromPrice(p *Price) ads.Price {
+	if p == nil {
+		return ads.Price{}
+	}
+	return ads.Price{Amount: p.Amount, Currency: p.Currency, Negotiable: p.Negotiable}
+}
+
+// newPrice возвращает nil для объявления без цены
+func newPrice(p ads.Price) *Price {
+	if p == (ads.Price{}) {
+		return nil
+	}
+	return &Price{Amount: p.Amount, Currency: p.Currency, Negotiable: p.Negotiable}
+}
+
+// priceDisplay переводит цены в валюту показа. Нулевой priceDisplay цены не переводит
+type priceDisplay struct {
+	ctx      context.Context
+	app      app.App
+	currency string
+}
+
+func (as AdService) newPriceDisplay(ctx context.Context, currency string) (priceDisplay, error) {
+	currency = money.Normalize(currency)
+	if currency == "" {
+		return priceDisplay{}, nil
+	}
+	if !money.Known(currency) {
+		return priceDisplay{}, status.Errorf(codes.InvalidArgument, "%s %q", app.ErrInvalidCurrency, currency)
+	}
+	return priceDisplay{ctx: ctx, app: as.app, currency: currency}, nil
+}
+
+func (d priceDisplay) price(p ads.Price) *Price {
+	if d.app == nil || p.Currency == "" {
+		return nil
+	}
+	converted, err := d.app.ConvertPrice(d.ctx, p, d.currency)
+	if err != nil {
+		// без курса цена показывается только в исходной валюте
+		return nil
+	}
+	return newPrice(converted)
 }
 
 func newUserResponse(u *users.User) *UserResponse {
 	return &UserResponse{
-		Id:   u.ID,
-		Name: u.Nickname,
+		Id:    u.ID,
+		Name:  u.Nickname,
+		Email: u.Email,
+		Role:  string(u.EffectiveRole()),
 	}
 }
 
-func newMultipleAdsResponse(ads []ads.Ad) *ListAdResponse {
+// newMultipleAdsResponse: favorites - число в избранном по ID объявлений
+func newMultipleAdsResponse(page *app.AdsPage, favorites map[int64]int, d priceDisplay) *ListAdResponse {
 	res := make([]*AdResponse, 0)
-	for _, ad := range ads {
-		res = append(res, newAdResponse(&ad))
+	for i := range page.Ads {
+		res = append(res, newAdResponse(&page.Ads[i], favorites[page.Ads[i].ID], d))
 	}
 	return &ListAdResponse{
-		List: res,
+		List:       res,
+		NextCursor: page.NextCursor,
+	}
+}
+
+var sortFields = map[SortField]ads.SortField{
+	SortField_ID:       ads.SortByID,
+	SortField_CREATED:  ads.SortByCreated,
+	SortField_MODIFIED: ads.SortByModified,
+	SortField_TITLE:    ads.SortByTitle,
+	SortField_PRICE:    ads.SortByPrice,
+	SortField_DISTANCE: ads.SortByDistance,
+}
+
+// newPageRequest переводит параметры страницы из запроса. Отсутствующий page - первая страница по возрастанию ID
+func newPageRequest(in *PageRequest) (app.PageRequest, error) {
+	if in == nil {
+		return app.PageRequest{}, nil
+	}
+	field, ok := sortFields[in.Sort]
+	if !ok {
+		return app.PageRequest{}, status.Errorf(codes.InvalidArgument, "unknown sort field %d", in.Sort)
+	}
+	return app.PageRequest{
+		Sort:   ads.Sort{Field: field, Desc: in.Desc, Origin: fromPoint(in.Origin)},
+		Limit:  int(in.Limit),
+		Cursor: in.Cursor,
+	}, nil
+}
+
+func pageError(err error) error {
+	switch err {
+	case app.ErrInvalidCursor, app.ErrInvalidSort, app.ErrInvalidCategory, app.ErrInvalidPrice, app.ErrInvalidCurrency,
+		app.ErrInvalidArea, app.ErrInvalidLocation:
+		return status.Error(codes.InvalidArgument, err.Error())
+	default:
+		return status.Error(codes.Internal, err.Error())
+	}
+}
+
+// fromTimestamp переводит отсутствующую границу в нулевое время, то есть «без ограничения»
+func fromTimestamp(ts *timestamppb.Timestamp) time.Time {
+	if ts == nil {
+		return time.Time{}
 	}
+	return ts.AsTime()
 }
@@ -0,0 +1,48 @@
+package grpc
+
+import (
+	"homework9/internal/ads"
+	"homework9/internal/geo"
+)
+
+// fromLocation возвращает nil, если место в запросе не задано
+func fromLocation(l *Location) *ads.Location {
+	if l == nil {
+		return nil
+	}
+	return &ads.Location{Lat: l.Lat, Lon: l.Lon, City: l.City}
+}
+
+// newLocation возвращает nil для объявления без места
+func newLocation(l *ads.Location) *Location {
+	if l == nil {
+		return nil
+	}
+	return &Location{Lat: l.Lat, Lon: l.Lon, City: l.City}
+}
+
+func fromPoint(p *Point) *geo.Point {
+	if p == nil {
+		return nil
+	}
+	return &geo.Point{Lat: p.Lat, Lon: p.Lon}
+}
+
+// fromCircle: круг без центра получает центр (0, 0), как и в REST
+func fromCircle(c *Circle) *geo.Circle {
+	if c == nil {
+		return nil
+	}
+	res := &geo.Circle{RadiusKm: c.RadiusKm}
+	if center := fromPoint(c.Center); center != nil {
+		res.Center = *center
+	}
+	return res
+}
+
+func fromBox(b *Box) *geo.Box {
+	if b == nil {
+		return nil
+	}
+	return &geo.Box{MinLat: b.MinLat, MinLon: b.MinLon, MaxLat: b.MaxLat, MaxLon: b.MaxLon}
+}
@@ -0,0 +1,91 @@
+package grpc
+
+import (
+	"context"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"homework9/internal/app"
+	"homework9/internal/reports"
+)
+
+func (as AdService) CreateReport(ctx context.Context, in *CreateReportRequest) (*ReportResponse, error) {
+	r, err := as.app.CreateReport(ctx, reports.Target(in.Target), in.TargetId, reports.Reason(in.Reason), in.Comment)
+	if err != nil {
+		return nil, reportError(err)
+	}
+	return newReportResponse(r), nil
+}
+
+func (as AdService) ListReports(ctx context.Context, in *ListReportsRequest) (*ListReportsResponse, error) {
+	p, err := newPageRequest(in.Page)
+	if err != nil {
+		return nil, err
+	}
+	f := reports.Filter{Status: reports.Status(in.Status), Target: reports.Target(in.Target), TargetIDs: in.TargetIds}
+	page, err := as.app.ListReports(ctx, f, p)
+	if err != nil {
+		return nil, reportError(err)
+	}
+	res := &ListReportsResponse{List: make([]*ReportResponse, 0, len(page.Reports)), NextCursor: page.NextCursor}
+	for i := range page.Reports {
+		res.List = append(res.List, newReportResponse(&page.Reports[i]))
+	}
+	return res, nil
+}
+
+func (as AdService) ResolveReport(ctx context.Context, in *CloseReportRequest) (*ReportResponse, error) {
+	r, err := as.app.ResolveReport(ctx, in.ReportId, in.Note)
+	if err != nil {
+		return nil, reportError(err)
+	}
+	return newReportResponse(r), nil
+}
+
+func (as AdService) DismissReport(ctx context.Context, in *CloseReportRequest) (*ReportResponse, error) {
+	r, err := as.app.DismissReport(ctx, in.ReportId, in.Note)
+	if err != nil {
+		return nil, reportError(err)
+	}
+	return newReportResponse(r), nil
+}
+
+func newReportResponse(r *reports.Report) *ReportResponse {
+	res := &ReportResponse{
+		Id:         r.ID,
+		ReporterId: r.ReporterID,
+		Target:     string(r.Target),
+		TargetId:   r.TargetID,
+		Reason:     string(r.Reason),
+		Comment:    r.Comment,
+		Status:     string(r.Status),
+		CreatedAt:  timestamppb.New(r.Created),
+	}
+	if !r.Resolution.At.IsZero() {
+		res.Resolution = &Resolution{ModeratorId: r.Resolution.ModeratorID, Note: r.Resolution.Note, ResolvedAt: timestamppb.New(r.Resolution.At)}
+	}
+	return res
+}
+
+func reportError(err error) error {
+	switch err {
+	case app.ErrUnauthenticated:
+		return status.Error(codes.Unauthenticated, err.Error())
+	case app.ErrAccessDenied:
+		return status.Error(codes.PermissionDenied, err.Error())
+	case app.ErrNotFound:
+		return status.Error(codes.NotFound, err.Error())
+	case app.ErrAlreadyReported:
+		return status.Error(codes.AlreadyExists, err.Error())
+	case app.ErrReportClosed:
+		return status.Error(codes.FailedPrecondition, err.Error())
+	case app.ErrInvalidReport, app.ErrSelfReport, app.ErrInvalidNote:
+		return status.Error(codes.InvalidArgument, err.Error())
+	case app.ErrReportsDisabled:
+		return status.Error(codes.Unimplemented, err.Error())
+	default:
+		return pageError(err)
+	}
+}
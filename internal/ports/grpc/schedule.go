@@ -0,0 +1,53 @@
+package grpc
+
+import (
+	"context"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"homework9/internal/app"
+)
+
+func (as AdService) ScheduleAd(ctx context.Context, in *ScheduleAdRequest) (*AdResponse, error) {
+	a, err := as.app.ScheduleAd(ctx, in.AdId, fromTimestamp(in.PublishAt), fromTimestamp(in.ExpiresAt))
+	if err != nil {
+		return nil, scheduleError(err)
+	}
+	return newAdResponse(a, as.favoriteCount(ctx, a.ID), priceDisplay{}), nil
+}
+
+func (as AdService) RenewAd(ctx context.Context, in *RenewAdRequest) (*AdResponse, error) {
+	a, err := as.app.RenewAd(ctx, in.AdId)
+	if err != nil {
+		return nil, scheduleError(err)
+	}
+	return newAdResponse(a, as.favoriteCount(ctx, a.ID), priceDisplay{}), nil
+}
+
+// newTimestamp возвращает nil для незаданного срока
+func newTimestamp(t time.Time) *timestamppb.Timestamp {
+	if t.IsZero() {
+		return nil
+	}
+	return timestamppb.New(t)
+}
+
+func scheduleError(err error) error {
+	switch err {
+	case app.ErrUnauthenticated:
+		return status.Error(codes.Unauthenticated, err.Error())
+	case app.ErrAccessDenied:
+		return status.Error(codes.PermissionDenied, err.Error())
+	case app.ErrNotFound:
+		return status.Error(codes.NotFound, err.Error())
+	case app.ErrVersionConflict:
+		return status.Error(codes.Aborted, err.Error())
+	case app.ErrInvalidSchedule:
+		return status.Error(codes.InvalidArgument, err.Error())
+	default:
+		return status.Error(codes.Internal, err.Error())
+	}
+}
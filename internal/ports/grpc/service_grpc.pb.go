@@ -24,10 +24,42 @@ const (
 	AdService_ChangeAdStatus_FullMethodName = "/ad.AdService/ChangeAdStatus"
 	AdService_UpdateAd_FullMethodName       = "/ad.AdService/UpdateAd"
 	AdService_ListAds_FullMethodName        = "/ad.AdService/ListAds"
+	AdService_FindAds_FullMethodName        = "/ad.AdService/FindAds"
+	AdService_SearchAds_FullMethodName      = "/ad.AdService/SearchAds"
 	AdService_CreateUser_FullMethodName     = "/ad.AdService/CreateUser"
 	AdService_GetUser_FullMethodName        = "/ad.AdService/GetUser"
 	AdService_DeleteUser_FullMethodName     = "/ad.AdService/DeleteUser"
 	AdService_DeleteAd_FullMethodName       = "/ad.AdService/DeleteAd"
+	AdService_Login_FullMethodName          = "/ad.AdService/Login"
+	AdService_Refresh_FullMethodName        = "/ad.AdService/Refresh"
+	AdService_Logout_FullMethodName         = "/ad.AdService/Logout"
+	AdService_SetUserRole_FullMethodName    = "/ad.AdService/SetUserRole"
+	AdService_ListCategories_FullMethodName = "/ad.AdService/ListCategories"
+	AdService_GetCategory_FullMethodName    = "/ad.AdService/GetCategory"
+	AdService_CreateCategory_FullMethodName = "/ad.AdService/CreateCategory"
+	AdService_UpdateCategory_FullMethodName = "/ad.AdService/UpdateCategory"
+	AdService_DeleteCategory_FullMethodName = "/ad.AdService/DeleteCategory"
+	AdService_UploadAdImage_FullMethodName  = "/ad.AdService/UploadAdImage"
+	AdService_ScheduleAd_FullMethodName     = "/ad.AdService/ScheduleAd"
+	AdService_RenewAd_FullMethodName        = "/ad.AdService/RenewAd"
+	AdService_ReviewQueue_FullMethodName    = "/ad.AdService/ReviewQueue"
+	AdService_ApproveAd_FullMethodName      = "/ad.AdService/ApproveAd"
+	AdService_RejectAd_FullMethodName       = "/ad.AdService/RejectAd"
+	AdService_CreateReport_FullMethodName   = "/ad.AdService/CreateReport"
+	AdService_ListReports_FullMethodName    = "/ad.AdService/ListReports"
+	AdService_ResolveReport_FullMethodName  = "/ad.AdService/ResolveReport"
+	AdService_DismissReport_FullMethodName  = "/ad.AdService/DismissReport"
+	AdService_AddFavorite_FullMethodName    = "/ad.AdService/AddFavorite"
+	AdService_RemoveFavorite_FullMethodName = "/ad.AdService/RemoveFavorite"
+	AdService_ListFavorites_FullMethodName  = "/ad.AdService/ListFavorites"
+	AdService_ContactSeller_FullMethodName  = "/ad.AdService/ContactSeller"
+	AdService_SendMessage_FullMethodName    = "/ad.AdService/SendMessage"
+	AdService_ListThreads_FullMethodName    = "/ad.AdService/ListThreads"
+	AdService_ListMessages_FullMethodName   = "/ad.AdService/ListMessages"
+	AdService_MarkThreadRead_FullMethodName = "/ad.AdService/MarkThreadRead"
+	AdService_BlockUser_FullMethodName      = "/ad.AdService/BlockUser"
+	AdService_UnblockUser_FullMethodName    = "/ad.AdService/UnblockUser"
+	AdService_WatchAds_FullMethodName       = "/ad.AdService/WatchAds"
 )
 
 // AdServiceClient is the client API for AdService service.
@@ -37,11 +69,69 @@ type AdServiceClient interface {
 	CreateAd(ctx context.Context, in *CreateAdRequest, opts ...grpc.CallOption) (*AdResponse, error)
 	ChangeAdStatus(ctx context.Context, in *ChangeAdStatusRequest, opts ...grpc.CallOption) (*AdResponse, error)
 	UpdateAd(ctx context.Context, in *UpdateAdRequest, opts ...grpc.CallOption) (*AdResponse, error)
-	ListAds(ctx context.Context, in *emptypb.Empty, opts ...grpc.CallOption) (*ListAdResponse, error)
+	// ListAds и FindAds возвращают выдачу постранично, следующую страницу запрашивают с next_cursor
+	ListAds(ctx context.Context, in *ListAdsRequest, opts ...grpc.CallOption) (*ListAdResponse, error)
+	// FindAds возвращает объявления, подходящие под все заданные условия. Неопубликованные объявления
+	// в выдачу попадают только для автора и модераторов
+	FindAds(ctx context.Context, in *FindAdsRequest, opts ...grpc.CallOption) (*ListAdResponse, error)
+	// SearchAds ищет опубликованные объявления по словам из заголовка и текста, самые релевантные первыми
+	SearchAds(ctx context.Context, in *SearchAdsRequest, opts ...grpc.CallOption) (*SearchAdsResponse, error)
 	CreateUser(ctx context.Context, in *CreateUserRequest, opts ...grpc.CallOption) (*UserResponse, error)
 	GetUser(ctx context.Context, in *GetUserRequest, opts ...grpc.CallOption) (*UserResponse, error)
 	DeleteUser(ctx context.Context, in *DeleteUserRequest, opts ...grpc.CallOption) (*emptypb.Empty, error)
 	DeleteAd(ctx context.Context, in *DeleteAdRequest, opts ...grpc.CallOption) (*emptypb.Empty, error)
+	Login(ctx context.Context, in *LoginRequest, opts ...grpc.CallOption) (*LoginResponse, error)
+	Refresh(ctx context.Context, in *RefreshRequest, opts ...grpc.CallOption) (*LoginResponse, error)
+	Logout(ctx context.Context, in *RefreshRequest, opts ...grpc.CallOption) (*emptypb.Empty, error)
+	// SetUserRole доступен администраторам
+	SetUserRole(ctx context.Context, in *SetUserRoleRequest, opts ...grpc.CallOption) (*UserResponse, error)
+	ListCategories(ctx context.Context, in *emptypb.Empty, opts ...grpc.CallOption) (*ListCategoriesResponse, error)
+	GetCategory(ctx context.Context, in *GetCategoryRequest, opts ...grpc.CallOption) (*CategoryResponse, error)
+	// CreateCategory, UpdateCategory и DeleteCategory доступны администраторам
+	CreateCategory(ctx context.Context, in *CreateCategoryRequest, opts ...grpc.CallOption) (*CategoryResponse, error)
+	UpdateCategory(ctx context.Context, in *UpdateCategoryRequest, opts ...grpc.CallOption) (*CategoryResponse, error)
+	DeleteCategory(ctx context.Context, in *DeleteCategoryRequest, opts ...grpc.CallOption) (*emptypb.Empty, error)
+	// UploadAdImage добавляет фотографию в конец списка объявления. Первое сообщение потока задаёт ad_id,
+	// файл передаётся кусками в chunk этого и следующих сообщений. Доступно автору объявления
+	UploadAdImage(ctx context.Context, opts ...grpc.CallOption) (AdService_UploadAdImageClient, error)
+	// ScheduleAd назначает публикацию и снятие объявления, отсутствующий срок отменяется
+	ScheduleAd(ctx context.Context, in *ScheduleAdRequest, opts ...grpc.CallOption) (*AdResponse, error)
+	// RenewAd продлевает объявление, истёкшее объявление снова публикуется
+	RenewAd(ctx context.Context, in *RenewAdRequest, opts ...grpc.CallOption) (*AdResponse, error)
+	// ReviewQueue, ApproveAd и RejectAd доступны модераторам и администраторам. Очередь - объявления на проверке
+	ReviewQueue(ctx context.Context, in *ReviewQueueRequest, opts ...grpc.CallOption) (*ListAdResponse, error)
+	ApproveAd(ctx context.Context, in *ReviewAdRequest, opts ...grpc.CallOption) (*AdResponse, error)
+	// RejectAd требует причину, автор получает уведомление
+	RejectAd(ctx context.Context, in *ReviewAdRequest, opts ...grpc.CallOption) (*AdResponse, error)
+	// CreateReport - жалоба на объявление или пользователя, на одну цель пользователь жалуется один раз.
+	// Опубликованное объявление, на которое набралось достаточно открытых жалоб, снимается с публикации
+	CreateReport(ctx context.Context, in *CreateReportRequest, opts ...grpc.CallOption) (*ReportResponse, error)
+	// ListReports, ResolveReport и DismissReport доступны модераторам и администраторам
+	ListReports(ctx context.Context, in *ListReportsRequest, opts ...grpc.CallOption) (*ListReportsResponse, error)
+	ResolveReport(ctx context.Context, in *CloseReportRequest, opts ...grpc.CallOption) (*ReportResponse, error)
+	DismissReport(ctx context.Context, in *CloseReportRequest, opts ...grpc.CallOption) (*ReportResponse, error)
+	// AddFavorite добавляет опубликованное объявление в избранное, повторный вызов ничего не меняет
+	AddFavorite(ctx context.Context, in *FavoriteRequest, opts ...grpc.CallOption) (*FavoriteResponse, error)
+	RemoveFavorite(ctx context.Context, in *FavoriteRequest, opts ...grpc.CallOption) (*emptypb.Empty, error)
+	// ListFavorites - избранное текущего пользователя от новых к старым
+	ListFavorites(ctx context.Context, in *ListFavoritesRequest, opts ...grpc.CallOption) (*ListFavoritesResponse, error)
+	// ContactSeller пишет автору объявления: первое сообщение начинает переписку (только по опубликованному
+	// объявлению), следующие продолжают её. SendMessage отвечает в переписке. Заблокированным писать нельзя
+	ContactSeller(ctx context.Context, in *ContactSellerRequest, opts ...grpc.CallOption) (*MessageResponse, error)
+	SendMessage(ctx context.Context, in *SendMessageRequest, opts ...grpc.CallOption) (*MessageResponse, error)
+	// ListThreads - входящие текущего пользователя, сначала переписки с более новым сообщением
+	ListThreads(ctx context.Context, in *ListThreadsRequest, opts ...grpc.CallOption) (*ListThreadsResponse, error)
+	// ListMessages возвращает сообщения от старых к новым и не отмечает их прочитанными
+	ListMessages(ctx context.Context, in *ListMessagesRequest, opts ...grpc.CallOption) (*ListMessagesResponse, error)
+	MarkThreadRead(ctx context.Context, in *ThreadRequest, opts ...grpc.CallOption) (*ThreadResponse, error)
+	BlockUser(ctx context.Context, in *BlockUserRequest, opts ...grpc.CallOption) (*emptypb.Empty, error)
+	UnblockUser(ctx context.Context, in *BlockUserRequest, opts ...grpc.CallOption) (*emptypb.Empty, error)
+	// WatchAds - поток событий об объявлениях, подходящих под фильтр до или после изменения. Каждое событие
+	// несёт resume_token: переподключившийся клиент передаёт последний полученный и продолжает без пропусков.
+	// Если события после токена уже не хранятся (или сервер перезапущен) - FAILED_PRECONDITION, тогда объявления
+	// загружают заново через FindAds и наблюдают без токена. Медленный клиент отключается с RESOURCE_EXHAUSTED,
+	// при остановке сервера поток завершается с UNAVAILABLE - в обоих случаях можно переподключиться с токеном
+	WatchAds(ctx context.Context, in *WatchAdsRequest, opts ...grpc.CallOption) (AdService_WatchAdsClient, error)
 }
 
 type adServiceClient struct {
@@ -79,7 +169,7 @@ func (c *adServiceClient) UpdateAd(ctx context.Context, in *UpdateAdRequest, opt
 	return out, nil
 }
 
-func (c *adServiceClient) ListAds(ctx context.Context, in *emptypb.Empty, opts ...grpc.CallOption) (*ListAdResponse, error) {
+func (c *adServiceClient) ListAds(ctx context.Context, in *ListAdsRequest, opts ...grpc.CallOption) (*ListAdResponse, error) {
 	out := new(ListAdResponse)
 	err := c.cc.Invoke(ctx, AdService_ListAds_FullMethodName, in, out, opts...)
 	if err != nil {
@@ -88,240 +178,1317 @@ func (c *adServiceClient) ListAds(ctx context.Context, in *emptypb.Empty, opts .
 	return out, nil
 }
 
+func (c *adServiceClient) FindAds(ctx context.Context, in *FindAdsRequest, opts ...grpc.CallOption) (*ListAdResponse, error) {
+	out := new(ListAdResponse)
+	err := c.cc.Invoke(ctx, AdService_FindAds_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *adServiceClient) SearchAds(ctx context.Context, in *SearchAdsRequest, opts ...grpc.CallOption) (*SearchAdsResponse, error) {
+	out := new(SearchAdsResponse)
+	err := c.cc.Invoke(ctx, AdService_SearchAds_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 func (c *adServiceClient) CreateUser(ctx context.Context, in *CreateUserRequest, opts ...grpc.CallOption) (*UserResponse, error) {
 	out := new(UserResponse)
 	err := c.cc.Invoke(ctx, AdService_CreateUser_FullMethodName, in, out, opts...)
 	if err != nil {
 		return nil, err
 	}
-	return out, nil
+	return out, nil
+}
+
+func (c *adServiceClient) GetUser(ctx context.Context, in *GetUserRequest, opts ...grpc.CallOption) (*UserResponse, error) {
+	out := new(UserResponse)
+	err := c.cc.Invoke(ctx, AdService_GetUser_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *adServiceClient) DeleteUser(ctx context.Context, in *DeleteUserRequest, opts ...grpc.CallOption) (*emptypb.Empty, error) {
+	out := new(emptypb.Empty)
+	err := c.cc.Invoke(ctx, AdService_DeleteUser_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *adServiceClient) DeleteAd(ctx context.Context, in *DeleteAdRequest, opts ...grpc.CallOption) (*emptypb.Empty, error) {
+	out := new(emptypb.Empty)
+	err := c.cc.Invoke(ctx, AdService_DeleteAd_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *adServiceClient) Login(ctx context.Context, in *LoginRequest, opts ...grpc.CallOption) (*LoginResponse, error) {
+	out := new(LoginResponse)
+	err := c.cc.Invoke(ctx, AdService_Login_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *adServiceClient) Refresh(ctx context.Context, in *RefreshRequest, opts ...grpc.CallOption) (*LoginResponse, error) {
+	out := new(LoginResponse)
+	err := c.cc.Invoke(ctx, AdService_Refresh_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *adServiceClient) Logout(ctx context.Context, in *RefreshRequest, opts ...grpc.CallOption) (*emptypb.Empty, error) {
+	out := new(emptypb.Empty)
+	err := c.cc.Invoke(ctx, AdService_Logout_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *adServiceClient) SetUserRole(ctx context.Context, in *SetUserRoleRequest, opts ...grpc.CallOption) (*UserResponse, error) {
+	out := new(UserResponse)
+	err := c.cc.Invoke(ctx, AdService_SetUserRole_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *adServiceClient) ListCategories(ctx context.Context, in *emptypb.Empty, opts ...grpc.CallOption) (*ListCategoriesResponse, error) {
+	out := new(ListCategoriesResponse)
+	err := c.cc.Invoke(ctx, AdService_ListCategories_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *adServiceClient) GetCategory(ctx context.Context, in *GetCategoryRequest, opts ...grpc.CallOption) (*CategoryResponse, error) {
+	out := new(CategoryResponse)
+	err := c.cc.Invoke(ctx, AdService_GetCategory_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *adServiceClient) CreateCategory(ctx context.Context, in *CreateCategoryRequest, opts ...grpc.CallOption) (*CategoryResponse, error) {
+	out := new(CategoryResponse)
+	err := c.cc.Invoke(ctx, AdService_CreateCategory_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *adServiceClient) UpdateCategory(ctx context.Context, in *UpdateCategoryRequest, opts ...grpc.CallOption) (*CategoryResponse, error) {
+	out := new(CategoryResponse)
+	err := c.cc.Invoke(ctx, AdService_UpdateCategory_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *adServiceClient) DeleteCategory(ctx context.Context, in *DeleteCategoryRequest, opts ...grpc.CallOption) (*emptypb.Empty, error) {
+	out := new(emptypb.Empty)
+	err := c.cc.Invoke(ctx, AdService_DeleteCategory_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *adServiceClient) UploadAdImage(ctx context.Context, opts ...grpc.CallOption) (AdService_UploadAdImageClient, error) {
+	stream, err := c.cc.NewStream(ctx, &AdService_ServiceDesc.Streams[0], AdService_UploadAdImage_FullMethodName, opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &adServiceUploadAdImageClient{stream}
+	return x, nil
+}
+
+type AdService_UploadAdImageClient interface {
+	Send(*UploadAdImageRequest) error
+	CloseAndRecv() (*AdResponse, error)
+	grpc.ClientStream
+}
+
+type adServiceUploadAdImageClient struct {
+	grpc.ClientStream
+}
+
+func (x *adServiceUploadAdImageClient) Send(m *UploadAdImageRequest) error {
+	return x.ClientStream.SendMsg(m)
+}
+
+func (x *adServiceUploadAdImageClient) CloseAndRecv() (*AdResponse, error) {
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	m := new(AdResponse)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *adServiceClient) ScheduleAd(ctx context.Context, in *ScheduleAdRequest, opts ...grpc.CallOption) (*AdResponse, error) {
+	out := new(AdResponse)
+	err := c.cc.Invoke(ctx, AdService_ScheduleAd_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *adServiceClient) RenewAd(ctx context.Context, in *RenewAdRequest, opts ...grpc.CallOption) (*AdResponse, error) {
+	out := new(AdResponse)
+	err := c.cc.Invoke(ctx, AdService_RenewAd_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *adServiceClient) ReviewQueue(ctx context.Context, in *ReviewQueueRequest, opts ...grpc.CallOption) (*ListAdResponse, error) {
+	out := new(ListAdResponse)
+	err := c.cc.Invoke(ctx, AdService_ReviewQueue_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *adServiceClient) ApproveAd(ctx context.Context, in *ReviewAdRequest, opts ...grpc.CallOption) (*AdResponse, error) {
+	out := new(AdResponse)
+	err := c.cc.Invoke(ctx, AdService_ApproveAd_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *adServiceClient) RejectAd(ctx context.Context, in *ReviewAdRequest, opts ...grpc.CallOption) (*AdResponse, error) {
+	out := new(AdResponse)
+	err := c.cc.Invoke(ctx, AdService_RejectAd_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *adServiceClient) CreateReport(ctx context.Context, in *CreateReportRequest, opts ...grpc.CallOption) (*ReportResponse, error) {
+	out := new(ReportResponse)
+	err := c.cc.Invoke(ctx, AdService_CreateReport_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *adServiceClient) ListReports(ctx context.Context, in *ListReportsRequest, opts ...grpc.CallOption) (*ListReportsResponse, error) {
+	out := new(ListReportsResponse)
+	err := c.cc.Invoke(ctx, AdService_ListReports_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *adServiceClient) ResolveReport(ctx context.Context, in *CloseReportRequest, opts ...grpc.CallOption) (*ReportResponse, error) {
+	out := new(ReportResponse)
+	err := c.cc.Invoke(ctx, AdService_ResolveReport_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *adServiceClient) DismissReport(ctx context.Context, in *CloseReportRequest, opts ...grpc.CallOption) (*ReportResponse, error) {
+	out := new(ReportResponse)
+	err := c.cc.Invoke(ctx, AdService_DismissReport_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *adServiceClient) AddFavorite(ctx context.Context, in *FavoriteRequest, opts ...grpc.CallOption) (*FavoriteResponse, error) {
+	out := new(FavoriteResponse)
+	err := c.cc.Invoke(ctx, AdService_AddFavorite_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *adServiceClient) RemoveFavorite(ctx context.Context, in *FavoriteRequest, opts ...grpc.CallOption) (*emptypb.Empty, error) {
+	out := new(emptypb.Empty)
+	err := c.cc.Invoke(ctx, AdService_RemoveFavorite_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *adServiceClient) ListFavorites(ctx context.Context, in *ListFavoritesRequest, opts ...grpc.CallOption) (*ListFavoritesResponse, error) {
+	out := new(ListFavoritesResponse)
+	err := c.cc.Invoke(ctx, AdService_ListFavorites_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *adServiceClient) ContactSeller(ctx context.Context, in *ContactSellerRequest, opts ...grpc.CallOption) (*MessageResponse, error) {
+	out := new(MessageResponse)
+	err := c.cc.Invoke(ctx, AdService_ContactSeller_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *adServiceClient) SendMessage(ctx context.Context, in *SendMessageRequest, opts ...grpc.CallOption) (*MessageResponse, error) {
+	out := new(MessageResponse)
+	err := c.cc.Invoke(ctx, AdService_SendMessage_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *adServiceClient) ListThreads(ctx context.Context, in *ListThreadsRequest, opts ...grpc.CallOption) (*ListThreadsResponse, error) {
+	out := new(ListThreadsResponse)
+	err := c.cc.Invoke(ctx, AdService_ListThreads_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *adServiceClient) ListMessages(ctx context.Context, in *ListMessagesRequest, opts ...grpc.CallOption) (*ListMessagesResponse, error) {
+	out := new(ListMessagesResponse)
+	err := c.cc.Invoke(ctx, AdService_ListMessages_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *adServiceClient) MarkThreadRead(ctx context.Context, in *ThreadRequest, opts ...grpc.CallOption) (*ThreadResponse, error) {
+	out := new(ThreadResponse)
+	err := c.cc.Invoke(ctx, AdService_MarkThreadRead_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *adServiceClient) BlockUser(ctx context.Context, in *BlockUserRequest, opts ...grpc.CallOption) (*emptypb.Empty, error) {
+	out := new(emptypb.Empty)
+	err := c.cc.Invoke(ctx, AdService_BlockUser_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *adServiceClient) UnblockUser(ctx context.Context, in *BlockUserRequest, opts ...grpc.CallOption) (*emptypb.Empty, error) {
+	out := new(emptypb.Empty)
+	err := c.cc.Invoke(ctx, AdService_UnblockUser_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *adServiceClient) WatchAds(ctx context.Context, in *WatchAdsRequest, opts ...grpc.CallOption) (AdService_WatchAdsClient, error) {
+	stream, err := c.cc.NewStream(ctx, &AdService_ServiceDesc.Streams[1], AdService_WatchAds_FullMethodName, opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &adServiceWatchAdsClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type AdService_WatchAdsClient interface {
+	Recv() (*AdEvent, error)
+	grpc.ClientStream
+}
+
+type adServiceWatchAdsClient struct {
+	grpc.ClientStream
+}
+
+func (x *adServiceWatchAdsClient) Recv() (*AdEvent, error) {
+	m := new(AdEvent)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// AdServiceServer is the server API for AdService service.
+// All implementations should embed UnimplementedAdServiceServer
+// for forward compatibility
+type AdServiceServer interface {
+	CreateAd(context.Context, *CreateAdRequest) (*AdResponse, error)
+	ChangeAdStatus(context.Context, *ChangeAdStatusRequest) (*AdResponse, error)
+	UpdateAd(context.Context, *UpdateAdRequest) (*AdResponse, error)
+	// ListAds и FindAds возвращают выдачу постранично, следующую страницу запрашивают с next_cursor
+	ListAds(context.Context, *ListAdsRequest) (*ListAdResponse, error)
+	// FindAds возвращает объявления, подходящие под все заданные условия. Неопубликованные объявления
+	// в выдачу попадают только для автора и модераторов
+	FindAds(context.Context, *FindAdsRequest) (*ListAdResponse, error)
+	// SearchAds ищет опубликованные объявления по словам из заголовка и текста, самые релевантные первыми
+	SearchAds(context.Context, *SearchAdsRequest) (*SearchAdsResponse, error)
+	CreateUser(context.Context, *CreateUserRequest) (*UserResponse, error)
+	GetUser(context.Context, *GetUserRequest) (*UserResponse, error)
+	DeleteUser(context.Context, *DeleteUserRequest) (*emptypb.Empty, error)
+	DeleteAd(context.Context, *DeleteAdRequest) (*emptypb.Empty, error)
+	Login(context.Context, *LoginRequest) (*LoginResponse, error)
+	Refresh(context.Context, *RefreshRequest) (*LoginResponse, error)
+	Logout(context.Context, *RefreshRequest) (*emptypb.Empty, error)
+	// SetUserRole доступен администраторам
+	SetUserRole(context.Context, *SetUserRoleRequest) (*UserResponse, error)
+	ListCategories(context.Context, *emptypb.Empty) (*ListCategoriesResponse, error)
+	GetCategory(context.Context, *GetCategoryRequest) (*CategoryResponse, error)
+	// CreateCategory, UpdateCategory и DeleteCategory доступны администраторам
+	CreateCategory(context.Context, *CreateCategoryRequest) (*CategoryResponse, error)
+	UpdateCategory(context.Context, *UpdateCategoryRequest) (*CategoryResponse, error)
+	DeleteCategory(context.Context, *DeleteCategoryRequest) (*emptypb.Empty, error)
+	// UploadAdImage добавляет фотографию в конец списка объявления. Первое сообщение потока задаёт ad_id,
+	// файл передаётся кусками в chunk этого и следующих сообщений. Доступно автору объявления
+	UploadAdImage(AdService_UploadAdImageServer) error
+	// ScheduleAd назначает публикацию и снятие объявления, отсутствующий срок отменяется
+	ScheduleAd(context.Context, *ScheduleAdRequest) (*AdResponse, error)
+	// RenewAd продлевает объявление, истёкшее объявление снова публикуется
+	RenewAd(context.Context, *RenewAdRequest) (*AdResponse, error)
+	// ReviewQueue, ApproveAd и RejectAd доступны модераторам и администраторам. Очередь - объявления на проверке
+	ReviewQueue(context.Context, *ReviewQueueRequest) (*ListAdResponse, error)
+	ApproveAd(context.Context, *ReviewAdRequest) (*AdResponse, error)
+	// RejectAd требует причину, автор получает уведомление
+	RejectAd(context.Context, *ReviewAdRequest) (*AdResponse, error)
+	// CreateReport - жалоба на объявление или пользователя, на одну цель пользователь жалуется один раз.
+	// Опубликованное объявление, на которое набралось достаточно открытых жалоб, снимается с публикации
+	CreateReport(context.Context, *CreateReportRequest) (*ReportResponse, error)
+	// ListReports, ResolveReport и DismissReport доступны модераторам и администраторам
+	ListReports(context.Context, *ListReportsRequest) (*ListReportsResponse, error)
+	ResolveReport(context.Context, *CloseReportRequest) (*ReportResponse, error)
+	DismissReport(context.Context, *CloseReportRequest) (*ReportResponse, error)
+	// AddFavorite добавляет опубликованное объявление в избранное, повторный вызов ничего не меняет
+	AddFavorite(context.Context, *FavoriteRequest) (*FavoriteResponse, error)
+	RemoveFavorite(context.Context, *FavoriteRequest) (*emptypb.Empty, error)
+	// ListFavorites - избранное текущего пользователя от новых к старым
+	ListFavorites(context.Context, *ListFavoritesRequest) (*ListFavoritesResponse, error)
+	// ContactSeller пишет автору объявления: первое сообщение начинает переписку (только по опубликованному
+	// объявлению), следующие продолжают её. SendMessage отвечает в переписке. Заблокированным писать нельзя
+	ContactSeller(context.Context, *ContactSellerRequest) (*MessageResponse, error)
+	SendMessage(context.Context, *SendMessageRequest) (*MessageResponse, error)
+	// ListThreads - входящие текущего пользователя, сначала переписки с более новым сообщением
+	ListThreads(context.Context, *ListThreadsRequest) (*ListThreadsResponse, error)
+	// ListMessages возвращает сообщения от старых к новым и не отмечает их прочитанными
+	ListMessages(context.Context, *ListMessagesRequest) (*ListMessagesResponse, error)
+	MarkThreadRead(context.Context, *ThreadRequest) (*ThreadResponse, error)
+	BlockUser(context.Context, *BlockUserRequest) (*emptypb.Empty, error)
+	UnblockUser(context.Context, *BlockUserRequest) (*emptypb.Empty, error)
+	// WatchAds - поток событий об объявлениях, подходящих под фильтр до или после изменения. Каждое событие
+	// несёт resume_token: переподключившийся клиент передаёт последний полученный и продолжает без пропусков.
+	// Если события после токена уже не хранятся (или сервер перезапущен) - FAILED_PRECONDITION, тогда объявления
+	// загружают заново через FindAds и наблюдают без токена. Медленный клиент отключается с RESOURCE_EXHAUSTED,
+	// при остановке сервера поток завершается с UNAVAILABLE - в обоих случаях можно переподключиться с токеном
+	WatchAds(*WatchAdsRequest, AdService_WatchAdsServer) error
+}
+
+// UnimplementedAdServiceServer should be embedded to have forward compatible implementations.
+type UnimplementedAdServiceServer struct {
+}
+
+func (UnimplementedAdServiceServer) CreateAd(context.Context, *CreateAdRequest) (*AdResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CreateAd not implemented")
+}
+func (UnimplementedAdServiceServer) ChangeAdStatus(context.Context, *ChangeAdStatusRequest) (*AdResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ChangeAdStatus not implemented")
+}
+func (UnimplementedAdServiceServer) UpdateAd(context.Context, *UpdateAdRequest) (*AdResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method UpdateAd not implemented")
+}
+func (UnimplementedAdServiceServer) ListAds(context.Context, *ListAdsRequest) (*ListAdResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListAds not implemented")
+}
+func (UnimplementedAdServiceServer) FindAds(context.Context, *FindAdsRequest) (*ListAdResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method FindAds not implemented")
+}
+func (UnimplementedAdServiceServer) SearchAds(context.Context, *SearchAdsRequest) (*SearchAdsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SearchAds not implemented")
+}
+func (UnimplementedAdServiceServer) CreateUser(context.Context, *CreateUserRequest) (*UserResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CreateUser not implemented")
+}
+func (UnimplementedAdServiceServer) GetUser(context.Context, *GetUserRequest) (*UserResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetUser not implemented")
+}
+func (UnimplementedAdServiceServer) DeleteUser(context.Context, *DeleteUserRequest) (*emptypb.Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DeleteUser not implemented")
+}
+func (UnimplementedAdServiceServer) DeleteAd(context.Context, *DeleteAdRequest) (*emptypb.Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DeleteAd not implemented")
+}
+func (UnimplementedAdServiceServer) Login(context.Context, *LoginRequest) (*LoginResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Login not implemented")
+}
+func (UnimplementedAdServiceServer) Refresh(context.Context, *RefreshRequest) (*LoginResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Refresh not implemented")
+}
+func (UnimplementedAdServiceServer) Logout(context.Context, *RefreshRequest) (*emptypb.Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Logout not implemented")
+}
+func (UnimplementedAdServiceServer) SetUserRole(context.Context, *SetUserRoleRequest) (*UserResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SetUserRole not implemented")
+}
+func (UnimplementedAdServiceServer) ListCategories(context.Context, *emptypb.Empty) (*ListCategoriesResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListCategories not implemented")
+}
+func (UnimplementedAdServiceServer) GetCategory(context.Context, *GetCategoryRequest) (*CategoryResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetCategory not implemented")
+}
+func (UnimplementedAdServiceServer) CreateCategory(context.Context, *CreateCategoryRequest) (*CategoryResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CreateCategory not implemented")
+}
+func (UnimplementedAdServiceServer) UpdateCategory(context.Context, *UpdateCategoryRequest) (*CategoryResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method UpdateCategory not implemented")
+}
+func (UnimplementedAdServiceServer) DeleteCategory(context.Context, *DeleteCategoryRequest) (*emptypb.Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DeleteCategory not implemented")
+}
+func (UnimplementedAdServiceServer) UploadAdImage(AdService_UploadAdImageServer) error {
+	return status.Errorf(codes.Unimplemented, "method UploadAdImage not implemented")
+}
+func (UnimplementedAdServiceServer) ScheduleAd(context.Context, *ScheduleAdRequest) (*AdResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ScheduleAd not implemented")
+}
+func (UnimplementedAdServiceServer) RenewAd(context.Context, *RenewAdRequest) (*AdResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RenewAd not implemented")
+}
+func (UnimplementedAdServiceServer) ReviewQueue(context.Context, *ReviewQueueRequest) (*ListAdResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ReviewQueue not implemented")
+}
+func (UnimplementedAdServiceServer) ApproveAd(context.Context, *ReviewAdRequest) (*AdResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ApproveAd not implemented")
+}
+func (UnimplementedAdServiceServer) RejectAd(context.Context, *ReviewAdRequest) (*AdResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RejectAd not implemented")
+}
+func (UnimplementedAdServiceServer) CreateReport(context.Context, *CreateReportRequest) (*ReportResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CreateReport not implemented")
+}
+func (UnimplementedAdServiceServer) ListReports(context.Context, *ListReportsRequest) (*ListReportsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListReports not implemented")
+}
+func (UnimplementedAdServiceServer) ResolveReport(context.Context, *CloseReportRequest) (*ReportResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ResolveReport not implemented")
+}
+func (UnimplementedAdServiceServer) DismissReport(context.Context, *CloseReportRequest) (*ReportResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DismissReport not implemented")
+}
+func (UnimplementedAdServiceServer) AddFavorite(context.Context, *FavoriteRequest) (*FavoriteResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method AddFavorite not implemented")
+}
+func (UnimplementedAdServiceServer) RemoveFavorite(context.Context, *FavoriteRequest) (*emptypb.Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RemoveFavorite not implemented")
+}
+func (UnimplementedAdServiceServer) ListFavorites(context.Context, *ListFavoritesRequest) (*ListFavoritesResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListFavorites not implemented")
+}
+func (UnimplementedAdServiceServer) ContactSeller(context.Context, *ContactSellerRequest) (*MessageResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ContactSeller not implemented")
+}
+func (UnimplementedAdServiceServer) SendMessage(context.Context, *SendMessageRequest) (*MessageResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SendMessage not implemented")
+}
+func (UnimplementedAdServiceServer) ListThreads(context.Context, *ListThreadsRequest) (*ListThreadsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListThreads not implemented")
+}
+func (UnimplementedAdServiceServer) ListMessages(context.Context, *ListMessagesRequest) (*ListMessagesResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListMessages not implemented")
+}
+func (UnimplementedAdServiceServer) MarkThreadRead(context.Context, *ThreadRequest) (*ThreadResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method MarkThreadRead not implemented")
+}
+func (UnimplementedAdServiceServer) BlockUser(context.Context, *BlockUserRequest) (*emptypb.Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method BlockUser not implemented")
+}
+func (UnimplementedAdServiceServer) UnblockUser(context.Context, *BlockUserRequest) (*emptypb.Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method UnblockUser not implemented")
+}
+func (UnimplementedAdServiceServer) WatchAds(*WatchAdsRequest, AdService_WatchAdsServer) error {
+	return status.Errorf(codes.Unimplemented, "method WatchAds not implemented")
+}
+
+// UnsafeAdServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to AdServiceServer will
+// result in compilation errors.
+type UnsafeAdServiceServer interface {
+	mustEmbedUnimplementedAdServiceServer()
+}
+
+func RegisterAdServiceServer(s grpc.ServiceRegistrar, srv AdServiceServer) {
+	s.RegisterService(&AdService_ServiceDesc, srv)
+}
+
+func _AdService_CreateAd_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CreateAdRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AdServiceServer).CreateAd(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: AdService_CreateAd_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AdServiceServer).CreateAd(ctx, req.(*CreateAdRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AdService_ChangeAdStatus_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ChangeAdStatusRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AdServiceServer).ChangeAdStatus(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: AdService_ChangeAdStatus_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AdServiceServer).ChangeAdStatus(ctx, req.(*ChangeAdStatusRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AdService_UpdateAd_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UpdateAdRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AdServiceServer).UpdateAd(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: AdService_UpdateAd_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AdServiceServer).UpdateAd(ctx, req.(*UpdateAdRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AdService_ListAds_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListAdsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AdServiceServer).ListAds(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: AdService_ListAds_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AdServiceServer).ListAds(ctx, req.(*ListAdsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AdService_FindAds_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(FindAdsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AdServiceServer).FindAds(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: AdService_FindAds_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AdServiceServer).FindAds(ctx, req.(*FindAdsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AdService_SearchAds_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SearchAdsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AdServiceServer).SearchAds(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: AdService_SearchAds_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AdServiceServer).SearchAds(ctx, req.(*SearchAdsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AdService_CreateUser_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CreateUserRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AdServiceServer).CreateUser(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: AdService_CreateUser_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AdServiceServer).CreateUser(ctx, req.(*CreateUserRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AdService_GetUser_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetUserRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AdServiceServer).GetUser(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: AdService_GetUser_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AdServiceServer).GetUser(ctx, req.(*GetUserRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AdService_DeleteUser_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DeleteUserRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AdServiceServer).DeleteUser(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: AdService_DeleteUser_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AdServiceServer).DeleteUser(ctx, req.(*DeleteUserRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AdService_DeleteAd_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DeleteAdRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AdServiceServer).DeleteAd(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: AdService_DeleteAd_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AdServiceServer).DeleteAd(ctx, req.(*DeleteAdRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AdService_Login_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(LoginRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AdServiceServer).Login(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: AdService_Login_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AdServiceServer).Login(ctx, req.(*LoginRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AdService_Refresh_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RefreshRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AdServiceServer).Refresh(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: AdService_Refresh_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AdServiceServer).Refresh(ctx, req.(*RefreshRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AdService_Logout_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RefreshRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AdServiceServer).Logout(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: AdService_Logout_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AdServiceServer).Logout(ctx, req.(*RefreshRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AdService_SetUserRole_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SetUserRoleRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AdServiceServer).SetUserRole(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: AdService_SetUserRole_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AdServiceServer).SetUserRole(ctx, req.(*SetUserRoleRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AdService_ListCategories_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(emptypb.Empty)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AdServiceServer).ListCategories(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: AdService_ListCategories_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AdServiceServer).ListCategories(ctx, req.(*emptypb.Empty))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AdService_GetCategory_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetCategoryRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AdServiceServer).GetCategory(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: AdService_GetCategory_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AdServiceServer).GetCategory(ctx, req.(*GetCategoryRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AdService_CreateCategory_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CreateCategoryRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AdServiceServer).CreateCategory(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: AdService_CreateCategory_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AdServiceServer).CreateCategory(ctx, req.(*CreateCategoryRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AdService_UpdateCategory_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UpdateCategoryRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AdServiceServer).UpdateCategory(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: AdService_UpdateCategory_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AdServiceServer).UpdateCategory(ctx, req.(*UpdateCategoryRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AdService_DeleteCategory_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DeleteCategoryRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AdServiceServer).DeleteCategory(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: AdService_DeleteCategory_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AdServiceServer).DeleteCategory(ctx, req.(*DeleteCategoryRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AdService_UploadAdImage_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(AdServiceServer).UploadAdImage(&adServiceUploadAdImageServer{stream})
+}
+
+type AdService_UploadAdImageServer interface {
+	SendAndClose(*AdResponse) error
+	Recv() (*UploadAdImageRequest, error)
+	grpc.ServerStream
+}
+
+type adServiceUploadAdImageServer struct {
+	grpc.ServerStream
+}
+
+func (x *adServiceUploadAdImageServer) SendAndClose(m *AdResponse) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func (x *adServiceUploadAdImageServer) Recv() (*UploadAdImageRequest, error) {
+	m := new(UploadAdImageRequest)
+	if err := x.ServerStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func _AdService_ScheduleAd_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ScheduleAdRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AdServiceServer).ScheduleAd(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: AdService_ScheduleAd_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AdServiceServer).ScheduleAd(ctx, req.(*ScheduleAdRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AdService_RenewAd_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RenewAdRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AdServiceServer).RenewAd(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: AdService_RenewAd_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AdServiceServer).RenewAd(ctx, req.(*RenewAdRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AdService_ReviewQueue_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ReviewQueueRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AdServiceServer).ReviewQueue(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: AdService_ReviewQueue_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AdServiceServer).ReviewQueue(ctx, req.(*ReviewQueueRequest))
+	}
+	return interceptor(ctx, in, info, handler)
 }
 
-func (c *adServiceClient) GetUser(ctx context.Context, in *GetUserRequest, opts ...grpc.CallOption) (*UserResponse, error) {
-	out := new(UserResponse)
-	err := c.cc.Invoke(ctx, AdService_GetUser_FullMethodName, in, out, opts...)
-	if err != nil {
+func _AdService_ApproveAd_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ReviewAdRequest)
+	if err := dec(in); err != nil {
 		return nil, err
 	}
-	return out, nil
+	if interceptor == nil {
+		return srv.(AdServiceServer).ApproveAd(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: AdService_ApproveAd_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AdServiceServer).ApproveAd(ctx, req.(*ReviewAdRequest))
+	}
+	return interceptor(ctx, in, info, handler)
 }
 
-func (c *adServiceClient) DeleteUser(ctx context.Context, in *DeleteUserRequest, opts ...grpc.CallOption) (*emptypb.Empty, error) {
-	out := new(emptypb.Empty)
-	err := c.cc.Invoke(ctx, AdService_DeleteUser_FullMethodName, in, out, opts...)
-	if err != nil {
+func _AdService_RejectAd_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ReviewAdRequest)
+	if err := dec(in); err != nil {
 		return nil, err
 	}
-	return out, nil
+	if interceptor == nil {
+		return srv.(AdServiceServer).RejectAd(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: AdService_RejectAd_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AdServiceServer).RejectAd(ctx, req.(*ReviewAdRequest))
+	}
+	return interceptor(ctx, in, info, handler)
 }
 
-func (c *adServiceClient) DeleteAd(ctx context.Context, in *DeleteAdRequest, opts ...grpc.CallOption) (*emptypb.Empty, error) {
-	out := new(emptypb.Empty)
-	err := c.cc.Invoke(ctx, AdService_DeleteAd_FullMethodName, in, out, opts...)
-	if err != nil {
+func _AdService_CreateReport_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CreateReportRequest)
+	if err := dec(in); err != nil {
 		return nil, err
 	}
-	return out, nil
+	if interceptor == nil {
+		return srv.(AdServiceServer).CreateReport(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: AdService_CreateReport_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AdServiceServer).CreateReport(ctx, req.(*CreateReportRequest))
+	}
+	return interceptor(ctx, in, info, handler)
 }
 
-// AdServiceServer is the server API for AdService service.
-// All implementations should embed UnimplementedAdServiceServer
-// for forward compatibility
-type AdServiceServer interface {
-	CreateAd(context.Context, *CreateAdRequest) (*AdResponse, error)
-	ChangeAdStatus(context.Context, *ChangeAdStatusRequest) (*AdResponse, error)
-	UpdateAd(context.Context, *UpdateAdRequest) (*AdResponse, error)
-	ListAds(context.Context, *emptypb.Empty) (*ListAdResponse, error)
-	CreateUser(context.Context, *CreateUserRequest) (*UserResponse, error)
-	GetUser(context.Context, *GetUserRequest) (*UserResponse, error)
-	DeleteUser(context.Context, *DeleteUserRequest) (*emptypb.Empty, error)
-	DeleteAd(context.Context, *DeleteAdRequest) (*emptypb.Empty, error)
+func _AdService_ListReports_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListReportsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AdServiceServer).ListReports(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: AdService_ListReports_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AdServiceServer).ListReports(ctx, req.(*ListReportsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
 }
 
-// UnimplementedAdServiceServer should be embedded to have forward compatible implementations.
-type UnimplementedAdServiceServer struct {
+func _AdService_ResolveReport_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CloseReportRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AdServiceServer).ResolveReport(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: AdService_ResolveReport_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AdServiceServer).ResolveReport(ctx, req.(*CloseReportRequest))
+	}
+	return interceptor(ctx, in, info, handler)
 }
 
-func (UnimplementedAdServiceServer) CreateAd(context.Context, *CreateAdRequest) (*AdResponse, error) {
-	return nil, status.Errorf(codes.Unimplemented, "method CreateAd not implemented")
-}
-func (UnimplementedAdServiceServer) ChangeAdStatus(context.Context, *ChangeAdStatusRequest) (*AdResponse, error) {
-	return nil, status.Errorf(codes.Unimplemented, "method ChangeAdStatus not implemented")
-}
-func (UnimplementedAdServiceServer) UpdateAd(context.Context, *UpdateAdRequest) (*AdResponse, error) {
-	return nil, status.Errorf(codes.Unimplemented, "method UpdateAd not implemented")
-}
-func (UnimplementedAdServiceServer) ListAds(context.Context, *emptypb.Empty) (*ListAdResponse, error) {
-	return nil, status.Errorf(codes.Unimplemented, "method ListAds not implemented")
-}
-func (UnimplementedAdServiceServer) CreateUser(context.Context, *CreateUserRequest) (*UserResponse, error) {
-	return nil, status.Errorf(codes.Unimplemented, "method CreateUser not implemented")
-}
-func (UnimplementedAdServiceServer) GetUser(context.Context, *GetUserRequest) (*UserResponse, error) {
-	return nil, status.Errorf(codes.Unimplemented, "method GetUser not implemented")
-}
-func (UnimplementedAdServiceServer) DeleteUser(context.Context, *DeleteUserRequest) (*emptypb.Empty, error) {
-	return nil, status.Errorf(codes.Unimplemented, "method DeleteUser not implemented")
-}
-func (UnimplementedAdServiceServer) DeleteAd(context.Context, *DeleteAdRequest) (*emptypb.Empty, error) {
-	return nil, status.Errorf(codes.Unimplemented, "method DeleteAd not implemented")
+func _AdService_DismissReport_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CloseReportRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AdServiceServer).DismissReport(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: AdService_DismissReport_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AdServiceServer).DismissReport(ctx, req.(*CloseReportRequest))
+	}
+	return interceptor(ctx, in, info, handler)
 }
 
-// UnsafeAdServiceServer may be embedded to opt out of forward compatibility for this service.
-// Use of this interface is not recommended, as added methods to AdServiceServer will
-// result in compilation errors.
-type UnsafeAdServiceServer interface {
-	mustEmbedUnimplementedAdServiceServer()
+func _AdService_AddFavorite_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(FavoriteRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AdServiceServer).AddFavorite(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: AdService_AddFavorite_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AdServiceServer).AddFavorite(ctx, req.(*FavoriteRequest))
+	}
+	return interceptor(ctx, in, info, handler)
 }
 
-func RegisterAdServiceServer(s grpc.ServiceRegistrar, srv AdServiceServer) {
-	s.RegisterService(&AdService_ServiceDesc, srv)
+func _AdService_RemoveFavorite_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(FavoriteRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AdServiceServer).RemoveFavorite(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: AdService_RemoveFavorite_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AdServiceServer).RemoveFavorite(ctx, req.(*FavoriteRequest))
+	}
+	return interceptor(ctx, in, info, handler)
 }
 
-func _AdService_CreateAd_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
-	in := new(CreateAdRequest)
+func _AdService_ListFavorites_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListFavoritesRequest)
 	if err := dec(in); err != nil {
 		return nil, err
 	}
 	if interceptor == nil {
-		return srv.(AdServiceServer).CreateAd(ctx, in)
+		return srv.(AdServiceServer).ListFavorites(ctx, in)
 	}
 	info := &grpc.UnaryServerInfo{
 		Server:     srv,
-		FullMethod: AdService_CreateAd_FullMethodName,
+		FullMethod: AdService_ListFavorites_FullMethodName,
 	}
 	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
-		return srv.(AdServiceServer).CreateAd(ctx, req.(*CreateAdRequest))
+		return srv.(AdServiceServer).ListFavorites(ctx, req.(*ListFavoritesRequest))
 	}
 	return interceptor(ctx, in, info, handler)
 }
 
-func _AdService_ChangeAdStatus_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
-	in := new(ChangeAdStatusRequest)
+func _AdService_ContactSeller_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ContactSellerRequest)
 	if err := dec(in); err != nil {
 		return nil, err
 	}
 	if interceptor == nil {
-		return srv.(AdServiceServer).ChangeAdStatus(ctx, in)
+		return srv.(AdServiceServer).ContactSeller(ctx, in)
 	}
 	info := &grpc.UnaryServerInfo{
 		Server:     srv,
-		FullMethod: AdService_ChangeAdStatus_FullMethodName,
+		FullMethod: AdService_ContactSeller_FullMethodName,
 	}
 	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
-		return srv.(AdServiceServer).ChangeAdStatus(ctx, req.(*ChangeAdStatusRequest))
+		return srv.(AdServiceServer).ContactSeller(ctx, req.(*ContactSellerRequest))
 	}
 	return interceptor(ctx, in, info, handler)
 }
 
-func _AdService_UpdateAd_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
-	in := new(UpdateAdRequest)
+func _AdService_SendMessage_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SendMessageRequest)
 	if err := dec(in); err != nil {
 		return nil, err
 	}
 	if interceptor == nil {
-		return srv.(AdServiceServer).UpdateAd(ctx, in)
+		return srv.(AdServiceServer).SendMessage(ctx, in)
 	}
 	info := &grpc.UnaryServerInfo{
 		Server:     srv,
-		FullMethod: AdService_UpdateAd_FullMethodName,
+		FullMethod: AdService_SendMessage_FullMethodName,
 	}
 	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
-		return srv.(AdServiceServer).UpdateAd(ctx, req.(*UpdateAdRequest))
+		return srv.(AdServiceServer).SendMessage(ctx, req.(*SendMessageRequest))
 	}
 	return interceptor(ctx, in, info, handler)
 }
 
-func _AdService_ListAds_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
-	in := new(emptypb.Empty)
+func _AdService_ListThreads_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListThreadsRequest)
 	if err := dec(in); err != nil {
 		return nil, err
 	}
 	if interceptor == nil {
-		return srv.(AdServiceServer).ListAds(ctx, in)
+		return srv.(AdServiceServer).ListThreads(ctx, in)
 	}
 	info := &grpc.UnaryServerInfo{
 		Server:     srv,
-		FullMethod: AdService_ListAds_FullMethodName,
+		FullMethod: AdService_ListThreads_FullMethodName,
 	}
 	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
-		return srv.(AdServiceServer).ListAds(ctx, req.(*emptypb.Empty))
+		return srv.(AdServiceServer).ListThreads(ctx, req.(*ListThreadsRequest))
 	}
 	return interceptor(ctx, in, info, handler)
 }
 
-func _AdService_CreateUser_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
-	in := new(CreateUserRequest)
+func _AdService_ListMessages_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListMessagesRequest)
 	if err := dec(in); err != nil {
 		return nil, err
 	}
 	if interceptor == nil {
-		return srv.(AdServiceServer).CreateUser(ctx, in)
+		return srv.(AdServiceServer).ListMessages(ctx, in)
 	}
 	info := &grpc.UnaryServerInfo{
 		Server:     srv,
-		FullMethod: AdService_CreateUser_FullMethodName,
+		FullMethod: AdService_ListMessages_FullMethodName,
 	}
 	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
-		return srv.(AdServiceServer).CreateUser(ctx, req.(*CreateUserRequest))
+		return srv.(AdServiceServer).ListMessages(ctx, req.(*ListMessagesRequest))
 	}
 	return interceptor(ctx, in, info, handler)
 }
 
-func _AdService_GetUser_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
-	in := new(GetUserRequest)
+func _AdService_MarkThreadRead_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ThreadRequest)
 	if err := dec(in); err != nil {
 		return nil, err
 	}
 	if interceptor == nil {
-		return srv.(AdServiceServer).GetUser(ctx, in)
+		return srv.(AdServiceServer).MarkThreadRead(ctx, in)
 	}
 	info := &grpc.UnaryServerInfo{
 		Server:     srv,
-		FullMethod: AdService_GetUser_FullMethodName,
+		FullMethod: AdService_MarkThreadRead_FullMethodName,
 	}
 	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
-		return srv.(AdServiceServer).GetUser(ctx, req.(*GetUserRequest))
+		return srv.(AdServiceServer).MarkThreadRead(ctx, req.(*ThreadRequest))
 	}
 	return interceptor(ctx, in, info, handler)
 }
 
-func _AdService_DeleteUser_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
-	in := new(DeleteUserRequest)
+func _AdService_BlockUser_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(BlockUserRequest)
 	if err := dec(in); err != nil {
 		return nil, err
 	}
 	if interceptor == nil {
-		return srv.(AdServiceServer).DeleteUser(ctx, in)
+		return srv.(AdServiceServer).BlockUser(ctx, in)
 	}
 	info := &grpc.UnaryServerInfo{
 		Server:     srv,
-		FullMethod: AdService_DeleteUser_FullMethodName,
+		FullMethod: AdService_BlockUser_FullMethodName,
 	}
 	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
-		return srv.(AdServiceServer).DeleteUser(ctx, req.(*DeleteUserRequest))
+		return srv.(AdServiceServer).BlockUser(ctx, req.(*BlockUserRequest))
 	}
 	return interceptor(ctx, in, info, handler)
 }
 
-func _AdService_DeleteAd_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
-	in := new(DeleteAdRequest)
+func _AdService_UnblockUser_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(BlockUserRequest)
 	if err := dec(in); err != nil {
 		return nil, err
 	}
 	if interceptor == nil {
-		return srv.(AdServiceServer).DeleteAd(ctx, in)
+		return srv.(AdServiceServer).UnblockUser(ctx, in)
 	}
 	info := &grpc.UnaryServerInfo{
 		Server:     srv,
-		FullMethod: AdService_DeleteAd_FullMethodName,
+		FullMethod: AdService_UnblockUser_FullMethodName,
 	}
 	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
-		return srv.(AdServiceServer).DeleteAd(ctx, req.(*DeleteAdRequest))
+		return srv.(AdServiceServer).UnblockUser(ctx, req.(*BlockUserRequest))
 	}
 	return interceptor(ctx, in, info, handler)
 }
 
+func _AdService_WatchAds_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(WatchAdsRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(AdServiceServer).WatchAds(m, &adServiceWatchAdsServer{stream})
+}
+
+type AdService_WatchAdsServer interface {
+	Send(*AdEvent) error
+	grpc.ServerStream
+}
+
+type adServiceWatchAdsServer struct {
+	grpc.ServerStream
+}
+
+func (x *adServiceWatchAdsServer) Send(m *AdEvent) error {
+	return x.ServerStream.SendMsg(m)
+}
+
 // AdService_ServiceDesc is the grpc.ServiceDesc for AdService service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -345,6 +1512,14 @@ var AdService_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "ListAds",
 			Handler:    _AdService_ListAds_Handler,
 		},
+		{
+			MethodName: "FindAds",
+			Handler:    _AdService_FindAds_Handler,
+		},
+		{
+			MethodName: "SearchAds",
+			Handler:    _AdService_SearchAds_Handler,
+		},
 		{
 			MethodName: "CreateUser",
 			Handler:    _AdService_CreateUser_Handler,
@@ -361,7 +1536,130 @@ var AdService_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "DeleteAd",
 			Handler:    _AdService_DeleteAd_Handler,
 		},
+		{
+			MethodName: "Login",
+			Handler:    _AdService_Login_Handler,
+		},
+		{
+			MethodName: "Refresh",
+			Handler:    _AdService_Refresh_Handler,
+		},
+		{
+			MethodName: "Logout",
+			Handler:    _AdService_Logout_Handler,
+		},
+		{
+			MethodName: "SetUserRole",
+			Handler:    _AdService_SetUserRole_Handler,
+		},
+		{
+			MethodName: "ListCategories",
+			Handler:    _AdService_ListCategories_Handler,
+		},
+		{
+			MethodName: "GetCategory",
+			Handler:    _AdService_GetCategory_Handler,
+		},
+		{
+			MethodName: "CreateCategory",
+			Handler:    _AdService_CreateCategory_Handler,
+		},
+		{
+			MethodName: "UpdateCategory",
+			Handler:    _AdService_UpdateCategory_Handler,
+		},
+		{
+			MethodName: "DeleteCategory",
+			Handler:    _AdService_DeleteCategory_Handler,
+		},
+		{
+			MethodName: "ScheduleAd",
+			Handler:    _AdService_ScheduleAd_Handler,
+		},
+		{
+			MethodName: "RenewAd",
+			Handler:    _AdService_RenewAd_Handler,
+		},
+		{
+			MethodName: "ReviewQueue",
+			Handler:    _AdService_ReviewQueue_Handler,
+		},
+		{
+			MethodName: "ApproveAd",
+			Handler:    _AdService_ApproveAd_Handler,
+		},
+		{
+			MethodName: "RejectAd",
+			Handler:    _AdService_RejectAd_Handler,
+		},
+		{
+			MethodName: "CreateReport",
+			Handler:    _AdService_CreateReport_Handler,
+		},
+		{
+			MethodName: "ListReports",
+			Handler:    _AdService_ListReports_Handler,
+		},
+		{
+			MethodName: "ResolveReport",
+			Handler:    _AdService_ResolveReport_Handler,
+		},
+		{
+			MethodName: "DismissReport",
+			Handler:    _AdService_DismissReport_Handler,
+		},
+		{
+			MethodName: "AddFavorite",
+			Handler:    _AdService_AddFavorite_Handler,
+		},
+		{
+			MethodName: "RemoveFavorite",
+			Handler:    _AdService_RemoveFavorite_Handler,
+		},
+		{
+			MethodName: "ListFavorites",
+			Handler:    _AdService_ListFavorites_Handler,
+		},
+		{
+			MethodName: "ContactSeller",
+			Handler:    _AdService_ContactSeller_Handler,
+		},
+		{
+			MethodName: "SendMessage",
+			Handler:    _AdService_SendMessage_Handler,
+		},
+		{
+			MethodName: "ListThreads",
+			Handler:    _AdService_ListThreads_Handler,
+		},
+		{
+			MethodName: "ListMessages",
+			Handler:    _AdService_ListMessages_Handler,
+		},
+		{
+			MethodName: "MarkThreadRead",
+			Handler:    _AdService_MarkThreadRead_Handler,
+		},
+		{
+			MethodName: "BlockUser",
+			Handler:    _AdService_BlockUser_Handler,
+		},
+		{
+			MethodName: "UnblockUser",
+			Handler:    _AdService_UnblockUser_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "UploadAdImage",
+			Handler:       _AdService_UploadAdImage_Handler,
+			ClientStreams: true,
+		},
+		{
+			StreamName:    "WatchAds",
+			Handler:       _AdService_WatchAds_Handler,
+			ServerStreams: true,
+		},
 	},
-	Streams:  []grpc.StreamDesc{},
 	Metadata: "service.proto",
 }
@@ -0,0 +1,137 @@
+package httpgin
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"homework9/internal/app"
+)
+
+// bearerToken достаёт токен из заголовка Authorization: Bearer <token>
+func bearerToken(c *gin.Context) string {
+	header := c.GetHeader("Authorization")
+	scheme, token, ok := strings.Cut(header, " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") {
+		return ""
+	}
+	return strings.TrimSpace(token)
+}
+
+// authenticate проверяет access-токен из Authorization и кладёт ID пользователя в контекст запроса.
+// Запрос без токена проходит дальше анонимно, с недействительным токеном - получает 401
+func authenticate(a app.App) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.GetHeader("Authorization") == "" {
+			c.Next()
+			return
+		}
+		userID, err := a.Authenticate(c, bearerToken(c))
+		if err == app.ErrUnauthenticated {
+			unauthorized(c, err)
+			return
+		} else if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, UserErrorResponse(err))
+			return
+		}
+		c.Request = c.Request.WithContext(app.WithUserID(c.Request.Context(), userID))
+		c.Next()
+	}
+}
+
+// requireUser пропускает только запросы, прошедшие authenticate с токеном
+func requireUser(c *gin.Context) {
+	if _, ok := app.UserIDFromContext(c); !ok {
+		unauthorized(c, app.ErrUnauthenticated)
+		return
+	}
+	c.Next()
+}
+
+func unauthorized(c *gin.Context, err error) {
+	c.Header("WWW-Authenticate", `Bearer realm="ads"`)
+	c.AbortWithStatusJSON(http.StatusUnauthorized, UserErrorResponse(err))
+}
+
+// Метод для регистрации пользователя
+func register(a app.App) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var reqBody registerRequest
+		if err := c.Bind(&reqBody); err != nil {
+			c.JSON(http.StatusBadRequest, UserErrorResponse(err))
+			return
+		}
+
+		u, err := a.CreateUser(c, reqBody.Nickname, reqBody.Email, reqBody.Password)
+		if err == app.ErrInvalidEmail || err == app.ErrInvalidPassword {
+			c.JSON(http.StatusBadRequest, UserErrorResponse(err))
+			return
+		} else if err == app.ErrEmailTaken {
+			c.JSON(http.StatusConflict, UserErrorResponse(err))
+			return
+		} else if err != nil {
+			c.JSON(http.StatusInternalServerError, UserErrorResponse(err))
+			return
+		}
+		c.JSON(http.StatusOK, UserSuccessResponse(u))
+	}
+}
+
+// Метод для входа: обменивает email и пароль на пару токенов
+func login(a app.App) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var reqBody loginRequest
+		if err := c.Bind(&reqBody); err != nil {
+			c.JSON(http.StatusBadRequest, UserErrorResponse(err))
+			return
+		}
+
+		t, err := a.Login(c, reqBody.Email, reqBody.Password)
+		if err == app.ErrInvalidCredentials {
+			c.JSON(http.StatusUnauthorized, UserErrorResponse(err))
+			return
+		} else if err != nil {
+			c.JSON(http.StatusInternalServerError, UserErrorResponse(err))
+			return
+		}
+		c.JSON(http.StatusOK, TokensSuccessResponse(t))
+	}
+}
+
+// Метод для обновления токенов: refresh-токен обменивается на новую пару и больше не действует
+func refresh(a app.App) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var reqBody refreshRequest
+		if err := c.Bind(&reqBody); err != nil {
+			c.JSON(http.StatusBadRequest, UserErrorResponse(err))
+			return
+		}
+
+		t, err := a.Refresh(c, reqBody.RefreshToken)
+		if err == app.ErrUnauthenticated {
+			unauthorized(c, err)
+			return
+		} else if err != nil {
+			c.JSON(http.StatusInternalServerError, UserErrorResponse(err))
+			return
+		}
+		c.JSON(http.StatusOK, TokensSuccessResponse(t))
+	}
+}
+
+// Метод для выхода: отзывает refresh-токен
+func logout(a app.App) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var reqBody refreshRequest
+		if err := c.Bind(&reqBody); err != nil {
+			c.JSON(http.StatusBadRequest, UserErrorResponse(err))
+			return
+		}
+		if err := a.Logout(c, reqBody.RefreshToken); err != nil {
+			c.JSON(http.StatusInternalServerError, UserErrorResponse(err))
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"data": nil, "error": nil})
+	}
+}
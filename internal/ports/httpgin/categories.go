@@ -0,0 +1,144 @@
+package httpgin
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"homework9/internal/app"
+	"homework9/internal/categories"
+)
+
+// categoryRequest: parent_id 0 или отсутствует - корневая категория
+type categoryRequest struct {
+	Name     string `json:"name"`
+	ParentID int64  `json:"parent_id"`
+}
+
+type categoryResponse struct {
+	ID       int64  `json:"id"`
+	Name     string `json:"name"`
+	ParentID int64  `json:"parent_id"`
+}
+
+func newCategoryResponse(c *categories.Category) categoryResponse {
+	return categoryResponse{ID: c.ID, Name: c.Name, ParentID: c.ParentID}
+}
+
+func CategorySuccessResponse(c *categories.Category) *gin.H {
+	return &gin.H{
+		"data":  newCategoryResponse(c),
+		"error": nil,
+	}
+}
+
+func CategoriesSuccessResponse(list []categories.Category) *gin.H {
+	res := make([]categoryResponse, 0, len(list))
+	for i := range list {
+		res = append(res, newCategoryResponse(&list[i]))
+	}
+	return &gin.H{
+		"data":  res,
+		"error": nil,
+	}
+}
+
+// categoryError отвечает на ошибку методов категорий
+func categoryError(c *gin.Context, err error) {
+	switch err {
+	case app.ErrUnauthenticated:
+		unauthorized(c, err)
+	case app.ErrAccessDenied:
+		c.JSON(http.StatusForbidden, AdErrorResponse(err))
+	case app.ErrNotFound:
+		c.JSON(http.StatusNotFound, AdErrorResponse(err))
+	case app.ErrInvalidCategory, app.ErrCategoryName, app.ErrCategoryCycle:
+		c.JSON(http.StatusBadRequest, AdErrorResponse(err))
+	case app.ErrCategoryNotEmpty:
+		c.JSON(http.StatusConflict, AdErrorResponse(err))
+	default:
+		c.JSON(http.StatusInternalServerError, AdErrorResponse(err))
+	}
+}
+
+// Метод для получения всего дерева категорий плоским списком, связи задаёт parent_id
+func listCategories(a app.App) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		list, err := a.ListCategories(c)
+		if err != nil {
+			categoryError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, CategoriesSuccessResponse(list))
+	}
+}
+
+func getCategory(a app.App) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, err := strconv.ParseInt(c.Param("category_id"), 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, AdErrorResponse(err))
+			return
+		}
+		category, err := a.GetCategory(c, id)
+		if err != nil {
+			categoryError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, CategorySuccessResponse(category))
+	}
+}
+
+func createCategory(a app.App) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var reqBody categoryRequest
+		if err := c.Bind(&reqBody); err != nil {
+			c.JSON(http.StatusBadRequest, AdErrorResponse(err))
+			return
+		}
+		category, err := a.CreateCategory(c, reqBody.Name, reqBody.ParentID)
+		if err != nil {
+			categoryError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, CategorySuccessResponse(category))
+	}
+}
+
+// Метод для переименования категории и переноса её (вместе с подкатегориями) под другого родителя
+func updateCategory(a app.App) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var reqBody categoryRequest
+		if err := c.Bind(&reqBody); err != nil {
+			c.JSON(http.StatusBadRequest, AdErrorResponse(err))
+			return
+		}
+		id, err := strconv.ParseInt(c.Param("category_id"), 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, AdErrorResponse(err))
+			return
+		}
+		category, err := a.UpdateCategory(c, id, reqBody.Name, reqBody.ParentID)
+		if err != nil {
+			categoryError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, CategorySuccessResponse(category))
+	}
+}
+
+func deleteCategory(a app.App) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, err := strconv.ParseInt(c.Param("category_id"), 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, AdErrorResponse(err))
+			return
+		}
+		if err := a.DeleteCategory(c, id); err != nil {
+			categoryError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"data": nil, "error": nil})
+	}
+}
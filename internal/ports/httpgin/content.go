@@ -0,0 +1,57 @@
+package httpgin
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"homework9/internal/ads"
+	"homework9/internal/app"
+)
+
+// findingResponse - замечание проверки содержимого. field пуст, если замечание ко всему объявлению
+type findingResponse struct {
+	Check   string `json:"check"`
+	Verdict string `json:"verdict"`
+	Field   string `json:"field"`
+	Reason  string `json:"reason"`
+}
+
+// flagResponse - замечание, с которым объявление сохранено и ждёт модератора
+type flagResponse struct {
+	Check  string `json:"check"`
+	Field  string `json:"field"`
+	Reason string `json:"reason"`
+}
+
+func newFlagsResponse(flags []ads.Flag) []flagResponse {
+	res := make([]flagResponse, 0, len(flags))
+	for _, f := range flags {
+		res = append(res, flagResponse{Check: f.Check, Field: f.Field, Reason: f.Reason})
+	}
+	return res
+}
+
+// ContentErrorResponse - отказ проверок содержимого: в findings все замечания, чтобы автор исправил их разом
+func ContentErrorResponse(e *app.ContentError) *gin.H {
+	findings := make([]findingResponse, 0, len(e.Findings))
+	for _, f := range e.Findings {
+		findings = append(findings, findingResponse{Check: f.Check, Verdict: f.Verdict.String(), Field: f.Field, Reason: f.Reason})
+	}
+	return &gin.H{
+		"data":     nil,
+		"error":    e.Error(),
+		"findings": findings,
+	}
+}
+
+// contentRejected отвечает 422, если объявление не прошло проверки содержимого
+func contentRejected(c *gin.Context, err error) bool {
+	var rejected *app.ContentError
+	if !errors.As(err, &rejected) {
+		return false
+	}
+	c.JSON(http.StatusUnprocessableEntity, ContentErrorResponse(rejected))
+	return true
+}
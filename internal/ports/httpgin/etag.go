@@ -0,0 +1,34 @@
+package httpgin
+
+import (
+	"strconv"
+	"strings"
+)
+
+// ETag объявления - его версия в кавычках
+func etag(version int64) string {
+	return `"` + strconv.FormatInt(version, 10) + `"`
+}
+
+// etagMatches проверяет заголовок If-Match (strong = true) или If-None-Match (strong = false)
+// против текущей версии. Заголовок может содержать "*" или список ETag через запятую.
+func etagMatches(header string, version int64, strong bool) bool {
+	current := etag(version)
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimSpace(tag)
+		if tag == "*" {
+			return true
+		}
+		if strings.HasPrefix(tag, "W/") {
+			// слабые ETag не годятся для If-Match
+			if strong {
+				continue
+			}
+			tag = strings.TrimPrefix(tag, "W/")
+		}
+		if tag == current {
+			return true
+		}
+	}
+	return false
+}
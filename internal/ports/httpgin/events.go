@@ -0,0 +1,169 @@
+package httpgin
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/net/websocket"
+
+	"homework9/internal/app"
+	"homework9/internal/events"
+)
+
+const (
+	// DefaultEventBuffer - сколько событий может ждать отправки в одно соединение
+	DefaultEventBuffer = 64
+	// eventWriteTimeout - сколько ждать записи события в соединение, прежде чем его оборвать
+	eventWriteTimeout = 10 * time.Second
+)
+
+// eventRequest - сообщение клиента в WebSocket: filter заменяет фильтр объявлений подписки, null его снимает
+type eventRequest struct {
+	Filter *findAdsRequest `json:"filter"`
+}
+
+// eventResponse - сообщение сервера в WebSocket. type:
+// ad.published - опубликовано объявление под фильтр подписки;
+// favorite.status - сменился статус объявления из избранного, ad null, если объявление удалено или скрыто;
+// message.new - новое сообщение; filter - фильтр подписки принят; error - ошибка, после slow_consumer соединение закрывается
+type eventResponse struct {
+	Type    string           `json:"type"`
+	Seq     uint64           `json:"seq,omitempty"`
+	At      *time.Time       `json:"at,omitempty"`
+	AdID    *int64           `json:"ad_id,omitempty"`
+	Ad      *adResponse      `json:"ad,omitempty"`
+	Message *messageResponse `json:"message,omitempty"`
+	Error   string           `json:"error,omitempty"`
+}
+
+var eventTypes = map[events.Kind]string{
+	events.KindAdStatus:       "ad.published",
+	events.KindFavoriteStatus: "favorite.status",
+	events.KindMessage:        "message.new",
+}
+
+func newEventResponse(e events.Event, favorites int) eventResponse {
+	res := eventResponse{Type: eventTypes[e.Kind], Seq: e.Seq, At: &e.At}
+	if e.Kind != events.KindMessage {
+		res.AdID = &e.AdID
+	}
+	if e.Ad != nil {
+		ad := newAdResponse(e.Ad, favorites, priceDisplay{})
+		res.Ad = &ad
+	}
+	if e.Message != nil {
+		m := newMessageResponse(e.Message, false)
+		res.Message = &m
+	}
+	return res
+}
+
+// eventError отвечает на ошибку подписки до перехода на WebSocket
+func eventError(c *gin.Context, err error) {
+	switch err {
+	case app.ErrUnauthenticated:
+		unauthorized(c, err)
+	case app.ErrAccessDenied:
+		c.JSON(http.StatusForbidden, AdErrorResponse(err))
+	case app.ErrEventsDisabled:
+		c.JSON(http.StatusNotImplemented, AdErrorResponse(err))
+	default:
+		c.JSON(http.StatusInternalServerError, AdErrorResponse(err))
+	}
+}
+
+// watchEvents - WebSocket с событиями пользователя. Браузер не может передать заголовок Authorization,
+// поэтому access-токен принимается и в параметре access_token. done закрывается при остановке сервера
+func watchEvents(a app.App, buffer int, done <-chan struct{}) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if _, ok := app.UserIDFromContext(c); !ok {
+			token := c.Query("access_token")
+			if token == "" {
+				unauthorized(c, app.ErrUnauthenticated)
+				return
+			}
+			userID, err := a.Authenticate(c, token)
+			if err != nil {
+				eventError(c, err)
+				return
+			}
+			c.Request = c.Request.WithContext(app.WithUserID(c.Request.Context(), userID))
+		}
+		sub, err := a.SubscribeEvents(c, buffer)
+		if err != nil {
+			eventError(c, err)
+			return
+		}
+		defer sub.Close()
+
+		// проверку Origin заменяет токен: без него подписаться нельзя
+		websocket.Server{Handler: func(ws *websocket.Conn) {
+			serveEvents(c, a, ws, sub, done)
+		}}.ServeHTTP(c.Writer, c.Request)
+	}
+}
+
+// serveEvents пишет события в соединение, пока его не закроет клиент, сервер или подписка
+func serveEvents(c *gin.Context, a app.App, ws *websocket.Conn, sub *app.EventSubscription, done <-chan struct{}) {
+	defer ws.Close()
+	// читатель применяет фильтры клиента, а ответы отдаёт писателю: писать в соединение может только он
+	replies := make(chan eventResponse, 1)
+	closed, quit := make(chan struct{}), make(chan struct{})
+	defer close(quit)
+	go func() {
+		defer close(closed)
+		for {
+			var req eventRequest
+			if err := websocket.JSON.Receive(ws, &req); err != nil {
+				return
+			}
+			var opts *app.FilterOpts
+			if req.Filter != nil {
+				f := req.Filter.filter()
+				opts = &f
+			}
+			reply := eventResponse{Type: "filter"}
+			if err := sub.SetFilter(c, opts); err != nil {
+				reply = eventResponse{Type: "error", Error: err.Error()}
+			}
+			select {
+			case replies <- reply:
+			case <-quit:
+				return
+			}
+		}
+	}()
+
+	send := func(res eventResponse) bool {
+		_ = ws.SetWriteDeadline(time.Now().Add(eventWriteTimeout))
+		return websocket.JSON.Send(ws, res) == nil
+	}
+	for {
+		select {
+		case e, ok := <-sub.Events():
+			if !ok {
+				if sub.Err() == events.ErrSlowSubscriber {
+					send(eventResponse{Type: "error", Error: "slow_consumer"})
+				}
+				return
+			}
+			e = sub.View(e)
+			var favorites int
+			if e.Ad != nil {
+				favorites = favoriteCount(c, a, e.AdID)
+			}
+			if !send(newEventResponse(e, favorites)) {
+				return
+			}
+		case reply := <-replies:
+			if !send(reply) {
+				return
+			}
+		case <-closed:
+			return
+		case <-done:
+			return
+		}
+	}
+}
@@ -0,0 +1,150 @@
+package httpgin
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"homework9/internal/ads"
+	"homework9/internal/app"
+)
+
+// favoriteResponse: ad - объявление, null если оно удалено. available - объявление не удалено и опубликовано
+type favoriteResponse struct {
+	AdID      int64       `json:"ad_id"`
+	AddedAt   time.Time   `json:"added_at"`
+	Available bool        `json:"available"`
+	Deleted   bool        `json:"deleted"`
+	Ad        *adResponse `json:"ad"`
+}
+
+func newFavoriteResponse(f *app.FavoriteAd, favorites int) favoriteResponse {
+	res := favoriteResponse{AdID: f.AdID, AddedAt: f.Added, Available: f.Available(), Deleted: f.Ad == nil}
+	if f.Ad != nil {
+		ad := newAdResponse(f.Ad, favorites, priceDisplay{})
+		res.Ad = &ad
+	}
+	return res
+}
+
+func FavoriteSuccessResponse(f *app.FavoriteAd, favorites int) *gin.H {
+	return &gin.H{
+		"data":  newFavoriteResponse(f, favorites),
+		"error": nil,
+	}
+}
+
+func FavoritesPageSuccessResponse(page *app.FavoritesPage, favorites map[int64]int) *gin.H {
+	res := make([]favoriteResponse, 0, len(page.Favorites))
+	for i := range page.Favorites {
+		res = append(res, newFavoriteResponse(&page.Favorites[i], favorites[page.Favorites[i].AdID]))
+	}
+	var next *string
+	if page.NextCursor != "" {
+		next = &page.NextCursor
+	}
+	return &gin.H{
+		"data":        res,
+		"next_cursor": next,
+		"error":       nil,
+	}
+}
+
+// favoriteCounts возвращает число в избранном для объявлений ответа. Счётчик не главное в ответе,
+// поэтому его ошибка только пишется в журнал, а число остаётся нулевым
+func favoriteCounts(c *gin.Context, a app.App, ids []int64) map[int64]int {
+	counts, err := a.FavoriteCounts(c, ids)
+	if err != nil {
+		log.Printf("count favorites: %v", err)
+		return map[int64]int{}
+	}
+	return counts
+}
+
+func favoriteCount(c *gin.Context, a app.App, id int64) int {
+	return favoriteCounts(c, a, []int64{id})[id]
+}
+
+func adIDs(list []ads.Ad) []int64 {
+	res := make([]int64, 0, len(list))
+	for i := range list {
+		res = append(res, list[i].ID)
+	}
+	return res
+}
+
+// favoriteError отвечает на ошибку методов избранного
+func favoriteError(c *gin.Context, err error) {
+	switch err {
+	case app.ErrUnauthenticated:
+		unauthorized(c, err)
+	case app.ErrAccessDenied:
+		c.JSON(http.StatusForbidden, AdErrorResponse(err))
+	case app.ErrNotFound:
+		c.JSON(http.StatusNotFound, AdErrorResponse(err))
+	case app.ErrFavoriteUnavailable:
+		c.JSON(http.StatusConflict, AdErrorResponse(err))
+	case app.ErrInvalidCursor:
+		c.JSON(http.StatusBadRequest, AdErrorResponse(err))
+	case app.ErrFavoritesDisabled:
+		c.JSON(http.StatusNotImplemented, AdErrorResponse(err))
+	default:
+		c.JSON(http.StatusInternalServerError, AdErrorResponse(err))
+	}
+}
+
+// addFavorite - метод для добавления объявления в избранное, повторный запрос ничего не меняет
+func addFavorite(a app.App) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		adID, err := strconv.ParseInt(c.Param("ad_id"), 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, AdErrorResponse(err))
+			return
+		}
+		f, err := a.AddFavorite(c, adID)
+		if err != nil {
+			favoriteError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, FavoriteSuccessResponse(f, favoriteCount(c, a, adID)))
+	}
+}
+
+func removeFavorite(a app.App) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		adID, err := strconv.ParseInt(c.Param("ad_id"), 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, AdErrorResponse(err))
+			return
+		}
+		if err := a.RemoveFavorite(c, adID); err != nil {
+			favoriteError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"data": nil, "error": nil})
+	}
+}
+
+// Метод для просмотра своего избранного от новых к старым: limit и cursor
+func listFavorites(a app.App) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		p, err := idPageRequest(c)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, AdErrorResponse(err))
+			return
+		}
+		page, err := a.ListFavorites(c, p)
+		if err != nil {
+			favoriteError(c, err)
+			return
+		}
+		ids := make([]int64, 0, len(page.Favorites))
+		for _, f := range page.Favorites {
+			ids = append(ids, f.AdID)
+		}
+		c.JSON(http.StatusOK, FavoritesPageSuccessResponse(page, favoriteCounts(c, a, ids)))
+	}
+}
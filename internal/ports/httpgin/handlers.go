@@ -1,15 +1,19 @@
 package httpgin
 
 import (
+	"fmt"
 	"github.com/gin-gonic/gin"
 	"net/http"
 	"strconv"
 
+	"homework9/internal/ads"
 	"homework9/internal/app"
+	"homework9/internal/geo"
+	"homework9/internal/users"
 )
 
 // Метод для создания объявления (ad)
-func createAd(a app.App) gin.HandlerFunc {
+func createAd(a app.App, limits Limits) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		var reqBody createAdRequest
 		err := c.Bind(&reqBody)
@@ -18,38 +22,73 @@ func createAd(a app.App) gin.HandlerFunc {
 			return
 		}
 
-		_, err = a.GetUserByID(c, reqBody.UserID)
-		if err == app.ErrNotFound {
-			c.JSON(http.StatusNotFound, AdErrorResponse(err))
-			return
-		} else if err != nil {
-			c.JSON(http.StatusInternalServerError, AdErrorResponse(err))
+		if err := reqBody.Validate(limits); err != nil {
+			c.JSON(http.StatusBadRequest, AdErrorResponse(err))
 			return
 		}
 
-		if err := reqBody.Validate(); err != nil {
+		u, err := a.CreateAd(c, reqBody.Title, reqBody.Text, reqBody.CategoryID, reqBody.Price.price(), reqBody.Location.location())
+		if contentRejected(c, err) {
+			return
+		} else if err == app.ErrUnauthenticated {
+			unauthorized(c, err)
+			return
+		} else if err == app.ErrInvalidCategory || err == app.ErrInvalidPrice || err == app.ErrInvalidCurrency || err == app.ErrInvalidLocation {
 			c.JSON(http.StatusBadRequest, AdErrorResponse(err))
 			return
-		}
-
-		u, err := a.CreateAd(c, reqBody.Title, reqBody.Text, reqBody.UserID)
-		if err != nil {
+		} else if err != nil {
 			c.JSON(http.StatusInternalServerError, AdErrorResponse(err))
 			return
 		}
-		c.JSON(http.StatusOK, AdSuccessResponse(u))
+		c.JSON(http.StatusOK, AdSuccessResponse(u, favoriteCount(c, a, u.ID), priceDisplay{}))
 	}
 }
 
-// метод для получения объявления
+// метод для получения опубликованных объявлений, постранично. category_id оставляет объявления
+// из категории и её подкатегорий, radius_km - не дальше этого расстояния от точки lat, lon
 func getPublishedAds(a app.App) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		l, err := a.ListPublishedAds(c)
+		p, err := pageRequest(c)
 		if err != nil {
+			c.JSON(http.StatusBadRequest, AdErrorResponse(err))
+			return
+		}
+		categoryID, err := queryID(c, "category_id")
+		if err != nil {
+			c.JSON(http.StatusBadRequest, AdErrorResponse(err))
+			return
+		}
+		d, err := newPriceDisplay(c, a)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, AdErrorResponse(err))
+			return
+		}
+
+		near, err := queryNear(c, p.Sort.Origin)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, AdErrorResponse(err))
+			return
+		}
+
+		var page *app.AdsPage
+		if categoryID != 0 || near != nil {
+			f := app.FilterOpts{Published: ads.OnlyPublished, Near: near}
+			if categoryID != 0 {
+				f.CategoryIDs = []int64{categoryID}
+			}
+			page, err = a.GetAdsByFilter(c, f, p)
+		} else {
+			page, err = a.ListPublishedAds(c, p)
+		}
+		if err == app.ErrInvalidCursor || err == app.ErrInvalidSort || err == app.ErrInvalidCategory ||
+			err == app.ErrInvalidArea || err == app.ErrInvalidLocation {
+			c.JSON(http.StatusBadRequest, AdErrorResponse(err))
+			return
+		} else if err != nil {
 			c.JSON(http.StatusInternalServerError, AdErrorResponse(err))
 			return
 		}
-		c.JSON(http.StatusOK, MultipleAdsSuccessResponse(l))
+		c.JSON(http.StatusOK, AdsPageSuccessResponse(page, favoriteCounts(c, a, adIDs(page.Ads)), d))
 	}
 }
 
@@ -62,68 +101,138 @@ func changeAdStatus(a app.App) gin.HandlerFunc {
 			return
 		}
 
-		_, err := a.GetUserByID(c, reqBody.UserID)
-		if err == app.ErrNotFound {
+		adID, err := strconv.Atoi(c.Param("ad_id"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, AdErrorResponse(err))
+			return
+		}
+
+		u, err := a.UpdateStatusById(c, int64(adID), reqBody.Published)
+		if err == app.ErrUnauthenticated {
+			unauthorized(c, err)
+			return
+		} else if err == app.ErrAdExpired {
+			c.JSON(http.StatusConflict, AdErrorResponse(err))
+			return
+		} else if err == app.ErrAccessDenied {
+			c.JSON(http.StatusForbidden, AdErrorResponse(err))
+			return
+		} else if err == app.ErrNotFound {
 			c.JSON(http.StatusNotFound, AdErrorResponse(err))
 			return
+		} else if err == app.ErrVersionConflict {
+			c.JSON(http.StatusConflict, AdErrorResponse(err))
+			return
 		} else if err != nil {
 			c.JSON(http.StatusInternalServerError, AdErrorResponse(err))
 			return
 		}
 
+		c.JSON(http.StatusOK, AdSuccessResponse(u, favoriteCount(c, a, u.ID), priceDisplay{}))
+	}
+}
+
+func getAdById(a app.App) gin.HandlerFunc {
+	return func(c *gin.Context) {
 		adID, err := strconv.Atoi(c.Param("ad_id"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, UserErrorResponse(err))
+			return
+		}
+		d, err := newPriceDisplay(c, a)
 		if err != nil {
 			c.JSON(http.StatusBadRequest, AdErrorResponse(err))
 			return
 		}
-
-		u, err := a.UpdateStatusById(c, int64(adID), reqBody.Published, reqBody.UserID)
-		if err == app.ErrAccessDenied {
-			c.JSON(http.StatusForbidden, AdErrorResponse(err))
+		ad, err := a.GetAdByID(c, int64(adID))
+		if err == app.ErrNotFound {
+			c.JSON(http.StatusNotFound, AdErrorResponse(err))
 			return
 		} else if err != nil {
 			c.JSON(http.StatusInternalServerError, AdErrorResponse(err))
 			return
 		}
 
-		c.JSON(http.StatusOK, AdSuccessResponse(u))
+		c.Header("ETag", etag(ad.Version))
+		if noneMatch := c.GetHeader("If-None-Match"); noneMatch != "" && etagMatches(noneMatch, ad.Version, false) {
+			c.Status(http.StatusNotModified)
+			return
+		}
+		c.JSON(http.StatusOK, AdSuccessResponse(ad, favoriteCount(c, a, ad.ID), d))
 	}
 }
 
-func getAdById(a app.App) gin.HandlerFunc {
+// Метод полнотекстового поиска: GET /ads/search?q=...&limit=N, результаты по убыванию релевантности
+func searchAds(a app.App) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		adID, err := strconv.Atoi(c.Param("ad_id"))
+		limit := 0
+		if v := c.Query("limit"); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil || n < 0 {
+				c.JSON(http.StatusBadRequest, AdErrorResponse(fmt.Errorf("invalid limit %q", v)))
+				return
+			}
+			limit = n
+		}
+
+		categoryID, err := queryID(c, "category_id")
 		if err != nil {
-			c.JSON(http.StatusBadRequest, UserErrorResponse(err))
+			c.JSON(http.StatusBadRequest, AdErrorResponse(err))
 			return
 		}
-		l, err := a.GetAdsByFilter(c, app.FilterOpts{ID: int64(adID), Hidden: false})
+
+		d, err := newPriceDisplay(c, a)
 		if err != nil {
-			c.JSON(http.StatusNotFound, UserErrorResponse(err))
+			c.JSON(http.StatusBadRequest, AdErrorResponse(err))
 			return
 		}
 
-		c.JSON(http.StatusOK, AdSuccessResponse(&l[0]))
+		results, err := a.SearchAds(c, c.Query("q"), categoryID, limit)
+		if err == app.ErrEmptyQuery || err == app.ErrInvalidCategory {
+			c.JSON(http.StatusBadRequest, AdErrorResponse(err))
+			return
+		} else if err != nil {
+			c.JSON(http.StatusInternalServerError, AdErrorResponse(err))
+			return
+		}
+		ids := make([]int64, 0, len(results))
+		for i := range results {
+			ids = append(ids, results[i].Ad.ID)
+		}
+		c.JSON(http.StatusOK, SearchSuccessResponse(results, favoriteCounts(c, a, ids), d))
 	}
 }
 
 func getAdByTitle(a app.App) gin.HandlerFunc {
 	return func(c *gin.Context) {
+		p, err := pageRequest(c)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, AdErrorResponse(err))
+			return
+		}
+		d, err := newPriceDisplay(c, a)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, AdErrorResponse(err))
+			return
+		}
 		title := c.Param("title")
-		l, err := a.GetAdsByFilter(c, app.FilterOpts{Title: title})
-		if err == nil && len(l) == 0 {
+		page, err := a.GetAdsByFilter(c, app.FilterOpts{TitleContains: title}, p)
+		if err == nil && len(page.Ads) == 0 && p.Cursor == "" {
 			c.JSON(http.StatusNotFound, AdErrorResponse(nil))
 			return
+		} else if err == app.ErrInvalidCursor || err == app.ErrInvalidSort || err == app.ErrInvalidLocation {
+			c.JSON(http.StatusBadRequest, AdErrorResponse(err))
+			return
 		} else if err != nil {
 			c.JSON(http.StatusInternalServerError, AdErrorResponse(err))
 			return
 		}
-		c.JSON(http.StatusOK, MultipleAdsSuccessResponse(l))
+		c.JSON(http.StatusOK, AdsPageSuccessResponse(page, favoriteCounts(c, a, adIDs(page.Ads)), d))
 	}
 }
 
 // Метод для обновления текста(Text) или заголовка(Title) объявления
-func updateAd(a app.App) gin.HandlerFunc {
+func updateAd(a app.App, limits Limits) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		var reqBody updateAdRequest
 		if err := c.Bind(&reqBody); err != nil {
@@ -131,89 +240,195 @@ func updateAd(a app.App) gin.HandlerFunc {
 			return
 		}
 
-		_, err := a.GetUserByID(c, reqBody.UserID)
-		if err == app.ErrNotFound {
-			c.JSON(http.StatusNotFound, AdErrorResponse(err))
-			return
-		} else if err != nil {
-			c.JSON(http.StatusInternalServerError, AdErrorResponse(err))
-			return
-		}
-
 		adID, err := strconv.Atoi(c.Param("ad_id"))
 		if err != nil {
 			c.JSON(http.StatusBadRequest, AdErrorResponse(err))
 			return
 		}
 
-		if err := reqBody.Validate(); err != nil {
+		if err := reqBody.Validate(limits); err != nil {
 			c.JSON(http.StatusBadRequest, AdErrorResponse(err))
 			return
 		}
 
-		u, err := a.UpdateAdById(c, int64(adID), reqBody.Title, reqBody.Text, reqBody.UserID)
-		if err == app.ErrAccessDenied {
+		// If-Match превращаем в ожидаемую версию, которую приложение проверит атомарно вместе с записью
+		var version int64
+		ifMatch := c.GetHeader("If-Match")
+		if ifMatch != "" {
+			current, err := a.GetAdByID(c, int64(adID))
+			if err == app.ErrNotFound {
+				c.JSON(http.StatusPreconditionFailed, AdErrorResponse(err))
+				return
+			} else if err != nil {
+				c.JSON(http.StatusInternalServerError, AdErrorResponse(err))
+				return
+			}
+			if !etagMatches(ifMatch, current.Version, true) {
+				c.JSON(http.StatusPreconditionFailed, AdErrorResponse(app.ErrVersionConflict))
+				return
+			}
+			version = current.Version
+		}
+
+		u, err := a.UpdateAdById(c, int64(adID), reqBody.Title, reqBody.Text, reqBody.CategoryID, reqBody.newPrice(), reqBody.Location.location(), version)
+		if contentRejected(c, err) {
+			return
+		} else if err == app.ErrUnauthenticated {
+			unauthorized(c, err)
+			return
+		} else if err == app.ErrInvalidCategory || err == app.ErrInvalidPrice || err == app.ErrInvalidCurrency || err == app.ErrInvalidLocation {
+			c.JSON(http.StatusBadRequest, AdErrorResponse(err))
+			return
+		} else if err == app.ErrAccessDenied {
 			c.JSON(http.StatusForbidden, AdErrorResponse(err))
 			return
+		} else if err == app.ErrNotFound {
+			c.JSON(http.StatusNotFound, AdErrorResponse(err))
+			return
+		} else if err == app.ErrVersionConflict && ifMatch != "" {
+			c.JSON(http.StatusPreconditionFailed, AdErrorResponse(err))
+			return
+		} else if err == app.ErrVersionConflict {
+			c.JSON(http.StatusConflict, AdErrorResponse(err))
+			return
 		} else if err != nil {
 			c.JSON(http.StatusInternalServerError, AdErrorResponse(err))
 			return
 		}
 
-		c.JSON(http.StatusOK, AdSuccessResponse(u))
+		c.Header("ETag", etag(u.Version))
+		c.JSON(http.StatusOK, AdSuccessResponse(u, favoriteCount(c, a, u.ID), priceDisplay{}))
 	}
 }
 
-func createUser(a app.App) gin.HandlerFunc {
+func updateUser(a app.App) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		var reqBody createUserRequest
-		err := c.Bind(&reqBody)
-		if err != nil {
+		var reqBody updateUserRequest
+		if err := c.Bind(&reqBody); err != nil {
 			c.JSON(http.StatusBadRequest, UserErrorResponse(err))
 			return
 		}
 
-		u, err := a.CreateUser(c, reqBody.Nickname, reqBody.Email)
+		userID, err := strconv.Atoi(c.Param("user_id"))
 		if err != nil {
+			c.JSON(http.StatusBadRequest, UserErrorResponse(err))
+			return
+		}
+
+		u, err := a.UpdateUserByID(c, int64(userID), reqBody.Nickname, reqBody.Email)
+		if err == app.ErrUnauthenticated {
+			unauthorized(c, err)
+			return
+		} else if err == app.ErrAccessDenied {
+			c.JSON(http.StatusForbidden, UserErrorResponse(err))
+			return
+		} else if err == app.ErrNotFound {
+			c.JSON(http.StatusNotFound, UserErrorResponse(err))
+			return
+		} else if err == app.ErrInvalidEmail {
+			c.JSON(http.StatusBadRequest, UserErrorResponse(err))
+			return
+		} else if err == app.ErrEmailTaken || err == app.ErrVersionConflict {
+			c.JSON(http.StatusConflict, UserErrorResponse(err))
+			return
+		} else if err != nil {
 			c.JSON(http.StatusInternalServerError, UserErrorResponse(err))
 			return
 		}
+
 		c.JSON(http.StatusOK, UserSuccessResponse(u))
 	}
 }
 
-func updateUser(a app.App) gin.HandlerFunc {
+// Метод для удаления объявления автором или администратором
+func deleteAd(a app.App) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		var reqBody updateUserRequest
-		if err := c.Bind(&reqBody); err != nil {
-			c.JSON(http.StatusBadRequest, UserErrorResponse(err))
+		adID, err := strconv.Atoi(c.Param("ad_id"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, AdErrorResponse(err))
 			return
 		}
 
-		_, err := a.GetUserByID(c, reqBody.UserID)
-		if err == app.ErrNotFound {
+		err = a.DeleteAd(c, int64(adID))
+		if err == app.ErrUnauthenticated {
+			unauthorized(c, err)
+			return
+		} else if err == app.ErrAccessDenied {
+			c.JSON(http.StatusForbidden, AdErrorResponse(err))
+			return
+		} else if err == app.ErrNotFound {
 			c.JSON(http.StatusNotFound, AdErrorResponse(err))
 			return
 		} else if err != nil {
 			c.JSON(http.StatusInternalServerError, AdErrorResponse(err))
 			return
 		}
+		c.JSON(http.StatusOK, gin.H{"data": nil, "error": nil})
+	}
+}
 
+// Метод для удаления пользователя вместе с его объявлениями: себя или любого, если это администратор
+func deleteUser(a app.App) gin.HandlerFunc {
+	return func(c *gin.Context) {
 		userID, err := strconv.Atoi(c.Param("user_id"))
 		if err != nil {
 			c.JSON(http.StatusBadRequest, UserErrorResponse(err))
 			return
 		}
 
-		u, err := a.UpdateUserByID(c, int64(userID), reqBody.Nickname, reqBody.Email, reqBody.UserID)
-		if err == app.ErrAccessDenied {
+		err = a.DeleteUser(c, int64(userID))
+		if err == app.ErrUnauthenticated {
+			unauthorized(c, err)
+			return
+		} else if err == app.ErrAccessDenied {
 			c.JSON(http.StatusForbidden, UserErrorResponse(err))
 			return
+		} else if err == app.ErrNotFound {
+			c.JSON(http.StatusNotFound, UserErrorResponse(err))
+			return
 		} else if err != nil {
 			c.JSON(http.StatusInternalServerError, UserErrorResponse(err))
 			return
 		}
+		c.JSON(http.StatusOK, gin.H{"data": nil, "error": nil})
+	}
+}
+
+// Метод для назначения роли, доступен администраторам
+func setUserRole(a app.App) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var reqBody setRoleRequest
+		if err := c.Bind(&reqBody); err != nil {
+			c.JSON(http.StatusBadRequest, UserErrorResponse(err))
+			return
+		}
 
+		userID, err := strconv.Atoi(c.Param("user_id"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, UserErrorResponse(err))
+			return
+		}
+
+		u, err := a.SetUserRole(c, int64(userID), users.Role(reqBody.Role))
+		if err == app.ErrUnauthenticated {
+			unauthorized(c, err)
+			return
+		} else if err == app.ErrAccessDenied {
+			c.JSON(http.StatusForbidden, UserErrorResponse(err))
+			return
+		} else if err == app.ErrNotFound {
+			c.JSON(http.StatusNotFound, UserErrorResponse(err))
+			return
+		} else if err == app.ErrInvalidRole {
+			c.JSON(http.StatusBadRequest, UserErrorResponse(err))
+			return
+		} else if err == app.ErrVersionConflict {
+			c.JSON(http.StatusConflict, UserErrorResponse(err))
+			return
+		} else if err != nil {
+			c.JSON(http.StatusInternalServerError, UserErrorResponse(err))
+			return
+		}
 		c.JSON(http.StatusOK, UserSuccessResponse(u))
 	}
 }
@@ -225,21 +440,56 @@ func getAdsByFilter(a app.App) gin.HandlerFunc {
 			c.JSON(http.StatusBadRequest, UserErrorResponse(err))
 			return
 		}
-		opts := app.FilterOpts{
-			ID:           reqBody.ID,
-			Title:        reqBody.Title,
-			CreatedTime:  reqBody.CreatedTime,
-			AuthorID:     reqBody.AuthorID,
-			ModifiedTime: reqBody.ModifiedTime,
-			Hidden:       !reqBody.Published,
+		p, err := pageRequest(c)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, AdErrorResponse(err))
+			return
+		}
+		d, err := newPriceDisplay(c, a)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, AdErrorResponse(err))
+			return
 		}
 
-		l, err := a.GetAdsByFilter(c, opts)
-		if err != nil {
+		page, err := a.GetAdsByFilter(c, reqBody.filter(), p)
+		if err == app.ErrInvalidCursor || err == app.ErrInvalidSort || err == app.ErrInvalidCategory ||
+			err == app.ErrInvalidPrice || err == app.ErrInvalidCurrency || err == app.ErrInvalidArea || err == app.ErrInvalidLocation {
+			c.JSON(http.StatusBadRequest, AdErrorResponse(err))
+			return
+		} else if err != nil {
 			c.JSON(http.StatusInternalServerError, AdErrorResponse(err))
 			return
 		}
 
-		c.JSON(http.StatusOK, MultipleAdsSuccessResponse(l))
+		c.JSON(http.StatusOK, AdsPageSuccessResponse(page, favoriteCounts(c, a, adIDs(page.Ads)), d))
+	}
+}
+
+// queryNear читает радиус поиска radius_km вокруг точки origin из lat и lon. Без radius_km условия нет
+func queryNear(c *gin.Context, origin *geo.Point) (*geo.Circle, error) {
+	v := c.Query("radius_km")
+	if v == "" {
+		return nil, nil
+	}
+	radius, err := strconv.ParseFloat(v, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid radius_km %q", v)
+	}
+	if origin == nil {
+		return nil, fmt.Errorf("radius_km requires lat and lon")
+	}
+	return &geo.Circle{Center: *origin, RadiusKm: radius}, nil
+}
+
+// queryID читает необязательный числовой параметр query, отсутствующий параметр - 0
+func queryID(c *gin.Context, name string) (int64, error) {
+	v := c.Query(name)
+	if v == "" {
+		return 0, nil
+	}
+	id, err := strconv.ParseInt(v, 10, 64)
+	if err != nil || id < 0 {
+		return 0, fmt.Errorf("invalid %s %q", name, v)
 	}
+	return id, nil
 }
@@ -0,0 +1,194 @@
+package httpgin
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"homework9/internal/ads"
+	"homework9/internal/app"
+)
+
+// imageField - имя поля multipart-формы с файлом фотографии
+const imageField = "image"
+
+var errNoImage = errors.New("multipart form must contain an image field")
+
+// imageResponse: url и thumb_url - адреса оригинала и превью в этом API. Файлы по ним не меняются,
+// поэтому их можно кешировать сколько угодно
+type imageResponse struct {
+	ID          string `json:"id"`
+	ContentType string `json:"content_type"`
+	Size        int64  `json:"size"`
+	Width       int    `json:"width"`
+	Height      int    `json:"height"`
+	URL         string `json:"url"`
+	ThumbURL    string `json:"thumb_url"`
+}
+
+func newImagesResponse(ad *ads.Ad) []imageResponse {
+	res := make([]imageResponse, 0, len(ad.Images))
+	for _, image := range ad.Images {
+		url := fmt.Sprintf("/api/v1/ads/%d/images/%s", ad.ID, image.ID)
+		res = append(res, imageResponse{
+			ID:          image.ID,
+			ContentType: image.ContentType,
+			Size:        image.Size,
+			Width:       image.Width,
+			Height:      image.Height,
+			URL:         url,
+			ThumbURL:    url + "/thumb",
+		})
+	}
+	return res
+}
+
+type reorderImagesRequest struct {
+	Order []string `json:"order"`
+}
+
+// imageError отвечает на ошибку методов фотографий
+func imageError(c *gin.Context, err error) {
+	switch err {
+	case app.ErrUnauthenticated:
+		unauthorized(c, err)
+	case app.ErrAccessDenied:
+		c.JSON(http.StatusForbidden, AdErrorResponse(err))
+	case app.ErrNotFound:
+		c.JSON(http.StatusNotFound, AdErrorResponse(err))
+	case app.ErrVersionConflict:
+		c.JSON(http.StatusConflict, AdErrorResponse(err))
+	case app.ErrTooManyImages, app.ErrImageOrder, errNoImage:
+		c.JSON(http.StatusBadRequest, AdErrorResponse(err))
+	case app.ErrImageTooLarge:
+		c.JSON(http.StatusRequestEntityTooLarge, AdErrorResponse(err))
+	case app.ErrUnsupportedImage:
+		c.JSON(http.StatusUnsupportedMediaType, AdErrorResponse(err))
+	case app.ErrImagesDisabled:
+		c.JSON(http.StatusNotImplemented, AdErrorResponse(err))
+	default:
+		c.JSON(http.StatusInternalServerError, AdErrorResponse(err))
+	}
+}
+
+// imagePart находит в multipart-форме поле image, не читая файл в память целиком.
+// Заявленный клиентом Content-Type части должен быть image/*, настоящий формат приложение определит по содержимому
+func imagePart(c *gin.Context) (io.Reader, error) {
+	form, err := c.Request.MultipartReader()
+	if err != nil {
+		return nil, errNoImage
+	}
+	for {
+		part, err := form.NextPart()
+		if err == io.EOF {
+			return nil, errNoImage
+		} else if err != nil {
+			return nil, err
+		}
+		if part.FormName() != imageField {
+			continue
+		}
+		if declared := part.Header.Get("Content-Type"); declared != "" {
+			mediaType, _, err := mime.ParseMediaType(declared)
+			if err != nil || !strings.HasPrefix(mediaType, "image/") {
+				return nil, app.ErrUnsupportedImage
+			}
+		}
+		return part, nil
+	}
+}
+
+// Метод для загрузки фотографии объявления: multipart/form-data с файлом в поле image.
+// Фотография добавляется в конец списка, в ответе - объявление целиком
+func uploadAdImage(a app.App) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		adID, err := strconv.ParseInt(c.Param("ad_id"), 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, AdErrorResponse(err))
+			return
+		}
+		part, err := imagePart(c)
+		if err != nil {
+			imageError(c, err)
+			return
+		}
+		ad, err := a.AddAdImage(c, adID, part)
+		if err != nil {
+			imageError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, AdSuccessResponse(ad, favoriteCount(c, a, ad.ID), priceDisplay{}))
+	}
+}
+
+// Метод для смены порядка фотографий: {"order": [id, ...]} со всеми фотографиями объявления
+func reorderAdImages(a app.App) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		adID, err := strconv.ParseInt(c.Param("ad_id"), 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, AdErrorResponse(err))
+			return
+		}
+		var reqBody reorderImagesRequest
+		if err := c.Bind(&reqBody); err != nil {
+			c.JSON(http.StatusBadRequest, AdErrorResponse(err))
+			return
+		}
+		ad, err := a.ReorderAdImages(c, adID, reqBody.Order)
+		if err != nil {
+			imageError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, AdSuccessResponse(ad, favoriteCount(c, a, ad.ID), priceDisplay{}))
+	}
+}
+
+func deleteAdImage(a app.App) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		adID, err := strconv.ParseInt(c.Param("ad_id"), 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, AdErrorResponse(err))
+			return
+		}
+		ad, err := a.DeleteAdImage(c, adID, c.Param("image_id"))
+		if err != nil {
+			imageError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, AdSuccessResponse(ad, favoriteCount(c, a, ad.ID), priceDisplay{}))
+	}
+}
+
+// Метод для скачивания оригинала фотографии или, если thumb, её превью в JPEG
+func getAdImage(a app.App, thumb bool) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		adID, err := strconv.ParseInt(c.Param("ad_id"), 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, AdErrorResponse(err))
+			return
+		}
+		f, image, err := a.OpenAdImage(c, adID, c.Param("image_id"), thumb)
+		if err != nil {
+			imageError(c, err)
+			return
+		}
+		defer f.Close()
+
+		contentType, size := image.ContentType, image.Size
+		if thumb {
+			contentType, size = "image/jpeg", -1
+		}
+		cache := "public, max-age=31536000, immutable"
+		if _, ok := app.UserIDFromContext(c); ok {
+			// с токеном отдаются и фотографии скрытых объявлений, общим кэшам их хранить нельзя
+			cache = "private, max-age=31536000, immutable"
+		}
+		c.DataFromReader(http.StatusOK, size, contentType, f, map[string]string{"Cache-Control": cache})
+	}
+}
@@ -0,0 +1,254 @@
+package httpgin
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"homework9/internal/app"
+	"homework9/internal/messages"
+)
+
+type messageRequest struct {
+	Text string `json:"text"`
+}
+
+// threadResponse: unread - сколько сообщений собеседника текущий пользователь не прочитал,
+// blocked - кто-то из участников заблокировал другого
+type threadResponse struct {
+	ID            int64     `json:"id"`
+	AdID          int64     `json:"ad_id"`
+	SellerID      int64     `json:"seller_id"`
+	BuyerID       int64     `json:"buyer_id"`
+	CreatedAt     time.Time `json:"created_at"`
+	LastMessageAt time.Time `json:"last_message_at"`
+	Unread        int       `json:"unread"`
+	Blocked       bool      `json:"blocked"`
+}
+
+// messageResponse: read - сообщение прочитал получатель
+type messageResponse struct {
+	ID       int64     `json:"id"`
+	ThreadID int64     `json:"thread_id"`
+	SenderID int64     `json:"sender_id"`
+	Text     string    `json:"text"`
+	SentAt   time.Time `json:"sent_at"`
+	Read     bool      `json:"read"`
+}
+
+func newThreadResponse(t *app.ThreadView) threadResponse {
+	return threadResponse{
+		ID:            t.ID,
+		AdID:          t.AdID,
+		SellerID:      t.SellerID,
+		BuyerID:       t.BuyerID,
+		CreatedAt:     t.Created,
+		LastMessageAt: t.LastMessageAt,
+		Unread:        t.Unread,
+		Blocked:       t.Blocked,
+	}
+}
+
+func newMessageResponse(m *messages.Message, read bool) messageResponse {
+	return messageResponse{ID: m.ID, ThreadID: m.ThreadID, SenderID: m.SenderID, Text: m.Text, SentAt: m.Sent, Read: read}
+}
+
+// MessageSuccessResponse - только что отправленное сообщение, получатель его ещё не прочитал
+func MessageSuccessResponse(m *messages.Message) *gin.H {
+	return &gin.H{
+		"data":  newMessageResponse(m, false),
+		"error": nil,
+	}
+}
+
+func ThreadSuccessResponse(t *app.ThreadView) *gin.H {
+	return &gin.H{
+		"data":  newThreadResponse(t),
+		"error": nil,
+	}
+}
+
+func ThreadsPageSuccessResponse(page *app.ThreadsPage) *gin.H {
+	res := make([]threadResponse, 0, len(page.Threads))
+	for i := range page.Threads {
+		res = append(res, newThreadResponse(&page.Threads[i]))
+	}
+	var next *string
+	if page.NextCursor != "" {
+		next = &page.NextCursor
+	}
+	return &gin.H{
+		"data":        res,
+		"next_cursor": next,
+		"error":       nil,
+	}
+}
+
+// MessagesPageSuccessResponse отдаёт сообщения в data, а саму переписку - в thread
+func MessagesPageSuccessResponse(page *app.MessagesPage) *gin.H {
+	res := make([]messageResponse, 0, len(page.Messages))
+	for i := range page.Messages {
+		res = append(res, newMessageResponse(&page.Messages[i], page.Thread.ReadBy(page.Messages[i])))
+	}
+	var next *string
+	if page.NextCursor != "" {
+		next = &page.NextCursor
+	}
+	return &gin.H{
+		"data":        res,
+		"thread":      newThreadResponse(&page.Thread),
+		"next_cursor": next,
+		"error":       nil,
+	}
+}
+
+// messageError отвечает на ошибку методов переписки
+func messageError(c *gin.Context, err error) {
+	switch err {
+	case app.ErrUnauthenticated:
+		unauthorized(c, err)
+	case app.ErrAccessDenied, app.ErrConversationBlocked:
+		c.JSON(http.StatusForbidden, AdErrorResponse(err))
+	case app.ErrNotFound:
+		c.JSON(http.StatusNotFound, AdErrorResponse(err))
+	case app.ErrThreadUnavailable:
+		c.JSON(http.StatusConflict, AdErrorResponse(err))
+	case app.ErrInvalidMessage, app.ErrSelfMessage, app.ErrInvalidCursor:
+		c.JSON(http.StatusBadRequest, AdErrorResponse(err))
+	case app.ErrMessagesDisabled:
+		c.JSON(http.StatusNotImplemented, AdErrorResponse(err))
+	default:
+		c.JSON(http.StatusInternalServerError, AdErrorResponse(err))
+	}
+}
+
+// contactSeller - метод для сообщения автору объявления, первое сообщение начинает переписку
+func contactSeller(a app.App) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		adID, err := strconv.ParseInt(c.Param("ad_id"), 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, AdErrorResponse(err))
+			return
+		}
+		var reqBody messageRequest
+		if err := c.Bind(&reqBody); err != nil {
+			c.JSON(http.StatusBadRequest, AdErrorResponse(err))
+			return
+		}
+		m, err := a.ContactSeller(c, adID, reqBody.Text)
+		if err != nil {
+			messageError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, MessageSuccessResponse(m))
+	}
+}
+
+func sendMessage(a app.App) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		threadID, err := strconv.ParseInt(c.Param("thread_id"), 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, AdErrorResponse(err))
+			return
+		}
+		var reqBody messageRequest
+		if err := c.Bind(&reqBody); err != nil {
+			c.JSON(http.StatusBadRequest, AdErrorResponse(err))
+			return
+		}
+		m, err := a.SendMessage(c, threadID, reqBody.Text)
+		if err != nil {
+			messageError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, MessageSuccessResponse(m))
+	}
+}
+
+// Метод для просмотра входящих: ad_id - переписки об одном объявлении, limit и cursor
+func listThreads(a app.App) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		p, err := idPageRequest(c)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, AdErrorResponse(err))
+			return
+		}
+		var adID *int64
+		if v := c.Query("ad_id"); v != "" {
+			id, err := strconv.ParseInt(v, 10, 64)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, AdErrorResponse(fmt.Errorf("invalid ad_id %q", v)))
+				return
+			}
+			adID = &id
+		}
+		page, err := a.ListThreads(c, adID, p)
+		if err != nil {
+			messageError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, ThreadsPageSuccessResponse(page))
+	}
+}
+
+// Метод для просмотра сообщений переписки от старых к новым: limit и cursor. Прочитанными они не отмечаются
+func listMessages(a app.App) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		threadID, err := strconv.ParseInt(c.Param("thread_id"), 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, AdErrorResponse(err))
+			return
+		}
+		p, err := idPageRequest(c)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, AdErrorResponse(err))
+			return
+		}
+		page, err := a.ListMessages(c, threadID, p)
+		if err != nil {
+			messageError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, MessagesPageSuccessResponse(page))
+	}
+}
+
+func markThreadRead(a app.App) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		threadID, err := strconv.ParseInt(c.Param("thread_id"), 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, AdErrorResponse(err))
+			return
+		}
+		t, err := a.MarkThreadRead(c, threadID)
+		if err != nil {
+			messageError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, ThreadSuccessResponse(t))
+	}
+}
+
+// blockUser - метод для блокировки (block = true) и разблокировки собеседника
+func blockUser(a app.App, block bool) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, err := strconv.ParseInt(c.Param("user_id"), 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, AdErrorResponse(err))
+			return
+		}
+		if block {
+			err = a.BlockUser(c, userID)
+		} else {
+			err = a.UnblockUser(c, userID)
+		}
+		if err != nil {
+			messageError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"data": nil, "error": nil})
+	}
+}
@@ -0,0 +1,93 @@
+package httpgin
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"homework9/internal/ads"
+	"homework9/internal/app"
+)
+
+// reviewRequest: reason - причина отклонения или комментарий к одобрению
+type reviewRequest struct {
+	Reason string `json:"reason"`
+}
+
+// reviewResponse - последнее решение модератора
+type reviewResponse struct {
+	ModeratorID int64     `json:"moderator_id"`
+	Reason      string    `json:"reason"`
+	ReviewedAt  time.Time `json:"reviewed_at"`
+}
+
+// newReviewResponse возвращает nil, если объявление ещё не проверяли
+func newReviewResponse(r ads.Review) *reviewResponse {
+	if r.At.IsZero() {
+		return nil
+	}
+	return &reviewResponse{ModeratorID: r.ModeratorID, Reason: r.Reason, ReviewedAt: r.At}
+}
+
+// moderationError отвечает на ошибку методов модерации
+func moderationError(c *gin.Context, err error) {
+	switch err {
+	case app.ErrUnauthenticated:
+		unauthorized(c, err)
+	case app.ErrAccessDenied:
+		c.JSON(http.StatusForbidden, AdErrorResponse(err))
+	case app.ErrNotFound:
+		c.JSON(http.StatusNotFound, AdErrorResponse(err))
+	case app.ErrVersionConflict, app.ErrInvalidTransition:
+		c.JSON(http.StatusConflict, AdErrorResponse(err))
+	case app.ErrInvalidReason, app.ErrInvalidCursor, app.ErrInvalidSort:
+		c.JSON(http.StatusBadRequest, AdErrorResponse(err))
+	default:
+		c.JSON(http.StatusInternalServerError, AdErrorResponse(err))
+	}
+}
+
+// Метод для просмотра очереди модерации, параметры страницы как у списка объявлений
+func reviewQueue(a app.App) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		p, err := pageRequest(c)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, AdErrorResponse(err))
+			return
+		}
+		page, err := a.ReviewQueue(c, p)
+		if err != nil {
+			moderationError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, AdsPageSuccessResponse(page, favoriteCounts(c, a, adIDs(page.Ads)), priceDisplay{}))
+	}
+}
+
+// reviewAd - метод для одобрения (approve) или отклонения объявления
+func reviewAd(a app.App, approve bool) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		adID, err := strconv.ParseInt(c.Param("ad_id"), 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, AdErrorResponse(err))
+			return
+		}
+		var reqBody reviewRequest
+		if err := c.Bind(&reqBody); err != nil {
+			c.JSON(http.StatusBadRequest, AdErrorResponse(err))
+			return
+		}
+		review := a.RejectAd
+		if approve {
+			review = a.ApproveAd
+		}
+		ad, err := review(c, adID, reqBody.Reason)
+		if err != nil {
+			moderationError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, AdSuccessResponse(ad, favoriteCount(c, a, ad.ID), priceDisplay{}))
+	}
+}
@@ -1,86 +1,355 @@
 package httpgin
 
 import (
+	"context"
+	"fmt"
 	"github.com/gin-gonic/gin"
 	validation "github.com/unicoooorn/tag_validation"
 	"homework9/internal/ads"
+	"homework9/internal/app"
+	"homework9/internal/geo"
+	"homework9/internal/money"
 	"homework9/internal/users"
+	"strconv"
 	"time"
 )
 
+// Limits - допустимая длина заголовка и текста объявления в байтах
+type Limits struct {
+	TitleMin int
+	TitleMax int
+	TextMin  int
+	TextMax  int
+}
+
+var DefaultLimits = Limits{TitleMin: 1, TitleMax: 100, TextMin: 1, TextMax: 500}
+
+func (l Limits) validateAd(title string, text string) error {
+	var vs validation.ValidationErrors
+	if len(title) < l.TitleMin || len(title) > l.TitleMax {
+		vs = append(vs, validation.ValidationError{Err: fmt.Errorf("title length must be between %d and %d", l.TitleMin, l.TitleMax)})
+	}
+	if len(text) < l.TextMin || len(text) > l.TextMax {
+		vs = append(vs, validation.ValidationError{Err: fmt.Errorf("text length must be between %d and %d", l.TextMin, l.TextMax)})
+	}
+	if len(vs) > 0 {
+		return vs
+	}
+	return nil
+}
+
+// priceRequest - цена объявления: amount в минимальных единицах валюты (копейках, центах),
+// currency - код ISO 4217. Без currency amount должен быть 0, negotiable - цена договорная
+type priceRequest struct {
+	Amount     int64  `json:"amount"`
+	Currency   string `json:"currency"`
+	Negotiable bool   `json:"negotiable"`
+}
+
+func (p *priceRequest) price() ads.Price {
+	if p == nil {
+		return ads.Price{}
+	}
+	return ads.Price{Amount: p.Amount, Currency: p.Currency, Negotiable: p.Negotiable}
+}
+
+func validatePrice(p *priceRequest) error {
+	if p == nil {
+		return nil
+	}
+	var vs validation.ValidationErrors
+	currency := money.Normalize(p.Currency)
+	if currency != "" && !money.Known(currency) {
+		vs = append(vs, validation.ValidationError{Err: fmt.Errorf("%w %q", app.ErrInvalidCurrency, p.Currency)})
+	}
+	if p.Amount < 0 || p.Amount > money.MaxAmount {
+		vs = append(vs, validation.ValidationError{Err: fmt.Errorf("price amount must be between 0 and %d", money.MaxAmount)})
+	} else if currency == "" && p.Amount != 0 {
+		vs = append(vs, validation.ValidationError{Err: fmt.Errorf("price amount requires a currency")})
+	}
+	if len(vs) > 0 {
+		return vs
+	}
+	return nil
+}
+
+// locationRequest - место объявления: координаты в градусах и название города
+type locationRequest struct {
+	Lat  float64 `json:"lat"`
+	Lon  float64 `json:"lon"`
+	City string  `json:"city"`
+}
+
+func (l *locationRequest) location() *ads.Location {
+	if l == nil {
+		return nil
+	}
+	return &ads.Location{Lat: l.Lat, Lon: l.Lon, City: l.City}
+}
+
 type createAdRequest struct {
-	Title  string `json:"title" validate:"between:1,100"`
-	Text   string `json:"text" validate:"between:1,500"`
-	UserID int64  `json:"user_id"`
+	Title      string           `json:"title"`
+	Text       string           `json:"text"`
+	CategoryID int64            `json:"category_id"`
+	Price      *priceRequest    `json:"price"`
+	Location   *locationRequest `json:"location"`
 }
 
-func (c createAdRequest) Validate() error {
-	return validation.Validate(c)
+func (c createAdRequest) Validate(l Limits) error {
+	if err := l.validateAd(c.Title, c.Text); err != nil {
+		return err
+	}
+	return validatePrice(c.Price)
 }
 
-type createUserRequest struct {
+type registerRequest struct {
 	Nickname string `json:"nickname"`
 	Email    string `json:"email"`
+	Password string `json:"password"`
 }
 
+type loginRequest struct {
+	Email    string `json:"email"`
+	Password string `json:"password"`
+}
+
+type refreshRequest struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+type tokensResponse struct {
+	UserID           int64     `json:"user_id"`
+	TokenType        string    `json:"token_type"`
+	AccessToken      string    `json:"access_token"`
+	ExpiresAt        time.Time `json:"expires_at"`
+	RefreshToken     string    `json:"refresh_token"`
+	RefreshExpiresAt time.Time `json:"refresh_expires_at"`
+}
+
+type priceResponse struct {
+	Amount     int64  `json:"amount"`
+	Currency   string `json:"currency"`
+	Negotiable bool   `json:"negotiable"`
+}
+
+// newPriceResponse возвращает nil для объявления без цены
+func newPriceResponse(p ads.Price) *priceResponse {
+	if p == (ads.Price{}) {
+		return nil
+	}
+	return &priceResponse{Amount: p.Amount, Currency: p.Currency, Negotiable: p.Negotiable}
+}
+
+type locationResponse struct {
+	Lat  float64 `json:"lat"`
+	Lon  float64 `json:"lon"`
+	City string  `json:"city"`
+}
+
+// newLocationResponse возвращает nil для объявления без места
+func newLocationResponse(l *ads.Location) *locationResponse {
+	if l == nil {
+		return nil
+	}
+	return &locationResponse{Lat: l.Lat, Lon: l.Lon, City: l.City}
+}
+
+// adResponse: display_price - цена в валюте из display_currency, null без параметра или без курса.
+// publish_at и expires_at - запланированные публикация и снятие, null - срок не задан.
+// status - этап модерации, review - последнее решение модератора, null - объявление не проверяли.
+// flags - замечания проверок содержимого, с которыми объявление ждёт модератора.
+// review и flags видны только автору и модераторам: остальным приходят null и пустой список.
+// favorites - у скольких пользователей объявление в избранном, версию объявления это число не меняет
 type adResponse struct {
-	ID           int64     `json:"id"`
-	Title        string    `json:"title"`
-	Text         string    `json:"text"`
-	AuthorID     int64     `json:"author_id"`
-	Published    bool      `json:"published"`
-	CreatedTime  time.Time `json:"created_time"`
-	ModifiedTime time.Time `json:"modified_time"`
+	ID           int64             `json:"id"`
+	Title        string            `json:"title"`
+	Text         string            `json:"text"`
+	AuthorID     int64             `json:"author_id"`
+	CategoryID   int64             `json:"category_id"`
+	Price        *priceResponse    `json:"price"`
+	DisplayPrice *priceResponse    `json:"display_price,omitempty"`
+	Images       []imageResponse   `json:"images"`
+	Location     *locationResponse `json:"location"`
+	Status       string            `json:"status"`
+	Published    bool              `json:"published"`
+	Review       *reviewResponse   `json:"review"`
+	Flags        []flagResponse    `json:"flags"`
+	Favorites    int               `json:"favorites"`
+	CreatedTime  time.Time         `json:"created_time"`
+	ModifiedTime time.Time         `json:"modified_time"`
+	PublishAt    *time.Time        `json:"publish_at"`
+	ExpiresAt    *time.Time        `json:"expires_at"`
+	Version      int64             `json:"version"`
+}
+
+// priceDisplay переводит цены в валюту показа. Нулевой priceDisplay цены не переводит
+type priceDisplay struct {
+	ctx      context.Context
+	app      app.App
+	currency string
 }
 
+// newPriceDisplay читает валюту показа из query-параметра display_currency
+func newPriceDisplay(c *gin.Context, a app.App) (priceDisplay, error) {
+	currency := money.Normalize(c.Query("display_currency"))
+	if currency == "" {
+		return priceDisplay{}, nil
+	}
+	if !money.Known(currency) {
+		return priceDisplay{}, fmt.Errorf("%w %q", app.ErrInvalidCurrency, c.Query("display_currency"))
+	}
+	return priceDisplay{ctx: c, app: a, currency: currency}, nil
+}
+
+func (d priceDisplay) price(p ads.Price) *priceResponse {
+	if d.app == nil || p.Currency == "" {
+		return nil
+	}
+	converted, err := d.app.ConvertPrice(d.ctx, p, d.currency)
+	if err != nil {
+		// без курса цена показывается только в исходной валюте
+		return nil
+	}
+	return newPriceResponse(converted)
+}
+
+// findAdsRequest - фильтр объявлений. Все условия необязательные и объединяются через И.
+// published: true - только опубликованные, false - только снятые, отсутствует - любые.
+// Диапазоны времени полуоткрытые: [from, to). title и text ищут подстроку без учёта регистра.
+// category_ids отбирает объявления из этих категорий и всех их подкатегорий.
+// price_min и price_max - границы цены в минимальных единицах currency (по умолчанию базовой валюты), обе включаются.
+// near - не дальше radius_km километров от точки, box - внутри прямоугольника координат
 type findAdsRequest struct {
-	ID           int64     `json:"id"`
-	Title        string    `json:"title"`
-	Text         string    `json:"text"`
-	AuthorID     int64     `json:"author_id"`
-	Published    bool      `json:"published"`
-	CreatedTime  time.Time `json:"created_time"`
-	ModifiedTime time.Time `json:"modified_time"`
+	AuthorIDs    []int64      `json:"author_ids"`
+	CategoryIDs  []int64      `json:"category_ids"`
+	Published    *bool        `json:"published"`
+	CreatedFrom  time.Time    `json:"created_from"`
+	CreatedTo    time.Time    `json:"created_to"`
+	ModifiedFrom time.Time    `json:"modified_from"`
+	ModifiedTo   time.Time    `json:"modified_to"`
+	Title        string       `json:"title"`
+	Text         string       `json:"text"`
+	Currency     string       `json:"currency"`
+	PriceMin     *int64       `json:"price_min"`
+	PriceMax     *int64       `json:"price_max"`
+	Near         *nearRequest `json:"near"`
+	Box          *boxRequest  `json:"box"`
+}
+
+type nearRequest struct {
+	Lat      float64 `json:"lat"`
+	Lon      float64 `json:"lon"`
+	RadiusKm float64 `json:"radius_km"`
+}
+
+type boxRequest struct {
+	MinLat float64 `json:"min_lat"`
+	MinLon float64 `json:"min_lon"`
+	MaxLat float64 `json:"max_lat"`
+	MaxLon float64 `json:"max_lon"`
+}
+
+func (r findAdsRequest) filter() app.FilterOpts {
+	f := app.FilterOpts{
+		AuthorIDs:     r.AuthorIDs,
+		CategoryIDs:   r.CategoryIDs,
+		CreatedFrom:   r.CreatedFrom,
+		CreatedTo:     r.CreatedTo,
+		ModifiedFrom:  r.ModifiedFrom,
+		ModifiedTo:    r.ModifiedTo,
+		TitleContains: r.Title,
+		TextContains:  r.Text,
+		Currency:      r.Currency,
+		PriceMin:      r.PriceMin,
+		PriceMax:      r.PriceMax,
+	}
+	if r.Near != nil {
+		f.Near = &geo.Circle{Center: geo.Point{Lat: r.Near.Lat, Lon: r.Near.Lon}, RadiusKm: r.Near.RadiusKm}
+	}
+	if r.Box != nil {
+		f.Box = &geo.Box{MinLat: r.Box.MinLat, MinLon: r.Box.MinLon, MaxLat: r.Box.MaxLat, MaxLon: r.Box.MaxLon}
+	}
+	if r.Published != nil {
+		f.Published = ads.OnlyUnpublished
+		if *r.Published {
+			f.Published = ads.OnlyPublished
+		}
+	}
+	return f
 }
 
 type userResponse struct {
 	ID       int64  `json:"id"`
 	Nickname string `json:"nickname"`
 	Email    string `json:"email"`
+	Role     string `json:"role"`
 }
 
 type changeAdStatusRequest struct {
-	Published bool  `json:"published"`
-	UserID    int64 `json:"user_id"`
+	Published bool `json:"published"`
 }
 
+// updateAdRequest: без category_id категория объявления не меняется, без price - цена, без location - место
 type updateAdRequest struct {
-	Title  string `json:"title" validate:"between:1,100"`
-	Text   string `json:"text" validate:"between:1,500"`
-	UserID int64  `json:"user_id"`
+	Title      string           `json:"title"`
+	Text       string           `json:"text"`
+	CategoryID int64            `json:"category_id"`
+	Price      *priceRequest    `json:"price"`
+	Location   *locationRequest `json:"location"`
 }
 
 type updateUserRequest struct {
 	Nickname string `json:"nickname"`
 	Email    string `json:"email"`
-	UserID   int64  `json:"user_id"`
 }
 
-func (u updateAdRequest) Validate() error {
-	return validation.Validate(u)
+type setRoleRequest struct {
+	Role string `json:"role"`
 }
 
-func AdSuccessResponse(ad *ads.Ad) *gin.H {
+func (u updateAdRequest) Validate(l Limits) error {
+	if err := l.validateAd(u.Title, u.Text); err != nil {
+		return err
+	}
+	return validatePrice(u.Price)
+}
+
+// newPrice возвращает nil, если цену менять не нужно
+func (u updateAdRequest) newPrice() *ads.Price {
+	if u.Price == nil {
+		return nil
+	}
+	p := u.Price.price()
+	return &p
+}
+
+func newAdResponse(ad *ads.Ad, favorites int, d priceDisplay) adResponse {
+	return adResponse{
+		ID:           ad.ID,
+		Title:        ad.Title,
+		Text:         ad.Text,
+		AuthorID:     ad.AuthorID,
+		CategoryID:   ad.CategoryID,
+		Price:        newPriceResponse(ad.Price),
+		DisplayPrice: d.price(ad.Price),
+		Images:       newImagesResponse(ad),
+		Location:     newLocationResponse(ad.Location),
+		Status:       string(ad.EffectiveStatus()),
+		Published:    ad.Published,
+		Review:       newReviewResponse(ad.Review),
+		Flags:        newFlagsResponse(ad.Flags),
+		Favorites:    favorites,
+		CreatedTime:  ad.Created,
+		ModifiedTime: ad.Modified,
+		PublishAt:    newTimeResponse(ad.PublishAt),
+		ExpiresAt:    newTimeResponse(ad.ExpiresAt),
+		Version:      ad.Version,
+	}
+}
+
+func AdSuccessResponse(ad *ads.Ad, favorites int, d priceDisplay) *gin.H {
 	return &gin.H{
-		"data": adResponse{
-			ID:           ad.ID,
-			Title:        ad.Title,
-			Text:         ad.Text,
-			AuthorID:     ad.AuthorID,
-			Published:    ad.Published,
-			CreatedTime:  ad.Created,
-			ModifiedTime: ad.Modified,
-		},
+		"data":  newAdResponse(ad, favorites, d),
 		"error": nil,
 	}
 }
@@ -91,26 +360,113 @@ func UserSuccessResponse(user *users.User) *gin.H {
 			ID:       user.ID,
 			Nickname: user.Nickname,
 			Email:    user.Email,
+			Role:     string(user.EffectiveRole()),
 		},
 		"error": nil,
 	}
 }
 
-func MultipleAdsSuccessResponse(ads []ads.Ad) *gin.H {
+func TokensSuccessResponse(t *app.Tokens) *gin.H {
+	return &gin.H{
+		"data": tokensResponse{
+			UserID:           t.UserID,
+			TokenType:        "Bearer",
+			AccessToken:      t.AccessToken,
+			ExpiresAt:        t.AccessExpires,
+			RefreshToken:     t.RefreshToken,
+			RefreshExpiresAt: t.RefreshExpires,
+		},
+		"error": nil,
+	}
+}
+
+// AdsPageSuccessResponse - страница объявлений. next_cursor передаётся в параметре cursor
+// за следующей страницей и равен null на последней. favorites - число в избранном по ID объявлений
+func AdsPageSuccessResponse(page *app.AdsPage, favorites map[int64]int, d priceDisplay) *gin.H {
 	multipleAdsResponse := make([]adResponse, 0)
-	for _, ad := range ads {
-		multipleAdsResponse = append(multipleAdsResponse, adResponse{
-			ID:           ad.ID,
-			Title:        ad.Title,
-			Text:         ad.Text,
-			AuthorID:     ad.AuthorID,
-			Published:    ad.Published,
-			CreatedTime:  ad.Created,
-			ModifiedTime: ad.Modified,
-		})
+	for i := range page.Ads {
+		multipleAdsResponse = append(multipleAdsResponse, newAdResponse(&page.Ads[i], favorites[page.Ads[i].ID], d))
+	}
+	var next *string
+	if page.NextCursor != "" {
+		next = &page.NextCursor
+	}
+	return &gin.H{
+		"data":        multipleAdsResponse,
+		"next_cursor": next,
+		"error":       nil,
+	}
+}
+
+var sortFields = map[string]ads.SortField{
+	"":         ads.SortByID,
+	"id":       ads.SortByID,
+	"created":  ads.SortByCreated,
+	"modified": ads.SortByModified,
+	"title":    ads.SortByTitle,
+	"price":    ads.SortByPrice,
+	"distance": ads.SortByDistance,
+}
+
+// pageRequest читает параметры страницы из query: sort (id, created, modified, title, price, distance),
+// order (asc, desc), limit и cursor. lat и lon задают точку, от которой считается расстояние
+func pageRequest(c *gin.Context) (app.PageRequest, error) {
+	field, ok := sortFields[c.Query("sort")]
+	if !ok {
+		return app.PageRequest{}, fmt.Errorf("%w %q", app.ErrInvalidSort, c.Query("sort"))
+	}
+	origin, err := queryPoint(c)
+	if err != nil {
+		return app.PageRequest{}, err
+	}
+	p := app.PageRequest{Sort: ads.Sort{Field: field, Origin: origin}, Cursor: c.Query("cursor")}
+	switch order := c.Query("order"); order {
+	case "", "asc":
+	case "desc":
+		p.Sort.Desc = true
+	default:
+		return app.PageRequest{}, fmt.Errorf("invalid order %q", order)
+	}
+	if v := c.Query("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			return app.PageRequest{}, fmt.Errorf("invalid limit %q", v)
+		}
+		p.Limit = n
+	}
+	return p, nil
+}
+
+// queryPoint читает точку из параметров lat и lon. Без обоих параметров точки нет
+func queryPoint(c *gin.Context) (*geo.Point, error) {
+	lat, lon := c.Query("lat"), c.Query("lon")
+	if lat == "" && lon == "" {
+		return nil, nil
+	}
+	var p geo.Point
+	var err error
+	if p.Lat, err = strconv.ParseFloat(lat, 64); err != nil {
+		return nil, fmt.Errorf("invalid lat %q", lat)
+	}
+	if p.Lon, err = strconv.ParseFloat(lon, 64); err != nil {
+		return nil, fmt.Errorf("invalid lon %q", lon)
+	}
+	return &p, nil
+}
+
+// searchHitResponse - объявление с релевантностью. Поля объявления лежат на верхнем уровне, как в adResponse
+type searchHitResponse struct {
+	adResponse
+	Score float64 `json:"score"`
+}
+
+func SearchSuccessResponse(results []app.SearchResult, favorites map[int64]int, d priceDisplay) *gin.H {
+	hits := make([]searchHitResponse, 0, len(results))
+	for i := range results {
+		hits = append(hits, searchHitResponse{adResponse: newAdResponse(&results[i].Ad, favorites[results[i].Ad.ID], d), Score: results[i].Score})
 	}
 	return &gin.H{
-		"data":  multipleAdsResponse,
+		"data":  hits,
 		"error": nil,
 	}
 }
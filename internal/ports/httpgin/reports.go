@@ -0,0 +1,205 @@
+package httpgin
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"homework9/internal/app"
+	"homework9/internal/reports"
+)
+
+// reportRequest: reason - scam, spam, prohibited, offensive или other, для other нужен comment
+type reportRequest struct {
+	Reason  string `json:"reason"`
+	Comment string `json:"comment"`
+}
+
+// closeReportRequest: note - необязательный комментарий модератора
+type closeReportRequest struct {
+	Note string `json:"note"`
+}
+
+type reportResponse struct {
+	ID         int64               `json:"id"`
+	ReporterID int64               `json:"reporter_id"`
+	Target     string              `json:"target"`
+	TargetID   int64               `json:"target_id"`
+	Reason     string              `json:"reason"`
+	Comment    string              `json:"comment"`
+	Status     string              `json:"status"`
+	Resolution *resolutionResponse `json:"resolution"`
+	CreatedAt  time.Time           `json:"created_at"`
+}
+
+// resolutionResponse - решение модератора по закрытой жалобе
+type resolutionResponse struct {
+	ModeratorID int64     `json:"moderator_id"`
+	Note        string    `json:"note"`
+	ResolvedAt  time.Time `json:"resolved_at"`
+}
+
+func newReportResponse(r *reports.Report) reportResponse {
+	res := reportResponse{
+		ID:         r.ID,
+		ReporterID: r.ReporterID,
+		Target:     string(r.Target),
+		TargetID:   r.TargetID,
+		Reason:     string(r.Reason),
+		Comment:    r.Comment,
+		Status:     string(r.Status),
+		CreatedAt:  r.Created,
+	}
+	if !r.Resolution.At.IsZero() {
+		res.Resolution = &resolutionResponse{ModeratorID: r.Resolution.ModeratorID, Note: r.Resolution.Note, ResolvedAt: r.Resolution.At}
+	}
+	return res
+}
+
+func ReportSuccessResponse(r *reports.Report) *gin.H {
+	return &gin.H{
+		"data":  newReportResponse(r),
+		"error": nil,
+	}
+}
+
+func ReportsPageSuccessResponse(page *app.ReportsPage) *gin.H {
+	res := make([]reportResponse, 0, len(page.Reports))
+	for i := range page.Reports {
+		res = append(res, newReportResponse(&page.Reports[i]))
+	}
+	var next *string
+	if page.NextCursor != "" {
+		next = &page.NextCursor
+	}
+	return &gin.H{
+		"data":        res,
+		"next_cursor": next,
+		"error":       nil,
+	}
+}
+
+// reportError отвечает на ошибку методов жалоб
+func reportError(c *gin.Context, err error) {
+	switch err {
+	case app.ErrUnauthenticated:
+		unauthorized(c, err)
+	case app.ErrAccessDenied:
+		c.JSON(http.StatusForbidden, AdErrorResponse(err))
+	case app.ErrNotFound:
+		c.JSON(http.StatusNotFound, AdErrorResponse(err))
+	case app.ErrAlreadyReported, app.ErrReportClosed:
+		c.JSON(http.StatusConflict, AdErrorResponse(err))
+	case app.ErrInvalidReport, app.ErrSelfReport, app.ErrInvalidNote, app.ErrInvalidCursor:
+		c.JSON(http.StatusBadRequest, AdErrorResponse(err))
+	case app.ErrReportsDisabled:
+		c.JSON(http.StatusNotImplemented, AdErrorResponse(err))
+	default:
+		c.JSON(http.StatusInternalServerError, AdErrorResponse(err))
+	}
+}
+
+// createReport - метод для жалобы на объявление (param ad_id) или пользователя (param user_id)
+func createReport(a app.App, target reports.Target, param string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		targetID, err := strconv.ParseInt(c.Param(param), 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, AdErrorResponse(err))
+			return
+		}
+		var reqBody reportRequest
+		if err := c.Bind(&reqBody); err != nil {
+			c.JSON(http.StatusBadRequest, AdErrorResponse(err))
+			return
+		}
+		r, err := a.CreateReport(c, target, targetID, reports.Reason(reqBody.Reason), reqBody.Comment)
+		if err != nil {
+			reportError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, ReportSuccessResponse(r))
+	}
+}
+
+// Метод для просмотра жалоб: status (open, resolved, dismissed), target (ad, user), target_id, limit и cursor
+func listReports(a app.App) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		f, err := reportFilter(c)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, AdErrorResponse(err))
+			return
+		}
+		p, err := idPageRequest(c)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, AdErrorResponse(err))
+			return
+		}
+		page, err := a.ListReports(c, f, p)
+		if err != nil {
+			reportError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, ReportsPageSuccessResponse(page))
+	}
+}
+
+// idPageRequest читает limit и cursor для списков без выбора сортировки
+func idPageRequest(c *gin.Context) (app.PageRequest, error) {
+	p := app.PageRequest{Cursor: c.Query("cursor")}
+	if v := c.Query("limit"); v != "" {
+		var err error
+		p.Limit, err = strconv.Atoi(v)
+		if err != nil || p.Limit < 0 {
+			return app.PageRequest{}, fmt.Errorf("invalid limit %q", v)
+		}
+	}
+	return p, nil
+}
+
+// reportFilter читает фильтр жалоб из query. target_id без target не имеет смысла
+func reportFilter(c *gin.Context) (reports.Filter, error) {
+	f := reports.Filter{Status: reports.Status(c.Query("status")), Target: reports.Target(c.Query("target"))}
+	if f.Status != "" && !f.Status.Valid() {
+		return reports.Filter{}, fmt.Errorf("invalid status %q", f.Status)
+	}
+	if f.Target != "" && !f.Target.Valid() {
+		return reports.Filter{}, fmt.Errorf("invalid target %q", f.Target)
+	}
+	if v := c.Query("target_id"); v != "" {
+		id, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || f.Target == "" {
+			return reports.Filter{}, fmt.Errorf("invalid target_id %q, it needs a target", v)
+		}
+		f.TargetIDs = []int64{id}
+	}
+	return f, nil
+}
+
+// closeReport - метод для подтверждения (resolve) или отклонения жалобы
+func closeReport(a app.App, resolve bool) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, err := strconv.ParseInt(c.Param("report_id"), 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, AdErrorResponse(err))
+			return
+		}
+		var reqBody closeReportRequest
+		if err := c.Bind(&reqBody); err != nil {
+			c.JSON(http.StatusBadRequest, AdErrorResponse(err))
+			return
+		}
+		decide := a.DismissReport
+		if resolve {
+			decide = a.ResolveReport
+		}
+		r, err := decide(c, id, reqBody.Note)
+		if err != nil {
+			reportError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, ReportSuccessResponse(r))
+	}
+}
@@ -4,17 +4,61 @@ import (
 	"github.com/gin-gonic/gin"
 
 	"homework9/internal/app"
+	"homework9/internal/reports"
 )
 
-func AppRouter(r *gin.RouterGroup, a app.App) {
+// AppRouter регистрирует методы API. Методы с requireUser доступны только с access-токеном
+func AppRouter(r *gin.RouterGroup, a app.App, limits Limits) {
+	r.Use(authenticate(a))
+
 	r.GET("/ads", getPublishedAds(a))
-	r.POST("/ads", createAd(a))
-	r.PUT("/ads/:ad_id/status", changeAdStatus(a)) // Метод для изменения статуса объявления (опубликовано - Published = true или снято с публикации Published = false)
-	r.PUT("/ads/:ad_id", updateAd(a))              // Метод для обновления текста(Text) или заголовка(Title) объявления
+	r.POST("/ads", requireUser, createAd(a, limits))
+	r.PUT("/ads/:ad_id/status", requireUser, changeAdStatus(a)) // Метод для изменения статуса объявления (опубликовано - Published = true или снято с публикации Published = false)
+	r.PUT("/ads/:ad_id", requireUser, updateAd(a, limits))      // Метод для обновления текста(Text) или заголовка(Title) объявления
+	r.GET("/ads/search", searchAds(a))                          // Полнотекстовый поиск по заголовку и тексту
 	r.GET("/ads/:ad_id", getAdById(a))
+	r.DELETE("/ads/:ad_id", requireUser, deleteAd(a))
+	r.PUT("/ads/:ad_id/schedule", requireUser, scheduleAd(a)) // Метод для публикации и снятия по расписанию
+	r.POST("/ads/:ad_id/renew", requireUser, renewAd(a))
+	r.POST("/ads/:ad_id/images", requireUser, uploadAdImage(a)) // Метод для загрузки фотографии (multipart, поле image)
+	r.PUT("/ads/:ad_id/images", requireUser, reorderAdImages(a))
+	r.GET("/ads/:ad_id/images/:image_id", getAdImage(a, false))
+	r.GET("/ads/:ad_id/images/:image_id/thumb", getAdImage(a, true))
+	r.DELETE("/ads/:ad_id/images/:image_id", requireUser, deleteAdImage(a))
+	r.POST("/ads/:ad_id/reports", requireUser, createReport(a, reports.TargetAd, "ad_id")) // Метод для жалобы на объявление
+	r.PUT("/ads/:ad_id/favorite", requireUser, addFavorite(a))                             // Метод для добавления объявления в избранное
+	r.DELETE("/ads/:ad_id/favorite", requireUser, removeFavorite(a))
+	r.GET("/favorites", requireUser, listFavorites(a))            // Избранное текущего пользователя
+	r.POST("/ads/:ad_id/messages", requireUser, contactSeller(a)) // Метод для сообщения автору объявления
+	r.GET("/threads", requireUser, listThreads(a))                // Входящие текущего пользователя
+	r.GET("/threads/:thread_id/messages", requireUser, listMessages(a))
+	r.POST("/threads/:thread_id/messages", requireUser, sendMessage(a))
+	r.POST("/threads/:thread_id/read", requireUser, markThreadRead(a))
 	r.GET("/search/:title", getAdByTitle(a))
 	r.POST("/search", getAdsByFilter(a))
 
-	r.POST("/users", createUser(a))
-	r.PUT("/users/:user_id", updateUser(a))
+	r.GET("/moderation/ads", requireUser, reviewQueue(a)) // Очередь модерации, только для модераторов и администраторов
+	r.POST("/moderation/ads/:ad_id/approve", requireUser, reviewAd(a, true))
+	r.POST("/moderation/ads/:ad_id/reject", requireUser, reviewAd(a, false))
+	r.GET("/moderation/reports", requireUser, listReports(a)) // Жалобы пользователей, только для модераторов и администраторов
+	r.POST("/moderation/reports/:report_id/resolve", requireUser, closeReport(a, true))
+	r.POST("/moderation/reports/:report_id/dismiss", requireUser, closeReport(a, false))
+
+	r.GET("/categories", listCategories(a))
+	r.GET("/categories/:category_id", getCategory(a))
+	r.POST("/categories", requireUser, createCategory(a))
+	r.PUT("/categories/:category_id", requireUser, updateCategory(a)) // Метод для переименования и переноса категории, только для администратора
+	r.DELETE("/categories/:category_id", requireUser, deleteCategory(a))
+
+	r.POST("/users", register(a)) // Прежний адрес регистрации, то же, что /auth/register
+	r.POST("/auth/register", register(a))
+	r.POST("/auth/login", login(a))
+	r.POST("/auth/refresh", refresh(a))
+	r.POST("/auth/logout", logout(a))
+	r.PUT("/users/:user_id", requireUser, updateUser(a))
+	r.DELETE("/users/:user_id", requireUser, deleteUser(a))
+	r.PUT("/users/:user_id/role", requireUser, setUserRole(a)) // Метод для назначения роли: user, moderator или admin
+	r.POST("/users/:user_id/reports", requireUser, createReport(a, reports.TargetUser, "user_id"))
+	r.PUT("/users/:user_id/block", requireUser, blockUser(a, true)) // Метод для блокировки собеседника в переписке
+	r.DELETE("/users/:user_id/block", requireUser, blockUser(a, false))
 }
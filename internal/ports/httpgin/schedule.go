@@ -0,0 +1,91 @@
+package httpgin
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"homework9/internal/app"
+)
+
+// scheduleRequest: publish_at - когда опубликовать, expires_at - когда снять, RFC 3339.
+// Отсутствующее поле отменяет срок
+type scheduleRequest struct {
+	PublishAt *time.Time `json:"publish_at"`
+	ExpiresAt *time.Time `json:"expires_at"`
+}
+
+// optionalTime переводит отсутствующий срок в нулевое время
+func optionalTime(t *time.Time) time.Time {
+	if t == nil {
+		return time.Time{}
+	}
+	return *t
+}
+
+// newTimeResponse возвращает nil для незаданного срока
+func newTimeResponse(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+	return &t
+}
+
+// scheduleError отвечает на ошибку методов сроков публикации
+func scheduleError(c *gin.Context, err error) {
+	switch err {
+	case app.ErrUnauthenticated:
+		unauthorized(c, err)
+	case app.ErrAccessDenied:
+		c.JSON(http.StatusForbidden, AdErrorResponse(err))
+	case app.ErrNotFound:
+		c.JSON(http.StatusNotFound, AdErrorResponse(err))
+	case app.ErrVersionConflict:
+		c.JSON(http.StatusConflict, AdErrorResponse(err))
+	case app.ErrInvalidSchedule:
+		c.JSON(http.StatusBadRequest, AdErrorResponse(err))
+	default:
+		c.JSON(http.StatusInternalServerError, AdErrorResponse(err))
+	}
+}
+
+// Метод для назначения публикации и снятия объявления по расписанию
+func scheduleAd(a app.App) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		adID, err := strconv.ParseInt(c.Param("ad_id"), 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, AdErrorResponse(err))
+			return
+		}
+		var reqBody scheduleRequest
+		if err := c.Bind(&reqBody); err != nil {
+			c.JSON(http.StatusBadRequest, AdErrorResponse(err))
+			return
+		}
+		ad, err := a.ScheduleAd(c, adID, optionalTime(reqBody.PublishAt), optionalTime(reqBody.ExpiresAt))
+		if err != nil {
+			scheduleError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, AdSuccessResponse(ad, favoriteCount(c, a, ad.ID), priceDisplay{}))
+	}
+}
+
+// Метод для продления объявления. Истёкшее объявление снова публикуется
+func renewAd(a app.App) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		adID, err := strconv.ParseInt(c.Param("ad_id"), 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, AdErrorResponse(err))
+			return
+		}
+		ad, err := a.RenewAd(c, adID)
+		if err != nil {
+			scheduleError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, AdSuccessResponse(ad, favoriteCount(c, a, ad.ID), priceDisplay{}))
+	}
+}
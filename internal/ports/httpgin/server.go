@@ -1,6 +1,8 @@
 package httpgin
 
 import (
+	"context"
+	"errors"
 	"github.com/gin-gonic/gin"
 	"log"
 	"net/http"
@@ -20,26 +22,86 @@ func customLogger(c *gin.Context) {
 type Server struct {
 	port string
 	app  *gin.Engine
+	srv  *http.Server
 }
 
-func NewHTTPServer(port string, a app.App) Server {
+type options struct {
+	limits      Limits
+	requestLog  bool
+	eventBuffer int
+}
+
+type Option func(o *options)
+
+// WithLimits задаёт ограничения на длину полей объявления вместо DefaultLimits
+func WithLimits(l Limits) Option {
+	return func(o *options) {
+		o.limits = l
+	}
+}
+
+// WithEventBuffer задаёт, сколько событий может ждать отправки в одно WebSocket-соединение, по умолчанию
+// DefaultEventBuffer. Соединение, которое не успевает их забирать, закрывается
+func WithEventBuffer(n int) Option {
+	return func(o *options) {
+		o.eventBuffer = n
+	}
+}
+
+// WithRequestLog включает или выключает журнал запросов. По умолчанию включён
+func WithRequestLog(enabled bool) Option {
+	return func(o *options) {
+		o.requestLog = enabled
+	}
+}
+
+func NewHTTPServer(port string, a app.App, opts ...Option) Server {
+	o := options{limits: DefaultLimits, requestLog: true, eventBuffer: DefaultEventBuffer}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
 	gin.SetMode(gin.ReleaseMode)
 	s := Server{port: port, app: gin.New()}
+	// c.Value читает контекст запроса, куда authenticate кладёт пользователя
+	s.app.ContextWithFallback = true
+	s.srv = &http.Server{Addr: port, Handler: s.app}
+	// Shutdown не ждёт WebSocket-соединений, поэтому они закрываются сами по сигналу
+	closing := make(chan struct{})
+	s.srv.RegisterOnShutdown(func() { close(closing) })
 
 	s.app.Use(gin.Recovery())
-	s.app.Use(customLogger)
+	if o.requestLog {
+		s.app.Use(customLogger)
+	}
 
 	api := s.app.Group("/api/v1")
 	s.app.NoRoute(func(c *gin.Context) {
 		c.JSON(404, gin.H{"code": "PAGE_NOT_FOUND", "message": "Page not found"})
 	})
-	AppRouter(api, a)
+	AppRouter(api, a, o.limits)
+	api.GET("/events", watchEvents(a, o.eventBuffer, closing)) // WebSocket с событиями текущего пользователя
 
 	return s
 }
 
+// Listen блокируется до остановки сервера. После Shutdown возвращает nil
 func (s *Server) Listen() error {
-	return s.app.Run(s.port)
+	err := s.srv.ListenAndServe()
+	if errors.Is(err, http.ErrServerClosed) {
+		return nil
+	}
+	return err
+}
+
+// Shutdown перестаёт принимать соединения и ждёт завершения текущих запросов.
+// Если ctx истёк раньше, оставшиеся соединения обрываются
+func (s *Server) Shutdown(ctx context.Context) error {
+	err := s.srv.Shutdown(ctx)
+	if err != nil {
+		_ = s.srv.Close()
+	}
+	return err
 }
 
 func (s *Server) Handler() http.Handler {
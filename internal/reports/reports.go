@@ -0,0 +1,98 @@
+// Package reports - жалобы пользователей на объявления и других пользователей
+package reports
+
+import "time"
+
+// Target - на что жалуются
+type Target string
+
+const (
+	TargetAd   Target = "ad"
+	TargetUser Target = "user"
+)
+
+func (t Target) Valid() bool {
+	return t == TargetAd || t == TargetUser
+}
+
+// Reason - повод жалобы. Для ReasonOther нужен комментарий
+type Reason string
+
+const (
+	ReasonScam       Reason = "scam"
+	ReasonSpam       Reason = "spam"
+	ReasonProhibited Reason = "prohibited"
+	ReasonOffensive  Reason = "offensive"
+	ReasonOther      Reason = "other"
+)
+
+func (r Reason) Valid() bool {
+	switch r {
+	case ReasonScam, ReasonSpam, ReasonProhibited, ReasonOffensive, ReasonOther:
+		return true
+	}
+	return false
+}
+
+// Status - этап рассмотрения жалобы. Закрытая жалоба (resolved или dismissed) больше не меняется
+type Status string
+
+const (
+	StatusOpen Status = "open"
+	// StatusResolved - модератор подтвердил жалобу
+	StatusResolved Status = "resolved"
+	// StatusDismissed - модератор отклонил жалобу
+	StatusDismissed Status = "dismissed"
+)
+
+func (s Status) Valid() bool {
+	return s == StatusOpen || s == StatusResolved || s == StatusDismissed
+}
+
+// Report - жалоба ReporterID на объявление или пользователя TargetID
+type Report struct {
+	ID         int64
+	ReporterID int64
+	Target     Target
+	TargetID   int64
+	Reason     Reason
+	Comment    string
+	Status     Status
+	// Resolution - решение модератора, у открытой жалобы пустое
+	Resolution Resolution
+	Created    time.Time
+}
+
+// Resolution - кто и когда закрыл жалобу, Note - необязательный комментарий модератора
+type Resolution struct {
+	ModeratorID int64
+	Note        string
+	At          time.Time
+}
+
+// Filter - условия отбора жалоб, пустое поле не ограничивает выборку
+type Filter struct {
+	Status Status
+	Target Target
+	// TargetIDs имеет смысл вместе с Target: ID объявлений и пользователей пересекаются
+	TargetIDs []int64
+}
+
+// Match - подходит ли жалоба под фильтр
+func (f Filter) Match(r Report) bool {
+	if f.Status != "" && r.Status != f.Status {
+		return false
+	}
+	if f.Target != "" && r.Target != f.Target {
+		return false
+	}
+	if len(f.TargetIDs) == 0 {
+		return true
+	}
+	for _, id := range f.TargetIDs {
+		if r.TargetID == id {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,23 @@
+package reports
+
+import (
+	"context"
+	"errors"
+)
+
+var (
+	ErrNotFound = errors.New("report not found")
+	// ErrDuplicate - пользователь уже жаловался на эту цель
+	ErrDuplicate = errors.New("report already filed")
+)
+
+// ReportRepository хранит жалобы. ID выдаются с единицы и никогда не переиспользуются
+type ReportRepository interface {
+	// AddReport сохраняет жалобу и возвращает её ID. На одну цель пользователь жалуется один раз,
+	// повторная жалоба - ErrDuplicate, даже если прежняя уже закрыта
+	AddReport(ctx context.Context, r Report) (int64, error)
+	GetReport(ctx context.Context, id int64) (*Report, error)
+	// FindReports возвращает не больше limit жалоб с ID больше afterID по возрастанию ID. Нулевой limit - без ограничения
+	FindReports(ctx context.Context, f Filter, afterID int64, limit int) ([]Report, error)
+	UpdateReport(ctx context.Context, id int64, r Report) error
+}
@@ -0,0 +1,141 @@
+// Package search - полнотекстовый индекс объявлений в памяти процесса с ранжированием BM25
+package search
+
+import (
+	"math"
+	"sort"
+	"sync"
+)
+
+// Параметры BM25. Слово из заголовка весит как titleBoost слов из текста
+const (
+	k1         = 1.2
+	b          = 0.75
+	titleBoost = 2
+)
+
+type Hit struct {
+	ID    int64
+	Score float64
+}
+
+// Index - инвертированный индекс: для каждого терма хранится, в каких документах и сколько раз он встречается
+type Index struct {
+	mu       sync.RWMutex
+	postings map[string]map[int64]int
+	// docs - термы и длина каждого документа, нужны для удаления и нормировки по длине
+	docs     map[int64]document
+	totalLen int
+}
+
+type document struct {
+	terms map[string]int
+	len   int
+}
+
+func NewIndex() *Index {
+	return &Index{
+		postings: make(map[string]map[int64]int),
+		docs:     make(map[int64]document),
+	}
+}
+
+// Put индексирует документ id, заменяя прежнюю версию
+func (idx *Index) Put(id int64, title string, text string) {
+	doc := document{terms: make(map[string]int)}
+	for _, t := range Terms(title) {
+		doc.terms[t] += titleBoost
+		doc.len += titleBoost
+	}
+	for _, t := range Terms(text) {
+		doc.terms[t]++
+		doc.len++
+	}
+
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	idx.remove(id)
+	for t, tf := range doc.terms {
+		p, ok := idx.postings[t]
+		if !ok {
+			p = make(map[int64]int)
+			idx.postings[t] = p
+		}
+		p[id] = tf
+	}
+	idx.docs[id] = doc
+	idx.totalLen += doc.len
+}
+
+// Remove убирает документ из индекса. Отсутствующий документ не считается ошибкой
+func (idx *Index) Remove(id int64) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	idx.remove(id)
+}
+
+func (idx *Index) remove(id int64) {
+	doc, ok := idx.docs[id]
+	if !ok {
+		return
+	}
+	for t := range doc.terms {
+		p := idx.postings[t]
+		delete(p, id)
+		if len(p) == 0 {
+			delete(idx.postings, t)
+		}
+	}
+	delete(idx.docs, id)
+	idx.totalLen -= doc.len
+}
+
+func (idx *Index) Len() int {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+	return len(idx.docs)
+}
+
+// Search возвращает документы, содержащие хотя бы один терм запроса, по убыванию релевантности.
+// При равной релевантности раньше идёт меньший ID
+func (idx *Index) Search(query string) []Hit {
+	terms := Terms(query)
+
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+	if len(idx.docs) == 0 {
+		return nil
+	}
+	n := float64(len(idx.docs))
+	avgLen := float64(idx.totalLen) / n
+
+	scores := make(map[int64]float64)
+	seen := make(map[string]bool, len(terms))
+	for _, t := range terms {
+		if seen[t] {
+			continue
+		}
+		seen[t] = true
+		p := idx.postings[t]
+		if len(p) == 0 {
+			continue
+		}
+		idf := math.Log(1 + (n-float64(len(p))+0.5)/(float64(len(p))+0.5))
+		for id, tf := range p {
+			norm := 1 - b + b*float64(idx.docs[id].len)/avgLen
+			scores[id] += idf * float64(tf) * (k1 + 1) / (float64(tf) + k1*norm)
+		}
+	}
+
+	hits := make([]Hit, 0, len(scores))
+	for id, score := range scores {
+		hits = append(hits, Hit{ID: id, Score: score})
+	}
+	sort.Slice(hits, func(i, j int) bool {
+		if hits[i].Score != hits[j].Score {
+			return hits[i].Score > hits[j].Score
+		}
+		return hits[i].ID < hits[j].ID
+	})
+	return hits
+}
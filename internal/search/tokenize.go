@@ -0,0 +1,51 @@
+package search
+
+import (
+	"strings"
+	"unicode"
+
+	"github.com/kljensen/snowball/english"
+	"github.com/kljensen/snowball/russian"
+)
+
+// Terms разбивает текст на слова и приводит каждое к основе. Язык выбирается по алфавиту слова:
+// кириллица стеммится по-русски, латиница по-английски, числа остаются как есть. Стоп-слова отбрасываются
+func Terms(text string) []string {
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	terms := make([]string, 0, len(words))
+	for _, w := range words {
+		if t := stem(w); t != "" {
+			terms = append(terms, t)
+		}
+	}
+	return terms
+}
+
+func stem(word string) string {
+	switch {
+	case hasScript(word, unicode.Cyrillic):
+		word = strings.ReplaceAll(word, "ё", "е")
+		if russian.IsStopWord(word) {
+			return ""
+		}
+		return russian.Stem(word, false)
+	case hasScript(word, unicode.Latin):
+		if english.IsStopWord(word) {
+			return ""
+		}
+		return english.Stem(word, false)
+	default:
+		return word
+	}
+}
+
+func hasScript(word string, script *unicode.RangeTable) bool {
+	for _, r := range word {
+		if unicode.Is(script, r) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,19 @@
+package sessions
+
+import (
+	"context"
+	"errors"
+)
+
+var ErrNotFound = errors.New("session not found")
+
+type SessionRepository interface {
+	AddSession(ctx context.Context, s Session) error
+	GetSession(ctx context.Context, tokenHash string) (*Session, error)
+	// TakeSession удаляет сессию и возвращает её. Из одновременных вызовов с одним хешем сессию получает
+	// только один, остальные - ErrNotFound
+	TakeSession(ctx context.Context, tokenHash string) (*Session, error)
+	DeleteSession(ctx context.Context, tokenHash string)
+	// DeleteUserSessions завершает все сессии пользователя
+	DeleteUserSessions(ctx context.Context, userID int64)
+}
@@ -0,0 +1,11 @@
+package sessions
+
+import "time"
+
+// Session - вход пользователя. Сам токен на сервере не хранится, только его хеш
+type Session struct {
+	TokenHash string
+	UserID    int64
+	Created   time.Time
+	Expires   time.Time
+}
@@ -31,10 +31,9 @@ func TestFilteredSearch(t *testing.T) {
 	publishedAd, err := client.changeAdStatus(vasya.Data.ID, response.Data.ID, true)
 	assert.NoError(t, err)
 
-	// time filtering
-	f := findAdsRequest{
-		CreatedTime: publishedAd.Data.Created,
-	}
+	// диапазон времени создания: [from, to)
+	created := publishedAd.Data.Created
+	f := findAdsRequest{CreatedFrom: &created}
 	ads, err := client.listAdsByFilter(f)
 	assert.NoError(t, err)
 	assert.Len(t, ads.Data, 1)
@@ -43,15 +42,39 @@ func TestFilteredSearch(t *testing.T) {
 	assert.Equal(t, ads.Data[0].Text, publishedAd.Data.Text)
 	assert.Equal(t, ads.Data[0].AuthorID, publishedAd.Data.AuthorID)
 
-	// author ID filtering
-	f = findAdsRequest{
-		AuthorID: vasya.Data.ID,
-	}
-	ads, err = client.listAdsByFilter(f)
+	ads, err = client.listAdsByFilter(findAdsRequest{CreatedTo: &created})
+	assert.NoError(t, err)
+	assert.Len(t, ads.Data, 2)
+
+	// фильтр по списку авторов
+	ads, err = client.listAdsByFilter(findAdsRequest{AuthorIDs: []int64{vasya.Data.ID}})
 	assert.NoError(t, err)
 	assert.Len(t, ads.Data, 2)
 	assert.Equal(t, vasya.Data.ID, ads.Data[0].AuthorID)
 	assert.Equal(t, vasya.Data.ID, ads.Data[1].AuthorID)
+
+	ads, err = client.listAdsByFilter(findAdsRequest{AuthorIDs: []int64{petya.Data.ID, vasya.Data.ID}})
+	assert.NoError(t, err)
+	assert.Len(t, ads.Data, 3)
+
+	// статус публикации: отсутствие поля - любые, false - только снятые. Снятые видит только автор
+	_, err = client.changeAdStatus(vasya.Data.ID, publishedAd.Data.ID, false)
+	assert.NoError(t, err)
+	unpublished := false
+	err = client.sendJSON(http.MethodPost, "/api/v1/search", vasya.Data.ID, findAdsRequest{Published: &unpublished}, &ads)
+	assert.NoError(t, err)
+	assert.Len(t, ads.Data, 1)
+	assert.Equal(t, publishedAd.Data.ID, ads.Data[0].ID)
+
+	ads, err = client.listAdsByFilter(findAdsRequest{Published: &unpublished})
+	assert.NoError(t, err)
+	assert.Empty(t, ads.Data)
+
+	// подстроки без учёта регистра, условия объединяются через И
+	err = client.sendJSON(http.MethodPost, "/api/v1/search", vasya.Data.ID, findAdsRequest{Text: "FUNNY", Title: "ts"}, &ads)
+	assert.NoError(t, err)
+	assert.Len(t, ads.Data, 1)
+	assert.Equal(t, "Rats", ads.Data[0].Title)
 }
 
 func TestGetByID(t *testing.T) {
@@ -64,7 +87,7 @@ func TestGetByID(t *testing.T) {
 	publishedAd, err := client.changeAdStatus(u.Data.ID, response.Data.ID, true)
 	assert.NoError(t, err)
 
-	ads, err := client.getAdById(publishedAd.Data.ID)
+	ads, err := client.getAdById(u.Data.ID, publishedAd.Data.ID)
 	assert.NoError(t, err)
 	assert.Equal(t, ads.Data.ID, publishedAd.Data.ID)
 	assert.Equal(t, ads.Data.Title, publishedAd.Data.Title)
@@ -100,16 +123,7 @@ func TestGetByTitle(t *testing.T) {
 }
 
 func (tc *testClient) listAdsByFilter(f findAdsRequest) (adsResponse, error) {
-	body := map[string]any{
-		"id":            f.ID,
-		"title":         f.Title,
-		"text":          f.Text,
-		"author_id":     f.AuthorID,
-		"published":     f.Published,
-		"created_time":  f.CreatedTime,
-		"modified_time": f.ModifiedTime,
-	}
-	data, _ := json.Marshal(body)
+	data, _ := json.Marshal(f)
 	req, err := http.NewRequest(http.MethodPost, tc.baseURL+"/api/v1/search", bytes.NewReader(data))
 	if err != nil {
 		return adsResponse{}, fmt.Errorf("unable to create request: %w", err)
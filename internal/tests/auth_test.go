@@ -0,0 +1,233 @@
+package tests
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"homework9/internal/adapters/jwtauth"
+	"homework9/internal/ads"
+	"homework9/internal/app"
+)
+
+func TestRegisterValidation(t *testing.T) {
+	client := getTestClient()
+
+	_, err := client.register("Petya", "petya@mail.ru", "short")
+	assert.ErrorIs(t, err, ErrBadRequest)
+	_, err = client.register("Petya", "not an email", testPassword)
+	assert.ErrorIs(t, err, ErrBadRequest)
+
+	_, err = client.register("Petya", "petya@mail.ru", testPassword)
+	require.NoError(t, err)
+	_, err = client.register("Petya 2", " Petya@Mail.ru ", testPassword)
+	assert.Error(t, err, "email must be unique regardless of case")
+}
+
+func TestLogin(t *testing.T) {
+	client := getTestClient()
+	u, err := client.register("Petya", "petya@mail.ru", testPassword)
+	require.NoError(t, err)
+
+	_, err = client.login("petya@mail.ru", "wrong password")
+	assert.ErrorIs(t, err, ErrUnauthorized)
+	_, err = client.login("vasya@mail.ru", testPassword)
+	assert.ErrorIs(t, err, ErrUnauthorized)
+
+	tokens, err := client.login("PETYA@mail.ru", testPassword)
+	require.NoError(t, err)
+	assert.NotEmpty(t, tokens.Data.AccessToken)
+	assert.NotEmpty(t, tokens.Data.RefreshToken)
+	assert.Equal(t, u.Data.ID, tokens.Data.UserID)
+	assert.True(t, tokens.Data.ExpiresAt.After(time.Now()))
+	assert.True(t, tokens.Data.RefreshExpiresAt.After(tokens.Data.ExpiresAt))
+}
+
+func TestCreateAdRequiresToken(t *testing.T) {
+	client := getTestClient()
+	u, err := client.createUser("Petya", "petya@mail.ru")
+	require.NoError(t, err)
+
+	resp, err := client.do(http.MethodPost, "/api/v1/ads", map[string]any{"title": "hello", "text": "world"}, nil)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+	assert.NotEmpty(t, resp.Header.Get("WWW-Authenticate"))
+
+	resp, err = client.do(http.MethodPost, "/api/v1/ads", map[string]any{"title": "hello", "text": "world"},
+		map[string]string{"Authorization": "Bearer forged"})
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+
+	// user_id в теле больше ничего не значит: автор берётся из токена
+	other, err := client.createUser("Vasya", "vasya@mail.ru")
+	require.NoError(t, err)
+	ad, err := client.createAd(u.Data.ID, "hello", "world")
+	require.NoError(t, err)
+	assert.Equal(t, u.Data.ID, ad.Data.AuthorID)
+	_, err = client.changeAdStatus(other.Data.ID, ad.Data.ID, true)
+	assert.ErrorIs(t, err, ErrForbidden)
+}
+
+func TestRefresh(t *testing.T) {
+	client := getTestClient()
+	u, err := client.register("Petya", "petya@mail.ru", testPassword)
+	require.NoError(t, err)
+	tokens, err := client.login("petya@mail.ru", testPassword)
+	require.NoError(t, err)
+
+	refreshed, err := client.refresh(tokens.Data.RefreshToken)
+	require.NoError(t, err)
+	assert.Equal(t, u.Data.ID, refreshed.Data.UserID)
+	assert.NotEqual(t, tokens.Data.RefreshToken, refreshed.Data.RefreshToken)
+
+	client.tokens[u.Data.ID] = refreshed.Data.AccessToken
+	_, err = client.createAd(u.Data.ID, "hello", "world")
+	assert.NoError(t, err)
+
+	// refresh-токен одноразовый
+	_, err = client.refresh(tokens.Data.RefreshToken)
+	assert.ErrorIs(t, err, ErrUnauthorized)
+	_, err = client.refresh("forged")
+	assert.ErrorIs(t, err, ErrUnauthorized)
+}
+
+func TestConcurrentRefresh(t *testing.T) {
+	a := newTestApp()
+	ctx := context.Background()
+	_, err := a.CreateUser(ctx, "Petya", "petya@mail.ru", testPassword)
+	require.NoError(t, err)
+	tokens, err := a.Login(ctx, "petya@mail.ru", testPassword)
+	require.NoError(t, err)
+
+	// из одновременных обменов одного refresh-токена новую пару получает только один
+	var wg sync.WaitGroup
+	var succeeded, rejected atomic.Int32
+	for i := 0; i < 16; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := a.Refresh(ctx, tokens.RefreshToken)
+			if err == nil {
+				succeeded.Add(1)
+			} else if err == app.ErrUnauthenticated {
+				rejected.Add(1)
+			}
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, int32(1), succeeded.Load())
+	assert.Equal(t, int32(15), rejected.Load())
+}
+
+func TestLogout(t *testing.T) {
+	client := getTestClient()
+	_, err := client.register("Petya", "petya@mail.ru", testPassword)
+	require.NoError(t, err)
+	tokens, err := client.login("petya@mail.ru", testPassword)
+	require.NoError(t, err)
+
+	resp, err := client.do(http.MethodPost, "/api/v1/auth/logout", map[string]any{"refresh_token": tokens.Data.RefreshToken}, nil)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	_, err = client.refresh(tokens.Data.RefreshToken)
+	assert.ErrorIs(t, err, ErrUnauthorized)
+}
+
+func TestUpdateUserKeepsPassword(t *testing.T) {
+	client := getTestClient()
+	u, err := client.createUser("Petya", "petya@mail.ru")
+	require.NoError(t, err)
+	_, err = client.createUser("Vasya", "vasya@mail.ru")
+	require.NoError(t, err)
+
+	_, err = client.updateUser(u.Data.ID, u.Data.ID, "Petya", "vasya@mail.ru")
+	assert.Error(t, err, "email of another user")
+
+	_, err = client.updateUser(u.Data.ID, u.Data.ID, "Pyotr", "pyotr@mail.ru")
+	require.NoError(t, err)
+	_, err = client.login("pyotr@mail.ru", testPassword)
+	assert.NoError(t, err)
+}
+
+func TestTokensExpire(t *testing.T) {
+	ctx := context.Background()
+	keyring := newTestKeyring(jwtauth.Key{ID: "test", Secret: testSecret})
+	a := newTestApp(app.WithAccessTokens(keyring), app.WithRefreshTTL(time.Millisecond))
+
+	u, err := a.CreateUser(ctx, "Petya", "petya@mail.ru", testPassword)
+	require.NoError(t, err)
+	tokens, err := a.Login(ctx, "petya@mail.ru", testPassword)
+	require.NoError(t, err)
+
+	id, err := a.Authenticate(ctx, tokens.AccessToken)
+	require.NoError(t, err)
+	assert.Equal(t, u.ID, id)
+
+	time.Sleep(5 * time.Millisecond)
+	_, err = a.Refresh(ctx, tokens.RefreshToken)
+	assert.ErrorIs(t, err, app.ErrUnauthenticated)
+
+	expired, err := keyring.Issue(u.ID, time.Now().Add(-time.Minute))
+	require.NoError(t, err)
+	_, err = a.Authenticate(ctx, expired)
+	assert.ErrorIs(t, err, app.ErrUnauthenticated)
+}
+
+func TestSigningKeyRotation(t *testing.T) {
+	ctx := context.Background()
+	oldKey := jwtauth.Key{ID: "old", Secret: []byte(strings.Repeat("o", jwtauth.MinSecretLen))}
+	newKey := jwtauth.Key{ID: "new", Secret: []byte(strings.Repeat("n", jwtauth.MinSecretLen))}
+	keyring := newTestKeyring(oldKey)
+	a := newTestApp(app.WithAccessTokens(keyring))
+
+	u, err := a.CreateUser(ctx, "Petya", "petya@mail.ru", testPassword)
+	require.NoError(t, err)
+	before, err := a.Login(ctx, "petya@mail.ru", testPassword)
+	require.NoError(t, err)
+
+	require.NoError(t, keyring.Rotate(newKey))
+	after, err := a.Login(ctx, "petya@mail.ru", testPassword)
+	require.NoError(t, err)
+
+	// пока старый ключ активен, действуют токены, подписанные обоими ключами
+	for _, token := range []string{before.AccessToken, after.AccessToken} {
+		id, err := a.Authenticate(ctx, token)
+		require.NoError(t, err)
+		assert.Equal(t, u.ID, id)
+	}
+
+	assert.Error(t, keyring.Retire("new"), "the signing key cannot be retired")
+	require.NoError(t, keyring.Retire("old"))
+	_, err = a.Authenticate(ctx, before.AccessToken)
+	assert.ErrorIs(t, err, app.ErrUnauthenticated)
+	_, err = a.Authenticate(ctx, after.AccessToken)
+	assert.NoError(t, err)
+
+	// токен, подписанный чужим ключом с тем же kid, не принимается
+	forged, err := newTestKeyring(jwtauth.Key{ID: "new", Secret: testSecret}).Issue(u.ID, time.Now().Add(time.Hour))
+	require.NoError(t, err)
+	_, err = a.Authenticate(ctx, forged)
+	assert.ErrorIs(t, err, app.ErrUnauthenticated)
+}
+
+func TestAppReadsUserFromContext(t *testing.T) {
+	ctx := context.Background()
+	a := newTestApp()
+	u, err := a.CreateUser(ctx, "Petya", "petya@mail.ru", testPassword)
+	require.NoError(t, err)
+
+	_, err = a.CreateAd(ctx, "hello", "world", testCategoryID, ads.Price{}, nil)
+	assert.ErrorIs(t, err, app.ErrUnauthenticated)
+
+	ad, err := a.CreateAd(app.WithUserID(ctx, u.ID), "hello", "world", testCategoryID, ads.Price{}, nil)
+	require.NoError(t, err)
+	assert.Equal(t, u.ID, ad.AuthorID)
+}
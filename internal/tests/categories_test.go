@@ -0,0 +1,294 @@
+package tests
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+
+	"homework9/internal/ads"
+	"homework9/internal/app"
+	"homework9/internal/categories"
+	grpcPort "homework9/internal/ports/grpc"
+)
+
+type categoryData struct {
+	ID       int64  `json:"id"`
+	Name     string `json:"name"`
+	ParentID int64  `json:"parent_id"`
+}
+
+type categoryResponse struct {
+	Data categoryData `json:"data"`
+}
+
+func (tc *testClient) sendJSON(method string, path string, userID int64, body any, out any) error {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return fmt.Errorf("unable to marshal: %w", err)
+	}
+	req, err := http.NewRequest(method, tc.baseURL+path, bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("unable to create request: %w", err)
+	}
+	req.Header.Add("Content-Type", "application/json")
+	tc.authorize(req, userID)
+	return tc.getResponse(req, out)
+}
+
+func (tc *testClient) createCategory(userID int64, name string, parentID int64) (categoryResponse, error) {
+	var response categoryResponse
+	err := tc.sendJSON(http.MethodPost, "/api/v1/categories", userID, map[string]any{"name": name, "parent_id": parentID}, &response)
+	return response, err
+}
+
+func (tc *testClient) createAdIn(userID int64, title string, categoryID int64) (adResponse, error) {
+	var response adResponse
+	err := tc.sendJSON(http.MethodPost, "/api/v1/ads", userID, map[string]any{"title": title, "text": "text", "category_id": categoryID}, &response)
+	return response, err
+}
+
+func TestCategoriesAdminOnly(t *testing.T) {
+	client := newTestClient(newTestApp(app.WithAdminEmails("admin@mail.ru")))
+	admin, err := client.createUser("Admin", "admin@mail.ru")
+	require.NoError(t, err)
+	user, err := client.createUser("Petya", "petya@mail.ru")
+	require.NoError(t, err)
+
+	_, err = client.createCategory(user.Data.ID, "Транспорт", 0)
+	assert.ErrorIs(t, err, ErrForbidden)
+
+	transport, err := client.createCategory(admin.Data.ID, " Транспорт ", 0)
+	require.NoError(t, err)
+	assert.Equal(t, "Транспорт", transport.Data.Name)
+	cars, err := client.createCategory(admin.Data.ID, "Автомобили", transport.Data.ID)
+	require.NoError(t, err)
+	assert.Equal(t, transport.Data.ID, cars.Data.ParentID)
+
+	// имя уникально среди соседей без учёта регистра, у родителя должен быть существующий ID
+	_, err = client.createCategory(admin.Data.ID, "транспорт", 0)
+	assert.ErrorIs(t, err, ErrBadRequest)
+	_, err = client.createCategory(admin.Data.ID, "", 0)
+	assert.ErrorIs(t, err, ErrBadRequest)
+	_, err = client.createCategory(admin.Data.ID, "Мотоциклы", 100)
+	assert.ErrorIs(t, err, ErrBadRequest)
+
+	// категорию нельзя перенести в её собственное поддерево
+	var moved categoryResponse
+	err = client.sendJSON(http.MethodPut, fmt.Sprintf("/api/v1/categories/%d", transport.Data.ID), admin.Data.ID,
+		map[string]any{"name": "Транспорт", "parent_id": cars.Data.ID}, &moved)
+	assert.ErrorIs(t, err, ErrBadRequest)
+	err = client.sendJSON(http.MethodPut, fmt.Sprintf("/api/v1/categories/%d", cars.Data.ID), admin.Data.ID,
+		map[string]any{"name": "Легковые", "parent_id": 0}, &moved)
+	require.NoError(t, err)
+	assert.Equal(t, categoryData{ID: cars.Data.ID, Name: "Легковые"}, moved.Data)
+
+	var list struct {
+		Data []categoryData `json:"data"`
+	}
+	err = client.sendJSON(http.MethodGet, "/api/v1/categories", user.Data.ID, nil, &list)
+	require.NoError(t, err)
+	assert.Len(t, list.Data, 3)
+
+	resp, err := client.do(http.MethodDelete, fmt.Sprintf("/api/v1/categories/%d", cars.Data.ID), nil,
+		map[string]string{"Authorization": client.bearer(admin.Data.ID)})
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	resp, err = client.do(http.MethodGet, fmt.Sprintf("/api/v1/categories/%d", cars.Data.ID), nil, nil)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+}
+
+func TestCategorySubtreeFilters(t *testing.T) {
+	client := newTestClient(newTestApp(app.WithAdminEmails("admin@mail.ru")))
+	admin, err := client.createUser("Admin", "admin@mail.ru")
+	require.NoError(t, err)
+	user, err := client.createUser("Petya", "petya@mail.ru")
+	require.NoError(t, err)
+
+	transport, err := client.createCategory(admin.Data.ID, "Транспорт", 0)
+	require.NoError(t, err)
+	bikes, err := client.createCategory(admin.Data.ID, "Велосипеды", transport.Data.ID)
+	require.NoError(t, err)
+
+	_, err = client.createAdIn(user.Data.ID, "Велосипед без категории", 0)
+	assert.ErrorIs(t, err, ErrBadRequest, "category is required")
+	_, err = client.createAdIn(user.Data.ID, "Велосипед", 100)
+	assert.ErrorIs(t, err, ErrBadRequest)
+
+	bike, err := client.createAdIn(user.Data.ID, "Горный велосипед", bikes.Data.ID)
+	require.NoError(t, err)
+	assert.Equal(t, bikes.Data.ID, bike.Data.CategoryID)
+	other, err := client.createAd(user.Data.ID, "Велосипедный насос", "text")
+	require.NoError(t, err)
+	for _, id := range []int64{bike.Data.ID, other.Data.ID} {
+		_, err = client.changeAdStatus(user.Data.ID, id, true)
+		require.NoError(t, err)
+	}
+
+	// фильтр по родительской категории включает подкатегории
+	page, err := client.listAdsPage(fmt.Sprintf("category_id=%d", transport.Data.ID))
+	require.NoError(t, err)
+	require.Len(t, page.Data, 1)
+	assert.Equal(t, bike.Data.ID, page.Data[0].ID)
+
+	page, err = client.listAds()
+	require.NoError(t, err)
+	assert.Len(t, page.Data, 2)
+
+	var found adsResponse
+	err = client.sendJSON(http.MethodPost, "/api/v1/search", user.Data.ID, map[string]any{"category_ids": []int64{transport.Data.ID}}, &found)
+	require.NoError(t, err)
+	require.Len(t, found.Data, 1)
+	assert.Equal(t, bike.Data.ID, found.Data[0].ID)
+
+	var hits searchResponse
+	err = client.sendJSON(http.MethodGet, fmt.Sprintf("/api/v1/ads/search?q=велосипед&category_id=%d", transport.Data.ID), user.Data.ID, nil, &hits)
+	require.NoError(t, err)
+	require.Len(t, hits.Data, 1)
+	assert.Equal(t, bike.Data.ID, hits.Data[0].ID)
+
+	// категорию с подкатегориями или объявлениями удалить нельзя
+	for _, id := range []int64{transport.Data.ID, bikes.Data.ID} {
+		resp, err := client.do(http.MethodDelete, fmt.Sprintf("/api/v1/categories/%d", id), nil,
+			map[string]string{"Authorization": client.bearer(admin.Data.ID)})
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusConflict, resp.StatusCode)
+	}
+
+	// перенос объявления в другую категорию, без category_id категория сохраняется
+	var updated adResponse
+	err = client.sendJSON(http.MethodPut, fmt.Sprintf("/api/v1/ads/%d", bike.Data.ID), user.Data.ID,
+		map[string]any{"title": "Горный велосипед", "text": "text", "category_id": testCategoryID}, &updated)
+	require.NoError(t, err)
+	assert.Equal(t, testCategoryID, updated.Data.CategoryID)
+	updatedAd, err := client.updateAd(user.Data.ID, bike.Data.ID, "Велосипед", "text")
+	require.NoError(t, err)
+	assert.Equal(t, testCategoryID, updatedAd.Data.CategoryID)
+}
+
+func TestCategoryTreeCycle(t *testing.T) {
+	// цикл в хранилище не должен подвешивать обход поддерева
+	tree := categories.NewTree([]categories.Category{{ID: 1, Name: "a", ParentID: 2}, {ID: 2, Name: "b", ParentID: 1}})
+	assert.ElementsMatch(t, []int64{1, 2}, tree.Subtree(1))
+}
+
+func TestConcurrentCategoryWrites(t *testing.T) {
+	a := newTestApp(app.WithAdminEmails("admin@mail.ru"))
+	admin, err := a.CreateUser(context.Background(), "Admin", "admin@mail.ru", testPassword)
+	require.NoError(t, err)
+	ctx := app.WithUserID(context.Background(), admin.ID)
+	first, err := a.CreateCategory(ctx, "first", 0)
+	require.NoError(t, err)
+	second, err := a.CreateCategory(ctx, "second", 0)
+	require.NoError(t, err)
+
+	for i := 0; i < 50; i++ {
+		// встречные переносы: проходит не больше одного, иначе категории стали бы подкатегориями друг друга
+		var wg sync.WaitGroup
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			_, _ = a.UpdateCategory(ctx, first.ID, "first", second.ID)
+		}()
+		go func() {
+			defer wg.Done()
+			_, _ = a.UpdateCategory(ctx, second.ID, "second", first.ID)
+		}()
+		wg.Wait()
+		tree, err := a.ListCategories(ctx)
+		require.NoError(t, err)
+		moved := 0
+		for _, c := range tree {
+			if c.ParentID != 0 {
+				moved++
+			}
+		}
+		require.Equal(t, 1, moved)
+		_, err = a.UpdateCategory(ctx, first.ID, "first", 0)
+		require.NoError(t, err)
+		_, err = a.UpdateCategory(ctx, second.ID, "second", 0)
+		require.NoError(t, err)
+
+		// объявление не попадает в категорию, удалённую одновременно с его созданием
+		c, err := a.CreateCategory(ctx, fmt.Sprintf("removed %d", i), 0)
+		require.NoError(t, err)
+		var ad *ads.Ad
+		var createErr, deleteErr error
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			ad, createErr = a.CreateAd(ctx, "bike", "text", c.ID, ads.Price{}, nil)
+		}()
+		go func() {
+			defer wg.Done()
+			deleteErr = a.DeleteCategory(ctx, c.ID)
+		}()
+		wg.Wait()
+		if deleteErr == nil {
+			require.ErrorIs(t, createErr, app.ErrInvalidCategory)
+		} else {
+			require.ErrorIs(t, deleteErr, app.ErrCategoryNotEmpty)
+			require.NoError(t, createErr)
+			require.NoError(t, a.DeleteAd(ctx, ad.ID))
+		}
+	}
+}
+
+func TestGRPCCategories(t *testing.T) {
+	a := newTestApp(app.WithAdminEmails("admin@mail.ru"))
+	client := newGRPCClient(t, a)
+	ctx := context.Background()
+
+	_, adminCtx := grpcSignUp(t, ctx, client, "Admin")
+	_, userCtx := grpcSignUp(t, ctx, client, "Petya")
+
+	_, err := client.CreateCategory(userCtx, &grpcPort.CreateCategoryRequest{Name: "Техника"})
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+	tech, err := client.CreateCategory(adminCtx, &grpcPort.CreateCategoryRequest{Name: "Техника"})
+	require.NoError(t, err)
+	phones, err := client.CreateCategory(adminCtx, &grpcPort.CreateCategoryRequest{Name: "Телефоны", ParentId: tech.Id})
+	require.NoError(t, err)
+
+	list, err := client.ListCategories(ctx, &emptypb.Empty{})
+	require.NoError(t, err)
+	assert.Len(t, list.List, 3)
+	got, err := client.GetCategory(ctx, &grpcPort.GetCategoryRequest{Id: phones.Id})
+	require.NoError(t, err)
+	assert.Equal(t, tech.Id, got.ParentId)
+
+	_, err = client.CreateAd(userCtx, &grpcPort.CreateAdRequest{Title: "Телефон", Text: "text"})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	ad, err := client.CreateAd(userCtx, &grpcPort.CreateAdRequest{Title: "Телефон", Text: "text", CategoryId: phones.Id})
+	require.NoError(t, err)
+	assert.Equal(t, phones.Id, ad.CategoryId)
+	_, err = client.ChangeAdStatus(userCtx, &grpcPort.ChangeAdStatusRequest{AdId: ad.Id, Published: true})
+	require.NoError(t, err)
+
+	res, err := client.ListAds(ctx, &grpcPort.ListAdsRequest{CategoryId: tech.Id})
+	require.NoError(t, err)
+	require.Len(t, res.List, 1)
+	assert.Equal(t, ad.Id, res.List[0].Id)
+	res, err = client.FindAds(ctx, &grpcPort.FindAdsRequest{CategoryIds: []int64{testCategoryID}})
+	require.NoError(t, err)
+	assert.Empty(t, res.List)
+	hits, err := client.SearchAds(ctx, &grpcPort.SearchAdsRequest{Query: "телефон", CategoryId: tech.Id})
+	require.NoError(t, err)
+	assert.Len(t, hits.Hits, 1)
+
+	_, err = client.UpdateCategory(adminCtx, &grpcPort.UpdateCategoryRequest{Id: tech.Id, Name: "Техника", ParentId: phones.Id})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	_, err = client.DeleteCategory(adminCtx, &grpcPort.DeleteCategoryRequest{Id: phones.Id})
+	assert.Equal(t, codes.FailedPrecondition, status.Code(err))
+	_, err = client.GetCategory(ctx, &grpcPort.GetCategoryRequest{Id: 100})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+}
@@ -0,0 +1,238 @@
+package tests
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"homework9/internal/config"
+	"homework9/internal/ports/httpgin"
+)
+
+func writeConfig(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+	return path
+}
+
+func envOf(vars map[string]string) func(string) string {
+	return func(key string) string {
+		return vars[key]
+	}
+}
+
+func TestConfigDefaults(t *testing.T) {
+	cfg, err := config.Load(nil, envOf(nil))
+	require.NoError(t, err)
+	assert.Equal(t, config.Default(), cfg)
+	assert.Equal(t, ":18080", cfg.HTTPAddr)
+	assert.Equal(t, ":50054", cfg.GRPCAddr)
+	assert.Equal(t, config.Limits{TitleMin: 1, TitleMax: 100, TextMin: 1, TextMax: 500}, cfg.Limits)
+	assert.True(t, cfg.Moderation.Enabled)
+	assert.Equal(t, 3, cfg.Reports.Threshold)
+
+	cfg, err = config.Load([]string{"-moderation=false"}, envOf(nil))
+	require.NoError(t, err)
+	assert.False(t, cfg.Moderation.Enabled)
+}
+
+func TestConfigPrecedence(t *testing.T) {
+	path := writeConfig(t, `
+http_addr: ":8001"
+grpc_addr: ":9001"
+log_level: warn
+storage:
+  backend: file
+  compact_interval: 1m
+limits:
+  title_max: 50
+  text_max: 200
+`)
+	env := envOf(map[string]string{
+		"ADS_CONFIG":    path,
+		"ADS_GRPC_ADDR": ":9002",
+		"ADS_TEXT_MAX":  "300",
+	})
+
+	cfg, err := config.Load([]string{"-text-max", "400", "-storage-path", "/tmp/x.wal"}, env)
+	require.NoError(t, err)
+	assert.Equal(t, ":8001", cfg.HTTPAddr, "file overrides default")
+	assert.Equal(t, ":9002", cfg.GRPCAddr, "env overrides file")
+	assert.Equal(t, 400, cfg.Limits.TextMax, "flag overrides env")
+	assert.Equal(t, 50, cfg.Limits.TitleMax)
+	assert.Equal(t, 1, cfg.Limits.TitleMin, "keys missing from file keep defaults")
+	assert.Equal(t, config.LogWarn, cfg.LogLevel)
+	assert.Equal(t, time.Minute, cfg.Storage.CompactInterval)
+	assert.Equal(t, "/tmp/x.wal", cfg.Storage.Path)
+}
+
+func TestConfigLogEnabled(t *testing.T) {
+	cfg := config.Config{LogLevel: config.LogWarn}
+	assert.False(t, cfg.LogEnabled(config.LogDebug))
+	assert.False(t, cfg.LogEnabled(config.LogInfo))
+	assert.True(t, cfg.LogEnabled(config.LogWarn))
+	assert.True(t, cfg.LogEnabled(config.LogError))
+
+	cfg.LogLevel = config.LogError
+	assert.False(t, cfg.LogEnabled(config.LogWarn))
+	assert.True(t, cfg.LogEnabled(config.LogError))
+	cfg.LogLevel = config.LogDebug
+	assert.True(t, cfg.LogEnabled(config.LogInfo))
+}
+
+func TestConfigStoragePathDefault(t *testing.T) {
+	cfg, err := config.Load([]string{"-storage", "sqlite"}, envOf(nil))
+	require.NoError(t, err)
+	assert.Equal(t, "data/ads.db", cfg.Storage.Path)
+}
+
+func TestConfigValidation(t *testing.T) {
+	_, err := config.Load([]string{"-storage", "mongo", "-title-min", "10", "-title-max", "5", "-log-level", "loud"}, envOf(nil))
+	require.ErrorIs(t, err, config.ErrInvalid)
+	assert.Contains(t, err.Error(), "storage.backend")
+	assert.Contains(t, err.Error(), "limits.title_max")
+	assert.Contains(t, err.Error(), "log_level")
+
+	_, err = config.Load(nil, envOf(map[string]string{"ADS_SHUTDOWN_TIMEOUT": "soon"}))
+	require.ErrorIs(t, err, config.ErrInvalid)
+	assert.Contains(t, err.Error(), "ADS_SHUTDOWN_TIMEOUT")
+
+	_, err = config.Load([]string{"-http-addr", "localhost"}, envOf(nil))
+	require.ErrorIs(t, err, config.ErrInvalid)
+	assert.Contains(t, err.Error(), "http_addr")
+
+	_, err = config.Load([]string{"-image-max-bytes", "0", "-thumb-size", "5000"}, envOf(nil))
+	require.ErrorIs(t, err, config.ErrInvalid)
+	assert.Contains(t, err.Error(), "images.max_bytes")
+	assert.Contains(t, err.Error(), "images.thumb_size")
+
+	_, err = config.Load([]string{"-schedule-interval", "0s", "-ad-lifetime", "-1h"}, envOf(nil))
+	require.ErrorIs(t, err, config.ErrInvalid)
+	assert.Contains(t, err.Error(), "schedule.interval")
+	assert.Contains(t, err.Error(), "schedule.ad_lifetime")
+
+	_, err = config.Load([]string{"-moderation", "maybe"}, envOf(nil))
+	require.ErrorIs(t, err, config.ErrInvalid)
+	assert.Contains(t, err.Error(), "not a boolean")
+
+	_, err = config.Load([]string{"-spam-check", "block"}, envOf(nil))
+	require.ErrorIs(t, err, config.ErrInvalid)
+	assert.Contains(t, err.Error(), "content.spam")
+
+	_, err = config.Load(nil, envOf(map[string]string{"ADS_REPORT_THRESHOLD": "-1"}))
+	require.ErrorIs(t, err, config.ErrInvalid)
+	assert.Contains(t, err.Error(), "reports.threshold")
+}
+
+func TestConfigContent(t *testing.T) {
+	cfg, err := config.Load(nil, envOf(nil))
+	require.NoError(t, err)
+	assert.Equal(t, config.Content{Contacts: "flag", Spam: "flag", Duplicates: "reject"}, cfg.Content)
+
+	path := writeConfig(t, `
+content:
+  banned_words: [казино, casino]
+  contacts: reject
+`)
+	cfg, err = config.Load([]string{"-duplicates-check", "allow"}, envOf(map[string]string{
+		"ADS_CONFIG":        path,
+		"ADS_FLAGGED_WORDS": "предоплата, prepayment",
+	}))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"казино", "casino"}, cfg.Content.BannedWords)
+	assert.Equal(t, []string{"предоплата", "prepayment"}, cfg.Content.FlaggedWords)
+	assert.Equal(t, "reject", cfg.Content.Contacts)
+	assert.Equal(t, "flag", cfg.Content.Spam)
+	assert.Equal(t, "allow", cfg.Content.Duplicates)
+}
+
+func TestConfigSigningKeys(t *testing.T) {
+	path := writeConfig(t, `
+auth:
+  access_ttl: 5m
+  keys:
+    - id: "2024-02"
+      secret: "`+strings.Repeat("b", 32)+`"
+    - id: "2024-01"
+      secret: "`+strings.Repeat("a", 32)+`"
+`)
+	cfg, err := config.Load([]string{"-config", path}, envOf(nil))
+	require.NoError(t, err)
+	assert.Equal(t, 5*time.Minute, cfg.Auth.AccessTTL)
+	require.Len(t, cfg.Auth.Keys, 2)
+	assert.Equal(t, "2024-02", cfg.Auth.Keys[0].ID)
+
+	cfg, err = config.Load(nil, envOf(map[string]string{"ADS_JWT_KEYS": "k1:" + strings.Repeat("c", 32)}))
+	require.NoError(t, err)
+	assert.Equal(t, []config.SigningKey{{ID: "k1", Secret: strings.Repeat("c", 32)}}, cfg.Auth.Keys)
+
+	_, err = config.Load([]string{"-jwt-keys", "k1:short,k1:" + strings.Repeat("c", 32)}, envOf(nil))
+	require.ErrorIs(t, err, config.ErrInvalid)
+	assert.Contains(t, err.Error(), "auth.keys[0].secret")
+	assert.Contains(t, err.Error(), "duplicated")
+
+	_, err = config.Load(nil, envOf(map[string]string{"ADS_JWT_KEYS": "no-separator-secret"}))
+	require.ErrorIs(t, err, config.ErrInvalid)
+	assert.NotContains(t, err.Error(), "no-separator-secret", "secrets must not leak into errors")
+}
+
+func TestConfigCurrencyRates(t *testing.T) {
+	path := writeConfig(t, `
+currency:
+  base: RUB
+  rates:
+    USD: 92.5
+    EUR: 99.1
+`)
+	cfg, err := config.Load([]string{"-config", path}, envOf(nil))
+	require.NoError(t, err)
+	assert.Equal(t, map[string]float64{"USD": 92.5, "EUR": 99.1}, cfg.Currency.Rates)
+
+	cfg, err = config.Load(nil, envOf(map[string]string{"ADS_CURRENCY_BASE": "USD", "ADS_CURRENCY_RATES": "EUR:1.08"}))
+	require.NoError(t, err)
+	assert.Equal(t, config.Currency{Base: "USD", Rates: map[string]float64{"EUR": 1.08}}, cfg.Currency)
+
+	_, err = config.Load([]string{"-currency-rates", "XYZ:1,USD:-2"}, envOf(nil))
+	require.ErrorIs(t, err, config.ErrInvalid)
+	assert.Contains(t, err.Error(), "currency")
+
+	_, err = config.Load([]string{"-currency-rates", "USD=92"}, envOf(nil))
+	require.ErrorIs(t, err, config.ErrInvalid)
+}
+
+func TestConfigUnknownKey(t *testing.T) {
+	path := writeConfig(t, "htp_addr: \":8001\"\n")
+	_, err := config.Load([]string{"-config", path}, envOf(nil))
+	require.ErrorIs(t, err, config.ErrInvalid)
+	assert.Contains(t, err.Error(), "htp_addr")
+}
+
+func TestConfigMissingFile(t *testing.T) {
+	_, err := config.Load([]string{"-config", filepath.Join(t.TempDir(), "nope.yaml")}, envOf(nil))
+	assert.ErrorIs(t, err, os.ErrNotExist)
+}
+
+func TestCustomAdLimits(t *testing.T) {
+	limits := httpgin.Limits{TitleMin: 3, TitleMax: 10, TextMin: 1, TextMax: 20}
+	client := newTestClient(newTestApp(), httpgin.WithLimits(limits))
+	u, err := client.createUser("Pepe", "pepe@yandex.ru")
+	require.NoError(t, err)
+
+	_, err = client.createAd(u.Data.ID, "ab", "world")
+	assert.ErrorIs(t, err, ErrBadRequest)
+	_, err = client.createAd(u.Data.ID, strings.Repeat("a", 11), "world")
+	assert.ErrorIs(t, err, ErrBadRequest)
+	_, err = client.createAd(u.Data.ID, "title", strings.Repeat("a", 21))
+	assert.ErrorIs(t, err, ErrBadRequest)
+
+	ad, err := client.createAd(u.Data.ID, strings.Repeat("a", 10), strings.Repeat("a", 20))
+	require.NoError(t, err)
+	_, err = client.updateAd(u.Data.ID, ad.Data.ID, "ab", "world")
+	assert.ErrorIs(t, err, ErrBadRequest)
+}
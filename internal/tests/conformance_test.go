@@ -0,0 +1,119 @@
+package tests
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"homework9/internal/adapters/adrepo"
+	"homework9/internal/adapters/categoryrepo"
+	"homework9/internal/adapters/favoriterepo"
+	"homework9/internal/adapters/filerepo"
+	"homework9/internal/adapters/memtx"
+	"homework9/internal/adapters/reportrepo"
+	"homework9/internal/adapters/repotest"
+	"homework9/internal/adapters/sqlrepo"
+	"homework9/internal/adapters/userrepo"
+	"homework9/internal/ads"
+	"homework9/internal/categories"
+	"homework9/internal/favorites"
+	"homework9/internal/reports"
+	"homework9/internal/users"
+)
+
+func openFileStore(t *testing.T) *filerepo.Store {
+	store, err := filerepo.Open(filepath.Join(t.TempDir(), "store.wal"), 0)
+	require.NoError(t, err)
+	t.Cleanup(func() { store.Close() })
+	return store
+}
+
+func openSQLDB(t *testing.T) *sqlrepo.DB {
+	db, err := sqlrepo.Open(context.Background(), filepath.Join(t.TempDir(), "ads.db"))
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+func TestAdRepositoryConformance(t *testing.T) {
+	t.Run("map", func(t *testing.T) {
+		repotest.AdRepository(t, func(t *testing.T) ads.AdRepository { return adrepo.New() })
+	})
+	t.Run("file", func(t *testing.T) {
+		repotest.AdRepository(t, func(t *testing.T) ads.AdRepository { return openFileStore(t).Ads() })
+	})
+	t.Run("sqlite", func(t *testing.T) {
+		repotest.AdRepository(t, func(t *testing.T) ads.AdRepository { return openSQLDB(t).Ads() })
+	})
+}
+
+func TestUserRepositoryConformance(t *testing.T) {
+	t.Run("map", func(t *testing.T) {
+		repotest.UserRepository(t, func(t *testing.T) users.UserRepository { return userrepo.New() })
+	})
+	t.Run("file", func(t *testing.T) {
+		repotest.UserRepository(t, func(t *testing.T) users.UserRepository { return openFileStore(t).Users() })
+	})
+	t.Run("sqlite", func(t *testing.T) {
+		repotest.UserRepository(t, func(t *testing.T) users.UserRepository { return openSQLDB(t).Users() })
+	})
+}
+
+func TestReportRepositoryConformance(t *testing.T) {
+	t.Run("map", func(t *testing.T) {
+		repotest.ReportRepository(t, func(t *testing.T) reports.ReportRepository { return reportrepo.New() })
+	})
+	t.Run("file", func(t *testing.T) {
+		repotest.ReportRepository(t, func(t *testing.T) reports.ReportRepository { return openFileStore(t).Reports() })
+	})
+	t.Run("sqlite", func(t *testing.T) {
+		repotest.ReportRepository(t, func(t *testing.T) reports.ReportRepository { return openSQLDB(t).Reports() })
+	})
+}
+
+func TestFavoriteRepositoryConformance(t *testing.T) {
+	t.Run("map", func(t *testing.T) {
+		repotest.FavoriteRepository(t, func(t *testing.T) favorites.FavoriteRepository { return favoriterepo.New() })
+	})
+	t.Run("file", func(t *testing.T) {
+		repotest.FavoriteRepository(t, func(t *testing.T) favorites.FavoriteRepository { return openFileStore(t).Favorites() })
+	})
+	t.Run("sqlite", func(t *testing.T) {
+		repotest.FavoriteRepository(t, func(t *testing.T) favorites.FavoriteRepository { return openSQLDB(t).Favorites() })
+	})
+}
+
+func TestCategoryRepositoryConformance(t *testing.T) {
+	t.Run("map", func(t *testing.T) {
+		repotest.CategoryRepository(t, func(t *testing.T) categories.CategoryRepository { return categoryrepo.New() })
+	})
+	t.Run("file", func(t *testing.T) {
+		repotest.CategoryRepository(t, func(t *testing.T) categories.CategoryRepository { return openFileStore(t).Categories() })
+	})
+	t.Run("sqlite", func(t *testing.T) {
+		repotest.CategoryRepository(t, func(t *testing.T) categories.CategoryRepository { return openSQLDB(t).Categories() })
+	})
+}
+
+func TestUnitOfWorkConformance(t *testing.T) {
+	t.Run("map", func(t *testing.T) {
+		repotest.UnitOfWork(t, func(t *testing.T) repotest.Backend {
+			adRepo, userRepo := adrepo.New(), userrepo.New()
+			return repotest.Backend{Ads: adRepo, Users: userRepo, UoW: memtx.New(adRepo, userRepo)}
+		})
+	})
+	t.Run("file", func(t *testing.T) {
+		repotest.UnitOfWork(t, func(t *testing.T) repotest.Backend {
+			store := openFileStore(t)
+			return repotest.Backend{Ads: store.Ads(), Users: store.Users(), UoW: store}
+		})
+	})
+	t.Run("sqlite", func(t *testing.T) {
+		repotest.UnitOfWork(t, func(t *testing.T) repotest.Backend {
+			db := openSQLDB(t)
+			return repotest.Backend{Ads: db.Ads(), Users: db.Users(), UoW: db}
+		})
+	})
+}
@@ -0,0 +1,299 @@
+package tests
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"homework9/internal/adapters/adrepo"
+	"homework9/internal/ads"
+	"homework9/internal/app"
+	"homework9/internal/checks"
+	grpcPort "homework9/internal/ports/grpc"
+)
+
+func TestContentCheckers(t *testing.T) {
+	ctx := context.Background()
+	banned := checks.NewWords("banned_words", checks.Reject, []string{"казино", "easy money"})
+	tests := []struct {
+		name    string
+		checker checks.Checker
+		title   string
+		text    string
+		want    []checks.Finding
+	}{
+		{"clean", banned, "Продам велосипед", "Почти новый", nil},
+		{"banned word", banned, "Лучшее КАЗИНО", "заходите", []checks.Finding{
+			{Check: "banned_words", Verdict: checks.Reject, Field: checks.FieldTitle, Reason: `contains "казино"`},
+		}},
+		{"banned phrase", banned, "Work", "Easy, money fast", []checks.Finding{
+			{Check: "banned_words", Verdict: checks.Reject, Field: checks.FieldText, Reason: `contains "easy money"`},
+		}},
+		{"words apart", banned, "Work", "easy to earn money", nil},
+		{"phone", checks.NewContacts(checks.Flag), "Велосипед", "звоните +7 (912) 345-67-89", []checks.Finding{
+			{Check: "contacts", Verdict: checks.Flag, Field: checks.FieldText, Reason: "contains a phone number"},
+		}},
+		{"short number", checks.NewContacts(checks.Flag), "iPhone 12", "цена 12 500, торг", nil},
+		{"link", checks.NewContacts(checks.Flag), "see shop.com", "или https://example.org/x", []checks.Finding{
+			{Check: "contacts", Verdict: checks.Flag, Field: checks.FieldTitle, Reason: "contains a link"},
+			{Check: "contacts", Verdict: checks.Flag, Field: checks.FieldText, Reason: "contains a link"},
+		}},
+		{"repeats", checks.NewSpam(checks.Flag), "Срочноооооо", "продам", []checks.Finding{
+			{Check: "spam", Verdict: checks.Flag, Field: checks.FieldTitle, Reason: "repeats a character too many times"},
+		}},
+		{"caps", checks.NewSpam(checks.Flag), "Диван", "ПРОДАМ ДИВАН НЕДОРОГО", []checks.Finding{
+			{Check: "spam", Verdict: checks.Flag, Field: checks.FieldText, Reason: "written in capital letters"},
+		}},
+		{"short caps", checks.NewSpam(checks.Flag), "БМВ X5", "в хорошем состоянии", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			report, err := checks.Run(ctx, []checks.Checker{tt.checker}, checks.Submission{New: true, Title: tt.title, Text: tt.text})
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, report.Findings)
+			want := checks.Allow
+			for _, f := range tt.want {
+				want = f.Verdict
+			}
+			assert.Equal(t, want, report.Verdict)
+		})
+	}
+}
+
+func TestParseVerdict(t *testing.T) {
+	for _, v := range []checks.Verdict{checks.Allow, checks.Flag, checks.Reject} {
+		parsed, err := checks.ParseVerdict(v.String())
+		require.NoError(t, err)
+		assert.Equal(t, v, parsed)
+	}
+	_, err := checks.ParseVerdict("block")
+	assert.Error(t, err)
+}
+
+func TestContentCheckedAds(t *testing.T) {
+	a := newTestApp(app.WithContentCheckers(
+		checks.NewWords("banned_words", checks.Reject, []string{"casino"}),
+		checks.NewContacts(checks.Flag),
+	))
+	ctx := context.Background()
+	u, err := a.CreateUser(ctx, "Petya", "petya@mail.ru", testPassword)
+	require.NoError(t, err)
+	ctx = app.WithUserID(ctx, u.ID)
+
+	_, err = a.CreateAd(ctx, "Casino", "call 89123456789", testCategoryID, ads.Price{}, nil)
+	require.ErrorIs(t, err, app.ErrContentRejected)
+	var rejected *app.ContentError
+	require.ErrorAs(t, err, &rejected)
+	assert.Len(t, rejected.Findings, 2, "flags are reported together with the rejection")
+	assert.Contains(t, err.Error(), `banned_words: contains "casino"`)
+	assert.NotContains(t, err.Error(), "phone")
+
+	// замечание с решением Flag отправляет объявление модератору даже без модерации
+	ad, err := a.CreateAd(ctx, "Bike", "call 89123456789", testCategoryID, ads.Price{}, nil)
+	require.NoError(t, err)
+	assert.Equal(t, []ads.Flag{{Check: "contacts", Field: checks.FieldText, Reason: "contains a phone number"}}, ad.Flags)
+	ad, err = a.UpdateStatusById(ctx, ad.ID, true)
+	require.NoError(t, err)
+	assert.Equal(t, ads.StatusPending, ad.Status)
+	assert.False(t, ad.Published)
+
+	_, err = a.UpdateAdById(ctx, ad.ID, "Bike", "casino", testCategoryID, nil, nil, 0)
+	assert.ErrorIs(t, err, app.ErrContentRejected)
+
+	// исправленное объявление возвращается в черновики и публикуется без проверки
+	ad, err = a.UpdateAdById(ctx, ad.ID, "Bike", "write me here", testCategoryID, nil, nil, 0)
+	require.NoError(t, err)
+	assert.Empty(t, ad.Flags)
+	assert.Equal(t, ads.StatusDraft, ad.Status)
+	ad, err = a.UpdateStatusById(ctx, ad.ID, true)
+	require.NoError(t, err)
+	assert.Equal(t, ads.StatusPublished, ad.Status)
+
+	// чужое объявление проверки не раскрывают
+	other, err := a.CreateUser(context.Background(), "Vasya", "vasya@mail.ru", testPassword)
+	require.NoError(t, err)
+	_, err = a.UpdateAdById(app.WithUserID(context.Background(), other.ID), ad.ID, "casino", "casino", testCategoryID, nil, nil, 0)
+	assert.ErrorIs(t, err, app.ErrAccessDenied)
+}
+
+func TestContentDuplicates(t *testing.T) {
+	adRepo := adrepo.New()
+	a := newTestAppOn(adRepo, app.WithContentCheckers(checks.NewDuplicates(adRepo, checks.Reject)))
+	ctx := context.Background()
+	u, err := a.CreateUser(ctx, "Petya", "petya@mail.ru", testPassword)
+	require.NoError(t, err)
+	other, err := a.CreateUser(ctx, "Vasya", "vasya@mail.ru", testPassword)
+	require.NoError(t, err)
+	ctx = app.WithUserID(ctx, u.ID)
+
+	ad, err := a.CreateAd(ctx, "Продам диван", "Почти новый.", testCategoryID, ads.Price{}, nil)
+	require.NoError(t, err)
+	_, err = a.CreateAd(ctx, "продам  ДИВАН!", "почти новый", testCategoryID, ads.Price{}, nil)
+	require.ErrorIs(t, err, app.ErrContentRejected)
+	assert.Contains(t, err.Error(), fmt.Sprintf("duplicates ad %d", ad.ID))
+
+	// другой автор может продавать такой же диван
+	_, err = a.CreateAd(app.WithUserID(context.Background(), other.ID), "Продам диван", "Почти новый.", testCategoryID, ads.Price{}, nil)
+	assert.NoError(t, err)
+
+	// объявление не дублирует само себя
+	_, err = a.UpdateAdById(ctx, ad.ID, "Продам диван", "Почти новый!", testCategoryID, nil, nil, 0)
+	assert.NoError(t, err)
+
+	second, err := a.CreateAd(ctx, "Продам кресло", "Почти новое", testCategoryID, ads.Price{}, nil)
+	require.NoError(t, err)
+	_, err = a.UpdateAdById(ctx, second.ID, "Продам диван", "Почти новый", testCategoryID, nil, nil, 0)
+	assert.ErrorIs(t, err, app.ErrContentRejected)
+}
+
+func TestContentFlagsVisibility(t *testing.T) {
+	a := newTestApp(app.WithContentCheckers(checks.NewContacts(checks.Flag)), app.WithAdminEmails("admin@mail.ru"))
+	authorCtx, moderatorCtx := moderationUsers(t, a)
+	strangerCtx := reporters(t, a, 1)[0]
+
+	// модератор одобряет объявление с замечанием, замечание остаётся на опубликованном объявлении
+	ad, err := a.CreateAd(authorCtx, "Bike", "call 89123456789", testCategoryID, ads.Price{}, nil)
+	require.NoError(t, err)
+	_, err = a.UpdateStatusById(authorCtx, ad.ID, true)
+	require.NoError(t, err)
+	_, err = a.ApproveAd(moderatorCtx, ad.ID, "")
+	require.NoError(t, err)
+
+	for _, ctx := range []context.Context{authorCtx, moderatorCtx} {
+		got, err := a.GetAdByID(ctx, ad.ID)
+		require.NoError(t, err)
+		assert.True(t, got.Published)
+		assert.Len(t, got.Flags, 1)
+	}
+	for _, ctx := range []context.Context{strangerCtx, context.Background()} {
+		got, err := a.GetAdByID(ctx, ad.ID)
+		require.NoError(t, err)
+		assert.Empty(t, got.Flags)
+		page, err := a.GetAdsByFilter(ctx, app.FilterOpts{}, app.PageRequest{})
+		require.NoError(t, err)
+		require.Len(t, page.Ads, 1)
+		assert.Empty(t, page.Ads[0].Flags)
+	}
+}
+
+func TestContentHTTP(t *testing.T) {
+	client := newTestClient(newTestApp(app.WithContentCheckers(
+		checks.NewWords("banned_words", checks.Reject, []string{"казино"}),
+		checks.NewSpam(checks.Flag),
+	)))
+	u, err := client.createUser("Petya", "petya@mail.ru")
+	require.NoError(t, err)
+
+	resp, err := client.post("/api/v1/ads", u.Data.ID, map[string]any{
+		"title": "Казино", "text": "ЗАХОДИТЕ СКОРЕЕ", "category_id": testCategoryID,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusUnprocessableEntity, resp.StatusCode)
+	var rejected struct {
+		Error    string `json:"error"`
+		Findings []struct {
+			Check   string `json:"check"`
+			Verdict string `json:"verdict"`
+			Field   string `json:"field"`
+			Reason  string `json:"reason"`
+		} `json:"findings"`
+	}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&rejected))
+	resp.Body.Close()
+	assert.Contains(t, rejected.Error, "казино")
+	require.Len(t, rejected.Findings, 2)
+	assert.Equal(t, "banned_words", rejected.Findings[0].Check)
+	assert.Equal(t, "reject", rejected.Findings[0].Verdict)
+	assert.Equal(t, "title", rejected.Findings[0].Field)
+	assert.Equal(t, "spam", rejected.Findings[1].Check)
+	assert.Equal(t, "flag", rejected.Findings[1].Verdict)
+
+	var flagged struct {
+		Data struct {
+			ID    int64 `json:"id"`
+			Flags []struct {
+				Check  string `json:"check"`
+				Field  string `json:"field"`
+				Reason string `json:"reason"`
+			} `json:"flags"`
+		} `json:"data"`
+	}
+	err = client.sendJSON(http.MethodPost, "/api/v1/ads", u.Data.ID, map[string]any{
+		"title": "Диван", "text": "ЗАХОДИТЕ СКОРЕЕ", "category_id": testCategoryID,
+	}, &flagged)
+	require.NoError(t, err)
+	require.Len(t, flagged.Data.Flags, 1)
+	assert.Equal(t, "spam", flagged.Data.Flags[0].Check)
+	assert.Equal(t, "text", flagged.Data.Flags[0].Field)
+
+	// замечания видит автор, но не другие пользователи
+	other, err := client.createUser("Vasya", "vasya@mail.ru")
+	require.NoError(t, err)
+	path := fmt.Sprintf("/api/v1/ads/%d", flagged.Data.ID)
+	err = client.sendJSON(http.MethodGet, path, u.Data.ID, nil, &flagged)
+	require.NoError(t, err)
+	assert.Len(t, flagged.Data.Flags, 1)
+	resp, err = client.do(http.MethodGet, path, nil, map[string]string{"Authorization": client.bearer(other.Data.ID)})
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode, "flagged ad waits for a moderator unpublished")
+
+	resp, err = client.do(http.MethodPut, fmt.Sprintf("/api/v1/ads/%d", flagged.Data.ID), map[string]any{
+		"title": "Диван", "text": "казино", "category_id": testCategoryID,
+	}, map[string]string{"Authorization": client.bearer(u.Data.ID)})
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusUnprocessableEntity, resp.StatusCode)
+}
+
+// post отправляет JSON от имени пользователя и возвращает ответ с непрочитанным телом
+func (tc *testClient) post(path string, userID int64, body any) (*http.Response, error) {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequest(http.MethodPost, tc.baseURL+path, bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Add("Content-Type", "application/json")
+	tc.authorize(req, userID)
+	return tc.client.Do(req)
+}
+
+func TestGRPCContent(t *testing.T) {
+	client := newGRPCClient(t, newTestApp(app.WithContentCheckers(
+		checks.NewWords("banned_words", checks.Reject, []string{"casino"}),
+		checks.NewContacts(checks.Flag),
+	)))
+	_, ctx := grpcSignUp(t, context.Background(), client, "Petya")
+
+	_, err := client.CreateAd(ctx, &grpcPort.CreateAdRequest{Title: "casino", Text: "see www.example.com", CategoryId: testCategoryID})
+	require.Equal(t, codes.InvalidArgument, status.Code(err))
+	var violations []*errdetails.BadRequest_FieldViolation
+	for _, d := range status.Convert(err).Details() {
+		if br, ok := d.(*errdetails.BadRequest); ok {
+			violations = append(violations, br.FieldViolations...)
+		}
+	}
+	require.Len(t, violations, 2)
+	assert.Equal(t, "title", violations[0].Field)
+	assert.Equal(t, `banned_words (reject): contains "casino"`, violations[0].Description)
+	assert.Equal(t, "text", violations[1].Field)
+	assert.Equal(t, "contacts (flag): contains a link", violations[1].Description)
+
+	ad, err := client.CreateAd(ctx, &grpcPort.CreateAdRequest{Title: "bike", Text: "see www.example.com", CategoryId: testCategoryID})
+	require.NoError(t, err)
+	require.Len(t, ad.Flags, 1)
+	assert.Equal(t, "contacts", ad.Flags[0].Check)
+
+	_, err = client.UpdateAd(ctx, &grpcPort.UpdateAdRequest{AdId: ad.Id, Title: "casino", Text: "bike", CategoryId: testCategoryID})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+}
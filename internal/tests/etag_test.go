@@ -0,0 +1,88 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func (tc *testClient) do(method string, path string, body any, headers map[string]string) (*http.Response, error) {
+	var data []byte
+	if body != nil {
+		var err error
+		data, err = json.Marshal(body)
+		if err != nil {
+			return nil, fmt.Errorf("unable to marshal: %w", err)
+		}
+	}
+	req, err := http.NewRequest(method, tc.baseURL+path, bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("unable to create request: %w", err)
+	}
+	req.Header.Add("Content-Type", "application/json")
+	for k, v := range headers {
+		req.Header.Add(k, v)
+	}
+	resp, err := tc.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	resp.Body.Close()
+	return resp, nil
+}
+
+func TestAdETag(t *testing.T) {
+	client := getTestClient()
+	u, err := client.createUser("Petya", "petya@yahoo.com")
+	require.NoError(t, err)
+	ad, err := client.createAd(u.Data.ID, "Cats", "are cute")
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), ad.Data.Version)
+
+	path := fmt.Sprintf("/api/v1/ads/%d", ad.Data.ID)
+	auth := map[string]string{"Authorization": client.bearer(u.Data.ID)}
+	resp, err := client.do(http.MethodGet, path, nil, auth)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, `"1"`, resp.Header.Get("ETag"))
+
+	resp, err = client.do(http.MethodGet, path, nil, map[string]string{"If-None-Match": `"1"`, "Authorization": client.bearer(u.Data.ID)})
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusNotModified, resp.StatusCode)
+
+	body := map[string]any{"title": "Dogs", "text": "are cute too"}
+	resp, err = client.do(http.MethodPut, path, body, map[string]string{"If-Match": `"1"`, "Authorization": client.bearer(u.Data.ID)})
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, `"2"`, resp.Header.Get("ETag"))
+
+	resp, err = client.do(http.MethodGet, path, nil, map[string]string{"If-None-Match": `"1"`, "Authorization": client.bearer(u.Data.ID)})
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+}
+
+func TestUpdateAdStaleIfMatch(t *testing.T) {
+	client := getTestClient()
+	u, err := client.createUser("Petya", "petya@yahoo.com")
+	require.NoError(t, err)
+	ad, err := client.createAd(u.Data.ID, "Cats", "are cute")
+	require.NoError(t, err)
+	_, err = client.updateAd(u.Data.ID, ad.Data.ID, "Dogs", "are cute too")
+	require.NoError(t, err)
+
+	path := fmt.Sprintf("/api/v1/ads/%d", ad.Data.ID)
+	body := map[string]any{"title": "Birds", "text": "sing"}
+	resp, err := client.do(http.MethodPut, path, body, map[string]string{"If-Match": `"1"`, "Authorization": client.bearer(u.Data.ID)})
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusPreconditionFailed, resp.StatusCode)
+
+	got, err := client.getAdById(u.Data.ID, ad.Data.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "Dogs", got.Data.Title)
+	assert.Equal(t, int64(2), got.Data.Version)
+}
@@ -0,0 +1,510 @@
+package tests
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/websocket"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"homework9/internal/adapters/favoriterepo"
+	"homework9/internal/adapters/messagerepo"
+	"homework9/internal/ads"
+	"homework9/internal/app"
+	"homework9/internal/checks"
+	"homework9/internal/events"
+	grpcPort "homework9/internal/ports/grpc"
+)
+
+func newEventsApp(opts ...app.Option) app.App {
+	return newTestApp(append([]app.Option{
+		app.WithEvents(events.NewHub()),
+		app.WithFavorites(favoriterepo.New()),
+		app.WithMessages(messagerepo.New()),
+	}, opts...)...)
+}
+
+// nextEvent ждёт событие подписки, чтобы зависший тест падал, а не висел
+func nextEvent(t *testing.T, sub *app.EventSubscription) events.Event {
+	t.Helper()
+	select {
+	case e, ok := <-sub.Events():
+		require.True(t, ok, "subscription closed: %v", sub.Err())
+		return e
+	case <-time.After(time.Second):
+		require.FailNow(t, "no event")
+		return events.Event{}
+	}
+}
+
+func assertNoEvent(t *testing.T, sub *app.EventSubscription) {
+	t.Helper()
+	select {
+	case e := <-sub.Events():
+		assert.Failf(t, "unexpected event", "%s for ad %d", e.Kind, e.AdID)
+	default:
+	}
+}
+
+func TestHubDropsSlowSubscriber(t *testing.T) {
+	h := events.NewHub()
+	slow, err := h.Subscribe(1, func(events.Event) bool { return true })
+	require.NoError(t, err)
+	other, err := h.Subscribe(1, func(e events.Event) bool { return e.AdID == 2 })
+	require.NoError(t, err)
+
+	first := h.Publish(events.Event{Kind: events.KindAdStatus, AdID: 1})
+	second := h.Publish(events.Event{Kind: events.KindAdStatus, AdID: 2})
+	assert.Less(t, first.Seq, second.Seq)
+
+	e, ok := <-slow.Events()
+	require.True(t, ok)
+	assert.Equal(t, first.Seq, e.Seq)
+	_, ok = <-slow.Events()
+	assert.False(t, ok, "slow subscriber is dropped instead of blocking the hub")
+	assert.ErrorIs(t, slow.Err(), events.ErrSlowSubscriber)
+
+	e, ok = <-other.Events()
+	require.True(t, ok)
+	assert.Equal(t, int64(2), e.AdID)
+	other.Close()
+	other.Close()
+	_, ok = <-other.Events()
+	assert.False(t, ok)
+	assert.NoError(t, other.Err())
+}
+
+func TestHubResume(t *testing.T) {
+	h := events.NewHub(events.WithHistory(2))
+	for i := int64(1); i <= 3; i++ {
+		h.Publish(events.Event{Kind: events.KindAdCreated, AdID: i})
+	}
+
+	sub, backlog, err := h.SubscribeFrom(1, 1, nil)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(3), sub.Head())
+	require.Len(t, backlog, 2)
+	assert.Equal(t, uint64(2), backlog[0].Seq)
+	assert.Equal(t, uint64(3), backlog[1].Seq)
+	h.Publish(events.Event{Kind: events.KindAdCreated, AdID: 4})
+	e := <-sub.Events()
+	assert.Equal(t, uint64(4), e.Seq, "no gap and no repeat after backlog")
+
+	_, backlog, err = h.SubscribeFrom(4, 1, nil)
+	require.NoError(t, err)
+	assert.Empty(t, backlog)
+	_, _, err = h.SubscribeFrom(1, 1, nil)
+	assert.ErrorIs(t, err, events.ErrHistoryExpired)
+	_, _, err = h.SubscribeFrom(5, 1, nil)
+	assert.ErrorIs(t, err, events.ErrUnknownPosition)
+
+	h.Close()
+	_, ok := <-sub.Events()
+	assert.False(t, ok)
+	assert.ErrorIs(t, sub.Err(), events.ErrClosed)
+	_, err = h.Subscribe(1, nil)
+	assert.ErrorIs(t, err, events.ErrClosed)
+}
+
+func TestWatchAds(t *testing.T) {
+	a := newTestApp(app.WithEvents(events.NewHub(events.WithHistory(2))))
+	ctx := reporters(t, a, 1)[0]
+
+	_, err := a.WatchAds(context.Background(), app.FilterOpts{}, "", 8)
+	assert.ErrorIs(t, err, app.ErrUnauthenticated)
+	_, err = newTestApp().WatchAds(ctx, app.FilterOpts{}, "", 8)
+	assert.ErrorIs(t, err, app.ErrEventsDisabled)
+	_, err = a.WatchAds(ctx, app.FilterOpts{}, "garbage", 8)
+	assert.ErrorIs(t, err, app.ErrInvalidResumeToken)
+
+	w, err := a.WatchAds(ctx, app.FilterOpts{Published: ads.OnlyPublished}, "", 8)
+	require.NoError(t, err)
+	defer w.Close()
+	assert.Empty(t, w.Backlog)
+	start := w.Start
+
+	// черновик под фильтр не подходит, а снятое объявление подходило до изменения
+	ad := publishedAd(t, a, ctx, "bike")
+	e := <-w.Events()
+	assert.Equal(t, events.KindAdStatus, e.Kind)
+	assert.Equal(t, ads.StatusDraft, e.Was.EffectiveStatus())
+	assert.True(t, e.Ad.Published)
+	_, err = a.UpdateStatusById(ctx, ad.ID, false)
+	require.NoError(t, err)
+	e = <-w.Events()
+	assert.Equal(t, events.KindAdStatus, e.Kind)
+	assert.False(t, e.Ad.Published)
+
+	resumed, err := a.WatchAds(ctx, app.FilterOpts{Published: ads.OnlyPublished}, w.ResumeToken(e), 8)
+	require.NoError(t, err)
+	assert.Empty(t, resumed.Backlog)
+	resumed.Close()
+	// с начала наблюдения было три события, а хранятся два
+	_, err = a.WatchAds(ctx, app.FilterOpts{}, start, 8)
+	assert.ErrorIs(t, err, app.ErrResumeTokenExpired)
+}
+
+func TestWatchAdsVisibility(t *testing.T) {
+	a := newEventsApp(app.WithAdminEmails("admin@mail.ru"))
+	authorCtx, moderatorCtx := moderationUsers(t, a)
+	strangerCtx := reporters(t, a, 1)[0]
+	watch := func(ctx context.Context) *app.AdWatch {
+		w, err := a.WatchAds(ctx, app.FilterOpts{}, "", 8)
+		require.NoError(t, err)
+		t.Cleanup(w.Close)
+		return w
+	}
+	author, moderator, stranger := watch(authorCtx), watch(moderatorCtx), watch(strangerCtx)
+	next := func(w *app.AdWatch) events.Event {
+		t.Helper()
+		select {
+		case e := <-w.Events():
+			return w.View(e)
+		case <-time.After(time.Second):
+			require.FailNow(t, "no event")
+			return events.Event{}
+		}
+	}
+
+	ad, err := a.CreateAd(authorCtx, "bike", "text", testCategoryID, ads.Price{}, nil)
+	require.NoError(t, err)
+	for _, w := range []*app.AdWatch{author, moderator} {
+		e := next(w)
+		assert.Equal(t, events.KindAdCreated, e.Kind)
+		require.NotNil(t, e.Ad)
+	}
+	_, err = a.UpdateStatusById(authorCtx, ad.ID, true)
+	require.NoError(t, err)
+	for _, w := range []*app.AdWatch{author, moderator, stranger} {
+		e := next(w)
+		assert.Equal(t, events.KindAdStatus, e.Kind)
+		require.NotNil(t, e.Ad)
+		assert.True(t, e.Ad.Published)
+		if w == stranger {
+			assert.Nil(t, e.Was, "draft is hidden, the first event of a stranger is the publication")
+		} else {
+			assert.NotNil(t, e.Was)
+		}
+	}
+
+	// снятие объявления посторонний видит как уход из выборки, но не само снятое объявление
+	_, err = a.UpdateStatusById(authorCtx, ad.ID, false)
+	require.NoError(t, err)
+	e := next(stranger)
+	assert.Equal(t, events.KindAdStatus, e.Kind)
+	assert.Equal(t, ad.ID, e.AdID)
+	assert.Nil(t, e.Ad)
+	require.NotNil(t, e.Was)
+	assert.True(t, e.Was.Published)
+	e = next(author)
+	require.NotNil(t, e.Ad)
+	assert.False(t, e.Ad.Published)
+
+	_, err = a.UpdateAdById(authorCtx, ad.ID, "bike", "changed", 0, nil, nil, 0)
+	require.NoError(t, err)
+	assert.Equal(t, events.KindAdUpdated, next(author).Kind)
+	select {
+	case e := <-stranger.Events():
+		assert.Failf(t, "unexpected event", "%s for ad %d", e.Kind, e.AdID)
+	default:
+	}
+}
+
+func TestWatchAdsOrder(t *testing.T) {
+	// часы с задержкой растягивают время между фиксацией изменения и его событием
+	var ticks atomic.Int64
+	a := newEventsApp(app.WithClock(func() time.Time {
+		time.Sleep(time.Duration(ticks.Add(1)%3) * 100 * time.Microsecond)
+		return time.Now()
+	}))
+	ctx := reporters(t, a, 1)[0]
+	ad, err := a.CreateAd(ctx, "bike", "text", testCategoryID, ads.Price{}, nil)
+	require.NoError(t, err)
+	const n = 20
+	w, err := a.WatchAds(ctx, app.FilterOpts{}, "", n)
+	require.NoError(t, err)
+	defer w.Close()
+
+	// одновременные изменения одного объявления приходят в порядке фиксации
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			_, err := a.UpdateAdById(ctx, ad.ID, "bike", fmt.Sprintf("text %d", i), 0, nil, nil, 0)
+			assert.NoError(t, err)
+		}(i)
+	}
+	wg.Wait()
+	version := ad.Version
+	for i := 0; i < n; i++ {
+		e := <-w.Events()
+		require.NotNil(t, e.Ad)
+		assert.Equal(t, version+1, e.Ad.Version)
+		assert.Equal(t, e.Ad.Version-1, e.Was.Version)
+		version = e.Ad.Version
+	}
+}
+
+func TestSubscribeEvents(t *testing.T) {
+	a := newEventsApp()
+	ctxs := reporters(t, a, 2)
+	sellerCtx, buyerCtx := ctxs[0], ctxs[1]
+	buyerID, _ := app.UserIDFromContext(buyerCtx)
+
+	_, err := a.SubscribeEvents(context.Background(), 8)
+	assert.ErrorIs(t, err, app.ErrUnauthenticated)
+	_, err = newTestApp().SubscribeEvents(buyerCtx, 8)
+	assert.ErrorIs(t, err, app.ErrEventsDisabled)
+
+	sub, err := a.SubscribeEvents(buyerCtx, 8)
+	require.NoError(t, err)
+	defer sub.Close()
+
+	// без фильтра публикации не приходят
+	publishedAd(t, a, sellerCtx, "bike")
+	assertNoEvent(t, sub)
+
+	require.NoError(t, sub.SetFilter(buyerCtx, &app.FilterOpts{TitleContains: "car"}))
+	publishedAd(t, a, sellerCtx, "bike")
+	assertNoEvent(t, sub)
+	car := publishedAd(t, a, sellerCtx, "red car")
+	e := nextEvent(t, sub)
+	assert.Equal(t, events.KindAdStatus, e.Kind)
+	assert.Equal(t, car.ID, e.AdID)
+	require.NotNil(t, e.Ad)
+	assert.True(t, e.Ad.Published)
+
+	// снятие объявления не публикация
+	_, err = a.UpdateStatusById(sellerCtx, car.ID, false)
+	require.NoError(t, err)
+	assertNoEvent(t, sub)
+	require.NoError(t, sub.SetFilter(buyerCtx, nil))
+	_, err = a.UpdateStatusById(sellerCtx, car.ID, true)
+	require.NoError(t, err)
+	assertNoEvent(t, sub)
+
+	_, err = a.AddFavorite(buyerCtx, car.ID)
+	require.NoError(t, err)
+	_, err = a.UpdateStatusById(sellerCtx, car.ID, false)
+	require.NoError(t, err)
+	e = nextEvent(t, sub)
+	assert.Equal(t, events.KindFavoriteStatus, e.Kind)
+	assert.Equal(t, []int64{buyerID}, e.UserIDs)
+	require.NotNil(t, e.Ad)
+	assert.False(t, e.Ad.Published)
+	require.NoError(t, a.DeleteAd(sellerCtx, car.ID))
+	e = nextEvent(t, sub)
+	assert.Equal(t, events.KindFavoriteStatus, e.Kind)
+	assert.Nil(t, e.Ad, "deleted ad")
+
+	bike := publishedAd(t, a, sellerCtx, "another bike")
+	msg, err := a.ContactSeller(buyerCtx, bike.ID, "hi")
+	require.NoError(t, err)
+	assertNoEvent(t, sub)
+	_, err = a.SendMessage(sellerCtx, msg.ThreadID, "hello")
+	require.NoError(t, err)
+	e = nextEvent(t, sub)
+	assert.Equal(t, events.KindMessage, e.Kind)
+	require.NotNil(t, e.Message)
+	assert.Equal(t, "hello", e.Message.Text)
+}
+
+func TestSubscribeEventsView(t *testing.T) {
+	a := newEventsApp(app.WithContentCheckers(checks.NewContacts(checks.Flag)), app.WithAdminEmails("admin@mail.ru"))
+	authorCtx, moderatorCtx := moderationUsers(t, a)
+	buyerCtx := reporters(t, a, 1)[0]
+
+	subs := make([]*app.EventSubscription, 0, 2)
+	for _, ctx := range []context.Context{authorCtx, buyerCtx} {
+		sub, err := a.SubscribeEvents(ctx, 8)
+		require.NoError(t, err)
+		defer sub.Close()
+		require.NoError(t, sub.SetFilter(ctx, &app.FilterOpts{}))
+		subs = append(subs, sub)
+	}
+
+	ad, err := a.CreateAd(authorCtx, "bike", "call 89123456789", testCategoryID, ads.Price{}, nil)
+	require.NoError(t, err)
+	_, err = a.UpdateStatusById(authorCtx, ad.ID, true)
+	require.NoError(t, err)
+	_, err = a.ApproveAd(moderatorCtx, ad.ID, "ok")
+	require.NoError(t, err)
+
+	// решение модератора и замечания видит только автор
+	own := subs[0].View(nextEvent(t, subs[0]))
+	require.NotNil(t, own.Ad)
+	assert.Len(t, own.Ad.Flags, 1)
+	assert.Equal(t, "ok", own.Ad.Review.Reason)
+	other := subs[1].View(nextEvent(t, subs[1]))
+	require.NotNil(t, other.Ad)
+	assert.Empty(t, other.Ad.Flags)
+	assert.Equal(t, ads.Review{}, other.Ad.Review)
+}
+
+func TestEventsWebSocket(t *testing.T) {
+	client := newTestClient(newEventsApp())
+	seller, err := client.createUser("Petya", "petya@mail.ru")
+	require.NoError(t, err)
+	buyer, err := client.createUser("Vasya", "vasya@mail.ru")
+	require.NoError(t, err)
+	url := "ws" + client.baseURL[len("http"):] + "/api/v1/events"
+
+	resp, err := client.do(http.MethodGet, "/api/v1/events", nil, nil)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+	disabled := newTestClient(newTestApp())
+	user, err := disabled.createUser("Petya", "petya@mail.ru")
+	require.NoError(t, err)
+	resp, err = disabled.do(http.MethodGet, "/api/v1/events", nil, map[string]string{"Authorization": disabled.bearer(user.Data.ID)})
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusNotImplemented, resp.StatusCode)
+
+	ws, err := websocket.Dial(url+"?access_token="+client.tokens[buyer.Data.ID], "", client.baseURL)
+	require.NoError(t, err)
+	defer ws.Close()
+	require.NoError(t, ws.SetReadDeadline(time.Now().Add(5*time.Second)))
+	receive := func() map[string]any {
+		t.Helper()
+		var msg map[string]any
+		require.NoError(t, websocket.JSON.Receive(ws, &msg))
+		return msg
+	}
+
+	require.NoError(t, websocket.JSON.Send(ws, map[string]any{"filter": map[string]any{"price_min": -1}}))
+	assert.Equal(t, "error", receive()["type"])
+	require.NoError(t, websocket.JSON.Send(ws, map[string]any{"filter": map[string]any{"title": "car"}}))
+	assert.Equal(t, "filter", receive()["type"])
+
+	ad, err := client.createAd(seller.Data.ID, "red car", "text")
+	require.NoError(t, err)
+	_, err = client.changeAdStatus(seller.Data.ID, ad.Data.ID, true)
+	require.NoError(t, err)
+	msg := receive()
+	assert.Equal(t, "ad.published", msg["type"])
+	assert.Equal(t, float64(ad.Data.ID), msg["ad_id"])
+	assert.Equal(t, "red car", msg["ad"].(map[string]any)["title"])
+
+	resp, err = client.do(http.MethodPut, fmt.Sprintf("/api/v1/ads/%d/favorite", ad.Data.ID), nil,
+		map[string]string{"Authorization": client.bearer(buyer.Data.ID)})
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	_, err = client.changeAdStatus(seller.Data.ID, ad.Data.ID, false)
+	require.NoError(t, err)
+	// снятое объявление покупателю не видно: приходит только его ID
+	msg = receive()
+	assert.Equal(t, "favorite.status", msg["type"])
+	assert.Equal(t, float64(ad.Data.ID), msg["ad_id"])
+	assert.NotContains(t, msg, "ad")
+
+	_, err = client.changeAdStatus(seller.Data.ID, ad.Data.ID, true)
+	require.NoError(t, err)
+	msg = receive()
+	assert.Equal(t, "ad.published", msg["type"])
+	msg = receive()
+	assert.Equal(t, "favorite.status", msg["type"])
+	assert.Equal(t, true, msg["ad"].(map[string]any)["published"])
+
+	resp, err = client.post(fmt.Sprintf("/api/v1/ads/%d/messages", ad.Data.ID), seller.Data.ID, map[string]any{"text": "hi"})
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode, "seller cannot contact themselves")
+	var sent struct {
+		Data struct {
+			ThreadID int64 `json:"thread_id"`
+		} `json:"data"`
+	}
+	require.NoError(t, client.sendJSON(http.MethodPost, fmt.Sprintf("/api/v1/ads/%d/messages", ad.Data.ID), buyer.Data.ID, map[string]any{"text": "hi"}, &sent))
+	require.NoError(t, client.sendJSON(http.MethodPost, fmt.Sprintf("/api/v1/threads/%d/messages", sent.Data.ThreadID), seller.Data.ID, map[string]any{"text": "hello"}, &sent))
+	msg = receive()
+	assert.Equal(t, "message.new", msg["type"])
+	assert.Equal(t, "hello", msg["message"].(map[string]any)["text"])
+}
+
+func TestGRPCWatchAds(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	client := newGRPCClient(t, newEventsApp())
+	_, sellerCtx := grpcSignUp(t, ctx, client, "Petya")
+	_, watcherCtx := grpcSignUp(t, ctx, client, "Vasya")
+	filter := &grpcPort.FindAdsRequest{TitleContains: "car"}
+
+	recv := func(stream grpcPort.AdService_WatchAdsClient) *grpcPort.AdEvent {
+		t.Helper()
+		e, err := stream.Recv()
+		require.NoError(t, err)
+		return e
+	}
+	watch := func(token string) (grpcPort.AdService_WatchAdsClient, context.CancelFunc) {
+		t.Helper()
+		streamCtx, stop := context.WithCancel(watcherCtx)
+		stream, err := client.WatchAds(streamCtx, &grpcPort.WatchAdsRequest{Filter: filter, ResumeToken: token})
+		require.NoError(t, err)
+		return stream, stop
+	}
+
+	stream, err := client.WatchAds(ctx, &grpcPort.WatchAdsRequest{})
+	require.NoError(t, err)
+	_, err = stream.Recv()
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+	stream, stop := watch("garbage")
+	_, err = stream.Recv()
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	stop()
+
+	stream, stop = watch("")
+	assert.Equal(t, grpcPort.AdEventType_AD_BOOKMARK, recv(stream).Type)
+	_, err = client.CreateAd(sellerCtx, &grpcPort.CreateAdRequest{Title: "bike", Text: "text", CategoryId: testCategoryID})
+	require.NoError(t, err)
+	// чужой черновик наблюдающему не виден, первое событие - публикация
+	car, err := client.CreateAd(sellerCtx, &grpcPort.CreateAdRequest{Title: "red car", Text: "text", CategoryId: testCategoryID})
+	require.NoError(t, err)
+	_, err = client.ChangeAdStatus(sellerCtx, &grpcPort.ChangeAdStatusRequest{AdId: car.Id, Published: true})
+	require.NoError(t, err)
+	e := recv(stream)
+	assert.Equal(t, grpcPort.AdEventType_AD_STATUS_CHANGED, e.Type)
+	assert.Equal(t, car.Id, e.AdId)
+	assert.Equal(t, "red car", e.Ad.Title)
+	assert.Equal(t, "published", e.Ad.Status)
+	_, err = client.UpdateAd(sellerCtx, &grpcPort.UpdateAdRequest{AdId: car.Id, Title: "blue car", Text: "text"})
+	require.NoError(t, err)
+	e = recv(stream)
+	assert.Equal(t, grpcPort.AdEventType_AD_UPDATED, e.Type)
+	assert.Equal(t, "blue car", e.Ad.Title)
+	stop()
+
+	// пока клиент отключён, объявление меняют и удаляют
+	_, err = client.UpdateAd(sellerCtx, &grpcPort.UpdateAdRequest{AdId: car.Id, Title: "green car", Text: "text"})
+	require.NoError(t, err)
+	_, err = client.CreateAd(sellerCtx, &grpcPort.CreateAdRequest{Title: "another bike", Text: "text", CategoryId: testCategoryID})
+	require.NoError(t, err)
+	_, err = client.DeleteAd(sellerCtx, &grpcPort.DeleteAdRequest{AdId: car.Id})
+	require.NoError(t, err)
+
+	stream, stop = watch(e.ResumeToken)
+	defer stop()
+	e = recv(stream)
+	assert.Equal(t, grpcPort.AdEventType_AD_UPDATED, e.Type)
+	assert.Equal(t, "green car", e.Ad.Title)
+	e = recv(stream)
+	assert.Equal(t, grpcPort.AdEventType_AD_DELETED, e.Type)
+	assert.Equal(t, car.Id, e.AdId)
+	assert.Nil(t, e.Ad)
+	bookmark := recv(stream)
+	assert.Equal(t, grpcPort.AdEventType_AD_BOOKMARK, bookmark.Type)
+
+	// токен другого запуска не продолжить без пропусков
+	other := newGRPCClient(t, newEventsApp())
+	_, otherCtx := grpcSignUp(t, ctx, other, "Vasya")
+	stream, err = other.WatchAds(otherCtx, &grpcPort.WatchAdsRequest{ResumeToken: bookmark.ResumeToken})
+	require.NoError(t, err)
+	_, err = stream.Recv()
+	assert.Equal(t, codes.FailedPrecondition, status.Code(err))
+}
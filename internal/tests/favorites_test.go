@@ -0,0 +1,233 @@
+package tests
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"homework9/internal/adapters/favoriterepo"
+	"homework9/internal/ads"
+	"homework9/internal/app"
+	grpcPort "homework9/internal/ports/grpc"
+)
+
+func newFavoritesApp(opts ...app.Option) app.App {
+	return newTestApp(append([]app.Option{app.WithFavorites(favoriterepo.New())}, opts...)...)
+}
+
+// publishedAd создаёт и публикует объявление от имени автора из ctx
+func publishedAd(t *testing.T, a app.App, ctx context.Context, title string) *ads.Ad {
+	t.Helper()
+	ad, err := a.CreateAd(ctx, title, "text", testCategoryID, ads.Price{}, nil)
+	require.NoError(t, err)
+	ad, err = a.UpdateStatusById(ctx, ad.ID, true)
+	require.NoError(t, err)
+	return ad
+}
+
+func TestFavorites(t *testing.T) {
+	clock := newTestClock()
+	a := newFavoritesApp(app.WithClock(clock.Now))
+	ctxs := reporters(t, a, 3)
+	authorCtx, userCtx := ctxs[0], ctxs[1]
+	first := publishedAd(t, a, authorCtx, "first")
+	second := publishedAd(t, a, authorCtx, "second")
+	draft, err := a.CreateAd(authorCtx, "draft", "text", testCategoryID, ads.Price{}, nil)
+	require.NoError(t, err)
+
+	_, err = a.AddFavorite(context.Background(), first.ID)
+	assert.ErrorIs(t, err, app.ErrUnauthenticated)
+	_, err = a.AddFavorite(userCtx, 100)
+	assert.ErrorIs(t, err, app.ErrNotFound)
+	_, err = a.AddFavorite(userCtx, draft.ID)
+	assert.ErrorIs(t, err, app.ErrFavoriteUnavailable)
+
+	f, err := a.AddFavorite(userCtx, first.ID)
+	require.NoError(t, err)
+	assert.Equal(t, first.ID, f.AdID)
+	assert.True(t, f.Available())
+	added := f.Added
+
+	// повторное добавление возвращает прежнюю запись
+	clock.Advance(time.Minute)
+	f, err = a.AddFavorite(userCtx, first.ID)
+	require.NoError(t, err)
+	assert.True(t, added.Equal(f.Added))
+	_, err = a.AddFavorite(userCtx, second.ID)
+	require.NoError(t, err)
+	_, err = a.AddFavorite(ctxs[2], first.ID)
+	require.NoError(t, err)
+
+	counts, err := a.FavoriteCounts(context.Background(), []int64{first.ID, second.ID, draft.ID})
+	require.NoError(t, err)
+	assert.Equal(t, map[int64]int{first.ID: 2, second.ID: 1}, counts)
+
+	page, err := a.ListFavorites(userCtx, app.PageRequest{Limit: 1})
+	require.NoError(t, err)
+	require.Len(t, page.Favorites, 1)
+	assert.Equal(t, second.ID, page.Favorites[0].AdID, "newest first")
+	require.NotEmpty(t, page.NextCursor)
+	page, err = a.ListFavorites(userCtx, app.PageRequest{Limit: 1, Cursor: page.NextCursor})
+	require.NoError(t, err)
+	require.Len(t, page.Favorites, 1)
+	assert.Equal(t, first.ID, page.Favorites[0].AdID)
+	assert.Empty(t, page.NextCursor)
+
+	// снятое и удалённое объявления остаются в избранном с пометкой
+	_, err = a.UpdateStatusById(authorCtx, first.ID, false)
+	require.NoError(t, err)
+	require.NoError(t, a.DeleteAd(authorCtx, second.ID))
+	page, err = a.ListFavorites(userCtx, app.PageRequest{})
+	require.NoError(t, err)
+	require.Len(t, page.Favorites, 2)
+	assert.Nil(t, page.Favorites[0].Ad)
+	assert.False(t, page.Favorites[0].Available())
+	require.NotNil(t, page.Favorites[1].Ad)
+	assert.Equal(t, ads.StatusArchived, page.Favorites[1].Ad.Status)
+	assert.False(t, page.Favorites[1].Available())
+	f, err = a.AddFavorite(userCtx, first.ID)
+	require.NoError(t, err, "unpublished favorite is kept")
+	assert.False(t, f.Available())
+
+	require.NoError(t, a.RemoveFavorite(userCtx, second.ID))
+	assert.ErrorIs(t, a.RemoveFavorite(userCtx, second.ID), app.ErrNotFound)
+
+	// избранное удалённого пользователя не считается
+	gone, _ := app.UserIDFromContext(ctxs[2])
+	require.NoError(t, a.DeleteUser(ctxs[2], gone))
+	counts, err = a.FavoriteCounts(context.Background(), []int64{first.ID})
+	require.NoError(t, err)
+	assert.Equal(t, map[int64]int{first.ID: 1}, counts)
+}
+
+func TestFavoritesDisabled(t *testing.T) {
+	a := newTestApp()
+	ctx := reporters(t, a, 1)[0]
+	ad := publishedAd(t, a, ctx, "hello")
+	_, err := a.AddFavorite(ctx, ad.ID)
+	assert.ErrorIs(t, err, app.ErrFavoritesDisabled)
+	_, err = a.ListFavorites(ctx, app.PageRequest{})
+	assert.ErrorIs(t, err, app.ErrFavoritesDisabled)
+	counts, err := a.FavoriteCounts(ctx, []int64{ad.ID})
+	require.NoError(t, err)
+	assert.Empty(t, counts)
+}
+
+func TestFavoritesHTTP(t *testing.T) {
+	client := newTestClient(newFavoritesApp())
+	author, err := client.createUser("Petya", "petya@mail.ru")
+	require.NoError(t, err)
+	u, err := client.createUser("Vasya", "vasya@mail.ru")
+	require.NoError(t, err)
+	ad, err := client.createAd(author.Data.ID, "hello", "world")
+	require.NoError(t, err)
+
+	favorite := fmt.Sprintf("/api/v1/ads/%d/favorite", ad.Data.ID)
+	resp, err := client.do(http.MethodPut, favorite, nil, map[string]string{"Authorization": client.bearer(u.Data.ID)})
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusConflict, resp.StatusCode, "draft cannot be added")
+	_, err = client.changeAdStatus(author.Data.ID, ad.Data.ID, true)
+	require.NoError(t, err)
+
+	type favoriteData struct {
+		AdID      int64     `json:"ad_id"`
+		AddedAt   time.Time `json:"added_at"`
+		Available bool      `json:"available"`
+		Deleted   bool      `json:"deleted"`
+		Ad        *adData   `json:"ad"`
+	}
+	var added struct {
+		Data favoriteData `json:"data"`
+	}
+	resp, err = client.do(http.MethodPut, favorite, nil, nil)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+	err = client.sendJSON(http.MethodPut, favorite, u.Data.ID, nil, &added)
+	require.NoError(t, err)
+	assert.Equal(t, ad.Data.ID, added.Data.AdID)
+	assert.True(t, added.Data.Available)
+	require.NotNil(t, added.Data.Ad)
+	assert.Equal(t, 1, added.Data.Ad.Favorites)
+
+	got, err := client.getAdById(u.Data.ID, ad.Data.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 1, got.Data.Favorites)
+	list, err := client.listAds()
+	require.NoError(t, err)
+	require.Len(t, list.Data, 1)
+	assert.Equal(t, 1, list.Data[0].Favorites)
+
+	resp, err = client.do(http.MethodDelete, fmt.Sprintf("/api/v1/ads/%d", ad.Data.ID), nil, map[string]string{"Authorization": client.bearer(author.Data.ID)})
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	var favorites struct {
+		Data       []favoriteData `json:"data"`
+		NextCursor *string        `json:"next_cursor"`
+	}
+	err = client.sendJSON(http.MethodGet, "/api/v1/favorites?limit=x", u.Data.ID, nil, &favorites)
+	assert.ErrorIs(t, err, ErrBadRequest)
+	err = client.sendJSON(http.MethodGet, "/api/v1/favorites", u.Data.ID, nil, &favorites)
+	require.NoError(t, err)
+	require.Len(t, favorites.Data, 1)
+	assert.True(t, favorites.Data[0].Deleted)
+	assert.False(t, favorites.Data[0].Available)
+	assert.Nil(t, favorites.Data[0].Ad)
+	assert.Nil(t, favorites.NextCursor)
+
+	resp, err = client.do(http.MethodDelete, favorite, nil, map[string]string{"Authorization": client.bearer(u.Data.ID)})
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	resp, err = client.do(http.MethodDelete, favorite, nil, map[string]string{"Authorization": client.bearer(u.Data.ID)})
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+}
+
+func TestGRPCFavorites(t *testing.T) {
+	client := newGRPCClient(t, newFavoritesApp())
+	ctx := context.Background()
+	_, authorCtx := grpcSignUp(t, ctx, client, "Petya")
+	_, userCtx := grpcSignUp(t, ctx, client, "Vasya")
+
+	ad, err := client.CreateAd(authorCtx, &grpcPort.CreateAdRequest{Title: "ad", Text: "text", CategoryId: testCategoryID})
+	require.NoError(t, err)
+	_, err = client.AddFavorite(userCtx, &grpcPort.FavoriteRequest{AdId: ad.Id})
+	assert.Equal(t, codes.FailedPrecondition, status.Code(err))
+	_, err = client.ChangeAdStatus(authorCtx, &grpcPort.ChangeAdStatusRequest{AdId: ad.Id, Published: true})
+	require.NoError(t, err)
+
+	_, err = client.AddFavorite(ctx, &grpcPort.FavoriteRequest{AdId: ad.Id})
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+	_, err = client.AddFavorite(userCtx, &grpcPort.FavoriteRequest{AdId: ad.Id + 100})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+	f, err := client.AddFavorite(userCtx, &grpcPort.FavoriteRequest{AdId: ad.Id})
+	require.NoError(t, err)
+	assert.True(t, f.Available)
+	assert.Equal(t, int64(1), f.Ad.Favorites)
+
+	list, err := client.ListAds(ctx, &grpcPort.ListAdsRequest{})
+	require.NoError(t, err)
+	require.Len(t, list.List, 1)
+	assert.Equal(t, int64(1), list.List[0].Favorites)
+
+	_, err = client.ChangeAdStatus(authorCtx, &grpcPort.ChangeAdStatusRequest{AdId: ad.Id, Published: false})
+	require.NoError(t, err)
+	favorites, err := client.ListFavorites(userCtx, &grpcPort.ListFavoritesRequest{})
+	require.NoError(t, err)
+	require.Len(t, favorites.List, 1)
+	assert.False(t, favorites.List[0].Available)
+	assert.False(t, favorites.List[0].Deleted)
+	assert.Equal(t, "archived", favorites.List[0].Ad.Status)
+
+	_, err = client.RemoveFavorite(userCtx, &grpcPort.FavoriteRequest{AdId: ad.Id})
+	require.NoError(t, err)
+	_, err = client.RemoveFavorite(userCtx, &grpcPort.FavoriteRequest{AdId: ad.Id})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+}
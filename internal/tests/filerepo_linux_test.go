@@ -0,0 +1,67 @@
+//go:build linux
+
+package tests
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"homework9/internal/adapters/filerepo"
+	"homework9/internal/ads"
+)
+
+// limitFileSize ограничивает размер файлов процесса, чтобы запись за пределом падала с EFBIG, как при нехватке места
+func limitFileSize(t *testing.T, size int64) (restore func()) {
+	t.Helper()
+	var old syscall.Rlimit
+	require.NoError(t, syscall.Getrlimit(syscall.RLIMIT_FSIZE, &old))
+	signal.Ignore(syscall.SIGXFSZ)
+	limit := old
+	limit.Cur = uint64(size)
+	require.NoError(t, syscall.Setrlimit(syscall.RLIMIT_FSIZE, &limit))
+	return func() {
+		require.NoError(t, syscall.Setrlimit(syscall.RLIMIT_FSIZE, &old))
+		signal.Reset(syscall.SIGXFSZ)
+	}
+}
+
+func TestFileRepoFailedWriteAfterCompaction(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "store.wal")
+
+	store, err := filerepo.Open(path, 0)
+	require.NoError(t, err)
+	first, err := store.Ads().AddAd(ctx, ads.Ad{Title: "hello", Text: "world"})
+	assert.NoError(t, err)
+	require.NoError(t, store.Compact())
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+
+	// запись обрывается на середине строки
+	restore := limitFileSize(t, info.Size()+10)
+	_, err = store.Ads().AddAd(ctx, ads.Ad{Title: "lost", Text: "no space"})
+	restore()
+	assert.Error(t, err)
+	after, err := os.Stat(path)
+	require.NoError(t, err)
+	assert.Equal(t, info.Size(), after.Size(), "the snapshot is kept, the torn line is removed")
+
+	second, err := store.Ads().AddAd(ctx, ads.Ad{Title: "cats", Text: "are cute"})
+	assert.NoError(t, err)
+	assert.NoError(t, store.Close())
+
+	store, err = filerepo.Open(path, 0)
+	require.NoError(t, err)
+	defer store.Close()
+	all, err := store.Ads().GetAllAds(ctx)
+	assert.NoError(t, err)
+	require.Len(t, all, 2)
+	assert.Equal(t, []int64{first, second}, []int64{all[0].ID, all[1].ID})
+}
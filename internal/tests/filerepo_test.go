@@ -0,0 +1,259 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"homework9/internal/adapters/filerepo"
+	"homework9/internal/ads"
+	"homework9/internal/categories"
+	"homework9/internal/favorites"
+	"homework9/internal/reports"
+	"homework9/internal/users"
+)
+
+func TestFileRepoReplay(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "store.wal")
+
+	store, err := filerepo.Open(path, 0)
+	assert.NoError(t, err)
+
+	userID, err := store.Users().AddUser(ctx, users.User{Nickname: "Petya", Email: "petya@mail.ru"})
+	assert.NoError(t, err)
+	first, err := store.Ads().AddAd(ctx, ads.Ad{Title: "hello", Text: "world", AuthorID: userID})
+	assert.NoError(t, err)
+	second, err := store.Ads().AddAd(ctx, ads.Ad{Title: "cats", Text: "are cute", AuthorID: userID})
+	assert.NoError(t, err)
+	assert.NoError(t, store.Ads().UpdateById(ctx, first, ads.Ad{Title: "hello", Text: "world", AuthorID: userID, Published: true, Version: 1}))
+	assert.NoError(t, store.Ads().DeleteAdById(ctx, second))
+	assert.NoError(t, store.Close())
+
+	store, err = filerepo.Open(path, 0)
+	assert.NoError(t, err)
+	defer store.Close()
+
+	ad, err := store.Ads().GetAdById(ctx, first)
+	assert.NoError(t, err)
+	assert.Equal(t, first, ad.ID)
+	assert.True(t, ad.Published)
+	assert.Equal(t, int64(2), ad.Version)
+
+	_, err = store.Ads().GetAdById(ctx, second)
+	assert.ErrorIs(t, err, ads.ErrNotFound)
+
+	u, err := store.Users().GetUserByID(ctx, userID)
+	assert.NoError(t, err)
+	assert.Equal(t, "Petya", u.Nickname)
+
+	// удалённый ID не выдаётся повторно
+	third, err := store.Ads().AddAd(ctx, ads.Ad{Title: "dogs", Text: "are funny", AuthorID: userID})
+	assert.NoError(t, err)
+	assert.Equal(t, second+1, third)
+}
+
+func TestFileRepoCompaction(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "store.wal")
+
+	store, err := filerepo.Open(path, 0)
+	assert.NoError(t, err)
+
+	id, err := store.Ads().AddAd(ctx, ads.Ad{Title: "hello", Text: "world"})
+	assert.NoError(t, err)
+	for i := 0; i < 50; i++ {
+		assert.NoError(t, store.Ads().UpdateById(ctx, id, ads.Ad{Title: "hello", Text: "world", Published: i%2 == 0, Version: int64(i + 1)}))
+	}
+	removed, err := store.Ads().AddAd(ctx, ads.Ad{Title: "bye", Text: "world"})
+	assert.NoError(t, err)
+	store.Ads().DeleteAd(ctx, removed)
+
+	before, err := os.Stat(path)
+	assert.NoError(t, err)
+	assert.NoError(t, store.Compact())
+	after, err := os.Stat(path)
+	assert.NoError(t, err)
+	assert.Less(t, after.Size(), before.Size())
+
+	_, err = store.Ads().AddAd(ctx, ads.Ad{Title: "after", Text: "compaction"})
+	assert.NoError(t, err)
+	assert.NoError(t, store.Close())
+
+	store, err = filerepo.Open(path, 0)
+	assert.NoError(t, err)
+	defer store.Close()
+
+	all, err := store.Ads().GetAllAds(ctx)
+	assert.NoError(t, err)
+	assert.Len(t, all, 2)
+
+	next, err := store.Ads().AddAd(ctx, ads.Ad{Title: "next", Text: "one"})
+	assert.NoError(t, err)
+	assert.Equal(t, removed+2, next)
+}
+
+func TestFileRepoTornTail(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "store.wal")
+
+	store, err := filerepo.Open(path, 0)
+	assert.NoError(t, err)
+	_, err = store.Ads().AddAd(ctx, ads.Ad{Title: "hello", Text: "world"})
+	assert.NoError(t, err)
+	assert.NoError(t, store.Close())
+
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0o644)
+	assert.NoError(t, err)
+	_, err = f.WriteString(`{"records":[{"kind":"ad","op":"put","id":1,`)
+	assert.NoError(t, err)
+	assert.NoError(t, f.Close())
+
+	store, err = filerepo.Open(path, 0)
+	assert.NoError(t, err)
+	defer store.Close()
+
+	all, err := store.Ads().GetAllAds(ctx)
+	assert.NoError(t, err)
+	assert.Len(t, all, 1)
+
+	id, err := store.Ads().AddAd(ctx, ads.Ad{Title: "again", Text: "world"})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), id)
+}
+
+func TestFileRepoTransactionReplay(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "store.wal")
+
+	store, err := filerepo.Open(path, 0)
+	assert.NoError(t, err)
+
+	var userID int64
+	err = store.Do(ctx, func(adRepo ads.AdRepository, userRepo users.UserRepository) error {
+		var err error
+		userID, err = userRepo.AddUser(ctx, users.User{Nickname: "Petya"})
+		if err != nil {
+			return err
+		}
+		_, err = adRepo.AddAd(ctx, ads.Ad{Title: "hello", AuthorID: userID})
+		return err
+	})
+	assert.NoError(t, err)
+
+	err = store.Do(ctx, func(adRepo ads.AdRepository, userRepo users.UserRepository) error {
+		userRepo.DeleteUser(ctx, userID)
+		return errors.New("abort")
+	})
+	assert.Error(t, err)
+	assert.NoError(t, store.Close())
+
+	store, err = filerepo.Open(path, 0)
+	assert.NoError(t, err)
+	defer store.Close()
+
+	_, err = store.Users().GetUserByID(ctx, userID)
+	assert.NoError(t, err)
+	all, err := store.Ads().GetAllAds(ctx)
+	assert.NoError(t, err)
+	assert.Len(t, all, 1)
+}
+
+func TestFileRepoCategoriesReplay(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "store.wal")
+
+	store, err := filerepo.Open(path, 0)
+	assert.NoError(t, err)
+
+	root, err := store.Categories().AddCategory(ctx, categories.Category{Name: "Транспорт"})
+	assert.NoError(t, err)
+	child, err := store.Categories().AddCategory(ctx, categories.Category{Name: "Велосипеды", ParentID: root})
+	assert.NoError(t, err)
+	removed, err := store.Categories().AddCategory(ctx, categories.Category{Name: "Лодки", ParentID: root})
+	assert.NoError(t, err)
+	assert.NoError(t, store.Categories().DeleteCategory(ctx, removed))
+	// после сжатия дерево и счётчик ID восстанавливаются из снимка
+	assert.NoError(t, store.Compact())
+	assert.NoError(t, store.Categories().UpdateCategory(ctx, child, categories.Category{Name: "Самокаты", ParentID: root}))
+	assert.NoError(t, store.Close())
+
+	store, err = filerepo.Open(path, 0)
+	assert.NoError(t, err)
+	defer store.Close()
+
+	list, err := store.Categories().ListCategories(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, []categories.Category{
+		{ID: root, Name: "Транспорт"},
+		{ID: child, Name: "Самокаты", ParentID: root},
+	}, list)
+
+	next, err := store.Categories().AddCategory(ctx, categories.Category{Name: "Лыжи"})
+	assert.NoError(t, err)
+	assert.Equal(t, removed+1, next)
+}
+
+func TestFileRepoReportsReplay(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "store.wal")
+
+	store, err := filerepo.Open(path, 0)
+	assert.NoError(t, err)
+
+	first, err := store.Reports().AddReport(ctx, reports.Report{ReporterID: 1, Target: reports.TargetAd, TargetID: 3, Reason: reports.ReasonScam, Status: reports.StatusOpen})
+	assert.NoError(t, err)
+	assert.NoError(t, store.Compact())
+	second, err := store.Reports().AddReport(ctx, reports.Report{ReporterID: 2, Target: reports.TargetUser, TargetID: 1, Reason: reports.ReasonSpam, Status: reports.StatusOpen})
+	assert.NoError(t, err)
+	assert.NoError(t, store.Reports().UpdateReport(ctx, first, reports.Report{Reason: reports.ReasonScam, Status: reports.StatusResolved}))
+	assert.NoError(t, store.Close())
+
+	store, err = filerepo.Open(path, 0)
+	assert.NoError(t, err)
+	defer store.Close()
+
+	list, err := store.Reports().FindReports(ctx, reports.Filter{}, 0, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, []reports.Report{
+		{ID: first, ReporterID: 1, Target: reports.TargetAd, TargetID: 3, Reason: reports.ReasonScam, Status: reports.StatusResolved},
+		{ID: second, ReporterID: 2, Target: reports.TargetUser, TargetID: 1, Reason: reports.ReasonSpam, Status: reports.StatusOpen},
+	}, list)
+
+	// повторные жалобы отсекаются и после перезапуска
+	_, err = store.Reports().AddReport(ctx, reports.Report{ReporterID: 1, Target: reports.TargetAd, TargetID: 3, Reason: reports.ReasonSpam})
+	assert.ErrorIs(t, err, reports.ErrDuplicate)
+	next, err := store.Reports().AddReport(ctx, reports.Report{ReporterID: 1, Target: reports.TargetAd, TargetID: 4, Reason: reports.ReasonSpam})
+	assert.NoError(t, err)
+	assert.Equal(t, second+1, next)
+}
+
+func TestFileRepoFavoritesReplay(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "store.wal")
+
+	store, err := filerepo.Open(path, 0)
+	assert.NoError(t, err)
+
+	assert.NoError(t, store.Favorites().AddFavorite(ctx, favorites.Favorite{UserID: 1, AdID: 1}))
+	assert.NoError(t, store.Favorites().AddFavorite(ctx, favorites.Favorite{UserID: 1, AdID: 2}))
+	assert.NoError(t, store.Compact())
+	assert.NoError(t, store.Favorites().AddFavorite(ctx, favorites.Favorite{UserID: 2, AdID: 1}))
+	assert.NoError(t, store.Favorites().AddFavorite(ctx, favorites.Favorite{UserID: 3, AdID: 2}))
+	assert.NoError(t, store.Favorites().RemoveFavorite(ctx, 1, 2))
+	assert.NoError(t, store.Favorites().DeleteUserFavorites(ctx, 3))
+	assert.NoError(t, store.Close())
+
+	store, err = filerepo.Open(path, 0)
+	assert.NoError(t, err)
+	defer store.Close()
+
+	counts, err := store.Favorites().CountFavorites(ctx, []int64{1, 2})
+	assert.NoError(t, err)
+	assert.Equal(t, map[int64]int{1: 2}, counts)
+	assert.ErrorIs(t, store.Favorites().AddFavorite(ctx, favorites.Favorite{UserID: 2, AdID: 1}), favorites.ErrDuplicate)
+}
@@ -0,0 +1,172 @@
+package tests
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	grpcPort "homework9/internal/ports/grpc"
+)
+
+type locationData struct {
+	Lat  float64 `json:"lat"`
+	Lon  float64 `json:"lon"`
+	City string  `json:"city"`
+}
+
+type placedAdsResponse struct {
+	Data []struct {
+		ID       int64         `json:"id"`
+		Location *locationData `json:"location"`
+	} `json:"data"`
+	NextCursor *string `json:"next_cursor"`
+}
+
+func (r placedAdsResponse) ids() []int64 {
+	res := make([]int64, 0, len(r.Data))
+	for _, ad := range r.Data {
+		res = append(res, ad.ID)
+	}
+	return res
+}
+
+func (tc *testClient) createPlacedAd(userID int64, title string, location any) (adResponse, error) {
+	var response adResponse
+	body := map[string]any{"title": title, "text": "text", "category_id": testCategoryID, "location": location}
+	err := tc.sendJSON(http.MethodPost, "/api/v1/ads", userID, body, &response)
+	return response, err
+}
+
+func TestAdLocations(t *testing.T) {
+	client := newTestClient(newTestApp())
+	u, err := client.createUser("Petya", "petya@mail.ru")
+	require.NoError(t, err)
+
+	for _, location := range []locationData{
+		{Lat: 91, Lon: 0},
+		{Lat: 0, Lon: -180.5},
+		{Lat: 55, Lon: 37, City: strings.Repeat("я", 101)},
+	} {
+		_, err := client.createPlacedAd(u.Data.ID, "bad place", location)
+		assert.ErrorIs(t, err, ErrBadRequest, "%+v", location)
+	}
+
+	var ids []int64
+	for _, location := range []*locationData{
+		{Lat: 55.7558, Lon: 37.6173, City: " Москва "},
+		{Lat: 55.8970, Lon: 37.4297, City: "Химки"},
+		{Lat: 59.9343, Lon: 30.3351, City: "Санкт-Петербург"},
+		nil,
+	} {
+		ad, err := client.createPlacedAd(u.Data.ID, "ad", location)
+		require.NoError(t, err)
+		_, err = client.changeAdStatus(u.Data.ID, ad.Data.ID, true)
+		require.NoError(t, err)
+		ids = append(ids, ad.Data.ID)
+	}
+
+	// без location в запросе место не меняется
+	_, err = client.updateAd(u.Data.ID, ids[0], "ad", "changed")
+	require.NoError(t, err)
+
+	var page placedAdsResponse
+	err = client.sendJSON(http.MethodGet, "/api/v1/ads?lat=55.75&lon=37.61&radius_km=30&sort=distance", u.Data.ID, nil, &page)
+	require.NoError(t, err)
+	assert.Equal(t, []int64{ids[0], ids[1]}, page.ids())
+	assert.Equal(t, &locationData{Lat: 55.7558, Lon: 37.6173, City: "Москва"}, page.Data[0].Location)
+
+	// lat и lon без radius_km задают только точку отсчёта, объявления без места идут последними
+	err = client.sendJSON(http.MethodGet, "/api/v1/ads?lat=59.93&lon=30.33&sort=distance&limit=2", u.Data.ID, nil, &page)
+	require.NoError(t, err)
+	assert.Equal(t, []int64{ids[2], ids[1]}, page.ids())
+	require.NotNil(t, page.NextCursor)
+	err = client.sendJSON(http.MethodGet, "/api/v1/ads?lat=59.93&lon=30.33&sort=distance&limit=2&cursor="+*page.NextCursor, u.Data.ID, nil, &page)
+	require.NoError(t, err)
+	assert.Equal(t, []int64{ids[0], ids[3]}, page.ids())
+	assert.Nil(t, page.Data[1].Location)
+
+	err = client.sendJSON(http.MethodPost, "/api/v1/search?sort=distance&order=desc", u.Data.ID, map[string]any{
+		"near": map[string]any{"lat": 55.75, "lon": 37.61, "radius_km": 1000},
+		"box":  map[string]any{"min_lat": 55, "min_lon": 30, "max_lat": 60, "max_lon": 38},
+	}, &page)
+	require.NoError(t, err)
+	assert.Equal(t, []int64{ids[2], ids[1], ids[0]}, page.ids())
+
+	for _, req := range []struct {
+		method string
+		path   string
+		body   any
+	}{
+		{http.MethodGet, "/api/v1/ads?sort=distance", nil},
+		{http.MethodGet, "/api/v1/ads?radius_km=10", nil},
+		{http.MethodGet, "/api/v1/ads?lat=55&lon=37&radius_km=-1", nil},
+		{http.MethodGet, "/api/v1/ads?lat=95&lon=37&sort=distance", nil},
+		{http.MethodPost, "/api/v1/search", map[string]any{"box": map[string]any{"min_lat": 60, "max_lat": 55}}},
+		{http.MethodPut, fmt.Sprintf("/api/v1/ads/%d", ids[0]), map[string]any{"title": "ad", "text": "text", "location": locationData{Lat: -100}}},
+	} {
+		resp, err := client.do(req.method, req.path, req.body, map[string]string{"Authorization": client.bearer(u.Data.ID)})
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode, req.path)
+	}
+
+	// курсор с другой точкой отсчёта не принимается
+	err = client.sendJSON(http.MethodGet, "/api/v1/ads?lat=59.93&lon=30.33&sort=distance&limit=1", u.Data.ID, nil, &page)
+	require.NoError(t, err)
+	require.NotNil(t, page.NextCursor)
+	resp, err := client.do(http.MethodGet, "/api/v1/ads?lat=55&lon=37&sort=distance&cursor="+*page.NextCursor, nil, nil)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+}
+
+func TestGRPCAdLocations(t *testing.T) {
+	client := newGRPCClient(t, newTestApp())
+	ctx := context.Background()
+	_, userCtx := grpcSignUp(t, ctx, client, "Petya")
+
+	_, err := client.CreateAd(userCtx, &grpcPort.CreateAdRequest{Title: "bad", Text: "place", CategoryId: testCategoryID,
+		Location: &grpcPort.Location{Lat: 100}})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	var ids []int64
+	for _, location := range []*grpcPort.Location{
+		{Lat: 55.7558, Lon: 37.6173, City: "Москва"},
+		{Lat: 54.1931, Lon: 37.6173, City: "Тула"},
+		{Lat: 59.9343, Lon: 30.3351, City: "Санкт-Петербург"},
+	} {
+		ad, err := client.CreateAd(userCtx, &grpcPort.CreateAdRequest{Title: "ad", Text: "text", CategoryId: testCategoryID, Location: location})
+		require.NoError(t, err)
+		assert.Equal(t, location.City, ad.Location.City)
+		_, err = client.ChangeAdStatus(userCtx, &grpcPort.ChangeAdStatusRequest{AdId: ad.Id, Published: true})
+		require.NoError(t, err)
+		ids = append(ids, ad.Id)
+	}
+
+	updated, err := client.UpdateAd(userCtx, &grpcPort.UpdateAdRequest{AdId: ids[1], Title: "ad", Text: "text"})
+	require.NoError(t, err)
+	assert.Equal(t, "Тула", updated.Location.City, "location is kept when omitted")
+
+	moscow := &grpcPort.Point{Lat: 55.75, Lon: 37.61}
+	res, err := client.ListAds(ctx, &grpcPort.ListAdsRequest{Near: &grpcPort.Circle{Center: moscow, RadiusKm: 200},
+		Page: &grpcPort.PageRequest{Sort: grpcPort.SortField_DISTANCE, Desc: true}})
+	require.NoError(t, err)
+	require.Len(t, res.List, 2)
+	assert.Equal(t, []int64{ids[1], ids[0]}, []int64{res.List[0].Id, res.List[1].Id})
+
+	res, err = client.FindAds(ctx, &grpcPort.FindAdsRequest{Box: &grpcPort.Box{MinLat: 54, MinLon: 30, MaxLat: 56, MaxLon: 38},
+		Page: &grpcPort.PageRequest{Sort: grpcPort.SortField_DISTANCE, Origin: &grpcPort.Point{Lat: 54, Lon: 37.6}}})
+	require.NoError(t, err)
+	require.Len(t, res.List, 2)
+	assert.Equal(t, []int64{ids[1], ids[0]}, []int64{res.List[0].Id, res.List[1].Id})
+
+	_, err = client.ListAds(ctx, &grpcPort.ListAdsRequest{Page: &grpcPort.PageRequest{Sort: grpcPort.SortField_DISTANCE}})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	_, err = client.FindAds(ctx, &grpcPort.FindAdsRequest{Near: &grpcPort.Circle{Center: moscow}})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+}
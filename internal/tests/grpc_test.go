@@ -3,13 +3,17 @@ package tests
 import (
 	"context"
 	"google.golang.org/grpc/credentials/insecure"
-	"google.golang.org/protobuf/types/known/emptypb"
+	"google.golang.org/protobuf/types/known/timestamppb"
 	"net"
 	"testing"
 	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
 	"google.golang.org/grpc/test/bufconn"
 	grpcPort "homework9/internal/ports/grpc"
 )
@@ -20,12 +24,13 @@ func TestGRRPCCreateUser(t *testing.T) {
 		lis.Close()
 	})
 
-	srv := grpc.NewServer()
+	a := newTestApp()
+	srv := grpc.NewServer(grpc.UnaryInterceptor(grpcPort.AuthInterceptor(a)))
 	t.Cleanup(func() {
 		srv.Stop()
 	})
 
-	svc := grpcPort.NewService()
+	svc := grpcPort.NewService(a)
 	grpcPort.RegisterAdServiceServer(srv, svc)
 
 	go func() {
@@ -49,7 +54,7 @@ func TestGRRPCCreateUser(t *testing.T) {
 	})
 
 	client := grpcPort.NewAdServiceClient(conn)
-	res, err := client.CreateUser(ctx, &grpcPort.CreateUserRequest{Name: "Oleg"})
+	res, err := client.CreateUser(ctx, &grpcPort.CreateUserRequest{Name: "Oleg", Email: "oleg@mail.ru", Password: testPassword})
 	assert.NoError(t, err, "client.GetUser")
 
 	assert.Equal(t, "Oleg", res.Name)
@@ -61,12 +66,13 @@ func TestGRRPCCreateAd(t *testing.T) {
 		lis.Close()
 	})
 
-	srv := grpc.NewServer()
+	a := newTestApp()
+	srv := grpc.NewServer(grpc.UnaryInterceptor(grpcPort.AuthInterceptor(a)))
 	t.Cleanup(func() {
 		srv.Stop()
 	})
 
-	svc := grpcPort.NewService()
+	svc := grpcPort.NewService(a)
 	grpcPort.RegisterAdServiceServer(srv, svc)
 
 	go func() {
@@ -90,9 +96,8 @@ func TestGRRPCCreateAd(t *testing.T) {
 	})
 
 	client := grpcPort.NewAdServiceClient(conn)
-	user, err := client.CreateUser(ctx, &grpcPort.CreateUserRequest{Name: "Vladimir"})
-	assert.NoError(t, err, "client.CreateUser")
-	res, err := client.CreateAd(ctx, &grpcPort.CreateAdRequest{Title: "Amerike konec", Text: "Ya otvechayu", UserId: user.Id})
+	_, userCtx := grpcSignUp(t, ctx, client, "Vladimir")
+	res, err := client.CreateAd(userCtx, &grpcPort.CreateAdRequest{Title: "Amerike konec", Text: "Ya otvechayu", CategoryId: testCategoryID})
 	assert.NoError(t, err, "client.CreateAd")
 
 	assert.Equal(t, "Amerike konec", res.Title)
@@ -106,12 +111,13 @@ func TestGRRPCChangeAdStatus(t *testing.T) {
 		lis.Close()
 	})
 
-	srv := grpc.NewServer()
+	a := newTestApp()
+	srv := grpc.NewServer(grpc.UnaryInterceptor(grpcPort.AuthInterceptor(a)))
 	t.Cleanup(func() {
 		srv.Stop()
 	})
 
-	svc := grpcPort.NewService()
+	svc := grpcPort.NewService(a)
 	grpcPort.RegisterAdServiceServer(srv, svc)
 
 	go func() {
@@ -135,11 +141,10 @@ func TestGRRPCChangeAdStatus(t *testing.T) {
 	})
 
 	client := grpcPort.NewAdServiceClient(conn)
-	user, err := client.CreateUser(ctx, &grpcPort.CreateUserRequest{Name: "Vladimir"})
-	assert.NoError(t, err, "client.CreateUser")
-	ad, err := client.CreateAd(ctx, &grpcPort.CreateAdRequest{Title: "Amerike konec", Text: "Ya otvechayu", UserId: user.Id})
+	_, userCtx := grpcSignUp(t, ctx, client, "Vladimir")
+	ad, err := client.CreateAd(userCtx, &grpcPort.CreateAdRequest{Title: "Amerike konec", Text: "Ya otvechayu", CategoryId: testCategoryID})
 	assert.NoError(t, err, "client.CreateAd")
-	adUpd, err := client.ChangeAdStatus(ctx, &grpcPort.ChangeAdStatusRequest{AdId: ad.Id, UserId: user.Id, Published: true})
+	adUpd, err := client.ChangeAdStatus(userCtx, &grpcPort.ChangeAdStatusRequest{AdId: ad.Id, Published: true})
 	assert.NoError(t, err, "client.ChangeAdStatus")
 
 	assert.True(t, adUpd.Published)
@@ -151,12 +156,13 @@ func TestGRRPCUpdateAd(t *testing.T) {
 		lis.Close()
 	})
 
-	srv := grpc.NewServer()
+	a := newTestApp()
+	srv := grpc.NewServer(grpc.UnaryInterceptor(grpcPort.AuthInterceptor(a)))
 	t.Cleanup(func() {
 		srv.Stop()
 	})
 
-	svc := grpcPort.NewService()
+	svc := grpcPort.NewService(a)
 	grpcPort.RegisterAdServiceServer(srv, svc)
 
 	go func() {
@@ -180,11 +186,10 @@ func TestGRRPCUpdateAd(t *testing.T) {
 	})
 
 	client := grpcPort.NewAdServiceClient(conn)
-	user, err := client.CreateUser(ctx, &grpcPort.CreateUserRequest{Name: "Vladimir"})
-	assert.NoError(t, err, "client.CreateUser")
-	ad, err := client.CreateAd(ctx, &grpcPort.CreateAdRequest{Title: "Amerike konec", Text: "Ya otvechayu", UserId: user.Id})
+	_, userCtx := grpcSignUp(t, ctx, client, "Vladimir")
+	ad, err := client.CreateAd(userCtx, &grpcPort.CreateAdRequest{Title: "Amerike konec", Text: "Ya otvechayu", CategoryId: testCategoryID})
 	assert.NoError(t, err, "client.CreateAd")
-	adUpd, err := client.UpdateAd(ctx, &grpcPort.UpdateAdRequest{AdId: ad.Id, UserId: user.Id, Title: "Ya poshutil", Text: "A vi poverili?"})
+	adUpd, err := client.UpdateAd(userCtx, &grpcPort.UpdateAdRequest{AdId: ad.Id, Title: "Ya poshutil", Text: "A vi poverili?"})
 	assert.NoError(t, err, "client.UpdateAd")
 
 	assert.Equal(t, adUpd.Text, "A vi poverili?")
@@ -198,12 +203,13 @@ func TestGRRPCListAds(t *testing.T) {
 		lis.Close()
 	})
 
-	srv := grpc.NewServer()
+	a := newTestApp()
+	srv := grpc.NewServer(grpc.UnaryInterceptor(grpcPort.AuthInterceptor(a)))
 	t.Cleanup(func() {
 		srv.Stop()
 	})
 
-	svc := grpcPort.NewService()
+	svc := grpcPort.NewService(a)
 	grpcPort.RegisterAdServiceServer(srv, svc)
 
 	go func() {
@@ -227,23 +233,21 @@ func TestGRRPCListAds(t *testing.T) {
 	})
 
 	client := grpcPort.NewAdServiceClient(conn)
-	user1, err := client.CreateUser(ctx, &grpcPort.CreateUserRequest{Name: "Vladimir"})
-	assert.NoError(t, err, "client.CreateUser")
-	user2, err := client.CreateUser(ctx, &grpcPort.CreateUserRequest{Name: "Dmitry"})
-	assert.NoError(t, err, "client.CreateUser")
-	ad1, err := client.CreateAd(ctx, &grpcPort.CreateAdRequest{Title: "Amerike konec", Text: "Ya otvechayu", UserId: user1.Id})
+	_, user1Ctx := grpcSignUp(t, ctx, client, "Vladimir")
+	_, user2Ctx := grpcSignUp(t, ctx, client, "Dmitry")
+	ad1, err := client.CreateAd(user1Ctx, &grpcPort.CreateAdRequest{Title: "Amerike konec", Text: "Ya otvechayu", CategoryId: testCategoryID})
 	assert.NoError(t, err, "client.CreateAd")
-	ad2, err := client.CreateAd(ctx, &grpcPort.CreateAdRequest{Title: "Europe tozhe", Text: "Ya guarantee", UserId: user1.Id})
+	ad2, err := client.CreateAd(user1Ctx, &grpcPort.CreateAdRequest{Title: "Europe tozhe", Text: "Ya guarantee", CategoryId: testCategoryID})
 	assert.NoError(t, err, "client.CreateAd")
-	ad3, err := client.CreateAd(ctx, &grpcPort.CreateAdRequest{Title: "Nam horosho", Text: "Da", UserId: user2.Id})
+	ad3, err := client.CreateAd(user2Ctx, &grpcPort.CreateAdRequest{Title: "Nam horosho", Text: "Da", CategoryId: testCategoryID})
 	assert.NoError(t, err, "client.CreateAd")
-	ad1, err = client.ChangeAdStatus(ctx, &grpcPort.ChangeAdStatusRequest{AdId: ad1.Id, UserId: user1.Id, Published: true})
+	ad1, err = client.ChangeAdStatus(user1Ctx, &grpcPort.ChangeAdStatusRequest{AdId: ad1.Id, Published: true})
 	assert.NoError(t, err, "client.ChangeAdStatus")
-	ad2, err = client.ChangeAdStatus(ctx, &grpcPort.ChangeAdStatusRequest{AdId: ad2.Id, UserId: user1.Id, Published: true})
+	ad2, err = client.ChangeAdStatus(user1Ctx, &grpcPort.ChangeAdStatusRequest{AdId: ad2.Id, Published: true})
 	assert.NoError(t, err, "client.ChangeAdStatus")
-	ad3, err = client.ChangeAdStatus(ctx, &grpcPort.ChangeAdStatusRequest{AdId: ad3.Id, UserId: user2.Id, Published: true})
+	ad3, err = client.ChangeAdStatus(user2Ctx, &grpcPort.ChangeAdStatusRequest{AdId: ad3.Id, Published: true})
 	assert.NoError(t, err, "client.ChangeAdStatus")
-	ads, err := client.ListAds(ctx, &emptypb.Empty{})
+	ads, err := client.ListAds(ctx, &grpcPort.ListAdsRequest{})
 	assert.NoError(t, err, "client.ListAds")
 
 	assert.Contains(t, []string{ad1.Title, ad2.Title, ad3.Title}, ads.List[0].Title)
@@ -256,12 +260,13 @@ func TestGRRPCDeleteUser(t *testing.T) {
 		lis.Close()
 	})
 
-	srv := grpc.NewServer()
+	a := newTestApp()
+	srv := grpc.NewServer(grpc.UnaryInterceptor(grpcPort.AuthInterceptor(a)))
 	t.Cleanup(func() {
 		srv.Stop()
 	})
 
-	svc := grpcPort.NewService()
+	svc := grpcPort.NewService(a)
 	grpcPort.RegisterAdServiceServer(srv, svc)
 
 	go func() {
@@ -285,11 +290,9 @@ func TestGRRPCDeleteUser(t *testing.T) {
 	})
 
 	client := grpcPort.NewAdServiceClient(conn)
-	_, err = client.CreateUser(ctx, &grpcPort.CreateUserRequest{Name: "Vladimir"})
-	assert.NoError(t, err, "client.CreateUser")
-	user1, err := client.CreateUser(ctx, &grpcPort.CreateUserRequest{Name: "Dmitry"})
-	assert.NoError(t, err, "client.CreateUser")
-	_, err = client.DeleteUser(ctx, &grpcPort.DeleteUserRequest{Id: user1.Id})
+	grpcSignUp(t, ctx, client, "Vladimir")
+	user1, user1Ctx := grpcSignUp(t, ctx, client, "Dmitry")
+	_, err = client.DeleteUser(user1Ctx, &grpcPort.DeleteUserRequest{Id: user1.Id})
 	assert.NoError(t, err, "client.DeleteUser")
 	user, err := client.GetUser(ctx, &grpcPort.GetUserRequest{Id: user1.Id})
 	assert.Error(t, err, "not found")
@@ -302,12 +305,13 @@ func TestGRRPCDeleteAd(t *testing.T) {
 		lis.Close()
 	})
 
-	srv := grpc.NewServer()
+	a := newTestApp()
+	srv := grpc.NewServer(grpc.UnaryInterceptor(grpcPort.AuthInterceptor(a)))
 	t.Cleanup(func() {
 		srv.Stop()
 	})
 
-	svc := grpcPort.NewService()
+	svc := grpcPort.NewService(a)
 	grpcPort.RegisterAdServiceServer(srv, svc)
 
 	go func() {
@@ -331,15 +335,14 @@ func TestGRRPCDeleteAd(t *testing.T) {
 	})
 
 	client := grpcPort.NewAdServiceClient(conn)
-	u, err := client.CreateUser(ctx, &grpcPort.CreateUserRequest{Name: "Vladimir"})
-	assert.NoError(t, err, "client.CreateUser")
-	ad, err := client.CreateAd(ctx, &grpcPort.CreateAdRequest{Title: "What", Text: "The", UserId: u.Id})
+	_, uCtx := grpcSignUp(t, ctx, client, "Vladimir")
+	ad, err := client.CreateAd(uCtx, &grpcPort.CreateAdRequest{Title: "What", Text: "The", CategoryId: testCategoryID})
 	assert.NoError(t, err, "client.CreateAd")
-	_, err = client.ChangeAdStatus(ctx, &grpcPort.ChangeAdStatusRequest{UserId: u.Id, Published: true, AdId: ad.Id})
+	_, err = client.ChangeAdStatus(uCtx, &grpcPort.ChangeAdStatusRequest{Published: true, AdId: ad.Id})
 	assert.NoError(t, err, "client.ChangeAdStatus")
-	_, err = client.DeleteAd(ctx, &grpcPort.DeleteAdRequest{AdId: ad.Id})
+	_, err = client.DeleteAd(uCtx, &grpcPort.DeleteAdRequest{AdId: ad.Id})
 	assert.NoError(t, err, "client.DeleteAd")
-	ads, err := client.ListAds(ctx, &emptypb.Empty{})
+	ads, err := client.ListAds(ctx, &grpcPort.ListAdsRequest{})
 	assert.NoError(t, err, "client.ListAds")
 	assert.NotContains(t, ads.List, ad)
 }
@@ -350,12 +353,13 @@ func TestGRRPCGetUser(t *testing.T) {
 		lis.Close()
 	})
 
-	srv := grpc.NewServer()
+	a := newTestApp()
+	srv := grpc.NewServer(grpc.UnaryInterceptor(grpcPort.AuthInterceptor(a)))
 	t.Cleanup(func() {
 		srv.Stop()
 	})
 
-	svc := grpcPort.NewService()
+	svc := grpcPort.NewService(a)
 	grpcPort.RegisterAdServiceServer(srv, svc)
 
 	go func() {
@@ -379,11 +383,249 @@ func TestGRRPCGetUser(t *testing.T) {
 	})
 
 	client := grpcPort.NewAdServiceClient(conn)
-	_, err = client.CreateUser(ctx, &grpcPort.CreateUserRequest{Name: "Dmitry"})
-	assert.NoError(t, err, "client.GetUser")
-	userCreated, err := client.CreateUser(ctx, &grpcPort.CreateUserRequest{Name: "Pavel"})
-	assert.NoError(t, err, "client.CreateUser")
+	grpcSignUp(t, ctx, client, "Dmitry")
+	userCreated, _ := grpcSignUp(t, ctx, client, "Pavel")
 	userGot, err := client.GetUser(ctx, &grpcPort.GetUserRequest{Id: userCreated.Id})
 	assert.NoError(t, err, "client.GetUser")
 	assert.Equal(t, userCreated.Name, userGot.Name)
 }
+
+func TestGRRPCDeleteUserDeletesAds(t *testing.T) {
+	lis := bufconn.Listen(1024 * 1024)
+	t.Cleanup(func() {
+		lis.Close()
+	})
+
+	a := newTestApp()
+	srv := grpc.NewServer(grpc.UnaryInterceptor(grpcPort.AuthInterceptor(a)))
+	t.Cleanup(func() {
+		srv.Stop()
+	})
+
+	svc := grpcPort.NewService(a)
+	grpcPort.RegisterAdServiceServer(srv, svc)
+
+	go func() {
+		assert.NoError(t, srv.Serve(lis), "srv.Serve")
+	}()
+
+	dialer := func(context.Context, string) (net.Conn, error) {
+		return lis.Dial()
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	t.Cleanup(func() {
+		cancel()
+	})
+
+	conn, err := grpc.DialContext(ctx, "", grpc.WithContextDialer(dialer), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	assert.NoError(t, err, "grpc.DialContext")
+
+	t.Cleanup(func() {
+		conn.Close()
+	})
+
+	client := grpcPort.NewAdServiceClient(conn)
+	_, keeperCtx := grpcSignUp(t, ctx, client, "Dmitry")
+	leaver, leaverCtx := grpcSignUp(t, ctx, client, "Pavel")
+
+	kept, err := client.CreateAd(keeperCtx, &grpcPort.CreateAdRequest{Title: "Stays", Text: "here", CategoryId: testCategoryID})
+	assert.NoError(t, err, "client.CreateAd")
+	_, err = client.ChangeAdStatus(keeperCtx, &grpcPort.ChangeAdStatusRequest{AdId: kept.Id, Published: true})
+	assert.NoError(t, err, "client.ChangeAdStatus")
+	for _, title := range []string{"Goes", "Away"} {
+		ad, err := client.CreateAd(leaverCtx, &grpcPort.CreateAdRequest{Title: title, Text: "with author", CategoryId: testCategoryID})
+		assert.NoError(t, err, "client.CreateAd")
+		_, err = client.ChangeAdStatus(leaverCtx, &grpcPort.ChangeAdStatusRequest{AdId: ad.Id, Published: true})
+		assert.NoError(t, err, "client.ChangeAdStatus")
+	}
+
+	_, err = client.DeleteUser(leaverCtx, &grpcPort.DeleteUserRequest{Id: leaver.Id})
+	assert.NoError(t, err, "client.DeleteUser")
+
+	ads, err := client.ListAds(ctx, &grpcPort.ListAdsRequest{})
+	assert.NoError(t, err, "client.ListAds")
+	assert.Len(t, ads.List, 1)
+	assert.Equal(t, kept.Id, ads.List[0].Id)
+
+	_, err = client.GetUser(ctx, &grpcPort.GetUserRequest{Id: leaver.Id})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+	_, err = client.DeleteUser(leaverCtx, &grpcPort.DeleteUserRequest{Id: leaver.Id})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+}
+
+func TestGRRPCUpdateAdStaleVersion(t *testing.T) {
+	lis := bufconn.Listen(1024 * 1024)
+	t.Cleanup(func() {
+		lis.Close()
+	})
+
+	a := newTestApp()
+	srv := grpc.NewServer(grpc.UnaryInterceptor(grpcPort.AuthInterceptor(a)))
+	t.Cleanup(func() {
+		srv.Stop()
+	})
+
+	svc := grpcPort.NewService(a)
+	grpcPort.RegisterAdServiceServer(srv, svc)
+
+	go func() {
+		assert.NoError(t, srv.Serve(lis), "srv.Serve")
+	}()
+
+	dialer := func(context.Context, string) (net.Conn, error) {
+		return lis.Dial()
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	t.Cleanup(func() {
+		cancel()
+	})
+
+	conn, err := grpc.DialContext(ctx, "", grpc.WithContextDialer(dialer), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	assert.NoError(t, err, "grpc.DialContext")
+
+	t.Cleanup(func() {
+		conn.Close()
+	})
+
+	client := grpcPort.NewAdServiceClient(conn)
+	_, userCtx := grpcSignUp(t, ctx, client, "Vladimir")
+	ad, err := client.CreateAd(userCtx, &grpcPort.CreateAdRequest{Title: "Amerike konec", Text: "Ya otvechayu", CategoryId: testCategoryID})
+	assert.NoError(t, err, "client.CreateAd")
+	assert.Equal(t, int64(1), ad.Version)
+
+	adUpd, err := client.UpdateAd(userCtx, &grpcPort.UpdateAdRequest{AdId: ad.Id, Title: "Ya poshutil", Text: "A vi poverili?", Version: ad.Version})
+	assert.NoError(t, err, "client.UpdateAd")
+	assert.Equal(t, int64(2), adUpd.Version)
+
+	_, err = client.UpdateAd(userCtx, &grpcPort.UpdateAdRequest{AdId: ad.Id, Title: "Net", Text: "Ne poshutil", Version: ad.Version})
+	assert.Equal(t, codes.Aborted, status.Code(err))
+}
+
+func TestGRRPCAuthentication(t *testing.T) {
+	lis := bufconn.Listen(1024 * 1024)
+	t.Cleanup(func() {
+		lis.Close()
+	})
+
+	a := newTestApp()
+	srv := grpc.NewServer(grpc.UnaryInterceptor(grpcPort.AuthInterceptor(a)))
+	t.Cleanup(func() {
+		srv.Stop()
+	})
+
+	svc := grpcPort.NewService(a)
+	grpcPort.RegisterAdServiceServer(srv, svc)
+
+	go func() {
+		assert.NoError(t, srv.Serve(lis), "srv.Serve")
+	}()
+
+	dialer := func(context.Context, string) (net.Conn, error) {
+		return lis.Dial()
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	t.Cleanup(func() {
+		cancel()
+	})
+
+	conn, err := grpc.DialContext(ctx, "", grpc.WithContextDialer(dialer), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	assert.NoError(t, err, "grpc.DialContext")
+
+	t.Cleanup(func() {
+		conn.Close()
+	})
+
+	client := grpcPort.NewAdServiceClient(conn)
+	_, err = client.CreateAd(ctx, &grpcPort.CreateAdRequest{Title: "Amerike konec", Text: "Ya otvechayu", CategoryId: testCategoryID})
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+
+	_, err = client.Login(ctx, &grpcPort.LoginRequest{Email: "vladimir@mail.ru", Password: testPassword})
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+
+	vova, vovaCtx := grpcSignUp(t, ctx, client, "Vladimir")
+	_, dimaCtx := grpcSignUp(t, ctx, client, "Dmitry")
+	_, err = client.DeleteUser(dimaCtx, &grpcPort.DeleteUserRequest{Id: vova.Id})
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+	_, err = client.SetUserRole(vovaCtx, &grpcPort.SetUserRoleRequest{Id: vova.Id, Role: "admin"})
+	assert.Equal(t, codes.PermissionDenied, status.Code(err), "users cannot promote themselves")
+	assert.Equal(t, "user", vova.Role)
+
+	_, err = client.CreateAd(vovaCtx, &grpcPort.CreateAdRequest{Title: "Amerike konec", Text: "Ya otvechayu", CategoryId: testCategoryID})
+	assert.NoError(t, err, "client.CreateAd")
+	forgedCtx := metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer forged")
+	_, err = client.ListAds(forgedCtx, &grpcPort.ListAdsRequest{})
+	assert.Equal(t, codes.Unauthenticated, status.Code(err), "an invalid token is rejected even for public methods")
+
+	tokens, err := client.Login(ctx, &grpcPort.LoginRequest{Email: "vladimir@mail.ru", Password: testPassword})
+	require.NoError(t, err, "client.Login")
+	refreshed, err := client.Refresh(ctx, &grpcPort.RefreshRequest{RefreshToken: tokens.RefreshToken})
+	require.NoError(t, err, "client.Refresh")
+	assert.Equal(t, vova.Id, refreshed.UserId)
+	_, err = client.Refresh(ctx, &grpcPort.RefreshRequest{RefreshToken: tokens.RefreshToken})
+	assert.Equal(t, codes.Unauthenticated, status.Code(err), "refresh token is single use")
+
+	_, err = client.Logout(ctx, &grpcPort.RefreshRequest{RefreshToken: refreshed.RefreshToken})
+	assert.NoError(t, err, "client.Logout")
+	_, err = client.Refresh(ctx, &grpcPort.RefreshRequest{RefreshToken: refreshed.RefreshToken})
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+}
+
+func TestGRRPCFindAds(t *testing.T) {
+	a := newTestApp()
+	client := newGRPCClient(t, a)
+	ctx := context.Background()
+
+	user1, user1Ctx := grpcSignUp(t, ctx, client, "Vladimir")
+	_, user2Ctx := grpcSignUp(t, ctx, client, "Dmitry")
+	ad1, err := client.CreateAd(user1Ctx, &grpcPort.CreateAdRequest{Title: "Velosiped", Text: "Gornyi", CategoryId: testCategoryID})
+	require.NoError(t, err)
+	_, err = client.ChangeAdStatus(user1Ctx, &grpcPort.ChangeAdStatusRequest{AdId: ad1.Id, Published: true})
+	require.NoError(t, err)
+	ad2, err := client.CreateAd(user1Ctx, &grpcPort.CreateAdRequest{Title: "Samokat", Text: "Detskii velosiped v podarok", CategoryId: testCategoryID})
+	require.NoError(t, err)
+	between := timestamppb.Now()
+	ad3, err := client.CreateAd(user2Ctx, &grpcPort.CreateAdRequest{Title: "Velosiped", Text: "Dorozhnyi", CategoryId: testCategoryID})
+	require.NoError(t, err)
+
+	ids := func(res *grpcPort.ListAdResponse) []int64 {
+		var ids []int64
+		for _, ad := range res.List {
+			ids = append(ids, ad.Id)
+		}
+		return ids
+	}
+
+	// метод публичный, токен не нужен, но без него неопубликованные объявления не видны
+	res, err := client.FindAds(ctx, &grpcPort.FindAdsRequest{AuthorIds: []int64{user1.Id}})
+	require.NoError(t, err)
+	assert.Equal(t, []int64{ad1.Id}, ids(res))
+	res, err = client.FindAds(user1Ctx, &grpcPort.FindAdsRequest{AuthorIds: []int64{user1.Id}})
+	require.NoError(t, err)
+	assert.Equal(t, []int64{ad1.Id, ad2.Id}, ids(res))
+
+	// автор видит только свои неопубликованные объявления
+	res, err = client.FindAds(user1Ctx, &grpcPort.FindAdsRequest{Published: grpcPort.PublishedFilter_UNPUBLISHED})
+	require.NoError(t, err)
+	assert.Equal(t, []int64{ad2.Id}, ids(res))
+	res, err = client.FindAds(ctx, &grpcPort.FindAdsRequest{Published: grpcPort.PublishedFilter_UNPUBLISHED})
+	require.NoError(t, err)
+	assert.Empty(t, ids(res))
+
+	res, err = client.FindAds(user2Ctx, &grpcPort.FindAdsRequest{CreatedFrom: between})
+	require.NoError(t, err)
+	assert.Equal(t, []int64{ad3.Id}, ids(res))
+
+	res, err = client.FindAds(ctx, &grpcPort.FindAdsRequest{TitleContains: "VELO", CreatedTo: between})
+	require.NoError(t, err)
+	assert.Equal(t, []int64{ad1.Id}, ids(res))
+
+	res, err = client.FindAds(user1Ctx, &grpcPort.FindAdsRequest{TextContains: "velosiped"})
+	require.NoError(t, err)
+	assert.Equal(t, []int64{ad2.Id}, ids(res))
+
+	_, err = client.FindAds(ctx, &grpcPort.FindAdsRequest{Published: 42})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+}
@@ -0,0 +1,375 @@
+package tests
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"homework9/internal/adapters/blobfs"
+	"homework9/internal/app"
+	"homework9/internal/images"
+	grpcPort "homework9/internal/ports/grpc"
+)
+
+type imageData struct {
+	ID          string `json:"id"`
+	ContentType string `json:"content_type"`
+	Size        int64  `json:"size"`
+	Width       int    `json:"width"`
+	Height      int    `json:"height"`
+	URL         string `json:"url"`
+	ThumbURL    string `json:"thumb_url"`
+}
+
+type imagesResponse struct {
+	Data struct {
+		ID     int64       `json:"id"`
+		Images []imageData `json:"images"`
+	} `json:"data"`
+}
+
+// newImagesApp возвращает приложение с хранилищем фотографий во временном каталоге
+func newImagesApp(t *testing.T, limits app.ImageLimits) (app.App, string) {
+	t.Helper()
+	dir := t.TempDir()
+	store, err := blobfs.New(dir)
+	require.NoError(t, err)
+	return newTestApp(app.WithBlobStore(store), app.WithImageLimits(limits)), dir
+}
+
+func pngImage(t *testing.T, w int, h int) []byte {
+	t.Helper()
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	for x := 0; x < w; x++ {
+		img.Set(x, 0, color.RGBA{R: 200, A: 255})
+	}
+	var buf bytes.Buffer
+	require.NoError(t, png.Encode(&buf, img))
+	return buf.Bytes()
+}
+
+// uploadImage отправляет файл в поле image multipart-формы и возвращает код ответа
+func (tc *testClient) uploadImage(userID int64, adID int64, contentType string, data []byte, out any) (int, error) {
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	header := textproto.MIMEHeader{}
+	header.Set("Content-Disposition", `form-data; name="image"; filename="photo"`)
+	header.Set("Content-Type", contentType)
+	part, err := form.CreatePart(header)
+	if err != nil {
+		return 0, err
+	}
+	if _, err := part.Write(data); err != nil {
+		return 0, err
+	}
+	if err := form.Close(); err != nil {
+		return 0, err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, fmt.Sprintf("%s/api/v1/ads/%d/images", tc.baseURL, adID), &body)
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", form.FormDataContentType())
+	tc.authorize(req, userID)
+	resp, err := tc.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusOK && out != nil {
+		return resp.StatusCode, json.NewDecoder(resp.Body).Decode(out)
+	}
+	return resp.StatusCode, nil
+}
+
+func (tc *testClient) download(path string) (*http.Response, []byte, error) {
+	req, err := http.NewRequest(http.MethodGet, tc.baseURL+path, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	return tc.fetch(req)
+}
+
+// downloadAs - download от имени пользователя
+func (tc *testClient) downloadAs(userID int64, path string) (*http.Response, []byte, error) {
+	req, err := http.NewRequest(http.MethodGet, tc.baseURL+path, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	tc.authorize(req, userID)
+	return tc.fetch(req)
+}
+
+func (tc *testClient) fetch(req *http.Request) (*http.Response, []byte, error) {
+	resp, err := tc.client.Do(req)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	return resp, data, err
+}
+
+// blobFiles возвращает пути всех файлов в каталоге хранилища
+func blobFiles(t *testing.T, dir string) []string {
+	t.Helper()
+	var files []string
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err == nil && !info.IsDir() {
+			files = append(files, path)
+		}
+		return err
+	})
+	require.NoError(t, err)
+	return files
+}
+
+func TestThumbnail(t *testing.T) {
+	// левая половина красная, правая прозрачная и заливается белым
+	img := image.NewNRGBA(image.Rect(10, 10, 14, 12))
+	for x := 10; x < 12; x++ {
+		for y := 10; y < 12; y++ {
+			img.Set(x, y, color.NRGBA{R: 255, A: 255})
+		}
+	}
+	thumb := images.Thumbnail(img, 2)
+	assert.Equal(t, image.Rect(0, 0, 2, 1), thumb.Bounds())
+	assert.Equal(t, color.RGBA{R: 255, A: 255}, thumb.RGBAAt(0, 0))
+	assert.Equal(t, color.RGBA{R: 255, G: 255, B: 255, A: 255}, thumb.RGBAAt(1, 0))
+
+	// превью большой фотографии не требует её полноразмерной копии (4000x3000 RGBA - 48MB)
+	photo := image.NewYCbCr(image.Rect(0, 0, 4000, 3000), image.YCbCrSubsampleRatio420)
+	var before, after runtime.MemStats
+	runtime.ReadMemStats(&before)
+	thumb = images.Thumbnail(photo, 320)
+	runtime.ReadMemStats(&after)
+	assert.Equal(t, image.Rect(0, 0, 320, 240), thumb.Bounds())
+	assert.Less(t, after.TotalAlloc-before.TotalAlloc, uint64(4<<20))
+}
+
+func TestAdImages(t *testing.T) {
+	a, dir := newImagesApp(t, app.ImageLimits{MaxBytes: 64 << 10, PerAd: 2, ThumbSize: 100})
+	client := newTestClient(a)
+	u, err := client.createUser("Petya", "petya@mail.ru")
+	require.NoError(t, err)
+	other, err := client.createUser("Vasya", "vasya@mail.ru")
+	require.NoError(t, err)
+	ad, err := client.createAd(u.Data.ID, "Велосипед", "почти новый")
+	require.NoError(t, err)
+
+	photo := pngImage(t, 400, 200)
+	var uploaded imagesResponse
+	code, err := client.uploadImage(u.Data.ID, ad.Data.ID, "image/png", photo, &uploaded)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, code)
+	require.Len(t, uploaded.Data.Images, 1)
+	first := uploaded.Data.Images[0]
+	assert.Equal(t, "image/png", first.ContentType)
+	assert.Equal(t, int64(len(photo)), first.Size)
+	assert.Equal(t, [2]int{400, 200}, [2]int{first.Width, first.Height})
+
+	// фотографии черновика видны только тем, кому виден он сам
+	for _, url := range []string{first.URL, first.ThumbURL} {
+		resp, _, err := client.download(url)
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusNotFound, resp.StatusCode, url)
+		resp, _, err = client.downloadAs(other.Data.ID, url)
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusNotFound, resp.StatusCode, url)
+	}
+	resp, data, err := client.downloadAs(u.Data.ID, first.URL)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.True(t, strings.HasPrefix(resp.Header.Get("Cache-Control"), "private"))
+	assert.Equal(t, "image/png", resp.Header.Get("Content-Type"))
+	assert.Equal(t, photo, data)
+
+	resp, data, err = client.downloadAs(u.Data.ID, first.ThumbURL)
+	require.NoError(t, err)
+	assert.Equal(t, "image/jpeg", resp.Header.Get("Content-Type"))
+	thumb, err := jpeg.Decode(bytes.NewReader(data))
+	require.NoError(t, err)
+	assert.Equal(t, image.Rect(0, 0, 100, 50), thumb.Bounds(), "thumbnail keeps the aspect ratio")
+
+	// формат определяется по содержимому, заявленный тип тоже проверяется
+	for _, bad := range []struct {
+		contentType string
+		data        []byte
+		code        int
+	}{
+		{"image/png", []byte("not an image at all"), http.StatusUnsupportedMediaType},
+		{"text/plain", photo, http.StatusUnsupportedMediaType},
+		{"image/png", nil, http.StatusUnsupportedMediaType},
+		{"image/png", bytes.Repeat([]byte{0}, 65<<10), http.StatusRequestEntityTooLarge},
+	} {
+		code, err := client.uploadImage(u.Data.ID, ad.Data.ID, bad.contentType, bad.data, nil)
+		require.NoError(t, err)
+		assert.Equal(t, bad.code, code, bad.contentType)
+	}
+	code, err = client.uploadImage(other.Data.ID, ad.Data.ID, "image/png", photo, nil)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusForbidden, code)
+	code, err = client.uploadImage(u.Data.ID, 100, "image/png", photo, nil)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusNotFound, code)
+
+	code, err = client.uploadImage(u.Data.ID, ad.Data.ID, "image/png", pngImage(t, 10, 20), &uploaded)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, code)
+	second := uploaded.Data.Images[1]
+	code, err = client.uploadImage(u.Data.ID, ad.Data.ID, "image/png", photo, nil)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusBadRequest, code, "only two images per ad")
+	assert.Len(t, blobFiles(t, dir), 4, "rejected uploads leave no files")
+
+	// порядок задаётся списком всех фотографий
+	err = client.sendJSON(http.MethodPut, fmt.Sprintf("/api/v1/ads/%d/images", ad.Data.ID), u.Data.ID,
+		map[string]any{"order": []string{second.ID}}, &uploaded)
+	assert.ErrorIs(t, err, ErrBadRequest)
+	err = client.sendJSON(http.MethodPut, fmt.Sprintf("/api/v1/ads/%d/images", ad.Data.ID), u.Data.ID,
+		map[string]any{"order": []string{second.ID, first.ID}}, &uploaded)
+	require.NoError(t, err)
+	assert.Equal(t, []string{second.ID, first.ID}, []string{uploaded.Data.Images[0].ID, uploaded.Data.Images[1].ID})
+
+	// фотографии переживают правку объявления
+	_, err = client.updateAd(u.Data.ID, ad.Data.ID, "Велосипед", "торг")
+	require.NoError(t, err)
+	var got imagesResponse
+	err = client.sendJSON(http.MethodGet, fmt.Sprintf("/api/v1/ads/%d", ad.Data.ID), u.Data.ID, nil, &got)
+	require.NoError(t, err)
+	assert.Len(t, got.Data.Images, 2)
+
+	err = client.sendJSON(http.MethodDelete, fmt.Sprintf("/api/v1/ads/%d/images/%s", ad.Data.ID, second.ID), u.Data.ID, nil, &got)
+	require.NoError(t, err)
+	require.Len(t, got.Data.Images, 1)
+	assert.Equal(t, first.ID, got.Data.Images[0].ID)
+	resp, _, err = client.downloadAs(u.Data.ID, second.URL)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+	assert.Len(t, blobFiles(t, dir), 2)
+
+	// после публикации фотографии видны всем
+	_, err = client.changeAdStatus(u.Data.ID, ad.Data.ID, true)
+	require.NoError(t, err)
+	resp, data, err = client.download(first.URL)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.True(t, strings.HasPrefix(resp.Header.Get("Cache-Control"), "public"))
+	assert.Equal(t, photo, data)
+
+	// удаление объявления удаляет и файлы
+	resp, err = client.do(http.MethodDelete, fmt.Sprintf("/api/v1/ads/%d", ad.Data.ID), nil,
+		map[string]string{"Authorization": client.bearer(u.Data.ID)})
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Empty(t, blobFiles(t, dir))
+}
+
+func TestAdImagesDeletedWithUser(t *testing.T) {
+	a, dir := newImagesApp(t, app.ImageLimits{})
+	client := newTestClient(a)
+	u, err := client.createUser("Petya", "petya@mail.ru")
+	require.NoError(t, err)
+	for i := 0; i < 2; i++ {
+		ad, err := client.createAd(u.Data.ID, "Велосипед", "почти новый")
+		require.NoError(t, err)
+		code, err := client.uploadImage(u.Data.ID, ad.Data.ID, "image/png", pngImage(t, 20, 20), nil)
+		require.NoError(t, err)
+		require.Equal(t, http.StatusOK, code)
+	}
+	assert.Len(t, blobFiles(t, dir), 4)
+
+	resp, err := client.do(http.MethodDelete, fmt.Sprintf("/api/v1/users/%d", u.Data.ID), nil,
+		map[string]string{"Authorization": client.bearer(u.Data.ID)})
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Empty(t, blobFiles(t, dir))
+}
+
+func TestAdImagesDisabled(t *testing.T) {
+	client := getTestClient()
+	u, err := client.createUser("Petya", "petya@mail.ru")
+	require.NoError(t, err)
+	ad, err := client.createAd(u.Data.ID, "Велосипед", "почти новый")
+	require.NoError(t, err)
+
+	code, err := client.uploadImage(u.Data.ID, ad.Data.ID, "image/png", pngImage(t, 20, 20), nil)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusNotImplemented, code)
+}
+
+func TestGRPCUploadAdImage(t *testing.T) {
+	a, _ := newImagesApp(t, app.ImageLimits{MaxBytes: 64 << 10})
+	client := newGRPCClient(t, a)
+	ctx := context.Background()
+	_, userCtx := grpcSignUp(t, ctx, client, "Petya")
+	_, otherCtx := grpcSignUp(t, ctx, client, "Vasya")
+
+	ad, err := client.CreateAd(userCtx, &grpcPort.CreateAdRequest{Title: "Велосипед", Text: "почти новый", CategoryId: testCategoryID})
+	require.NoError(t, err)
+
+	upload := func(ctx context.Context, adID int64, data []byte, chunk int) (*grpcPort.AdResponse, error) {
+		stream, err := client.UploadAdImage(ctx)
+		require.NoError(t, err)
+		msg := &grpcPort.UploadAdImageRequest{AdId: adID}
+		for {
+			n := chunk
+			if n > len(data) {
+				n = len(data)
+			}
+			msg.Chunk = data[:n]
+			data = data[n:]
+			if err := stream.Send(msg); err != nil {
+				break
+			}
+			if len(data) == 0 {
+				break
+			}
+			msg = &grpcPort.UploadAdImageRequest{}
+		}
+		return stream.CloseAndRecv()
+	}
+
+	photo := pngImage(t, 300, 600)
+	res, err := upload(userCtx, ad.Id, photo, 1000)
+	require.NoError(t, err)
+	require.Len(t, res.Images, 1)
+	assert.Equal(t, "image/png", res.Images[0].ContentType)
+	assert.Equal(t, int64(len(photo)), res.Images[0].Size)
+	assert.Equal(t, int32(600), res.Images[0].Height)
+
+	_, err = upload(otherCtx, ad.Id, photo, 1000)
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+	_, err = upload(ctx, ad.Id, photo, 1000)
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+	_, err = upload(userCtx, ad.Id, []byte("plain text"), 4)
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	_, err = upload(userCtx, ad.Id, bytes.Repeat([]byte{1}, 65<<10), 16<<10)
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	// фотографии возвращаются и в остальных ответах с объявлением
+	updated, err := client.ChangeAdStatus(userCtx, &grpcPort.ChangeAdStatusRequest{AdId: ad.Id, Published: true})
+	require.NoError(t, err)
+	require.Len(t, updated.Images, 1)
+	assert.Equal(t, res.Images[0].Id, updated.Images[0].Id)
+}
@@ -0,0 +1,344 @@
+package tests
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"homework9/internal/adapters/messagerepo"
+	"homework9/internal/ads"
+	"homework9/internal/app"
+	grpcPort "homework9/internal/ports/grpc"
+)
+
+func newMessagingApp(opts ...app.Option) app.App {
+	return newTestApp(append([]app.Option{app.WithMessages(messagerepo.New())}, opts...)...)
+}
+
+func TestMessages(t *testing.T) {
+	clock := newTestClock()
+	a := newMessagingApp(app.WithClock(clock.Now))
+	ctxs := reporters(t, a, 3)
+	sellerCtx, buyerCtx, strangerCtx := ctxs[0], ctxs[1], ctxs[2]
+	sellerID, _ := app.UserIDFromContext(sellerCtx)
+	buyerID, _ := app.UserIDFromContext(buyerCtx)
+	ad := publishedAd(t, a, sellerCtx, "bike")
+	draft, err := a.CreateAd(sellerCtx, "draft", "text", testCategoryID, ads.Price{}, nil)
+	require.NoError(t, err)
+
+	_, err = a.ContactSeller(context.Background(), ad.ID, "hi")
+	assert.ErrorIs(t, err, app.ErrUnauthenticated)
+	_, err = a.ContactSeller(buyerCtx, ad.ID, "  ")
+	assert.ErrorIs(t, err, app.ErrInvalidMessage)
+	_, err = a.ContactSeller(buyerCtx, ad.ID, strings.Repeat("я", app.MaxMessageLength+1))
+	assert.ErrorIs(t, err, app.ErrInvalidMessage)
+	_, err = a.ContactSeller(sellerCtx, ad.ID, "hi")
+	assert.ErrorIs(t, err, app.ErrSelfMessage)
+	_, err = a.ContactSeller(buyerCtx, draft.ID, "hi")
+	assert.ErrorIs(t, err, app.ErrThreadUnavailable)
+	_, err = a.ContactSeller(buyerCtx, 100, "hi")
+	assert.ErrorIs(t, err, app.ErrNotFound)
+
+	first, err := a.ContactSeller(buyerCtx, ad.ID, " is it available? ")
+	require.NoError(t, err)
+	assert.Equal(t, "is it available?", first.Text)
+	assert.Equal(t, buyerID, first.SenderID)
+	clock.Advance(time.Minute)
+	second, err := a.ContactSeller(buyerCtx, ad.ID, "hello?")
+	require.NoError(t, err)
+	assert.Equal(t, first.ThreadID, second.ThreadID, "same thread for the same ad and buyer")
+	threadID := first.ThreadID
+
+	inbox, err := a.ListThreads(sellerCtx, nil, app.PageRequest{})
+	require.NoError(t, err)
+	require.Len(t, inbox.Threads, 1)
+	assert.Equal(t, 2, inbox.Threads[0].Unread)
+	assert.Equal(t, sellerID, inbox.Threads[0].SellerID)
+	assert.Equal(t, buyerID, inbox.Threads[0].BuyerID)
+	inbox, err = a.ListThreads(buyerCtx, nil, app.PageRequest{})
+	require.NoError(t, err)
+	require.Len(t, inbox.Threads, 1)
+	assert.Zero(t, inbox.Threads[0].Unread, "own messages are read")
+	inbox, err = a.ListThreads(sellerCtx, &draft.ID, app.PageRequest{})
+	require.NoError(t, err)
+	assert.Empty(t, inbox.Threads)
+
+	// чужая переписка не видна
+	_, err = a.ListMessages(strangerCtx, threadID, app.PageRequest{})
+	assert.ErrorIs(t, err, app.ErrNotFound)
+	_, err = a.SendMessage(strangerCtx, threadID, "me too")
+	assert.ErrorIs(t, err, app.ErrNotFound)
+
+	page, err := a.ListMessages(sellerCtx, threadID, app.PageRequest{Limit: 1})
+	require.NoError(t, err)
+	require.Len(t, page.Messages, 1)
+	assert.Equal(t, first.ID, page.Messages[0].ID)
+	assert.False(t, page.Thread.ReadBy(page.Messages[0]))
+	require.NotEmpty(t, page.NextCursor)
+	page, err = a.ListMessages(sellerCtx, threadID, app.PageRequest{Limit: 1, Cursor: page.NextCursor})
+	require.NoError(t, err)
+	require.Len(t, page.Messages, 1)
+	assert.Equal(t, second.ID, page.Messages[0].ID)
+	assert.Empty(t, page.NextCursor)
+	assert.Equal(t, 2, page.Thread.Unread, "listing does not mark read")
+
+	view, err := a.MarkThreadRead(sellerCtx, threadID)
+	require.NoError(t, err)
+	assert.Zero(t, view.Unread)
+	page, err = a.ListMessages(buyerCtx, threadID, app.PageRequest{})
+	require.NoError(t, err)
+	for _, m := range page.Messages {
+		assert.True(t, page.Thread.ReadBy(m))
+	}
+
+	clock.Advance(time.Minute)
+	reply, err := a.SendMessage(sellerCtx, threadID, "yes")
+	require.NoError(t, err)
+	assert.Equal(t, sellerID, reply.SenderID)
+	inbox, err = a.ListThreads(buyerCtx, nil, app.PageRequest{})
+	require.NoError(t, err)
+	assert.Equal(t, 1, inbox.Threads[0].Unread)
+	assert.True(t, reply.Sent.Equal(inbox.Threads[0].LastMessageAt))
+
+	// начатая переписка продолжается и после снятия объявления
+	_, err = a.UpdateStatusById(sellerCtx, ad.ID, false)
+	require.NoError(t, err)
+	_, err = a.ContactSeller(buyerCtx, ad.ID, "still there?")
+	require.NoError(t, err)
+}
+
+func TestMessagesInboxOrder(t *testing.T) {
+	clock := newTestClock()
+	a := newMessagingApp(app.WithClock(clock.Now))
+	ctxs := reporters(t, a, 3)
+	sellerCtx := ctxs[0]
+	first := publishedAd(t, a, sellerCtx, "first")
+	second := publishedAd(t, a, sellerCtx, "second")
+
+	older, err := a.ContactSeller(ctxs[1], first.ID, "hi")
+	require.NoError(t, err)
+	clock.Advance(time.Minute)
+	newer, err := a.ContactSeller(ctxs[2], second.ID, "hi")
+	require.NoError(t, err)
+
+	page, err := a.ListThreads(sellerCtx, nil, app.PageRequest{Limit: 1})
+	require.NoError(t, err)
+	require.Len(t, page.Threads, 1)
+	assert.Equal(t, newer.ThreadID, page.Threads[0].ID, "newest activity first")
+	page, err = a.ListThreads(sellerCtx, nil, app.PageRequest{Limit: 1, Cursor: page.NextCursor})
+	require.NoError(t, err)
+	require.Len(t, page.Threads, 1)
+	assert.Equal(t, older.ThreadID, page.Threads[0].ID)
+	assert.Empty(t, page.NextCursor)
+
+	byAd, err := a.ListThreads(sellerCtx, &first.ID, app.PageRequest{})
+	require.NoError(t, err)
+	require.Len(t, byAd.Threads, 1)
+	assert.Equal(t, older.ThreadID, byAd.Threads[0].ID)
+
+	_, err = a.ListThreads(sellerCtx, nil, app.PageRequest{Cursor: "bad"})
+	assert.ErrorIs(t, err, app.ErrInvalidCursor)
+}
+
+func TestMessagesBlock(t *testing.T) {
+	a := newMessagingApp()
+	ctxs := reporters(t, a, 3)
+	sellerCtx, buyerCtx := ctxs[0], ctxs[1]
+	sellerID, _ := app.UserIDFromContext(sellerCtx)
+	buyerID, _ := app.UserIDFromContext(buyerCtx)
+	ad := publishedAd(t, a, sellerCtx, "bike")
+	m, err := a.ContactSeller(buyerCtx, ad.ID, "hi")
+	require.NoError(t, err)
+
+	assert.ErrorIs(t, a.BlockUser(sellerCtx, sellerID), app.ErrSelfMessage)
+	assert.ErrorIs(t, a.BlockUser(sellerCtx, 100), app.ErrNotFound)
+	require.NoError(t, a.BlockUser(sellerCtx, buyerID))
+	require.NoError(t, a.BlockUser(sellerCtx, buyerID), "blocking twice changes nothing")
+
+	// блокировка закрывает переписку в обе стороны
+	_, err = a.ContactSeller(buyerCtx, ad.ID, "hello?")
+	assert.ErrorIs(t, err, app.ErrConversationBlocked)
+	_, err = a.SendMessage(sellerCtx, m.ThreadID, "go away")
+	assert.ErrorIs(t, err, app.ErrConversationBlocked)
+	inbox, err := a.ListThreads(buyerCtx, nil, app.PageRequest{})
+	require.NoError(t, err)
+	require.Len(t, inbox.Threads, 1)
+	assert.True(t, inbox.Threads[0].Blocked)
+
+	// новую переписку с заблокировавшим тоже не начать
+	other := publishedAd(t, a, sellerCtx, "car")
+	_, err = a.ContactSeller(buyerCtx, other.ID, "hi")
+	assert.ErrorIs(t, err, app.ErrConversationBlocked)
+	inbox, err = a.ListThreads(sellerCtx, &other.ID, app.PageRequest{})
+	require.NoError(t, err)
+	assert.Empty(t, inbox.Threads, "no empty thread left behind")
+
+	assert.ErrorIs(t, a.UnblockUser(buyerCtx, sellerID), app.ErrNotFound)
+	require.NoError(t, a.UnblockUser(sellerCtx, buyerID))
+	_, err = a.ContactSeller(buyerCtx, ad.ID, "hello?")
+	require.NoError(t, err)
+}
+
+func TestMessagesDisabled(t *testing.T) {
+	a := newTestApp()
+	ctxs := reporters(t, a, 2)
+	ad := publishedAd(t, a, ctxs[0], "bike")
+	_, err := a.ContactSeller(ctxs[1], ad.ID, "hi")
+	assert.ErrorIs(t, err, app.ErrMessagesDisabled)
+	_, err = a.ListThreads(ctxs[1], nil, app.PageRequest{})
+	assert.ErrorIs(t, err, app.ErrMessagesDisabled)
+}
+
+func TestMessagesHTTP(t *testing.T) {
+	client := newTestClient(newMessagingApp())
+	seller, err := client.createUser("Petya", "petya@mail.ru")
+	require.NoError(t, err)
+	buyer, err := client.createUser("Vasya", "vasya@mail.ru")
+	require.NoError(t, err)
+	ad, err := client.createAd(seller.Data.ID, "hello", "world")
+	require.NoError(t, err)
+	_, err = client.changeAdStatus(seller.Data.ID, ad.Data.ID, true)
+	require.NoError(t, err)
+
+	type messageData struct {
+		ID       int64     `json:"id"`
+		ThreadID int64     `json:"thread_id"`
+		SenderID int64     `json:"sender_id"`
+		Text     string    `json:"text"`
+		SentAt   time.Time `json:"sent_at"`
+		Read     bool      `json:"read"`
+	}
+	type threadData struct {
+		ID      int64 `json:"id"`
+		AdID    int64 `json:"ad_id"`
+		Unread  int   `json:"unread"`
+		Blocked bool  `json:"blocked"`
+	}
+	var sent struct {
+		Data messageData `json:"data"`
+	}
+	contact := fmt.Sprintf("/api/v1/ads/%d/messages", ad.Data.ID)
+	resp, err := client.do(http.MethodPost, contact, map[string]any{"text": "hi"}, nil)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+	err = client.sendJSON(http.MethodPost, contact, buyer.Data.ID, map[string]any{"text": ""}, &sent)
+	assert.ErrorIs(t, err, ErrBadRequest)
+	err = client.sendJSON(http.MethodPost, contact, buyer.Data.ID, map[string]any{"text": "is it available?"}, &sent)
+	require.NoError(t, err)
+	assert.Equal(t, buyer.Data.ID, sent.Data.SenderID)
+	assert.False(t, sent.Data.Read)
+	threadID := sent.Data.ThreadID
+
+	var inbox struct {
+		Data       []threadData `json:"data"`
+		NextCursor *string      `json:"next_cursor"`
+	}
+	err = client.sendJSON(http.MethodGet, "/api/v1/threads?ad_id=x", seller.Data.ID, nil, &inbox)
+	assert.ErrorIs(t, err, ErrBadRequest)
+	err = client.sendJSON(http.MethodGet, fmt.Sprintf("/api/v1/threads?ad_id=%d", ad.Data.ID), seller.Data.ID, nil, &inbox)
+	require.NoError(t, err)
+	require.Len(t, inbox.Data, 1)
+	assert.Equal(t, threadID, inbox.Data[0].ID)
+	assert.Equal(t, 1, inbox.Data[0].Unread)
+	assert.Nil(t, inbox.NextCursor)
+
+	messages := fmt.Sprintf("/api/v1/threads/%d/messages", threadID)
+	err = client.sendJSON(http.MethodPost, messages, seller.Data.ID, map[string]any{"text": "yes"}, &sent)
+	require.NoError(t, err)
+	assert.Equal(t, seller.Data.ID, sent.Data.SenderID)
+
+	var read struct {
+		Data threadData `json:"data"`
+	}
+	err = client.sendJSON(http.MethodPost, fmt.Sprintf("/api/v1/threads/%d/read", threadID), buyer.Data.ID, nil, &read)
+	require.NoError(t, err)
+	assert.Zero(t, read.Data.Unread)
+
+	var list struct {
+		Data       []messageData `json:"data"`
+		Thread     threadData    `json:"thread"`
+		NextCursor *string       `json:"next_cursor"`
+	}
+	err = client.sendJSON(http.MethodGet, messages, seller.Data.ID, nil, &list)
+	require.NoError(t, err)
+	require.Len(t, list.Data, 2)
+	assert.Equal(t, "is it available?", list.Data[0].Text)
+	assert.True(t, list.Data[1].Read, "buyer has read the reply")
+	assert.Equal(t, threadID, list.Thread.ID)
+
+	stranger, err := client.createUser("Kolya", "kolya@mail.ru")
+	require.NoError(t, err)
+	resp, err = client.do(http.MethodGet, messages, nil, map[string]string{"Authorization": client.bearer(stranger.Data.ID)})
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+
+	block := fmt.Sprintf("/api/v1/users/%d/block", buyer.Data.ID)
+	resp, err = client.do(http.MethodPut, block, nil, map[string]string{"Authorization": client.bearer(seller.Data.ID)})
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	err = client.sendJSON(http.MethodPost, contact, buyer.Data.ID, map[string]any{"text": "hello?"}, &sent)
+	assert.ErrorIs(t, err, ErrForbidden)
+	resp, err = client.do(http.MethodDelete, block, nil, map[string]string{"Authorization": client.bearer(seller.Data.ID)})
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	resp, err = client.do(http.MethodDelete, block, nil, map[string]string{"Authorization": client.bearer(seller.Data.ID)})
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+}
+
+func TestGRPCMessages(t *testing.T) {
+	client := newGRPCClient(t, newMessagingApp())
+	ctx := context.Background()
+	seller, sellerCtx := grpcSignUp(t, ctx, client, "Petya")
+	buyer, buyerCtx := grpcSignUp(t, ctx, client, "Vasya")
+
+	ad, err := client.CreateAd(sellerCtx, &grpcPort.CreateAdRequest{Title: "ad", Text: "text", CategoryId: testCategoryID})
+	require.NoError(t, err)
+	_, err = client.ContactSeller(buyerCtx, &grpcPort.ContactSellerRequest{AdId: ad.Id, Text: "hi"})
+	assert.Equal(t, codes.FailedPrecondition, status.Code(err))
+	_, err = client.ChangeAdStatus(sellerCtx, &grpcPort.ChangeAdStatusRequest{AdId: ad.Id, Published: true})
+	require.NoError(t, err)
+
+	_, err = client.ContactSeller(ctx, &grpcPort.ContactSellerRequest{AdId: ad.Id, Text: "hi"})
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+	_, err = client.ContactSeller(sellerCtx, &grpcPort.ContactSellerRequest{AdId: ad.Id, Text: "hi"})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	m, err := client.ContactSeller(buyerCtx, &grpcPort.ContactSellerRequest{AdId: ad.Id, Text: "hi"})
+	require.NoError(t, err)
+	assert.Equal(t, buyer.Id, m.SenderId)
+
+	threads, err := client.ListThreads(sellerCtx, &grpcPort.ListThreadsRequest{})
+	require.NoError(t, err)
+	require.Len(t, threads.List, 1)
+	assert.Equal(t, int64(1), threads.List[0].Unread)
+	assert.Equal(t, seller.Id, threads.List[0].SellerId)
+
+	_, err = client.SendMessage(sellerCtx, &grpcPort.SendMessageRequest{ThreadId: m.ThreadId, Text: "hello"})
+	require.NoError(t, err)
+	read, err := client.MarkThreadRead(buyerCtx, &grpcPort.ThreadRequest{ThreadId: m.ThreadId})
+	require.NoError(t, err)
+	assert.Zero(t, read.Unread)
+	list, err := client.ListMessages(sellerCtx, &grpcPort.ListMessagesRequest{ThreadId: m.ThreadId})
+	require.NoError(t, err)
+	require.Len(t, list.List, 2)
+	assert.True(t, list.List[1].Read)
+	_, err = client.ListMessages(sellerCtx, &grpcPort.ListMessagesRequest{ThreadId: m.ThreadId + 100})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+
+	_, err = client.BlockUser(buyerCtx, &grpcPort.BlockUserRequest{UserId: seller.Id})
+	require.NoError(t, err)
+	_, err = client.SendMessage(sellerCtx, &grpcPort.SendMessageRequest{ThreadId: m.ThreadId, Text: "hello?"})
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+	_, err = client.UnblockUser(buyerCtx, &grpcPort.BlockUserRequest{UserId: seller.Id})
+	require.NoError(t, err)
+	_, err = client.UnblockUser(buyerCtx, &grpcPort.BlockUserRequest{UserId: seller.Id})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+}
@@ -0,0 +1,369 @@
+package tests
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"homework9/internal/ads"
+	"homework9/internal/app"
+	"homework9/internal/notifications"
+	grpcPort "homework9/internal/ports/grpc"
+	"homework9/internal/users"
+)
+
+// recordingNotifier запоминает отправленные уведомления
+type recordingNotifier struct {
+	mx   sync.Mutex
+	sent []notifications.Notification
+}
+
+func (n *recordingNotifier) Notify(ctx context.Context, msg notifications.Notification) error {
+	n.mx.Lock()
+	defer n.mx.Unlock()
+	n.sent = append(n.sent, msg)
+	return nil
+}
+
+func (n *recordingNotifier) Sent() []notifications.Notification {
+	n.mx.Lock()
+	defer n.mx.Unlock()
+	return append([]notifications.Notification(nil), n.sent...)
+}
+
+// newModeratedApp - приложение с модерацией, в котором admin@mail.ru регистрируется администратором
+func newModeratedApp(opts ...app.Option) app.App {
+	return newTestApp(append([]app.Option{app.WithModeration(true), app.WithAdminEmails("admin@mail.ru")}, opts...)...)
+}
+
+// moderationUsers регистрирует автора и модератора и возвращает их контексты
+func moderationUsers(t *testing.T, a app.App) (author context.Context, moderator context.Context) {
+	t.Helper()
+	ctx := context.Background()
+	admin, err := a.CreateUser(ctx, "Admin", "admin@mail.ru", testPassword)
+	require.NoError(t, err)
+	u, err := a.CreateUser(ctx, "Petya", "petya@mail.ru", testPassword)
+	require.NoError(t, err)
+	m, err := a.CreateUser(ctx, "Vasya", "vasya@mail.ru", testPassword)
+	require.NoError(t, err)
+	_, err = a.SetUserRole(app.WithUserID(ctx, admin.ID), m.ID, users.RoleModerator)
+	require.NoError(t, err)
+	return app.WithUserID(ctx, u.ID), app.WithUserID(ctx, m.ID)
+}
+
+func TestModerationWorkflow(t *testing.T) {
+	notifier := &recordingNotifier{}
+	a := newModeratedApp(app.WithNotifier(notifier))
+	authorCtx, moderatorCtx := moderationUsers(t, a)
+
+	ad, err := a.CreateAd(authorCtx, "hello", "world", testCategoryID, ads.Price{}, nil)
+	require.NoError(t, err)
+	assert.Equal(t, ads.StatusDraft, ad.Status)
+	_, err = a.ApproveAd(moderatorCtx, ad.ID, "")
+	assert.ErrorIs(t, err, app.ErrInvalidTransition, "draft is not in review")
+
+	ad, err = a.UpdateStatusById(authorCtx, ad.ID, true)
+	require.NoError(t, err)
+	assert.Equal(t, ads.StatusPending, ad.Status)
+	assert.False(t, ad.Published)
+
+	_, err = a.ReviewQueue(authorCtx, app.PageRequest{})
+	assert.ErrorIs(t, err, app.ErrAccessDenied)
+	queue, err := a.ReviewQueue(moderatorCtx, app.PageRequest{})
+	require.NoError(t, err)
+	require.Len(t, queue.Ads, 1)
+	assert.Equal(t, ad.ID, queue.Ads[0].ID)
+
+	_, err = a.RejectAd(authorCtx, ad.ID, "сам себе")
+	assert.ErrorIs(t, err, app.ErrAccessDenied)
+	_, err = a.RejectAd(moderatorCtx, ad.ID, "  ")
+	assert.ErrorIs(t, err, app.ErrInvalidReason)
+	ad, err = a.RejectAd(moderatorCtx, ad.ID, "нет фотографий")
+	require.NoError(t, err)
+	assert.Equal(t, ads.StatusRejected, ad.Status)
+	assert.Equal(t, "нет фотографий", ad.Review.Reason)
+	assert.False(t, ad.Review.At.IsZero())
+
+	sent := notifier.Sent()
+	require.Len(t, sent, 1)
+	assert.Equal(t, ad.AuthorID, sent[0].UserID)
+	assert.Equal(t, notifications.KindAdRejected, sent[0].Kind)
+	assert.Equal(t, ad.ID, sent[0].AdID)
+	assert.Equal(t, "нет фотографий", sent[0].Text)
+
+	// исправленное объявление снова уходит на проверку и после одобрения публикуется
+	_, err = a.UpdateAdById(authorCtx, ad.ID, "hello", "world with photos", 0, nil, nil, 0)
+	require.NoError(t, err)
+	ad, err = a.UpdateStatusById(authorCtx, ad.ID, true)
+	require.NoError(t, err)
+	assert.Equal(t, ads.StatusPending, ad.Status)
+	ad, err = a.ApproveAd(moderatorCtx, ad.ID, "")
+	require.NoError(t, err)
+	assert.Equal(t, ads.StatusPublished, ad.Status)
+	assert.True(t, ad.Published)
+	_, err = a.ApproveAd(moderatorCtx, ad.ID, "")
+	assert.ErrorIs(t, err, app.ErrInvalidTransition)
+
+	// изменение опубликованного объявления снимает его до повторной проверки
+	ad, err = a.UpdateAdById(authorCtx, ad.ID, "hello", "changed", 0, nil, nil, 0)
+	require.NoError(t, err)
+	assert.Equal(t, ads.StatusPending, ad.Status)
+	assert.False(t, ad.Published)
+	ad, err = a.ApproveAd(moderatorCtx, ad.ID, "ok")
+	require.NoError(t, err)
+	assert.True(t, ad.Published)
+
+	// снятое объявление уходит в архив и публикуется снова без проверки
+	ad, err = a.UpdateStatusById(authorCtx, ad.ID, false)
+	require.NoError(t, err)
+	assert.Equal(t, ads.StatusArchived, ad.Status)
+	ad, err = a.UpdateStatusById(authorCtx, ad.ID, true)
+	require.NoError(t, err)
+	assert.Equal(t, ads.StatusPublished, ad.Status)
+
+	// свои объявления модератор не проверяет
+	own, err := a.CreateAd(moderatorCtx, "mine", "text", testCategoryID, ads.Price{}, nil)
+	require.NoError(t, err)
+	_, err = a.UpdateStatusById(moderatorCtx, own.ID, true)
+	require.NoError(t, err)
+	_, err = a.ApproveAd(moderatorCtx, own.ID, "")
+	assert.ErrorIs(t, err, app.ErrAccessDenied)
+
+	// отозванное с проверки объявление возвращается в черновики
+	own, err = a.UpdateStatusById(moderatorCtx, own.ID, false)
+	require.NoError(t, err)
+	assert.Equal(t, ads.StatusDraft, own.Status)
+	queue, err = a.ReviewQueue(moderatorCtx, app.PageRequest{})
+	require.NoError(t, err)
+	assert.Empty(t, queue.Ads)
+}
+
+func TestUnpublishedAdVisibility(t *testing.T) {
+	a := newModeratedApp()
+	authorCtx, moderatorCtx := moderationUsers(t, a)
+	strangerCtx := reporters(t, a, 1)[0]
+	anonymousCtx := context.Background()
+
+	ad, err := a.CreateAd(authorCtx, "hello", "world", testCategoryID, ads.Price{}, nil)
+	require.NoError(t, err)
+	_, err = a.UpdateStatusById(authorCtx, ad.ID, true)
+	require.NoError(t, err)
+	_, err = a.RejectAd(moderatorCtx, ad.ID, "нет фотографий")
+	require.NoError(t, err)
+
+	// неопубликованное объявление видят только автор и модераторы
+	for _, ctx := range []context.Context{strangerCtx, anonymousCtx} {
+		_, err = a.GetAdByID(ctx, ad.ID)
+		assert.ErrorIs(t, err, app.ErrNotFound)
+		page, err := a.GetAdsByFilter(ctx, app.FilterOpts{}, app.PageRequest{})
+		require.NoError(t, err)
+		assert.Empty(t, page.Ads)
+	}
+	for _, ctx := range []context.Context{authorCtx, moderatorCtx} {
+		got, err := a.GetAdByID(ctx, ad.ID)
+		require.NoError(t, err)
+		assert.Equal(t, "нет фотографий", got.Review.Reason)
+		page, err := a.GetAdsByFilter(ctx, app.FilterOpts{Published: ads.OnlyUnpublished}, app.PageRequest{})
+		require.NoError(t, err)
+		require.Len(t, page.Ads, 1)
+		assert.Equal(t, "нет фотографий", page.Ads[0].Review.Reason)
+	}
+
+	// у опубликованного объявления решение модератора видно тоже только им
+	_, err = a.UpdateStatusById(authorCtx, ad.ID, true)
+	require.NoError(t, err)
+	_, err = a.ApproveAd(moderatorCtx, ad.ID, "спасибо")
+	require.NoError(t, err)
+	got, err := a.GetAdByID(strangerCtx, ad.ID)
+	require.NoError(t, err)
+	assert.Equal(t, ads.Review{}, got.Review)
+	list, err := a.ListPublishedAds(anonymousCtx, app.PageRequest{})
+	require.NoError(t, err)
+	require.Len(t, list.Ads, 1)
+	assert.Equal(t, ads.Review{}, list.Ads[0].Review)
+	got, err = a.GetAdByID(authorCtx, ad.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "спасибо", got.Review.Reason)
+}
+
+func TestModerationDisabled(t *testing.T) {
+	a := newTestApp()
+	ctx := context.Background()
+	u, err := a.CreateUser(ctx, "Petya", "petya@mail.ru", testPassword)
+	require.NoError(t, err)
+	userCtx := app.WithUserID(ctx, u.ID)
+
+	ad, err := a.CreateAd(userCtx, "hello", "world", testCategoryID, ads.Price{}, nil)
+	require.NoError(t, err)
+	ad, err = a.UpdateStatusById(userCtx, ad.ID, true)
+	require.NoError(t, err)
+	assert.Equal(t, ads.StatusPublished, ad.Status)
+	ad, err = a.UpdateAdById(userCtx, ad.ID, "hello", "changed", 0, nil, nil, 0)
+	require.NoError(t, err)
+	assert.Equal(t, ads.StatusPublished, ad.Status)
+	assert.True(t, ad.Published)
+}
+
+func TestModerationSchedule(t *testing.T) {
+	clock := newTestClock()
+	a := newModeratedApp(app.WithClock(clock.Now))
+	authorCtx, moderatorCtx := moderationUsers(t, a)
+
+	// одобренное объявление ждёт назначенной публикации
+	ad, err := a.CreateAd(authorCtx, "later", "world", testCategoryID, ads.Price{}, nil)
+	require.NoError(t, err)
+	_, err = a.ScheduleAd(authorCtx, ad.ID, clock.Now().Add(time.Hour), time.Time{})
+	require.NoError(t, err)
+	_, err = a.UpdateStatusById(authorCtx, ad.ID, true)
+	require.NoError(t, err)
+	ad, err = a.ApproveAd(moderatorCtx, ad.ID, "")
+	require.NoError(t, err)
+	assert.Equal(t, ads.StatusApproved, ad.Status)
+	assert.False(t, ad.Published)
+
+	// непроверенное объявление с наступившей публикацией уходит на проверку
+	draft, err := a.CreateAd(authorCtx, "draft", "world", testCategoryID, ads.Price{}, nil)
+	require.NoError(t, err)
+	_, err = a.ScheduleAd(authorCtx, draft.ID, clock.Now().Add(time.Minute), time.Time{})
+	require.NoError(t, err)
+
+	clock.Advance(2 * time.Hour)
+	res, err := a.RunSchedule(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, app.ScheduleResult{Published: 1, Submitted: 1}, res)
+
+	ad, err = a.GetAdByID(context.Background(), ad.ID)
+	require.NoError(t, err)
+	assert.Equal(t, ads.StatusPublished, ad.Status)
+	draft, err = a.GetAdByID(authorCtx, draft.ID)
+	require.NoError(t, err)
+	assert.Equal(t, ads.StatusPending, draft.Status)
+	assert.True(t, draft.PublishAt.IsZero())
+}
+
+func TestModerationHTTP(t *testing.T) {
+	client := newTestClient(newModeratedApp())
+	admin, err := client.createUser("Admin", "admin@mail.ru")
+	require.NoError(t, err)
+	u, err := client.createUser("Petya", "petya@mail.ru")
+	require.NoError(t, err)
+	ad, err := client.createAd(u.Data.ID, "hello", "world")
+	require.NoError(t, err)
+	_, err = client.changeAdStatus(u.Data.ID, ad.Data.ID, true)
+	require.NoError(t, err)
+
+	type reviewedAd struct {
+		Data struct {
+			ID        int64  `json:"id"`
+			Status    string `json:"status"`
+			Published bool   `json:"published"`
+			Review    *struct {
+				ModeratorID int64     `json:"moderator_id"`
+				Reason      string    `json:"reason"`
+				ReviewedAt  time.Time `json:"reviewed_at"`
+			} `json:"review"`
+		} `json:"data"`
+	}
+	var queue struct {
+		Data []struct {
+			ID     int64  `json:"id"`
+			Status string `json:"status"`
+		} `json:"data"`
+	}
+	err = client.sendJSON(http.MethodGet, "/api/v1/moderation/ads", u.Data.ID, nil, &queue)
+	assert.ErrorIs(t, err, ErrForbidden)
+	err = client.sendJSON(http.MethodGet, "/api/v1/moderation/ads?limit=10", admin.Data.ID, nil, &queue)
+	require.NoError(t, err)
+	require.Len(t, queue.Data, 1)
+	assert.Equal(t, "pending", queue.Data[0].Status)
+
+	path := fmt.Sprintf("/api/v1/ads/%d", ad.Data.ID)
+	resp, err := client.do(http.MethodGet, path, nil, nil)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode, "ad in review is hidden from anonymous readers")
+
+	reject := fmt.Sprintf("/api/v1/moderation/ads/%d/reject", ad.Data.ID)
+	var reviewed reviewedAd
+	err = client.sendJSON(http.MethodPost, reject, admin.Data.ID, map[string]any{}, &reviewed)
+	assert.ErrorIs(t, err, ErrBadRequest)
+	err = client.sendJSON(http.MethodPost, reject, admin.Data.ID, map[string]any{"reason": "spam"}, &reviewed)
+	require.NoError(t, err)
+	assert.Equal(t, "rejected", reviewed.Data.Status)
+	require.NotNil(t, reviewed.Data.Review)
+	assert.Equal(t, admin.Data.ID, reviewed.Data.Review.ModeratorID)
+	assert.Equal(t, "spam", reviewed.Data.Review.Reason)
+
+	resp, err = client.do(http.MethodPost, fmt.Sprintf("/api/v1/moderation/ads/%d/approve", ad.Data.ID), map[string]any{},
+		map[string]string{"Authorization": client.bearer(admin.Data.ID)})
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusConflict, resp.StatusCode, "rejected ad is not in review")
+
+	_, err = client.changeAdStatus(u.Data.ID, ad.Data.ID, true)
+	require.NoError(t, err)
+	err = client.sendJSON(http.MethodPost, fmt.Sprintf("/api/v1/moderation/ads/%d/approve", ad.Data.ID), admin.Data.ID, map[string]any{}, &reviewed)
+	require.NoError(t, err)
+	assert.Equal(t, "published", reviewed.Data.Status)
+	assert.True(t, reviewed.Data.Published)
+
+	// решение модератора видят только автор и модераторы
+	err = client.sendJSON(http.MethodGet, path, -1, nil, &reviewed)
+	require.NoError(t, err)
+	assert.Nil(t, reviewed.Data.Review)
+	err = client.sendJSON(http.MethodGet, path, u.Data.ID, nil, &reviewed)
+	require.NoError(t, err)
+	assert.NotNil(t, reviewed.Data.Review)
+
+	list, err := client.listAds()
+	require.NoError(t, err)
+	require.Len(t, list.Data, 1)
+	assert.Equal(t, ad.Data.ID, list.Data[0].ID)
+}
+
+func TestGRPCModeration(t *testing.T) {
+	a := newModeratedApp()
+	client := newGRPCClient(t, a)
+	ctx := context.Background()
+	admin, adminCtx := grpcSignUp(t, ctx, client, "Admin")
+	_, userCtx := grpcSignUp(t, ctx, client, "Petya")
+
+	ad, err := client.CreateAd(userCtx, &grpcPort.CreateAdRequest{Title: "ad", Text: "text", CategoryId: testCategoryID})
+	require.NoError(t, err)
+	assert.Equal(t, "draft", ad.Status)
+	ad, err = client.ChangeAdStatus(userCtx, &grpcPort.ChangeAdStatusRequest{AdId: ad.Id, Published: true})
+	require.NoError(t, err)
+	assert.Equal(t, "pending", ad.Status)
+
+	_, err = client.ReviewQueue(userCtx, &grpcPort.ReviewQueueRequest{})
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+	queue, err := client.ReviewQueue(adminCtx, &grpcPort.ReviewQueueRequest{})
+	require.NoError(t, err)
+	require.Len(t, queue.List, 1)
+
+	_, err = client.RejectAd(adminCtx, &grpcPort.ReviewAdRequest{AdId: ad.Id})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	rejected, err := client.RejectAd(adminCtx, &grpcPort.ReviewAdRequest{AdId: ad.Id, Reason: "spam"})
+	require.NoError(t, err)
+	assert.Equal(t, "rejected", rejected.Status)
+	assert.Equal(t, admin.Id, rejected.Review.ModeratorId)
+	assert.Equal(t, "spam", rejected.Review.Reason)
+
+	_, err = client.ApproveAd(adminCtx, &grpcPort.ReviewAdRequest{AdId: ad.Id})
+	assert.Equal(t, codes.FailedPrecondition, status.Code(err))
+	_, err = client.ChangeAdStatus(userCtx, &grpcPort.ChangeAdStatusRequest{AdId: ad.Id, Published: true})
+	require.NoError(t, err)
+	approved, err := client.ApproveAd(adminCtx, &grpcPort.ReviewAdRequest{AdId: ad.Id, Reason: "ok"})
+	require.NoError(t, err)
+	assert.Equal(t, "published", approved.Status)
+	assert.True(t, approved.Published)
+
+	_, err = client.ApproveAd(ctx, &grpcPort.ReviewAdRequest{AdId: ad.Id})
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+}
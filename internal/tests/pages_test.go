@@ -0,0 +1,123 @@
+package tests
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	grpcPort "homework9/internal/ports/grpc"
+)
+
+func TestListAdsPages(t *testing.T) {
+	client := getTestClient()
+	u, err := client.createUser("Petya", "petya@mail.ru")
+	require.NoError(t, err)
+
+	titles := []string{"c", "a", "d", "b", "e"}
+	for _, title := range titles {
+		ad, err := client.createAd(u.Data.ID, title, "text")
+		require.NoError(t, err)
+		_, err = client.changeAdStatus(u.Data.ID, ad.Data.ID, true)
+		require.NoError(t, err)
+	}
+
+	var got []string
+	query := url.Values{"sort": {"title"}, "order": {"desc"}, "limit": {"2"}}
+	for pages := 0; ; pages++ {
+		require.Less(t, pages, len(titles), "pagination must terminate")
+		page, err := client.listAdsPage(query.Encode())
+		require.NoError(t, err)
+		assert.LessOrEqual(t, len(page.Data), 2)
+		for _, ad := range page.Data {
+			got = append(got, ad.Title)
+		}
+		if page.NextCursor == nil {
+			break
+		}
+		query.Set("cursor", *page.NextCursor)
+	}
+	assert.Equal(t, []string{"e", "d", "c", "b", "a"}, got)
+
+	// без параметров - первая страница по возрастанию ID
+	page, err := client.listAds()
+	require.NoError(t, err)
+	require.Len(t, page.Data, len(titles))
+	assert.Equal(t, "c", page.Data[0].Title)
+	assert.Nil(t, page.NextCursor)
+}
+
+func TestListAdsPagesInvalid(t *testing.T) {
+	client := getTestClient()
+	u, err := client.createUser("Petya", "petya@mail.ru")
+	require.NoError(t, err)
+	for i := 0; i < 3; i++ {
+		ad, err := client.createAd(u.Data.ID, fmt.Sprintf("ad %d", i), "text")
+		require.NoError(t, err)
+		_, err = client.changeAdStatus(u.Data.ID, ad.Data.ID, true)
+		require.NoError(t, err)
+	}
+	page, err := client.listAdsPage("sort=created&limit=1")
+	require.NoError(t, err)
+	require.NotNil(t, page.NextCursor)
+
+	for _, query := range []string{
+		"sort=rating",
+		"order=up",
+		"limit=-1",
+		"cursor=garbage",
+		// курсор выдан для другой сортировки
+		"sort=title&cursor=" + url.QueryEscape(*page.NextCursor),
+	} {
+		resp, err := client.do(http.MethodGet, "/api/v1/ads?"+query, nil, nil)
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode, query)
+	}
+}
+
+func TestGRPCListAdsPages(t *testing.T) {
+	a := newTestApp()
+	client := newGRPCClient(t, a)
+	ctx := context.Background()
+
+	_, userCtx := grpcSignUp(t, ctx, client, "Petya")
+	var ids []int64
+	for i := 0; i < 5; i++ {
+		ad, err := client.CreateAd(userCtx, &grpcPort.CreateAdRequest{Title: "ad", Text: "text", CategoryId: testCategoryID})
+		require.NoError(t, err)
+		_, err = client.ChangeAdStatus(userCtx, &grpcPort.ChangeAdStatusRequest{AdId: ad.Id, Published: true})
+		require.NoError(t, err)
+		ids = append([]int64{ad.Id}, ids...)
+	}
+
+	var got []int64
+	page := &grpcPort.PageRequest{Sort: grpcPort.SortField_CREATED, Desc: true, Limit: 2}
+	for {
+		res, err := client.ListAds(ctx, &grpcPort.ListAdsRequest{Page: page})
+		require.NoError(t, err)
+		for _, ad := range res.List {
+			got = append(got, ad.Id)
+		}
+		if res.NextCursor == "" {
+			break
+		}
+		page.Cursor = res.NextCursor
+	}
+	assert.Equal(t, ids, got)
+
+	res, err := client.FindAds(ctx, &grpcPort.FindAdsRequest{TitleContains: "ad", Page: &grpcPort.PageRequest{Limit: 3}})
+	require.NoError(t, err)
+	assert.Len(t, res.List, 3)
+	assert.NotEmpty(t, res.NextCursor)
+
+	_, err = client.ListAds(ctx, &grpcPort.ListAdsRequest{Page: &grpcPort.PageRequest{Cursor: "garbage"}})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	_, err = client.ListAds(ctx, &grpcPort.ListAdsRequest{Page: &grpcPort.PageRequest{Sort: 42}})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+}
@@ -0,0 +1,187 @@
+package tests
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"homework9/internal/ads"
+	"homework9/internal/app"
+	"homework9/internal/money"
+	grpcPort "homework9/internal/ports/grpc"
+)
+
+type priceData struct {
+	Amount     int64  `json:"amount"`
+	Currency   string `json:"currency"`
+	Negotiable bool   `json:"negotiable"`
+}
+
+type pricedAdsResponse struct {
+	Data []struct {
+		ID           int64      `json:"id"`
+		Price        *priceData `json:"price"`
+		DisplayPrice *priceData `json:"display_price"`
+	} `json:"data"`
+}
+
+func newRatesApp(t *testing.T) app.App {
+	t.Helper()
+	rates, err := money.NewRates("RUB", map[string]float64{"USD": 90, "EUR": 100})
+	require.NoError(t, err)
+	return newTestApp(app.WithRates(rates))
+}
+
+func adsPrice(amount int64, currency string) ads.Price {
+	return ads.Price{Amount: amount, Currency: currency}
+}
+
+func (tc *testClient) createPricedAd(userID int64, title string, price any) (adResponse, error) {
+	var response adResponse
+	body := map[string]any{"title": title, "text": "text", "category_id": testCategoryID, "price": price}
+	err := tc.sendJSON(http.MethodPost, "/api/v1/ads", userID, body, &response)
+	return response, err
+}
+
+func TestAdPrices(t *testing.T) {
+	client := newTestClient(newRatesApp(t))
+	u, err := client.createUser("Petya", "petya@mail.ru")
+	require.NoError(t, err)
+
+	for _, price := range []priceData{
+		{Amount: -1, Currency: "RUB"},
+		{Amount: 100, Currency: "XYZ"},
+		{Amount: 100},
+		{Amount: money.MaxAmount + 1, Currency: "RUB"},
+	} {
+		_, err := client.createPricedAd(u.Data.ID, "bad price", price)
+		assert.ErrorIs(t, err, ErrBadRequest, "%+v", price)
+	}
+
+	// код валюты приводится к ISO 4217, договорная цена может быть без суммы
+	sofa, err := client.createPricedAd(u.Data.ID, "Диван", priceData{Amount: 1500000, Currency: "rub", Negotiable: true})
+	require.NoError(t, err)
+	chair, err := client.createPricedAd(u.Data.ID, "Стул", priceData{Amount: 2000, Currency: "USD"})
+	require.NoError(t, err)
+	lamp, err := client.createPricedAd(u.Data.ID, "Лампа", priceData{Amount: 50000, Currency: "RUB"})
+	require.NoError(t, err)
+	table, err := client.createPricedAd(u.Data.ID, "Стол", priceData{Negotiable: true})
+	require.NoError(t, err)
+	for _, id := range []int64{sofa.Data.ID, chair.Data.ID, lamp.Data.ID, table.Data.ID} {
+		_, err = client.changeAdStatus(u.Data.ID, id, true)
+		require.NoError(t, err)
+	}
+
+	// без price в запросе цена не меняется
+	_, err = client.updateAd(u.Data.ID, sofa.Data.ID, "Диван", "почти новый")
+	require.NoError(t, err)
+
+	var page pricedAdsResponse
+	err = client.sendJSON(http.MethodGet, "/api/v1/ads?sort=price&order=desc&display_currency=usd", u.Data.ID, nil, &page)
+	require.NoError(t, err)
+	require.Len(t, page.Data, 4)
+	assert.Equal(t, sofa.Data.ID, page.Data[0].ID)
+	assert.Equal(t, &priceData{Amount: 1500000, Currency: "RUB", Negotiable: true}, page.Data[0].Price)
+	assert.Equal(t, &priceData{Amount: 16667, Currency: "USD", Negotiable: true}, page.Data[0].DisplayPrice)
+	assert.Equal(t, &priceData{Amount: 2000, Currency: "USD"}, page.Data[2].DisplayPrice)
+	assert.Equal(t, &priceData{Negotiable: true}, page.Data[3].Price)
+	assert.Nil(t, page.Data[3].DisplayPrice, "ad without amount has nothing to convert")
+
+	// границы без валюты относятся к базовой, обе включаются
+	var found pricedAdsResponse
+	err = client.sendJSON(http.MethodPost, "/api/v1/search?sort=price", u.Data.ID,
+		map[string]any{"price_min": 50000, "price_max": 1500000}, &found)
+	require.NoError(t, err)
+	require.Len(t, found.Data, 2)
+	assert.Equal(t, lamp.Data.ID, found.Data[0].ID)
+	assert.Equal(t, sofa.Data.ID, found.Data[1].ID)
+
+	err = client.sendJSON(http.MethodPost, "/api/v1/search", u.Data.ID, map[string]any{"currency": "usd", "price_max": 2000}, &found)
+	require.NoError(t, err)
+	require.Len(t, found.Data, 1)
+	assert.Equal(t, chair.Data.ID, found.Data[0].ID)
+
+	for _, req := range []struct {
+		method string
+		path   string
+		body   any
+	}{
+		{http.MethodPost, "/api/v1/search", map[string]any{"currency": "XYZ"}},
+		{http.MethodPost, "/api/v1/search", map[string]any{"price_min": -5}},
+		{http.MethodGet, "/api/v1/ads?display_currency=XYZ", nil},
+		{http.MethodPut, fmt.Sprintf("/api/v1/ads/%d", sofa.Data.ID), map[string]any{"title": "Диван", "text": "text", "price": priceData{Amount: 5}}},
+	} {
+		resp, err := client.do(req.method, req.path, req.body, map[string]string{"Authorization": client.bearer(u.Data.ID)})
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode, req.path)
+	}
+}
+
+func TestConvertPrice(t *testing.T) {
+	a := newRatesApp(t)
+	ctx := context.Background()
+
+	usd, err := a.ConvertPrice(ctx, adsPrice(100000, "EUR"), "USD")
+	require.NoError(t, err)
+	assert.Equal(t, adsPrice(111111, "USD"), usd)
+
+	_, err = a.ConvertPrice(ctx, adsPrice(100, "RUB"), "JPY")
+	assert.ErrorIs(t, err, app.ErrNoRate)
+	_, err = a.ConvertPrice(ctx, adsPrice(100, "RUB"), "ABC")
+	assert.ErrorIs(t, err, app.ErrInvalidCurrency)
+
+	// у иены нет дробной части, у рубля - две цифры
+	rates, err := money.NewRates("RUB", map[string]float64{"JPY": 0.6})
+	require.NoError(t, err)
+	converted, err := rates.Convert(60000, "RUB", "JPY")
+	require.NoError(t, err)
+	assert.Equal(t, int64(1000), converted)
+}
+
+func TestGRPCAdPrices(t *testing.T) {
+	client := newGRPCClient(t, newRatesApp(t))
+	ctx := context.Background()
+	_, userCtx := grpcSignUp(t, ctx, client, "Petya")
+
+	_, err := client.CreateAd(userCtx, &grpcPort.CreateAdRequest{Title: "bad", Text: "price", CategoryId: testCategoryID,
+		Price: &grpcPort.Price{Amount: 100, Currency: "XYZ"}})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	var ids []int64
+	for _, amount := range []int64{30000, 10000, 20000} {
+		ad, err := client.CreateAd(userCtx, &grpcPort.CreateAdRequest{Title: "ad", Text: "text", CategoryId: testCategoryID,
+			Price: &grpcPort.Price{Amount: amount, Currency: "EUR"}})
+		require.NoError(t, err)
+		assert.Equal(t, amount, ad.Price.Amount)
+		_, err = client.ChangeAdStatus(userCtx, &grpcPort.ChangeAdStatusRequest{AdId: ad.Id, Published: true})
+		require.NoError(t, err)
+		ids = append(ids, ad.Id)
+	}
+
+	updated, err := client.UpdateAd(userCtx, &grpcPort.UpdateAdRequest{AdId: ids[0], Title: "ad", Text: "text"})
+	require.NoError(t, err)
+	assert.Equal(t, "EUR", updated.Price.Currency, "price is kept when omitted")
+	updated, err = client.UpdateAd(userCtx, &grpcPort.UpdateAdRequest{AdId: ids[0], Title: "ad", Text: "text",
+		Price: &grpcPort.Price{Amount: 25000, Currency: "EUR", Negotiable: true}})
+	require.NoError(t, err)
+	assert.True(t, updated.Price.Negotiable)
+
+	min := int64(15000)
+	res, err := client.FindAds(ctx, &grpcPort.FindAdsRequest{Currency: "EUR", PriceMin: &min, DisplayCurrency: "RUB",
+		Page: &grpcPort.PageRequest{Sort: grpcPort.SortField_PRICE}})
+	require.NoError(t, err)
+	require.Len(t, res.List, 2)
+	assert.Equal(t, []int64{ids[2], ids[0]}, []int64{res.List[0].Id, res.List[1].Id})
+	require.NotNil(t, res.List[0].DisplayPrice)
+	assert.Equal(t, int64(2000000), res.List[0].DisplayPrice.Amount)
+	assert.Equal(t, "RUB", res.List[0].DisplayPrice.Currency)
+
+	_, err = client.ListAds(ctx, &grpcPort.ListAdsRequest{DisplayCurrency: "XYZ"})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+}
@@ -0,0 +1,400 @@
+package tests
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"homework9/internal/adapters/reportrepo"
+	"homework9/internal/ads"
+	"homework9/internal/app"
+	"homework9/internal/notifications"
+	grpcPort "homework9/internal/ports/grpc"
+	"homework9/internal/reports"
+)
+
+// newReportingApp - приложение с жалобами без модерации, admin@mail.ru регистрируется администратором
+func newReportingApp(opts ...app.Option) app.App {
+	return newTestApp(append([]app.Option{app.WithReports(reportrepo.New()), app.WithAdminEmails("admin@mail.ru")}, opts...)...)
+}
+
+// reporters регистрирует n пользователей и возвращает их контексты
+func reporters(t *testing.T, a app.App, n int) []context.Context {
+	t.Helper()
+	res := make([]context.Context, 0, n)
+	for i := 0; i < n; i++ {
+		u, err := a.CreateUser(context.Background(), fmt.Sprintf("Reporter%d", i), fmt.Sprintf("reporter%d@mail.ru", i), testPassword)
+		require.NoError(t, err)
+		res = append(res, app.WithUserID(context.Background(), u.ID))
+	}
+	return res
+}
+
+func TestCreateReport(t *testing.T) {
+	a := newReportingApp()
+	authorCtx, _ := moderationUsers(t, a)
+	ctx := reporters(t, a, 1)[0]
+	ad, err := a.CreateAd(authorCtx, "hello", "world", testCategoryID, ads.Price{}, nil)
+	require.NoError(t, err)
+
+	_, err = a.CreateReport(ctx, reports.TargetAd, ad.ID, reports.Reason("rude"), "")
+	assert.ErrorIs(t, err, app.ErrInvalidReport)
+	_, err = a.CreateReport(ctx, reports.TargetAd, ad.ID, reports.ReasonOther, "  ")
+	assert.ErrorIs(t, err, app.ErrInvalidReport, "reason other needs a comment")
+	_, err = a.CreateReport(ctx, reports.TargetAd, ad.ID, reports.ReasonSpam, strings.Repeat("я", app.MaxReportCommentLength+1))
+	assert.ErrorIs(t, err, app.ErrInvalidReport)
+	_, err = a.CreateReport(context.Background(), reports.TargetAd, ad.ID, reports.ReasonSpam, "")
+	assert.ErrorIs(t, err, app.ErrUnauthenticated)
+	_, err = a.CreateReport(ctx, reports.TargetAd, 100, reports.ReasonSpam, "")
+	assert.ErrorIs(t, err, app.ErrNotFound)
+	_, err = a.CreateReport(authorCtx, reports.TargetAd, ad.ID, reports.ReasonSpam, "")
+	assert.ErrorIs(t, err, app.ErrSelfReport)
+	_, err = a.CreateReport(authorCtx, reports.TargetUser, ad.AuthorID, reports.ReasonOffensive, "")
+	assert.ErrorIs(t, err, app.ErrSelfReport)
+
+	r, err := a.CreateReport(ctx, reports.TargetAd, ad.ID, reports.ReasonScam, " просит предоплату ")
+	require.NoError(t, err)
+	assert.Equal(t, reports.StatusOpen, r.Status)
+	assert.Equal(t, "просит предоплату", r.Comment)
+	assert.Equal(t, reports.TargetAd, r.Target)
+	assert.Equal(t, ad.ID, r.TargetID)
+
+	// одна жалоба от пользователя на цель, на автора можно пожаловаться отдельно
+	_, err = a.CreateReport(ctx, reports.TargetAd, ad.ID, reports.ReasonSpam, "")
+	assert.ErrorIs(t, err, app.ErrAlreadyReported)
+	_, err = a.CreateReport(ctx, reports.TargetUser, ad.AuthorID, reports.ReasonScam, "")
+	assert.NoError(t, err)
+}
+
+func TestReportsDisabled(t *testing.T) {
+	a := newTestApp()
+	_, err := a.CreateReport(context.Background(), reports.TargetAd, 0, reports.ReasonSpam, "")
+	assert.ErrorIs(t, err, app.ErrReportsDisabled)
+	_, err = a.ListReports(context.Background(), reports.Filter{}, app.PageRequest{})
+	assert.ErrorIs(t, err, app.ErrReportsDisabled)
+}
+
+func TestReportsUnpublishAd(t *testing.T) {
+	notifier := &recordingNotifier{}
+	a := newReportingApp(app.WithReportThreshold(2), app.WithNotifier(notifier))
+	authorCtx, moderatorCtx := moderationUsers(t, a)
+	ctxs := reporters(t, a, 3)
+
+	ad, err := a.CreateAd(authorCtx, "hello", "world", testCategoryID, ads.Price{}, nil)
+	require.NoError(t, err)
+	_, err = a.UpdateStatusById(authorCtx, ad.ID, true)
+	require.NoError(t, err)
+
+	first, err := a.CreateReport(ctxs[0], reports.TargetAd, ad.ID, reports.ReasonScam, "")
+	require.NoError(t, err)
+	// отклонённая жалоба не считается
+	_, err = a.DismissReport(moderatorCtx, first.ID, "")
+	require.NoError(t, err)
+	second, err := a.CreateReport(ctxs[1], reports.TargetAd, ad.ID, reports.ReasonScam, "")
+	require.NoError(t, err)
+	ad, err = a.GetAdByID(context.Background(), ad.ID)
+	require.NoError(t, err)
+	assert.True(t, ad.Published)
+
+	_, err = a.CreateReport(ctxs[2], reports.TargetAd, ad.ID, reports.ReasonSpam, "")
+	require.NoError(t, err)
+	ad, err = a.GetAdByID(authorCtx, ad.ID)
+	require.NoError(t, err)
+	assert.False(t, ad.Published)
+	assert.Equal(t, ads.StatusArchived, ad.Status)
+
+	// снятое объявление больше не видно ни жалующимся, ни анонимам
+	for _, ctx := range []context.Context{ctxs[0], context.Background()} {
+		_, err = a.GetAdByID(ctx, ad.ID)
+		assert.ErrorIs(t, err, app.ErrNotFound)
+		page, err := a.GetAdsByFilter(ctx, app.FilterOpts{Published: ads.OnlyUnpublished}, app.PageRequest{})
+		require.NoError(t, err)
+		assert.Empty(t, page.Ads)
+	}
+
+	sent := notifier.Sent()
+	require.Len(t, sent, 1)
+	assert.Equal(t, notifications.KindAdSuspended, sent[0].Kind)
+	assert.Equal(t, ad.AuthorID, sent[0].UserID)
+	assert.Equal(t, ad.ID, sent[0].AdID)
+	assert.Equal(t, "unpublished after 2 reports", sent[0].Text)
+
+	// пока жалобы открыты, автор не возвращает объявление сам: оно уходит на проверку, в том числе после правки
+	ad, err = a.UpdateStatusById(authorCtx, ad.ID, true)
+	require.NoError(t, err)
+	assert.Equal(t, ads.StatusPending, ad.EffectiveStatus())
+	_, err = a.UpdateAdById(authorCtx, ad.ID, "hello", "fixed", 0, nil, nil, 0)
+	require.NoError(t, err)
+	ad, err = a.UpdateStatusById(authorCtx, ad.ID, true)
+	require.NoError(t, err)
+	assert.Equal(t, ads.StatusPending, ad.EffectiveStatus())
+	ad, err = a.ApproveAd(moderatorCtx, ad.ID, "")
+	require.NoError(t, err)
+	assert.True(t, ad.Published)
+
+	// после отклонения жалобы их меньше порога, и снятое объявление автор публикует сам
+	_, err = a.DismissReport(moderatorCtx, second.ID, "")
+	require.NoError(t, err)
+	_, err = a.UpdateStatusById(authorCtx, ad.ID, false)
+	require.NoError(t, err)
+	ad, err = a.UpdateStatusById(authorCtx, ad.ID, true)
+	require.NoError(t, err)
+	assert.True(t, ad.Published)
+
+	// жалобы на пользователя объявления не снимают
+	for _, ctx := range ctxs {
+		_, err = a.CreateReport(ctx, reports.TargetUser, ad.AuthorID, reports.ReasonSpam, "")
+		require.NoError(t, err)
+	}
+	ad, err = a.GetAdByID(context.Background(), ad.ID)
+	require.NoError(t, err)
+	assert.True(t, ad.Published)
+	assert.Len(t, notifier.Sent(), 1)
+}
+
+func TestReviewReports(t *testing.T) {
+	a := newReportingApp()
+	authorCtx, moderatorCtx := moderationUsers(t, a)
+	ctxs := reporters(t, a, 3)
+	ad, err := a.CreateAd(authorCtx, "hello", "world", testCategoryID, ads.Price{}, nil)
+	require.NoError(t, err)
+	own, err := a.CreateAd(moderatorCtx, "mine", "text", testCategoryID, ads.Price{}, nil)
+	require.NoError(t, err)
+
+	var filed []*reports.Report
+	for _, ctx := range ctxs {
+		r, err := a.CreateReport(ctx, reports.TargetAd, ad.ID, reports.ReasonSpam, "")
+		require.NoError(t, err)
+		filed = append(filed, r)
+	}
+	onOwn, err := a.CreateReport(ctxs[0], reports.TargetAd, own.ID, reports.ReasonSpam, "")
+	require.NoError(t, err)
+
+	_, err = a.ListReports(authorCtx, reports.Filter{}, app.PageRequest{})
+	assert.ErrorIs(t, err, app.ErrAccessDenied)
+	_, err = a.ListReports(moderatorCtx, reports.Filter{Status: "closed"}, app.PageRequest{})
+	assert.ErrorIs(t, err, app.ErrInvalidReport)
+
+	page, err := a.ListReports(moderatorCtx, reports.Filter{Target: reports.TargetAd, TargetIDs: []int64{ad.ID}}, app.PageRequest{Limit: 2})
+	require.NoError(t, err)
+	require.Len(t, page.Reports, 2)
+	assert.Equal(t, filed[0].ID, page.Reports[0].ID)
+	require.NotEmpty(t, page.NextCursor)
+	page, err = a.ListReports(moderatorCtx, reports.Filter{Target: reports.TargetAd, TargetIDs: []int64{ad.ID}}, app.PageRequest{Limit: 2, Cursor: page.NextCursor})
+	require.NoError(t, err)
+	require.Len(t, page.Reports, 1)
+	assert.Equal(t, filed[2].ID, page.Reports[0].ID)
+	assert.Empty(t, page.NextCursor)
+
+	_, err = a.ResolveReport(authorCtx, filed[0].ID, "")
+	assert.ErrorIs(t, err, app.ErrAccessDenied)
+	_, err = a.ResolveReport(moderatorCtx, 100, "")
+	assert.ErrorIs(t, err, app.ErrNotFound)
+	_, err = a.ResolveReport(moderatorCtx, filed[0].ID, strings.Repeat("я", app.MaxReasonLength+1))
+	assert.ErrorIs(t, err, app.ErrInvalidNote)
+	// жалобы на свои объявления модератор не рассматривает
+	_, err = a.DismissReport(moderatorCtx, onOwn.ID, "")
+	assert.ErrorIs(t, err, app.ErrAccessDenied)
+
+	r, err := a.ResolveReport(moderatorCtx, filed[0].ID, "объявление снято")
+	require.NoError(t, err)
+	assert.Equal(t, reports.StatusResolved, r.Status)
+	assert.Equal(t, "объявление снято", r.Resolution.Note)
+	assert.False(t, r.Resolution.At.IsZero())
+	_, err = a.DismissReport(moderatorCtx, filed[0].ID, "")
+	assert.ErrorIs(t, err, app.ErrReportClosed)
+	r, err = a.DismissReport(moderatorCtx, filed[1].ID, "")
+	require.NoError(t, err)
+	assert.Equal(t, reports.StatusDismissed, r.Status)
+
+	page, err = a.ListReports(moderatorCtx, reports.Filter{Status: reports.StatusOpen}, app.PageRequest{})
+	require.NoError(t, err)
+	require.Len(t, page.Reports, 2)
+	assert.Equal(t, filed[2].ID, page.Reports[0].ID)
+	assert.Equal(t, onOwn.ID, page.Reports[1].ID)
+
+	// жалобу на удалённое объявление можно закрыть
+	require.NoError(t, a.DeleteAd(authorCtx, ad.ID))
+	_, err = a.ResolveReport(moderatorCtx, filed[2].ID, "")
+	assert.NoError(t, err)
+}
+
+func TestReportsHTTP(t *testing.T) {
+	client := newTestClient(newReportingApp())
+	admin, err := client.createUser("Admin", "admin@mail.ru")
+	require.NoError(t, err)
+	author, err := client.createUser("Petya", "petya@mail.ru")
+	require.NoError(t, err)
+	reporter, err := client.createUser("Vasya", "vasya@mail.ru")
+	require.NoError(t, err)
+	ad, err := client.createAd(author.Data.ID, "hello", "world")
+	require.NoError(t, err)
+
+	type reportResponse struct {
+		Data struct {
+			ID         int64  `json:"id"`
+			ReporterID int64  `json:"reporter_id"`
+			Target     string `json:"target"`
+			TargetID   int64  `json:"target_id"`
+			Reason     string `json:"reason"`
+			Status     string `json:"status"`
+			Resolution *struct {
+				ModeratorID int64  `json:"moderator_id"`
+				Note        string `json:"note"`
+			} `json:"resolution"`
+		} `json:"data"`
+	}
+	adReports := fmt.Sprintf("/api/v1/ads/%d/reports", ad.Data.ID)
+	var report reportResponse
+	err = client.sendJSON(http.MethodPost, adReports, reporter.Data.ID, map[string]any{"reason": "rude"}, &report)
+	assert.ErrorIs(t, err, ErrBadRequest)
+	err = client.sendJSON(http.MethodPost, adReports, author.Data.ID, map[string]any{"reason": "spam"}, &report)
+	assert.ErrorIs(t, err, ErrBadRequest)
+	err = client.sendJSON(http.MethodPost, adReports, reporter.Data.ID, map[string]any{"reason": "scam", "comment": "предоплата"}, &report)
+	require.NoError(t, err)
+	assert.Equal(t, reporter.Data.ID, report.Data.ReporterID)
+	assert.Equal(t, "ad", report.Data.Target)
+	assert.Equal(t, "open", report.Data.Status)
+	assert.Nil(t, report.Data.Resolution)
+
+	resp, err := client.post(adReports, reporter.Data.ID, map[string]any{"reason": "spam"})
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusConflict, resp.StatusCode)
+
+	var userReport reportResponse
+	err = client.sendJSON(http.MethodPost, fmt.Sprintf("/api/v1/users/%d/reports", author.Data.ID), reporter.Data.ID,
+		map[string]any{"reason": "offensive"}, &userReport)
+	require.NoError(t, err)
+	assert.Equal(t, "user", userReport.Data.Target)
+	assert.Equal(t, author.Data.ID, userReport.Data.TargetID)
+
+	var list struct {
+		Data []struct {
+			ID     int64  `json:"id"`
+			Target string `json:"target"`
+		} `json:"data"`
+		NextCursor *string `json:"next_cursor"`
+	}
+	err = client.sendJSON(http.MethodGet, "/api/v1/moderation/reports", reporter.Data.ID, nil, &list)
+	assert.ErrorIs(t, err, ErrForbidden)
+	err = client.sendJSON(http.MethodGet, "/api/v1/moderation/reports?target_id=1", admin.Data.ID, nil, &list)
+	assert.ErrorIs(t, err, ErrBadRequest, "target_id needs a target")
+	err = client.sendJSON(http.MethodGet, "/api/v1/moderation/reports?status=open&target=user", admin.Data.ID, nil, &list)
+	require.NoError(t, err)
+	require.Len(t, list.Data, 1)
+	assert.Equal(t, userReport.Data.ID, list.Data[0].ID)
+	assert.Nil(t, list.NextCursor)
+
+	resolve := fmt.Sprintf("/api/v1/moderation/reports/%d/resolve", report.Data.ID)
+	err = client.sendJSON(http.MethodPost, resolve, reporter.Data.ID, map[string]any{}, &report)
+	assert.ErrorIs(t, err, ErrForbidden)
+	err = client.sendJSON(http.MethodPost, resolve, admin.Data.ID, map[string]any{"note": "снято"}, &report)
+	require.NoError(t, err)
+	assert.Equal(t, "resolved", report.Data.Status)
+	require.NotNil(t, report.Data.Resolution)
+	assert.Equal(t, admin.Data.ID, report.Data.Resolution.ModeratorID)
+	assert.Equal(t, "снято", report.Data.Resolution.Note)
+
+	resp, err = client.post(fmt.Sprintf("/api/v1/moderation/reports/%d/dismiss", report.Data.ID), admin.Data.ID, map[string]any{})
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusConflict, resp.StatusCode)
+}
+
+func TestReportedAdHidden(t *testing.T) {
+	a := newReportingApp(app.WithReportThreshold(1))
+	client := newTestClient(a)
+	grpcClient := newGRPCClient(t, a)
+	author, err := client.createUser("Petya", "petya@mail.ru")
+	require.NoError(t, err)
+	reporter, err := client.createUser("Vasya", "vasya@mail.ru")
+	require.NoError(t, err)
+	ad, err := client.createAd(author.Data.ID, "hello", "world")
+	require.NoError(t, err)
+	_, err = client.changeAdStatus(author.Data.ID, ad.Data.ID, true)
+	require.NoError(t, err)
+
+	var report struct{}
+	err = client.sendJSON(http.MethodPost, fmt.Sprintf("/api/v1/ads/%d/reports", ad.Data.ID), reporter.Data.ID, map[string]any{"reason": "spam"}, &report)
+	require.NoError(t, err)
+
+	// объявление снято по жалобе: посторонние его не находят ни по ID, ни среди снятых
+	path := fmt.Sprintf("/api/v1/ads/%d", ad.Data.ID)
+	for _, headers := range []map[string]string{nil, {"Authorization": client.bearer(reporter.Data.ID)}} {
+		resp, err := client.do(http.MethodGet, path, nil, headers)
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+	}
+	got, err := client.getAdById(author.Data.ID, ad.Data.ID)
+	require.NoError(t, err)
+	assert.False(t, got.Data.Published)
+
+	ctx := context.Background()
+	unpublished := &grpcPort.FindAdsRequest{Published: grpcPort.PublishedFilter_UNPUBLISHED}
+	reporterCtx := metadata.AppendToOutgoingContext(ctx, "authorization", client.bearer(reporter.Data.ID))
+	for _, ctx := range []context.Context{ctx, reporterCtx} {
+		res, err := grpcClient.FindAds(ctx, unpublished)
+		require.NoError(t, err)
+		assert.Empty(t, res.List)
+	}
+	authorCtx := metadata.AppendToOutgoingContext(ctx, "authorization", client.bearer(author.Data.ID))
+	res, err := grpcClient.FindAds(authorCtx, unpublished)
+	require.NoError(t, err)
+	require.Len(t, res.List, 1)
+	assert.Equal(t, ad.Data.ID, res.List[0].Id)
+}
+
+func TestGRPCReports(t *testing.T) {
+	client := newGRPCClient(t, newReportingApp())
+	ctx := context.Background()
+	_, adminCtx := grpcSignUp(t, ctx, client, "Admin")
+	author, authorCtx := grpcSignUp(t, ctx, client, "Petya")
+	reporter, reporterCtx := grpcSignUp(t, ctx, client, "Vasya")
+
+	ad, err := client.CreateAd(authorCtx, &grpcPort.CreateAdRequest{Title: "ad", Text: "text", CategoryId: testCategoryID})
+	require.NoError(t, err)
+
+	_, err = client.CreateReport(ctx, &grpcPort.CreateReportRequest{Target: "ad", TargetId: ad.Id, Reason: "spam"})
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+	_, err = client.CreateReport(reporterCtx, &grpcPort.CreateReportRequest{Target: "ad", TargetId: ad.Id, Reason: "other"})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	_, err = client.CreateReport(reporterCtx, &grpcPort.CreateReportRequest{Target: "ad", TargetId: ad.Id + 100, Reason: "spam"})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+	r, err := client.CreateReport(reporterCtx, &grpcPort.CreateReportRequest{Target: "ad", TargetId: ad.Id, Reason: "spam"})
+	require.NoError(t, err)
+	assert.Equal(t, reporter.Id, r.ReporterId)
+	assert.Equal(t, "open", r.Status)
+	assert.Nil(t, r.Resolution)
+	_, err = client.CreateReport(reporterCtx, &grpcPort.CreateReportRequest{Target: "ad", TargetId: ad.Id, Reason: "scam"})
+	assert.Equal(t, codes.AlreadyExists, status.Code(err))
+	_, err = client.CreateReport(reporterCtx, &grpcPort.CreateReportRequest{Target: "user", TargetId: author.Id, Reason: "scam"})
+	require.NoError(t, err)
+
+	_, err = client.ListReports(reporterCtx, &grpcPort.ListReportsRequest{})
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+	list, err := client.ListReports(adminCtx, &grpcPort.ListReportsRequest{Target: "ad", TargetIds: []int64{ad.Id}})
+	require.NoError(t, err)
+	require.Len(t, list.List, 1)
+	assert.Equal(t, r.Id, list.List[0].Id)
+	list, err = client.ListReports(adminCtx, &grpcPort.ListReportsRequest{Page: &grpcPort.PageRequest{Limit: 1}})
+	require.NoError(t, err)
+	require.Len(t, list.List, 1)
+	assert.NotEmpty(t, list.NextCursor)
+
+	dismissed, err := client.DismissReport(adminCtx, &grpcPort.CloseReportRequest{ReportId: r.Id, Note: "нормальное объявление"})
+	require.NoError(t, err)
+	assert.Equal(t, "dismissed", dismissed.Status)
+	require.NotNil(t, dismissed.Resolution)
+	assert.Equal(t, "нормальное объявление", dismissed.Resolution.Note)
+	_, err = client.ResolveReport(adminCtx, &grpcPort.CloseReportRequest{ReportId: r.Id})
+	assert.Equal(t, codes.FailedPrecondition, status.Code(err))
+}
@@ -0,0 +1,130 @@
+package tests
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"homework9/internal/app"
+	"homework9/internal/users"
+)
+
+func TestDefaultPolicy(t *testing.T) {
+	p := app.DefaultPolicy()
+	author := app.Actor{ID: 1, Role: users.RoleUser}
+	stranger := app.Actor{ID: 2, Role: users.RoleUser}
+	moderator := app.Actor{ID: 3, Role: users.RoleModerator}
+	admin := app.Actor{ID: 4, Role: users.RoleAdmin}
+
+	tests := []struct {
+		actor  app.Actor
+		action app.Action
+		allow  bool
+	}{
+		{author, app.ActionUpdateAd, true},
+		{stranger, app.ActionUpdateAd, false},
+		{moderator, app.ActionUpdateAd, false},
+		{admin, app.ActionUpdateAd, false},
+		{moderator, app.ActionUnpublishAd, true},
+		{moderator, app.ActionPublishAd, false},
+		{moderator, app.ActionDeleteAd, false},
+		{moderator, app.ActionReviewAd, true},
+		{admin, app.ActionReviewAd, true},
+		{author, app.ActionReviewAd, false},
+		{author, app.ActionViewUnpublishedAd, true},
+		{stranger, app.ActionViewUnpublishedAd, false},
+		{moderator, app.ActionViewUnpublishedAd, true},
+		{admin, app.ActionDeleteAd, true},
+		{author, app.ActionCreateReport, true},
+		{stranger, app.ActionCreateReport, false},
+		{moderator, app.ActionReviewReports, true},
+		{admin, app.ActionReviewReports, true},
+		{author, app.ActionReviewReports, false},
+		{author, app.ActionManageFavorites, true},
+		{admin, app.ActionManageFavorites, false},
+		{author, app.ActionSendMessages, true},
+		{stranger, app.ActionSendMessages, false},
+		{author, app.ActionWatchEvents, true},
+		{stranger, app.ActionWatchEvents, false},
+		{stranger, app.ActionDeleteUser, false},
+		{admin, app.ActionDeleteUser, true},
+		{author, app.ActionSetRole, false},
+		{admin, app.ActionSetRole, true},
+		{admin, app.Action("ad.unknown"), false},
+	}
+	for _, tt := range tests {
+		err := p.Authorize(tt.actor, tt.action, author.ID)
+		if tt.allow {
+			assert.NoError(t, err, "%s %s", tt.actor.Role, tt.action)
+		} else {
+			assert.ErrorIs(t, err, app.ErrAccessDenied, "%s %s", tt.actor.Role, tt.action)
+		}
+	}
+}
+
+func TestModeratorUnpublishesAnyAd(t *testing.T) {
+	client := newTestClient(newTestApp(app.WithAdminEmails("admin@mail.ru")))
+	admin, err := client.createUser("Admin", "admin@mail.ru")
+	require.NoError(t, err)
+	assert.Equal(t, "admin", admin.Data.Role)
+	author, err := client.createUser("Petya", "petya@mail.ru")
+	require.NoError(t, err)
+	assert.Equal(t, "user", author.Data.Role)
+	moderator, err := client.createUser("Vasya", "vasya@mail.ru")
+	require.NoError(t, err)
+
+	// раздавать роли может только администратор
+	_, err = client.setUserRole(moderator.Data.ID, moderator.Data.ID, "moderator")
+	assert.ErrorIs(t, err, ErrForbidden)
+	_, err = client.setUserRole(admin.Data.ID, moderator.Data.ID, "superuser")
+	assert.ErrorIs(t, err, ErrBadRequest)
+	promoted, err := client.setUserRole(admin.Data.ID, moderator.Data.ID, "moderator")
+	require.NoError(t, err)
+	assert.Equal(t, "moderator", promoted.Data.Role)
+
+	ad, err := client.createAd(author.Data.ID, "hello", "world")
+	require.NoError(t, err)
+	_, err = client.changeAdStatus(author.Data.ID, ad.Data.ID, true)
+	require.NoError(t, err)
+
+	unpublished, err := client.changeAdStatus(moderator.Data.ID, ad.Data.ID, false)
+	require.NoError(t, err)
+	assert.False(t, unpublished.Data.Published)
+	assert.Equal(t, author.Data.ID, unpublished.Data.AuthorID)
+
+	_, err = client.changeAdStatus(moderator.Data.ID, ad.Data.ID, true)
+	assert.ErrorIs(t, err, ErrForbidden, "moderators only unpublish")
+	_, err = client.updateAd(moderator.Data.ID, ad.Data.ID, "edited", "by moderator")
+	assert.ErrorIs(t, err, ErrForbidden)
+}
+
+func TestAdminDeletesAnyUser(t *testing.T) {
+	client := newTestClient(newTestApp(app.WithAdminEmails("admin@mail.ru")))
+	admin, err := client.createUser("Admin", "admin@mail.ru")
+	require.NoError(t, err)
+	author, err := client.createUser("Petya", "petya@mail.ru")
+	require.NoError(t, err)
+	other, err := client.createUser("Vasya", "vasya@mail.ru")
+	require.NoError(t, err)
+	ad, err := client.createAd(author.Data.ID, "hello", "world")
+	require.NoError(t, err)
+
+	path := fmt.Sprintf("/api/v1/users/%d", author.Data.ID)
+	resp, err := client.do(http.MethodDelete, path, nil, map[string]string{"Authorization": client.bearer(other.Data.ID)})
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+
+	resp, err = client.do(http.MethodDelete, path, nil, map[string]string{"Authorization": client.bearer(admin.Data.ID)})
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	_, err = client.getAdById(admin.Data.ID, ad.Data.ID)
+	assert.Error(t, err, "ads of the deleted user are deleted too")
+
+	// токен удалённого пользователя больше ничего не разрешает
+	_, err = client.createAd(author.Data.ID, "hello", "again")
+	assert.ErrorIs(t, err, ErrUnauthorized)
+}
@@ -0,0 +1,241 @@
+package tests
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"homework9/internal/ads"
+	"homework9/internal/app"
+	grpcPort "homework9/internal/ports/grpc"
+)
+
+// testClock - часы, которые идут только по команде теста
+type testClock struct {
+	mx  sync.Mutex
+	now time.Time
+}
+
+func newTestClock() *testClock {
+	return &testClock{now: time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)}
+}
+
+func (c *testClock) Now() time.Time {
+	c.mx.Lock()
+	defer c.mx.Unlock()
+	return c.now
+}
+
+func (c *testClock) Advance(d time.Duration) {
+	c.mx.Lock()
+	defer c.mx.Unlock()
+	c.now = c.now.Add(d)
+}
+
+const testAdLifetime = 24 * time.Hour
+
+func newScheduledApp(clock *testClock) app.App {
+	return newTestApp(app.WithClock(clock.Now), app.WithAdLifetime(testAdLifetime))
+}
+
+func TestRunSchedule(t *testing.T) {
+	clock := newTestClock()
+	a := newScheduledApp(clock)
+	ctx := context.Background()
+	u, err := a.CreateUser(ctx, "Petya", "petya@mail.ru", testPassword)
+	require.NoError(t, err)
+	userCtx := app.WithUserID(ctx, u.ID)
+
+	ad, err := a.CreateAd(userCtx, "hello", "world", testCategoryID, ads.Price{}, nil)
+	require.NoError(t, err)
+	start := clock.Now()
+
+	for _, s := range []struct{ publish, expires time.Time }{
+		{start.Add(-time.Minute), time.Time{}},
+		{time.Time{}, start},
+		{start.Add(2 * time.Hour), start.Add(time.Hour)},
+	} {
+		_, err = a.ScheduleAd(userCtx, ad.ID, s.publish, s.expires)
+		assert.ErrorIs(t, err, app.ErrInvalidSchedule, "%+v", s)
+	}
+	other, err := a.CreateUser(ctx, "Vasya", "vasya@mail.ru", testPassword)
+	require.NoError(t, err)
+	_, err = a.ScheduleAd(app.WithUserID(ctx, other.ID), ad.ID, start.Add(time.Hour), time.Time{})
+	assert.ErrorIs(t, err, app.ErrAccessDenied)
+
+	scheduled, err := a.ScheduleAd(userCtx, ad.ID, start.Add(time.Hour), start.Add(3*time.Hour))
+	require.NoError(t, err)
+	assert.True(t, start.Add(time.Hour).Equal(scheduled.PublishAt))
+
+	clock.Advance(30 * time.Minute)
+	res, err := a.RunSchedule(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, app.ScheduleResult{}, res)
+
+	clock.Advance(time.Hour)
+	res, err = a.RunSchedule(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, app.ScheduleResult{Published: 1}, res)
+	got, err := a.GetAdByID(ctx, ad.ID)
+	require.NoError(t, err)
+	assert.True(t, got.Published)
+	assert.True(t, got.PublishAt.IsZero(), "publication happens once")
+	assert.True(t, start.Add(3*time.Hour).Equal(got.ExpiresAt), "own expiration is kept")
+
+	clock.Advance(2 * time.Hour)
+	res, err = a.RunSchedule(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, app.ScheduleResult{Expired: 1}, res)
+	res, err = a.RunSchedule(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, app.ScheduleResult{}, res, "second pass has nothing to do")
+
+	// истёкшее объявление публикуется только продлением
+	_, err = a.UpdateStatusById(userCtx, ad.ID, true)
+	assert.ErrorIs(t, err, app.ErrAdExpired)
+	renewed, err := a.RenewAd(userCtx, ad.ID)
+	require.NoError(t, err)
+	assert.True(t, renewed.Published)
+	assert.True(t, clock.Now().Add(testAdLifetime).Equal(renewed.ExpiresAt))
+
+	// объявление без своего срока получает срок жизни при публикации
+	fresh, err := a.CreateAd(userCtx, "bye", "world", testCategoryID, ads.Price{}, nil)
+	require.NoError(t, err)
+	fresh, err = a.UpdateStatusById(userCtx, fresh.ID, true)
+	require.NoError(t, err)
+	assert.True(t, clock.Now().Add(testAdLifetime).Equal(fresh.ExpiresAt))
+
+	// запланированная публикация, срок которой истёк раньше прохода, отменяется
+	late, err := a.CreateAd(userCtx, "late", "world", testCategoryID, ads.Price{}, nil)
+	require.NoError(t, err)
+	_, err = a.ScheduleAd(userCtx, late.ID, clock.Now().Add(time.Minute), clock.Now().Add(2*time.Minute))
+	require.NoError(t, err)
+	clock.Advance(time.Hour)
+	_, err = a.RunSchedule(ctx)
+	require.NoError(t, err)
+	late, err = a.GetAdByID(userCtx, late.ID)
+	require.NoError(t, err)
+	assert.False(t, late.Published)
+	assert.True(t, late.PublishAt.IsZero())
+}
+
+func TestScheduler(t *testing.T) {
+	clock := newTestClock()
+	a := newScheduledApp(clock)
+	ctx := context.Background()
+	u, err := a.CreateUser(ctx, "Petya", "petya@mail.ru", testPassword)
+	require.NoError(t, err)
+	userCtx := app.WithUserID(ctx, u.ID)
+	ad, err := a.CreateAd(userCtx, "hello", "world", testCategoryID, ads.Price{}, nil)
+	require.NoError(t, err)
+	_, err = a.ScheduleAd(userCtx, ad.ID, clock.Now().Add(time.Hour), time.Time{})
+	require.NoError(t, err)
+
+	s := app.NewScheduler(a, time.Millisecond)
+	s.Start()
+	clock.Advance(2 * time.Hour)
+	assert.Eventually(t, func() bool {
+		got, err := a.GetAdByID(ctx, ad.ID)
+		return err == nil && got.Published
+	}, time.Second, time.Millisecond)
+	s.Stop()
+	s.Stop()
+
+	// остановленный планировщик больше ничего не публикует
+	other, err := a.CreateAd(userCtx, "bye", "world", testCategoryID, ads.Price{}, nil)
+	require.NoError(t, err)
+	_, err = a.ScheduleAd(userCtx, other.ID, clock.Now().Add(time.Minute), time.Time{})
+	require.NoError(t, err)
+	clock.Advance(time.Hour)
+	time.Sleep(10 * time.Millisecond)
+	other, err = a.GetAdByID(userCtx, other.ID)
+	require.NoError(t, err)
+	assert.False(t, other.Published)
+
+	app.NewScheduler(a, 0).Stop()
+}
+
+func TestAdScheduleHTTP(t *testing.T) {
+	clock := newTestClock()
+	client := newTestClient(newScheduledApp(clock))
+	u, err := client.createUser("Petya", "petya@mail.ru")
+	require.NoError(t, err)
+	ad, err := client.createAd(u.Data.ID, "hello", "world")
+	require.NoError(t, err)
+
+	var scheduled struct {
+		Data struct {
+			PublishAt *time.Time `json:"publish_at"`
+			ExpiresAt *time.Time `json:"expires_at"`
+			Published bool       `json:"published"`
+		} `json:"data"`
+	}
+	path := fmt.Sprintf("/api/v1/ads/%d/schedule", ad.Data.ID)
+	publishAt := clock.Now().Add(time.Hour)
+	err = client.sendJSON(http.MethodPut, path, u.Data.ID, map[string]any{"publish_at": publishAt}, &scheduled)
+	require.NoError(t, err)
+	require.NotNil(t, scheduled.Data.PublishAt)
+	assert.True(t, publishAt.Equal(*scheduled.Data.PublishAt))
+	assert.Nil(t, scheduled.Data.ExpiresAt)
+
+	err = client.sendJSON(http.MethodPut, path, u.Data.ID, map[string]any{"publish_at": clock.Now().Add(-time.Hour)}, &scheduled)
+	assert.ErrorIs(t, err, ErrBadRequest)
+
+	// снятое по сроку объявление нельзя просто опубликовать, но можно продлить
+	err = client.sendJSON(http.MethodPut, path, u.Data.ID, map[string]any{"expires_at": clock.Now().Add(time.Minute)}, &scheduled)
+	require.NoError(t, err)
+	clock.Advance(time.Hour)
+	resp, err := client.do(http.MethodPut, fmt.Sprintf("/api/v1/ads/%d/status", ad.Data.ID), map[string]any{"published": true},
+		map[string]string{"Authorization": client.bearer(u.Data.ID)})
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusConflict, resp.StatusCode)
+
+	err = client.sendJSON(http.MethodPost, fmt.Sprintf("/api/v1/ads/%d/renew", ad.Data.ID), u.Data.ID, nil, &scheduled)
+	require.NoError(t, err)
+	assert.True(t, scheduled.Data.Published)
+	require.NotNil(t, scheduled.Data.ExpiresAt)
+	assert.True(t, clock.Now().Add(testAdLifetime).Equal(*scheduled.Data.ExpiresAt))
+
+	resp, err = client.do(http.MethodPost, fmt.Sprintf("/api/v1/ads/%d/renew", ad.Data.ID), nil, nil)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+}
+
+func TestGRPCAdSchedule(t *testing.T) {
+	clock := newTestClock()
+	client := newGRPCClient(t, newScheduledApp(clock))
+	ctx := context.Background()
+	_, userCtx := grpcSignUp(t, ctx, client, "Petya")
+
+	ad, err := client.CreateAd(userCtx, &grpcPort.CreateAdRequest{Title: "ad", Text: "text", CategoryId: testCategoryID})
+	require.NoError(t, err)
+
+	_, err = client.ScheduleAd(userCtx, &grpcPort.ScheduleAdRequest{AdId: ad.Id, ExpiresAt: timestamppb.New(clock.Now().Add(-time.Hour))})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	scheduled, err := client.ScheduleAd(userCtx, &grpcPort.ScheduleAdRequest{AdId: ad.Id,
+		PublishAt: timestamppb.New(clock.Now().Add(time.Hour)), ExpiresAt: timestamppb.New(clock.Now().Add(2 * time.Hour))})
+	require.NoError(t, err)
+	assert.True(t, clock.Now().Add(time.Hour).Equal(scheduled.PublishAt.AsTime()))
+
+	clock.Advance(3 * time.Hour)
+	_, err = client.ChangeAdStatus(userCtx, &grpcPort.ChangeAdStatusRequest{AdId: ad.Id, Published: true})
+	assert.Equal(t, codes.FailedPrecondition, status.Code(err))
+
+	renewed, err := client.RenewAd(userCtx, &grpcPort.RenewAdRequest{AdId: ad.Id})
+	require.NoError(t, err)
+	assert.True(t, renewed.Published)
+	assert.Nil(t, renewed.PublishAt)
+
+	_, err = client.RenewAd(ctx, &grpcPort.RenewAdRequest{AdId: ad.Id})
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+}
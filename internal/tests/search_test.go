@@ -0,0 +1,121 @@
+package tests
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	grpcPort "homework9/internal/ports/grpc"
+	"homework9/internal/search"
+)
+
+func TestSearchTerms(t *testing.T) {
+	assert.Equal(t, search.Terms("велосипед"), search.Terms("Велосипеды"))
+	assert.Equal(t, search.Terms("Ёлка"), search.Terms("елки"))
+	assert.Equal(t, search.Terms("bike"), search.Terms("Bikes"))
+	assert.Equal(t, search.Terms("running"), search.Terms("run"))
+	assert.Equal(t, []string{"2020"}, search.Terms("2020"))
+	assert.Empty(t, search.Terms("the и, а ... в"), "stop words and punctuation are dropped")
+	assert.Len(t, search.Terms("Продаю mountain-bike"), 3)
+}
+
+func TestSearchIndexRanking(t *testing.T) {
+	idx := search.NewIndex()
+	idx.Put(1, "Продаю диван", "Хороший диван, почти новый")
+	idx.Put(2, "Продаю стол", "К столу прилагается диван")
+	idx.Put(3, "Продаю шкаф", "Шкаф большой")
+
+	hits := idx.Search("диваны")
+	require.Len(t, hits, 2)
+	assert.Equal(t, int64(1), hits[0].ID, "title match and higher frequency rank first")
+	assert.Equal(t, int64(2), hits[1].ID)
+	assert.Greater(t, hits[0].Score, hits[1].Score)
+
+	// общий для всех документов терм почти ничего не добавляет к релевантности
+	hits = idx.Search("продаю шкаф")
+	require.Len(t, hits, 3)
+	assert.Equal(t, int64(3), hits[0].ID)
+
+	idx.Put(1, "Продаю кресло", "Кресло мягкое")
+	idx.Remove(3)
+	assert.Equal(t, 2, idx.Len())
+	hits = idx.Search("диван")
+	require.Len(t, hits, 1)
+	assert.Equal(t, int64(2), hits[0].ID)
+	assert.Empty(t, idx.Search("шкаф"))
+	assert.Empty(t, idx.Search(""))
+}
+
+func TestSearchAds(t *testing.T) {
+	client := getTestClient()
+	u, err := client.createUser("Petya", "petya@mail.ru")
+	require.NoError(t, err)
+
+	sofa, err := client.createAd(u.Data.ID, "Продаю диван", "Хороший диван, почти новый")
+	require.NoError(t, err)
+	table, err := client.createAd(u.Data.ID, "Продаю стол", "К столу прилагается диван")
+	require.NoError(t, err)
+	hidden, err := client.createAd(u.Data.ID, "Диван", "Не опубликован")
+	require.NoError(t, err)
+	for _, id := range []int64{sofa.Data.ID, table.Data.ID} {
+		_, err = client.changeAdStatus(u.Data.ID, id, true)
+		require.NoError(t, err)
+	}
+
+	res, err := client.searchAds("диваны")
+	require.NoError(t, err)
+	require.Len(t, res.Data, 2, "unpublished ads are not found")
+	assert.Equal(t, sofa.Data.ID, res.Data[0].ID)
+	assert.Equal(t, "Продаю диван", res.Data[0].Title)
+	assert.Equal(t, table.Data.ID, res.Data[1].ID)
+	assert.Greater(t, res.Data[0].Score, res.Data[1].Score)
+	assert.NotEqual(t, hidden.Data.ID, res.Data[1].ID)
+
+	// индекс обновляется при изменении и удалении
+	_, err = client.updateAd(u.Data.ID, sofa.Data.ID, "Продаю кресло", "Мягкое кресло")
+	require.NoError(t, err)
+	res, err = client.searchAds("кресло")
+	require.NoError(t, err)
+	require.Len(t, res.Data, 1)
+	assert.Equal(t, sofa.Data.ID, res.Data[0].ID)
+	res, err = client.searchAds("диван")
+	require.NoError(t, err)
+	require.Len(t, res.Data, 1)
+
+	resp, err := client.do(http.MethodDelete, fmt.Sprintf("/api/v1/ads/%d", table.Data.ID), nil, map[string]string{"Authorization": client.bearer(u.Data.ID)})
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	res, err = client.searchAds("диван")
+	require.NoError(t, err)
+	assert.Empty(t, res.Data)
+
+	_, err = client.searchAds("")
+	assert.ErrorIs(t, err, ErrBadRequest)
+}
+
+func TestGRPCSearchAds(t *testing.T) {
+	a := newTestApp()
+	client := newGRPCClient(t, a)
+	ctx := context.Background()
+
+	_, userCtx := grpcSignUp(t, ctx, client, "Petya")
+	ad, err := client.CreateAd(userCtx, &grpcPort.CreateAdRequest{Title: "Running shoes", Text: "Barely used", CategoryId: testCategoryID})
+	require.NoError(t, err)
+	_, err = client.ChangeAdStatus(userCtx, &grpcPort.ChangeAdStatusRequest{AdId: ad.Id, Published: true})
+	require.NoError(t, err)
+
+	res, err := client.SearchAds(ctx, &grpcPort.SearchAdsRequest{Query: "run shoe"})
+	require.NoError(t, err)
+	require.Len(t, res.Hits, 1)
+	assert.Equal(t, ad.Id, res.Hits[0].Ad.Id)
+	assert.Greater(t, res.Hits[0].Score, 0.0)
+
+	_, err = client.SearchAds(ctx, &grpcPort.SearchAdsRequest{})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+}
@@ -0,0 +1,79 @@
+package tests
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/test/bufconn"
+
+	grpcPort "homework9/internal/ports/grpc"
+	"homework9/internal/ports/httpgin"
+)
+
+func TestHTTPAndGRPCShareApp(t *testing.T) {
+	a := newTestApp()
+	httpClient := newTestClient(a)
+
+	lis := bufconn.Listen(1024 * 1024)
+	t.Cleanup(func() {
+		lis.Close()
+	})
+	srv := grpc.NewServer(grpc.UnaryInterceptor(grpcPort.AuthInterceptor(a)))
+	t.Cleanup(func() {
+		srv.Stop()
+	})
+	grpcPort.RegisterAdServiceServer(srv, grpcPort.NewService(a))
+	go func() {
+		assert.NoError(t, srv.Serve(lis), "srv.Serve")
+	}()
+
+	dialer := func(context.Context, string) (net.Conn, error) {
+		return lis.Dial()
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	t.Cleanup(cancel)
+	conn, err := grpc.DialContext(ctx, "", grpc.WithContextDialer(dialer), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.NoError(t, err, "grpc.DialContext")
+	t.Cleanup(func() {
+		conn.Close()
+	})
+	client := grpcPort.NewAdServiceClient(conn)
+
+	user, err := httpClient.createUser("Vladimir", "vova@mail.ru")
+	require.NoError(t, err)
+	// сессия, открытая через REST, действует и в gRPC
+	userCtx := metadata.AppendToOutgoingContext(ctx, "authorization", httpClient.bearer(user.Data.ID))
+	ad, err := client.CreateAd(userCtx, &grpcPort.CreateAdRequest{Title: "Cats", Text: "are cute", CategoryId: testCategoryID})
+	require.NoError(t, err, "client.CreateAd")
+
+	got, err := httpClient.getAdById(user.Data.ID, ad.Id)
+	require.NoError(t, err)
+	assert.Equal(t, "Cats", got.Data.Title)
+	assert.Equal(t, user.Data.ID, got.Data.AuthorID)
+}
+
+func TestHTTPServerShutdown(t *testing.T) {
+	server := httpgin.NewHTTPServer("127.0.0.1:0", newTestApp())
+	done := make(chan error, 1)
+	go func() {
+		done <- server.Listen()
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	assert.NoError(t, server.Shutdown(ctx))
+
+	select {
+	case err := <-done:
+		assert.NoError(t, err, "Listen must return nil after Shutdown")
+	case <-time.After(5 * time.Second):
+		t.Fatal("Listen did not return after Shutdown")
+	}
+}
@@ -0,0 +1,83 @@
+package tests
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"homework9/internal/adapters/sqlrepo"
+	"homework9/internal/ads"
+	"homework9/internal/users"
+)
+
+func TestSQLRepoPersistence(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "ads.db")
+
+	db, err := sqlrepo.Open(ctx, path)
+	assert.NoError(t, err)
+
+	userID, err := db.Users().AddUser(ctx, users.User{Nickname: "Petya", Email: "petya@mail.ru"})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(0), userID)
+	adID, err := db.Ads().AddAd(ctx, ads.Ad{Title: "hello", Text: "world", AuthorID: userID})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(0), adID)
+	removed, err := db.Ads().AddAd(ctx, ads.Ad{Title: "bye", Text: "world", AuthorID: userID})
+	assert.NoError(t, err)
+	assert.NoError(t, db.Ads().DeleteAdById(ctx, removed))
+	assert.NoError(t, db.Close())
+
+	db, err = sqlrepo.Open(ctx, path)
+	assert.NoError(t, err)
+	defer db.Close()
+
+	ad, err := db.Ads().GetAdById(ctx, adID)
+	assert.NoError(t, err)
+	assert.Equal(t, "hello", ad.Title)
+	assert.Equal(t, userID, ad.AuthorID)
+
+	_, err = db.Ads().GetAdById(ctx, removed)
+	assert.ErrorIs(t, err, ads.ErrNotFound)
+
+	next, err := db.Ads().AddAd(ctx, ads.Ad{Title: "next", Text: "one"})
+	assert.NoError(t, err)
+	assert.Equal(t, removed+1, next)
+}
+
+func TestSQLRepoMigratesOldSchema(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "ads.db")
+
+	// база в том виде, в каком её оставила первая миграция
+	raw, err := sql.Open("sqlite", path)
+	assert.NoError(t, err)
+	for _, stmt := range []string{
+		`CREATE TABLE schema_migrations (version INTEGER PRIMARY KEY, name TEXT NOT NULL, applied_at INTEGER NOT NULL)`,
+		`CREATE TABLE users (id INTEGER PRIMARY KEY, nickname TEXT NOT NULL, email TEXT NOT NULL)`,
+		`INSERT INTO schema_migrations VALUES (1, 'create users', 0)`,
+		`INSERT INTO users VALUES (0, 'Petya', 'petya@mail.ru'), (4, 'Vasya', 'vasya@mail.ru')`,
+	} {
+		_, err := raw.ExecContext(ctx, stmt)
+		assert.NoError(t, err)
+	}
+	assert.NoError(t, raw.Close())
+
+	db, err := sqlrepo.Open(ctx, path)
+	assert.NoError(t, err)
+	defer db.Close()
+
+	u, err := db.Users().GetUserByID(ctx, 4)
+	assert.NoError(t, err)
+	assert.Equal(t, "Vasya", u.Nickname)
+
+	id, err := db.Users().AddUser(ctx, users.User{Nickname: "Fedya", Email: "fedya@mail.ru"})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(5), id)
+
+	_, err = db.Ads().AddAd(ctx, ads.Ad{Title: "hello", Text: "world", AuthorID: id})
+	assert.NoError(t, err)
+}
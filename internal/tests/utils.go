@@ -2,27 +2,48 @@ package tests
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"homework9/internal/adapters/userrepo"
 	"io"
+	"net"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
 	"time"
 
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/test/bufconn"
+
 	"homework9/internal/adapters/adrepo"
+	"homework9/internal/adapters/categoryrepo"
+	"homework9/internal/adapters/jwtauth"
+	"homework9/internal/adapters/memtx"
+	"homework9/internal/adapters/sessionrepo"
 	"homework9/internal/app"
+	"homework9/internal/categories"
+	grpcPort "homework9/internal/ports/grpc"
 	"homework9/internal/ports/httpgin"
 )
 
 type adData struct {
-	ID        int64     `json:"id"`
-	Title     string    `json:"title"`
-	Text      string    `json:"text"`
-	AuthorID  int64     `json:"author_id"`
-	Published bool      `json:"published"`
-	Created   time.Time `json:"created_time"`
-	Modified  time.Time `json:"modified_time"`
+	ID         int64     `json:"id"`
+	Title      string    `json:"title"`
+	Text       string    `json:"text"`
+	AuthorID   int64     `json:"author_id"`
+	Published  bool      `json:"published"`
+	Created    time.Time `json:"created_time"`
+	Modified   time.Time `json:"modified_time"`
+	Version    int64     `json:"version"`
+	CategoryID int64     `json:"category_id"`
+	Favorites  int       `json:"favorites"`
 }
 
 type adResponse struct {
@@ -30,26 +51,80 @@ type adResponse struct {
 }
 
 type adsResponse struct {
-	Data []adData `json:"data"`
+	Data       []adData `json:"data"`
+	NextCursor *string  `json:"next_cursor"`
 }
 
 var (
-	ErrBadRequest = fmt.Errorf("bad request")
-	ErrForbidden  = fmt.Errorf("forbidden")
+	ErrBadRequest   = fmt.Errorf("bad request")
+	ErrForbidden    = fmt.Errorf("forbidden")
+	ErrUnauthorized = fmt.Errorf("unauthorized")
 )
 
+const testPassword = "password123"
+
+// testCategoryID - категория, которую newTestApp создаёт заранее: без категории объявление не создать
+const testCategoryID int64 = 1
+
 type testClient struct {
 	client  *http.Client
 	baseURL string
+	// tokens - access-токены пользователей, созданных через createUser
+	tokens map[int64]string
+}
+
+func newTestApp(opts ...app.Option) app.App {
+	return newTestAppOn(adrepo.New(), opts...)
+}
+
+// newTestAppOn - newTestApp поверх заданного хранилища объявлений, для проверок, которым оно нужно
+func newTestAppOn(adRepo *adrepo.RepositoryMap, opts ...app.Option) app.App {
+	userRepo := userrepo.New()
+	opts = append([]app.Option{
+		app.WithSessions(sessionrepo.New()),
+		app.WithAccessTokens(newTestKeyring(jwtauth.Key{ID: "test", Secret: testSecret})),
+		app.WithPasswordCost(bcrypt.MinCost),
+	}, opts...)
+	categoryRepo := categoryrepo.New()
+	categoryRepo.Restore(categories.Category{ID: testCategoryID, Name: "Разное"})
+	categoryRepo.SetLastID(testCategoryID)
+	return app.NewApp(adRepo, userRepo, categoryRepo, memtx.New(adRepo, userRepo), opts...)
+}
+
+var testSecret = []byte(strings.Repeat("s", jwtauth.MinSecretLen))
+
+func newTestKeyring(keys ...jwtauth.Key) *jwtauth.Keyring {
+	k, err := jwtauth.New(keys...)
+	if err != nil {
+		panic(err)
+	}
+	return k
 }
 
 func getTestClient() *testClient {
-	server := httpgin.NewHTTPServer(":18080", app.NewApp(adrepo.New(), userrepo.New()))
+	return newTestClient(newTestApp())
+}
+
+func newTestClient(a app.App, opts ...httpgin.Option) *testClient {
+	server := httpgin.NewHTTPServer(":18080", a, opts...)
 	testServer := httptest.NewServer(server.Handler())
 
 	return &testClient{
 		client:  testServer.Client(),
 		baseURL: testServer.URL,
+		tokens:  make(map[int64]string),
+	}
+}
+
+// bearer возвращает значение заголовка Authorization для пользователя
+func (tc *testClient) bearer(userID int64) string {
+	return "Bearer " + tc.tokens[userID]
+}
+
+// authorize подписывает запрос токеном пользователя, если тот входил через createUser
+func (tc *testClient) authorize(req *http.Request, userID int64) {
+	if _, ok := tc.tokens[userID]; ok {
+		req.Header.Set("Authorization", tc.bearer(userID))
 	}
 }
 
@@ -66,6 +141,9 @@ func (tc *testClient) getResponse(req *http.Request, out any) error {
 		if resp.StatusCode == http.StatusForbidden {
 			return ErrForbidden
 		}
+		if resp.StatusCode == http.StatusUnauthorized {
+			return ErrUnauthorized
+		}
 		return fmt.Errorf("unexpected status code: %s", resp.Status)
 	}
 
@@ -84,9 +162,9 @@ func (tc *testClient) getResponse(req *http.Request, out any) error {
 
 func (tc *testClient) createAd(userID int64, title string, text string) (adResponse, error) {
 	body := map[string]any{
-		"user_id": userID,
-		"title":   title,
-		"text":    text,
+		"title":       title,
+		"text":        text,
+		"category_id": testCategoryID,
 	}
 
 	data, err := json.Marshal(body)
@@ -100,6 +178,7 @@ func (tc *testClient) createAd(userID int64, title string, text string) (adRespo
 	}
 
 	req.Header.Add("Content-Type", "application/json")
+	tc.authorize(req, userID)
 
 	var response adResponse
 	err = tc.getResponse(req, &response)
@@ -112,7 +191,6 @@ func (tc *testClient) createAd(userID int64, title string, text string) (adRespo
 
 func (tc *testClient) changeAdStatus(userID int64, adID int64, published bool) (adResponse, error) {
 	body := map[string]any{
-		"user_id":   userID,
 		"published": published,
 	}
 
@@ -127,6 +205,7 @@ func (tc *testClient) changeAdStatus(userID int64, adID int64, published bool) (
 	}
 
 	req.Header.Add("Content-Type", "application/json")
+	tc.authorize(req, userID)
 
 	var response adResponse
 	err = tc.getResponse(req, &response)
@@ -139,9 +218,8 @@ func (tc *testClient) changeAdStatus(userID int64, adID int64, published bool) (
 
 func (tc *testClient) updateAd(userID int64, adID int64, title string, text string) (adResponse, error) {
 	body := map[string]any{
-		"user_id": userID,
-		"title":   title,
-		"text":    text,
+		"title": title,
+		"text":  text,
 	}
 
 	data, err := json.Marshal(body)
@@ -155,6 +233,7 @@ func (tc *testClient) updateAd(userID int64, adID int64, title string, text stri
 	}
 
 	req.Header.Add("Content-Type", "application/json")
+	tc.authorize(req, userID)
 
 	var response adResponse
 	err = tc.getResponse(req, &response)
@@ -173,10 +252,16 @@ type userData struct {
 	ID       int64  `json:"id"`
 	Nickname string `json:"nickname"`
 	Email    string `json:"email"`
+	Role     string `json:"role"`
 }
 
 func (tc *testClient) listAds() (adsResponse, error) {
-	req, err := http.NewRequest(http.MethodGet, tc.baseURL+"/api/v1/ads", nil)
+	return tc.listAdsPage("")
+}
+
+// listAdsPage запрашивает страницу опубликованных объявлений, query - параметры sort, order, limit и cursor
+func (tc *testClient) listAdsPage(query string) (adsResponse, error) {
+	req, err := http.NewRequest(http.MethodGet, tc.baseURL+"/api/v1/ads?"+query, nil)
 	if err != nil {
 		return adsResponse{}, fmt.Errorf("unable to create request: %w", err)
 	}
@@ -191,19 +276,41 @@ func (tc *testClient) listAds() (adsResponse, error) {
 }
 
 type findAdsRequest struct {
-	ID           int64     `json:"id"`
-	Title        string    `json:"title"`
-	Text         string    `json:"text"`
-	AuthorID     int64     `json:"author_id"`
-	Published    bool      `json:"published"`
-	CreatedTime  time.Time `json:"created_time"`
-	ModifiedTime time.Time `json:"modified_time"`
+	AuthorIDs    []int64    `json:"author_ids,omitempty"`
+	Published    *bool      `json:"published,omitempty"`
+	CreatedFrom  *time.Time `json:"created_from,omitempty"`
+	CreatedTo    *time.Time `json:"created_to,omitempty"`
+	ModifiedFrom *time.Time `json:"modified_from,omitempty"`
+	ModifiedTo   *time.Time `json:"modified_to,omitempty"`
+	Title        string     `json:"title,omitempty"`
+	Text         string     `json:"text,omitempty"`
 }
 
+// createUser регистрирует пользователя с паролем testPassword через POST /users и сразу входит под ним
 func (tc *testClient) createUser(nickname string, email string) (userResponse, error) {
+	response, err := tc.postUser("/api/v1/users", nickname, email, testPassword)
+	if err != nil {
+		return userResponse{}, err
+	}
+
+	tokens, err := tc.login(email, testPassword)
+	if err != nil {
+		return userResponse{}, err
+	}
+	tc.tokens[response.Data.ID] = tokens.Data.AccessToken
+
+	return response, nil
+}
+
+func (tc *testClient) register(nickname string, email string, password string) (userResponse, error) {
+	return tc.postUser("/api/v1/auth/register", nickname, email, password)
+}
+
+func (tc *testClient) postUser(path string, nickname string, email string, password string) (userResponse, error) {
 	body := map[string]any{
 		"nickname": nickname,
 		"email":    email,
+		"password": password,
 	}
 
 	data, err := json.Marshal(body)
@@ -211,7 +318,7 @@ func (tc *testClient) createUser(nickname string, email string) (userResponse, e
 		return userResponse{}, fmt.Errorf("unable to marshal: %w", err)
 	}
 
-	req, err := http.NewRequest(http.MethodPost, tc.baseURL+"/api/v1/users", bytes.NewReader(data))
+	req, err := http.NewRequest(http.MethodPost, tc.baseURL+path, bytes.NewReader(data))
 	if err != nil {
 		return userResponse{}, fmt.Errorf("unable to create request: %w", err)
 	}
@@ -227,9 +334,67 @@ func (tc *testClient) createUser(nickname string, email string) (userResponse, e
 	return response, nil
 }
 
+type tokensResponse struct {
+	Data struct {
+		UserID           int64     `json:"user_id"`
+		AccessToken      string    `json:"access_token"`
+		ExpiresAt        time.Time `json:"expires_at"`
+		RefreshToken     string    `json:"refresh_token"`
+		RefreshExpiresAt time.Time `json:"refresh_expires_at"`
+	} `json:"data"`
+}
+
+func (tc *testClient) login(email string, password string) (tokensResponse, error) {
+	body := map[string]any{
+		"email":    email,
+		"password": password,
+	}
+
+	data, err := json.Marshal(body)
+	if err != nil {
+		return tokensResponse{}, fmt.Errorf("unable to marshal: %w", err)
+	}
+
+	req, err := http.NewRequest(http.MethodPost, tc.baseURL+"/api/v1/auth/login", bytes.NewReader(data))
+	if err != nil {
+		return tokensResponse{}, fmt.Errorf("unable to create request: %w", err)
+	}
+
+	req.Header.Add("Content-Type", "application/json")
+
+	var response tokensResponse
+	err = tc.getResponse(req, &response)
+	if err != nil {
+		return tokensResponse{}, err
+	}
+
+	return response, nil
+}
+
+func (tc *testClient) refresh(refreshToken string) (tokensResponse, error) {
+	data, err := json.Marshal(map[string]any{"refresh_token": refreshToken})
+	if err != nil {
+		return tokensResponse{}, fmt.Errorf("unable to marshal: %w", err)
+	}
+
+	req, err := http.NewRequest(http.MethodPost, tc.baseURL+"/api/v1/auth/refresh", bytes.NewReader(data))
+	if err != nil {
+		return tokensResponse{}, fmt.Errorf("unable to create request: %w", err)
+	}
+
+	req.Header.Add("Content-Type", "application/json")
+
+	var response tokensResponse
+	err = tc.getResponse(req, &response)
+	if err != nil {
+		return tokensResponse{}, err
+	}
+
+	return response, nil
+}
+
 func (tc *testClient) updateUser(userID int64, userIDToEdit int64, nickname string, email string) (userResponse, error) {
 	body := map[string]any{
-		"user_id":  userID,
 		"nickname": nickname,
 		"email":    email,
 	}
@@ -245,6 +410,7 @@ func (tc *testClient) updateUser(userID int64, userIDToEdit int64, nickname stri
 	}
 
 	req.Header.Add("Content-Type", "application/json")
+	tc.authorize(req, userID)
 
 	var response userResponse
 	err = tc.getResponse(req, &response)
@@ -255,12 +421,36 @@ func (tc *testClient) updateUser(userID int64, userIDToEdit int64, nickname stri
 	return response, nil
 }
 
-func (tc *testClient) getAdById(adID int64) (adResponse, error) {
+func (tc *testClient) setUserRole(userID int64, userIDToEdit int64, role string) (userResponse, error) {
+	data, err := json.Marshal(map[string]any{"role": role})
+	if err != nil {
+		return userResponse{}, fmt.Errorf("unable to marshal: %w", err)
+	}
+
+	req, err := http.NewRequest(http.MethodPut, fmt.Sprintf(tc.baseURL+"/api/v1/users/%d/role", userIDToEdit), bytes.NewReader(data))
+	if err != nil {
+		return userResponse{}, fmt.Errorf("unable to create request: %w", err)
+	}
+
+	req.Header.Add("Content-Type", "application/json")
+	tc.authorize(req, userID)
+
+	var response userResponse
+	err = tc.getResponse(req, &response)
+	if err != nil {
+		return userResponse{}, err
+	}
+
+	return response, nil
+}
+
+func (tc *testClient) getAdById(userID int64, adID int64) (adResponse, error) {
 	req, err := http.NewRequest(http.MethodGet, fmt.Sprintf(tc.baseURL+"/api/v1/ads/%d", adID), nil)
 	if err != nil {
 		return adResponse{}, fmt.Errorf("unable to create request: %w", err)
 	}
 	req.Header.Add("Content-Type", "application/json")
+	tc.authorize(req, userID)
 	var response adResponse
 	err = tc.getResponse(req, &response)
 	if err != nil {
@@ -269,6 +459,26 @@ func (tc *testClient) getAdById(adID int64) (adResponse, error) {
 	return response, nil
 }
 
+type searchResponse struct {
+	Data []struct {
+		adData
+		Score float64 `json:"score"`
+	} `json:"data"`
+}
+
+func (tc *testClient) searchAds(query string) (searchResponse, error) {
+	req, err := http.NewRequest(http.MethodGet, tc.baseURL+"/api/v1/ads/search?q="+url.QueryEscape(query), nil)
+	if err != nil {
+		return searchResponse{}, fmt.Errorf("unable to create request: %w", err)
+	}
+	var response searchResponse
+	err = tc.getResponse(req, &response)
+	if err != nil {
+		return searchResponse{}, err
+	}
+	return response, nil
+}
+
 func (tc *testClient) getAdsByTitle(title string) (adsResponse, error) {
 	req, err := http.NewRequest(http.MethodGet, fmt.Sprintf(tc.baseURL+"/api/v1/search/%s", title), nil)
 	if err != nil {
@@ -281,3 +491,40 @@ func (tc *testClient) getAdsByTitle(title string) (adsResponse, error) {
 	}
 	return response, nil
 }
+
+// grpcSignUp регистрирует пользователя через gRPC, входит под ним и возвращает контекст с его access-токеном
+func grpcSignUp(t *testing.T, ctx context.Context, client grpcPort.AdServiceClient, name string) (*grpcPort.UserResponse, context.Context) {
+	t.Helper()
+	email := strings.ToLower(name) + "@mail.ru"
+	user, err := client.CreateUser(ctx, &grpcPort.CreateUserRequest{Name: name, Email: email, Password: testPassword})
+	require.NoError(t, err, "client.CreateUser")
+	session, err := client.Login(ctx, &grpcPort.LoginRequest{Email: email, Password: testPassword})
+	require.NoError(t, err, "client.Login")
+	return user, metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer "+session.AccessToken)
+}
+
+// newGRPCClient поднимает gRPC-сервер поверх a в памяти, с проверкой токенов как в main, и возвращает клиента к нему
+func newGRPCClient(t *testing.T, a app.App) grpcPort.AdServiceClient {
+	t.Helper()
+	lis := bufconn.Listen(1024 * 1024)
+	t.Cleanup(func() {
+		lis.Close()
+	})
+
+	srv := grpc.NewServer(grpc.UnaryInterceptor(grpcPort.AuthInterceptor(a)), grpc.StreamInterceptor(grpcPort.AuthStreamInterceptor(a)))
+	t.Cleanup(srv.Stop)
+	grpcPort.RegisterAdServiceServer(srv, grpcPort.NewService(a))
+	go func() {
+		_ = srv.Serve(lis)
+	}()
+
+	dialer := func(context.Context, string) (net.Conn, error) {
+		return lis.Dial()
+	}
+	conn, err := grpc.Dial("", grpc.WithContextDialer(dialer), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.NoError(t, err, "grpc.Dial")
+	t.Cleanup(func() {
+		conn.Close()
+	})
+	return grpcPort.NewAdServiceClient(conn)
+}
@@ -2,10 +2,19 @@ package users
 
 import (
 	"context"
+	"errors"
 )
 
+var ErrNotFound = errors.New("not found")
+
+// ErrVersionConflict возвращается из UpdateByID, если пользователь успел измениться с момента чтения
+var ErrVersionConflict = errors.New("user version conflict")
+
+// UserRepository версионирует пользователей так же, как ads.AdRepository версионирует объявления
 type UserRepository interface {
 	GetUserByID(ctx context.Context, id int64) (*User, error)
+	// GetUserByEmail ищет по точному совпадению. Если таких пользователей несколько, возвращает первого по ID
+	GetUserByEmail(ctx context.Context, email string) (*User, error)
 	AddUser(ctx context.Context, user User) (int64, error)
 	UpdateByID(ctx context.Context, id int64, user User) error
 	DeleteUser(ctx context.Context, id int64)
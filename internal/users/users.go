@@ -1,7 +1,38 @@
 package users
 
+// Role определяет, что пользователю разрешено делать с чужими объявлениями и профилями
+type Role string
+
+const (
+	RoleUser      Role = "user"
+	RoleModerator Role = "moderator"
+	RoleAdmin     Role = "admin"
+)
+
+func (r Role) Valid() bool {
+	switch r {
+	case RoleUser, RoleModerator, RoleAdmin:
+		return true
+	}
+	return false
+}
+
 type User struct {
 	ID       int64
 	Nickname string
 	Email    string
+	// PasswordHash - bcrypt-хеш пароля. Пустой хеш означает, что войти под пользователем нельзя
+	PasswordHash string
+	// Role пуста у пользователей, сохранённых до появления ролей; см. EffectiveRole
+	Role Role
+	// Version растёт на единицу при каждом изменении пользователя
+	Version int64
+}
+
+// EffectiveRole возвращает роль пользователя, считая пустую роль обычным пользователем
+func (u User) EffectiveRole() Role {
+	if u.Role == "" {
+		return RoleUser
+	}
+	return u.Role
 }